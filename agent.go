@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/google/uuid"
+	"github.com/mudler/cogito/prompt"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -47,6 +48,9 @@ type AgentDefinition struct {
 	Iterations  int // optional per-type iteration cap (0 = inherit parent)
 	MaxAttempts int // optional per-type attempt cap (0 = inherit parent)
 	MaxRetries  int // optional per-type retry cap (0 = inherit parent)
+	// Prompts optionally overrides built-in prompts for this type, on top of
+	// the prompts inherited from the parent.
+	Prompts prompt.PromptMap
 }
 
 // AgentRunSpec is a portable, self-contained description of a single sub-agent
@@ -68,7 +72,7 @@ type AgentRunSpec struct {
 	// Emit, when non-nil, lets the executor stream progress back to the
 	// embedder. cogito wires this to forward tagged sub-agent StreamEvents to
 	// the parent stream callback. It is safe to ignore.
-	Emit func(AgentEvent)
+	Emit func(AgentEvent) `json:"-"`
 }
 
 // AgentEvent is a transport-friendly progress event emitted by an out-of-process
@@ -435,6 +439,9 @@ func (r *spawnAgentRunner) Run(args SpawnAgentArgs) (string, any, error) {
 		if def.MaxRetries > 0 {
 			subOpts = append(subOpts, WithMaxRetries(def.MaxRetries))
 		}
+		if len(def.Prompts) > 0 {
+			subOpts = append(subOpts, WithPrompts(def.Prompts))
+		}
 	}
 
 	// Seed the system prompt from the definition.
@@ -0,0 +1,192 @@
+package cogito
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AgentConfig is the configuration of an agent, read from a YAML or JSON
+// file by LoadAgentConfig and ReadAgentConfig, so its behavior can be
+// changed without recompiling. Values can reference environment variables
+// as $VAR or ${VAR}:
+//
+//	model: qwen3-8b
+//	base_url: http://localhost:8080/v1
+//	api_key: ${OPENAI_API_KEY}
+//	mcp_servers:
+//	  - name: github
+//	    command: docker
+//	    args: [run, -i, --rm, ghcr.io/github/github-mcp-server]
+//	    env: {GITHUB_PERSONAL_ACCESS_TOKEN: "${GITHUB_TOKEN}"}
+//	    namespace: github
+//	guidelines:
+//	  - condition: the user asks about an issue
+//	    action: look the issue up before answering
+//	    tools: [github__get_issue]
+//	options:
+//	  preset: thorough_researcher
+//	  iterations: 5
+//	  loop_detection: 3
+type AgentConfig struct {
+	// Model, BaseURL and APIKey select the LLM. They are not options, as
+	// the LLM is passed to ExecuteTools: use them to create the client.
+	Model   string `yaml:"model" json:"model"`
+	BaseURL string `yaml:"base_url" json:"base_url"`
+	APIKey  string `yaml:"api_key" json:"api_key"`
+
+	MCPServers []MCPServerSpec    `yaml:"mcp_servers" json:"mcp_servers"`
+	Guidelines []GuidelineConfig  `yaml:"guidelines" json:"guidelines"`
+	Options    AgentConfigOptions `yaml:"options" json:"options"`
+}
+
+// GuidelineConfig is a guideline of an AgentConfig. Its tools are
+// referenced by name among the tools of the run, including the ones of the
+// MCP servers.
+type GuidelineConfig struct {
+	Condition string   `yaml:"condition" json:"condition"`
+	Action    string   `yaml:"action" json:"action"`
+	Tools     []string `yaml:"tools" json:"tools"`
+}
+
+// AgentConfigOptions are the options of an AgentConfig. Zero values keep the
+// defaults.
+type AgentConfigOptions struct {
+	// Preset is applied before the other options: fast_chat or
+	// thorough_researcher.
+	Preset            string `yaml:"preset" json:"preset"`
+	Iterations        int    `yaml:"iterations" json:"iterations"`
+	MaxAttempts       int    `yaml:"max_attempts" json:"max_attempts"`
+	MaxRetries        int    `yaml:"max_retries" json:"max_retries"`
+	LoopDetection     int    `yaml:"loop_detection" json:"loop_detection"`
+	ToolCorrection    int    `yaml:"tool_correction" json:"tool_correction"`
+	ForceReasoning    bool   `yaml:"force_reasoning" json:"force_reasoning"`
+	DisableSinkState  bool   `yaml:"disable_sink_state" json:"disable_sink_state"`
+	ParallelToolCalls bool   `yaml:"parallel_tool_calls" json:"parallel_tool_calls"`
+	StrictGuidelines  bool   `yaml:"strict_guidelines" json:"strict_guidelines"`
+	GuidedTools       bool   `yaml:"guided_tools" json:"guided_tools"`
+	AutoPlan          bool   `yaml:"auto_plan" json:"auto_plan"`
+	Reflection        bool   `yaml:"reflection" json:"reflection"`
+	Locale            string `yaml:"locale" json:"locale"`
+	// SystemPrompt is the system prompt to start conversations with. It is
+	// not an option: add it to the fragments passed to the agent.
+	SystemPrompt string `yaml:"system_prompt" json:"system_prompt"`
+}
+
+// LoadAgentConfig reads the agent configuration at path and returns its
+// options. Use ReadAgentConfig to also get the model settings.
+func LoadAgentConfig(path string) ([]Option, error) {
+	c, err := ReadAgentConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return c.RunOptions()
+}
+
+// ReadAgentConfig reads the agent configuration at path.
+func ReadAgentConfig(path string) (*AgentConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent config: %w", err)
+	}
+	return ParseAgentConfig(data)
+}
+
+// ParseAgentConfig parses an agent configuration in YAML or JSON, expanding
+// the environment variables it references.
+func ParseAgentConfig(data []byte) (*AgentConfig, error) {
+	c := &AgentConfig{}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), c); err != nil {
+		return nil, fmt.Errorf("failed to parse agent config: %w", err)
+	}
+	for i, server := range c.MCPServers {
+		if err := server.validate(); err != nil {
+			return nil, fmt.Errorf("MCP server %d (%s): %w", i, server.Name, err)
+		}
+	}
+	if _, err := agentPreset(c.Options.Preset); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// RunOptions returns the options of the configuration. The MCP servers
+// are started for each run and stopped when it ends.
+func (c *AgentConfig) RunOptions() ([]Option, error) {
+	opts := []Option{}
+	o := c.Options
+	preset, err := agentPreset(o.Preset)
+	if err != nil {
+		return nil, err
+	}
+	if preset != nil {
+		opts = append(opts, preset)
+	}
+
+	if len(c.MCPServers) > 0 {
+		opts = append(opts, WithMCPServers(c.MCPServers...))
+	}
+	if len(c.Guidelines) > 0 {
+		guidelines := Guidelines{}
+		for _, g := range c.Guidelines {
+			guidelines = append(guidelines, Guideline{Condition: g.Condition, Action: g.Action, ToolNames: g.Tools})
+		}
+		opts = append(opts, WithGuidelines(guidelines...))
+	}
+
+	if o.Iterations > 0 {
+		opts = append(opts, WithIterations(o.Iterations))
+	}
+	if o.MaxAttempts > 0 {
+		opts = append(opts, WithMaxAttempts(o.MaxAttempts))
+	}
+	if o.MaxRetries > 0 {
+		opts = append(opts, WithMaxRetries(o.MaxRetries))
+	}
+	if o.LoopDetection > 0 {
+		opts = append(opts, WithLoopDetection(o.LoopDetection))
+	}
+	if o.ToolCorrection > 0 {
+		opts = append(opts, WithToolCorrection(o.ToolCorrection))
+	}
+	if o.ForceReasoning {
+		opts = append(opts, WithForceReasoning())
+	}
+	if o.DisableSinkState {
+		opts = append(opts, DisableSinkState)
+	}
+	if o.ParallelToolCalls {
+		opts = append(opts, EnableParallelToolExecution)
+	}
+	if o.StrictGuidelines {
+		opts = append(opts, EnableStrictGuidelines)
+	}
+	if o.GuidedTools {
+		opts = append(opts, EnableGuidedTools)
+	}
+	if o.AutoPlan {
+		opts = append(opts, EnableAutoPlan)
+	}
+	if o.Reflection {
+		opts = append(opts, EnableReflection)
+	}
+	if o.Locale != "" {
+		opts = append(opts, WithLocale(o.Locale))
+	}
+	return opts, nil
+}
+
+// agentPreset returns the preset named name in configuration files.
+// Presets needing code, like PresetCautiousOps, are not available.
+func agentPreset(name string) (Option, error) {
+	switch name {
+	case "":
+		return nil, nil
+	case "fast_chat":
+		return PresetFastChat(), nil
+	case "thorough_researcher":
+		return PresetThoroughResearcher(), nil
+	}
+	return nil, fmt.Errorf("unknown preset %q, use fast_chat or thorough_researcher", name)
+}
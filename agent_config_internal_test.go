@@ -0,0 +1,90 @@
+package cogito
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAgentConfig(t *testing.T) {
+	t.Setenv("TEST_AGENT_KEY", "sk-test")
+	c, err := ParseAgentConfig([]byte(`
+model: qwen3
+api_key: ${TEST_AGENT_KEY}
+mcp_servers:
+  - name: search
+    url: http://localhost:9000/mcp
+    namespace: web
+guidelines:
+  - condition: the user asks about a customer
+    action: look the customer up first
+    tools: [lookup]
+options:
+  preset: thorough_researcher
+  iterations: 4
+  tool_correction: 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Model != "qwen3" || c.APIKey != "sk-test" {
+		t.Fatalf("model settings = %q, %q", c.Model, c.APIKey)
+	}
+	opts, err := c.RunOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := defaultOptions()
+	o.Apply(opts...)
+	if o.maxIterations != 4 {
+		t.Errorf("iterations = %d, want the option to override the preset", o.maxIterations)
+	}
+	if !o.autoPlan || o.toolCorrectionRounds != 2 {
+		t.Errorf("preset or options not applied")
+	}
+	if len(o.mcpServers) != 1 || o.mcpServers[0].Namespace != "web" {
+		t.Errorf("mcp servers = %+v", o.mcpServers)
+	}
+	if len(o.guidelines) != 1 || o.guidelines[0].ToolNames[0] != "lookup" {
+		t.Errorf("guidelines = %+v", o.guidelines)
+	}
+}
+
+func TestParseAgentConfigJSON(t *testing.T) {
+	c, err := ParseAgentConfig([]byte(`{"model": "gpt-4o", "options": {"force_reasoning": true, "loop_detection": 2}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts, _ := c.RunOptions()
+	o := defaultOptions()
+	o.Apply(opts...)
+	if !o.forceReasoning || o.loopDetectionSteps != 2 {
+		t.Errorf("options not applied: force reasoning %v, loop detection %d", o.forceReasoning, o.loopDetectionSteps)
+	}
+}
+
+func TestParseAgentConfigErrors(t *testing.T) {
+	for config, want := range map[string]string{
+		"mcp_servers:\n  - name: broken":                  "either a command or a url",
+		"options:\n  preset: cautious_ops":                "unknown preset",
+		"mcp_servers:\n  - {name: x, command: y, url: z}": "either a command or a url",
+	} {
+		if _, err := ParseAgentConfig([]byte(config)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config %q: error = %v, want %q", config, err, want)
+		}
+	}
+}
+
+func TestResolveGuidelineTools(t *testing.T) {
+	lookup := NewToolDefinition[lookupArgs](&lookupRunner{}, lookupArgs{}, "lookup", "Look a customer up")
+	guidelines := resolveGuidelineTools(Guidelines{
+		{Condition: "customer", ToolNames: []string{"lookup", "missing"}},
+		{Condition: "other"},
+	}, Tools{lookup}, &recordingLogger{})
+	if len(guidelines[0].Tools) != 1 || guidelines[0].Tools[0] != lookup {
+		t.Errorf("tools = %v, want the lookup tool", guidelines[0].Tools.Names())
+	}
+	if len(guidelines[1].Tools) != 0 {
+		t.Errorf("guideline without tool names got tools")
+	}
+}
@@ -7,17 +7,17 @@ import (
 	"time"
 
 	. "github.com/mudler/cogito"
-	"github.com/mudler/cogito/tests/mock"
+	"github.com/mudler/cogito/cogitotest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
 )
 
 var _ = Describe("Agent dispatcher seam", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 	})
 
 	Context("Foreground dispatch", func() {
@@ -151,7 +151,7 @@ var _ = Describe("Agent dispatcher seam", func() {
 
 	Context("Fallback", func() {
 		It("runs the in-process path when the dispatcher returns ErrDispatchFallback", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			const sentinel = "IN-PROCESS-SUBAGENT-RAN"
 
@@ -161,7 +161,7 @@ var _ = Describe("Agent dispatcher seam", func() {
 				`{"task": "Search", "background": false}`)
 			// 2. Sub-agent (in-process, after fallback): select search.
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "x"}`)
-			mock.SetRunResult(mockTool, "tool ran in-process")
+			cogitotest.SetRunResult(mockTool, "tool ran in-process")
 			// 3. Sub-agent: no more tools (sink). The sink content is what cogito
 			// propagates as the agent's result, so the sentinel goes here.
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -217,14 +217,14 @@ var _ = Describe("Agent dispatcher seam", func() {
 
 	Context("Nil dispatcher", func() {
 		It("preserves existing in-process spawn behavior", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// 1. Parent: spawn_agent (foreground).
 			mockLLM.AddCreateChatCompletionFunction("spawn_agent",
 				`{"task": "Search", "background": false}`)
 			// 2. Sub-agent: select search.
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "x"}`)
-			mock.SetRunResult(mockTool, "tool result")
+			cogitotest.SetRunResult(mockTool, "tool result")
 			// 3. Sub-agent: no more tools (sink).
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{{
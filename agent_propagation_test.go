@@ -17,7 +17,7 @@ func TestSessionStateHasAgentID(t *testing.T) {
 
 // scriptedLLM is a minimal internal LLM mock for sub-agent tests. It drives a
 // single tool call (via CreateChatCompletion) then a plain reply (via Ask),
-// mirroring how the public mock.MockOpenAIClient is scripted in agent_test.go.
+// mirroring how the public cogitotest.MockLLM is scripted in agent_test.go.
 // We use an internal mock because these tests live in package cogito and need
 // to construct spawnAgentRunner directly.
 type scriptedLLM struct {
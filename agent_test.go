@@ -6,7 +6,7 @@ import (
 	"time"
 
 	. "github.com/mudler/cogito"
-	"github.com/mudler/cogito/tests/mock"
+	"github.com/mudler/cogito/cogitotest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
@@ -27,10 +27,10 @@ func (s *slowToolRunner) Run(args SlowToolArgs) (string, any, error) {
 }
 
 var _ = Describe("Sub-Agent Spawning", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 	})
 
 	Context("AgentManager", func() {
@@ -93,7 +93,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 
 	Context("Foreground agent spawning", func() {
 		It("should execute sub-agent synchronously and return result", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// 1. Parent iteration 1: LLM selects spawn_agent tool
 			mockLLM.AddCreateChatCompletionFunction("spawn_agent",
@@ -102,7 +102,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 			// --- Sub-agent starts (synchronous, consumes from same mock) ---
 			// 2. Sub-agent iteration 1: LLM selects search tool
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "photosynthesis"}`)
-			mock.SetRunResult(mockTool, "Photosynthesis converts sunlight to energy.")
+			cogitotest.SetRunResult(mockTool, "Photosynthesis converts sunlight to energy.")
 
 			// 3. Sub-agent iteration 2: no more tools (sink state)
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -156,7 +156,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 
 	Context("Background agent spawning", func() {
 		It("should spawn agent in background and return ID", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Parent: LLM selects spawn_agent with background=true
 			mockLLM.AddCreateChatCompletionFunction("spawn_agent",
@@ -164,7 +164,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 
 			// Sub-agent (in goroutine): LLM selects search tool
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "background"}`)
-			mock.SetRunResult(mockTool, "Background result.")
+			cogitotest.SetRunResult(mockTool, "Background result.")
 
 			// Sub-agent: no more tools
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -317,7 +317,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 
 	Context("Completion callback", func() {
 		It("should fire callback when background agent finishes", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Parent: LLM selects spawn_agent with background=true
 			mockLLM.AddCreateChatCompletionFunction("spawn_agent",
@@ -325,7 +325,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 
 			// Sub-agent: LLM selects search
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Callback result.")
+			cogitotest.SetRunResult(mockTool, "Callback result.")
 
 			// Sub-agent: no more tools
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -387,9 +387,9 @@ var _ = Describe("Sub-Agent Spawning", func() {
 	Context("Tool filtering", func() {
 		It("should exclude agent tools from sub-agents by default", func() {
 			parentTools := Tools{}
-			searchTool := mock.NewMockTool("search", "Search")
-			spawnTool := mock.NewMockTool("spawn_agent", "Spawn agent")
-			checkTool := mock.NewMockTool("check_agent", "Check agent")
+			searchTool := cogitotest.NewMockTool("search", "Search")
+			spawnTool := cogitotest.NewMockTool("spawn_agent", "Spawn agent")
+			checkTool := cogitotest.NewMockTool("check_agent", "Check agent")
 			parentTools = append(parentTools, searchTool, spawnTool, checkTool)
 
 			filtered := FilterToolsForSubAgent(parentTools, nil)
@@ -399,8 +399,8 @@ var _ = Describe("Sub-Agent Spawning", func() {
 
 		It("should filter to requested tools only", func() {
 			parentTools := Tools{}
-			searchTool := mock.NewMockTool("search", "Search")
-			weatherTool := mock.NewMockTool("weather", "Weather")
+			searchTool := cogitotest.NewMockTool("search", "Search")
+			weatherTool := cogitotest.NewMockTool("weather", "Weather")
 			parentTools = append(parentTools, searchTool, weatherTool)
 
 			filtered := FilterToolsForSubAgent(parentTools, []string{"weather"})
@@ -412,7 +412,7 @@ var _ = Describe("Sub-Agent Spawning", func() {
 	Context("Loop stays alive for background agents", func() {
 		It("should keep ExecuteTools alive until background agents complete", func() {
 			// Use a separate mock for the sub-agent to avoid response ordering issues
-			subAgentMockLLM := mock.NewMockOpenAIClient()
+			subAgentMockLLM := cogitotest.NewMockLLM()
 
 			// A slow tool that blocks until we release it — simulates a long-running sub-agent
 			slowToolReady := make(chan struct{})
@@ -510,8 +510,8 @@ var _ = Describe("Sub-Agent Spawning", func() {
 		// with an EMPTY AgentID. The sub-agent's restricted "echo" tool running
 		// proves the AgentDefinition's tool restriction took effect.
 		It("propagates an empty AgentID for the parent's tool call and a non-empty AgentID for the restricted sub-agent tool", func() {
-			parentMock := mock.NewMockOpenAIClient()
-			subMock := mock.NewMockOpenAIClient()
+			parentMock := cogitotest.NewMockLLM()
+			subMock := cogitotest.NewMockLLM()
 
 			// --- Parent script ---
 			// 1. Parent iteration 1: LLM decides to call spawn_agent (foreground).
@@ -545,8 +545,8 @@ var _ = Describe("Sub-Agent Spawning", func() {
 			subMock.SetAskResponse("echoed: hi")
 
 			// The echo tool the sub-agent is allowed to use.
-			echoTool := mock.NewMockTool("echo", "Echo back the provided text")
-			mock.SetRunResult(echoTool, "echoed: hi")
+			echoTool := cogitotest.NewMockTool("echo", "Echo back the provided text")
+			cogitotest.SetRunResult(echoTool, "echoed: hi")
 
 			// The named sub-agent persona, restricted to the echo tool.
 			def := AgentDefinition{
@@ -637,8 +637,8 @@ var _ = Describe("Sub-Agent Spawning", func() {
 		// WithAgentSpawnCallback must fire at spawn time with a running explore
 		// agent, and WithAgentCompletionCallback must fire when it completes.
 		It("fires the spawn callback with a running explore agent and completes the background agent", func() {
-			parentMock := mock.NewMockOpenAIClient()
-			subMock := mock.NewMockOpenAIClient()
+			parentMock := cogitotest.NewMockLLM()
+			subMock := cogitotest.NewMockLLM()
 
 			// --- Parent script ---
 			// 1. Parent: spawn an explore agent in the background.
@@ -676,8 +676,8 @@ var _ = Describe("Sub-Agent Spawning", func() {
 			})
 			subMock.SetAskResponse("echoed: bg")
 
-			echoTool := mock.NewMockTool("echo", "Echo back the provided text")
-			mock.SetRunResult(echoTool, "echoed: bg")
+			echoTool := cogitotest.NewMockTool("echo", "Echo back the provided text")
+			cogitotest.SetRunResult(echoTool, "echoed: bg")
 
 			def := AgentDefinition{
 				Name:         "explore",
@@ -0,0 +1,48 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ExecuteToolsAndAnswer runs ExecuteTools on f with ctx and returns a
+// fragment ending with an answer. When the run ends without an assistant
+// message, as when no tool is needed or the iterations run out after a tool
+// call, llm is asked for the answer over the result of the run, so it builds
+// on the tool calls and results. Running out of tools to call
+// (ErrNoToolSelected) is not an error.
+func ExecuteToolsAndAnswer(ctx context.Context, llm LLM, f Fragment, opts ...Option) (Fragment, error) {
+	opts = withCtx(ctx, opts)
+	result, err := ExecuteTools(llm, f, opts...)
+	if err != nil && !errors.Is(err, ErrNoToolSelected) {
+		return result, err
+	}
+	if last := result.LastMessage(); last != nil && last.Role == openai.ChatMessageRoleAssistant {
+		return result, nil
+	}
+
+	// The answer goes through the same redactor, limits and cache as the run
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+	if o.textToolCalls {
+		llm = newTextToolsLLM(llm, o.prompts, o.logger)
+	} else if o.toolAPIFallback {
+		llm = newToolFallbackLLM(llm, o.prompts, o.logger)
+	}
+
+	var cumulative LLMUsage
+	if result.Status != nil {
+		cumulative = result.Status.CumulativeUsage
+	}
+	answer, err := askFiltered(o, llm, result)
+	if err != nil {
+		return answer, err
+	}
+	if answer.Status != nil {
+		answer.Status.CumulativeUsage = addUsage(cumulative, answer.Status.LastUsage)
+	}
+	return answer, nil
+}
@@ -0,0 +1,73 @@
+package cogito_test
+
+import (
+	"context"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("ExecuteToolsAndAnswer", func() {
+	It("answers over the tool results when the run ends without an answer", func() {
+		llm := cogitotest.NewMockLLM()
+		search := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(search, "Rome is the capital of Italy")
+		llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+		// The final answer of the run comes back empty
+		llm.AskResponses = append(llm.AskResponses, NewEmptyFragment())
+		llm.SetAskResponse("The capital of Italy is Rome.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What is the capital of Italy?")
+		result, err := ExecuteToolsAndAnswer(context.Background(), llm, f, WithTools(search), WithIterations(1), DisableSinkState)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LastMessage().Content).To(Equal("The capital of Italy is Rome."))
+
+		Expect(llm.FragmentHistory).To(HaveLen(2))
+		Expect(llm.FragmentHistory[1].Messages).To(ContainElement(And(
+			HaveField("Role", openai.ChatMessageRoleTool),
+			HaveField("Content", "Rome is the capital of Italy"),
+		)))
+	})
+
+	It("asks for the answer through the run options", func() {
+		llm := cogitotest.NewMockLLM()
+		search := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(search, "Write to jane@example.com")
+		llm.AddCreateChatCompletionFunction("search", `{"query": "contact"}`)
+		llm.AskResponses = append(llm.AskResponses, NewEmptyFragment())
+		llm.SetAskResponse("Write to the address above.")
+		llm.AskUsage = []LLMUsage{{}, {PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}}
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Who do I contact?")
+		result, err := ExecuteToolsAndAnswer(context.Background(), llm, f, WithTools(search), WithIterations(1), DisableSinkState,
+			WithRedactor(NewRedactor()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LastMessage().Content).To(Equal("Write to the address above."))
+
+		Expect(llm.FragmentHistory).To(HaveLen(2))
+		for _, msg := range llm.FragmentHistory[1].Messages {
+			Expect(msg.Content).ToNot(ContainSubstring("jane@example.com"))
+		}
+		Expect(result.Status.CumulativeUsage.TotalTokens).To(BeNumerically(">=", 15))
+	})
+
+	It("keeps the attachments of the conversation in the answer", func() {
+		llm := cogitotest.NewMockLLM()
+		search := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(search, "A photo of a cat")
+		llm.AddCreateChatCompletionFunction("search", `{"query": "photo"}`)
+		llm.AskResponses = append(llm.AskResponses, NewEmptyFragment())
+		llm.SetAskResponse("A cat.")
+		photo := NewImageURL("https://example.com/cat.png")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What is in this photo?", photo)
+		result, err := ExecuteToolsAndAnswer(context.Background(), llm, f, WithTools(search), WithIterations(1), DisableSinkState)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LastMessage().Content).To(Equal("A cat."))
+		Expect(result.Multimedia).To(HaveLen(1))
+		Expect(result.Multimedia[0].URL()).To(Equal(photo.URL()))
+	})
+})
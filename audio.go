@@ -0,0 +1,53 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Transcriber turns speech into text, e.g. with a Whisper model.
+// clients.OpenAITranscriber implements it for OpenAI-compatible
+// /audio/transcriptions endpoints.
+type Transcriber interface {
+	// Transcribe returns the text spoken in audio, encoded as mimeType
+	// (e.g. "audio/wav").
+	Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error)
+}
+
+// Speaker turns text into speech. clients.OpenAISpeaker implements it for
+// OpenAI-compatible /audio/speech endpoints.
+type Speaker interface {
+	// Speak returns text read aloud, and the MIME type of the audio.
+	Speak(ctx context.Context, text string) (audio []byte, mimeType string, err error)
+}
+
+// AddAudioMessage transcribes audio with t and adds the transcript as a
+// message of role, so voice input can drive Ask, ExecuteTools and the other
+// primitives. The audio is not attached: use AddMessage with NewAudioData
+// for models that understand audio themselves.
+func (r Fragment) AddAudioMessage(ctx context.Context, t Transcriber, role MessageRole, audio []byte, mimeType string) (Fragment, error) {
+	text, err := t.Transcribe(ctx, audio, mimeType)
+	if err != nil {
+		return r, fmt.Errorf("failed to transcribe audio: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return r, fmt.Errorf("failed to transcribe audio: no speech recognized")
+	}
+	return r.AddMessage(role, text), nil
+}
+
+// SpeakAnswer reads the last message of f aloud with s, to reply to voice
+// input with the final answer of a run.
+func SpeakAnswer(ctx context.Context, s Speaker, f Fragment) ([]byte, string, error) {
+	last := f.LastMessage()
+	if last == nil || strings.TrimSpace(last.Content) == "" {
+		return nil, "", fmt.Errorf("no answer to speak")
+	}
+	audio, mimeType, err := s.Speak(ctx, last.Content)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to synthesize speech: %w", err)
+	}
+	return audio, mimeType, nil
+}
@@ -0,0 +1,47 @@
+package cogito_test
+
+import (
+	"context"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type fakeTranscriber struct{ text string }
+
+func (t fakeTranscriber) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	return t.text, nil
+}
+
+// echoSpeaker "speaks" by returning the text as audio.
+type echoSpeaker struct{}
+
+func (echoSpeaker) Speak(ctx context.Context, text string) ([]byte, string, error) {
+	return []byte(text), "audio/wav", nil
+}
+
+var _ = Describe("Audio", func() {
+	It("drives a run from voice input and speaks the answer", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.SetAskResponse("It is sunny.")
+
+		f, err := NewEmptyFragment().AddAudioMessage(context.Background(), fakeTranscriber{text: " What's the weather? "}, UserMessageRole, []byte("RIFF"), "audio/wav")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(f.LastMessage().Content).To(Equal("What's the weather?"))
+
+		f, err = mockLLM.Ask(context.Background(), f)
+		Expect(err).ToNot(HaveOccurred())
+
+		audio, mimeType, err := SpeakAnswer(context.Background(), echoSpeaker{}, f)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(audio)).To(Equal("It is sunny."))
+		Expect(mimeType).To(Equal("audio/wav"))
+	})
+
+	It("fails when no speech is recognized", func() {
+		_, err := NewEmptyFragment().AddAudioMessage(context.Background(), fakeTranscriber{}, UserMessageRole, []byte("RIFF"), "audio/wav")
+		Expect(err).To(MatchError(ContainSubstring("no speech recognized")))
+	})
+})
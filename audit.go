@@ -0,0 +1,78 @@
+package cogito
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// AuditEntry is a step of an audit trail: what changed in the fragment
+// during the step.
+type AuditEntry struct {
+	Step      string // "iteration", or "final" for the last changes of the run
+	Iteration int    // Iterations completed at the end of the step
+	Time      time.Time
+	Diff      FragmentDiff
+}
+
+// AuditTrail records, step by step, what ExecuteTools did to a fragment, see
+// WithAuditTrail. It is safe for concurrent use.
+type AuditTrail struct {
+	mu      sync.Mutex
+	entries []AuditEntry
+}
+
+// Entries returns the recorded steps, in order.
+func (t *AuditTrail) Entries() []AuditEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.entries)
+}
+
+func (t *AuditTrail) add(e AuditEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = append(t.entries, e)
+}
+
+// auditRecorder diffs the fragment of a run against its last snapshot.
+type auditRecorder struct {
+	trail     *AuditTrail
+	snapshot  Fragment
+	usage     *usageCounter // of the run, the status only has it at the end
+	lastUsage LLMUsage
+}
+
+// newAuditRecorder returns a recorder of the changes to f in trail, nil
+// when trail is nil.
+func newAuditRecorder(trail *AuditTrail, f Fragment, usage *usageCounter) *auditRecorder {
+	if trail == nil {
+		return nil
+	}
+	return &auditRecorder{trail: trail, snapshot: f.Fork(), usage: usage}
+}
+
+// record adds the changes to f since the last call as a step, if any.
+func (r *auditRecorder) record(step string, f Fragment) {
+	if r == nil {
+		return
+	}
+	diff := DiffFragments(r.snapshot, f)
+	usage := r.usage.snapshot()
+	diff.Usage = LLMUsage{
+		PromptTokens:     usage.PromptTokens - r.lastUsage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens - r.lastUsage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens - r.lastUsage.TotalTokens,
+	}
+	if diff.IsEmpty() {
+		return
+	}
+	iteration := 0
+	if f.Status != nil {
+		iteration = f.Status.Iterations
+	}
+	r.trail.add(AuditEntry{Step: step, Iteration: iteration, Time: time.Now(), Diff: diff})
+	// The status is updated in place, keep a copy
+	r.snapshot = f.Fork()
+	r.lastUsage = usage
+}
@@ -5,7 +5,6 @@ import (
 	"strings"
 
 	"github.com/mudler/cogito/prompt"
-	"github.com/mudler/xlog"
 )
 
 // AutoImproveState holds the state for the autoimproving feature.
@@ -73,7 +72,7 @@ func executeAutoImproveReview(llm LLM, f Fragment, state *AutoImproveState, o *O
 		CurrentPrompt: state.SystemPrompt,
 	})
 	if err != nil {
-		xlog.Warn("[autoimprove] Failed to render review system prompt", "error", err)
+		o.logger.Warn("[autoimprove] Failed to render review system prompt", "error", err)
 		return
 	}
 
@@ -89,7 +88,7 @@ func executeAutoImproveReview(llm LLM, f Fragment, state *AutoImproveState, o *O
 		ToolResults:  formatToolResults(f),
 	})
 	if err != nil {
-		xlog.Warn("[autoimprove] Failed to render review user prompt", "error", err)
+		o.logger.Warn("[autoimprove] Failed to render review user prompt", "error", err)
 		return
 	}
 
@@ -120,7 +119,7 @@ func executeAutoImproveReview(llm LLM, f Fragment, state *AutoImproveState, o *O
 
 	_, err = ExecuteTools(reviewerLLM, reviewFragment, reviewOpts...)
 	if err != nil {
-		xlog.Warn("[autoimprove] Review step failed, state unchanged", "error", err)
+		o.logger.Warn("[autoimprove] Review step failed, state unchanged", "error", err)
 		return
 	}
 
@@ -130,11 +129,11 @@ func executeAutoImproveReview(llm LLM, f Fragment, state *AutoImproveState, o *O
 	// If the tool was called, update the system prompt
 	if captured.NewSystemPrompt != "" {
 		state.SystemPrompt = captured.NewSystemPrompt
-		xlog.Debug("[autoimprove] System prompt updated",
+		o.logger.Debug("[autoimprove] System prompt updated",
 			"reasoning", captured.Reasoning,
 			"reviewCount", state.ReviewCount)
 	} else {
-		xlog.Debug("[autoimprove] Reviewer did not call edit_system_prompt tool, prompt unchanged",
+		o.logger.Debug("[autoimprove] Reviewer did not call edit_system_prompt tool, prompt unchanged",
 			"reviewCount", state.ReviewCount)
 	}
 }
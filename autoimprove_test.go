@@ -2,18 +2,18 @@ package cogito_test
 
 import (
 	. "github.com/mudler/cogito"
-	"github.com/mudler/cogito/tests/mock"
+	"github.com/mudler/cogito/cogitotest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
 )
 
 var _ = Describe("AutoImprove", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 	var originalFragment Fragment
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 		originalFragment = NewEmptyFragment().
 			AddMessage(UserMessageRole, "What is photosynthesis?").
 			AddMessage(AssistantMessageRole, "Photosynthesis is how plants make energy from sunlight.")
@@ -22,11 +22,11 @@ var _ = Describe("AutoImprove", func() {
 	Context("Empty initial state", func() {
 		It("should create a system prompt from scratch when state is empty", func() {
 			state := &AutoImproveState{}
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Main loop: tool selection and execution
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "photosynthesis"}`)
-			mock.SetRunResult(mockTool, "Plants use chlorophyll to convert sunlight.")
+			cogitotest.SetRunResult(mockTool, "Plants use chlorophyll to convert sunlight.")
 
 			// Main loop: second iteration returns no tool (sink state equivalent)
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -68,11 +68,11 @@ var _ = Describe("AutoImprove", func() {
 				SystemPrompt: "Be concise and accurate.",
 				ReviewCount:  2,
 			}
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Main loop: tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Test result")
+			cogitotest.SetRunResult(mockTool, "Test result")
 
 			// Second iteration: no more tools
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -113,12 +113,12 @@ var _ = Describe("AutoImprove", func() {
 	Context("WithAutoImproveReviewerLLM", func() {
 		It("should use separate LLM for review step", func() {
 			state := &AutoImproveState{}
-			mockTool := mock.NewMockTool("search", "Search for information")
-			reviewerLLM := mock.NewMockOpenAIClient()
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			reviewerLLM := cogitotest.NewMockLLM()
 
 			// Main loop on primary LLM
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{
 					{
@@ -160,12 +160,12 @@ var _ = Describe("AutoImprove", func() {
 				SystemPrompt: "Original prompt.",
 				ReviewCount:  5,
 			}
-			mockTool := mock.NewMockTool("search", "Search for information")
-			reviewerLLM := mock.NewMockOpenAIClient()
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			reviewerLLM := cogitotest.NewMockLLM()
 
 			// Main loop
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{
 					{
@@ -204,11 +204,11 @@ var _ = Describe("AutoImprove", func() {
 				SystemPrompt: "Existing prompt.",
 				ReviewCount:  1,
 			}
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Main loop
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{
 					{
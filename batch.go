@@ -0,0 +1,46 @@
+package cogito
+
+import "sync"
+
+// ExecuteToolsBatch runs ExecuteTools on every fragment with the same options,
+// at most WithBatchConcurrency fragments at a time (4 by default). Results and
+// errors are returned in the order of fragments. State held by the options is
+// shared by the whole batch; with WithToolResultCache, a tool called with the
+// same arguments by several fragments runs only once.
+func ExecuteToolsBatch(llm LLM, fragments []Fragment, opts ...Option) ([]Fragment, []error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+
+	if o.toolCacheEnabled && o.toolCache == nil {
+		cache := newToolResultCache(o.toolCacheTools)
+		opts = append(opts, func(o *Options) { o.toolCache = cache })
+	}
+
+	results := make([]Fragment, len(fragments))
+	errs := make([]error, len(fragments))
+	sem := make(chan struct{}, max(o.batchConcurrency, 1))
+
+	var wg sync.WaitGroup
+	for i, f := range fragments {
+		select {
+		case sem <- struct{}{}:
+		case <-o.context.Done():
+			results[i], errs[i] = f, o.context.Err()
+			continue
+		}
+		// select picks at random when both cases are ready
+		if err := o.context.Err(); err != nil {
+			<-sem
+			results[i], errs[i] = f, err
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = ExecuteTools(llm, f, opts...)
+		}()
+	}
+	wg.Wait()
+	return results, errs
+}
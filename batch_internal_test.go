@@ -0,0 +1,146 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// lookupLLM calls the lookup tool with the company named in the user message,
+// then answers. It is safe for concurrent use and tracks in-flight calls.
+type lookupLLM struct {
+	mu                    sync.Mutex
+	inFlight, maxInFlight int
+}
+
+func (l *lookupLLM) enter() func() {
+	l.mu.Lock()
+	l.inFlight++
+	l.maxInFlight = max(l.maxInFlight, l.inFlight)
+	l.mu.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	return func() {
+		l.mu.Lock()
+		l.inFlight--
+		l.mu.Unlock()
+	}
+}
+
+func (l *lookupLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	defer l.enter()()
+	var company string
+	for _, m := range req.Messages {
+		if m.Role == UserMessageRole.String() {
+			company = strings.TrimPrefix(m.Content, "Classify ticket from ")
+		}
+	}
+	return LLMReply{ChatCompletionResponse: openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+			Role: AssistantMessageRole.String(),
+			ToolCalls: []openai.ToolCall{{
+				ID:       "call",
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "lookup", Arguments: fmt.Sprintf(`{"company": %q}`, company)},
+			}},
+		}}},
+	}}, LLMUsage{}, nil
+}
+
+func (l *lookupLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	defer l.enter()()
+	return f.AddMessage(AssistantMessageRole, "classified"), nil
+}
+
+type lookupArgs struct {
+	Company string `json:"company"`
+}
+
+type lookupRunner struct{ runs atomic.Int32 }
+
+func (r *lookupRunner) Run(args lookupArgs) (string, any, error) {
+	r.runs.Add(1)
+	return "plan of " + args.Company + ": enterprise", nil, nil
+}
+
+func batchFragments(companies ...string) []Fragment {
+	var fragments []Fragment
+	for _, c := range companies {
+		fragments = append(fragments, NewEmptyFragment().AddMessage(UserMessageRole, "Classify ticket from "+c))
+	}
+	return fragments
+}
+
+func TestExecuteToolsBatchRunsEveryFragmentInOrder(t *testing.T) {
+	llm := &lookupLLM{}
+	runner := &lookupRunner{}
+	tool := NewToolDefinition(runner, lookupArgs{}, "lookup", "Look up the plan of a company")
+
+	companies := []string{"acme", "globex", "initech", "umbrella", "hooli"}
+	results, errs := ExecuteToolsBatch(llm, batchFragments(companies...), WithTools(tool), WithBatchConcurrency(2))
+
+	if len(results) != len(companies) || len(errs) != len(companies) {
+		t.Fatalf("got %d results and %d errors, want %d", len(results), len(errs), len(companies))
+	}
+	for i, c := range companies {
+		if errs[i] != nil {
+			t.Fatalf("fragment %d: %v", i, errs[i])
+		}
+		if got := results[i].Status.ToolResults[0].Result; got != "plan of "+c+": enterprise" {
+			t.Fatalf("fragment %d: tool result = %q", i, got)
+		}
+	}
+	if got := llm.maxInFlight; got > 2 {
+		t.Fatalf("max concurrent LLM calls = %d, want at most 2", got)
+	}
+	if got := runner.runs.Load(); got != int32(len(companies)) {
+		t.Fatalf("tool ran %d times, want %d", got, len(companies))
+	}
+}
+
+func TestExecuteToolsBatchSharesToolResultCache(t *testing.T) {
+	llm := &lookupLLM{}
+	runner := &lookupRunner{}
+	tool := NewToolDefinition(runner, lookupArgs{}, "lookup", "Look up the plan of a company")
+
+	results, errs := ExecuteToolsBatch(llm, batchFragments("acme", "acme", "globex", "acme"),
+		WithTools(tool), WithToolResultCache("lookup"))
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("fragment %d: %v", i, err)
+		}
+	}
+	if got := runner.runs.Load(); got != 2 {
+		t.Fatalf("tool ran %d times, want 2 (one per distinct company)", got)
+	}
+	if got := results[3].Status.ToolResults[0].Result; got != "plan of acme: enterprise" {
+		t.Fatalf("cached result = %q", got)
+	}
+}
+
+func TestExecuteToolsBatchStopsOnCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tool := NewToolDefinition(&lookupRunner{}, lookupArgs{}, "lookup", "Look up the plan of a company")
+
+	llm := &lookupLLM{}
+	companies := make([]string, 20)
+	for i := range companies {
+		companies[i] = fmt.Sprintf("company %d", i)
+	}
+	_, errs := ExecuteToolsBatch(llm, batchFragments(companies...), WithTools(tool), WithContext(ctx))
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("fragment %d: error = %v, want context canceled", i, err)
+		}
+	}
+	if llm.maxInFlight != 0 {
+		t.Fatal("fragments started after the context was cancelled")
+	}
+}
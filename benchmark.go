@@ -0,0 +1,187 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BenchmarkScenario is a task run by RunBenchmark with every strategy and
+// model.
+type BenchmarkScenario struct {
+	Name     string
+	Fragment Fragment
+	// Options of the scenario, typically WithTools. They are applied before
+	// the options of the strategy.
+	Options []Option
+	// Success reports whether a run solved the scenario, e.g. by checking the
+	// tools called in result.Status.ToolResults. By default a run succeeds
+	// when it produces a reply without error.
+	Success func(result Fragment, err error) bool
+}
+
+// BenchmarkStrategy is a way of running the scenarios, compared by
+// RunBenchmark.
+type BenchmarkStrategy struct {
+	Name    string
+	Options []Option
+	// Samples is the number of times each scenario is run, keeping the
+	// outcome most runs agree on (self-consistency). 0 and 1 run it once.
+	Samples int
+}
+
+var (
+	// StrategyDirect selects tools directly, without forced reasoning.
+	StrategyDirect = BenchmarkStrategy{Name: "direct"}
+	// StrategyForcedReasoning reasons before selecting each tool and its
+	// arguments, in separate completions.
+	StrategyForcedReasoning = BenchmarkStrategy{Name: "forced_reasoning", Options: []Option{WithForceReasoning()}}
+	// StrategyReAct interleaves a thought and an action in one completion at
+	// each step, ReAct style.
+	StrategyReAct = BenchmarkStrategy{Name: "react", Options: []Option{WithForceReasoning(), WithConsolidatedReasoning()}}
+)
+
+// SelfConsistencyStrategy runs each scenario samples times with forced
+// reasoning and keeps the outcome most runs agree on.
+func SelfConsistencyStrategy(samples int) BenchmarkStrategy {
+	return BenchmarkStrategy{
+		Name:    fmt.Sprintf("self_consistency_%d", samples),
+		Options: []Option{WithForceReasoning()},
+		Samples: samples,
+	}
+}
+
+// DefaultBenchmarkStrategies are the strategies compared by RunBenchmark
+// when none are given.
+func DefaultBenchmarkStrategies() []BenchmarkStrategy {
+	return []BenchmarkStrategy{StrategyDirect, StrategyForcedReasoning, StrategyReAct, SelfConsistencyStrategy(3)}
+}
+
+// BenchmarkModel is an LLM compared by RunBenchmark.
+type BenchmarkModel struct {
+	Name string
+	LLM  LLM
+}
+
+// BenchmarkResult holds the metrics of a strategy with a model over all the
+// scenarios.
+type BenchmarkResult struct {
+	Model, Strategy string
+
+	Scenarios   int
+	Successes   int
+	SuccessRate float64
+	LLMCalls    int           // LLM calls, over every sample
+	Usage       LLMUsage      // token usage, over every sample
+	Latency     time.Duration // mean wall time per scenario
+	Failed      []string      // scenarios not solved
+}
+
+// BenchmarkReport is the outcome of RunBenchmark, one result per model and
+// strategy. Print it for a table.
+type BenchmarkReport struct {
+	Results []BenchmarkResult
+}
+
+// RunBenchmark runs every scenario with every strategy and model and
+// reports the success rate, LLM calls, tokens and latency of each
+// combination, to choose options on a workload rather than by intuition.
+// opts apply to every run, before the scenario and strategy options.
+// Scenarios run one after another so latencies are comparable; an error is
+// returned only if ctx is done.
+func RunBenchmark(ctx context.Context, models []BenchmarkModel, scenarios []BenchmarkScenario, strategies []BenchmarkStrategy, opts ...Option) (BenchmarkReport, error) {
+	if len(strategies) == 0 {
+		strategies = DefaultBenchmarkStrategies()
+	}
+	var report BenchmarkReport
+	for _, model := range models {
+		for _, strategy := range strategies {
+			res := BenchmarkResult{Model: model.Name, Strategy: strategy.Name, Scenarios: len(scenarios)}
+			var elapsed time.Duration
+			for _, scenario := range scenarios {
+				if err := ctx.Err(); err != nil {
+					return report, err
+				}
+				counter := &usageCounter{}
+				llm := newCountingLLM(model.LLM, counter)
+
+				start := time.Now()
+				solved := runBenchmarkScenario(ctx, llm, scenario, strategy, opts)
+				elapsed += time.Since(start)
+
+				res.LLMCalls += int(counter.calls.Load())
+				res.Usage = addUsage(res.Usage, counter.snapshot())
+				if solved {
+					res.Successes++
+				} else {
+					res.Failed = append(res.Failed, scenario.Name)
+				}
+			}
+			if len(scenarios) > 0 {
+				res.SuccessRate = float64(res.Successes) / float64(len(scenarios))
+				res.Latency = elapsed / time.Duration(len(scenarios))
+			}
+			report.Results = append(report.Results, res)
+		}
+	}
+	return report, nil
+}
+
+// runBenchmarkScenario runs scenario with strategy and reports whether the
+// kept outcome solved it.
+func runBenchmarkScenario(ctx context.Context, llm LLM, scenario BenchmarkScenario, strategy BenchmarkStrategy, opts []Option) bool {
+	runOpts := append([]Option{WithContext(ctx)}, opts...)
+	runOpts = append(runOpts, scenario.Options...)
+	runOpts = append(runOpts, strategy.Options...)
+
+	type sample struct {
+		result Fragment
+		err    error
+	}
+	var samples []sample
+	votes := map[string]int{}
+	for range max(strategy.Samples, 1) {
+		result, err := ExecuteTools(llm, scenario.Fragment, runOpts...)
+		samples = append(samples, sample{result, err})
+		votes[benchmarkOutcomeKey(result, err)]++
+	}
+
+	// Keep the first sample with the most common outcome
+	kept := samples[0]
+	for _, s := range samples[1:] {
+		if votes[benchmarkOutcomeKey(s.result, s.err)] > votes[benchmarkOutcomeKey(kept.result, kept.err)] {
+			kept = s
+		}
+	}
+
+	success := scenario.Success
+	if success == nil {
+		success = func(_ Fragment, err error) bool {
+			return err == nil || errors.Is(err, ErrNoToolSelected) || errors.Is(err, ErrDirectResponse)
+		}
+	}
+	return success(kept.result, kept.err)
+}
+
+// benchmarkOutcomeKey identifies the outcome of a run for self-consistency
+// votes: the tools called and the final reply, or the error.
+func benchmarkOutcomeKey(result Fragment, err error) string {
+	if err != nil && !errors.Is(err, ErrNoToolSelected) && !errors.Is(err, ErrDirectResponse) {
+		return "error: " + err.Error()
+	}
+	s := summarizeRun(result)
+	return strings.Join(s.Tools, ",") + "\x00" + strings.ToLower(strings.TrimSpace(s.Outcome))
+}
+
+// String returns the report as a table, one row per model and strategy.
+func (r BenchmarkReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-20s %-22s %8s %9s %10s %10s\n", "Model", "Strategy", "Success", "LLM calls", "Tokens", "Latency")
+	for _, res := range r.Results {
+		fmt.Fprintf(&b, "%-20s %-22s %7.1f%% %9d %10d %10s\n",
+			res.Model, res.Strategy, res.SuccessRate*100, res.LLMCalls, res.Usage.TotalTokens, res.Latency.Round(time.Millisecond))
+	}
+	return b.String()
+}
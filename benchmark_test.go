@@ -0,0 +1,89 @@
+package cogito_test
+
+import (
+	"context"
+	"slices"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Benchmark", func() {
+	answer := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "It's sunny."}},
+		},
+	}
+
+	calledForecast := func(result Fragment, err error) bool {
+		return err == nil && result.Status != nil && slices.ContainsFunc(result.Status.ToolResults, func(s ToolStatus) bool {
+			return s.Name == "forecast"
+		})
+	}
+
+	It("reports success rate, LLM calls and tokens per strategy", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		// direct: the tool is called, then the reply
+		mockLLM.AddCreateChatCompletionFunction("forecast", `{"city": "Rome"}`)
+		mockLLM.SetCreateChatCompletionResponse(answer)
+		// majority of 3: two samples call the tool, one replies right away
+		mockLLM.AddCreateChatCompletionFunction("forecast", `{"city": "Rome"}`)
+		mockLLM.SetCreateChatCompletionResponse(answer)
+		mockLLM.SetCreateChatCompletionResponse(answer)
+		mockLLM.AddCreateChatCompletionFunction("forecast", `{"city": "Rome"}`)
+		mockLLM.SetCreateChatCompletionResponse(answer)
+		for range 7 {
+			mockLLM.CreateChatCompletionUsage = append(mockLLM.CreateChatCompletionUsage, LLMUsage{TotalTokens: 10})
+		}
+
+		tool := NewToolDefinition[WeatherArgs](forecastRunner{}, WeatherArgs{}, "forecast", "Get the weather forecast")
+		scenarios := []BenchmarkScenario{{
+			Name:     "weather",
+			Fragment: NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?"),
+			Options:  []Option{WithTools(tool), WithIterations(2)},
+			Success:  calledForecast,
+		}}
+		strategies := []BenchmarkStrategy{StrategyDirect, {Name: "majority", Samples: 3}}
+
+		report, err := RunBenchmark(context.Background(), []BenchmarkModel{{Name: "mock", LLM: mockLLM}}, scenarios, strategies)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Results).To(HaveLen(2))
+
+		direct := report.Results[0]
+		Expect(direct.Model).To(Equal("mock"))
+		Expect(direct.Strategy).To(Equal("direct"))
+		Expect(direct.SuccessRate).To(Equal(1.0))
+		Expect(direct.LLMCalls).To(Equal(2))
+		Expect(direct.Usage.TotalTokens).To(Equal(20))
+
+		majority := report.Results[1]
+		Expect(majority.Strategy).To(Equal("majority"))
+		Expect(majority.SuccessRate).To(Equal(1.0))
+		Expect(majority.LLMCalls).To(Equal(5))
+		Expect(majority.Usage.TotalTokens).To(Equal(50))
+
+		Expect(report.String()).To(ContainSubstring("majority"))
+	})
+
+	It("lists the scenarios a strategy did not solve", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.SetCreateChatCompletionResponse(answer)
+
+		scenarios := []BenchmarkScenario{{
+			Name:     "weather",
+			Fragment: NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?"),
+			Options: []Option{
+				WithTools(NewToolDefinition[WeatherArgs](forecastRunner{}, WeatherArgs{}, "forecast", "Get the weather forecast")),
+			},
+			Success: calledForecast,
+		}}
+
+		report, err := RunBenchmark(context.Background(), []BenchmarkModel{{Name: "mock", LLM: mockLLM}}, scenarios, []BenchmarkStrategy{StrategyDirect})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(report.Results[0].SuccessRate).To(Equal(0.0))
+		Expect(report.Results[0].Failed).To(ConsistOf("weather"))
+	})
+})
@@ -0,0 +1,190 @@
+package cogito
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Candidate is an answer generated by BestOf, with the score the judge gave it.
+type Candidate struct {
+	Content   string
+	Score     float64
+	Reasoning string
+	Winner    bool
+}
+
+type candidateScore struct {
+	Candidate int     `json:"candidate"`
+	Score     float64 `json:"score"`
+	Reasoning string  `json:"reasoning"`
+}
+
+type candidateScores struct {
+	Scores []candidateScore `json:"scores"`
+}
+
+type candidateJudgeToolRunner struct{}
+
+func (c *candidateJudgeToolRunner) Run(args candidateScores) (string, any, error) {
+	return "", nil, fmt.Errorf("candidate judge tool should not be executed")
+}
+
+func (c *candidateJudgeToolRunner) NewArgs() *candidateScores {
+	return &candidateScores{}
+}
+
+// candidateJudgeTool asks the LLM to score each of n candidates.
+func candidateJudgeTool(n int) *ToolDefinition[candidateScores] {
+	return &ToolDefinition[candidateScores]{
+		ToolRunner:  &candidateJudgeToolRunner{},
+		Name:        "score_candidates",
+		Description: "Score every candidate answer.",
+		InputArguments: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"scores": map[string]interface{}{
+					"type":        "array",
+					"description": "One score per candidate.",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"candidate": map[string]interface{}{
+								"type":        "integer",
+								"description": "The number of the candidate.",
+								"minimum":     1,
+								"maximum":     n,
+							},
+							"score": map[string]interface{}{
+								"type":        "number",
+								"description": "The score of the candidate, from 0 to 10.",
+								"minimum":     0,
+								"maximum":     10,
+							},
+							"reasoning": map[string]interface{}{
+								"type":        "string",
+								"description": "Why the candidate got this score.",
+							},
+						},
+						"required": []string{"candidate", "score"},
+					},
+				},
+			},
+			"required": []string{"scores"},
+		},
+	}
+}
+
+// BestOf generates n candidate replies to f, asks the LLM to score them
+// against judgePrompt (the default criteria are used when empty) and returns
+// f with the best scoring reply appended. The scored candidates are recorded
+// in Status.Candidates. Use WithCandidateLLMs to generate the candidates
+// with different models or temperatures.
+func BestOf(llm LLM, f Fragment, n int, judgePrompt string, opts ...Option) (Fragment, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+
+	if n < 1 {
+		return Fragment{}, fmt.Errorf("invalid number of candidates: %d", n)
+	}
+
+	generators := o.candidateLLMs
+	if len(generators) == 0 {
+		generators = []LLM{llm}
+	}
+	llm = withLLMOptions(llm, o)
+
+	var candidates []Candidate
+	var errs []error
+	for i := range n {
+		generator := withLLMOptions(generators[i%len(generators)], o)
+		reply, err := generator.Ask(o.context, f.Fork())
+		if err != nil {
+			o.logger.Warn("Failed to generate candidate", "candidate", i+1, "error", err)
+			errs = append(errs, fmt.Errorf("candidate %d: %w", i+1, err))
+			continue
+		}
+		candidates = append(candidates, Candidate{Content: reply.LastMessage().Content})
+		o.statusCallback(reply.LastMessage().Content)
+	}
+	if len(candidates) == 0 {
+		return Fragment{}, fmt.Errorf("failed to generate candidates: %w", errors.Join(errs...))
+	}
+
+	if len(candidates) > 1 {
+		if err := scoreCandidates(o, llm, f, candidates, judgePrompt); err != nil {
+			return Fragment{}, err
+		}
+	}
+
+	best := 0
+	for i, c := range candidates {
+		if c.Score > candidates[best].Score {
+			best = i
+		}
+	}
+	candidates[best].Winner = true
+	o.logger.Debug("Picked best candidate", "candidate", best+1, "score", candidates[best].Score)
+
+	result := f.AddMessage(AssistantMessageRole, candidates[best].Content)
+	// The status is shared with f, which is left untouched
+	if result.Status == nil {
+		result.Status = &Status{}
+	} else {
+		result.Status = result.Status.clone()
+	}
+	result.Status.Candidates = candidates
+	return result, nil
+}
+
+// scoreCandidates asks the LLM to score candidates, setting their Score and
+// Reasoning.
+func scoreCandidates(o *Options, llm LLM, f Fragment, candidates []Candidate, criteria string) error {
+	contents := make([]string, len(candidates))
+	for i, c := range candidates {
+		contents[i] = c.Content
+	}
+	judgePrompt, err := o.prompts.GetPrompt(prompt.PromptBestOfJudgeType).Render(struct {
+		Candidates []string
+		Criteria   string
+	}{
+		Candidates: contents,
+		Criteria:   criteria,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to render best-of judge prompt: %w", err)
+	}
+
+	judgeTool := candidateJudgeTool(len(candidates))
+	result, err := decisionWithStreaming(o.context, llm,
+		append(slices.Clone(f.Messages), openai.ChatCompletionMessage{
+			Role:    SystemMessageRole.String(),
+			Content: judgePrompt,
+		}),
+		Tools{judgeTool}, judgeTool.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
+	if err != nil {
+		return fmt.Errorf("failed to score candidates: %w", err)
+	}
+	if len(result.toolChoices) == 0 {
+		return errors.New("no candidate scores returned by the LLM")
+	}
+
+	var scores candidateScores
+	data, _ := json.Marshal(result.toolChoices[0].Arguments)
+	if err := json.Unmarshal(data, &scores); err != nil {
+		return fmt.Errorf("failed to parse candidate scores: %w", err)
+	}
+	for _, s := range scores.Scores {
+		if s.Candidate < 1 || s.Candidate > len(candidates) {
+			o.logger.Warn("Ignoring score of unknown candidate", "candidate", s.Candidate)
+			continue
+		}
+		candidates[s.Candidate-1].Score = s.Score
+		candidates[s.Candidate-1].Reasoning = s.Reasoning
+	}
+	return nil
+}
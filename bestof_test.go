@@ -0,0 +1,64 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("BestOf", func() {
+	var mockLLM *cogitotest.MockLLM
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+	})
+
+	It("returns the best scoring candidate", func() {
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Write a haiku about the sea")
+
+		mockLLM.SetAskResponse("The sea is blue.")
+		mockLLM.SetAskResponse("Waves fold into foam / the tide keeps its old promise / salt on the cold wind")
+		mockLLM.AddCreateChatCompletionFunction("score_candidates",
+			`{"scores": [{"candidate": 1, "score": 2, "reasoning": "not a haiku"}, {"candidate": 2, "score": 9, "reasoning": "vivid"}]}`)
+
+		result, err := BestOf(mockLLM, fragment, 2, "Is it a proper haiku?")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LastMessage().Content).To(HavePrefix("Waves fold into foam"))
+		Expect(result.Messages).To(HaveLen(2))
+		Expect(result.Status.Candidates).To(HaveLen(2))
+		Expect(result.Status.Candidates[0]).To(Equal(Candidate{Content: "The sea is blue.", Score: 2, Reasoning: "not a haiku"}))
+		Expect(result.Status.Candidates[1].Winner).To(BeTrue())
+
+		Expect(mockLLM.RequestHistory).To(HaveLen(1))
+		Expect(mockLLM.RequestHistory[0].Messages).To(ContainElement(
+			HaveField("Content", ContainSubstring("Is it a proper haiku?"))))
+	})
+
+	It("generates the candidates with the candidate LLMs", func() {
+		other := cogitotest.NewMockLLM()
+		other.SetAskResponse("From the other model.")
+		mockLLM.SetAskResponse("From the main model.")
+		mockLLM.AddCreateChatCompletionFunction("score_candidates",
+			`{"scores": [{"candidate": 1, "score": 4}, {"candidate": 2, "score": 7}]}`)
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Hi")
+		result, err := BestOf(mockLLM, fragment, 2, "", WithCandidateLLMs(mockLLM, other))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LastMessage().Content).To(Equal("From the other model."))
+	})
+
+	It("leaves the status of the fragment untouched", func() {
+		mockLLM.SetAskResponse("First.")
+		mockLLM.SetAskResponse("Second.")
+		mockLLM.AddCreateChatCompletionFunction("score_candidates",
+			`{"scores": [{"candidate": 1, "score": 4}, {"candidate": 2, "score": 7}]}`)
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Hi")
+		fragment.Status = &Status{}
+		result, err := BestOf(mockLLM, fragment, 2, "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Status.Candidates).To(HaveLen(2))
+		Expect(fragment.Status.Candidates).To(BeEmpty())
+	})
+})
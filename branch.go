@@ -0,0 +1,290 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Fork returns a copy of the fragment that shares no mutable state with f:
+// messages, their metadata, multimedia and status are copied, so the fork can be continued
+// (e.g. with ExecuteTools) without affecting f or other forks.
+func (f Fragment) Fork() Fragment {
+	fork := Fragment{
+		Messages:       slices.Clip(slices.Clone(f.Messages)),
+		ParentFragment: f.ParentFragment,
+		Multimedia:     slices.Clip(slices.Clone(f.Multimedia)),
+		Metadata:       slices.Clip(slices.Clone(f.Metadata)),
+	}
+	if f.Status != nil {
+		fork.Status = f.Status.clone()
+	}
+	return fork
+}
+
+// clone returns a copy of s whose slices and maps are not shared with s.
+func (s *Status) clone() *Status {
+	c := *s
+	c.ToolsCalled = slices.Clone(s.ToolsCalled)
+	c.ToolResults = slices.Clone(s.ToolResults)
+	c.Plans = slices.Clone(s.Plans)
+	c.PastActions = slices.Clone(s.PastActions)
+	c.ReasoningLog = slices.Clone(s.ReasoningLog)
+	c.InjectedMessages = slices.Clone(s.InjectedMessages)
+	c.Reflections = slices.Clone(s.Reflections)
+	c.ContextDegradations = slices.Clone(s.ContextDegradations)
+	c.Candidates = slices.Clone(s.Candidates)
+	c.ReviewScores = slices.Clone(s.ReviewScores)
+	c.UnsupportedClaims = slices.Clone(s.UnsupportedClaims)
+	c.Compensations = slices.Clone(s.Compensations)
+	c.Extensions = maps.Clone(s.Extensions)
+	if s.TODOs != nil {
+		todos := *s.TODOs
+		todos.TODOs = slices.Clone(s.TODOs.TODOs)
+		c.TODOs = &todos
+	}
+	return &c
+}
+
+// Merge returns f continued with what branch added to it: the messages and
+// multimedia of branch beyond those of f are appended, and the status of
+// branch, which already includes the one of f when branch was forked from
+// it, is adopted. f is left untouched.
+func (f Fragment) Merge(branch Fragment) Fragment {
+	merged := f.Fork()
+	if len(branch.Messages) > len(f.Messages) {
+		merged.Messages = append(merged.Messages, branch.Messages[len(f.Messages):]...)
+	}
+	for i := len(f.Messages); i < len(branch.Metadata); i++ {
+		merged = merged.SetMessageMetadata(i, branch.Metadata[i])
+	}
+	if len(branch.Multimedia) > len(f.Multimedia) {
+		merged.Multimedia = append(merged.Multimedia, branch.Multimedia[len(f.Multimedia):]...)
+	}
+	if branch.Status != nil {
+		merged.Status = branch.Status.clone()
+	}
+	return merged
+}
+
+// Branch is an alternative continuation of a conversation, e.g. running
+// ExecuteTools with a different set of tools or options.
+type Branch struct {
+	Name string
+	Run  func(ctx context.Context, f Fragment) (Fragment, error)
+}
+
+// ToolsBranch returns a Branch that continues the conversation with
+// ExecuteTools. Running out of tools to call (ErrNoToolSelected) is not
+// reported as a failure.
+func ToolsBranch(name string, llm LLM, opts ...Option) Branch {
+	return Branch{
+		Name: name,
+		Run: func(ctx context.Context, f Fragment) (Fragment, error) {
+			result, err := ExecuteTools(llm, f, append(slices.Clone(opts), WithContext(ctx))...)
+			if errors.Is(err, ErrNoToolSelected) {
+				err = nil
+			}
+			return result, err
+		},
+	}
+}
+
+// BranchResult is the outcome of running a Branch.
+type BranchResult struct {
+	Name     string
+	Fragment Fragment
+	Err      error
+}
+
+// RunBranches runs every branch concurrently, each on its own fork of f, and
+// returns their results in the order of branches.
+func RunBranches(ctx context.Context, f Fragment, branches ...Branch) []BranchResult {
+	results := make([]BranchResult, len(branches))
+	var wg sync.WaitGroup
+	for i, b := range branches {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fork := f.Fork()
+			result, err := b.Run(ctx, fork)
+			if err == nil && result.Messages == nil && result.Status == nil {
+				result = fork
+			}
+			results[i] = BranchResult{Name: b.Name, Fragment: result, Err: err}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// BranchVerdict is the branch picked by JudgeBranches.
+type BranchVerdict struct {
+	Best      int // index of the best branch in the judged results
+	Name      string
+	Reasoning string
+}
+
+type branchJudgeResponse struct {
+	Branch    string `json:"branch"`
+	Reasoning string `json:"reasoning"`
+}
+
+type branchJudgeToolRunner struct{}
+
+func (b *branchJudgeToolRunner) Run(args branchJudgeResponse) (string, any, error) {
+	return "", nil, fmt.Errorf("branch judge tool should not be executed")
+}
+
+func (b *branchJudgeToolRunner) NewArgs() *branchJudgeResponse {
+	return &branchJudgeResponse{}
+}
+
+// branchJudgeTool asks the LLM to pick one of the named branches.
+func branchJudgeTool(names []string) *ToolDefinition[branchJudgeResponse] {
+	return &ToolDefinition[branchJudgeResponse]{
+		ToolRunner:  &branchJudgeToolRunner{},
+		Name:        "pick_branch",
+		Description: "Pick the branch that best accomplishes what the user asked for.",
+		InputArguments: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"branch": map[string]interface{}{
+					"type":        "string",
+					"description": "The name of the best branch.",
+					"enum":        names,
+				},
+				"reasoning": map[string]interface{}{
+					"type":        "string",
+					"description": "Why this branch is better than the others.",
+				},
+			},
+			"required": []string{"branch"},
+		},
+	}
+}
+
+// JudgeBranches asks the LLM which of results best continues the
+// conversation f, comparing the tools each branch called and its final
+// reply. A single successful branch is picked without asking the LLM.
+func JudgeBranches(llm LLM, f Fragment, results []BranchResult, opts ...Option) (BranchVerdict, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	if len(results) == 0 {
+		return BranchVerdict{}, errors.New("no branches to judge")
+	}
+	var succeeded []int
+	for i, r := range results {
+		if r.Err == nil {
+			succeeded = append(succeeded, i)
+		}
+	}
+	if len(succeeded) == 1 {
+		i := succeeded[0]
+		return BranchVerdict{Best: i, Name: results[i].Name, Reasoning: "the only branch that succeeded"}, nil
+	}
+
+	type judgedBranch struct {
+		Name    string
+		Tools   []string
+		Outcome string
+		Error   string
+	}
+	var branches []judgedBranch
+	names := make([]string, 0, len(results))
+	for _, r := range results {
+		b := judgedBranch{Name: r.Name}
+		if r.Err != nil {
+			b.Error = r.Err.Error()
+		} else {
+			summary := summarizeRun(r.Fragment)
+			b.Tools = summary.Tools
+			b.Outcome = summary.Outcome
+			if b.Outcome == "" {
+				b.Outcome = lastToolResult(r.Fragment)
+			}
+		}
+		branches = append(branches, b)
+		names = append(names, r.Name)
+	}
+
+	judgePrompt, err := o.prompts.GetPrompt(prompt.PromptBranchJudgeType).Render(struct {
+		Branches []judgedBranch
+	}{Branches: branches})
+	if err != nil {
+		return BranchVerdict{}, fmt.Errorf("failed to render branch judge prompt: %w", err)
+	}
+
+	judgeTool := branchJudgeTool(names)
+	result, err := decisionWithStreaming(o.context, llm,
+		append(slices.Clone(f.Messages), openai.ChatCompletionMessage{
+			Role:    SystemMessageRole.String(),
+			Content: judgePrompt,
+		}),
+		Tools{judgeTool}, judgeTool.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
+	if err != nil {
+		return BranchVerdict{}, fmt.Errorf("failed to judge branches: %w", err)
+	}
+	if len(result.toolChoices) == 0 {
+		return BranchVerdict{}, errors.New("no branch picked by the LLM")
+	}
+
+	var verdict branchJudgeResponse
+	data, _ := json.Marshal(result.toolChoices[0].Arguments)
+	if err := json.Unmarshal(data, &verdict); err != nil {
+		return BranchVerdict{}, fmt.Errorf("failed to parse branch verdict: %w", err)
+	}
+	best := slices.Index(names, verdict.Branch)
+	if best < 0 {
+		return BranchVerdict{}, fmt.Errorf("the LLM picked unknown branch %q", verdict.Branch)
+	}
+	o.logger.Debug("Judged branches", "best", verdict.Branch, "reasoning", verdict.Reasoning)
+	return BranchVerdict{Best: best, Name: verdict.Branch, Reasoning: verdict.Reasoning}, nil
+}
+
+// lastToolResult returns the result of the last tool called in f, if any.
+func lastToolResult(f Fragment) string {
+	if f.Status == nil || len(f.Status.ToolResults) == 0 {
+		return ""
+	}
+	return f.Status.ToolResults[len(f.Status.ToolResults)-1].Result
+}
+
+// ExploreBranches runs branches on forks of f, lets the LLM judge which one
+// went best and merges it back into f. The verdict is returned along with
+// the merged fragment; an error is returned only if no branch succeeded or
+// the judge failed.
+func ExploreBranches(llm LLM, f Fragment, branches []Branch, opts ...Option) (Fragment, BranchVerdict, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+
+	if len(branches) == 0 {
+		return f, BranchVerdict{}, errors.New("no branches to explore")
+	}
+	results := RunBranches(o.context, f, branches...)
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			o.logger.Warn("Branch failed", "branch", r.Name, "error", r.Err)
+			errs = append(errs, fmt.Errorf("branch %q: %w", r.Name, r.Err))
+		}
+	}
+	if len(errs) == len(results) {
+		return f, BranchVerdict{}, fmt.Errorf("all branches failed: %w", errors.Join(errs...))
+	}
+
+	verdict, err := JudgeBranches(llm, f, results, opts...)
+	if err != nil {
+		return f, BranchVerdict{}, err
+	}
+	return f.Merge(results[verdict.Best].Fragment), verdict, nil
+}
@@ -0,0 +1,42 @@
+package cogito
+
+import (
+	"testing"
+
+	"github.com/mudler/cogito/structures"
+)
+
+func TestForkSharesNoState(t *testing.T) {
+	f := NewEmptyFragment().AddMessage(UserMessageRole, "hi")
+	f.Status.TODOs = &structures.TODOList{TODOs: []structures.TODO{{Description: "first"}}}
+	if err := SetExtension(f.Status, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	fork := f.Fork()
+	fork = fork.AddMessage(AssistantMessageRole, "hello")
+	fork.Status.ToolResults = append(fork.Status.ToolResults, ToolStatus{Name: "search"})
+	fork.Status.TODOs.TODOs[0].Completed = true
+	fork.Status.Extensions["other"] = []byte(`1`)
+
+	if len(f.Messages) != 1 || len(f.Status.ToolResults) != 0 || f.Status.TODOs.TODOs[0].Completed || len(f.Status.Extensions) != 1 {
+		t.Errorf("fork modified the original fragment: %+v", f.Status)
+	}
+}
+
+func TestMergeAppendsBranchMessages(t *testing.T) {
+	f := NewEmptyFragment().AddMessage(UserMessageRole, "hi")
+	branch := f.Fork().AddMessage(AssistantMessageRole, "hello")
+	branch.Status.Iterations = 2
+
+	merged := f.Merge(branch)
+	if len(merged.Messages) != 2 || merged.LastMessage().Content != "hello" || merged.Status.Iterations != 2 {
+		t.Errorf("merged = %+v", merged)
+	}
+	if merged.Status == branch.Status {
+		t.Error("merged fragment shares the status of the branch")
+	}
+	if len(f.Messages) != 1 || f.Status.Iterations != 0 {
+		t.Errorf("merge modified the original fragment: %+v", f)
+	}
+}
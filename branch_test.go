@@ -0,0 +1,81 @@
+package cogito_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Branches", func() {
+	var mockLLM *cogitotest.MockLLM
+
+	reply := func(content string) func(ctx context.Context, f Fragment) (Fragment, error) {
+		return func(ctx context.Context, f Fragment) (Fragment, error) {
+			f.Status.Iterations++
+			return f.AddMessage(AssistantMessageRole, content), nil
+		}
+	}
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+	})
+
+	It("merges the branch picked by the judge", func() {
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+
+		mockLLM.AddCreateChatCompletionFunction("pick_branch", `{"branch": "forecast", "reasoning": "it answers the question"}`)
+
+		result, verdict, err := ExploreBranches(mockLLM, fragment, []Branch{
+			{Name: "search", Run: reply("I could not find anything.")},
+			{Name: "forecast", Run: reply("It is sunny in Rome.")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verdict).To(Equal(BranchVerdict{Best: 1, Name: "forecast", Reasoning: "it answers the question"}))
+		Expect(result.Messages).To(HaveLen(2))
+		Expect(result.LastMessage().Content).To(Equal("It is sunny in Rome."))
+		Expect(result.Status.Iterations).To(Equal(1))
+
+		Expect(fragment.Messages).To(HaveLen(1))
+		Expect(fragment.Status.Iterations).To(BeZero())
+
+		Expect(mockLLM.RequestHistory).To(HaveLen(1))
+		Expect(mockLLM.RequestHistory[0].Messages).To(ContainElement(
+			HaveField("Content", ContainSubstring("It is sunny in Rome."))))
+	})
+
+	It("judges the branches through the redactor", func() {
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Who should I write to?")
+
+		mockLLM.AddCreateChatCompletionFunction("pick_branch", `{"branch": "directory", "reasoning": "it has an address"}`)
+
+		_, verdict, err := ExploreBranches(mockLLM, fragment, []Branch{
+			{Name: "search", Run: reply("I could not find anything.")},
+			{Name: "directory", Run: reply("Write to jane@example.com.")},
+		}, WithRedactor(NewRedactor()))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verdict.Name).To(Equal("directory"))
+
+		Expect(mockLLM.RequestHistory).To(HaveLen(1))
+		Expect(mockLLM.RequestHistory[0].Messages).ToNot(ContainElement(
+			HaveField("Content", ContainSubstring("jane@example.com"))))
+	})
+
+	It("picks the only branch that succeeded without asking the LLM", func() {
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+
+		result, verdict, err := ExploreBranches(mockLLM, fragment, []Branch{
+			{Name: "search", Run: func(ctx context.Context, f Fragment) (Fragment, error) {
+				return f, errors.New("backend unavailable")
+			}},
+			{Name: "forecast", Run: reply("It is sunny in Rome.")},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(verdict.Name).To(Equal("forecast"))
+		Expect(result.LastMessage().Content).To(Equal("It is sunny in Rome."))
+		Expect(mockLLM.RequestHistory).To(BeEmpty())
+	})
+})
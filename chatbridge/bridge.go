@@ -0,0 +1,348 @@
+// Package chatbridge binds cogito agents to chat platforms: messages received
+// from a channel are added to the conversation of the channel, the agent runs
+// on it with cogito.ExecuteTools, and its reply is posted back, updated as it
+// streams. Tool calls can require an approval, asked with Approve/Reject
+// buttons. Slack and Discord are supported through their HTTP APIs, see
+// NewSlack and NewDiscord; other platforms implement Platform.
+package chatbridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mudler/cogito"
+	"github.com/mudler/xlog"
+)
+
+// Message is a message received from a chat platform.
+type Message struct {
+	Channel string // the conversation of the message
+	User    string
+	Text    string
+}
+
+// Approval asks for the approval of a tool call, with Approve and Reject
+// buttons whose actions are passed to Bridge.Resolve with ID.
+type Approval struct {
+	ID        string
+	Tool      string
+	Arguments map[string]any
+	Text      string // the question, formatted for chat
+}
+
+// Platform posts messages to a chat platform.
+type Platform interface {
+	// PostMessage posts text to channel and returns the ID of the message.
+	PostMessage(ctx context.Context, channel, text string) (string, error)
+	// UpdateMessage replaces the text of the message id, removing its
+	// buttons if any.
+	UpdateMessage(ctx context.Context, channel, id, text string) error
+	// PostApproval posts the approval request a, with its buttons, to
+	// channel and returns the ID of the message.
+	PostApproval(ctx context.Context, channel string, a Approval) (string, error)
+}
+
+// Defaults of the Bridge settings.
+const (
+	DefaultUpdateInterval  = time.Second
+	DefaultApprovalTimeout = 10 * time.Minute
+)
+
+// Bridge runs an agent on the conversations of a chat platform.
+type Bridge struct {
+	platform        Platform
+	llm             cogito.LLM
+	opts            []cogito.Option
+	requireApproval func(*cogito.ToolChoice) bool
+	updateInterval  time.Duration
+	approvalTimeout time.Duration
+	logger          cogito.Logger
+
+	mu        sync.Mutex
+	sessions  map[string]*session
+	approvals map[string]*pendingApproval
+}
+
+// session is the conversation of a channel.
+type session struct {
+	mu       sync.Mutex // one run at a time
+	fragment cogito.Fragment
+}
+
+type pendingApproval struct {
+	channel, messageID string
+	approval           Approval
+	decision           chan bool
+}
+
+// New returns a bridge running cogito.ExecuteTools with llm and opts on the
+// conversations of platform.
+func New(platform Platform, llm cogito.LLM, opts ...cogito.Option) *Bridge {
+	return &Bridge{
+		platform:        platform,
+		llm:             llm,
+		opts:            opts,
+		updateInterval:  DefaultUpdateInterval,
+		approvalTimeout: DefaultApprovalTimeout,
+		sessions:        map[string]*session{},
+		approvals:       map[string]*pendingApproval{},
+	}
+}
+
+// SetApproval requires an approval in the channel for the tool calls
+// matching require, e.g. RequireApproval("delete_file").
+func (b *Bridge) SetApproval(require func(*cogito.ToolChoice) bool) {
+	b.requireApproval = require
+}
+
+// RequireApproval matches the calls of the given tools, or of every tool
+// when none is given.
+func RequireApproval(tools ...string) func(*cogito.ToolChoice) bool {
+	return func(tc *cogito.ToolChoice) bool {
+		if len(tools) == 0 {
+			return true
+		}
+		for _, t := range tools {
+			if t == tc.Name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SetUpdateInterval sets how often the reply is updated while it streams.
+// Defaults to DefaultUpdateInterval.
+func (b *Bridge) SetUpdateInterval(d time.Duration) {
+	b.updateInterval = d
+}
+
+// SetApprovalTimeout sets how long approvals are waited for before the tool
+// call is rejected. Defaults to DefaultApprovalTimeout.
+func (b *Bridge) SetApprovalTimeout(d time.Duration) {
+	b.approvalTimeout = d
+}
+
+// SetLogger sends the bridge's log output (failed runs and posts) to l
+// instead of the global xlog logger.
+func (b *Bridge) SetLogger(l cogito.Logger) {
+	b.logger = l
+}
+
+func (b *Bridge) logError(msg string, args ...any) {
+	if b.logger == nil {
+		xlog.Error(msg, args...)
+		return
+	}
+	b.logger.Error(msg, args...)
+}
+
+// Conversation returns the conversation of channel so far.
+func (b *Bridge) Conversation(channel string) cogito.Fragment {
+	s := b.session(channel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.fragment
+}
+
+// Reset forgets the conversation of channel.
+func (b *Bridge) Reset(channel string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.sessions, channel)
+}
+
+func (b *Bridge) session(channel string) *session {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, ok := b.sessions[channel]
+	if !ok {
+		s = &session{fragment: cogito.NewEmptyFragment()}
+		b.sessions[channel] = s
+	}
+	return s
+}
+
+// HandleMessage runs the agent on the conversation of the channel of m and
+// posts its reply. Messages of a channel are handled one at a time. It
+// returns when the reply is posted; platform handlers call it in the
+// background.
+func (b *Bridge) HandleMessage(ctx context.Context, m Message) error {
+	s := b.session(m.Channel)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replyID, err := b.platform.PostMessage(ctx, m.Channel, "_Thinking…_")
+	if err != nil {
+		return fmt.Errorf("failed to post reply: %w", err)
+	}
+	reply := &streamedReply{bridge: b, ctx: ctx, channel: m.Channel, id: replyID}
+
+	f := s.fragment.AddMessage(cogito.UserMessageRole, m.Text)
+	result, err := b.run(ctx, m.Channel, f, reply)
+	if err != nil {
+		b.logError("Chat bridge run failed", "channel", m.Channel, "error", err)
+		text := fmt.Sprintf("Sorry, something went wrong: %v", err)
+		if err := b.platform.UpdateMessage(ctx, m.Channel, replyID, text); err != nil {
+			b.logError("Failed to post reply", "channel", m.Channel, "error", err)
+		}
+		return err
+	}
+	s.fragment = result
+	if err := b.platform.UpdateMessage(ctx, m.Channel, replyID, result.LastMessage().Content); err != nil {
+		return fmt.Errorf("failed to post reply: %w", err)
+	}
+	return nil
+}
+
+// run runs the agent on f, see cogito.ExecuteToolsAndAnswer.
+func (b *Bridge) run(ctx context.Context, channel string, f cogito.Fragment, reply *streamedReply) (cogito.Fragment, error) {
+	opts := append([]cogito.Option{}, b.opts...)
+	opts = append(opts, cogito.WithStreamCallback(reply.event))
+	if b.requireApproval != nil {
+		opts = append(opts, cogito.WithToolCallBack(func(tc *cogito.ToolChoice, _ *cogito.SessionState) cogito.ToolCallDecision {
+			if !b.requireApproval(tc) {
+				return cogito.ToolCallDecision{Approved: true}
+			}
+			if !b.approve(ctx, channel, tc) {
+				// Rejected calls are skipped, so the agent can still answer
+				return cogito.ToolCallDecision{Approved: true, Skip: true}
+			}
+			return cogito.ToolCallDecision{Approved: true}
+		}))
+	}
+
+	return cogito.ExecuteToolsAndAnswer(ctx, b.llm, f, opts...)
+}
+
+// approve asks for the approval of tc in channel and waits for it.
+func (b *Bridge) approve(ctx context.Context, channel string, tc *cogito.ToolChoice) bool {
+	a := Approval{ID: uuid.NewString(), Tool: tc.Name, Arguments: tc.Arguments}
+	args, _ := json.MarshalIndent(tc.Arguments, "", "  ")
+	a.Text = fmt.Sprintf("The agent wants to run `%s` with:\n```\n%s\n```", tc.Name, args)
+	if tc.Reasoning != "" {
+		a.Text += "\nReason: " + tc.Reasoning
+	}
+
+	p := &pendingApproval{channel: channel, approval: a, decision: make(chan bool, 1)}
+	b.mu.Lock()
+	b.approvals[a.ID] = p
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.approvals, a.ID)
+		b.mu.Unlock()
+	}()
+
+	id, err := b.platform.PostApproval(ctx, channel, a)
+	if err != nil {
+		b.logError("Failed to post approval request", "channel", channel, "error", err)
+		return false
+	}
+	b.mu.Lock()
+	p.messageID = id
+	b.mu.Unlock()
+
+	timeout := time.NewTimer(b.approvalTimeout)
+	defer timeout.Stop()
+	select {
+	case approved := <-p.decision:
+		return approved
+	case <-timeout.C:
+		b.finishApproval(ctx, p, "Approval timed out, not running `"+a.Tool+"`.")
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Resolve answers the approval request id, from the action of one of its
+// buttons. It reports whether the request was pending.
+func (b *Bridge) Resolve(ctx context.Context, id string, approved bool) bool {
+	b.mu.Lock()
+	p, ok := b.approvals[id]
+	if ok {
+		delete(b.approvals, id)
+	}
+	b.mu.Unlock()
+	if !ok {
+		return false
+	}
+	if approved {
+		b.finishApproval(ctx, p, "Approved: running `"+p.approval.Tool+"`.")
+	} else {
+		b.finishApproval(ctx, p, "Rejected: not running `"+p.approval.Tool+"`.")
+	}
+	p.decision <- approved
+	return true
+}
+
+// finishApproval replaces the buttons of the approval request p with text.
+func (b *Bridge) finishApproval(ctx context.Context, p *pendingApproval, text string) {
+	b.mu.Lock()
+	id := p.messageID
+	b.mu.Unlock()
+	if id == "" {
+		return
+	}
+	if err := b.platform.UpdateMessage(ctx, p.channel, id, text); err != nil {
+		b.logError("Failed to update approval request", "channel", p.channel, "error", err)
+	}
+}
+
+// streamedReply updates the reply message as the answer streams, at most
+// every update interval.
+type streamedReply struct {
+	bridge      *Bridge
+	ctx         context.Context
+	channel, id string
+
+	mu         sync.Mutex
+	content    strings.Builder
+	lastUpdate time.Time
+}
+
+func (r *streamedReply) event(ev cogito.StreamEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var text string
+	switch ev.Type {
+	case cogito.StreamEventContent:
+		r.content.WriteString(ev.Content)
+		if time.Since(r.lastUpdate) < r.bridge.updateInterval {
+			return
+		}
+		text = r.content.String() + " …"
+	case cogito.StreamEventToolCall:
+		if ev.ToolName == "" || r.content.Len() > 0 {
+			return
+		}
+		text = fmt.Sprintf("_Running `%s`…_", ev.ToolName)
+	default:
+		return
+	}
+	r.lastUpdate = time.Now()
+	if err := r.bridge.platform.UpdateMessage(r.ctx, r.channel, r.id, text); err != nil {
+		r.bridge.logError("Failed to update reply", "channel", r.channel, "error", err)
+	}
+}
+
+// truncate shortens text to max bytes, for the message size limits of
+// platforms.
+func truncate(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max - len("…")
+	for cut > 0 && !utf8RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "…"
+}
+
+func utf8RuneStart(b byte) bool { return b&0xC0 != 0x80 }
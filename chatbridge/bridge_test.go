@@ -0,0 +1,147 @@
+package chatbridge_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/chatbridge"
+	"github.com/mudler/cogito/cogitotest"
+)
+
+// platform records the messages of the bridge.
+type platform struct {
+	mu        sync.Mutex
+	messages  map[string]string // ID -> text
+	approvals chan chatbridge.Approval
+}
+
+func newPlatform() *platform {
+	return &platform{messages: map[string]string{}, approvals: make(chan chatbridge.Approval, 1)}
+}
+
+func (p *platform) PostMessage(_ context.Context, _, text string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := fmt.Sprint(len(p.messages) + 1)
+	p.messages[id] = text
+	return id, nil
+}
+
+func (p *platform) UpdateMessage(_ context.Context, _, id, text string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages[id] = text
+	return nil
+}
+
+func (p *platform) PostApproval(ctx context.Context, channel string, a chatbridge.Approval) (string, error) {
+	id, err := p.PostMessage(ctx, channel, a.Text)
+	p.approvals <- a
+	return id, err
+}
+
+func (p *platform) message(id string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.messages[id]
+}
+
+func newBridge(t *testing.T) (*chatbridge.Bridge, *platform, *cogitotest.MockTool) {
+	t.Helper()
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("search", "Search for information")
+	cogitotest.SetRunResult(tool, "Rome is the capital of Italy")
+	llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+	llm.SetAskResponse("The capital of Italy is Rome.")
+
+	p := newPlatform()
+	return chatbridge.New(p, llm, cogito.WithTools(tool)), p, cogitotest.GetMockTool(tool)
+}
+
+func TestHandleMessage(t *testing.T) {
+	b, p, tool := newBridge(t)
+
+	if err := b.HandleMessage(context.Background(), chatbridge.Message{Channel: "C1", Text: "What is the capital of Italy?"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.message("1"); got != "The capital of Italy is Rome." {
+		t.Fatalf("reply = %q", got)
+	}
+	if len(tool.Calls()) != 1 {
+		t.Fatalf("the search tool did not run")
+	}
+	conv := b.Conversation("C1")
+	if len(conv.Messages) == 0 || conv.Messages[0].Content != "What is the capital of Italy?" {
+		t.Fatalf("conversation = %v", conv.Messages)
+	}
+
+	b.Reset("C1")
+	if len(b.Conversation("C1").Messages) != 0 {
+		t.Fatalf("the conversation was not reset")
+	}
+}
+
+func TestHandleMessageApproval(t *testing.T) {
+	for _, approved := range []bool{true, false} {
+		t.Run(fmt.Sprintf("approved=%v", approved), func(t *testing.T) {
+			b, p, tool := newBridge(t)
+			b.SetApproval(chatbridge.RequireApproval("search"))
+
+			done := make(chan error, 1)
+			go func() {
+				done <- b.HandleMessage(context.Background(), chatbridge.Message{Channel: "C1", Text: "What is the capital of Italy?"})
+			}()
+
+			var a chatbridge.Approval
+			select {
+			case a = <-p.approvals:
+			case <-time.After(5 * time.Second):
+				t.Fatal("no approval request")
+			}
+			if a.Tool != "search" || !strings.Contains(a.Text, "capital of Italy") {
+				t.Fatalf("approval = %+v", a)
+			}
+			if !b.Resolve(context.Background(), a.ID, approved) {
+				t.Fatal("the approval was not pending")
+			}
+			if b.Resolve(context.Background(), a.ID, approved) {
+				t.Fatal("the approval was resolved twice")
+			}
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+
+			if got := p.message("2"); !strings.HasPrefix(got, map[bool]string{true: "Approved", false: "Rejected"}[approved]) {
+				t.Fatalf("approval message = %q", got)
+			}
+			if calls := len(tool.Calls()); (calls == 1) != approved {
+				t.Fatalf("search ran %d times", calls)
+			}
+			if got := p.message("1"); got != "The capital of Italy is Rome." {
+				t.Fatalf("reply = %q", got)
+			}
+		})
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	b, p, tool := newBridge(t)
+	b.SetApproval(chatbridge.RequireApproval())
+	b.SetApprovalTimeout(10 * time.Millisecond)
+
+	if err := b.HandleMessage(context.Background(), chatbridge.Message{Channel: "C1", Text: "What is the capital of Italy?"}); err != nil {
+		t.Fatal(err)
+	}
+	<-p.approvals
+	if got := p.message("2"); !strings.HasPrefix(got, "Approval timed out") {
+		t.Fatalf("approval message = %q", got)
+	}
+	if len(tool.Calls()) != 0 {
+		t.Fatalf("search ran without approval")
+	}
+}
@@ -0,0 +1,213 @@
+package chatbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// discordMaxText is the message length limit of Discord.
+const discordMaxText = 2000
+
+// Discord is the Discord platform: it posts with the REST API and its
+// Handler receives the interactions of the application (slash commands and
+// button clicks) on its interactions endpoint URL.
+type Discord struct {
+	token     string
+	publicKey ed25519.PublicKey
+	// APIURL is the base URL of the REST API, "https://discord.com/api/v10"
+	// by default.
+	APIURL string
+	Client *http.Client
+}
+
+// NewDiscord returns the Discord platform of the application with the bot
+// token and the hex-encoded public key its interactions are signed with.
+func NewDiscord(token, publicKey string) (*Discord, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Discord public key")
+	}
+	return &Discord{
+		token:     token,
+		publicKey: key,
+		APIURL:    "https://discord.com/api/v10",
+		Client:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type discordComponent struct {
+	Type       int                `json:"type"`
+	Components []discordComponent `json:"components,omitempty"`
+	Style      int                `json:"style,omitempty"`
+	Label      string             `json:"label,omitempty"`
+	CustomID   string             `json:"custom_id,omitempty"`
+}
+
+// Discord component, button style and interaction types.
+const (
+	discordActionRow     = 1
+	discordButton        = 2
+	discordButtonSuccess = 3
+	discordButtonDanger  = 4
+
+	discordPing               = 1
+	discordApplicationCommand = 2
+	discordMessageComponent   = 3
+
+	discordPong                     = 1
+	discordChannelMessageWithSource = 4
+	discordDeferredUpdateMessage    = 6
+)
+
+const (
+	discordApprovePrefix = "approve:" // custom IDs of the buttons
+	discordRejectPrefix  = "reject:"
+	discordMessageOption = "message" // option of the slash commands
+)
+
+// PostMessage implements Platform.
+func (d *Discord) PostMessage(ctx context.Context, channel, text string) (string, error) {
+	return d.call(ctx, http.MethodPost, "/channels/"+channel+"/messages", map[string]any{"content": truncate(text, discordMaxText)})
+}
+
+// UpdateMessage implements Platform.
+func (d *Discord) UpdateMessage(ctx context.Context, channel, id, text string) error {
+	_, err := d.call(ctx, http.MethodPatch, "/channels/"+channel+"/messages/"+id, map[string]any{
+		"content":    truncate(text, discordMaxText),
+		"components": []discordComponent{}, // drops the buttons
+	})
+	return err
+}
+
+// PostApproval implements Platform.
+func (d *Discord) PostApproval(ctx context.Context, channel string, a Approval) (string, error) {
+	return d.call(ctx, http.MethodPost, "/channels/"+channel+"/messages", map[string]any{
+		"content": truncate(a.Text, discordMaxText),
+		"components": []discordComponent{{Type: discordActionRow, Components: []discordComponent{
+			{Type: discordButton, Style: discordButtonSuccess, Label: "Approve", CustomID: discordApprovePrefix + a.ID},
+			{Type: discordButton, Style: discordButtonDanger, Label: "Reject", CustomID: discordRejectPrefix + a.ID},
+		}}},
+	})
+}
+
+// call sends a REST API request and returns the ID of the message it
+// returns.
+func (d *Discord) call(ctx context.Context, method, path string, body any) (string, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.APIURL+path, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bot "+d.token)
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("discord %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("discord %s %s: status %d: %s", method, path, resp.StatusCode, msg)
+	}
+	var res struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("discord %s %s: %w", method, path, err)
+	}
+	return res.ID, nil
+}
+
+// Handler returns the handler of the interactions endpoint of the
+// application. Interactions are verified with the public key. Slash
+// commands with a "message" option are handled by b in the background, in
+// the channel of the command, and button clicks resolve approvals.
+func (d *Discord) Handler(b *Bridge) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		sig, err := hex.DecodeString(r.Header.Get("X-Signature-Ed25519"))
+		if err != nil || !ed25519.Verify(d.publicKey, append([]byte(r.Header.Get("X-Signature-Timestamp")), body...), sig) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var in struct {
+			Type      int    `json:"type"`
+			ChannelID string `json:"channel_id"`
+			Member    *struct {
+				User struct {
+					ID string `json:"id"`
+				} `json:"user"`
+			} `json:"member"`
+			User *struct {
+				ID string `json:"id"`
+			} `json:"user"`
+			Data struct {
+				CustomID string `json:"custom_id"`
+				Options  []struct {
+					Name  string `json:"name"`
+					Value any    `json:"value"`
+				} `json:"options"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(body, &in); err != nil {
+			http.Error(w, "invalid interaction", http.StatusBadRequest)
+			return
+		}
+
+		switch in.Type {
+		case discordPing:
+			writeDiscordResponse(w, map[string]any{"type": discordPong})
+		case discordApplicationCommand:
+			m := Message{Channel: in.ChannelID}
+			if in.Member != nil {
+				m.User = in.Member.User.ID
+			} else if in.User != nil {
+				m.User = in.User.ID
+			}
+			for _, o := range in.Data.Options {
+				if o.Name == discordMessageOption {
+					m.Text = fmt.Sprint(o.Value)
+				}
+			}
+			go func() {
+				_ = b.HandleMessage(context.Background(), m) // errors are logged and posted
+			}()
+			writeDiscordResponse(w, map[string]any{
+				"type": discordChannelMessageWithSource,
+				"data": map[string]any{"content": truncate("> "+m.Text, discordMaxText)},
+			})
+		case discordMessageComponent:
+			switch id := in.Data.CustomID; {
+			case strings.HasPrefix(id, discordApprovePrefix):
+				b.Resolve(context.Background(), strings.TrimPrefix(id, discordApprovePrefix), true)
+			case strings.HasPrefix(id, discordRejectPrefix):
+				b.Resolve(context.Background(), strings.TrimPrefix(id, discordRejectPrefix), false)
+			}
+			// The approval message is updated by the bridge
+			writeDiscordResponse(w, map[string]any{"type": discordDeferredUpdateMessage})
+		default:
+			http.Error(w, "unsupported interaction", http.StatusBadRequest)
+		}
+	})
+}
+
+func writeDiscordResponse(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
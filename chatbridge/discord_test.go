@@ -0,0 +1,100 @@
+package chatbridge_test
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/chatbridge"
+	"github.com/mudler/cogito/cogitotest"
+)
+
+func newDiscord(t *testing.T) (*httptest.Server, ed25519.PrivateKey, *apiCalls) {
+	t.Helper()
+	calls := &apiCalls{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bot bot-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		calls.add(r)
+		_, _ = io.WriteString(w, `{"id": "M1"}`)
+	}))
+	t.Cleanup(api.Close)
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	discord, err := chatbridge.NewDiscord("bot-token", hex.EncodeToString(pub))
+	if err != nil {
+		t.Fatal(err)
+	}
+	discord.APIURL = api.URL
+
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("search", "Search for information")
+	llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+	llm.SetAskResponse("The capital of Italy is Rome.")
+	b := chatbridge.New(discord, llm, cogito.WithTools(tool))
+	b.SetApproval(chatbridge.RequireApproval("search"))
+	ts := httptest.NewServer(discord.Handler(b))
+	t.Cleanup(ts.Close)
+	return ts, priv, calls
+}
+
+func postDiscord(t *testing.T, url string, key ed25519.PrivateKey, body string) map[string]any {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("X-Signature-Timestamp", "1700000000")
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(key, []byte("1700000000"+body))))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var res map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestDiscord(t *testing.T) {
+	ts, key, calls := newDiscord(t)
+
+	if res := postDiscord(t, ts.URL, key, `{"type": 1}`); res["type"] != float64(1) {
+		t.Fatalf("ping response = %v", res)
+	}
+	_, otherKey, _ := ed25519.GenerateKey(nil)
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(`{"type": 1}`))
+	req.Header.Set("X-Signature-Timestamp", "1700000000")
+	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(ed25519.Sign(otherKey, []byte(`1700000000{"type": 1}`))))
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("a wrong signature was accepted: %v", err)
+	}
+
+	command := `{"type": 2, "channel_id": "C1", "member": {"user": {"id": "U1"}}, "data": {"name": "ask", "options": [{"name": "message", "value": "What is the capital of Italy?"}]}}`
+	if res := postDiscord(t, ts.URL, key, command); res["type"] != float64(4) {
+		t.Fatalf("command response = %v", res)
+	}
+
+	approval := calls.wait(t, "POST /channels/C1/messages", `"custom_id":"approve:`)
+	id := approval[strings.Index(approval, `"custom_id":"approve:`)+len(`"custom_id":"approve:`):]
+	id = id[:strings.Index(id, `"`)]
+	if res := postDiscord(t, ts.URL, key, `{"type": 3, "channel_id": "C1", "data": {"custom_id": "reject:`+id+`"}}`); res["type"] != float64(6) {
+		t.Fatalf("button response = %v", res)
+	}
+
+	calls.wait(t, "PATCH /channels/C1/messages/M1", "Rejected: not running `search`")
+	calls.wait(t, "PATCH /channels/C1/messages/M1", "The capital of Italy is Rome.")
+}
@@ -0,0 +1,222 @@
+package chatbridge
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// slackMaxText is the length messages are truncated to, under the limit of
+// section blocks.
+const slackMaxText = 3000
+
+// Slack is the Slack platform: it posts with the Web API and its Handler
+// receives the Events API and interactivity requests of the Slack app.
+type Slack struct {
+	token, signingSecret string
+	// APIURL is the base URL of the Web API, "https://slack.com/api" by
+	// default.
+	APIURL string
+	Client *http.Client
+}
+
+// NewSlack returns the Slack platform of the app with the bot token
+// ("xoxb-...") and the signing secret of its requests.
+func NewSlack(token, signingSecret string) *Slack {
+	return &Slack{
+		token:         token,
+		signingSecret: signingSecret,
+		APIURL:        "https://slack.com/api",
+		Client:        &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type slackBlock struct {
+	Type     string       `json:"type"`
+	Text     *slackText   `json:"text,omitempty"`
+	Elements []slackBlock `json:"elements,omitempty"`
+	ActionID string       `json:"action_id,omitempty"`
+	Value    string       `json:"value,omitempty"`
+	Style    string       `json:"style,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// PostMessage implements Platform.
+func (s *Slack) PostMessage(ctx context.Context, channel, text string) (string, error) {
+	return s.call(ctx, "chat.postMessage", map[string]any{"channel": channel, "text": truncate(text, slackMaxText)})
+}
+
+// UpdateMessage implements Platform.
+func (s *Slack) UpdateMessage(ctx context.Context, channel, id, text string) error {
+	_, err := s.call(ctx, "chat.update", map[string]any{
+		"channel": channel,
+		"ts":      id,
+		"text":    truncate(text, slackMaxText),
+		"blocks":  []slackBlock{}, // drops the buttons
+	})
+	return err
+}
+
+// PostApproval implements Platform.
+func (s *Slack) PostApproval(ctx context.Context, channel string, a Approval) (string, error) {
+	text := truncate(a.Text, slackMaxText)
+	return s.call(ctx, "chat.postMessage", map[string]any{
+		"channel": channel,
+		"text":    text,
+		"blocks": []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}},
+			{Type: "actions", Elements: []slackBlock{
+				{Type: "button", Text: &slackText{Type: "plain_text", Text: "Approve"}, ActionID: "approve", Value: a.ID, Style: "primary"},
+				{Type: "button", Text: &slackText{Type: "plain_text", Text: "Reject"}, ActionID: "reject", Value: a.ID, Style: "danger"},
+			}},
+		},
+	})
+}
+
+// call calls the Web API method and returns the ts of the posted message.
+func (s *Slack) call(ctx context.Context, method string, body any) (string, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.APIURL+"/"+method, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("slack %s: %w", method, err)
+	}
+	defer resp.Body.Close()
+	var res struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+		TS    string `json:"ts"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return "", fmt.Errorf("slack %s: status %d: %w", method, resp.StatusCode, err)
+	}
+	if !res.OK {
+		return "", fmt.Errorf("slack %s: %s", method, res.Error)
+	}
+	return res.TS, nil
+}
+
+// slackMention matches the mentions of the app starting app_mention texts.
+var slackMention = regexp.MustCompile(`^\s*<@[A-Z0-9]+>\s*`)
+
+// Handler returns the handler of the Events API (message and app_mention
+// events) and interactivity (button actions) requests of the app, which can
+// share one request URL. Requests are verified with the signing secret and
+// messages are handled by b in the background.
+func (s *Slack) Handler(b *Bridge) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "failed to read request", http.StatusBadRequest)
+			return
+		}
+		if !s.verify(r.Header, body) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+			s.interaction(w, r, b, body)
+			return
+		}
+
+		var ev struct {
+			Type      string `json:"type"`
+			Challenge string `json:"challenge"`
+			Event     struct {
+				Type    string `json:"type"`
+				Subtype string `json:"subtype"`
+				BotID   string `json:"bot_id"`
+				Channel string `json:"channel"`
+				User    string `json:"user"`
+				Text    string `json:"text"`
+			} `json:"event"`
+		}
+		if err := json.Unmarshal(body, &ev); err != nil {
+			http.Error(w, "invalid event", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case ev.Type == "url_verification":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = io.WriteString(w, ev.Challenge)
+			return
+		case r.Header.Get("X-Slack-Retry-Num") != "":
+			// Already handled: Slack retries events not acknowledged in 3s
+		case ev.Type == "event_callback" && (ev.Event.Type == "message" || ev.Event.Type == "app_mention") &&
+			ev.Event.Subtype == "" && ev.Event.BotID == "":
+			m := Message{Channel: ev.Event.Channel, User: ev.Event.User, Text: slackMention.ReplaceAllString(ev.Event.Text, "")}
+			go func() {
+				_ = b.HandleMessage(context.Background(), m) // errors are logged and posted
+			}()
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// interaction resolves the approvals of button actions.
+func (s *Slack) interaction(w http.ResponseWriter, r *http.Request, b *Bridge, body []byte) {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+	var payload struct {
+		Type    string `json:"type"`
+		Actions []struct {
+			ActionID string `json:"action_id"`
+			Value    string `json:"value"`
+		} `json:"actions"`
+	}
+	if err := json.Unmarshal([]byte(r.PostForm.Get("payload")), &payload); err != nil {
+		http.Error(w, "invalid interaction", http.StatusBadRequest)
+		return
+	}
+	if payload.Type == "block_actions" {
+		for _, a := range payload.Actions {
+			if a.ActionID == "approve" || a.ActionID == "reject" {
+				b.Resolve(context.Background(), a.Value, a.ActionID == "approve")
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the v0 signature of a request, which must be at most five
+// minutes old.
+func (s *Slack) verify(h http.Header, body []byte) bool {
+	ts := h.Get("X-Slack-Request-Timestamp")
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || time.Since(time.Unix(sec, 0)).Abs() > 5*time.Minute {
+		return false
+	}
+	return hmac.Equal([]byte(h.Get("X-Slack-Signature")), []byte(slackSignature(s.signingSecret, ts, body)))
+}
+
+func slackSignature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", ts)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
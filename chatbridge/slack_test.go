@@ -0,0 +1,142 @@
+package chatbridge_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/chatbridge"
+	"github.com/mudler/cogito/cogitotest"
+)
+
+// apiCalls records the requests to a fake platform API.
+type apiCalls struct {
+	mu    sync.Mutex
+	calls []string // "path body"
+}
+
+func (a *apiCalls) add(r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, r.Method+" "+r.URL.Path+" "+string(body))
+}
+
+// wait waits for a call containing all of parts.
+func (a *apiCalls) wait(t *testing.T, parts ...string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		a.mu.Lock()
+		for _, c := range a.calls {
+			found := true
+			for _, p := range parts {
+				found = found && strings.Contains(c, p)
+			}
+			if found {
+				a.mu.Unlock()
+				return c
+			}
+		}
+		a.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("no call with %q in %v", parts, a.calls)
+	return ""
+}
+
+func newSlack(t *testing.T) (*httptest.Server, *apiCalls) {
+	t.Helper()
+	calls := &apiCalls{}
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xoxb-token" {
+			_, _ = io.WriteString(w, `{"ok": false, "error": "invalid_auth"}`)
+			return
+		}
+		calls.add(r)
+		_, _ = io.WriteString(w, `{"ok": true, "ts": "1700000000.000100"}`)
+	}))
+	t.Cleanup(api.Close)
+
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("search", "Search for information")
+	llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+	llm.SetAskResponse("The capital of Italy is Rome.")
+
+	slack := chatbridge.NewSlack("xoxb-token", "signing-secret")
+	slack.APIURL = api.URL
+	b := chatbridge.New(slack, llm, cogito.WithTools(tool))
+	b.SetApproval(chatbridge.RequireApproval("search"))
+	ts := httptest.NewServer(slack.Handler(b))
+	t.Cleanup(ts.Close)
+	return ts, calls
+}
+
+func postSlack(t *testing.T, url, contentType, body, secret string) *http.Response {
+	t.Helper()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + ts + ":" + body))
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestSlack(t *testing.T) {
+	ts, calls := newSlack(t)
+
+	resp := postSlack(t, ts.URL, "application/json", `{"type": "url_verification", "challenge": "abc"}`, "signing-secret")
+	if body, _ := io.ReadAll(resp.Body); string(body) != "abc" {
+		t.Fatalf("challenge = %q", body)
+	}
+	if resp := postSlack(t, ts.URL, "application/json", `{"type": "url_verification", "challenge": "abc"}`, "wrong"); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status with a wrong signature = %d", resp.StatusCode)
+	}
+
+	event := `{"type": "event_callback", "event": {"type": "app_mention", "channel": "C1", "user": "U1", "text": "<@U0BOT> What is the capital of Italy?"}}`
+	if resp := postSlack(t, ts.URL, "application/json", event, "signing-secret"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	// The approval is asked with buttons, then approved with one
+	approval := calls.wait(t, "/chat.postMessage", `"action_id":"approve"`)
+	var msg struct {
+		Blocks []struct {
+			Elements []struct {
+				Value string `json:"value"`
+			} `json:"elements"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal([]byte(approval[strings.Index(approval, "{"):]), &msg); err != nil {
+		t.Fatal(err)
+	}
+	payload, _ := json.Marshal(map[string]any{
+		"type":    "block_actions",
+		"actions": []map[string]string{{"action_id": "approve", "value": msg.Blocks[1].Elements[0].Value}},
+	})
+	form := url.Values{"payload": {string(payload)}}.Encode()
+	if resp := postSlack(t, ts.URL, "application/x-www-form-urlencoded", form, "signing-secret"); resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+
+	calls.wait(t, "/chat.update", "Approved: running `search`")
+	calls.wait(t, "/chat.update", "The capital of Italy is Rome.")
+}
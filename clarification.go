@@ -0,0 +1,157 @@
+package cogito
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ClarificationNeeded is returned by ExecuteTools, with
+// WithArgumentClarification, when required arguments of a selected tool
+// can't be derived from the conversation. The tool is not run: ask the user
+// Question and continue with Resume once they answer.
+type ClarificationNeeded struct {
+	Tool          string   `json:"tool"`
+	MissingFields []string `json:"missing_fields"`
+	Question      string   `json:"question"`
+	// Fragment is the conversation so far, ending with Question as the
+	// assistant message.
+	Fragment Fragment `json:"fragment"`
+}
+
+func (c *ClarificationNeeded) Error() string {
+	return fmt.Sprintf("clarification needed for tool %q (missing %s): %s", c.Tool, strings.Join(c.MissingFields, ", "), c.Question)
+}
+
+// Answer returns the conversation with the user's answer appended.
+func (c *ClarificationNeeded) Answer(answer string) Fragment {
+	return c.Fragment.AddMessage(UserMessageRole, answer)
+}
+
+// Resume continues the run with the user's answer to Question.
+func (c *ClarificationNeeded) Resume(llm LLM, answer string, opts ...Option) (Fragment, error) {
+	return ExecuteTools(llm, c.Answer(answer), opts...)
+}
+
+// argumentCheckResponse is used to extract the result of the argument check tool
+type argumentCheckResponse struct {
+	Missing  []string `json:"missing"`
+	Question string   `json:"question"`
+}
+
+type argumentCheckToolRunner struct{}
+
+func (a *argumentCheckToolRunner) Run(args argumentCheckResponse) (string, any, error) {
+	return "", nil, fmt.Errorf("argument check tool should not be executed")
+}
+
+func (a *argumentCheckToolRunner) NewArgs() *argumentCheckResponse {
+	return &argumentCheckResponse{}
+}
+
+// argumentCheckTool asks the LLM which of the required parameters were guessed
+func argumentCheckTool(required []string) *ToolDefinition[argumentCheckResponse] {
+	return &ToolDefinition[argumentCheckResponse]{
+		ToolRunner:  &argumentCheckToolRunner{},
+		Name:        "check_arguments",
+		Description: "Report the required parameters whose values are not stated in, nor can be confidently derived from, the conversation.",
+		InputArguments: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"missing": map[string]interface{}{
+					"type":        "array",
+					"description": "The required parameters that were guessed. Empty if all of them are grounded in the conversation.",
+					"items": map[string]interface{}{
+						"type": "string",
+						"enum": required,
+					},
+				},
+				"question": map[string]interface{}{
+					"type":        "string",
+					"description": "A short question to ask the user to obtain the missing values. Empty if nothing is missing.",
+				},
+			},
+			"required": []string{"missing"},
+		},
+	}
+}
+
+// requiredParameters returns the names of the required parameters of tool.
+func requiredParameters(tool ToolDefinitionInterface) []string {
+	toolFunc := tool.Tool().Function
+	if toolFunc == nil || toolFunc.Parameters == nil {
+		return nil
+	}
+	var schema struct {
+		Required []string `json:"required"`
+	}
+	data, err := json.Marshal(toolFunc.Parameters)
+	if err != nil || json.Unmarshal(data, &schema) != nil {
+		return nil
+	}
+	return schema.Required
+}
+
+// checkArguments returns a ClarificationNeeded if required arguments of
+// choice are missing or, according to the LLM, were guessed rather than
+// derived from the conversation. Failures of the check are logged and the
+// arguments are accepted.
+func checkArguments(o *Options, llm LLM, messages []openai.ChatCompletionMessage, tool ToolDefinitionInterface, choice *ToolChoice) *ClarificationNeeded {
+	required := requiredParameters(tool)
+	if len(required) == 0 {
+		return nil
+	}
+	missing := missingArguments(tool, choice.Arguments)
+
+	checkTool := argumentCheckTool(required)
+	checkPrompt, err := o.prompts.GetPrompt(prompt.PromptArgumentClarificationType).Render(struct {
+		Tool      string
+		Arguments string
+		Required  []string
+	}{
+		Tool:      choice.Name,
+		Arguments: string(mustMarshal(choice.Arguments)),
+		Required:  required,
+	})
+	if err != nil {
+		o.logger.Warn("Failed to render argument clarification prompt", "error", err)
+		return nil
+	}
+
+	var question string
+	result, err := decisionWithStreaming(o.context, llm,
+		append(slices.Clone(messages), openai.ChatCompletionMessage{
+			Role:    SystemMessageRole.String(),
+			Content: checkPrompt,
+		}),
+		Tools{checkTool}, checkTool.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
+	switch {
+	case err != nil:
+		o.logger.Warn("Failed to check tool arguments", "tool", choice.Name, "error", err)
+	case len(result.toolChoices) > 0:
+		var check argumentCheckResponse
+		data, _ := json.Marshal(result.toolChoices[0].Arguments)
+		if err := json.Unmarshal(data, &check); err != nil {
+			o.logger.Warn("Failed to parse tool arguments check", "tool", choice.Name, "error", err)
+			break
+		}
+		for _, field := range check.Missing {
+			if slices.Contains(required, field) && !slices.Contains(missing, field) {
+				missing = append(missing, field)
+			}
+		}
+		question = check.Question
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+	if question == "" {
+		question = fmt.Sprintf("Could you provide the %s to use?", strings.Join(missing, " and "))
+	}
+	return &ClarificationNeeded{Tool: choice.Name, MissingFields: missing, Question: question}
+}
@@ -0,0 +1,134 @@
+package clients
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/mudler/cogito"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ cogito.Transcriber = (*OpenAITranscriber)(nil)
+var _ cogito.Speaker = (*OpenAISpeaker)(nil)
+
+// OpenAITranscriber transcribes audio with an OpenAI-compatible
+// /audio/transcriptions endpoint (OpenAI Whisper, LocalAI, ...).
+type OpenAITranscriber struct {
+	model    string
+	language string
+	client   *openai.Client
+}
+
+// OpenAITranscriberOptions carries optional transcription settings.
+type OpenAITranscriberOptions struct {
+	// Language is the ISO-639-1 language of the audio, e.g. "en". Empty
+	// lets the model detect it.
+	Language string
+	// HTTPMiddleware wraps the HTTP transport of the client, first one
+	// outermost.
+	HTTPMiddleware []Middleware
+}
+
+func NewOpenAITranscriber(model, apiKey, baseURL string) *OpenAITranscriber {
+	return NewOpenAITranscriberWithOptions(model, apiKey, baseURL, OpenAITranscriberOptions{})
+}
+
+func NewOpenAITranscriberWithOptions(model, apiKey, baseURL string, opts OpenAITranscriberOptions) *OpenAITranscriber {
+	return &OpenAITranscriber{
+		model:    model,
+		language: opts.Language,
+		client:   openaiClient(apiKey, baseURL, nil, opts.HTTPMiddleware...),
+	}
+}
+
+// Transcribe implements cogito.Transcriber.
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	resp, err := t.client.CreateTranscription(ctx, openai.AudioRequest{
+		Model: t.model,
+		// The endpoint detects the encoding from the file name
+		FilePath: "audio." + audioFormat(mimeType),
+		Reader:   bytes.NewReader(audio),
+		Language: t.language,
+		Format:   openai.AudioResponseFormatJSON,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// OpenAISpeaker synthesizes speech with an OpenAI-compatible /audio/speech
+// endpoint (OpenAI TTS, LocalAI, ...).
+type OpenAISpeaker struct {
+	model  string
+	voice  string
+	format string
+	speed  float64
+	client *openai.Client
+}
+
+// OpenAISpeakerOptions carries optional speech settings.
+type OpenAISpeakerOptions struct {
+	// Format is the audio encoding, e.g. "mp3" (the default), "wav" or
+	// "opus".
+	Format string
+	// Speed of the speech, from 0.25 to 4. Zero keeps the default (1).
+	Speed float64
+	// HTTPMiddleware wraps the HTTP transport of the client, first one
+	// outermost.
+	HTTPMiddleware []Middleware
+}
+
+func NewOpenAISpeaker(model, voice, apiKey, baseURL string) *OpenAISpeaker {
+	return NewOpenAISpeakerWithOptions(model, voice, apiKey, baseURL, OpenAISpeakerOptions{})
+}
+
+func NewOpenAISpeakerWithOptions(model, voice, apiKey, baseURL string, opts OpenAISpeakerOptions) *OpenAISpeaker {
+	format := opts.Format
+	if format == "" {
+		format = "mp3"
+	}
+	return &OpenAISpeaker{
+		model:  model,
+		voice:  voice,
+		format: format,
+		speed:  opts.Speed,
+		client: openaiClient(apiKey, baseURL, nil, opts.HTTPMiddleware...),
+	}
+}
+
+// Speak implements cogito.Speaker.
+func (s *OpenAISpeaker) Speak(ctx context.Context, text string) ([]byte, string, error) {
+	resp, err := s.client.CreateSpeech(ctx, openai.CreateSpeechRequest{
+		Model:          openai.SpeechModel(s.model),
+		Input:          text,
+		Voice:          openai.SpeechVoice(s.voice),
+		ResponseFormat: openai.SpeechResponseFormat(s.format),
+		Speed:          s.speed,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Close()
+
+	audio, err := io.ReadAll(resp)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read speech: %w", err)
+	}
+	return audio, speechMIMEType(s.format), nil
+}
+
+// speechMIMEType maps a speech response format to its MIME type.
+func speechMIMEType(format string) string {
+	switch format {
+	case "mp3":
+		return "audio/mpeg"
+	case "pcm":
+		return "audio/pcm"
+	case "opus":
+		return "audio/ogg"
+	}
+	return "audio/" + format
+}
@@ -0,0 +1,65 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAITranscriberSendsAudio(t *testing.T) {
+	var gotModel, gotFilename, gotAudio, gotLanguage string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Fatalf("FormFile: %v", err)
+		}
+		data, _ := io.ReadAll(file)
+		gotModel, gotFilename, gotAudio, gotLanguage = r.FormValue("model"), header.Filename, string(data), r.FormValue("language")
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"text": "log a 12 euro lunch"}`))
+	}))
+	defer srv.Close()
+
+	transcriber := NewOpenAITranscriberWithOptions("whisper-1", "k", srv.URL+"/v1", OpenAITranscriberOptions{Language: "en"})
+	text, err := transcriber.Transcribe(context.Background(), []byte("RIFF...."), "audio/wav")
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+	if text != "log a 12 euro lunch" {
+		t.Errorf("text = %q", text)
+	}
+	if gotModel != "whisper-1" || gotFilename != "audio.wav" || gotAudio != "RIFF...." || gotLanguage != "en" {
+		t.Errorf("request = model %q, file %q, audio %q, language %q", gotModel, gotFilename, gotAudio, gotLanguage)
+	}
+}
+
+func TestOpenAISpeakerReturnsAudio(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/speech" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "audio/wav")
+		_, _ = w.Write([]byte("RIFF-speech"))
+	}))
+	defer srv.Close()
+
+	speaker := NewOpenAISpeakerWithOptions("tts-1", "alloy", "k", srv.URL+"/v1", OpenAISpeakerOptions{Format: "wav"})
+	audio, mimeType, err := speaker.Speak(context.Background(), "Logged.")
+	if err != nil {
+		t.Fatalf("Speak: %v", err)
+	}
+	if string(audio) != "RIFF-speech" || mimeType != "audio/wav" {
+		t.Errorf("audio = %q, %q", audio, mimeType)
+	}
+	if got["model"] != "tts-1" || got["voice"] != "alloy" || got["input"] != "Logged." || got["response_format"] != "wav" {
+		t.Errorf("request = %v", got)
+	}
+}
@@ -0,0 +1,53 @@
+package clients
+
+import (
+	"github.com/sashabaranov/go-openai"
+)
+
+// DefaultAzureAPIVersion is the api-version of Azure OpenAI requests when
+// AzureOpenAIOptions.APIVersion is empty.
+const DefaultAzureAPIVersion = "2024-10-21"
+
+// AzureOpenAIOptions carries the settings of an Azure OpenAI client.
+type AzureOpenAIOptions struct {
+	OpenAIOptions
+	// APIVersion is the api-version query parameter of requests. Empty uses
+	// DefaultAzureAPIVersion.
+	APIVersion string
+	// Deployments maps models to the names of the deployments serving them.
+	// Models not listed are used as deployment names.
+	Deployments map[string]string
+	// EntraID authenticates with a Microsoft Entra ID access token, passed
+	// as apiKey, instead of a resource key.
+	EntraID bool
+}
+
+// NewAzureOpenAILLM returns a client of a deployment of an Azure OpenAI
+// resource. endpoint is the URL of the resource, e.g.
+// "https://my-resource.openai.azure.com", and apiKey one of its keys.
+func NewAzureOpenAILLM(deployment, apiKey, endpoint string) *OpenAIClient {
+	return NewAzureOpenAILLMWithOptions(deployment, apiKey, endpoint, AzureOpenAIOptions{})
+}
+
+// NewAzureOpenAILLMWithOptions returns a client of model on an Azure OpenAI
+// resource, see NewAzureOpenAILLM. Requests are sent to the deployment of
+// model in opts.Deployments, or to the deployment named model.
+func NewAzureOpenAILLMWithOptions(model, apiKey, endpoint string, opts AzureOpenAIOptions) *OpenAIClient {
+	config := openai.DefaultAzureConfig(apiKey, endpoint)
+	if opts.EntraID {
+		config.APIType = openai.APITypeAzureAD
+	}
+	config.APIVersion = opts.APIVersion
+	if config.APIVersion == "" {
+		config.APIVersion = DefaultAzureAPIVersion
+	}
+	// The default mapper strips dots and colons, which deployment names
+	// can contain
+	config.AzureModelMapperFunc = func(model string) string {
+		if deployment, ok := opts.Deployments[model]; ok {
+			return deployment
+		}
+		return model
+	}
+	return newOpenAIClient(model, config, opts.OpenAIOptions)
+}
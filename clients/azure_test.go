@@ -0,0 +1,56 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestAzureOpenAIRequests(t *testing.T) {
+	var gotPath, gotVersion, gotKey string
+	srv := chatServer(t, func(r *http.Request) {
+		gotPath = r.URL.Path
+		gotVersion = r.URL.Query().Get("api-version")
+		gotKey = r.Header.Get("api-key")
+	})
+
+	llm := NewAzureOpenAILLMWithOptions("gpt-4.1", "secret", srv.URL, AzureOpenAIOptions{
+		Deployments: map[string]string{"gpt-4.1": "prod-gpt-4.1"},
+	})
+	_, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if gotPath != "/openai/deployments/prod-gpt-4.1/chat/completions" {
+		t.Fatalf("path = %s", gotPath)
+	}
+	if gotVersion != DefaultAzureAPIVersion {
+		t.Fatalf("api-version = %q, want %q", gotVersion, DefaultAzureAPIVersion)
+	}
+	if gotKey != "secret" {
+		t.Fatalf("api-key = %q, want secret", gotKey)
+	}
+}
+
+func TestAzureOpenAIEntraID(t *testing.T) {
+	var gotAuth, gotKey string
+	srv := chatServer(t, func(r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotKey = r.Header.Get("api-key")
+	})
+
+	llm := NewAzureOpenAILLMWithOptions("gpt-4o", "token", srv.URL, AzureOpenAIOptions{EntraID: true})
+	_, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if gotAuth != "Bearer token" || gotKey != "" {
+		t.Fatalf("Authorization = %q, api-key = %q, want a bearer token only", gotAuth, gotKey)
+	}
+}
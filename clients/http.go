@@ -0,0 +1,94 @@
+package clients
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// HTTPOptions tunes the HTTP client of a client: timeouts, proxy, TLS and
+// connection pool. Zero values keep the defaults of http.DefaultTransport.
+type HTTPOptions struct {
+	// Timeout bounds whole requests, streamed responses included: prefer
+	// ResponseHeaderTimeout for streaming.
+	Timeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for the response headers once
+	// the request is sent.
+	ResponseHeaderTimeout time.Duration
+	// Proxy selects the proxy of each request, e.g. http.ProxyURL(u). Nil
+	// uses the HTTP_PROXY and HTTPS_PROXY environment variables.
+	Proxy func(*http.Request) (*url.URL, error)
+	// TLSConfig configures TLS connections, e.g. with custom root CAs or
+	// client certificates.
+	TLSConfig *tls.Config
+	// MaxIdleConns bounds the idle (keep-alive) connections across hosts,
+	// MaxIdleConnsPerHost per host. Raise the latter for many concurrent
+	// requests to one endpoint: Go keeps 2 per host by default.
+	MaxIdleConns        int
+	MaxIdleConnsPerHost int
+	// MaxConnsPerHost bounds the connections per host, idle or not; requests
+	// beyond it wait for one. Zero means no limit.
+	MaxConnsPerHost int
+	// IdleConnTimeout is how long an idle connection is kept.
+	IdleConnTimeout   time.Duration
+	DisableKeepAlives bool
+	// Transport replaces the transport built from the options above, e.g.
+	// an instrumented one. Timeout still applies.
+	Transport http.RoundTripper
+}
+
+// NewHTTPClient returns an HTTP client configured by opts. Pass it as the
+// HTTPClient of the clients of an agent fleet to share its connection pool:
+//
+//	httpClient := clients.NewHTTPClient(clients.HTTPOptions{MaxIdleConnsPerHost: 64})
+//	llm := clients.NewOpenAILLMWithOptions(model, key, url, clients.OpenAIOptions{HTTPClient: httpClient})
+func NewHTTPClient(opts HTTPOptions) *http.Client {
+	return &http.Client{Transport: opts.transport(), Timeout: opts.Timeout}
+}
+
+func (opts HTTPOptions) transport() http.RoundTripper {
+	if opts.Transport != nil {
+		return opts.Transport
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	if opts.Proxy != nil {
+		t.Proxy = opts.Proxy
+	}
+	if opts.TLSConfig != nil {
+		t.TLSClientConfig = opts.TLSConfig
+	}
+	if opts.ResponseHeaderTimeout > 0 {
+		t.ResponseHeaderTimeout = opts.ResponseHeaderTimeout
+	}
+	if opts.MaxIdleConns > 0 {
+		t.MaxIdleConns = opts.MaxIdleConns
+	}
+	if opts.MaxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = opts.MaxIdleConnsPerHost
+	}
+	if opts.MaxConnsPerHost > 0 {
+		t.MaxConnsPerHost = opts.MaxConnsPerHost
+	}
+	if opts.IdleConnTimeout > 0 {
+		t.IdleConnTimeout = opts.IdleConnTimeout
+	}
+	t.DisableKeepAlives = opts.DisableKeepAlives
+	return t
+}
+
+// wrapHTTPClient returns a copy of client, or of a default client when it
+// is nil, with its transport wrapped by wrap. client is not modified, so
+// that clients sharing it keep sharing its connections.
+func wrapHTTPClient(client *http.Client, wrap func(http.RoundTripper) http.RoundTripper) *http.Client {
+	wrapped := &http.Client{}
+	base := http.DefaultTransport
+	if client != nil {
+		*wrapped = *client
+		if client.Transport != nil {
+			base = client.Transport
+		}
+	}
+	wrapped.Transport = wrap(base)
+	return wrapped
+}
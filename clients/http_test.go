@@ -0,0 +1,69 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestNewHTTPClientTunesTransport(t *testing.T) {
+	client := NewHTTPClient(HTTPOptions{
+		Timeout:               time.Minute,
+		ResponseHeaderTimeout: 10 * time.Second,
+		MaxIdleConnsPerHost:   64,
+		MaxConnsPerHost:       128,
+	})
+	if client.Timeout != time.Minute {
+		t.Fatalf("Timeout = %v, want 1m", client.Timeout)
+	}
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport = %T, want *http.Transport", client.Transport)
+	}
+	if transport.MaxIdleConnsPerHost != 64 || transport.MaxConnsPerHost != 128 {
+		t.Fatalf("pool = %d idle/%d max per host, want 64/128", transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+	}
+	if transport.ResponseHeaderTimeout != 10*time.Second {
+		t.Fatalf("ResponseHeaderTimeout = %v, want 10s", transport.ResponseHeaderTimeout)
+	}
+	if transport == http.DefaultTransport {
+		t.Fatalf("the default transport must not be modified")
+	}
+}
+
+// TestOpenAIClientsShareHTTPClient verifies clients built with one
+// HTTPClient send their requests through its transport, without modifying
+// it.
+func TestOpenAIClientsShareHTTPClient(t *testing.T) {
+	srv := chatServer(t, func(*http.Request) {})
+
+	var requests atomic.Int32
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	shared := &http.Client{Transport: transport}
+
+	for _, model := range []string{"a", "b"} {
+		llm := NewOpenAILLMWithOptions(model, "k", srv.URL+"/v1", OpenAIOptions{
+			HTTPClient:     shared,
+			HTTPMiddleware: []Middleware{HeaderMiddleware(map[string]string{"X-Model": model})},
+		})
+		_, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+			Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+		})
+		if err != nil {
+			t.Fatalf("CreateChatCompletion: %v", err)
+		}
+	}
+	if got := requests.Load(); got != 2 {
+		t.Fatalf("shared transport saw %d requests, want 2", got)
+	}
+	if _, ok := shared.Transport.(RoundTripperFunc); !ok {
+		t.Fatalf("the shared client was modified: Transport = %T", shared.Transport)
+	}
+}
@@ -27,6 +27,7 @@ type LocalAIClient struct {
 	apiKey   string
 	grammar  string
 	metadata map[string]string
+	effort   string
 	client   *http.Client
 }
 
@@ -63,6 +64,19 @@ func (llm *LocalAIClient) SetMetadata(metadata map[string]string) {
 	llm.metadata = copy
 }
 
+// SetReasoningEffort sets the "reasoning_effort" field of every request
+// (e.g. "none"/"low"/"medium"/"high"), see OpenAIOptions.ReasoningEffort.
+// Empty leaves the field unset.
+func (llm *LocalAIClient) SetReasoningEffort(effort string) {
+	llm.effort = effort
+}
+
+// SetHTTPMiddleware wraps the HTTP transport of the client with
+// middlewares, first one outermost. Calling it again replaces them.
+func (llm *LocalAIClient) SetHTTPMiddleware(middlewares ...Middleware) {
+	llm.client = &http.Client{Transport: chainMiddleware(http.DefaultTransport, middlewares...)}
+}
+
 // localAIExtendedRequest wraps the OpenAI request with LocalAI's optional
 // top-level extension fields (grammar, metadata).
 type localAIExtendedRequest struct {
@@ -74,14 +88,22 @@ type localAIExtendedRequest struct {
 // marshalRequest serializes a chat completion request, embedding any
 // LocalAI-specific extension fields (grammar, metadata) when set.
 func (llm *LocalAIClient) marshalRequest(request openai.ChatCompletionRequest) ([]byte, error) {
+	var body []byte
+	var err error
 	if llm.grammar == "" && len(llm.metadata) == 0 {
-		return json.Marshal(request)
+		body, err = json.Marshal(request)
+	} else {
+		body, err = json.Marshal(localAIExtendedRequest{
+			ChatCompletionRequest: request,
+			Grammar:               llm.grammar,
+			Metadata:              llm.metadata,
+		})
 	}
-	return json.Marshal(localAIExtendedRequest{
-		ChatCompletionRequest: request,
-		Grammar:               llm.grammar,
-		Metadata:              llm.metadata,
-	})
+	if err != nil {
+		return nil, err
+	}
+	// LocalAI takes audio and video (remote or data URLs) as audio_url/video_url parts.
+	return EncodeMultimediaParts(body, false)
 }
 
 // localAICompletionMessage extends the OpenAI message with LocalAI's "reasoning" field.
@@ -130,6 +152,9 @@ func (m *localAICompletionMessage) UnmarshalJSON(data []byte) error {
 // including LocalAI's optional "reasoning" field, into LLMReply.ReasoningContent.
 func (llm *LocalAIClient) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (cogito.LLMReply, cogito.LLMUsage, error) {
 	request.Model = llm.model
+	if llm.effort != "" {
+		request.ReasoningEffort = llm.effort
+	}
 
 	body, err := llm.marshalRequest(request)
 	if err != nil {
@@ -253,6 +278,9 @@ type localAIStreamChunk struct {
 // CreateChatCompletionStream streams chat completion events via a channel using SSE.
 func (llm *LocalAIClient) CreateChatCompletionStream(ctx context.Context, request openai.ChatCompletionRequest) (<-chan cogito.StreamEvent, error) {
 	request.Model = llm.model
+	if llm.effort != "" {
+		request.ReasoningEffort = llm.effort
+	}
 	request.Stream = true
 
 	body, err := llm.marshalRequest(request)
@@ -364,6 +392,9 @@ func (llm *LocalAIClient) Ask(ctx context.Context, f cogito.Fragment) (cogito.Fr
 		Model:    llm.model,
 		Messages: messages,
 	}
+	if seed, ok := cogito.SeedFromContext(ctx); ok {
+		request.Seed = &seed
+	}
 	reply, usage, err := llm.CreateChatCompletion(ctx, request)
 	if err != nil {
 		return cogito.Fragment{}, err
@@ -0,0 +1,95 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mudler/cogito"
+	"github.com/sashabaranov/go-openai"
+)
+
+func chatServer(t *testing.T, check func(r *http.Request)) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChainMiddlewareOrder(t *testing.T) {
+	var order []string
+	mw := func(name string) Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name)
+				return next.RoundTrip(req)
+			})
+		}
+	}
+	base := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		order = append(order, "base")
+		return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "http://example.com", nil)
+	if _, err := chainMiddleware(base, mw("a"), nil, mw("b")).RoundTrip(req); err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	if got := strings.Join(order, ","); got != "a,b,base" {
+		t.Fatalf("order = %s, want a,b,base", got)
+	}
+}
+
+func TestOpenAIClientHTTPMiddleware(t *testing.T) {
+	var gotHeader string
+	var gotBody bool
+	srv := chatServer(t, func(r *http.Request) {
+		gotHeader = r.Header.Get("X-Gateway-Key")
+	})
+
+	llm := NewOpenAILLMWithOptions("m", "k", srv.URL+"/v1", OpenAIOptions{
+		HTTPMiddleware: []Middleware{
+			HeaderMiddleware(map[string]string{"X-Gateway-Key": "secret"}),
+			func(next http.RoundTripper) http.RoundTripper {
+				return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					gotBody = req.Body != nil
+					return next.RoundTrip(req)
+				})
+			},
+		},
+	})
+	_, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if gotHeader != "secret" {
+		t.Fatalf("X-Gateway-Key = %q, want secret", gotHeader)
+	}
+	if !gotBody {
+		t.Fatalf("middleware did not see the request body")
+	}
+}
+
+func TestLocalAIClientHTTPMiddleware(t *testing.T) {
+	var gotHeader string
+	srv := chatServer(t, func(r *http.Request) {
+		gotHeader = r.Header.Get("X-Gateway-Key")
+	})
+
+	llm := NewLocalAILLM("m", "k", srv.URL)
+	llm.SetHTTPMiddleware(HeaderMiddleware(map[string]string{"X-Gateway-Key": "secret"}))
+	if _, err := llm.Ask(context.Background(), cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "hi")); err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if gotHeader != "secret" {
+		t.Fatalf("X-Gateway-Key = %q, want secret", gotHeader)
+	}
+}
@@ -0,0 +1,131 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mudler/cogito"
+)
+
+// EncodeMultimediaParts rewrites the audio and video message parts of a
+// serialized chat completion request into their wire format. cogito carries
+// their URL in the image_url field (go-openai has no audio/video parts); this
+// moves it to "audio_url"/"video_url". With inputAudio set, base64 audio is
+// sent as an OpenAI "input_audio" part instead. Bodies without such parts are
+// returned unchanged.
+func EncodeMultimediaParts(body []byte, inputAudio bool) ([]byte, error) {
+	if !bytes.Contains(body, []byte(cogito.ChatMessagePartTypeAudioURL)) &&
+		!bytes.Contains(body, []byte(cogito.ChatMessagePartTypeVideoURL)) {
+		return body, nil
+	}
+
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(req["messages"], &messages); err != nil {
+		return nil, err
+	}
+
+	for _, msg := range messages {
+		content := bytes.TrimSpace(msg["content"])
+		if len(content) == 0 || content[0] != '[' {
+			continue
+		}
+		var parts []map[string]any
+		if err := json.Unmarshal(content, &parts); err != nil {
+			return nil, err
+		}
+		for _, part := range parts {
+			encodeMultimediaPart(part, inputAudio)
+		}
+		encoded, err := json.Marshal(parts)
+		if err != nil {
+			return nil, err
+		}
+		msg["content"] = encoded
+	}
+
+	encoded, err := json.Marshal(messages)
+	if err != nil {
+		return nil, err
+	}
+	req["messages"] = encoded
+	return json.Marshal(req)
+}
+
+func encodeMultimediaPart(part map[string]any, inputAudio bool) {
+	partType, _ := part["type"].(string)
+	if partType != string(cogito.ChatMessagePartTypeAudioURL) && partType != string(cogito.ChatMessagePartTypeVideoURL) {
+		return
+	}
+	carrier, _ := part["image_url"].(map[string]any)
+	url, _ := carrier["url"].(string)
+	delete(part, "image_url")
+
+	if partType == string(cogito.ChatMessagePartTypeAudioURL) && inputAudio {
+		if mimeType, data, ok := splitDataURL(url); ok {
+			part["type"] = "input_audio"
+			part["input_audio"] = map[string]any{"data": data, "format": audioFormat(mimeType)}
+			return
+		}
+	}
+	part[partType] = map[string]any{"url": url}
+}
+
+// splitDataURL splits a base64 data URL into its MIME type and payload.
+func splitDataURL(url string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	mimeType, data, ok := strings.Cut(rest, ";base64,")
+	return mimeType, data, ok
+}
+
+// audioFormat maps an audio MIME type to the OpenAI input_audio format name.
+func audioFormat(mimeType string) string {
+	switch mimeType {
+	case "audio/mpeg", "audio/mp3":
+		return "mp3"
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return "wav"
+	}
+	_, sub, _ := strings.Cut(mimeType, "/")
+	return sub
+}
+
+// multimediaTransport applies EncodeMultimediaParts to outgoing request
+// bodies, for clients whose serialization is owned by go-openai.
+type multimediaTransport struct {
+	base       http.RoundTripper
+	inputAudio bool
+}
+
+func (t *multimediaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil || req.Method != http.MethodPost {
+		return t.base.RoundTrip(req)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := EncodeMultimediaParts(body, t.inputAudio)
+	if err != nil {
+		// Not a chat request we understand: send it as is.
+		encoded = body
+	}
+
+	out := req.Clone(req.Context())
+	out.Body = io.NopCloser(bytes.NewReader(encoded))
+	out.ContentLength = int64(len(encoded))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(encoded)), nil
+	}
+	return t.base.RoundTrip(out)
+}
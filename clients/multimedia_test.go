@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mudler/cogito"
+)
+
+func requestParts(t *testing.T, body []byte) []map[string]any {
+	t.Helper()
+	var req struct {
+		Messages []struct {
+			Content []map[string]any `json:"content"`
+		} `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("unmarshal request: %v (%s)", err, body)
+	}
+	return req.Messages[0].Content
+}
+
+func multimediaFragment() cogito.Fragment {
+	return cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "Describe these",
+		cogito.NewAudioData([]byte("RIFF"), "audio/wav"),
+		cogito.NewVideoURL("https://example.com/clip.mp4"),
+	)
+}
+
+func TestEncodeMultimediaPartsLocalAI(t *testing.T) {
+	body, _ := json.Marshal(map[string]any{"model": "m", "messages": multimediaFragment().Messages})
+	encoded, err := EncodeMultimediaParts(body, false)
+	if err != nil {
+		t.Fatalf("EncodeMultimediaParts: %v", err)
+	}
+	parts := requestParts(t, encoded)
+
+	audio := parts[1]
+	if audio["type"] != "audio_url" || audio["image_url"] != nil {
+		t.Fatalf("audio part = %v", audio)
+	}
+	if url := audio["audio_url"].(map[string]any)["url"]; url != "data:audio/wav;base64,UklGRg==" {
+		t.Errorf("audio url = %v", url)
+	}
+	video := parts[2]
+	if video["type"] != "video_url" || video["video_url"].(map[string]any)["url"] != "https://example.com/clip.mp4" {
+		t.Errorf("video part = %v", video)
+	}
+}
+
+func TestOpenAIClientSendsInputAudio(t *testing.T) {
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	llm := NewOpenAILLM("m", "k", srv.URL+"/v1")
+	if _, err := llm.Ask(context.Background(), multimediaFragment()); err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	parts := requestParts(t, body)
+
+	audio := parts[1]
+	if audio["type"] != "input_audio" {
+		t.Fatalf("audio part = %v", audio)
+	}
+	input := audio["input_audio"].(map[string]any)
+	if input["data"] != "UklGRg==" || input["format"] != "wav" {
+		t.Errorf("input_audio = %v", input)
+	}
+	if parts[2]["type"] != "video_url" {
+		t.Errorf("video part = %v", parts[2])
+	}
+}
+
+func TestEncodeMultimediaPartsLeavesOtherBodiesAlone(t *testing.T) {
+	body := []byte(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`)
+	encoded, err := EncodeMultimediaParts(body, true)
+	if err != nil || string(encoded) != string(body) {
+		t.Errorf("body changed: %s (%v)", encoded, err)
+	}
+}
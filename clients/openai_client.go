@@ -3,7 +3,9 @@ package clients
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"net/http"
 
 	"github.com/mudler/cogito"
 	"github.com/sashabaranov/go-openai"
@@ -11,6 +13,8 @@ import (
 
 var _ cogito.LLM = (*OpenAIClient)(nil)
 var _ cogito.StreamingLLM = (*OpenAIClient)(nil)
+var _ cogito.ExtractionConfigProvider = (*OpenAIClient)(nil)
+var _ cogito.Embedder = (*OpenAIClient)(nil)
 
 type OpenAIClient struct {
 	model           string
@@ -18,6 +22,8 @@ type OpenAIClient struct {
 	temperature     float32
 	metadata        map[string]string
 	reasoningEffort string
+	extraction      *cogito.ExtractionConfig
+	embeddingModel  string
 }
 
 // OpenAIOptions carries optional per-client settings.
@@ -33,6 +39,24 @@ type OpenAIOptions struct {
 	// model's chat template has no enable_thinking toggle (e.g. LFM2.5), so it's
 	// the reliable way to disable thinking. Empty leaves the field unset.
 	ReasoningEffort string
+	// Extraction overrides the pseudo-tool used by Fragment.ExtractStructure
+	// (tool name, strict flag, schema wrapping) for servers that reject the
+	// defaults. Nil keeps cogito.DefaultExtractionConfig().
+	Extraction *cogito.ExtractionConfig
+	// HTTPMiddleware wraps the HTTP transport of the client, first one
+	// outermost. Middlewares see requests as sent on the wire.
+	HTTPMiddleware []Middleware
+	// EmbeddingModel is the model used by Embeddings (e.g.
+	// "text-embedding-3-small"). Empty uses the chat model, as LocalAI
+	// allows for models serving both.
+	EmbeddingModel string
+	// HTTP tunes the HTTP client: timeouts, proxy, TLS and connection pool.
+	// Nil keeps the defaults.
+	HTTP *HTTPOptions
+	// HTTPClient is the HTTP client of the client, taking precedence over
+	// HTTP. Share one (see NewHTTPClient) across clients to reuse its
+	// connections. It is not modified: HTTPMiddleware wraps a copy.
+	HTTPClient *http.Client
 }
 
 func NewOpenAILLM(model, apiKey, baseURL string) *OpenAIClient {
@@ -40,7 +64,20 @@ func NewOpenAILLM(model, apiKey, baseURL string) *OpenAIClient {
 }
 
 func NewOpenAILLMWithOptions(model, apiKey, baseURL string, opts OpenAIOptions) *OpenAIClient {
-	client := openaiClient(apiKey, baseURL)
+	config := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		config.BaseURL = baseURL
+	}
+	return newOpenAIClient(model, config, opts)
+}
+
+// newOpenAIClient returns a client of model for the endpoint of config.
+func newOpenAIClient(model string, config openai.ClientConfig, opts OpenAIOptions) *OpenAIClient {
+	httpClient := opts.HTTPClient
+	if httpClient == nil && opts.HTTP != nil {
+		httpClient = NewHTTPClient(*opts.HTTP)
+	}
+	client := openaiClientWithConfig(config, httpClient, opts.HTTPMiddleware...)
 
 	return &OpenAIClient{
 		model:           model,
@@ -48,9 +85,19 @@ func NewOpenAILLMWithOptions(model, apiKey, baseURL string, opts OpenAIOptions)
 		temperature:     opts.Temperature,
 		metadata:        opts.Metadata,
 		reasoningEffort: opts.ReasoningEffort,
+		extraction:      opts.Extraction,
+		embeddingModel:  opts.EmbeddingModel,
 	}
 }
 
+// ExtractionConfig implements cogito.ExtractionConfigProvider.
+func (llm *OpenAIClient) ExtractionConfig() cogito.ExtractionConfig {
+	if llm.extraction == nil {
+		return cogito.DefaultExtractionConfig()
+	}
+	return *llm.extraction
+}
+
 // Ask prompts to the LLM with the provided messages
 // and returns a Fragment containing the response.
 // The Fragment.GetMessages() method automatically handles force-text-reply
@@ -74,6 +121,9 @@ func (llm *OpenAIClient) Ask(ctx context.Context, f cogito.Fragment) (cogito.Fra
 	if llm.reasoningEffort != "" {
 		req.ReasoningEffort = llm.reasoningEffort
 	}
+	if seed, ok := cogito.SeedFromContext(ctx); ok {
+		req.Seed = &seed
+	}
 
 	resp, err := llm.client.CreateChatCompletion(ctx, req)
 
@@ -199,11 +249,52 @@ func (llm *OpenAIClient) CreateChatCompletionStream(ctx context.Context, request
 }
 
 // NewOpenAIService creates a new OpenAI service instance
-func openaiClient(apiKey string, baseURL string) *openai.Client {
+func openaiClient(apiKey string, baseURL string, httpClient *http.Client, middlewares ...Middleware) *openai.Client {
 	config := openai.DefaultConfig(apiKey)
 	if baseURL != "" {
 		config.BaseURL = baseURL
 	}
+	return openaiClientWithConfig(config, httpClient, middlewares...)
+}
+
+func openaiClientWithConfig(config openai.ClientConfig, httpClient *http.Client, middlewares ...Middleware) *openai.Client {
+	// OpenAI only accepts inline audio, as "input_audio" parts.
+	config.HTTPClient = wrapHTTPClient(httpClient, func(base http.RoundTripper) http.RoundTripper {
+		return &multimediaTransport{
+			base:       chainMiddleware(base, middlewares...),
+			inputAudio: true,
+		}
+	})
 
 	return openai.NewClientWithConfig(config)
 }
+
+// Embeddings implements cogito.Embedder with the /embeddings endpoint.
+func (llm *OpenAIClient) Embeddings(ctx context.Context, texts []string) ([][]float32, error) {
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	model := llm.embeddingModel
+	if model == "" {
+		model = llm.model
+	}
+	resp, err := llm.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: texts,
+		Model: openai.EmbeddingModel(model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("expected %d embeddings, got %d", len(texts), len(resp.Data))
+	}
+
+	embeddings := make([][]float32, len(texts))
+	for _, e := range resp.Data {
+		if e.Index < 0 || e.Index >= len(texts) {
+			return nil, fmt.Errorf("embedding index %d out of range", e.Index)
+		}
+		embeddings[e.Index] = e.Embedding
+	}
+	return embeddings, nil
+}
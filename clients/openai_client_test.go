@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/mudler/cogito"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -128,3 +129,59 @@ func TestNewOpenAILLMDefaultsTemperatureZeroMeansUnset(t *testing.T) {
 		t.Fatalf("expected default temperature 0 (unset), got %v", llm.temperature)
 	}
 }
+
+func TestEmbeddingsUsesEmbeddingModelAndKeepsOrder(t *testing.T) {
+	var got struct {
+		Input []string `json:"input"`
+		Model string   `json:"model"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/embeddings" {
+			t.Errorf("path = %s", r.URL.Path)
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		// Out of order on purpose
+		_, _ = w.Write([]byte(`{"data":[{"index":1,"embedding":[0,1]},{"index":0,"embedding":[1,0]}]}`))
+	}))
+	defer srv.Close()
+
+	llm := NewOpenAILLMWithOptions("chat", "k", srv.URL+"/v1", OpenAIOptions{EmbeddingModel: "embed"})
+	embeddings, err := llm.Embeddings(context.Background(), []string{"cat", "dog"})
+	if err != nil {
+		t.Fatalf("Embeddings: %v", err)
+	}
+	if got.Model != "embed" || len(got.Input) != 2 || got.Input[0] != "cat" {
+		t.Errorf("request = %+v", got)
+	}
+	if len(embeddings) != 2 || embeddings[0][0] != 1 || embeddings[1][1] != 1 {
+		t.Errorf("embeddings = %v", embeddings)
+	}
+}
+
+// TestAskSendsSeed verifies the seed of a seeded run reaches the requests
+// built by Ask.
+func TestAskSendsSeed(t *testing.T) {
+	var seeds []*int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		seeds = append(seeds, req.Seed)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"index":0,"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	// Gap analysis asks first, then extracts the gaps
+	llm := NewOpenAILLM("m", "k", srv.URL+"/v1")
+	f := cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "hi")
+	_, _ = cogito.ExtractKnowledgeGaps(llm, f, cogito.WithSeed(42))
+	if len(seeds) == 0 {
+		t.Fatal("no request sent")
+	}
+	for _, seed := range seeds {
+		if seed == nil || *seed != 42 {
+			t.Fatalf("request seed = %v, want 42", seed)
+		}
+	}
+}
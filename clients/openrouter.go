@@ -0,0 +1,109 @@
+package clients
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// OpenRouterBaseURL is the API base of OpenRouter.
+const OpenRouterBaseURL = "https://openrouter.ai/api/v1"
+
+// OpenRouterOptions carries the settings of an OpenRouter client.
+type OpenRouterOptions struct {
+	OpenAIOptions
+	// SiteURL and AppName identify the application on OpenRouter, as the
+	// HTTP-Referer and X-Title headers.
+	SiteURL string
+	AppName string
+	// Fallbacks are the models to try, in order, when the model is
+	// unavailable or fails.
+	Fallbacks []string
+	// Provider sets the provider routing preferences of requests, e.g.
+	// {"order": ["anthropic", "openai"], "allow_fallbacks": false}.
+	Provider map[string]any
+	// BaseURL overrides OpenRouterBaseURL.
+	BaseURL string
+}
+
+// NewOpenRouterLLM returns a client of model (e.g. "openai/gpt-4o") on
+// OpenRouter.
+func NewOpenRouterLLM(model, apiKey string) *OpenAIClient {
+	return NewOpenRouterLLMWithOptions(model, apiKey, OpenRouterOptions{})
+}
+
+// NewOpenRouterLLMWithOptions returns a client of model on OpenRouter, see
+// NewOpenRouterLLM.
+func NewOpenRouterLLMWithOptions(model, apiKey string, opts OpenRouterOptions) *OpenAIClient {
+	config := openai.DefaultConfig(apiKey)
+	config.BaseURL = opts.BaseURL
+	if config.BaseURL == "" {
+		config.BaseURL = OpenRouterBaseURL
+	}
+
+	headers := map[string]string{}
+	if opts.SiteURL != "" {
+		headers["HTTP-Referer"] = opts.SiteURL
+	}
+	if opts.AppName != "" {
+		headers["X-Title"] = opts.AppName
+	}
+	fields := map[string]any{}
+	if len(opts.Fallbacks) > 0 {
+		fields["models"] = append([]string{model}, opts.Fallbacks...)
+	}
+	if len(opts.Provider) > 0 {
+		fields["provider"] = opts.Provider
+	}
+
+	// The OpenRouter middlewares go first, so that the middlewares of opts
+	// see the requests as sent
+	clientOpts := opts.OpenAIOptions
+	clientOpts.HTTPMiddleware = append([]Middleware{
+		HeaderMiddleware(headers),
+		chatFieldsMiddleware(fields),
+	}, clientOpts.HTTPMiddleware...)
+	return newOpenAIClient(model, config, clientOpts)
+}
+
+// chatFieldsMiddleware adds fields to the JSON body of chat completion
+// requests, for the extensions of OpenAI-compatible APIs.
+func chatFieldsMiddleware(fields map[string]any) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		if len(fields) == 0 {
+			return next
+		}
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Body == nil || !strings.HasSuffix(req.URL.Path, "/chat/completions") {
+				return next.RoundTrip(req)
+			}
+			body, err := io.ReadAll(req.Body)
+			req.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			var payload map[string]json.RawMessage
+			if err := json.Unmarshal(body, &payload); err == nil {
+				for k, v := range fields {
+					if raw, err := json.Marshal(v); err == nil {
+						payload[k] = raw
+					}
+				}
+				if extended, err := json.Marshal(payload); err == nil {
+					body = extended
+				}
+			}
+			req = req.Clone(req.Context())
+			req.Body = io.NopCloser(bytes.NewReader(body))
+			req.ContentLength = int64(len(body))
+			req.GetBody = func() (io.ReadCloser, error) {
+				return io.NopCloser(bytes.NewReader(body)), nil
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package clients
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestOpenRouterRequests(t *testing.T) {
+	var gotTitle string
+	var gotBody map[string]any
+	srv := chatServer(t, func(r *http.Request) {
+		gotTitle = r.Header.Get("X-Title")
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &gotBody)
+	})
+
+	llm := NewOpenRouterLLMWithOptions("openai/gpt-4o", "k", OpenRouterOptions{
+		BaseURL:   srv.URL + "/v1",
+		AppName:   "my-agent",
+		Fallbacks: []string{"anthropic/claude-sonnet-4"},
+		Provider:  map[string]any{"allow_fallbacks": false},
+	})
+	_, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if gotTitle != "my-agent" {
+		t.Fatalf("X-Title = %q, want my-agent", gotTitle)
+	}
+	if gotBody["model"] != "openai/gpt-4o" {
+		t.Fatalf("model = %v", gotBody["model"])
+	}
+	if want := []any{"openai/gpt-4o", "anthropic/claude-sonnet-4"}; !reflect.DeepEqual(gotBody["models"], want) {
+		t.Fatalf("models = %v, want %v", gotBody["models"], want)
+	}
+	if want := map[string]any{"allow_fallbacks": false}; !reflect.DeepEqual(gotBody["provider"], want) {
+		t.Fatalf("provider = %v, want %v", gotBody["provider"], want)
+	}
+	if _, ok := gotBody["messages"]; !ok {
+		t.Fatalf("the request lost its messages: %v", gotBody)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/clients"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the YAML configuration of the cogito command: an agent
+// configuration (see cogito.AgentConfig) with the OpenAPI services and the
+// server settings. Values can reference environment variables as $VAR or
+// ${VAR}.
+type Config struct {
+	cogito.AgentConfig `yaml:",inline"`
+
+	OpenAPI []OpenAPIConfig `yaml:"openapi"`
+	Server  ServerConfig    `yaml:"server"`
+}
+
+// ServerConfig configures the serve command.
+type ServerConfig struct {
+	// APIKey, when set, is required from clients as a bearer token.
+	APIKey string `yaml:"api_key"`
+}
+
+// OpenAPIConfig is a REST service whose operations are used as tools.
+type OpenAPIConfig struct {
+	Spec        string   `yaml:"spec"`
+	BaseURL     string   `yaml:"base_url"`
+	BearerToken string   `yaml:"bearer_token"`
+	APIKey      string   `yaml:"api_key"`
+	Operations  []string `yaml:"operations"`
+}
+
+// loadConfig reads the configuration at path.
+func loadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return parseConfig(data)
+}
+
+func parseConfig(data []byte) (*Config, error) {
+	c := &Config{}
+	if err := yaml.Unmarshal([]byte(os.ExpandEnv(string(data))), c); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if c.Model == "" {
+		return nil, fmt.Errorf("the config has no model")
+	}
+	// Validate the agent configuration
+	if _, err := cogito.ParseAgentConfig(data); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LLM returns the client for the configured model.
+func (c *Config) LLM() *clients.OpenAIClient {
+	return clients.NewOpenAILLM(c.Model, c.APIKey, c.BaseURL)
+}
+
+// Setup connects to the MCP servers and loads the OpenAPI tools, returning
+// the options to run with and a function closing the MCP sessions. The
+// sessions are kept open across the runs of the command.
+func (c *Config) Setup(ctx context.Context) ([]cogito.Option, func(), error) {
+	opts := []cogito.Option{}
+	sessions := []*mcp.ClientSession{}
+	closeAll := func() {
+		for _, s := range sessions {
+			s.Close()
+		}
+	}
+
+	for _, server := range c.MCPServers {
+		session, err := server.Connect(ctx)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		sessions = append(sessions, session)
+		if server.Namespace != "" {
+			opts = append(opts, cogito.WithMCPNamespace(server.Namespace, session))
+		} else {
+			opts = append(opts, cogito.WithMCPs(session))
+		}
+	}
+
+	for _, api := range c.OpenAPI {
+		apiOpts := []cogito.OpenAPIOption{}
+		if api.BaseURL != "" {
+			apiOpts = append(apiOpts, cogito.WithOpenAPIBaseURL(api.BaseURL))
+		}
+		if len(api.Operations) > 0 {
+			apiOpts = append(apiOpts, cogito.WithOpenAPIOperations(api.Operations...))
+		}
+		tools, err := cogito.ToolsFromOpenAPI(ctx, api.Spec, cogito.OpenAPIAuth{BearerToken: api.BearerToken, APIKey: api.APIKey}, apiOpts...)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to load OpenAPI tools from %s: %w", api.Spec, err)
+		}
+		opts = append(opts, cogito.WithTools(tools...))
+	}
+
+	agentOpts, err := c.options()
+	if err != nil {
+		closeAll()
+		return nil, nil, err
+	}
+	return append(opts, agentOpts...), closeAll, nil
+}
+
+// options converts the guidelines and the options section. The MCP servers
+// are connected by Setup.
+func (c *Config) options() ([]cogito.Option, error) {
+	agent := c.AgentConfig
+	agent.MCPServers = nil
+	return agent.RunOptions()
+}
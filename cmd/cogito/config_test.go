@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("TEST_COGITO_KEY", "sk-test")
+	c, err := parseConfig([]byte(`
+model: qwen3
+base_url: http://localhost:8080/v1
+api_key: ${TEST_COGITO_KEY}
+mcp_servers:
+  - name: weather
+    command: docker
+    args: [run, -i, --rm, weather]
+    namespace: weather
+  - name: search
+    url: http://localhost:9000/mcp
+guidelines:
+  - condition: the user asks about the weather
+    action: use the weather tools
+options:
+  iterations: 5
+  force_reasoning: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.APIKey != "sk-test" {
+		t.Fatalf("api key = %q, environment variables were not expanded", c.APIKey)
+	}
+	if len(c.MCPServers) != 2 || c.MCPServers[0].Args[3] != "weather" || c.MCPServers[1].URL == "" {
+		t.Fatalf("mcp servers = %+v", c.MCPServers)
+	}
+	// guidelines, iterations and force reasoning
+	if opts, err := c.options(); err != nil || len(opts) != 3 {
+		t.Fatalf("got %d options, want 3", len(opts))
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for config, want := range map[string]string{
+		`base_url: http://localhost`:                                                "no model",
+		"model: m\nmcp_servers:\n  - name: broken":                                  "either a command or a url",
+		"model: m\nmcp_servers:\n  - name: both\n    command: x\n    url: http://x": "either a command or a url",
+	} {
+		if _, err := parseConfig([]byte(config)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("config %q: error = %v, want %q", config, err, want)
+		}
+	}
+}
@@ -0,0 +1,296 @@
+// Command cogito runs cogito pipelines from a YAML configuration.
+//
+//	cogito [-config cogito.yaml] chat
+//	cogito [-config cogito.yaml] run [-prompt "..."]
+//	cogito [-config cogito.yaml] plan [-prompt "..."]
+//	cogito [-config cogito.yaml] serve [-addr :8080]
+//	cogito [-config cogito.yaml] check
+//
+// run and plan read the prompt from stdin when -prompt is not given. Answers
+// are streamed to stdout, tool calls and progress are reported on stderr.
+// check runs cogito.Preflight on the configuration and fails if a check
+// fails.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/server"
+)
+
+const usage = `Usage: cogito [-config file] <command> [flags]
+
+Commands:
+  chat          interactive chat with the tools of the configuration
+  run           answer a prompt, using the tools
+  plan          extract a goal and a plan from a prompt and execute it
+  serve         serve the agent as an OpenAI-compatible chat completions API
+  check         check the model, MCP servers and guidelines of the configuration
+
+Flags:
+`
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("cogito", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	configPath := flags.String("config", envOr("COGITO_CONFIG", "cogito.yaml"), "configuration file (or $COGITO_CONFIG)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return fmt.Errorf("missing command")
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	commandFlags := flag.NewFlagSet(command, flag.ContinueOnError)
+	commandFlags.SetOutput(stderr)
+	prompt := ""
+	if command == "run" || command == "plan" {
+		commandFlags.StringVar(&prompt, "prompt", "", "prompt to answer, read from stdin when empty")
+	}
+	addr := ""
+	if command == "serve" {
+		commandFlags.StringVar(&addr, "addr", ":8080", "address to listen on")
+	}
+	if err := commandFlags.Parse(commandArgs); err != nil {
+		return err
+	}
+
+	config, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+	opts, closeSessions, err := config.Setup(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeSessions()
+
+	r := &runner{
+		ctx:    ctx,
+		llm:    config.LLM(),
+		config: config,
+		stdout: stdout,
+		stderr: stderr,
+	}
+	r.opts = append(opts, cogito.WithContext(ctx), cogito.WithStreamCallback(r.stream))
+
+	switch command {
+	case "check":
+		report := cogito.Preflight(config.LLM(), append(opts, cogito.WithContext(ctx))...)
+		fmt.Fprint(stdout, report)
+		if !report.OK() {
+			return fmt.Errorf("%d checks failed", len(report.Failed()))
+		}
+		return nil
+	case "serve":
+		return serve(ctx, addr, config, opts, stderr)
+	case "chat":
+		return r.chat(stdin)
+	case "run", "plan":
+		if prompt == "" {
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return fmt.Errorf("failed to read the prompt: %w", err)
+			}
+			prompt = strings.TrimSpace(string(data))
+		}
+		if prompt == "" {
+			return fmt.Errorf("empty prompt")
+		}
+		if command == "run" {
+			_, err = r.answer(r.fragment().AddMessage(cogito.UserMessageRole, prompt))
+			return err
+		}
+		return r.plan(prompt)
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// serve runs the chat completions API until ctx is cancelled.
+func serve(ctx context.Context, addr string, config *Config, opts []cogito.Option, stderr io.Writer) error {
+	s := server.New(config.LLM(), opts...)
+	s.SetModel(config.Model)
+	if config.Server.APIKey != "" {
+		s.SetAPIKeys(config.Server.APIKey)
+	}
+
+	httpServer := &http.Server{Addr: addr, Handler: s}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+	fmt.Fprintf(stderr, "serving %s on %s\n", config.Model, addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+type runner struct {
+	ctx    context.Context
+	llm    cogito.LLM
+	config *Config
+	opts   []cogito.Option
+	stdout io.Writer
+	stderr io.Writer
+
+	// streamed is set when the answer was streamed to stdout.
+	streamed bool
+}
+
+// fragment returns the conversation to start from.
+func (r *runner) fragment() cogito.Fragment {
+	f := cogito.NewEmptyFragment()
+	if r.config.Options.SystemPrompt != "" {
+		f = f.AddMessage(cogito.SystemMessageRole, r.config.Options.SystemPrompt)
+	}
+	return f
+}
+
+// stream prints the answer tokens to stdout, and tool activity to stderr.
+func (r *runner) stream(ev cogito.StreamEvent) {
+	switch ev.Type {
+	case cogito.StreamEventContent:
+		r.streamed = true
+		fmt.Fprint(r.stdout, ev.Content)
+	case cogito.StreamEventToolCall:
+		if ev.ToolName != "" {
+			fmt.Fprintf(r.stderr, "[tool] %s\n", ev.ToolName)
+		}
+	case cogito.StreamEventToolProgress:
+		fmt.Fprintf(r.stderr, "[progress] %s\n", cogito.ToolProgress{ToolName: ev.ToolName, Message: ev.Content, Progress: ev.Progress, Total: ev.Total})
+	case cogito.StreamEventError:
+		fmt.Fprintf(r.stderr, "[error] %v\n", ev.Error)
+	}
+}
+
+// answer runs the tools on f and prints the answer, unless it was streamed.
+func (r *runner) answer(f cogito.Fragment) (cogito.Fragment, error) {
+	r.streamed = false
+	result, err := cogito.ExecuteTools(r.llm, f, r.opts...)
+	if err != nil && !errors.Is(err, cogito.ErrNoToolSelected) {
+		return f, err
+	}
+	if errors.Is(err, cogito.ErrNoToolSelected) && result.LastMessage().Role != cogito.AssistantMessageRole.String() {
+		// No tool was needed and the sink state is disabled: answer directly
+		result, err = r.llm.Ask(r.ctx, f)
+		if err != nil {
+			return f, err
+		}
+	}
+	r.print(result)
+	return result, nil
+}
+
+func (r *runner) print(f cogito.Fragment) {
+	if r.streamed {
+		fmt.Fprintln(r.stdout)
+		return
+	}
+	fmt.Fprintln(r.stdout, f.LastMessage().Content)
+}
+
+func (r *runner) chat(stdin io.Reader) error {
+	f := r.fragment()
+	scanner := bufio.NewScanner(stdin)
+	for {
+		fmt.Fprint(r.stderr, "> ")
+		if !scanner.Scan() {
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if line == "/exit" || line == "/quit" {
+			return nil
+		}
+
+		result, err := r.answer(f.AddMessage(cogito.UserMessageRole, line))
+		if err != nil {
+			if r.ctx.Err() != nil {
+				return nil
+			}
+			fmt.Fprintln(r.stderr, "error:", err)
+			continue
+		}
+		f = result
+	}
+}
+
+func (r *runner) plan(prompt string) error {
+	f := r.fragment().AddMessage(cogito.UserMessageRole, prompt)
+
+	goal, err := cogito.ExtractGoal(r.llm, f, r.opts...)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.stderr, "[goal] %s\n", goal.Goal)
+
+	plan, err := cogito.ExtractPlan(r.llm, f, goal, r.opts...)
+	if err != nil {
+		return err
+	}
+	for i, subtask := range plan.Subtasks {
+		fmt.Fprintf(r.stderr, "[plan] %d. %s\n", i+1, subtask)
+	}
+
+	opts := append(r.opts, cogito.WithPlanProgressCallback(func(p cogito.PlanProgress) {
+		switch p.Event {
+		case cogito.PlanSubtaskStarted:
+			fmt.Fprintf(r.stderr, "[%d/%d] %s (attempt %d)\n", p.SubtaskIndex+1, p.TotalSubtasks, p.Subtask, p.Attempt)
+		case cogito.PlanSubtaskFinished:
+			fmt.Fprintf(r.stderr, "[%d/%d] achieved: %v\n", p.SubtaskIndex+1, p.TotalSubtasks, p.Achieved)
+		case cogito.PlanReplanned:
+			fmt.Fprintf(r.stderr, "[plan] re-planned into %d subtasks\n", len(p.Plan.Subtasks))
+		}
+	}))
+	result, err := cogito.ExecutePlan(r.llm, f, plan, goal, opts...)
+	if err != nil {
+		return err
+	}
+
+	// Summarize the outcome for the user
+	r.streamed = false
+	answer, err := r.llm.Ask(r.ctx, result.AddMessage(cogito.UserMessageRole, "Summarize the outcome of the work above for the user."))
+	if err != nil {
+		return err
+	}
+	r.print(answer)
+	return nil
+}
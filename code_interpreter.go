@@ -0,0 +1,316 @@
+package cogito
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CodeArgs are the arguments of the code interpreter tool.
+type CodeArgs struct {
+	Language string `json:"language"`
+	Code     string `json:"code"`
+}
+
+// SandboxRequest is a snippet to run in a Sandbox.
+type SandboxRequest struct {
+	Language string
+	Code     string
+	Timeout  time.Duration
+}
+
+// SandboxFile is a file written by a snippet to its working directory.
+type SandboxFile struct {
+	Name string // path relative to the working directory
+	Data []byte
+}
+
+// SandboxResult is the outcome of a snippet. A snippet exiting with a
+// non-zero code is a result, not an error.
+type SandboxResult struct {
+	Stdout   string
+	Stderr   string
+	ExitCode int
+	Files    []SandboxFile
+}
+
+// Sandbox runs code isolated from the host, e.g. in a container or a
+// micro-VM. DockerSandbox runs it with Docker; implement Sandbox for other
+// runtimes (Firecracker, gVisor, a remote execution service, ...).
+type Sandbox interface {
+	Run(ctx context.Context, req SandboxRequest) (SandboxResult, error)
+}
+
+// CodeInterpreterConfig configures the code interpreter tool. See
+// NewCodeInterpreterTool.
+type CodeInterpreterConfig struct {
+	// Sandbox runs the snippets. Required.
+	Sandbox Sandbox
+	// Languages the LLM may use. Defaults to python and go.
+	Languages []string
+	// Timeout caps the run time of a snippet. Defaults to 30 seconds.
+	Timeout time.Duration
+	// MaxOutputLength caps the characters of stdout and stderr passed to the
+	// LLM. Defaults to 8000.
+	MaxOutputLength int
+	// Name is the tool name. Defaults to "run_code".
+	Name string
+}
+
+// NewCodeInterpreterTool returns a tool running code written by the LLM in
+// cfg.Sandbox, for data analysis and computations. The tool result reports
+// the exit code, stdout, stderr and the files written by the snippet; its
+// ResultData is a ToolResult with the generated images in Images and the
+// SandboxResult in JSON.
+//
+// The tool is never added implicitly: snippets run arbitrary code, so only
+// pass it with WithTools along with a Sandbox you trust to isolate them.
+func NewCodeInterpreterTool(cfg CodeInterpreterConfig) ToolDefinitionInterface {
+	if len(cfg.Languages) == 0 {
+		cfg.Languages = []string{"python", "go"}
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.MaxOutputLength <= 0 {
+		cfg.MaxOutputLength = 8000
+	}
+	if cfg.Name == "" {
+		cfg.Name = "run_code"
+	}
+
+	return NewToolDefinition(
+		&codeInterpreterRunner{cfg: cfg},
+		map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"language": map[string]any{
+					"type":        "string",
+					"enum":        cfg.Languages,
+					"description": "The language of the code",
+				},
+				"code": map[string]any{
+					"type":        "string",
+					"description": "The complete program to run. Print the results to stdout; files written to the working directory are returned.",
+				},
+			},
+			"required": []string{"language", "code"},
+		},
+		cfg.Name,
+		fmt.Sprintf("Run a %s program in a sandbox without network access, and get its output and the files it writes.",
+			strings.Join(cfg.Languages, " or ")),
+	)
+}
+
+type codeInterpreterRunner struct {
+	cfg CodeInterpreterConfig
+}
+
+func (r *codeInterpreterRunner) Run(args CodeArgs) (string, any, error) {
+	return r.RunWithContext(context.Background(), args)
+}
+
+func (r *codeInterpreterRunner) RunWithContext(ctx context.Context, args CodeArgs) (string, any, error) {
+	if r.cfg.Sandbox == nil {
+		return "", nil, fmt.Errorf("code interpreter has no sandbox configured")
+	}
+	if !slices.Contains(r.cfg.Languages, args.Language) {
+		return "", nil, fmt.Errorf("unsupported language %q, use one of: %s", args.Language, strings.Join(r.cfg.Languages, ", "))
+	}
+	if strings.TrimSpace(args.Code) == "" {
+		return "", nil, fmt.Errorf("no code to run")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.cfg.Timeout)
+	defer cancel()
+	res, err := r.cfg.Sandbox.Run(ctx, SandboxRequest{Language: args.Language, Code: args.Code, Timeout: r.cfg.Timeout})
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to run code: %w", err)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Exit code: %d", res.ExitCode)
+	if res.Stdout != "" {
+		fmt.Fprintf(&b, "\n\nStdout:\n%s", truncateChars(res.Stdout, r.cfg.MaxOutputLength))
+	}
+	if res.Stderr != "" {
+		fmt.Fprintf(&b, "\n\nStderr:\n%s", truncateChars(res.Stderr, r.cfg.MaxOutputLength))
+	}
+	var images []Multimedia
+	if len(res.Files) > 0 {
+		b.WriteString("\n\nFiles:")
+		for _, file := range res.Files {
+			mimeType := mime.TypeByExtension(filepath.Ext(file.Name))
+			if mimeType == "" {
+				mimeType = http.DetectContentType(file.Data)
+			}
+			fmt.Fprintf(&b, "\n- %s (%s, %d bytes)", file.Name, mimeType, len(file.Data))
+			if strings.HasPrefix(mimeType, "image/") {
+				images = append(images, NewImageFromBytes(file.Data, mimeType))
+			}
+		}
+	}
+
+	text := b.String()
+	return text, ToolResult{Text: text, Images: images, JSON: res}, nil
+}
+
+// DockerLanguage describes how DockerSandbox runs a language: the code is
+// written to File in the working directory and Command is run there in
+// Image.
+type DockerLanguage struct {
+	Image   string
+	File    string
+	Command []string
+}
+
+// DefaultDockerLanguages are the languages of a DockerSandbox without
+// Languages. The images only have the standard libraries: for data analysis
+// with pandas or matplotlib, use an image with them, e.g. one built FROM
+// python:3.12-slim with pip install pandas matplotlib.
+var DefaultDockerLanguages = map[string]DockerLanguage{
+	"python": {Image: "python:3.12-slim", File: "main.py", Command: []string{"python", "main.py"}},
+	"go":     {Image: "golang:1.24-alpine", File: "main.go", Command: []string{"go", "run", "main.go"}},
+}
+
+// DockerSandbox runs snippets in throw-away Docker containers, without
+// network access and with limited memory, CPU and processes. The containers
+// run as the user of the host process (nobody when it is root), without
+// capabilities or privilege escalation, on a read-only root filesystem with
+// a writable /tmp, which is also the home directory. The working directory
+// is a temporary directory of the host mounted in the container; the files
+// the snippet writes there are returned.
+type DockerSandbox struct {
+	// Languages maps language names to their image and command. Defaults to
+	// DefaultDockerLanguages.
+	Languages map[string]DockerLanguage
+	// Memory is the memory limit of the container. Defaults to "512m".
+	Memory string
+	// CPUs is the CPU limit of the container. Defaults to "1".
+	CPUs string
+	// PidsLimit caps the processes of the container. Defaults to 256.
+	PidsLimit int
+	// TmpSize is the size of the /tmp filesystem. Defaults to "256m".
+	TmpSize string
+	// Network enables network access.
+	Network bool
+	// Binary is the docker CLI. Defaults to "docker".
+	Binary string
+	// MaxFileSize caps the size of the returned files; larger files are
+	// skipped. Defaults to 10 MiB.
+	MaxFileSize int64
+}
+
+// Run implements Sandbox.
+func (d *DockerSandbox) Run(ctx context.Context, req SandboxRequest) (SandboxResult, error) {
+	languages := d.Languages
+	if languages == nil {
+		languages = DefaultDockerLanguages
+	}
+	lang, ok := languages[req.Language]
+	if !ok {
+		return SandboxResult{}, fmt.Errorf("no docker image configured for language %q", req.Language)
+	}
+
+	dir, err := os.MkdirTemp("", "cogito-sandbox-")
+	if err != nil {
+		return SandboxResult{}, fmt.Errorf("failed to create sandbox directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, lang.File), []byte(req.Code), 0o644); err != nil {
+		return SandboxResult{}, fmt.Errorf("failed to write code: %w", err)
+	}
+
+	binary := cmp.Or(d.Binary, "docker")
+	name := "cogito-sandbox-" + uuid.NewString()
+	args := []string{"run", "--rm", "--name", name,
+		"--memory", cmp.Or(d.Memory, "512m"), "--cpus", cmp.Or(d.CPUs, "1"),
+		"--pids-limit", strconv.Itoa(cmp.Or(d.PidsLimit, 256)),
+		"--cap-drop", "ALL", "--security-opt", "no-new-privileges",
+		// go run builds and runs its binaries in /tmp
+		"--read-only", "--tmpfs", "/tmp:rw,exec,size=" + cmp.Or(d.TmpSize, "256m"), "-e", "HOME=/tmp",
+		"-v", dir + ":/workspace", "-w", "/workspace"}
+	user := fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())
+	if os.Getuid() <= 0 {
+		// Never root: nobody, with a working directory open to it
+		user = "65534:65534"
+		if err := os.Chmod(dir, 0o777); err != nil {
+			return SandboxResult{}, fmt.Errorf("failed to create sandbox directory: %w", err)
+		}
+	}
+	args = append(args, "--user", user)
+	if !d.Network {
+		args = append(args, "--network", "none")
+	}
+	args = append(args, lang.Image)
+	args = append(args, lang.Command...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	runErr := cmd.Run()
+	if ctx.Err() != nil {
+		// Killing the CLI does not stop the container
+		_ = exec.Command(binary, "rm", "-f", name).Run()
+		return SandboxResult{}, fmt.Errorf("code did not complete: %w", ctx.Err())
+	}
+
+	res := SandboxResult{Stdout: stdout.String(), Stderr: stderr.String()}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(runErr, &exitErr):
+		res.ExitCode = exitErr.ExitCode()
+	case runErr != nil:
+		return SandboxResult{}, fmt.Errorf("failed to run docker: %w", runErr)
+	}
+
+	res.Files, err = collectSandboxFiles(dir, lang.File, d.MaxFileSize)
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// collectSandboxFiles returns the files of dir except the source file.
+func collectSandboxFiles(dir, source string, maxSize int64) ([]SandboxFile, error) {
+	if maxSize <= 0 {
+		maxSize = 10 << 20
+	}
+	var files []SandboxFile
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !entry.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == source {
+			return err
+		}
+		if info, err := entry.Info(); err != nil || info.Size() > maxSize {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, SandboxFile{Name: filepath.ToSlash(rel), Data: data})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect sandbox files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,102 @@
+package cogito_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+// fakeSandbox records the requests it receives and replies with result.
+type fakeSandbox struct {
+	requests []SandboxRequest
+	result   SandboxResult
+}
+
+func (s *fakeSandbox) Run(ctx context.Context, req SandboxRequest) (SandboxResult, error) {
+	s.requests = append(s.requests, req)
+	return s.result, nil
+}
+
+var _ = Describe("Code interpreter", func() {
+	It("runs the code written by the LLM in the sandbox", func() {
+		png := []byte("\x89PNG\r\n\x1a\n0000")
+		sandbox := &fakeSandbox{result: SandboxResult{
+			Stdout: "mean: 4.2\n",
+			Files:  []SandboxFile{{Name: "plot.png", Data: png}, {Name: "data.csv", Data: []byte("a,b\n")}},
+		}}
+		tool := NewCodeInterpreterTool(CodeInterpreterConfig{Sandbox: sandbox})
+		Expect(tool.Tool().Function.Name).To(Equal("run_code"))
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.AddCreateChatCompletionFunction("run_code", `{"language": "python", "code": "print('mean: 4.2')"}`)
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "The mean is 4.2."}},
+			},
+		})
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What is the mean?")
+		result, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sandbox.requests).To(HaveLen(1))
+		Expect(sandbox.requests[0].Language).To(Equal("python"))
+		Expect(sandbox.requests[0].Code).To(Equal("print('mean: 4.2')"))
+
+		status := result.Status.ToolResults[0]
+		Expect(status.Result).To(And(
+			ContainSubstring("Exit code: 0"),
+			ContainSubstring("mean: 4.2"),
+			ContainSubstring("- plot.png (image/png"),
+			ContainSubstring("- data.csv"),
+		))
+		Expect(result.Multimedia).To(HaveLen(1))
+	})
+
+	It("rejects languages that are not enabled", func() {
+		tool := NewCodeInterpreterTool(CodeInterpreterConfig{Sandbox: &fakeSandbox{}, Languages: []string{"python"}})
+		_, _, err := tool.Execute(map[string]any{"language": "go", "code": "package main"})
+		Expect(err).To(MatchError(ContainSubstring(`unsupported language "go"`)))
+	})
+
+	It("runs snippets in isolated docker containers", func() {
+		dir := GinkgoT().TempDir()
+		docker := filepath.Join(dir, "docker")
+		Expect(os.WriteFile(docker, []byte(`#!/bin/sh
+echo "$@" > `+filepath.Join(dir, "args")+`
+while [ $# -gt 0 ]; do
+  case "$1" in -v) workspace="${2%%:*}"; shift;; esac
+  shift
+done
+cat "$workspace/main.py"
+echo "warning" >&2
+echo "a,b" > "$workspace/out.csv"
+exit 3
+`), 0o755)).To(Succeed())
+
+		sandbox := &DockerSandbox{Binary: docker}
+		res, err := sandbox.Run(context.Background(), SandboxRequest{Language: "python", Code: "print(1)"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(res.ExitCode).To(Equal(3))
+		Expect(res.Stdout).To(Equal("print(1)"))
+		Expect(res.Stderr).To(Equal("warning\n"))
+		Expect(res.Files).To(Equal([]SandboxFile{{Name: "out.csv", Data: []byte("a,b\n")}}))
+
+		args, err := os.ReadFile(filepath.Join(dir, "args"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(args)).To(And(
+			ContainSubstring("--network none"),
+			ContainSubstring("--memory 512m"),
+			ContainSubstring("--pids-limit 256"),
+			ContainSubstring("--cap-drop ALL --security-opt no-new-privileges --read-only"),
+			ContainSubstring("--user "),
+			ContainSubstring("python:3.12-slim python main.py"),
+		))
+	})
+})
@@ -0,0 +1,91 @@
+package cogitotest
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mudler/cogito"
+)
+
+// EventRecorder records the events emitted by a run, for assertions. Pass
+// its Record method to cogito.WithStreamCallback.
+type EventRecorder struct {
+	mu     sync.Mutex
+	events []cogito.StreamEvent
+	notify chan struct{}
+}
+
+// NewEventRecorder returns an empty recorder.
+func NewEventRecorder() *EventRecorder {
+	return &EventRecorder{notify: make(chan struct{})}
+}
+
+// Record records ev. It is a cogito.StreamCallback.
+func (r *EventRecorder) Record(ev cogito.StreamEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+	close(r.notify)
+	r.notify = make(chan struct{})
+}
+
+// Events returns the recorded events, in order.
+func (r *EventRecorder) Events() []cogito.StreamEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]cogito.StreamEvent(nil), r.events...)
+}
+
+// OfType returns the recorded events of type t, in order.
+func (r *EventRecorder) OfType(t cogito.StreamEventType) []cogito.StreamEvent {
+	var out []cogito.StreamEvent
+	for _, ev := range r.Events() {
+		if ev.Type == t {
+			out = append(out, ev)
+		}
+	}
+	return out
+}
+
+// ToolResults returns the names of the tools whose results were emitted,
+// in order.
+func (r *EventRecorder) ToolResults() []string {
+	var names []string
+	for _, ev := range r.OfType(cogito.StreamEventToolResult) {
+		names = append(names, ev.ToolName)
+	}
+	return names
+}
+
+// Content returns the concatenated content deltas.
+func (r *EventRecorder) Content() string {
+	var b strings.Builder
+	for _, ev := range r.OfType(cogito.StreamEventContent) {
+		b.WriteString(ev.Content)
+	}
+	return b.String()
+}
+
+// WaitFor waits up to timeout for an event matched by match, among the
+// recorded ones and the ones to come, e.g. for runs of background agents.
+func (r *EventRecorder) WaitFor(match func(cogito.StreamEvent) bool, timeout time.Duration) (cogito.StreamEvent, bool) {
+	deadline := time.After(timeout)
+	seen := 0
+	for {
+		r.mu.Lock()
+		events, notify := r.events[seen:], r.notify
+		seen = len(r.events)
+		r.mu.Unlock()
+		for _, ev := range events {
+			if match(ev) {
+				return ev, true
+			}
+		}
+		select {
+		case <-notify:
+		case <-deadline:
+			return cogito.StreamEvent{}, false
+		}
+	}
+}
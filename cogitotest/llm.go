@@ -0,0 +1,263 @@
+// Package cogitotest provides test doubles for unit-testing cogito agents
+// without a model: a scriptable LLM, mock tools and a recorder of the events
+// emitted by a run.
+//
+// MockLLM answers either from ordered queues (SetAskResponse,
+// AddCreateChatCompletionFunction, ...) or from scripted rules keyed by the
+// content of the request (When), which keep working when the agent changes
+// how many calls it makes:
+//
+//	llm := cogitotest.NewMockLLM()
+//	llm.When(cogitotest.LastMessageContains("weather")).ReplyToolCall("get_weather", `{"city": "Rome"}`)
+//	llm.When(cogitotest.LastMessageContains("Sunny")).ReplyText("It's sunny in Rome.")
+//	llm.Expect("offers the weather tool", cogitotest.HasTool("get_weather"))
+//
+//	result, err := cogito.ExecuteTools(llm, fragment, cogito.WithTools(weatherTool))
+//	...
+//	if err := llm.Verify(); err != nil {
+//		t.Fatal(err)
+//	}
+package cogitotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mudler/cogito"
+	"github.com/sashabaranov/go-openai"
+)
+
+// MockLLM is a cogito.LLM answering from scripted rules and queues. It is
+// safe for concurrent use by the calls it serves; configure it before the
+// run.
+type MockLLM struct {
+	mu sync.Mutex
+
+	AskResponses                  []cogito.Fragment
+	AskResponseIndex              int
+	CreateChatCompletionResponses []openai.ChatCompletionResponse
+	CreateChatCompletionIndex     int
+	AskError                      error
+	CreateChatCompletionError     error
+	FragmentHistory               []cogito.Fragment
+	RequestHistory                []openai.ChatCompletionRequest
+
+	// Token usage for responses
+	AskUsage                       []cogito.LLMUsage
+	AskUsageIndex                  int
+	CreateChatCompletionUsage      []cogito.LLMUsage
+	CreateChatCompletionUsageIndex int
+
+	rules        []*Rule
+	expectations []expectation
+}
+
+// NewMockLLM returns a MockLLM without responses.
+func NewMockLLM() *MockLLM {
+	return &MockLLM{
+		AskResponses:                  []cogito.Fragment{},
+		CreateChatCompletionResponses: []openai.ChatCompletionResponse{},
+		AskUsage:                      []cogito.LLMUsage{},
+		CreateChatCompletionUsage:     []cogito.LLMUsage{},
+	}
+}
+
+// Ask implements cogito.LLM. It answers from the first rule matching the
+// messages of f, or else from the Ask queue.
+func (m *MockLLM) Ask(ctx context.Context, f cogito.Fragment) (cogito.Fragment, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.FragmentHistory = append(m.FragmentHistory, f)
+	if m.AskError != nil {
+		return cogito.Fragment{}, m.AskError
+	}
+
+	var response cogito.Fragment
+	if reply, ok := m.ruleReply(openai.ChatCompletionRequest{Messages: f.Messages}); ok {
+		response = cogito.NewEmptyFragment()
+		if len(reply.Choices) > 0 {
+			response.Messages = []openai.ChatCompletionMessage{reply.Choices[0].Message}
+		}
+	} else {
+		if m.AskResponseIndex >= len(m.AskResponses) {
+			return cogito.Fragment{}, fmt.Errorf("no more Ask responses configured (last message: %q)", lastMessage(f.Messages))
+		}
+		response = m.AskResponses[m.AskResponseIndex]
+		m.AskResponseIndex++
+	}
+
+	// Add the response to the fragment
+	response.Messages = append(f.Messages, response.Messages...)
+	response.ParentFragment = &f
+
+	var usage cogito.LLMUsage
+	if m.AskUsageIndex < len(m.AskUsage) {
+		usage = m.AskUsage[m.AskUsageIndex]
+		m.AskUsageIndex++
+	}
+	if response.Status == nil {
+		response.Status = f.Status
+	}
+	if response.Status == nil {
+		response.Status = &cogito.Status{}
+	}
+	response.Status.LastUsage = usage
+
+	return response, nil
+}
+
+// CreateChatCompletion implements cogito.LLM. It answers from the first
+// rule matching request, or else from the CreateChatCompletion queue.
+func (m *MockLLM) CreateChatCompletion(ctx context.Context, request openai.ChatCompletionRequest) (cogito.LLMReply, cogito.LLMUsage, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.RequestHistory = append(m.RequestHistory, request)
+	if m.CreateChatCompletionError != nil {
+		return cogito.LLMReply{}, cogito.LLMUsage{}, m.CreateChatCompletionError
+	}
+
+	response, ok := m.ruleReply(request)
+	if !ok {
+		if m.CreateChatCompletionIndex >= len(m.CreateChatCompletionResponses) {
+			return cogito.LLMReply{}, cogito.LLMUsage{}, fmt.Errorf("no more CreateChatCompletion responses configured (last message: %q)", lastMessage(request.Messages))
+		}
+		response = m.CreateChatCompletionResponses[m.CreateChatCompletionIndex]
+		m.CreateChatCompletionIndex++
+	}
+
+	var usage cogito.LLMUsage
+	if m.CreateChatCompletionUsageIndex < len(m.CreateChatCompletionUsage) {
+		usage = m.CreateChatCompletionUsage[m.CreateChatCompletionUsageIndex]
+		m.CreateChatCompletionUsageIndex++
+	}
+
+	var reasoning string
+	if len(response.Choices) > 0 {
+		reasoning = response.Choices[0].Message.ReasoningContent
+	}
+	return cogito.LLMReply{
+		ChatCompletionResponse: response,
+		ReasoningContent:       reasoning,
+	}, usage, nil
+}
+
+// SetAskResponse queues a reply to Ask.
+func (m *MockLLM) SetAskResponse(content string) {
+	fragment := cogito.NewEmptyFragment().AddMessage(cogito.AssistantMessageRole, content)
+	m.AskResponses = append(m.AskResponses, fragment)
+}
+
+// SetAskError makes every Ask call fail with err.
+func (m *MockLLM) SetAskError(err error) {
+	m.AskError = err
+}
+
+// SetCreateChatCompletionResponse queues a reply to CreateChatCompletion.
+func (m *MockLLM) SetCreateChatCompletionResponse(response openai.ChatCompletionResponse) {
+	m.CreateChatCompletionResponses = append(m.CreateChatCompletionResponses, response)
+}
+
+// AddCreateChatCompletionFunction queues a reply to CreateChatCompletion
+// calling the tool name with the JSON arguments args.
+func (m *MockLLM) AddCreateChatCompletionFunction(name, args string) {
+	m.SetCreateChatCompletionResponse(toolCallResponse(name, args))
+}
+
+// SetCreateChatCompletionError makes every CreateChatCompletion call fail
+// with err.
+func (m *MockLLM) SetCreateChatCompletionError(err error) {
+	m.CreateChatCompletionError = err
+}
+
+// SetUsage sets token usage for the next responses
+func (m *MockLLM) SetUsage(promptTokens, completionTokens, totalTokens int) {
+	usage := cogito.LLMUsage{
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      totalTokens,
+	}
+	m.AskUsage = append(m.AskUsage, usage)
+	m.CreateChatCompletionUsage = append(m.CreateChatCompletionUsage, usage)
+}
+
+// Requests returns the requests received by CreateChatCompletion, in order.
+func (m *MockLLM) Requests() []openai.ChatCompletionRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]openai.ChatCompletionRequest(nil), m.RequestHistory...)
+}
+
+// Verify returns an error listing the expectations no request met and the
+// scripted replies that were never used.
+func (m *MockLLM) Verify() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var errs []error
+	for _, e := range m.expectations {
+		if !m.requested(e.match) {
+			errs = append(errs, fmt.Errorf("expected a request that %s", e.description))
+		}
+	}
+	for _, r := range m.rules {
+		if left := len(r.replies) - r.used; left > 0 && !r.repeat {
+			errs = append(errs, fmt.Errorf("rule %d has %d unused replies", r.index, left))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MockLLM) requested(match RequestMatcher) bool {
+	for _, req := range m.RequestHistory {
+		if match(req) {
+			return true
+		}
+	}
+	for _, f := range m.FragmentHistory {
+		if match(openai.ChatCompletionRequest{Messages: f.Messages}) {
+			return true
+		}
+	}
+	return false
+}
+
+func toolCallResponse(name, args string) openai.ChatCompletionResponse {
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{
+				Message: openai.ChatCompletionMessage{
+					Role: cogito.AssistantMessageRole.String(),
+					ToolCalls: []openai.ToolCall{
+						{
+							Type: openai.ToolTypeFunction,
+							Function: openai.FunctionCall{
+								Name:      name,
+								Arguments: args,
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func textResponse(content string) openai.ChatCompletionResponse {
+	return openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: cogito.AssistantMessageRole.String(), Content: content}},
+		},
+	}
+}
+
+func lastMessage(messages []openai.ChatCompletionMessage) string {
+	if len(messages) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(messageText(messages[len(messages)-1]))
+}
@@ -0,0 +1,97 @@
+package cogitotest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+)
+
+func TestRulesAnswerByRequestContent(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("get_weather", "Get the weather")
+	cogitotest.SetRunResult(tool, "Sunny, 24C")
+
+	// Registered in reverse order of use: rules are matched by content
+	llm.When(cogitotest.LastMessageContains("Sunny")).ReplyText("It's sunny in Rome.")
+	llm.When(cogitotest.LastMessageContains("weather in Rome")).ReplyToolCall("get_weather", `{}`)
+	llm.Expect("offers the weather tool", cogitotest.HasTool("get_weather"))
+
+	f := cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "What's the weather in Rome?")
+	result, err := cogito.ExecuteTools(llm, f, cogito.WithTools(tool), cogito.WithIterations(2))
+	if err != nil {
+		t.Fatalf("ExecuteTools: %v", err)
+	}
+	if got := result.LastMessage().Content; got != "It's sunny in Rome." {
+		t.Errorf("reply = %q", got)
+	}
+	if calls := cogitotest.GetMockTool(tool).Calls(); len(calls) != 1 {
+		t.Errorf("tool calls = %d, want 1", len(calls))
+	}
+	if err := llm.Verify(); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+}
+
+func TestVerifyReportsUnmetExpectationsAndUnusedReplies(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	llm.When(cogitotest.MessageContains("never sent")).ReplyText("unused")
+	llm.Expect("offers the search tool", cogitotest.HasTool("search"))
+
+	err := llm.Verify()
+	if err == nil {
+		t.Fatal("Verify succeeded, want an error")
+	}
+	for _, want := range []string{"offers the search tool", "rule 1 has 1 unused replies"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRulesFallBackToQueues(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	llm.When(cogitotest.LastMessageContains("hello")).ReplyText("scripted").Repeat()
+	llm.SetAskResponse("queued")
+
+	for range 2 {
+		res, err := llm.Ask(t.Context(), cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "hello"))
+		if err != nil || res.LastMessage().Content != "scripted" {
+			t.Fatalf("Ask = %q, %v; want the repeated rule reply", res.LastMessage().Content, err)
+		}
+	}
+	res, err := llm.Ask(t.Context(), cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "bye"))
+	if err != nil || res.LastMessage().Content != "queued" {
+		t.Fatalf("Ask = %q, %v; want the queued reply", res.LastMessage().Content, err)
+	}
+	if _, err := llm.Ask(t.Context(), cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "bye")); err == nil {
+		t.Fatal("Ask succeeded without responses left")
+	}
+}
+
+func TestEventRecorder(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("get_weather", "Get the weather")
+	cogitotest.SetRunResult(tool, "Sunny")
+	llm.AddCreateChatCompletionFunction("get_weather", `{}`)
+	llm.SetAskResponse("It's sunny.")
+
+	events := cogitotest.NewEventRecorder()
+	f := cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "Weather?")
+	if _, err := cogito.ExecuteTools(llm, f, cogito.WithTools(tool), cogito.WithIterations(1),
+		cogito.WithStreamCallback(events.Record)); err != nil {
+		t.Fatalf("ExecuteTools: %v", err)
+	}
+
+	if got := events.ToolResults(); len(got) != 1 || got[0] != "get_weather" {
+		t.Errorf("ToolResults = %v", got)
+	}
+	ev, ok := events.WaitFor(func(ev cogito.StreamEvent) bool {
+		return ev.Type == cogito.StreamEventToolResult
+	}, time.Second)
+	if !ok || ev.ToolResult != "Sunny" {
+		t.Errorf("WaitFor = %+v, %v", ev, ok)
+	}
+}
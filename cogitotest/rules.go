@@ -0,0 +1,180 @@
+package cogitotest
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RequestMatcher reports whether a request has some content. Requests to
+// Ask are matched by their messages only.
+type RequestMatcher func(req openai.ChatCompletionRequest) bool
+
+// MessageContains matches requests with a message containing substr.
+func MessageContains(substr string) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool {
+		return slices.ContainsFunc(req.Messages, func(m openai.ChatCompletionMessage) bool {
+			return strings.Contains(messageText(m), substr)
+		})
+	}
+}
+
+// LastMessageContains matches requests whose last message contains substr.
+func LastMessageContains(substr string) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool {
+		return len(req.Messages) > 0 && strings.Contains(messageText(req.Messages[len(req.Messages)-1]), substr)
+	}
+}
+
+// SystemPromptContains matches requests with a system message containing
+// substr, e.g. to tell the reasoning, tool selection and review prompts of
+// cogito apart.
+func SystemPromptContains(substr string) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool {
+		return slices.ContainsFunc(req.Messages, func(m openai.ChatCompletionMessage) bool {
+			return m.Role == openai.ChatMessageRoleSystem && strings.Contains(messageText(m), substr)
+		})
+	}
+}
+
+// HasTool matches requests offering the tool name.
+func HasTool(name string) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool {
+		return slices.ContainsFunc(req.Tools, func(t openai.Tool) bool {
+			return t.Function != nil && t.Function.Name == name
+		})
+	}
+}
+
+// ForcesTool matches requests forcing the call of the tool name.
+func ForcesTool(name string) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool {
+		switch choice := req.ToolChoice.(type) {
+		case openai.ToolChoice:
+			return choice.Function.Name == name
+		case *openai.ToolChoice:
+			return choice != nil && choice.Function.Name == name
+		}
+		return false
+	}
+}
+
+// All matches requests matched by every matcher.
+func All(matchers ...RequestMatcher) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool {
+		for _, match := range matchers {
+			if !match(req) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Not matches requests not matched by match.
+func Not(match RequestMatcher) RequestMatcher {
+	return func(req openai.ChatCompletionRequest) bool { return !match(req) }
+}
+
+// Rule is a scripted reply of a MockLLM to the requests it matches. See
+// MockLLM.When.
+type Rule struct {
+	index   int
+	match   RequestMatcher
+	replies []openai.ChatCompletionResponse
+	used    int
+	repeat  bool
+}
+
+// When adds a rule answering the requests matched by match. Rules are
+// consulted in the order they were added, before the queues: the first
+// matching rule with replies left answers. A rule gives its replies in turn
+// and is then exhausted, unless Repeat is set.
+func (m *MockLLM) When(match RequestMatcher) *Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := &Rule{index: len(m.rules) + 1, match: match}
+	m.rules = append(m.rules, r)
+	return r
+}
+
+// Reply adds response to the replies of the rule.
+func (r *Rule) Reply(response openai.ChatCompletionResponse) *Rule {
+	r.replies = append(r.replies, response)
+	return r
+}
+
+// ReplyText adds a text reply to the rule.
+func (r *Rule) ReplyText(content string) *Rule {
+	return r.Reply(textResponse(content))
+}
+
+// ReplyToolCall adds a reply calling the tool name with the JSON arguments
+// args.
+func (r *Rule) ReplyToolCall(name, args string) *Rule {
+	return r.Reply(toolCallResponse(name, args))
+}
+
+// ReplyJSON adds a reply calling the tool name with v as arguments, for the
+// structured decisions of cogito (reasoning, reviews, plans, ...).
+func (r *Rule) ReplyJSON(name string, v any) *Rule {
+	args, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("cogitotest: cannot encode arguments of %s: %v", name, err))
+	}
+	return r.ReplyToolCall(name, string(args))
+}
+
+// Repeat keeps the rule answering with its last reply once the others are
+// used.
+func (r *Rule) Repeat() *Rule {
+	r.repeat = true
+	return r
+}
+
+// ruleReply returns the reply of the first rule matching req.
+func (m *MockLLM) ruleReply(req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, bool) {
+	for _, r := range m.rules {
+		if len(r.replies) == 0 || !r.match(req) {
+			continue
+		}
+		if r.used < len(r.replies) {
+			r.used++
+			return r.replies[r.used-1], true
+		}
+		if r.repeat {
+			return r.replies[len(r.replies)-1], true
+		}
+	}
+	return openai.ChatCompletionResponse{}, false
+}
+
+type expectation struct {
+	description string
+	match       RequestMatcher
+}
+
+// Expect records that some request of the run must be matched by match;
+// Verify reports the expectations no request met, by description.
+func (m *MockLLM) Expect(description string, match RequestMatcher) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.expectations = append(m.expectations, expectation{description: description, match: match})
+}
+
+// messageText returns the text of m, including its text parts.
+func messageText(m openai.ChatCompletionMessage) string {
+	if m.Content != "" || len(m.MultiContent) == 0 {
+		return m.Content
+	}
+	var b strings.Builder
+	for _, part := range m.MultiContent {
+		if part.Type == openai.ChatMessagePartTypeText {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
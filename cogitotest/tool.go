@@ -0,0 +1,106 @@
+package cogitotest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mudler/cogito"
+)
+
+// MockTool is a tool returning scripted results, recording its calls.
+type MockTool struct {
+	mu          sync.Mutex
+	name        string
+	description string
+	runResults  []string
+	runError    error
+	runIndex    int
+	calls       []map[string]any
+	status      *cogito.ToolStatus
+	toolDef     *cogito.ToolDefinition[map[string]any]
+}
+
+// NewMockTool returns a tool named name without arguments. Set its results
+// with SetRunResult and SetRunError.
+func NewMockTool(name, description string) cogito.ToolDefinitionInterface {
+	mockTool := &MockTool{
+		name:        name,
+		description: description,
+		status:      &cogito.ToolStatus{},
+	}
+	toolDef := &cogito.ToolDefinition[map[string]any]{
+		ToolRunner:  mockTool,
+		Name:        name,
+		Description: description,
+		InputArguments: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{},
+		},
+	}
+	mockTool.toolDef = toolDef
+	return toolDef
+}
+
+func (m *MockTool) Status() *cogito.ToolStatus {
+	return m.status
+}
+
+func (m *MockTool) Run(args map[string]any) (string, any, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.calls = append(m.calls, args)
+	if m.runError != nil {
+		return "", nil, m.runError
+	}
+	if m.runIndex >= len(m.runResults) {
+		return "", nil, fmt.Errorf("no more results configured for tool %s", m.name)
+	}
+	m.runIndex++
+	return m.runResults[m.runIndex-1], nil, nil
+}
+
+func (m *MockTool) NewArgs() *map[string]any {
+	args := make(map[string]any)
+	return &args
+}
+
+// SetRunResult queues a result of the tool.
+func (m *MockTool) SetRunResult(result string) {
+	m.runResults = append(m.runResults, result)
+}
+
+// SetRunError makes every call of the tool fail with err.
+func (m *MockTool) SetRunError(err error) {
+	m.runError = err
+}
+
+// Calls returns the arguments of the calls of the tool, in order.
+func (m *MockTool) Calls() []map[string]any {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]map[string]any(nil), m.calls...)
+}
+
+// GetMockTool extracts the MockTool from a ToolDef (if it contains one)
+func GetMockTool(toolDef cogito.ToolDefinitionInterface) *MockTool {
+	if toolDefT, ok := toolDef.(*cogito.ToolDefinition[map[string]any]); ok {
+		if mockTool, ok := toolDefT.ToolRunner.(*MockTool); ok {
+			return mockTool
+		}
+	}
+	return nil
+}
+
+// SetRunResult sets the result for a mock tool within a ToolDef
+func SetRunResult(toolDef cogito.ToolDefinitionInterface, result string) {
+	if mockTool := GetMockTool(toolDef); mockTool != nil {
+		mockTool.SetRunResult(result)
+	}
+}
+
+// SetRunError sets an error for a mock tool within a ToolDef
+func SetRunError(toolDef cogito.ToolDefinitionInterface, err error) {
+	if mockTool := GetMockTool(toolDef); mockTool != nil {
+		mockTool.SetRunError(err)
+	}
+}
@@ -0,0 +1,112 @@
+package cogito
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// RunSummary holds the metrics of a run compared by CompareRuns.
+type RunSummary struct {
+	Iterations int
+	Tools      []string // tools called, in order
+	Usage      LLMUsage // cumulative token usage of the run
+	Outcome    string   // final assistant reply
+}
+
+// RunComparison is the difference between two runs, typically the same task
+// executed with different options. See CompareRuns.
+type RunComparison struct {
+	A, B RunSummary
+
+	OnlyInA, OnlyInB []string // tools called in one run but not in the other
+	SameTools        bool     // both runs called the same tools in the same order
+	SameOutcome      bool     // both runs produced the same final reply
+}
+
+// CompareRuns compares two runs (the fragments returned by ExecuteTools or
+// ExecutePlan) by tools chosen, iterations, token usage and outcome, to
+// quantify the effect of an option on a workload. Print the result for a
+// human-readable summary.
+func CompareRuns(a, b Fragment) RunComparison {
+	c := RunComparison{A: summarizeRun(a), B: summarizeRun(b)}
+	c.OnlyInA = toolsMissingFrom(c.A.Tools, c.B.Tools)
+	c.OnlyInB = toolsMissingFrom(c.B.Tools, c.A.Tools)
+	c.SameTools = slices.Equal(c.A.Tools, c.B.Tools)
+	c.SameOutcome = strings.TrimSpace(c.A.Outcome) == strings.TrimSpace(c.B.Outcome)
+	return c
+}
+
+func summarizeRun(f Fragment) RunSummary {
+	var s RunSummary
+	if f.Status != nil {
+		s.Iterations = f.Status.Iterations
+		s.Usage = f.Status.CumulativeUsage
+		if s.Usage == (LLMUsage{}) {
+			s.Usage = f.Status.LastUsage
+		}
+		for _, r := range f.Status.ToolResults {
+			s.Tools = append(s.Tools, r.Name)
+		}
+	}
+	for i := len(f.Messages) - 1; i >= 0; i-- {
+		msg := f.Messages[i]
+		if msg.Role == AssistantMessageRole.String() && len(msg.ToolCalls) == 0 && msg.Content != "" {
+			s.Outcome = msg.Content
+			break
+		}
+	}
+	return s
+}
+
+// toolsMissingFrom returns the distinct tools of a that never appear in b.
+func toolsMissingFrom(a, b []string) []string {
+	var out []string
+	for _, t := range a {
+		if !slices.Contains(b, t) && !slices.Contains(out, t) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// String returns a summary of the comparison, one metric per line.
+func (c RunComparison) String() string {
+	var b strings.Builder
+	delta := func(name string, a, bv int) {
+		line := fmt.Sprintf("%s: %d -> %d", name, a, bv)
+		if a != bv {
+			line += fmt.Sprintf(" (%+d", bv-a)
+			if a != 0 {
+				line += fmt.Sprintf(", %+.1f%%", float64(bv-a)*100/float64(a))
+			}
+			line += ")"
+		}
+		b.WriteString(line + "\n")
+	}
+
+	delta("Iterations", c.A.Iterations, c.B.Iterations)
+	delta("Tool calls", len(c.A.Tools), len(c.B.Tools))
+	delta("Prompt tokens", c.A.Usage.PromptTokens, c.B.Usage.PromptTokens)
+	delta("Completion tokens", c.A.Usage.CompletionTokens, c.B.Usage.CompletionTokens)
+	delta("Total tokens", c.A.Usage.TotalTokens, c.B.Usage.TotalTokens)
+
+	if c.SameTools {
+		b.WriteString("Tools: same sequence\n")
+	} else {
+		b.WriteString(fmt.Sprintf("Tools: [%s] -> [%s]\n", strings.Join(c.A.Tools, ", "), strings.Join(c.B.Tools, ", ")))
+		if len(c.OnlyInA) > 0 {
+			b.WriteString(fmt.Sprintf("Only in A: %s\n", strings.Join(c.OnlyInA, ", ")))
+		}
+		if len(c.OnlyInB) > 0 {
+			b.WriteString(fmt.Sprintf("Only in B: %s\n", strings.Join(c.OnlyInB, ", ")))
+		}
+	}
+
+	if c.SameOutcome {
+		b.WriteString("Outcome: same\n")
+	} else {
+		b.WriteString("Outcome: different\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,48 @@
+package cogito
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompareRuns(t *testing.T) {
+	a := NewEmptyFragment().AddMessage(UserMessageRole, "weather?").AddMessage(AssistantMessageRole, "Sunny.")
+	a.Status = &Status{
+		Iterations:      3,
+		CumulativeUsage: LLMUsage{PromptTokens: 900, CompletionTokens: 100, TotalTokens: 1000},
+		ToolResults:     []ToolStatus{{Name: "search"}, {Name: "fetch"}, {Name: "weather"}},
+	}
+	b := NewEmptyFragment().AddMessage(UserMessageRole, "weather?").AddMessage(AssistantMessageRole, "Sunny.")
+	b.Status = &Status{
+		Iterations:  1,
+		LastUsage:   LLMUsage{PromptTokens: 700, CompletionTokens: 50, TotalTokens: 750},
+		ToolResults: []ToolStatus{{Name: "weather"}, {Name: "forecast"}},
+	}
+
+	c := CompareRuns(a, b)
+	if c.SameTools || !c.SameOutcome {
+		t.Errorf("comparison = %+v", c)
+	}
+	if strings.Join(c.OnlyInA, ",") != "search,fetch" || strings.Join(c.OnlyInB, ",") != "forecast" {
+		t.Errorf("only in A = %v, only in B = %v", c.OnlyInA, c.OnlyInB)
+	}
+	if c.B.Usage.TotalTokens != 750 {
+		t.Errorf("usage falls back to the last call: %+v", c.B.Usage)
+	}
+
+	summary := c.String()
+	for _, want := range []string{
+		"Iterations: 3 -> 1 (-2, -66.7%)",
+		"Total tokens: 1000 -> 750 (-250, -25.0%)",
+		"Only in B: forecast",
+		"Outcome: same",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("summary is missing %q:\n%s", want, summary)
+		}
+	}
+
+	if same := CompareRuns(a, a); !same.SameTools || strings.Contains(same.String(), "(") {
+		t.Errorf("self comparison:\n%s", same.String())
+	}
+}
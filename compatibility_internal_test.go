@@ -0,0 +1,15 @@
+package cogito
+
+import "testing"
+
+func TestWithCompatibilityMode(t *testing.T) {
+	o := defaultOptions()
+	o.Apply(WithForceReasoning(), WithCompatibilityMode(), WithMaxRetries(3))
+	if o.forceReasoning || o.toolReasoner || o.maxAdjustmentAttempts != 1 || o.maxToolFollowUps != 1 ||
+		o.loopDetectionSteps != 2 || !o.contextShrinking || !o.textToolCalls {
+		t.Errorf("compatibility settings not applied: %+v", o)
+	}
+	if o.maxRetries != 3 {
+		t.Errorf("retries = %d, want the later option to win", o.maxRetries)
+	}
+}
@@ -0,0 +1,69 @@
+package cogito_test
+
+import (
+	"strings"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Compatibility mode", func() {
+	reply := func(content string) openai.ChatCompletionResponse {
+		return openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: content}},
+			},
+		}
+	}
+
+	It("calls tools through plain text replies", func() {
+		llm := cogitotest.NewMockLLM()
+		tool := cogitotest.NewMockTool("weather", "Get the weather")
+		cogitotest.SetRunResult(tool, "sunny")
+		llm.SetCreateChatCompletionResponse(reply("```json\n{\"name\": \"weather\", \"arguments\": {\"city\": \"Rome\"}}\n```"))
+		llm.SetCreateChatCompletionResponse(reply("It is sunny in Rome."))
+
+		result, err := ExecuteTools(llm, NewEmptyFragment().AddMessage(UserMessageRole, "What is the weather in Rome?"),
+			WithTools(tool), WithIterations(2), WithCompatibilityMode())
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolsCalled).To(HaveLen(1))
+		Expect(result.Status.ToolResults[0].ToolArguments.Arguments).To(HaveKeyWithValue("city", "Rome"))
+
+		Expect(llm.RequestHistory).To(HaveLen(2))
+		for _, req := range llm.RequestHistory {
+			Expect(req.Tools).To(BeEmpty())
+			Expect(req.ToolChoice).To(BeNil())
+			for _, msg := range req.Messages {
+				Expect(msg.Role).ToNot(Equal(openai.ChatMessageRoleTool))
+				Expect(msg.ToolCalls).To(BeEmpty())
+			}
+		}
+		Expect(llm.RequestHistory[0].Messages[0].Content).To(ContainSubstring(`weather: Get the weather`))
+
+		var sawResult bool
+		for _, msg := range llm.RequestHistory[1].Messages {
+			if msg.Role == UserMessageRole.String() && strings.Contains(msg.Content, "sunny") {
+				sawResult = true
+			}
+		}
+		Expect(sawResult).To(BeTrue())
+	})
+
+	It("extracts structures without the tools API", func() {
+		llm := cogitotest.NewMockLLM()
+		llm.SetCreateChatCompletionResponse(reply(`{"extract_boolean": true}`))
+
+		b, err := ExtractBoolean(llm, NewEmptyFragment().AddMessage(UserMessageRole, "Is the sky blue?"), WithCompatibilityMode())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(b.Boolean).To(BeTrue())
+
+		req := llm.RequestHistory[0]
+		Expect(req.Tools).To(BeEmpty())
+		Expect(req.ResponseFormat).ToNot(BeNil())
+		Expect(req.ResponseFormat.Type).To(Equal(openai.ChatCompletionResponseFormatTypeJSONObject))
+	})
+})
@@ -0,0 +1,139 @@
+package cogito
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// CompensationFunc undoes a successful call of a tool, given its arguments
+// and result, e.g. by deleting the ticket the call created. See
+// EnableCompensation.
+type CompensationFunc func(ctx context.Context, args map[string]any, result string) error
+
+// CompensatedTool is implemented by tools with a compensating action.
+// ToolDefinition implements it with its Compensation field; use
+// AddCompensation for other tools.
+type CompensatedTool interface {
+	ToolCompensation() CompensationFunc
+}
+
+// ToolCompensation implements CompensatedTool.
+func (t *ToolDefinition[T]) ToolCompensation() CompensationFunc {
+	return t.Compensation
+}
+
+// AddCompensation returns tool with the compensating action fn, for tools not
+// built with ToolDefinition (MCP, OpenAPI, ...).
+func AddCompensation(tool ToolDefinitionInterface, fn CompensationFunc) ToolDefinitionInterface {
+	return &compensatedTool{ToolDefinitionInterface: tool, compensation: fn}
+}
+
+type compensatedTool struct {
+	ToolDefinitionInterface
+	compensation CompensationFunc
+}
+
+func (t *compensatedTool) ToolCompensation() CompensationFunc { return t.compensation }
+
+func (t *compensatedTool) ToolConcurrency() ToolConcurrency {
+	return toolConcurrency(t.ToolDefinitionInterface)
+}
+
+func (t *compensatedTool) OutputJSONSchema() *jsonschema.Definition {
+	return toolOutputSchema(t.ToolDefinitionInterface)
+}
+
+func (t *compensatedTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	return executeTool(ctx, t.ToolDefinitionInterface, args)
+}
+
+func (t *compensatedTool) unwrapTool() ToolDefinitionInterface { return t.ToolDefinitionInterface }
+
+// toolCompensation returns the compensating action of tool, or of the tool
+// it wraps, if any.
+func toolCompensation(tool ToolDefinitionInterface) CompensationFunc {
+	for tool != nil {
+		if t, ok := tool.(CompensatedTool); ok && t.ToolCompensation() != nil {
+			return t.ToolCompensation()
+		}
+		w, ok := tool.(toolWrapper)
+		if !ok {
+			break
+		}
+		tool = w.unwrapTool()
+	}
+	return nil
+}
+
+// CompensationStatus is the outcome of a compensating action, recorded in
+// Status.Compensations.
+type CompensationStatus struct {
+	Name      string         // tool whose call was undone
+	Arguments map[string]any // arguments of the call
+	Result    string         // result of the call
+	Error     string         // why the compensation failed, empty on success
+	Time      time.Time
+}
+
+// compensationLog records the successful calls of tools with a compensating
+// action during a run, shared with its plans and sub-agents.
+type compensationLog struct {
+	mu      sync.Mutex
+	entries []compensationEntry
+}
+
+type compensationEntry struct {
+	id     string // tool call ID
+	name   string
+	args   map[string]any
+	result string
+	fn     CompensationFunc
+}
+
+func (l *compensationLog) add(id, name string, args map[string]any, result string, fn CompensationFunc) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, compensationEntry{id: id, name: name, args: args, result: result, fn: fn})
+}
+
+// forget removes the recorded call with the given ID, name and result, once
+// compensated by a transaction.
+func (l *compensationLog) forget(id, name, result string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if e := l.entries[i]; e.id == id && e.name == name && e.result == result {
+			l.entries = slices.Delete(l.entries, i, i+1)
+			return
+		}
+	}
+}
+
+// compensate runs the compensating actions of the recorded calls, the most
+// recent first, and forgets them. They run even when the run was cancelled.
+func (l *compensationLog) compensate(o *Options) []CompensationStatus {
+	l.mu.Lock()
+	entries := l.entries
+	l.entries = nil
+	l.mu.Unlock()
+
+	ctx := context.WithoutCancel(o.context)
+	var statuses []CompensationStatus
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		status := CompensationStatus{Name: e.name, Arguments: e.args, Result: e.result}
+		if err := e.fn(ctx, e.args, e.result); err != nil {
+			o.logger.Error("Compensation failed", "tool", e.name, "error", err)
+			status.Error = err.Error()
+		} else {
+			o.logger.Info("Compensated tool call", "tool", e.name)
+		}
+		status.Time = time.Now()
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
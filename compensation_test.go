@@ -0,0 +1,97 @@
+package cogito_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type ticketArgs struct {
+	Title string `json:"title"`
+}
+
+type ticketCreator struct{}
+
+func (ticketCreator) Run(args ticketArgs) (string, any, error) {
+	return "created ticket " + args.Title, nil, nil
+}
+
+var _ = Describe("Compensation", func() {
+	var (
+		undone []string
+		tools  Tools
+		llm    *cogitotest.MockLLM
+	)
+
+	BeforeEach(func() {
+		undone = nil
+		tickets := &ToolDefinition[ticketArgs]{
+			ToolRunner:     ticketCreator{},
+			InputArguments: ticketArgs{},
+			Name:           "create_ticket",
+			Description:    "Create a ticket",
+			Compensation: func(ctx context.Context, args map[string]any, result string) error {
+				undone = append(undone, "delete "+args["title"].(string))
+				return nil
+			},
+		}
+		notify := cogitotest.NewMockTool("notify", "Notify the team")
+		cogitotest.SetRunResult(notify, "notified")
+		tools = Tools{tickets, AddCompensation(notify, func(ctx context.Context, args map[string]any, result string) error {
+			undone = append(undone, "retract "+result)
+			return errors.New("message already read")
+		})}
+
+		llm = cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("create_ticket", `{"title": "bug"}`)
+		llm.AddCreateChatCompletionFunction("notify", `{}`)
+		llm.AddCreateChatCompletionFunction("create_ticket", `{"title": "follow-up"}`)
+		llm.SetAskResponse("Done.")
+	})
+
+	abortThirdCall := func() Option {
+		calls := 0
+		return WithToolCallBack(func(*ToolChoice, *SessionState) ToolCallDecision {
+			calls++
+			return ToolCallDecision{Approved: calls < 3}
+		})
+	}
+
+	It("undoes the calls of an aborted run in reverse order", func() {
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "File the bug")
+		result, err := ExecuteTools(llm, f, WithTools(tools...), WithIterations(5), abortThirdCall(), EnableCompensation)
+		Expect(err).To(MatchError(ErrToolCallCallbackInterrupted))
+
+		Expect(undone).To(Equal([]string{"retract notified", "delete bug"}))
+		Expect(result.Status.Compensations).To(HaveLen(2))
+		Expect(result.Status.Compensations[0].Name).To(Equal("notify"))
+		Expect(result.Status.Compensations[0].Error).To(Equal("message already read"))
+		Expect(result.Status.Compensations[1].Name).To(Equal("create_ticket"))
+		Expect(result.Status.Compensations[1].Arguments).To(Equal(map[string]any{"title": "bug"}))
+		Expect(result.Status.Compensations[1].Result).To(Equal("created ticket bug"))
+		Expect(result.Status.Compensations[1].Error).To(BeEmpty())
+	})
+
+	It("does not compensate without EnableCompensation", func() {
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "File the bug")
+		result, err := ExecuteTools(llm, f, WithTools(tools...), WithIterations(5), abortThirdCall())
+		Expect(err).To(MatchError(ErrToolCallCallbackInterrupted))
+		Expect(undone).To(BeEmpty())
+		Expect(result.Status.Compensations).To(BeEmpty())
+	})
+
+	It("keeps the side effects of successful runs", func() {
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Filed."},
+		}}})
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "File the bug")
+		_, err := ExecuteTools(llm, f, WithTools(tools...), WithIterations(5), EnableCompensation)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(undone).To(BeEmpty())
+	})
+})
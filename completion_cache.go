@@ -0,0 +1,242 @@
+package cogito
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// CompletionCache stores chat completion replies so identical (or, for
+// semantic caches, near-identical) requests are answered without calling the
+// model. See WithCompletionCache.
+type CompletionCache interface {
+	// Get returns the reply cached for req, if any.
+	Get(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, bool)
+	// Put caches reply as the answer to req.
+	Put(ctx context.Context, req openai.ChatCompletionRequest, reply LLMReply)
+}
+
+// CompletionKey hashes the parts of req that determine the reply: the model,
+// the messages, the tools and the tool choice and response format.
+func CompletionKey(req openai.ChatCompletionRequest) string {
+	return hashRequest(req, true)
+}
+
+// hashRequest hashes req, leaving out the messages unless withMessages.
+func hashRequest(req openai.ChatCompletionRequest, withMessages bool) string {
+	key := struct {
+		Model          string                               `json:"model"`
+		Messages       []openai.ChatCompletionMessage       `json:"messages,omitempty"`
+		Tools          []openai.Tool                        `json:"tools,omitempty"`
+		ToolChoice     any                                  `json:"tool_choice,omitempty"`
+		ResponseFormat *openai.ChatCompletionResponseFormat `json:"response_format,omitempty"`
+		Seed           *int                                 `json:"seed,omitempty"`
+	}{
+		Model:          req.Model,
+		Tools:          req.Tools,
+		ToolChoice:     req.ToolChoice,
+		ResponseFormat: req.ResponseFormat,
+		Seed:           req.Seed,
+	}
+	if withMessages {
+		key.Messages = req.Messages
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// MemoryCompletionCache is an in-memory CompletionCache matching requests
+// exactly, by CompletionKey and tenant (see ContextWithTenant). Safe for
+// concurrent use.
+type MemoryCompletionCache struct {
+	mu      sync.Mutex
+	entries map[string]LLMReply
+}
+
+var _ CompletionCache = (*MemoryCompletionCache)(nil)
+
+func NewMemoryCompletionCache() *MemoryCompletionCache {
+	return &MemoryCompletionCache{entries: map[string]LLMReply{}}
+}
+
+func (c *MemoryCompletionCache) Get(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, bool) {
+	key := CompletionKey(req)
+	if key == "" {
+		return LLMReply{}, false
+	}
+	key = tenantScoped(ctx, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	reply, ok := c.entries[key]
+	return cloneLLMReply(reply), ok
+}
+
+func (c *MemoryCompletionCache) Put(ctx context.Context, req openai.ChatCompletionRequest, reply LLMReply) {
+	key := CompletionKey(req)
+	if key == "" {
+		return
+	}
+	key = tenantScoped(ctx, key)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = cloneLLMReply(reply)
+}
+
+// SemanticCompletionCache is an in-memory CompletionCache matching requests
+// by the embedding of their messages: a request is answered from the cache
+// when an earlier one with the same model, tools, response format and tenant
+// has messages with a cosine similarity of at least Threshold. Embedding errors
+// are logged and treated as cache misses. Safe for concurrent use.
+type SemanticCompletionCache struct {
+	Threshold float64
+
+	embed   EmbeddingFunc
+	mu      sync.Mutex
+	entries map[string][]semanticEntry
+}
+
+type semanticEntry struct {
+	embedding []float32
+	reply     LLMReply
+}
+
+var _ CompletionCache = (*SemanticCompletionCache)(nil)
+
+// NewSemanticCompletionCache returns a SemanticCompletionCache using embed
+// to compare requests.
+func NewSemanticCompletionCache(embed EmbeddingFunc, threshold float64) *SemanticCompletionCache {
+	return &SemanticCompletionCache{Threshold: threshold, embed: embed, entries: map[string][]semanticEntry{}}
+}
+
+func (c *SemanticCompletionCache) Get(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, bool) {
+	embedding, ok := c.embedMessages(ctx, req)
+	if !ok {
+		return LLMReply{}, false
+	}
+
+	key := tenantScoped(ctx, hashRequest(req, false))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var reply LLMReply
+	found, bestSim := false, c.Threshold
+	for _, e := range c.entries[key] {
+		if sim := cosineSimilarity(embedding, e.embedding); sim >= bestSim {
+			reply, found, bestSim = e.reply, true, sim
+		}
+	}
+	return cloneLLMReply(reply), found
+}
+
+func (c *SemanticCompletionCache) Put(ctx context.Context, req openai.ChatCompletionRequest, reply LLMReply) {
+	embedding, ok := c.embedMessages(ctx, req)
+	if !ok {
+		return
+	}
+	key := tenantScoped(ctx, hashRequest(req, false))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries[key] = append(c.entries[key], semanticEntry{embedding: embedding, reply: cloneLLMReply(reply)})
+}
+
+// cloneLLMReply returns a copy of reply sharing no memory with it, so
+// callers editing a cached reply, e.g. to parse tool calls out of its
+// content, don't change the cache.
+func cloneLLMReply(reply LLMReply) LLMReply {
+	choices := slices.Clone(reply.ChatCompletionResponse.Choices)
+	for i := range choices {
+		m := &choices[i].Message
+		m.MultiContent = slices.Clone(m.MultiContent)
+		m.ToolCalls = slices.Clone(m.ToolCalls)
+		if m.FunctionCall != nil {
+			call := *m.FunctionCall
+			m.FunctionCall = &call
+		}
+		if choices[i].LogProbs != nil {
+			logProbs := *choices[i].LogProbs
+			logProbs.Content = slices.Clone(logProbs.Content)
+			choices[i].LogProbs = &logProbs
+		}
+	}
+	reply.ChatCompletionResponse.Choices = choices
+	return reply
+}
+
+func (c *SemanticCompletionCache) embedMessages(ctx context.Context, req openai.ChatCompletionRequest) ([]float32, bool) {
+	if c.embed == nil {
+		return nil, false
+	}
+	var b strings.Builder
+	for _, m := range req.Messages {
+		b.WriteString(m.Role + ": " + m.Content + "\n")
+	}
+	embedding, err := c.embed(ctx, b.String())
+	if err != nil {
+		LoggerFromContext(ctx).Warn("Completion cache: failed to embed messages", "error", err)
+		return nil, false
+	}
+	return embedding, true
+}
+
+// cachedLLM wraps an LLM, answering chat completions from cache when
+// possible. Ask and streaming calls are not cached.
+type cachedLLM struct {
+	LLM
+	cache  CompletionCache
+	logger Logger
+}
+
+func (c *cachedLLM) unwrap() LLM { return c.LLM }
+
+func (c *cachedLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	ctx = contextWithLogger(ctx, c.logger)
+	if reply, ok := c.cache.Get(ctx, req); ok {
+		return reply, LLMUsage{}, nil
+	}
+	reply, usage, err := c.LLM.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return reply, usage, err
+	}
+	c.cache.Put(ctx, req, reply)
+	return reply, usage, nil
+}
+
+// cachedStreamingLLM preserves StreamingLLM.
+type cachedStreamingLLM struct {
+	cachedLLM
+	streaming StreamingLLM
+}
+
+func (c *cachedStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	return c.streaming.CreateChatCompletionStream(ctx, req)
+}
+
+// withCompletionCache wraps llm with the completion cache of o, if any. An
+// LLM already cached is returned as is.
+func withCompletionCache(llm LLM, o *Options) LLM {
+	if o.completionCache == nil || llm == nil {
+		return llm
+	}
+	if _, ok := unwrapLLM[*cachedLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*cachedStreamingLLM](llm); ok {
+		return llm
+	}
+	base := cachedLLM{LLM: llm, cache: o.completionCache, logger: o.logger}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &cachedStreamingLLM{cachedLLM: base, streaming: s}
+	}
+	return &base
+}
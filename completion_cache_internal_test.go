@@ -0,0 +1,128 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+type callCountingLLM struct {
+	fakeLLM
+	calls int
+	err   error
+}
+
+func (c *callCountingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	c.calls++
+	if c.err != nil {
+		return LLMReply{}, LLMUsage{}, c.err
+	}
+	return c.fakeLLM.CreateChatCompletion(ctx, req)
+}
+
+func chatRequest(content string, tools ...string) openai.ChatCompletionRequest {
+	req := openai.ChatCompletionRequest{
+		Model:    "test",
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: content}},
+	}
+	for _, t := range tools {
+		req.Tools = append(req.Tools, openai.Tool{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: t}})
+	}
+	return req
+}
+
+func TestMemoryCompletionCacheMatchesExactRequests(t *testing.T) {
+	inner := &callCountingLLM{fakeLLM: fakeLLM{ccUsage: LLMUsage{TotalTokens: 10}}}
+	o := defaultOptions()
+	o.Apply(WithCompletionCache(NewMemoryCompletionCache()))
+	llm := withLLMOptions(inner, o)
+	ctx := context.Background()
+
+	if _, usage, _ := llm.CreateChatCompletion(ctx, chatRequest("is it raining?", "search")); usage.TotalTokens != 10 {
+		t.Fatalf("first call usage = %+v, want the model's", usage)
+	}
+	_, usage, err := llm.CreateChatCompletion(ctx, chatRequest("is it raining?", "search"))
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if inner.calls != 1 || usage.TotalTokens != 0 {
+		t.Fatalf("calls = %d, usage = %+v; want the second call served from cache", inner.calls, usage)
+	}
+
+	llm.CreateChatCompletion(ctx, chatRequest("is it raining?", "weather"))
+	llm.CreateChatCompletion(ctx, chatRequest("is it sunny?", "search"))
+	if inner.calls != 3 {
+		t.Fatalf("calls = %d, want different tools or messages to miss the cache", inner.calls)
+	}
+}
+
+func TestCompletionCacheSkipsErrors(t *testing.T) {
+	inner := &callCountingLLM{err: errors.New("boom")}
+	llm := &cachedLLM{LLM: inner, cache: NewMemoryCompletionCache()}
+
+	for range 2 {
+		if _, _, err := llm.CreateChatCompletion(context.Background(), chatRequest("hi")); err == nil {
+			t.Fatalf("expected the error to be returned")
+		}
+	}
+	if inner.calls != 2 {
+		t.Fatalf("calls = %d, want failed completions not cached", inner.calls)
+	}
+}
+
+func TestSemanticCompletionCacheMatchesSimilarRequests(t *testing.T) {
+	// Embeds text as (mentions rain, mentions sun).
+	embed := func(_ context.Context, text string) ([]float32, error) {
+		v := []float32{0.1, 0.1}
+		if strings.Contains(text, "rain") {
+			v[0] = 1
+		}
+		if strings.Contains(text, "sun") {
+			v[1] = 1
+		}
+		return v, nil
+	}
+	inner := &callCountingLLM{}
+	llm := &cachedLLM{LLM: inner, cache: NewSemanticCompletionCache(embed, 0.95)}
+	ctx := context.Background()
+
+	llm.CreateChatCompletion(ctx, chatRequest("is it raining?", "search"))
+	llm.CreateChatCompletion(ctx, chatRequest("will it rain today?", "search"))
+	if inner.calls != 1 {
+		t.Fatalf("calls = %d, want the similar request served from cache", inner.calls)
+	}
+
+	llm.CreateChatCompletion(ctx, chatRequest("is it sunny?", "search"))
+	llm.CreateChatCompletion(ctx, chatRequest("will it rain today?", "weather"))
+	if inner.calls != 3 {
+		t.Fatalf("calls = %d, want dissimilar messages or other tools to miss the cache", inner.calls)
+	}
+}
+
+func TestCompletionCachesCopyReplies(t *testing.T) {
+	ctx := context.Background()
+	embed := func(ctx context.Context, text string) ([]float32, error) { return []float32{1, 0}, nil }
+	caches := map[string]CompletionCache{
+		"memory":   NewMemoryCompletionCache(),
+		"semantic": NewSemanticCompletionCache(embed, 0.9),
+	}
+	for name, cache := range caches {
+		reply := LLMReply{ChatCompletionResponse: openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "1"}}},
+		}}}}
+		cache.Put(ctx, chatRequest("hi"), reply)
+		reply.ChatCompletionResponse.Choices[0].Message.Content = "changed after Put"
+
+		got, _ := cache.Get(ctx, chatRequest("hi"))
+		got.ChatCompletionResponse.Choices[0].Message.Content = "changed after Get"
+		got.ChatCompletionResponse.Choices[0].Message.ToolCalls[0].ID = "2"
+
+		got, _ = cache.Get(ctx, chatRequest("hi"))
+		if m := got.ChatCompletionResponse.Choices[0].Message; m.Content != "" || m.ToolCalls[0].ID != "1" {
+			t.Errorf("%s cache: cached reply = %+v, want it unchanged", name, m)
+		}
+	}
+}
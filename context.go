@@ -0,0 +1,76 @@
+package cogito
+
+import (
+	"context"
+
+	"github.com/mudler/cogito/structures"
+)
+
+// Context-first variants of the public API. Each is equivalent to the
+// option-based function with WithContext(ctx) applied last, so ctx takes
+// precedence over any context passed in opts. The context is used for every
+// LLM call and, for tools implementing ToolWithContext, every tool call.
+
+func withCtx(ctx context.Context, opts []Option) []Option {
+	return append(append([]Option{}, opts...), WithContext(ctx))
+}
+
+// ExecuteToolsContext is ExecuteTools with an explicit context.
+func ExecuteToolsContext(ctx context.Context, llm LLM, f Fragment, opts ...Option) (Fragment, error) {
+	return ExecuteTools(llm, f, withCtx(ctx, opts)...)
+}
+
+// ExecutePlanContext is ExecutePlan with an explicit context.
+func ExecutePlanContext(ctx context.Context, llm LLM, conv Fragment, plan *structures.Plan, goal *structures.Goal, opts ...Option) (Fragment, error) {
+	return ExecutePlan(llm, conv, plan, goal, withCtx(ctx, opts)...)
+}
+
+// ExtractPlanContext is ExtractPlan with an explicit context.
+func ExtractPlanContext(ctx context.Context, llm LLM, f Fragment, goal *structures.Goal, opts ...Option) (*structures.Plan, error) {
+	return ExtractPlan(llm, f, goal, withCtx(ctx, opts)...)
+}
+
+// ReEvaluatePlanContext is ReEvaluatePlan with an explicit context.
+func ReEvaluatePlanContext(ctx context.Context, llm LLM, f, subtaskFragment Fragment, goal *structures.Goal, toolStatuses []ToolStatus, subtask string, opts ...Option) (*structures.Plan, error) {
+	return ReEvaluatePlan(llm, f, subtaskFragment, goal, toolStatuses, subtask, withCtx(ctx, opts)...)
+}
+
+// ExtractTODOsContext is ExtractTODOs with an explicit context.
+func ExtractTODOsContext(ctx context.Context, llm LLM, plan *structures.Plan, goal *structures.Goal, opts ...Option) (*structures.TODOList, error) {
+	return ExtractTODOs(llm, plan, goal, withCtx(ctx, opts)...)
+}
+
+// ContentReviewContext is ContentReview with an explicit context.
+func ContentReviewContext(ctx context.Context, llm LLM, originalFragment Fragment, opts ...Option) (Fragment, error) {
+	return ContentReview(llm, originalFragment, withCtx(ctx, opts)...)
+}
+
+// ExtractGoalContext is ExtractGoal with an explicit context.
+func ExtractGoalContext(ctx context.Context, llm LLM, f Fragment, opts ...Option) (*structures.Goal, error) {
+	return ExtractGoal(llm, f, withCtx(ctx, opts)...)
+}
+
+// IsGoalAchievedContext is IsGoalAchieved with an explicit context.
+func IsGoalAchievedContext(ctx context.Context, llm LLM, f Fragment, goal *structures.Goal, opts ...Option) (*structures.Boolean, error) {
+	return IsGoalAchieved(llm, f, goal, withCtx(ctx, opts)...)
+}
+
+// ExtractBooleanContext is ExtractBoolean with an explicit context.
+func ExtractBooleanContext(ctx context.Context, llm LLM, f Fragment, opts ...Option) (*structures.Boolean, error) {
+	return ExtractBoolean(llm, f, withCtx(ctx, opts)...)
+}
+
+// ExtractKnowledgeGapsContext is ExtractKnowledgeGaps with an explicit context.
+func ExtractKnowledgeGapsContext(ctx context.Context, llm LLM, f Fragment, opts ...Option) ([]string, error) {
+	return ExtractKnowledgeGaps(llm, f, withCtx(ctx, opts)...)
+}
+
+// GetRelevantGuidelinesContext is GetRelevantGuidelines with an explicit context.
+func GetRelevantGuidelinesContext(ctx context.Context, llm LLM, guidelines Guidelines, fragment Fragment, opts ...Option) (Guidelines, error) {
+	return GetRelevantGuidelines(llm, guidelines, fragment, withCtx(ctx, opts)...)
+}
+
+// ResumeContext is Resume with an explicit context.
+func (s *SessionState) ResumeContext(ctx context.Context, llm LLM, opts ...Option) (Fragment, error) {
+	return s.Resume(llm, withCtx(ctx, opts)...)
+}
@@ -0,0 +1,110 @@
+package cogito
+
+import (
+	"context"
+	"maps"
+
+	"github.com/mudler/cogito/prompt"
+)
+
+// ContextBuilder selects the part of a conversation given as context to a
+// prompt, to control its token usage. It returns the fragment serialized in
+// place of f. See WithContextBuilder.
+type ContextBuilder func(ctx context.Context, f Fragment) (Fragment, error)
+
+// LastMessagesContext keeps the last n messages of the conversation.
+func LastMessagesContext(n int) ContextBuilder {
+	return func(_ context.Context, f Fragment) (Fragment, error) {
+		if len(f.Messages) > n {
+			start := len(f.Messages) - n
+			f.Messages = f.Messages[start:]
+			if len(f.Metadata) > start {
+				f.Metadata = f.Metadata[start:]
+			} else {
+				f.Metadata = nil
+			}
+		}
+		return f, nil
+	}
+}
+
+// RoleContext keeps the messages of the given roles, e.g. RoleContext(UserMessageRole)
+// for the user messages only or RoleContext(ToolMessageRole) for the tool
+// results only.
+func RoleContext(roles ...MessageRole) ContextBuilder {
+	return func(_ context.Context, f Fragment) (Fragment, error) {
+		kept := f
+		kept.Messages, kept.Metadata = nil, nil
+		for i, msg := range f.Messages {
+			for _, role := range roles {
+				if msg.Role == role.String() {
+					kept.Messages = append(kept.Messages, msg)
+					kept.Metadata = append(kept.Metadata, f.MessageMetadata(i))
+					break
+				}
+			}
+		}
+		return kept, nil
+	}
+}
+
+// SummarizedContext keeps the last keepMessages messages of the conversation
+// and replaces the rest with a summary written by llm, with the conversation
+// compaction prompt. The summary costs an LLM call for every prompt it is
+// used for. opts can set the prompts and the logger.
+func SummarizedContext(llm LLM, keepMessages int, opts ...Option) ContextBuilder {
+	o := defaultOptions()
+	o.Apply(opts...)
+	return func(ctx context.Context, f Fragment) (Fragment, error) {
+		if len(f.Messages) <= keepMessages {
+			return f, nil
+		}
+		return compactFragment(ctx, llm, f, keepMessages, o.prompts, o.logger)
+	}
+}
+
+// WithContextBuilder sets the builder of the conversation context of the
+// prompts of the given types, e.g. of prompt.PromptPlanType, or of all the
+// prompts given the conversation as context when no type is given. Builders
+// set for a type take precedence over the one for all prompts:
+//
+//	cogito.WithContextBuilder(cogito.LastMessagesContext(6)),
+//	cogito.WithContextBuilder(cogito.SummarizedContext(llm, 6), prompt.PromptPlanType),
+func WithContextBuilder(builder ContextBuilder, types ...prompt.PromptType) func(o *Options) {
+	return func(o *Options) {
+		if len(types) == 0 {
+			o.contextBuilder = builder
+			return
+		}
+		// Options are copied into plans, do not share the map
+		builders := maps.Clone(o.contextBuilders)
+		if builders == nil {
+			builders = map[prompt.PromptType]ContextBuilder{}
+		}
+		for _, t := range types {
+			builders[t] = builder
+		}
+		o.contextBuilders = builders
+	}
+}
+
+// conversationContext serializes the conversation of f as the context of a
+// prompt of type t, through the ContextBuilder set for it. Hidden messages,
+// and those excluded by WithMessageFilter, are left out. The whole
+// conversation is used when the builder fails.
+func conversationContext(o *Options, t prompt.PromptType, f Fragment) string {
+	f = promptFragment(o, f)
+	builder, ok := o.contextBuilders[t]
+	if !ok {
+		builder = o.contextBuilder
+	}
+	if builder == nil {
+		return f.String()
+	}
+	built, err := builder(o.context, f)
+	if err != nil {
+		o.logger.Warn("Failed to build the prompt context, using the whole conversation", "prompt", t, "error", err)
+		return f.String()
+	}
+	return built.String()
+}
@@ -0,0 +1,61 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	"github.com/mudler/cogito/prompt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Context builders", func() {
+	var conv Fragment
+
+	BeforeEach(func() {
+		conv = NewEmptyFragment().
+			AddMessage(UserMessageRole, "I am planning a trip to Japan").
+			AddMessage(AssistantMessageRole, "Great, which cities?").
+			AddMessage(UserMessageRole, "Find the best time to visit Kyoto")
+	})
+
+	extractGoal := func(opts ...Option) string {
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.SetAskResponse("The goal is to find the best time to visit Kyoto")
+		mockLLM.AddCreateChatCompletionFunction("json", `{"goal": "Find the best time to visit Kyoto"}`)
+		goal, err := ExtractGoal(mockLLM, conv, opts...)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(goal.Goal).To(Equal("Find the best time to visit Kyoto"))
+		return mockLLM.FragmentHistory[0].LastMessage().Content
+	}
+
+	It("serializes the whole conversation by default", func() {
+		Expect(extractGoal()).To(ContainSubstring("trip to Japan"))
+	})
+
+	It("builds the context of the prompt types it is set for", func() {
+		goalPrompt := extractGoal(WithContextBuilder(LastMessagesContext(1), prompt.PromptIdentifyGoalType))
+		Expect(goalPrompt).ToNot(ContainSubstring("trip to Japan"))
+		Expect(goalPrompt).To(ContainSubstring("best time to visit Kyoto"))
+
+		Expect(extractGoal(WithContextBuilder(LastMessagesContext(1), prompt.PromptPlanType))).
+			To(ContainSubstring("trip to Japan"))
+	})
+
+	It("prefers the builder of the prompt type to the one for all prompts", func() {
+		goalPrompt := extractGoal(
+			WithContextBuilder(LastMessagesContext(1)),
+			WithContextBuilder(RoleContext(AssistantMessageRole), prompt.PromptIdentifyGoalType))
+		Expect(goalPrompt).To(ContainSubstring("which cities?"))
+		Expect(goalPrompt).ToNot(ContainSubstring("Kyoto"))
+	})
+
+	It("summarizes the older messages", func() {
+		summarizer := cogitotest.NewMockLLM()
+		summarizer.SetAskResponse("The user is planning a trip to Japan.")
+
+		goalPrompt := extractGoal(WithContextBuilder(SummarizedContext(summarizer, 1)))
+		Expect(goalPrompt).To(ContainSubstring("The user is planning a trip to Japan."))
+		Expect(goalPrompt).ToNot(ContainSubstring("which cities?"))
+		Expect(goalPrompt).To(ContainSubstring("best time to visit Kyoto"))
+	})
+})
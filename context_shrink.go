@@ -0,0 +1,336 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ContextDegradation records a prompt that had to be shrunk because the
+// provider rejected it for exceeding the model context.
+type ContextDegradation struct {
+	Stage          string // shrink stage that was applied
+	MessagesBefore int
+	MessagesAfter  int
+	Recovered      bool // true if the shrunk request succeeded
+}
+
+// IsContextLengthError reports whether err is a provider error caused by a
+// prompt exceeding the model context window.
+func IsContextLengthError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		if code, ok := apiErr.Code.(string); ok && code == "context_length_exceeded" {
+			return true
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	for _, needle := range []string{
+		"context_length_exceeded",
+		"context length",
+		"maximum context",
+		"context window",
+		"exceeds the available context size",
+	} {
+		if strings.Contains(msg, needle) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	shrinkToolResultChars = 1000
+	shrinkKeepMessages    = 6
+)
+
+// shrinker applies the shrink stages to a conversation, summarizing tool
+// results with llm.
+type shrinker struct {
+	llm     LLM
+	prompts prompt.PromptMap
+	logger  Logger
+}
+
+// newShrinker returns a shrinker summarizing with llm, accumulating the
+// usage of the summaries into usage when not nil.
+func newShrinker(llm LLM, prompts prompt.PromptMap, usage *usageCounter, logger Logger) shrinker {
+	if usage != nil {
+		llm = newCountingLLM(llm, usage)
+	}
+	return shrinker{llm: llm, prompts: prompts, logger: logger}
+}
+
+// shrinkStage progressively reduces a conversation. Stages are cumulative:
+// each one is applied on top of the previous.
+type shrinkStage struct {
+	name  string
+	apply func(ctx context.Context, s shrinker, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage
+}
+
+// messagesStage adapts a stage rewriting the messages on their own.
+func messagesStage(apply func([]openai.ChatCompletionMessage) []openai.ChatCompletionMessage) func(context.Context, shrinker, []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	return func(_ context.Context, _ shrinker, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+		return apply(messages)
+	}
+}
+
+var shrinkStages = []shrinkStage{
+	{name: "drop_additional_context", apply: messagesStage(dropAdditionalContext)},
+	{name: "summarize_tool_results", apply: summarizeToolResults},
+	{name: "trim_history", apply: messagesStage(trimHistory)},
+}
+
+// dropAdditionalContext removes the system messages injected after the first
+// one (guidelines, MCP prompts, reflections, deep context), keeping the
+// leading system prompt.
+func dropAdditionalContext(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	out := make([]openai.ChatCompletionMessage, 0, len(messages))
+	for i, msg := range messages {
+		if msg.Role == SystemMessageRole.String() && i > 0 {
+			continue
+		}
+		out = append(out, msg)
+	}
+	return out
+}
+
+// summarizeToolResults replaces the tool results longer than
+// shrinkToolResultChars bytes with a summary written by the LLM.
+func summarizeToolResults(ctx context.Context, s shrinker, messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	out := make([]openai.ChatCompletionMessage, len(messages))
+	calls := map[string]openai.FunctionCall{}
+	for i, msg := range messages {
+		for _, tc := range msg.ToolCalls {
+			calls[tc.ID] = tc.Function
+		}
+		if msg.Role == ToolMessageRole.String() && len(msg.Content) > shrinkToolResultChars {
+			msg.Content = s.summarizeToolResult(ctx, calls[msg.ToolCallID], msg.Content)
+		}
+		out[i] = msg
+	}
+	return out
+}
+
+// summarizeToolResult returns a summary of the result of call, at most
+// shrinkToolResultChars bytes long. Results that can't be summarized, for
+// instance as they exceed the context window on their own, are truncated.
+func (s shrinker) summarizeToolResult(ctx context.Context, call openai.FunctionCall, result string) string {
+	summary, err := s.summarize(ctx, call, result)
+	if err != nil {
+		s.logger.Warn("Failed to summarize tool result, truncating it", "tool", call.Name, "error", err)
+		return truncateUTF8(result, shrinkToolResultChars) + "\n[... truncated to fit the context window]"
+	}
+	return truncateUTF8(summary, shrinkToolResultChars)
+}
+
+func (s shrinker) summarize(ctx context.Context, call openai.FunctionCall, result string) (string, error) {
+	p, err := s.prompts.GetPrompt(prompt.PromptToolResultSummaryType).Render(struct {
+		Tool      string
+		Arguments string
+		Result    string
+	}{
+		Tool:      call.Name,
+		Arguments: call.Arguments,
+		Result:    result,
+	})
+	if err != nil {
+		return "", err
+	}
+	res, err := s.llm.Ask(ctx, NewEmptyFragment().AddMessage(UserMessageRole, p))
+	if err != nil {
+		return "", err
+	}
+	reply := res.LastMessage()
+	if reply == nil || reply.Role != AssistantMessageRole.String() || strings.TrimSpace(reply.Content) == "" {
+		return "", errors.New("empty summary")
+	}
+	return strings.TrimSpace(reply.Content), nil
+}
+
+// truncateUTF8 returns the first n bytes of s at most, cut on a rune
+// boundary.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// trimHistory keeps the leading system prompt, the first user message and the
+// most recent messages. Leading tool messages whose tool call was trimmed away
+// are dropped so the history stays valid.
+func trimHistory(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	if len(messages) <= shrinkKeepMessages+2 {
+		return messages
+	}
+
+	var head []openai.ChatCompletionMessage
+	rest := messages
+	if rest[0].Role == SystemMessageRole.String() {
+		head = append(head, rest[0])
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0].Role == UserMessageRole.String() {
+		head = append(head, rest[0])
+		rest = rest[1:]
+	}
+
+	if len(rest) > shrinkKeepMessages {
+		rest = rest[len(rest)-shrinkKeepMessages:]
+	}
+	for len(rest) > 0 && rest[0].Role == ToolMessageRole.String() {
+		rest = rest[1:]
+	}
+
+	return append(head, rest...)
+}
+
+// degradationLog collects the degradations applied during a run. Safe for
+// concurrent use.
+type degradationLog struct {
+	mu      sync.Mutex
+	entries []ContextDegradation
+}
+
+func (d *degradationLog) add(e ContextDegradation) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries = append(d.entries, e)
+}
+
+func (d *degradationLog) snapshot() []ContextDegradation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.entries) == 0 {
+		return nil
+	}
+	out := make([]ContextDegradation, len(d.entries))
+	copy(out, d.entries)
+	return out
+}
+
+// shrinkingLLM wraps an LLM, retrying requests rejected for exceeding the
+// context window with progressively shrunk conversations.
+type shrinkingLLM struct {
+	LLM
+	log      *degradationLog
+	shrinker shrinker
+}
+
+func (s *shrinkingLLM) unwrap() LLM { return s.LLM }
+
+// retryShrinking runs call with progressively shrunk messages as long as it
+// keeps failing with a context-length error.
+func retryShrinking[T any](ctx context.Context, s shrinker, log *degradationLog, messages []openai.ChatCompletionMessage, firstErr error,
+	call func([]openai.ChatCompletionMessage) (T, error)) (T, error) {
+	var zero T
+	err := firstErr
+	current := messages
+	for _, stage := range shrinkStages {
+		if !IsContextLengthError(err) {
+			return zero, err
+		}
+		shrunk := stage.apply(ctx, s, current)
+		entry := ContextDegradation{
+			Stage:          stage.name,
+			MessagesBefore: len(current),
+			MessagesAfter:  len(shrunk),
+		}
+		s.logger.Warn("Context length exceeded, retrying with a shrunk prompt", "stage", stage.name,
+			"messagesBefore", entry.MessagesBefore, "messagesAfter", entry.MessagesAfter)
+
+		var res T
+		res, err = call(shrunk)
+		entry.Recovered = err == nil
+		log.add(entry)
+		if err == nil {
+			return res, nil
+		}
+		current = shrunk
+	}
+	return zero, err
+}
+
+func (s *shrinkingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	type result struct {
+		reply LLMReply
+		usage LLMUsage
+	}
+	reply, usage, err := s.LLM.CreateChatCompletion(ctx, req)
+	if err == nil || !IsContextLengthError(err) {
+		return reply, usage, err
+	}
+	res, err := retryShrinking(ctx, s.shrinker, s.log, req.Messages, err, func(m []openai.ChatCompletionMessage) (result, error) {
+		shrunk := req
+		shrunk.Messages = m
+		reply, usage, err := s.LLM.CreateChatCompletion(ctx, shrunk)
+		return result{reply, usage}, err
+	})
+	return res.reply, res.usage, err
+}
+
+func (s *shrinkingLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	res, err := s.LLM.Ask(ctx, f)
+	if err == nil || !IsContextLengthError(err) {
+		return res, err
+	}
+	return retryShrinking(ctx, s.shrinker, s.log, f.Messages, err, func(m []openai.ChatCompletionMessage) (Fragment, error) {
+		shrunk := f
+		shrunk.Messages = m
+		res, err := s.LLM.Ask(ctx, shrunk)
+		if err == nil && len(res.Messages) >= len(m) {
+			// Only the request is shrunk: the reply follows the whole conversation
+			res.Messages = append(slices.Clone(f.Messages), res.Messages[len(m):]...)
+		}
+		return res, err
+	})
+}
+
+// shrinkingStreamingLLM preserves StreamingLLM. Only errors returned when
+// opening the stream are retried; errors delivered mid-stream are not.
+type shrinkingStreamingLLM struct {
+	shrinkingLLM
+	streaming StreamingLLM
+}
+
+func (s *shrinkingStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	ch, err := s.streaming.CreateChatCompletionStream(ctx, req)
+	if err == nil || !IsContextLengthError(err) {
+		return ch, err
+	}
+	return retryShrinking(ctx, s.shrinker, s.log, req.Messages, err, func(m []openai.ChatCompletionMessage) (<-chan StreamEvent, error) {
+		shrunk := req
+		shrunk.Messages = m
+		return s.streaming.CreateChatCompletionStream(ctx, shrunk)
+	})
+}
+
+// newShrinkingLLM wraps llm so context-length errors are retried with shrunk
+// prompts, recording each attempt into log. Tool results are summarized with
+// llm, accumulating the usage of the summaries into usage when not nil.
+func newShrinkingLLM(llm LLM, log *degradationLog, prompts prompt.PromptMap, usage *usageCounter, logger Logger) LLM {
+	if _, ok := unwrapLLM[*shrinkingLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*shrinkingStreamingLLM](llm); ok {
+		return llm
+	}
+	base := shrinkingLLM{LLM: llm, log: log, shrinker: newShrinker(llm, prompts, usage, logger)}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &shrinkingStreamingLLM{shrinkingLLM: base, streaming: s}
+	}
+	return &base
+}
@@ -0,0 +1,193 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// contextLimitedLLM rejects requests whose total content exceeds limit.
+type contextLimitedLLM struct {
+	fakeLLM
+	limit int
+	sizes []int
+}
+
+func (c *contextLimitedLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	size := 0
+	for _, m := range req.Messages {
+		size += len(m.Content)
+	}
+	c.sizes = append(c.sizes, size)
+	if size > c.limit {
+		return LLMReply{}, LLMUsage{}, &openai.APIError{Code: "context_length_exceeded", Message: "too long"}
+	}
+	return c.fakeLLM.CreateChatCompletion(ctx, req)
+}
+
+func (c *contextLimitedLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	size := 0
+	for _, m := range f.Messages {
+		size += len(m.Content)
+	}
+	c.sizes = append(c.sizes, size)
+	if size > c.limit {
+		return Fragment{}, &openai.APIError{Code: "context_length_exceeded", Message: "too long"}
+	}
+	return f.AddMessage(AssistantMessageRole, "answer"), nil
+}
+
+func TestIsContextLengthError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("connection refused"), false},
+		{&openai.APIError{Code: "context_length_exceeded"}, true},
+		{fmt.Errorf("wrapped: %w", &openai.APIError{Code: "context_length_exceeded"}), true},
+		{errors.New("the request exceeds the available context size"), true},
+		{errors.New("This model's maximum context length is 8192 tokens"), true},
+		{errors.New("Rate limit reached: too many tokens per minute"), false},
+	}
+	for _, c := range cases {
+		if got := IsContextLengthError(c.err); got != c.want {
+			t.Errorf("IsContextLengthError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestShrinkingLLMRecoversAndRecords(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: "system", Content: "be helpful"},
+		{Role: "user", Content: "question"},
+		{Role: "system", Content: strings.Repeat("guideline ", 50)},
+		{Role: "tool", Content: strings.Repeat("x", 5000)},
+	}
+	inner := &contextLimitedLLM{limit: 2000}
+	log := &degradationLog{}
+	llm := newShrinkingLLM(inner, log, nil, nil, defaultLogger)
+
+	if _, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Messages: messages}); err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+
+	got := log.snapshot()
+	if len(got) != 2 {
+		t.Fatalf("degradations = %+v, want 2 stages", got)
+	}
+	if got[0].Stage != "drop_additional_context" || got[0].Recovered {
+		t.Errorf("first degradation = %+v", got[0])
+	}
+	if got[1].Stage != "summarize_tool_results" || !got[1].Recovered {
+		t.Errorf("second degradation = %+v", got[1])
+	}
+	if len(messages[3].Content) != 5000 {
+		t.Errorf("caller messages were mutated")
+	}
+}
+
+func TestShrinkingLLMAskKeepsTheConversation(t *testing.T) {
+	f := NewEmptyFragment().
+		AddMessage(UserMessageRole, "question").
+		AddMessage(ToolMessageRole, strings.Repeat("è", 3000))
+	inner := &contextLimitedLLM{limit: 2000}
+	llm := newShrinkingLLM(inner, &degradationLog{}, nil, nil, defaultLogger)
+
+	result, err := llm.Ask(context.Background(), f)
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if len(result.Messages) != 3 || result.Messages[1].Content != f.Messages[1].Content || result.Messages[2].Content != "answer" {
+		t.Errorf("messages = %+v", result.Messages)
+	}
+}
+
+func TestShrinkingLLMSummarizesToolResults(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: "user", Content: strings.Repeat("q", 800)},
+		{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "1", Function: openai.FunctionCall{Name: "search", Arguments: `{"query":"q"}`}}}},
+		{Role: "tool", ToolCallID: "1", Content: strings.Repeat("x", 1200)},
+	}
+	// The conversation exceeds the limit, the summary request alone does not
+	inner := &contextLimitedLLM{limit: 1800}
+	log := &degradationLog{}
+	usage := &usageCounter{}
+	llm := newShrinkingLLM(inner, log, nil, usage, defaultLogger)
+
+	if _, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Messages: messages}); err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+
+	got := log.snapshot()
+	if len(got) != 2 || got[1].Stage != "summarize_tool_results" || !got[1].Recovered {
+		t.Fatalf("degradations = %+v", got)
+	}
+	// The last request carries the summary in place of the tool result
+	if last := inner.sizes[len(inner.sizes)-1]; last != 800+len("answer") {
+		t.Errorf("sizes = %v", inner.sizes)
+	}
+	if calls := usage.calls.Load(); calls != 1 {
+		t.Errorf("summary calls counted = %d, want 1", calls)
+	}
+}
+
+func TestSummarizeToolResultsTruncatesOnRunes(t *testing.T) {
+	s := newShrinker(&contextLimitedLLM{limit: 1}, nil, nil, defaultLogger)
+	trimmed := summarizeToolResults(context.Background(), s, []openai.ChatCompletionMessage{{Role: "tool", Content: "a" + strings.Repeat("è", 1000)}})
+	if !utf8.ValidString(trimmed[0].Content) || !strings.HasSuffix(trimmed[0].Content, "truncated to fit the context window]") {
+		t.Errorf("content = %q", trimmed[0].Content)
+	}
+}
+
+func TestNewShrinkingLLMIsIdempotent(t *testing.T) {
+	llm := newShrinkingLLM(&contextLimitedLLM{}, &degradationLog{}, nil, nil, defaultLogger)
+	if newShrinkingLLM(llm, &degradationLog{}, nil, nil, defaultLogger) != llm {
+		t.Error("shrinking LLM was wrapped twice")
+	}
+}
+
+func TestShrinkingLLMGivesUp(t *testing.T) {
+	inner := &contextLimitedLLM{limit: 1}
+	log := &degradationLog{}
+	llm := newShrinkingLLM(inner, log, nil, nil, defaultLogger)
+
+	_, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "question"}},
+	})
+	if !IsContextLengthError(err) {
+		t.Fatalf("err = %v, want context length error", err)
+	}
+	if n := len(log.snapshot()); n != len(shrinkStages) {
+		t.Errorf("degradations = %d, want %d", n, len(shrinkStages))
+	}
+}
+
+func TestTrimHistoryKeepsValidTail(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: "system", Content: "s"},
+		{Role: "user", Content: "u"},
+	}
+	for i := 0; i < 5; i++ {
+		messages = append(messages,
+			openai.ChatCompletionMessage{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: fmt.Sprint(i)}}},
+			openai.ChatCompletionMessage{Role: "tool", ToolCallID: fmt.Sprint(i)},
+		)
+	}
+
+	got := trimHistory(messages)
+	if got[0].Role != "system" || got[1].Role != "user" {
+		t.Fatalf("head not preserved: %+v", got[:2])
+	}
+	if got[2].Role != "assistant" {
+		t.Errorf("tail starts with orphaned %q message", got[2].Role)
+	}
+	if len(got) > shrinkKeepMessages+2 {
+		t.Errorf("len = %d, want <= %d", len(got), shrinkKeepMessages+2)
+	}
+}
@@ -0,0 +1,56 @@
+package cogito_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type ctxKey struct{}
+
+type WhoamiArgs struct{}
+
+// whoamiTool reports the request-scoped user found in its context.
+type whoamiTool struct{}
+
+func (w *whoamiTool) Run(args WhoamiArgs) (string, any, error) {
+	return "", nil, fmt.Errorf("Run must not be called when RunWithContext is available")
+}
+
+func (w *whoamiTool) RunWithContext(ctx context.Context, args WhoamiArgs) (string, any, error) {
+	user, _ := ctx.Value(ctxKey{}).(string)
+	return "user=" + user, nil, nil
+}
+
+var _ = Describe("Context-first API", func() {
+	var mockLLM *cogitotest.MockLLM
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+	})
+
+	It("threads the caller context to context-aware tools, overriding WithContext", func() {
+		tool := NewToolDefinition(&whoamiTool{}, WhoamiArgs{}, "whoami", "Return the current user")
+
+		mockLLM.AddCreateChatCompletionFunction("whoami", `{}`)
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "You are alice."}},
+			},
+		})
+
+		ctx := context.WithValue(context.Background(), ctxKey{}, "alice")
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Who am I?")
+		other := context.WithValue(context.Background(), ctxKey{}, "bob")
+		result, err := ExecuteToolsContext(ctx, mockLLM, fragment, WithTools(tool), WithIterations(2), WithContext(other))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		Expect(result.Status.ToolResults[0].Result).To(Equal("user=alice"))
+	})
+})
@@ -0,0 +1,200 @@
+package cogito
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// DatasetFormat is the format of the examples written by a DatasetRecorder.
+type DatasetFormat string
+
+const (
+	// DatasetFormatOpenAI writes OpenAI fine-tuning JSONL: one
+	// {"messages": [...], "tools": [...]} object per line.
+	DatasetFormatOpenAI DatasetFormat = "openai"
+	// DatasetFormatShareGPT writes ShareGPT JSONL with function calls, as
+	// read by LLaMA-Factory and Axolotl: {"conversations": [...],
+	// "system": "...", "tools": "..."}.
+	DatasetFormatShareGPT DatasetFormat = "sharegpt"
+)
+
+// DatasetExample is a tool call made by an agent: the conversation it was
+// selected from, the tools it was selected among, the call and its result.
+type DatasetExample struct {
+	Messages []openai.ChatCompletionMessage
+	Tools    []openai.Tool
+	Call     ToolChoice
+	Result   string
+}
+
+// DatasetRecorder writes DatasetExamples to a writer, one JSON line each.
+// It is safe for concurrent use. See WithDatasetRecorder.
+type DatasetRecorder struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format DatasetFormat
+}
+
+// NewDatasetRecorder returns a recorder writing examples to w in format.
+func NewDatasetRecorder(w io.Writer, format DatasetFormat) *DatasetRecorder {
+	return &DatasetRecorder{w: w, format: format}
+}
+
+// Record writes example.
+func (r *DatasetRecorder) Record(example DatasetExample) error {
+	call := openai.ToolCall{
+		ID:       example.Call.ID,
+		Type:     openai.ToolTypeFunction,
+		Function: openai.FunctionCall{Name: example.Call.Name, Arguments: string(mustMarshal(example.Call.Arguments))},
+	}
+	if call.ID == "" {
+		call.ID = "call_0"
+	}
+	messages := append(stripDatasetMessages(example.Messages),
+		openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), ToolCalls: []openai.ToolCall{call}},
+		openai.ChatCompletionMessage{Role: ToolMessageRole.String(), Content: example.Result, ToolCallID: call.ID},
+	)
+
+	var line any
+	switch r.format {
+	case DatasetFormatOpenAI:
+		line = struct {
+			Messages []openai.ChatCompletionMessage `json:"messages"`
+			Tools    []openai.Tool                  `json:"tools,omitempty"`
+		}{messages, example.Tools}
+	case DatasetFormatShareGPT:
+		line = shareGPTExample(messages, example.Tools)
+	default:
+		return fmt.Errorf("unknown dataset format %q", r.format)
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("failed to encode dataset example: %w", err)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, err := r.w.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write dataset example: %w", err)
+	}
+	return nil
+}
+
+// stripDatasetMessages keeps the fields of messages that fine-tuning
+// formats accept.
+func stripDatasetMessages(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	out := make([]openai.ChatCompletionMessage, len(messages))
+	for i, m := range messages {
+		out[i] = openai.ChatCompletionMessage{
+			Role:         m.Role,
+			Content:      m.Content,
+			MultiContent: m.MultiContent,
+			ToolCalls:    m.ToolCalls,
+			ToolCallID:   m.ToolCallID,
+		}
+	}
+	return out
+}
+
+type shareGPTTurn struct {
+	From  string `json:"from"`
+	Value string `json:"value"`
+}
+
+type shareGPTLine struct {
+	Conversations []shareGPTTurn `json:"conversations"`
+	System        string         `json:"system,omitempty"`
+	Tools         string         `json:"tools,omitempty"`
+}
+
+// shareGPTExample maps messages to ShareGPT turns: system messages become
+// the system prompt, tool calls "function_call" turns and tool results
+// "observation" turns.
+func shareGPTExample(messages []openai.ChatCompletionMessage, tools []openai.Tool) shareGPTLine {
+	var line shareGPTLine
+	var system []string
+	for _, m := range messages {
+		text := m.Content
+		if text == "" {
+			for _, part := range m.MultiContent {
+				if part.Type == openai.ChatMessagePartTypeText {
+					text += part.Text
+				}
+			}
+		}
+		switch m.Role {
+		case SystemMessageRole.String():
+			system = append(system, text)
+		case UserMessageRole.String():
+			line.Conversations = append(line.Conversations, shareGPTTurn{From: "human", Value: text})
+		case ToolMessageRole.String():
+			line.Conversations = append(line.Conversations, shareGPTTurn{From: "observation", Value: text})
+		case AssistantMessageRole.String():
+			if text != "" {
+				line.Conversations = append(line.Conversations, shareGPTTurn{From: "gpt", Value: text})
+			}
+			for _, call := range m.ToolCalls {
+				value, _ := json.Marshal(struct {
+					Name      string          `json:"name"`
+					Arguments json.RawMessage `json:"arguments"`
+				}{call.Function.Name, json.RawMessage(validArguments(call.Function.Arguments))})
+				line.Conversations = append(line.Conversations, shareGPTTurn{From: "function_call", Value: string(value)})
+			}
+		}
+	}
+	line.System = strings.Join(system, "\n\n")
+
+	var functions []*openai.FunctionDefinition
+	for _, t := range tools {
+		if t.Function != nil {
+			functions = append(functions, t.Function)
+		}
+	}
+	if len(functions) > 0 {
+		line.Tools = string(mustMarshal(functions))
+	}
+	return line
+}
+
+// validArguments returns arguments if it is valid JSON, or "{}".
+func validArguments(arguments string) string {
+	if json.Valid([]byte(arguments)) {
+		return arguments
+	}
+	return "{}"
+}
+
+// recordDatasetExample records the successful call choice to the dataset
+// recorder of o, if any. The context is the conversation of f before the
+// message making the call. Recorder errors are logged and do not stop the
+// run.
+func recordDatasetExample(o *Options, f Fragment, tools Tools, choice *ToolChoice, result string) {
+	if o.datasetRecorder == nil {
+		return
+	}
+	context := f.Messages
+	for i, m := range f.Messages {
+		if m.Role == AssistantMessageRole.String() && hasToolCall(m, choice.ID) {
+			context = f.Messages[:i]
+			break
+		}
+	}
+	example := DatasetExample{Messages: context, Tools: tools.ToOpenAI(), Call: *choice, Result: result}
+	if err := o.datasetRecorder.Record(example); err != nil {
+		o.logger.Warn("Failed to record dataset example", "tool", choice.Name, "error", err)
+	}
+}
+
+func hasToolCall(m openai.ChatCompletionMessage, id string) bool {
+	for _, call := range m.ToolCalls {
+		if call.ID == id {
+			return true
+		}
+	}
+	return false
+}
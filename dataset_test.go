@@ -0,0 +1,106 @@
+package cogito_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type forecastRunner struct{ err error }
+
+func (r forecastRunner) Run(args WeatherArgs) (string, any, error) {
+	if r.err != nil {
+		return "", nil, r.err
+	}
+	return "Sunny in " + args.City, nil, nil
+}
+
+var _ = Describe("Dataset recorder", func() {
+	var mockLLM *cogitotest.MockLLM
+	var fragment Fragment
+	var tool ToolDefinitionInterface
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		fragment = NewEmptyFragment().
+			AddMessage(SystemMessageRole, "You are a weather assistant.").
+			AddMessage(UserMessageRole, "What's the weather in Rome?")
+		tool = NewToolDefinition[WeatherArgs](forecastRunner{}, WeatherArgs{}, "forecast", "Get the weather forecast")
+
+		mockLLM.AddCreateChatCompletionFunction("forecast", `{"city": "Rome"}`)
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "It's sunny."}},
+			},
+		})
+	})
+
+	lines := func(buf *bytes.Buffer) []string {
+		return strings.Split(strings.TrimSpace(buf.String()), "\n")
+	}
+
+	It("records tool calls in the OpenAI fine-tuning format", func() {
+		var buf bytes.Buffer
+		_, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2),
+			WithDatasetRecorder(&buf, DatasetFormatOpenAI))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lines(&buf)).To(HaveLen(1))
+
+		var example struct {
+			Messages []openai.ChatCompletionMessage `json:"messages"`
+			Tools    []openai.Tool                  `json:"tools"`
+		}
+		Expect(json.Unmarshal(buf.Bytes(), &example)).To(Succeed())
+		Expect(example.Messages).To(HaveLen(4))
+		Expect(example.Messages[0].Content).To(Equal("You are a weather assistant."))
+		Expect(example.Messages[1].Content).To(Equal("What's the weather in Rome?"))
+		Expect(example.Messages[2].ToolCalls).To(HaveLen(1))
+		Expect(example.Messages[2].ToolCalls[0].Function.Name).To(Equal("forecast"))
+		Expect(example.Messages[2].ToolCalls[0].Function.Arguments).To(MatchJSON(`{"city": "Rome"}`))
+		Expect(example.Messages[3].Role).To(Equal(ToolMessageRole.String()))
+		Expect(example.Messages[3].Content).To(Equal("Sunny in Rome"))
+		Expect(example.Messages[3].ToolCallID).To(Equal(example.Messages[2].ToolCalls[0].ID))
+		Expect(example.Tools).To(ContainElement(HaveField("Function.Name", "forecast")))
+	})
+
+	It("records tool calls in the ShareGPT format", func() {
+		var buf bytes.Buffer
+		_, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2),
+			WithDatasetRecorder(&buf, DatasetFormatShareGPT))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(lines(&buf)).To(HaveLen(1))
+
+		var example struct {
+			Conversations []struct {
+				From  string `json:"from"`
+				Value string `json:"value"`
+			} `json:"conversations"`
+			System string `json:"system"`
+			Tools  string `json:"tools"`
+		}
+		Expect(json.Unmarshal(buf.Bytes(), &example)).To(Succeed())
+		Expect(example.System).To(Equal("You are a weather assistant."))
+		Expect(example.Conversations).To(HaveLen(3))
+		Expect(example.Conversations[0].From).To(Equal("human"))
+		Expect(example.Conversations[1].From).To(Equal("function_call"))
+		Expect(example.Conversations[1].Value).To(MatchJSON(`{"name": "forecast", "arguments": {"city": "Rome"}}`))
+		Expect(example.Conversations[2].From).To(Equal("observation"))
+		Expect(example.Conversations[2].Value).To(Equal("Sunny in Rome"))
+		Expect(example.Tools).To(ContainSubstring(`"forecast"`))
+	})
+
+	It("does not record failed tool calls", func() {
+		var buf bytes.Buffer
+		failing := NewToolDefinition[WeatherArgs](forecastRunner{err: errors.New("service down")}, WeatherArgs{}, "forecast", "Get the weather forecast")
+		_, _ = ExecuteTools(mockLLM, fragment, WithTools(failing), WithIterations(2),
+			WithDatasetRecorder(&buf, DatasetFormatOpenAI))
+		Expect(buf.String()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,220 @@
+package cogito
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// executionGraph is the flow of a run, rendered by Status.ToMermaid and
+// Status.ToDOT: the start, the subtasks of each plan (re-plans following the
+// plan they replace), the tool calls in order, and the answer.
+type executionGraph struct {
+	Nodes    []graphNode
+	Clusters []graphCluster
+	Edges    []graphEdge
+}
+
+type graphNodeKind int
+
+const (
+	graphTerminal graphNodeKind = iota
+	graphSubtask
+	graphTool
+	graphSkippedTool // selected but not executed
+)
+
+type graphNode struct {
+	ID    string
+	Label string
+	Kind  graphNodeKind
+}
+
+type graphCluster struct {
+	ID    string
+	Label string
+	Nodes []graphNode
+}
+
+type graphEdge struct {
+	From, To string
+	Label    string
+	Dashed   bool
+}
+
+// maxDiagramLabel is the length tool arguments are truncated to in labels.
+const maxDiagramLabel = 40
+
+func newExecutionGraph(s *Status) executionGraph {
+	g := executionGraph{Nodes: []graphNode{{ID: "start", Label: "Start", Kind: graphTerminal}}}
+	prev := "start"
+	if s == nil {
+		return g
+	}
+
+	// Completed plans may be recorded twice in a row
+	plans := slices.CompactFunc(slices.Clone(s.Plans), func(a, b PlanStatus) bool {
+		return a.Plan.Description == b.Plan.Description && slices.Equal(a.Plan.Subtasks, b.Plan.Subtasks)
+	})
+	for i, p := range plans {
+		if len(p.Plan.Subtasks) == 0 {
+			continue
+		}
+		c := graphCluster{ID: fmt.Sprintf("plan%d", i+1), Label: fmt.Sprintf("Plan %d: %s", i+1, p.Plan.Description)}
+		for j, subtask := range p.Plan.Subtasks {
+			id := fmt.Sprintf("plan%d_%d", i+1, j+1)
+			c.Nodes = append(c.Nodes, graphNode{ID: id, Label: subtask, Kind: graphSubtask})
+			if j == 0 && i > 0 {
+				g.Edges = append(g.Edges, graphEdge{From: prev, To: id, Label: "re-plan", Dashed: true})
+			} else {
+				g.Edges = append(g.Edges, graphEdge{From: prev, To: id})
+			}
+			prev = id
+		}
+		g.Clusters = append(g.Clusters, c)
+	}
+
+	for k, ts := range s.ToolResults {
+		id := fmt.Sprintf("tool%d", k+1)
+		kind := graphTool
+		if !ts.Executed {
+			kind = graphSkippedTool
+		}
+		name := ts.Name
+		if name == "" {
+			name = ts.ToolArguments.Name
+		}
+		g.Nodes = append(g.Nodes, graphNode{ID: id, Label: name + "(" + diagramArguments(ts.ToolArguments.Arguments) + ")", Kind: kind})
+		g.Edges = append(g.Edges, graphEdge{From: prev, To: id})
+		prev = id
+	}
+
+	if prev != "start" {
+		g.Nodes = append(g.Nodes, graphNode{ID: "answer", Label: "Answer", Kind: graphTerminal})
+		g.Edges = append(g.Edges, graphEdge{From: prev, To: "answer"})
+	}
+	return g
+}
+
+// diagramArguments formats tool arguments as key=value pairs, sorted by key
+// and truncated.
+func diagramArguments(args map[string]any) string {
+	keys := make([]string, 0, len(args))
+	for k := range args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = fmt.Sprintf("%s=%v", k, args[k])
+	}
+	label := strings.Join(pairs, ", ")
+	if r := []rune(label); len(r) > maxDiagramLabel {
+		label = string(r[:maxDiagramLabel-1]) + "…"
+	}
+	return label
+}
+
+// ToMermaid renders the execution graph of the run as a Mermaid flowchart:
+// the plans and their subtasks, re-plans, the tool calls in order and the
+// answer. Not executed tool calls are dotted.
+func (s *Status) ToMermaid() string {
+	g := newExecutionGraph(s)
+	var sb strings.Builder
+	sb.WriteString("flowchart TD\n")
+	mermaidNode := func(indent string, n graphNode) {
+		label := mermaidLabel(n.Label)
+		switch n.Kind {
+		case graphTerminal:
+			fmt.Fprintf(&sb, "%s%s([\"%s\"])\n", indent, n.ID, label)
+		case graphTool, graphSkippedTool:
+			fmt.Fprintf(&sb, "%s%s[[\"%s\"]]\n", indent, n.ID, label)
+		default:
+			fmt.Fprintf(&sb, "%s%s[\"%s\"]\n", indent, n.ID, label)
+		}
+	}
+	for _, c := range g.Clusters {
+		fmt.Fprintf(&sb, "    subgraph %s[\"%s\"]\n", c.ID, mermaidLabel(c.Label))
+		for _, n := range c.Nodes {
+			mermaidNode("        ", n)
+		}
+		sb.WriteString("    end\n")
+	}
+	var skipped []string
+	for _, n := range g.Nodes {
+		mermaidNode("    ", n)
+		if n.Kind == graphSkippedTool {
+			skipped = append(skipped, n.ID)
+		}
+	}
+	for _, e := range g.Edges {
+		switch {
+		case e.Dashed && e.Label != "":
+			fmt.Fprintf(&sb, "    %s -. \"%s\" .-> %s\n", e.From, mermaidLabel(e.Label), e.To)
+		case e.Dashed:
+			fmt.Fprintf(&sb, "    %s -.-> %s\n", e.From, e.To)
+		default:
+			fmt.Fprintf(&sb, "    %s --> %s\n", e.From, e.To)
+		}
+	}
+	if len(skipped) > 0 {
+		sb.WriteString("    classDef skipped stroke-dasharray: 5 5\n")
+		fmt.Fprintf(&sb, "    class %s skipped\n", strings.Join(skipped, ","))
+	}
+	return sb.String()
+}
+
+func mermaidLabel(s string) string {
+	return strings.NewReplacer(`"`, "#quot;", "\n", "<br>").Replace(s)
+}
+
+// ToDOT renders the execution graph of the run in the Graphviz DOT language,
+// see ToMermaid.
+func (s *Status) ToDOT() string {
+	g := newExecutionGraph(s)
+	var sb strings.Builder
+	sb.WriteString("digraph execution {\n    rankdir=TB;\n    node [shape=box];\n")
+	dotNode := func(indent string, n graphNode) {
+		attrs := ""
+		switch n.Kind {
+		case graphTerminal:
+			attrs = ", shape=oval"
+		case graphTool:
+			attrs = ", shape=component"
+		case graphSkippedTool:
+			attrs = ", shape=component, style=dashed"
+		}
+		fmt.Fprintf(&sb, "%s%s [label=\"%s\"%s];\n", indent, n.ID, dotLabel(n.Label), attrs)
+	}
+	for _, c := range g.Clusters {
+		fmt.Fprintf(&sb, "    subgraph cluster_%s {\n        label=\"%s\";\n", c.ID, dotLabel(c.Label))
+		for _, n := range c.Nodes {
+			dotNode("        ", n)
+		}
+		sb.WriteString("    }\n")
+	}
+	for _, n := range g.Nodes {
+		dotNode("    ", n)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		if e.Label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=\"%s\"", dotLabel(e.Label)))
+		}
+		if e.Dashed {
+			attrs = append(attrs, "style=dashed")
+		}
+		if len(attrs) > 0 {
+			fmt.Fprintf(&sb, "    %s -> %s [%s];\n", e.From, e.To, strings.Join(attrs, ", "))
+		} else {
+			fmt.Fprintf(&sb, "    %s -> %s;\n", e.From, e.To)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+func dotLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,61 @@
+package cogito
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mudler/cogito/structures"
+)
+
+func diagramStatus() *Status {
+	first := structures.Plan{Description: "Find the weather", Subtasks: []string{"Search the city", "Get the weather"}}
+	second := structures.Plan{Description: "Ask a \"forecast\" service", Subtasks: []string{"Get the forecast"}}
+	return &Status{
+		// The completed plan is recorded twice, as ExecutePlan does
+		Plans: []PlanStatus{{Plan: first}, {Plan: second}, {Plan: second}},
+		ToolResults: []ToolStatus{
+			{Name: "search", Executed: true, ToolArguments: ToolChoice{Arguments: map[string]any{"query": "Rome"}}},
+			{Name: "forecast", ToolArguments: ToolChoice{Arguments: map[string]any{"days": 3, "city": "Rome"}}},
+		},
+	}
+}
+
+func TestStatusToMermaid(t *testing.T) {
+	got := diagramStatus().ToMermaid()
+	for _, want := range []string{
+		"flowchart TD\n",
+		"    subgraph plan1[\"Plan 1: Find the weather\"]\n        plan1_1[\"Search the city\"]\n",
+		"    subgraph plan2[\"Plan 2: Ask a #quot;forecast#quot; service\"]\n",
+		"    start --> plan1_1\n",
+		"    plan1_2 -. \"re-plan\" .-> plan2_1\n",
+		"    tool2[[\"forecast(city=Rome, days=3)\"]]\n",
+		"    plan2_1 --> tool1\n    tool1 --> tool2\n    tool2 --> answer\n",
+		"    class tool2 skipped\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ToMermaid() is missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "plan3") {
+		t.Errorf("ToMermaid() repeated the completed plan:\n%s", got)
+	}
+}
+
+func TestStatusToDOT(t *testing.T) {
+	got := diagramStatus().ToDOT()
+	for _, want := range []string{
+		"digraph execution {\n",
+		"    subgraph cluster_plan2 {\n        label=\"Plan 2: Ask a \\\"forecast\\\" service\";\n",
+		"    plan1_2 -> plan2_1 [label=\"re-plan\", style=dashed];\n",
+		"    tool2 [label=\"forecast(city=Rome, days=3)\", shape=component, style=dashed];\n",
+		"    tool2 -> answer;\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("ToDOT() is missing %q:\n%s", want, got)
+		}
+	}
+	var empty *Status
+	if got := empty.ToDOT(); !strings.Contains(got, "start [label=\"Start\", shape=oval];") || strings.Contains(got, "answer") {
+		t.Errorf("ToDOT() of no status = %s", got)
+	}
+}
@@ -0,0 +1,453 @@
+// Package distributed offloads tool executions and sub-agents to worker
+// processes through a queue, so heavy tools (browsers, code sandboxes) scale
+// independently from the agent coordinating them.
+//
+// The coordinator wraps tools with RemoteTool and sub-agents with Dispatcher;
+// their results come back through the queue into its Fragment as if they had
+// run in-process. Workers serve the jobs with a Worker:
+//
+//	// coordinator
+//	q, _ := distributed.DialNATS("nats://localhost:4222")
+//	cogito.ExecuteTools(llm, f,
+//		cogito.WithTools(distributed.RemoteTool(q, "tools", browserTool)),
+//		cogito.EnableAgentSpawning,
+//		cogito.WithAgentDispatcher(distributed.Dispatcher(q, "agents")))
+//
+//	// worker
+//	w := distributed.NewWorker(llm, browserTool)
+//	go w.Serve(ctx, q, "agents")
+//	w.Serve(ctx, q, "tools")
+//
+// The queue is pluggable through Queue. MemoryQueue runs in-process, and
+// NATSQueue and RedisQueue run over NATS and Redis with their Go clients.
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/xlog"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// Queue carries jobs from coordinators to workers, and their results back.
+// Every job is handled by one worker serving its subject.
+type Queue interface {
+	// Request sends data to a worker serving subject and returns its reply.
+	// It waits for a worker until ctx is done.
+	Request(ctx context.Context, subject string, data []byte) ([]byte, error)
+	// Serve handles the jobs of subject with handler, one at a time, until
+	// ctx is done and returns ctx.Err(). Call it from several goroutines or
+	// processes to handle jobs concurrently.
+	Serve(ctx context.Context, subject string, handler Handler) error
+}
+
+// Handler handles a job and returns its reply.
+type Handler func(ctx context.Context, data []byte) ([]byte, error)
+
+// Job is a unit of work sent to workers: a tool call, the compensation of a
+// tool call, or a sub-agent run.
+type Job struct {
+	Tool      string               `json:"tool,omitempty"`
+	Arguments map[string]any       `json:"arguments,omitempty"`
+	Agent     *cogito.AgentRunSpec `json:"agent,omitempty"`
+
+	// Compensate undoes the call of Tool with Arguments, which returned
+	// Result, see cogito.EnableCompensation
+	Compensate bool   `json:"compensate,omitempty"`
+	Result     string `json:"result,omitempty"`
+
+	// Values of the run context, restored on the context of the worker
+	RunID     string `json:"run_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	TraceID   string `json:"trace_id,omitempty"`
+	Tenant    string `json:"tenant,omitempty"`
+	Caller    string `json:"caller,omitempty"`
+}
+
+// JobResult is the reply of a worker to a Job.
+type JobResult struct {
+	Result     string             `json:"result,omitempty"`
+	Data       any                `json:"data,omitempty"`        // result data of tools, decoded from JSON
+	ToolResult *cogito.ToolResult `json:"tool_result,omitempty"` // result data of tools returning a cogito.ToolResult
+	Fragment   *cogito.Fragment   `json:"fragment,omitempty"`
+	Error      string             `json:"error,omitempty"`
+}
+
+func newJob(ctx context.Context) Job {
+	return Job{
+		RunID:     cogito.RunIDFromContext(ctx),
+		SessionID: cogito.SessionIDFromContext(ctx),
+		TraceID:   cogito.TraceIDFromContext(ctx),
+		Tenant:    cogito.TenantFromContext(ctx),
+		Caller:    cogito.CallerFromContext(ctx),
+	}
+}
+
+// context returns ctx with the run context values of the job.
+func (j Job) context(ctx context.Context) context.Context {
+	for _, v := range []struct {
+		value string
+		with  func(context.Context, string) context.Context
+	}{
+		{j.RunID, cogito.ContextWithRunID},
+		{j.SessionID, cogito.ContextWithSessionID},
+		{j.TraceID, cogito.ContextWithTraceID},
+		{j.Tenant, cogito.ContextWithTenant},
+		{j.Caller, cogito.ContextWithCaller},
+	} {
+		if v.value != "" {
+			ctx = v.with(ctx, v.value)
+		}
+	}
+	return ctx
+}
+
+// send sends job to a worker of subject and returns its result. Errors of the
+// worker are returned as errors.
+func send(ctx context.Context, q Queue, subject string, job Job) (JobResult, error) {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return JobResult{}, fmt.Errorf("failed to encode job: %w", err)
+	}
+	reply, err := q.Request(ctx, subject, data)
+	if err != nil {
+		return JobResult{}, fmt.Errorf("failed to send job to %s: %w", subject, err)
+	}
+	var result JobResult
+	if err := json.Unmarshal(reply, &result); err != nil {
+		return JobResult{}, fmt.Errorf("failed to decode job result: %w", err)
+	}
+	if result.Error != "" {
+		return result, errors.New(result.Error)
+	}
+	return result, nil
+}
+
+// RemoteTool returns tool executed by the workers serving subject. The
+// coordinator only needs the definition of the tool: its runner can be a
+// stub. Concurrency limits, output schemas and roles of tool still apply.
+// When tool has a compensating action, the compensation runs on the workers
+// with the compensating action of their tool. Stateful tools are
+// initialized and closed by the workers, see Worker.
+func RemoteTool(q Queue, subject string, tool cogito.ToolDefinitionInterface) cogito.ToolDefinitionInterface {
+	return &remoteTool{ToolDefinitionInterface: tool, q: q, subject: subject}
+}
+
+// RemoteTools returns tools executed by the workers serving subject, see
+// RemoteTool.
+func RemoteTools(q Queue, subject string, tools ...cogito.ToolDefinitionInterface) cogito.Tools {
+	remote := make(cogito.Tools, len(tools))
+	for i, tool := range tools {
+		remote[i] = RemoteTool(q, subject, tool)
+	}
+	return remote
+}
+
+type remoteTool struct {
+	cogito.ToolDefinitionInterface
+	q       Queue
+	subject string
+}
+
+func (t *remoteTool) Execute(args map[string]any) (string, any, error) {
+	return t.ExecuteWithContext(context.Background(), args)
+}
+
+func (t *remoteTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	job := newJob(ctx)
+	job.Tool = t.Tool().Function.Name
+	job.Arguments = args
+	result, err := send(ctx, t.q, t.subject, job)
+	if err != nil {
+		return "", nil, err
+	}
+	if result.ToolResult != nil {
+		return result.Result, result.ToolResult, nil
+	}
+	return result.Result, result.Data, nil
+}
+
+// ToolCompensation sends the compensation of a call to the workers, when
+// the definition of the tool has a compensating action.
+func (t *remoteTool) ToolCompensation() cogito.CompensationFunc {
+	c, ok := t.ToolDefinitionInterface.(cogito.CompensatedTool)
+	if !ok || c.ToolCompensation() == nil {
+		return nil
+	}
+	return func(ctx context.Context, args map[string]any, result string) error {
+		job := newJob(ctx)
+		job.Tool = t.Tool().Function.Name
+		job.Arguments = args
+		job.Compensate = true
+		job.Result = result
+		_, err := send(ctx, t.q, t.subject, job)
+		return err
+	}
+}
+
+func (t *remoteTool) ToolConcurrency() cogito.ToolConcurrency {
+	if c, ok := t.ToolDefinitionInterface.(cogito.ConcurrencyLimitedTool); ok {
+		return c.ToolConcurrency()
+	}
+	return cogito.ToolConcurrency{}
+}
+
+func (t *remoteTool) OutputJSONSchema() *jsonschema.Definition {
+	if s, ok := t.ToolDefinitionInterface.(cogito.ToolWithOutputSchema); ok {
+		return s.OutputJSONSchema()
+	}
+	return nil
+}
+
+func (t *remoteTool) RequiredRoles() []string {
+	if r, ok := t.ToolDefinitionInterface.(cogito.RoleTool); ok {
+		return r.RequiredRoles()
+	}
+	return nil
+}
+
+// Dispatcher returns an agent dispatcher running sub-agents on the workers
+// serving subject, see cogito.WithAgentDispatcher. The sub-agents run with
+// the LLM and tools of the worker.
+func Dispatcher(q Queue, subject string) cogito.AgentDispatcher {
+	return func(ctx context.Context, spec cogito.AgentRunSpec) (cogito.Fragment, error) {
+		job := newJob(ctx)
+		job.Agent = &spec
+		result, err := send(ctx, q, subject, job)
+		if err != nil {
+			return cogito.Fragment{}, err
+		}
+		if result.Fragment == nil {
+			return cogito.Fragment{}, errors.New("worker returned no fragment")
+		}
+		return *result.Fragment, nil
+	}
+}
+
+// Worker handles the jobs of coordinators: tool calls with its tools, and
+// sub-agent runs with its LLM and tools. Stateful tools (see
+// cogito.StatefulTool) are initialized before their first call, are not
+// called while unhealthy, and stay initialized until Close.
+type Worker struct {
+	llm        cogito.LLM
+	tools      cogito.Tools
+	opts       []cogito.Option
+	llmFactory func(model string, temperature float32, metadata map[string]string) cogito.LLM
+	logger     cogito.Logger
+
+	mu       sync.Mutex
+	stateful map[string]*statefulTool
+	order    []*statefulTool // initialized tools, in order of initialization
+}
+
+// statefulTool is a stateful tool of a worker. mu serializes its
+// initialization.
+type statefulTool struct {
+	mu          sync.Mutex
+	tool        cogito.StatefulTool
+	initialized bool
+}
+
+// NewWorker returns a worker executing tools. llm runs the sub-agents, and
+// can be nil for workers only serving tools.
+func NewWorker(llm cogito.LLM, tools ...cogito.ToolDefinitionInterface) *Worker {
+	return &Worker{llm: llm, tools: tools}
+}
+
+// SetOptions sets the options of the sub-agents run by the worker, e.g.
+// their iterations or guidelines.
+func (w *Worker) SetOptions(opts ...cogito.Option) {
+	w.opts = opts
+}
+
+// SetLLMFactory builds the LLM of sub-agents asking for a model, as
+// cogito.WithAgentLLMFactory does in-process.
+func (w *Worker) SetLLMFactory(fn func(model string, temperature float32, metadata map[string]string) cogito.LLM) {
+	w.llmFactory = fn
+}
+
+// SetLogger sends the worker's log output (failed jobs) to l instead of the
+// global xlog logger.
+func (w *Worker) SetLogger(l cogito.Logger) {
+	w.logger = l
+}
+
+func (w *Worker) logError(msg string, args ...any) {
+	if w.logger == nil {
+		xlog.Error(msg, args...)
+		return
+	}
+	w.logger.Error(msg, args...)
+}
+
+// Serve handles the jobs of subject on q until ctx is done, see Queue.Serve.
+func (w *Worker) Serve(ctx context.Context, q Queue, subject string) error {
+	return q.Serve(ctx, subject, w.Handle)
+}
+
+// Handle handles an encoded Job and returns its encoded JobResult. Failed
+// jobs are reported in the result rather than as errors.
+func (w *Worker) Handle(ctx context.Context, data []byte) ([]byte, error) {
+	var job Job
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("failed to decode job: %w", err)
+	}
+	ctx = job.context(ctx)
+
+	var (
+		result JobResult
+		err    error
+	)
+	switch {
+	case job.Agent != nil:
+		result, err = w.runAgent(ctx, *job.Agent)
+	case job.Tool != "" && job.Compensate:
+		err = w.compensate(ctx, job.Tool, job.Arguments, job.Result)
+	case job.Tool != "":
+		result, err = w.runTool(ctx, job.Tool, job.Arguments)
+	default:
+		err = errors.New("empty job")
+	}
+	if err != nil {
+		w.logError("Job failed", "tool", job.Tool, "run", job.RunID, "error", err)
+		result.Error = err.Error()
+	}
+	return json.Marshal(result)
+}
+
+func (w *Worker) runTool(ctx context.Context, name string, args map[string]any) (JobResult, error) {
+	tool := w.tools.Find(name)
+	if tool == nil {
+		return JobResult{}, fmt.Errorf("tool %q not found on the worker", name)
+	}
+	if err := w.initTool(ctx, name, tool); err != nil {
+		return JobResult{}, err
+	}
+	var (
+		result string
+		data   any
+		err    error
+	)
+	if t, ok := tool.(cogito.ToolWithContext); ok {
+		result, data, err = t.ExecuteWithContext(ctx, args)
+	} else {
+		result, data, err = tool.Execute(args)
+	}
+	if err != nil {
+		return JobResult{}, err
+	}
+	switch r := data.(type) {
+	case cogito.ToolResult:
+		return JobResult{Result: result, ToolResult: &r}, nil
+	case *cogito.ToolResult:
+		return JobResult{Result: result, ToolResult: r}, nil
+	}
+	return JobResult{Result: result, Data: data}, nil
+}
+
+// initTool initializes tool, named name, before its first call when it is
+// stateful, and checks that it is healthy. A failed initialization is
+// attempted again on the next call.
+func (w *Worker) initTool(ctx context.Context, name string, tool cogito.ToolDefinitionInterface) error {
+	stateful, ok := cogito.StatefulToolOf(tool)
+	if !ok {
+		return nil
+	}
+	w.mu.Lock()
+	if w.stateful == nil {
+		w.stateful = map[string]*statefulTool{}
+	}
+	t, ok := w.stateful[name]
+	if !ok {
+		t = &statefulTool{tool: stateful}
+		w.stateful[name] = t
+	}
+	w.mu.Unlock()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.initialized {
+		if err := t.tool.Init(ctx); err != nil {
+			return fmt.Errorf("failed to initialize tool %s: %w", name, err)
+		}
+		t.initialized = true
+		w.mu.Lock()
+		w.order = append(w.order, t)
+		w.mu.Unlock()
+	}
+	if err := t.tool.Healthy(); err != nil {
+		return fmt.Errorf("tool %s is unavailable: %w", name, err)
+	}
+	return nil
+}
+
+// Close closes the stateful tools initialized by the worker, in reverse
+// order of initialization. Call it once the worker stops serving.
+func (w *Worker) Close() error {
+	w.mu.Lock()
+	order := w.order
+	w.order = nil
+	w.stateful = nil
+	w.mu.Unlock()
+
+	var errs []error
+	for i := len(order) - 1; i >= 0; i-- {
+		if err := order[i].tool.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// compensate runs the compensating action of the worker's tool named name
+// for a call with args, which returned result.
+func (w *Worker) compensate(ctx context.Context, name string, args map[string]any, result string) error {
+	tool := w.tools.Find(name)
+	if tool == nil {
+		return fmt.Errorf("tool %q not found on the worker", name)
+	}
+	c, ok := tool.(cogito.CompensatedTool)
+	if !ok || c.ToolCompensation() == nil {
+		return fmt.Errorf("tool %q has no compensating action on the worker", name)
+	}
+	return c.ToolCompensation()(ctx, args, result)
+}
+
+func (w *Worker) runAgent(ctx context.Context, spec cogito.AgentRunSpec) (JobResult, error) {
+	llm := w.llm
+	if w.llmFactory != nil && (spec.Model != "" || spec.Temperature != 0 || spec.Metadata != nil) {
+		llm = w.llmFactory(spec.Model, spec.Temperature, spec.Metadata)
+	}
+	if llm == nil {
+		return JobResult{}, errors.New("the worker has no LLM to run agents")
+	}
+
+	tools := w.tools
+	if len(spec.Tools) > 0 {
+		tools = nil
+		for _, tool := range w.tools {
+			if slices.Contains(spec.Tools, tool.Tool().Function.Name) {
+				tools = append(tools, tool)
+			}
+		}
+	}
+
+	f := cogito.NewEmptyFragment()
+	if spec.SystemPrompt != "" {
+		f = f.AddMessage(cogito.SystemMessageRole, spec.SystemPrompt)
+	}
+	f = f.AddMessage(cogito.UserMessageRole, spec.Task)
+
+	opts := append(slices.Clone(w.opts), cogito.WithTools(tools...))
+	result, err := cogito.ExecuteToolsAndAnswer(ctx, llm, f, opts...)
+	if err != nil {
+		return JobResult{}, err
+	}
+	return JobResult{Result: result.LastMessage().Content, Fragment: &result}, nil
+}
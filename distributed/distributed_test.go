@@ -0,0 +1,183 @@
+package distributed_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	"github.com/mudler/cogito/distributed"
+	"github.com/sashabaranov/go-openai"
+)
+
+// serve runs a worker on q until the test ends.
+func serve(t *testing.T, q distributed.Queue, w *distributed.Worker, subject string) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		w.Serve(ctx, q, subject)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+func TestRemoteTool(t *testing.T) {
+	q := distributed.NewMemoryQueue()
+
+	// The worker has the implementation, the coordinator only the definition
+	browser := cogitotest.NewMockTool("browse", "Browse a web page")
+	cogitotest.SetRunResult(browser, "<html>Rome</html>")
+	serve(t, q, distributed.NewWorker(nil, browser), "tools")
+
+	llm := cogitotest.NewMockLLM()
+	llm.AddCreateChatCompletionFunction("browse", `{"query": "https://example.com"}`)
+	llm.SetAskResponse("The page is about Rome.")
+	stub := cogitotest.NewMockTool("browse", "Browse a web page")
+
+	ctx := cogito.ContextWithTenant(context.Background(), "acme")
+	result, err := cogito.ExecuteTools(llm, cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "Read the page"),
+		cogito.WithTools(distributed.RemoteTool(q, "tools", stub)), cogito.WithIterations(1), cogito.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Status.ToolResults) != 1 || result.Status.ToolResults[0].Result != "<html>Rome</html>" {
+		t.Fatalf("tool results = %+v", result.Status.ToolResults)
+	}
+	if len(cogitotest.GetMockTool(browser).Calls()) != 1 || len(cogitotest.GetMockTool(stub).Calls()) != 0 {
+		t.Fatal("the tool did not run on the worker")
+	}
+}
+
+type plotArgs struct {
+	Series string `json:"series"`
+}
+
+// plotter is a stateful tool returning charts.
+type plotter struct {
+	inits, closes int
+	undone        []string
+}
+
+func (p *plotter) Init(ctx context.Context) error { p.inits++; return nil }
+func (p *plotter) Close() error                   { p.closes++; return nil }
+func (p *plotter) Healthy() error                 { return nil }
+
+func (p *plotter) Run(args plotArgs) (string, any, error) {
+	return "", cogito.ToolResult{Text: "Plotted " + args.Series, Images: []cogito.Multimedia{
+		cogito.NewImageFromBytes([]byte("\x89PNG\r\n\x1a\nchart"), "image/png"),
+	}}, nil
+}
+
+func TestRemoteToolRichResultsLifecycleAndCompensation(t *testing.T) {
+	q := distributed.NewMemoryQueue()
+
+	p := &plotter{}
+	workerTool := &cogito.ToolDefinition[plotArgs]{ToolRunner: p, InputArguments: plotArgs{}, Name: "plot", Description: "Plot a series",
+		Compensation: func(ctx context.Context, args map[string]any, result string) error {
+			p.undone = append(p.undone, result)
+			return nil
+		}}
+	w := distributed.NewWorker(nil, workerTool)
+	serve(t, q, w, "tools")
+
+	// The definition of the coordinator declares the compensation, which
+	// runs on the worker
+	stub := &cogito.ToolDefinition[plotArgs]{ToolRunner: &plotter{}, InputArguments: plotArgs{}, Name: "plot", Description: "Plot a series",
+		Compensation: func(ctx context.Context, args map[string]any, result string) error {
+			t.Error("the compensation ran on the coordinator")
+			return nil
+		}}
+
+	llm := cogitotest.NewMockLLM()
+	llm.AddCreateChatCompletionFunction("plot", `{"series": "sales"}`)
+	llm.AddCreateChatCompletionFunction("plot", `{"series": "costs"}`)
+	result, err := cogito.ExecuteTools(llm, cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "Plot sales and costs"),
+		cogito.WithTools(distributed.RemoteTool(q, "tools", stub)), cogito.WithIterations(3), cogito.WithMaxRetries(1),
+		cogito.EnableCompensation)
+	if err == nil {
+		t.Fatal("the run did not fail")
+	}
+
+	if len(result.Multimedia) == 0 {
+		t.Fatal("the chart did not reach the coordinator")
+	}
+	if data, ok := result.Status.ToolResults[0].ResultData.(*cogito.ToolResult); !ok || len(data.Images) != 1 {
+		t.Fatalf("result data = %#v", result.Status.ToolResults[0].ResultData)
+	}
+	if len(p.undone) != 2 || len(result.Status.Compensations) != 2 {
+		t.Fatalf("compensated %v, statuses %+v", p.undone, result.Status.Compensations)
+	}
+
+	if p.inits != 1 || p.closes != 0 {
+		t.Fatalf("inits = %d, closes = %d", p.inits, p.closes)
+	}
+	if err := w.Close(); err != nil || p.closes != 1 {
+		t.Fatalf("Close() = %v, closes = %d", err, p.closes)
+	}
+}
+
+func TestDispatcherKeepsTheImagesOfTheAgent(t *testing.T) {
+	q := distributed.NewMemoryQueue()
+
+	workerLLM := cogitotest.NewMockLLM()
+	workerLLM.AddCreateChatCompletionFunction("plot", `{"series": "sales"}`)
+	workerLLM.SetAskResponse("Sales are growing.")
+	serve(t, q, distributed.NewWorker(workerLLM, cogito.NewToolDefinition(&plotter{}, plotArgs{}, "plot", "Plot a series")), "agents")
+
+	f, err := distributed.Dispatcher(q, "agents")(context.Background(), cogito.AgentRunSpec{Task: "Plot the sales"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(f.Multimedia) != 1 || !strings.HasPrefix(f.Multimedia[0].URL(), "data:image/png;base64,") {
+		t.Fatalf("multimedia = %+v", f.Multimedia)
+	}
+}
+
+func TestRemoteToolErrors(t *testing.T) {
+	q := distributed.NewMemoryQueue()
+	serve(t, q, distributed.NewWorker(nil), "tools")
+
+	tool := distributed.RemoteTool(q, "tools", cogitotest.NewMockTool("browse", "Browse a web page"))
+	_, _, err := tool.Execute(map[string]any{})
+	if err == nil || !strings.Contains(err.Error(), `tool "browse" not found`) {
+		t.Fatalf("error = %v", err)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	q := distributed.NewMemoryQueue()
+
+	workerLLM := cogitotest.NewMockLLM()
+	workerLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+		Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Rome is the capital of Italy."},
+	}}})
+	serve(t, q, distributed.NewWorker(workerLLM), "agents")
+
+	llm := cogitotest.NewMockLLM()
+	llm.AddCreateChatCompletionFunction("spawn_agent", `{"task": "Find the capital of Italy", "background": false}`)
+	llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+		Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Done."},
+	}}})
+	llm.SetAskResponse("Rome.")
+
+	result, err := cogito.ExecuteTools(llm, cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "What is the capital of Italy?"),
+		cogito.EnableAgentSpawning, cogito.WithAgentDispatcher(distributed.Dispatcher(q, "agents")), cogito.WithIterations(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, m := range result.Messages {
+		found = found || strings.Contains(m.Content, "Rome is the capital of Italy.")
+	}
+	if !found {
+		t.Fatal("the answer of the remote sub-agent is not in the conversation")
+	}
+	if len(workerLLM.Requests()) == 0 {
+		t.Fatal("the sub-agent did not run on the worker")
+	}
+}
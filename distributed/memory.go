@@ -0,0 +1,69 @@
+package distributed
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryQueue is a Queue within a process, e.g. to run workers in goroutines
+// or in tests.
+type MemoryQueue struct {
+	mu       sync.Mutex
+	subjects map[string]chan memoryRequest
+}
+
+var _ Queue = (*MemoryQueue)(nil)
+
+type memoryRequest struct {
+	data  []byte
+	reply chan memoryReply
+}
+
+type memoryReply struct {
+	data []byte
+	err  error
+}
+
+// NewMemoryQueue returns an empty MemoryQueue.
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{subjects: map[string]chan memoryRequest{}}
+}
+
+func (q *MemoryQueue) subject(name string) chan memoryRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	ch, ok := q.subjects[name]
+	if !ok {
+		ch = make(chan memoryRequest)
+		q.subjects[name] = ch
+	}
+	return ch
+}
+
+func (q *MemoryQueue) Request(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	req := memoryRequest{data: data, reply: make(chan memoryReply, 1)}
+	select {
+	case q.subject(subject) <- req:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case reply := <-req.reply:
+		return reply.data, reply.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (q *MemoryQueue) Serve(ctx context.Context, subject string, handler Handler) error {
+	ch := q.subject(subject)
+	for {
+		select {
+		case req := <-ch:
+			data, err := handler(ctx, req.data)
+			req.reply <- memoryReply{data: data, err: err}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package distributed
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+// testQueue checks that q carries requests to a worker and replies back,
+// including the errors of the handler.
+func testQueue(t *testing.T, q Queue) {
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- q.Serve(ctx, "jobs", func(ctx context.Context, data []byte) ([]byte, error) {
+			if string(data) == "fail" {
+				return nil, errors.New("job failed")
+			}
+			return []byte(strings.ToUpper(string(data))), nil
+		})
+	}()
+
+	for _, job := range []string{"hello", "line\r\nbreak"} {
+		reply, err := q.Request(context.Background(), "jobs", []byte(job))
+		for errors.Is(err, ErrNoWorkers) {
+			// The worker is not subscribed yet
+			time.Sleep(10 * time.Millisecond)
+			reply, err = q.Request(context.Background(), "jobs", []byte(job))
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(reply) != strings.ToUpper(job) {
+			t.Fatalf("reply = %q", reply)
+		}
+	}
+	if _, err := q.Request(context.Background(), "jobs", []byte("fail")); err == nil || err.Error() != "job failed" {
+		t.Fatalf("error = %v, want job failed", err)
+	}
+
+	cancel()
+	if err := <-served; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Serve returned %v", err)
+	}
+}
+
+func TestMemoryQueue(t *testing.T) {
+	testQueue(t, NewMemoryQueue())
+}
+
+func TestNATSQueue(t *testing.T) {
+	q, err := DialNATS("nats://token@" + runNATS(t, "token"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	testQueue(t, q)
+}
+
+func TestRedisQueue(t *testing.T) {
+	r := miniredis.RunT(t)
+	r.RequireAuth("secret")
+	q, err := NewRedisQueue("redis://:secret@" + r.Addr() + "/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer q.Close()
+	testQueue(t, q)
+}
+
+// runNATS runs a NATS server requiring token.
+func runNATS(t *testing.T, token string) string {
+	t.Helper()
+	s, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, Authorization: token, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.Start()
+	t.Cleanup(s.Shutdown)
+	if !s.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	return s.Addr().String()
+}
@@ -0,0 +1,154 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPollInterval bounds the blocking pops, so they stop soon after their
+// context is done.
+const redisPollInterval = time.Second
+
+// RedisQueue is a Queue over Redis lists. Jobs are pushed on the list of
+// their subject and popped by one of the workers; each reply is pushed on a
+// list of its own, expiring after ReplyTTL. Jobs wait in Redis while no
+// worker is serving their subject.
+type RedisQueue struct {
+	// Prefix is prepended to the keys of the queue. Defaults to "cogito:".
+	Prefix string
+	// ReplyTTL is how long replies no one waits for are kept. Defaults to a
+	// minute.
+	ReplyTTL time.Duration
+
+	client redis.UniversalClient
+}
+
+var _ Queue = (*RedisQueue)(nil)
+
+// NewRedisQueue returns a queue on the Redis server at addr, e.g.
+// "redis://:password@localhost:6379/0" or "localhost:6379".
+func NewRedisQueue(addr string) (*RedisQueue, error) {
+	if !strings.Contains(addr, "://") {
+		addr = "redis://" + addr
+	}
+	options, err := redis.ParseURL(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Redis address: %w", err)
+	}
+	return NewRedisQueueWithClient(redis.NewClient(options)), nil
+}
+
+// NewRedisQueueWithClient returns a queue over client, e.g. a cluster or
+// sentinel client.
+func NewRedisQueueWithClient(client redis.UniversalClient) *RedisQueue {
+	return &RedisQueue{client: client}
+}
+
+// Close closes the client.
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
+
+func (q *RedisQueue) key(name string) string {
+	if q.Prefix == "" {
+		return "cogito:" + name
+	}
+	return q.Prefix + name
+}
+
+// redisJob is a job as pushed on the list of its subject.
+type redisJob struct {
+	Reply string `json:"reply"` // key of the reply list
+	Data  []byte `json:"data"`
+}
+
+// redisReply is the reply pushed on the reply list of a job.
+type redisReply struct {
+	Data  []byte `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+func (q *RedisQueue) Request(ctx context.Context, subject string, data []byte) ([]byte, error) {
+	replyKey := q.key("reply:" + uuid.NewString())
+	job, _ := json.Marshal(redisJob{Reply: replyKey, Data: data})
+	if err := q.client.LPush(ctx, q.key(subject), job).Err(); err != nil {
+		return nil, fmt.Errorf("failed to send job: %w", err)
+	}
+	popped, err := q.pop(ctx, replyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to receive reply: %w", err)
+	}
+
+	var reply redisReply
+	if err := json.Unmarshal([]byte(popped), &reply); err != nil {
+		return nil, fmt.Errorf("malformed reply: %w", err)
+	}
+	if reply.Error != "" {
+		return nil, errors.New(reply.Error)
+	}
+	return reply.Data, nil
+}
+
+func (q *RedisQueue) Serve(ctx context.Context, subject string, handler Handler) error {
+	ttl := q.ReplyTTL
+	if ttl <= 0 {
+		ttl = time.Minute
+	}
+	for {
+		popped, err := q.pop(ctx, q.key(subject))
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("failed to receive job: %w", err)
+		}
+		var job redisJob
+		if err := json.Unmarshal([]byte(popped), &job); err != nil {
+			// Not a job of ours
+			continue
+		}
+
+		data, err := handler(ctx, job.Data)
+		reply := redisReply{Data: data}
+		if err != nil {
+			reply.Error = err.Error()
+		}
+		encoded, _ := json.Marshal(reply)
+		// The reply is sent even when ctx is done, as the job was taken
+		replyCtx := context.WithoutCancel(ctx)
+		_, err = q.client.TxPipelined(replyCtx, func(pipe redis.Pipeliner) error {
+			pipe.LPush(replyCtx, job.Reply, encoded)
+			pipe.PExpire(replyCtx, job.Reply, ttl)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to send reply: %w", err)
+		}
+	}
+}
+
+// pop blocks until an element can be popped from the list key, or ctx is
+// done.
+func (q *RedisQueue) pop(ctx context.Context, key string) (string, error) {
+	for {
+		item, err := q.client.BRPop(ctx, redisPollInterval, key).Result()
+		switch {
+		case errors.Is(err, redis.Nil):
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+		case err != nil:
+			return "", err
+		default:
+			// BRPOP replies with the key and the element
+			return item[1], nil
+		}
+	}
+}
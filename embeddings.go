@@ -0,0 +1,29 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+)
+
+// Embedder computes embedding vectors, one per text and in the same order.
+// clients.OpenAIClient implements it for OpenAI-compatible /embeddings
+// endpoints.
+type Embedder interface {
+	Embeddings(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// EmbeddingFuncOf adapts e to an EmbeddingFunc, for EmbeddingLoopDetector,
+// NewSemanticCompletionCache and the other features embedding one text at
+// a time.
+func EmbeddingFuncOf(e Embedder) EmbeddingFunc {
+	return func(ctx context.Context, text string) ([]float32, error) {
+		embeddings, err := e.Embeddings(ctx, []string{text})
+		if err != nil {
+			return nil, err
+		}
+		if len(embeddings) != 1 {
+			return nil, fmt.Errorf("expected 1 embedding, got %d", len(embeddings))
+		}
+		return embeddings[0], nil
+	}
+}
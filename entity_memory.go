@@ -0,0 +1,173 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Entity is a value established in a conversation, like an email address
+// mentioned by the user or the city a tool was called for.
+type Entity struct {
+	Kind  string // e.g. "email", "date", or the name of a tool argument
+	Value string
+	// Source is where the value was seen: "user" for the user messages, or
+	// the name of the tool it was an argument of.
+	Source string
+}
+
+// EntityExtractor finds entities in the text of a user message.
+type EntityExtractor func(text string) []Entity
+
+// RegexpEntityExtractor returns an extractor of the matches of re, as
+// entities of kind.
+func RegexpEntityExtractor(kind string, re *regexp.Regexp) EntityExtractor {
+	return func(text string) []Entity {
+		var entities []Entity
+		for _, match := range re.FindAllString(text, -1) {
+			entities = append(entities, Entity{Kind: kind, Value: match, Source: "user"})
+		}
+		return entities
+	}
+}
+
+// DefaultEntityExtractors find emails, URLs, ISO dates, UUIDs and issue
+// style IDs (#123, PROJ-123).
+func DefaultEntityExtractors() []EntityExtractor {
+	return []EntityExtractor{
+		RegexpEntityExtractor("email", regexp.MustCompile(`[\w.+-]+@[\w-]+(?:\.[\w-]+)+`)),
+		RegexpEntityExtractor("url", regexp.MustCompile(`https?://[^\s)>\]"']+`)),
+		RegexpEntityExtractor("date", regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}\b`)),
+		RegexpEntityExtractor("uuid", regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)),
+		RegexpEntityExtractor("id", regexp.MustCompile(`(?:^|\s)#\d+\b|\b[A-Z][A-Z0-9]+-\d+\b`)),
+	}
+}
+
+// maxEntities bounds the entities kept by an EntityMemory; the least
+// recently seen are forgotten first.
+const maxEntities = 50
+
+// EntityMemory records the values established in a conversation: the
+// entities found in the user messages, and the scalar arguments of the
+// successful tool calls. WithEntityMemory hints them to the LLM when it
+// selects tools and generates their arguments, so repeated calls reuse them
+// instead of asking again or inventing new ones.
+//
+// Use one memory per conversation, shared by its runs. The entities of
+// each tenant (see ContextWithTenant) are kept apart. It is safe for
+// concurrent use.
+type EntityMemory struct {
+	mu         sync.Mutex
+	extractors []EntityExtractor
+	entities   map[string][]Entity // by tenant, least recently seen first
+}
+
+// NewEntityMemory returns an empty memory using extractors on the user
+// messages, or DefaultEntityExtractors when there are none.
+func NewEntityMemory(extractors ...EntityExtractor) *EntityMemory {
+	if len(extractors) == 0 {
+		extractors = DefaultEntityExtractors()
+	}
+	return &EntityMemory{extractors: extractors, entities: map[string][]Entity{}}
+}
+
+// Remember records an entity for the tenant of ctx, as the most recently
+// seen.
+func (m *EntityMemory) Remember(ctx context.Context, e Entity) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.remember(TenantFromContext(ctx), e)
+}
+
+func (m *EntityMemory) remember(tenant string, e Entity) {
+	e.Value = strings.TrimSpace(e.Value)
+	if e.Kind == "" || e.Value == "" {
+		return
+	}
+	entities := slices.DeleteFunc(m.entities[tenant], func(known Entity) bool {
+		return known.Kind == e.Kind && known.Value == e.Value
+	})
+	entities = append(entities, e)
+	if len(entities) > maxEntities {
+		entities = slices.Delete(entities, 0, len(entities)-maxEntities)
+	}
+	m.entities[tenant] = entities
+}
+
+// Entities returns the entities recorded for the tenant of ctx, the most
+// recently seen first.
+func (m *EntityMemory) Entities(ctx context.Context) []Entity {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entities := slices.Clone(m.entities[TenantFromContext(ctx)])
+	slices.Reverse(entities)
+	return entities
+}
+
+// Forget drops the entities of kind recorded for the tenant of ctx, or all
+// of them when kind is empty.
+func (m *EntityMemory) Forget(ctx context.Context, kind string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenant := TenantFromContext(ctx)
+	if kind == "" {
+		delete(m.entities, tenant)
+		return
+	}
+	m.entities[tenant] = slices.DeleteFunc(m.entities[tenant], func(e Entity) bool { return e.Kind == kind })
+}
+
+// observe records the entities of the user messages of f for the tenant of
+// ctx.
+func (m *EntityMemory) observe(ctx context.Context, f Fragment) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenant := TenantFromContext(ctx)
+	for _, msg := range f.Messages {
+		if msg.Role != UserMessageRole.String() {
+			continue
+		}
+		for _, extract := range m.extractors {
+			for _, e := range extract(msg.Content) {
+				m.remember(tenant, e)
+			}
+		}
+	}
+}
+
+// rememberArguments records the scalar arguments of a successful call for
+// the tenant of ctx.
+func (m *EntityMemory) rememberArguments(ctx context.Context, choice *ToolChoice) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tenant := TenantFromContext(ctx)
+	for name, value := range choice.Arguments {
+		switch value.(type) {
+		case string, float64, int, int64, bool:
+			m.remember(tenant, Entity{Kind: name, Value: fmt.Sprint(value), Source: choice.Name})
+		}
+	}
+}
+
+// entityHintsMessage records the entities of f in the memory of o and
+// returns the message hinting them, false when there are none.
+func entityHintsMessage(o *Options, f Fragment) (openai.ChatCompletionMessage, bool, error) {
+	o.entityMemory.observe(o.context, f)
+	entities := o.entityMemory.Entities(o.context)
+	if len(entities) == 0 {
+		return openai.ChatCompletionMessage{}, false, nil
+	}
+	content, err := o.prompts.GetPrompt(prompt.PromptEntityHintsType).Render(struct {
+		Entities []Entity
+	}{Entities: entities})
+	if err != nil {
+		return openai.ChatCompletionMessage{}, false, fmt.Errorf("failed to render entity hints prompt: %w", err)
+	}
+	return openai.ChatCompletionMessage{Role: SystemMessageRole.String(), Content: content}, true, nil
+}
@@ -0,0 +1,80 @@
+package cogito_test
+
+import (
+	"context"
+	"regexp"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Entity memory", func() {
+	var mockLLM *cogitotest.MockLLM
+	var memory *EntityMemory
+	var weatherTool ToolDefinitionInterface
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		memory = NewEntityMemory()
+		weatherTool = NewToolDefinition(forecastRunner{}, WeatherArgs{}, "get_weather", "Get the weather")
+	})
+
+	It("hints the values of the user messages during tool selection", func() {
+		mockLLM.Expect("hints the email", cogitotest.SystemPromptContains("- email: ada@example.com"))
+		mockLLM.Expect("hints the date", cogitotest.SystemPromptContains("- date: 2026-03-01"))
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("Sunny, have a nice flight.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Check the weather for my flight to Rome on 2026-03-01, and mail it to ada@example.com")
+		_, err := ExecuteTools(mockLLM, f, WithTools(weatherTool), WithEntityMemory(memory), WithIterations(1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mockLLM.Verify()).To(Succeed())
+	})
+
+	It("remembers the arguments of tool calls for later runs", func() {
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("Sunny in Rome.")
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		_, err := ExecuteTools(mockLLM, f, WithTools(weatherTool), WithEntityMemory(memory), WithIterations(1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(memory.Entities(context.Background())).To(ContainElement(Entity{Kind: "city", Value: "Rome", Source: "get_weather"}))
+
+		mockLLM.Expect("hints the city", cogitotest.SystemPromptContains("- city: Rome (used with get_weather)"))
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("Still sunny.")
+		f = f.AddMessage(AssistantMessageRole, "Sunny in Rome.").AddMessage(UserMessageRole, "And tomorrow?")
+		_, err = ExecuteTools(mockLLM, f, WithTools(weatherTool), WithEntityMemory(memory), WithIterations(1))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mockLLM.Verify()).To(Succeed())
+	})
+
+	It("keeps the most recently seen values first", func() {
+		memory = NewEntityMemory(RegexpEntityExtractor("order", regexp.MustCompile(`ORD\d+`)))
+		ctx := context.Background()
+		memory.Remember(ctx, Entity{Kind: "city", Value: "Rome", Source: "get_weather"})
+		memory.Remember(ctx, Entity{Kind: "city", Value: "Paris", Source: "get_weather"})
+		memory.Remember(ctx, Entity{Kind: "city", Value: "Rome", Source: "get_weather"})
+		Expect(memory.Entities(ctx)).To(Equal([]Entity{
+			{Kind: "city", Value: "Rome", Source: "get_weather"},
+			{Kind: "city", Value: "Paris", Source: "get_weather"},
+		}))
+
+		memory.Forget(ctx, "city")
+		Expect(memory.Entities(ctx)).To(BeEmpty())
+	})
+
+	It("keeps the entities of each tenant apart", func() {
+		acme, globex := ContextWithTenant(context.Background(), "acme"), ContextWithTenant(context.Background(), "globex")
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("Sunny in Rome.")
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome? Mail it to ada@example.com")
+		_, err := ExecuteToolsContext(acme, mockLLM, f, WithTools(weatherTool), WithEntityMemory(memory), WithIterations(1))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(memory.Entities(acme)).To(ContainElement(Entity{Kind: "city", Value: "Rome", Source: "get_weather"}))
+		Expect(memory.Entities(globex)).To(BeEmpty())
+		Expect(memory.Entities(context.Background())).To(BeEmpty())
+	})
+})
@@ -0,0 +1,195 @@
+// Package events fans the structured events of agent runs out to any number
+// of subscribers, so web frontends can render live reasoning, tool calls and
+// results without polling. Stream is transport-agnostic; SSEHandler and
+// WebSocketHandler serve it over HTTP.
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mudler/cogito"
+)
+
+// TypeEnd is the type of the event published by Stream.End when a run is
+// over.
+const TypeEnd = "end"
+
+// Event is the JSON form of a cogito.StreamEvent, tagged with its run.
+type Event struct {
+	Type          string           `json:"type"`
+	RunID         string           `json:"run_id,omitempty"`
+	Time          time.Time        `json:"time"`
+	Content       string           `json:"content,omitempty"`
+	ToolName      string           `json:"tool_name,omitempty"`
+	ToolArgs      string           `json:"tool_args,omitempty"`
+	ToolCallID    string           `json:"tool_call_id,omitempty"`
+	ToolCallIndex int              `json:"tool_call_index,omitempty"`
+	ToolResult    string           `json:"tool_result,omitempty"`
+	FinishReason  string           `json:"finish_reason,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	Usage         *cogito.LLMUsage `json:"usage,omitempty"`
+	AgentID       string           `json:"agent_id,omitempty"`
+	Progress      float64          `json:"progress,omitempty"`
+	Total         float64          `json:"total,omitempty"`
+}
+
+// FromStreamEvent converts ev, emitted by the run runID.
+func FromStreamEvent(runID string, ev cogito.StreamEvent) Event {
+	e := Event{
+		Type:          string(ev.Type),
+		RunID:         runID,
+		Time:          time.Now(),
+		Content:       ev.Content,
+		ToolName:      ev.ToolName,
+		ToolArgs:      ev.ToolArgs,
+		ToolCallID:    ev.ToolCallID,
+		ToolCallIndex: ev.ToolCallIndex,
+		ToolResult:    ev.ToolResult,
+		FinishReason:  ev.FinishReason,
+		AgentID:       ev.AgentID,
+		Progress:      ev.Progress,
+		Total:         ev.Total,
+	}
+	if ev.Error != nil {
+		e.Error = ev.Error.Error()
+	}
+	if ev.Usage != (cogito.LLMUsage{}) {
+		usage := ev.Usage
+		e.Usage = &usage
+	}
+	return e
+}
+
+// subscriberBuffer is the number of live events a subscriber can lag behind
+// before events are dropped for it. The events replayed to a late subscriber
+// come on top, so they are never dropped.
+const subscriberBuffer = 256
+
+type subscriber struct {
+	runID string
+	ch    chan Event
+}
+
+// Stream dispatches the events of runs to subscribers. The events of a run
+// are kept until the run ends, so subscribers joining late receive them
+// too; only the end event is kept for ended runs. A subscriber that does not
+// keep up loses events rather than slowing the run down.
+type Stream struct {
+	mu      sync.Mutex
+	subs    map[*subscriber]struct{}
+	history map[string][]Event
+	ended   map[string]Event // end events, by run
+	closed  bool
+}
+
+func New() *Stream {
+	return &Stream{
+		subs:    map[*subscriber]struct{}{},
+		history: map[string][]Event{},
+		ended:   map[string]Event{},
+	}
+}
+
+// Callback returns the callback to pass to cogito.WithStreamCallback to
+// publish the events of the run runID.
+func (s *Stream) Callback(runID string) cogito.StreamCallback {
+	return func(ev cogito.StreamEvent) {
+		s.Publish(FromStreamEvent(runID, ev))
+	}
+}
+
+// Publish sends e to the subscribers of its run and to the subscribers of
+// all runs.
+func (s *Stream) Publish(e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if e.Type == TypeEnd {
+		delete(s.history, e.RunID)
+		s.ended[e.RunID] = e
+	} else {
+		delete(s.ended, e.RunID)
+		s.history[e.RunID] = append(s.history[e.RunID], e)
+	}
+	for sub := range s.subs {
+		if sub.runID != "" && sub.runID != e.RunID {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+		}
+		if e.Type == TypeEnd && sub.runID != "" {
+			delete(s.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// End publishes the end of the run runID, with its error if any, and closes
+// the subscriptions to it. Its events are forgotten, except for the end
+// event itself.
+func (s *Stream) End(runID string, err error) {
+	e := Event{Type: TypeEnd, RunID: runID}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	s.Publish(e)
+}
+
+// Subscribe returns the events of the run runID, starting with the ones
+// already published, or of every run when runID is empty. The channel is
+// closed when the run ends, when the stream is closed or when cancel is
+// called; for a run that already ended, it only carries the end event.
+// Subscribing to a run that did not start yet waits for it.
+func (s *Stream) Subscribe(runID string) (events <-chan Event, cancel func()) {
+	s.mu.Lock()
+	history := s.history[runID]
+	sub := &subscriber{runID: runID, ch: make(chan Event, len(history)+subscriberBuffer)}
+	if s.closed {
+		s.mu.Unlock()
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	if runID != "" {
+		if end, ok := s.ended[runID]; ok {
+			s.mu.Unlock()
+			sub.ch <- end
+			close(sub.ch)
+			return sub.ch, func() {}
+		}
+		for _, e := range history {
+			sub.ch <- e
+		}
+	}
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+
+	return sub.ch, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if _, ok := s.subs[sub]; ok {
+			delete(s.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Close ends every subscription.
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for sub := range s.subs {
+		close(sub.ch)
+	}
+	s.subs = map[*subscriber]struct{}{}
+	s.history = map[string][]Event{}
+	s.ended = map[string]Event{}
+}
@@ -0,0 +1,154 @@
+package events
+
+import (
+	"bufio"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mudler/cogito"
+	"golang.org/x/net/websocket"
+)
+
+func TestStreamReplaysAndEndsRuns(t *testing.T) {
+	s := New()
+	all, cancelAll := s.Subscribe("")
+	defer cancelAll()
+
+	publish := s.Callback("run-1")
+	publish(cogito.StreamEvent{Type: cogito.StreamEventReasoning, Content: "thinking"})
+	s.Publish(Event{Type: string(cogito.StreamEventContent), RunID: "run-2", Content: "other run"})
+
+	// A late subscriber receives what was published so far
+	run1, cancel := s.Subscribe("run-1")
+	defer cancel()
+	publish(cogito.StreamEvent{Type: cogito.StreamEventToolResult, ToolName: "search", ToolResult: "found"})
+	s.End("run-1", errors.New("boom"))
+
+	got := []Event{}
+	for e := range run1 {
+		got = append(got, e)
+	}
+	if len(got) != 3 || got[0].Content != "thinking" || got[1].ToolResult != "found" || got[2].Type != TypeEnd || got[2].Error != "boom" {
+		t.Fatalf("run-1 events = %+v", got)
+	}
+	if len(all) != 4 {
+		t.Fatalf("subscriber of all runs got %d events, want 4", len(all))
+	}
+
+	s.Close()
+	if _, ok := <-drain(all); ok {
+		t.Fatal("subscription not closed by Close")
+	}
+}
+
+func TestStreamSubscribeAfterEnd(t *testing.T) {
+	s := New()
+	publish := s.Callback("run-1")
+	for i := 0; i < subscriberBuffer+10; i++ {
+		publish(cogito.StreamEvent{Type: cogito.StreamEventContent, Content: "token"})
+	}
+
+	// A late subscriber receives the whole history, beyond the buffer
+	run1, cancel := s.Subscribe("run-1")
+	defer cancel()
+	if n := len(run1); n != subscriberBuffer+10 {
+		t.Fatalf("replayed %d events, want %d", n, subscriberBuffer+10)
+	}
+
+	s.End("run-1", errors.New("boom"))
+	ended, cancel := s.Subscribe("run-1")
+	defer cancel()
+	select {
+	case e := <-ended:
+		if e.Type != TypeEnd || e.Error != "boom" {
+			t.Fatalf("event = %+v, want the end of the run", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no end event for an ended run")
+	}
+	if _, ok := <-ended; ok {
+		t.Fatal("subscription to an ended run not closed")
+	}
+}
+
+func drain(ch <-chan Event) <-chan Event {
+	for len(ch) > 0 {
+		<-ch
+	}
+	return ch
+}
+
+func TestSSEHandler(t *testing.T) {
+	s := New()
+	server := httptest.NewServer(SSEHandler(s))
+	defer server.Close()
+
+	s.Callback("r")(cogito.StreamEvent{Type: cogito.StreamEventContent, Content: "Hello"})
+	s.End("r", nil)
+	// Ended runs are forgotten: subscribe before the end to follow a run
+	s.Callback("r2")(cogito.StreamEvent{Type: cogito.StreamEventToolCall, ToolName: "search"})
+
+	done := make(chan string)
+	go func() {
+		resp, err := server.Client().Get(server.URL + "?run=r2")
+		if err != nil {
+			done <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body := strings.Builder{}
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			body.WriteString(scanner.Text() + "\n")
+		}
+		done <- body.String()
+	}()
+
+	// Wait for the replayed event to be received before ending the run
+	for {
+		s.mu.Lock()
+		n := len(s.subs)
+		s.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.End("r2", nil)
+
+	body := <-done
+	if !strings.Contains(body, "event: tool_call\ndata: {\"type\":\"tool_call\",\"run_id\":\"r2\"") || !strings.Contains(body, "event: end\n") {
+		t.Fatalf("body = %s", body)
+	}
+	if strings.Contains(body, "Hello") {
+		t.Fatalf("events of other runs were sent: %s", body)
+	}
+}
+
+func TestWebSocketHandler(t *testing.T) {
+	s := New()
+	server := httptest.NewServer(WebSocketHandler(s))
+	defer server.Close()
+
+	s.Callback("r")(cogito.StreamEvent{Type: cogito.StreamEventContent, Content: "Hello"})
+	ws, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"?run=r", "", server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ws.Close()
+
+	var e Event
+	if err := websocket.JSON.Receive(ws, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != "content" || e.Content != "Hello" || e.RunID != "r" {
+		t.Fatalf("event = %+v", e)
+	}
+	s.End("r", nil)
+	if err := websocket.JSON.Receive(ws, &e); err != nil || e.Type != TypeEnd {
+		t.Fatalf("event = %+v, %v", e, err)
+	}
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"golang.org/x/net/websocket"
+)
+
+// SSEHandler serves the events of s as server-sent events, each with its
+// type as event name and its JSON form as data. The "run" query parameter
+// selects a run, whose stream ends with the run; without it the events of
+// every run are sent until the client disconnects.
+func SSEHandler(s *Stream) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, cancel := s.Subscribe(r.URL.Query().Get("run"))
+		defer cancel()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	})
+}
+
+// WebSocketHandler serves the events of s over a WebSocket, one JSON message
+// per event. The "run" query parameter selects a run like for SSEHandler.
+// Messages sent by the client are ignored.
+func WebSocketHandler(s *Stream) http.Handler {
+	return websocket.Handler(func(ws *websocket.Conn) {
+		defer ws.Close()
+		events, cancel := s.Subscribe(ws.Request().URL.Query().Get("run"))
+		defer cancel()
+
+		// Detect the client going away
+		gone := make(chan struct{})
+		go func() {
+			defer close(gone)
+			var discard []byte
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		for {
+			select {
+			case <-gone:
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				if err := websocket.JSON.Send(ws, e); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
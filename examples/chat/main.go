@@ -2,7 +2,6 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -59,28 +58,7 @@ func main() {
 			}),
 			cogito.WithTools(searchTool),
 
-			cogito.WithToolCallBack(func(tool *cogito.ToolChoice, state *cogito.SessionState) cogito.ToolCallDecision {
-				args, err := json.Marshal(tool.Arguments)
-				if err != nil {
-					return cogito.ToolCallDecision{Approved: false}
-				}
-				fmt.Println("The agent wants to run the tool " + tool.Name + " with the following arguments: " + string(args))
-				fmt.Println("Do you want to run the tool? (y/n/adjust)")
-				reader := bufio.NewReader(os.Stdin)
-				text, _ := reader.ReadString('\n')
-				text = strings.TrimSpace(text)
-				switch text {
-				case "y":
-					return cogito.ToolCallDecision{Approved: true}
-				case "n":
-					return cogito.ToolCallDecision{Approved: false}
-				default:
-					return cogito.ToolCallDecision{
-						Approved:   true,
-						Adjustment: text,
-					}
-				}
-			}),
+			cogito.WithToolCallBack(cogito.StdinApproval()),
 		)
 		if err != nil && !errors.Is(err, cogito.ErrNoToolSelected) {
 			panic(err)
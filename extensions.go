@@ -0,0 +1,53 @@
+package cogito
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Extensions holds integrator-defined per-run data on Status (ticket IDs,
+// billing tags, ...), carried through tools, plans and compaction untouched by
+// cogito. Values are stored JSON-encoded so a Status always serializes; use
+// SetExtension and GetExtension to access them with their Go type.
+type Extensions map[string]json.RawMessage
+
+// SetExtension stores value under key in the extensions of s. It fails when
+// value cannot be encoded to JSON.
+func SetExtension(s *Status, key string, value any) error {
+	if s == nil {
+		return fmt.Errorf("cannot set extension %q on a nil status", key)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode extension %q: %w", key, err)
+	}
+	if s.Extensions == nil {
+		s.Extensions = Extensions{}
+	}
+	s.Extensions[key] = data
+	return nil
+}
+
+// GetExtension returns the value stored under key in the extensions of s,
+// decoded as T. ok is false when s has no such extension or it does not
+// decode as T.
+func GetExtension[T any](s *Status, key string) (value T, ok bool) {
+	if s == nil {
+		return value, false
+	}
+	data, found := s.Extensions[key]
+	if !found {
+		return value, false
+	}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return value, false
+	}
+	return value, true
+}
+
+// DeleteExtension removes key from the extensions of s.
+func DeleteExtension(s *Status, key string) {
+	if s != nil {
+		delete(s.Extensions, key)
+	}
+}
@@ -0,0 +1,74 @@
+package cogito_test
+
+import (
+	"encoding/json"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type billingInfo struct {
+	Account string
+	Tags    []string
+}
+
+var _ = Describe("Status extensions", func() {
+	It("stores typed values and survives JSON serialization", func() {
+		f := NewEmptyFragment()
+		Expect(SetExtension(f.Status, "ticket", "T-1234")).To(Succeed())
+		Expect(SetExtension(f.Status, "billing", billingInfo{Account: "acme", Tags: []string{"beta"}})).To(Succeed())
+
+		data, err := json.Marshal(f.Status)
+		Expect(err).ToNot(HaveOccurred())
+		var restored Status
+		Expect(json.Unmarshal(data, &restored)).To(Succeed())
+
+		ticket, ok := GetExtension[string](&restored, "ticket")
+		Expect(ok).To(BeTrue())
+		Expect(ticket).To(Equal("T-1234"))
+
+		billing, ok := GetExtension[billingInfo](&restored, "billing")
+		Expect(ok).To(BeTrue())
+		Expect(billing).To(Equal(billingInfo{Account: "acme", Tags: []string{"beta"}}))
+
+		_, ok = GetExtension[int](&restored, "ticket")
+		Expect(ok).To(BeFalse())
+		_, ok = GetExtension[string](&restored, "missing")
+		Expect(ok).To(BeFalse())
+
+		DeleteExtension(&restored, "ticket")
+		_, ok = GetExtension[string](&restored, "ticket")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("rejects values that cannot be serialized", func() {
+		f := NewEmptyFragment()
+		Expect(SetExtension(f.Status, "callback", func() {})).ToNot(Succeed())
+		Expect(SetExtension(nil, "ticket", "T-1234")).ToNot(Succeed())
+	})
+
+	It("carries extensions through ExecuteTools", func() {
+		llm := cogitotest.NewMockLLM()
+		tool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(tool, "Result")
+		llm.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
+		llm.SetAskResponse("Done")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Search something")
+		Expect(SetExtension(f.Status, "ticket", "T-1234")).To(Succeed())
+
+		var seen string
+		result, err := ExecuteTools(llm, f, WithTools(tool), WithToolCallBack(func(tc *ToolChoice, s *SessionState) ToolCallDecision {
+			seen, _ = GetExtension[string](s.Fragment.Status, "ticket")
+			return ToolCallDecision{Approved: true}
+		}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(seen).To(Equal("T-1234"))
+
+		ticket, ok := GetExtension[string](result.Status, "ticket")
+		Expect(ok).To(BeTrue())
+		Expect(ticket).To(Equal("T-1234"))
+	})
+})
@@ -5,14 +5,13 @@ import (
 
 	"github.com/mudler/cogito/prompt"
 	"github.com/mudler/cogito/structures"
-
-	"github.com/mudler/xlog"
 )
 
 // ExtractBoolean extracts a boolean from a conversation
 func ExtractBoolean(llm LLM, f Fragment, opts ...Option) (*structures.Boolean, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	prompter := o.prompts.GetPrompt(prompt.PromptBooleanType)
 
@@ -31,7 +30,7 @@ func ExtractBoolean(llm LLM, f Fragment, opts ...Option) (*structures.Boolean, e
 
 	booleanConv := NewEmptyFragment().AddMessage("user", prompt)
 
-	err = booleanConv.ExtractStructure(o.context, llm, structure)
+	err = booleanConv.ExtractStructure(o.context, llm, structure, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract boolean structure: %w", err)
 	}
@@ -42,6 +41,7 @@ func ExtractBoolean(llm LLM, f Fragment, opts ...Option) (*structures.Boolean, e
 func ExtractKnowledgeGaps(llm LLM, f Fragment, opts ...Option) ([]string, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	prompter := o.prompts.GetPrompt(prompt.GapAnalysisType)
 
@@ -49,7 +49,7 @@ func ExtractKnowledgeGaps(llm LLM, f Fragment, opts ...Option) ([]string, error)
 		Text    string
 		Context string
 	}{
-		Text: f.String(),
+		Text: conversationContext(o, prompt.GapAnalysisType, f),
 	}
 
 	if f.ParentFragment != nil {
@@ -65,7 +65,7 @@ func ExtractKnowledgeGaps(llm LLM, f Fragment, opts ...Option) ([]string, error)
 		return nil, fmt.Errorf("failed to render gap analysis prompt: %w", err)
 	}
 
-	xlog.Debug("Analyzing knowledge gaps", "prompt", prompt)
+	o.logger.Debug("Analyzing knowledge gaps", "prompt", prompt)
 	newFragment := NewEmptyFragment().AddMessage("system", prompt)
 
 	f, err = llm.Ask(o.context, newFragment)
@@ -73,11 +73,11 @@ func ExtractKnowledgeGaps(llm LLM, f Fragment, opts ...Option) ([]string, error)
 		return nil, err
 	}
 
-	xlog.Debug("LLM response for gap analysis", "response", f.String())
+	o.logger.Debug("LLM response for gap analysis", "response", f.String())
 	o.statusCallback(f.LastMessage().Content)
 
 	structure, gaps := structures.StructureGaps()
-	err = f.ExtractStructure(o.context, llm, structure)
+	err = f.ExtractStructure(o.context, llm, structure, opts...)
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,193 @@
+package cogito
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// UnsupportedClaim is a claim of an answer that the tool results of the run
+// do not support. See EnableFactCheck.
+type UnsupportedClaim struct {
+	Claim      string `json:"claim"`
+	Correction string `json:"correction,omitempty"` // what the evidence says instead, if anything
+}
+
+type factCheckResponse struct {
+	Unsupported []UnsupportedClaim `json:"unsupported"`
+	Revised     string             `json:"revised"`
+}
+
+type factCheckToolRunner struct{}
+
+func (f *factCheckToolRunner) Run(args factCheckResponse) (string, any, error) {
+	return "", nil, fmt.Errorf("fact check tool should not be executed")
+}
+
+func (f *factCheckToolRunner) NewArgs() *factCheckResponse {
+	return &factCheckResponse{}
+}
+
+// factCheckTool asks the LLM for the unsupported claims of an answer.
+func factCheckTool() *ToolDefinition[factCheckResponse] {
+	return &ToolDefinition[factCheckResponse]{
+		ToolRunner:  &factCheckToolRunner{},
+		Name:        "report_unsupported_claims",
+		Description: "Report the claims of the answer that the evidence does not support.",
+		InputArguments: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"unsupported": map[string]interface{}{
+					"type":        "array",
+					"description": "The unsupported claims. Empty if every claim is supported.",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"claim": map[string]interface{}{
+								"type":        "string",
+								"description": "The claim, as stated in the answer.",
+							},
+							"correction": map[string]interface{}{
+								"type":        "string",
+								"description": "What the evidence says instead. Empty if the evidence says nothing about it.",
+							},
+						},
+						"required": []string{"claim"},
+					},
+				},
+				"revised": map[string]interface{}{
+					"type":        "string",
+					"description": "The answer with the unsupported claims corrected or removed. Empty if every claim is supported.",
+				},
+			},
+			"required": []string{"unsupported"},
+		},
+	}
+}
+
+// FactCheck cross-checks the claims of the final assistant reply of f
+// against the tool results recorded in its status. Unsupported claims are
+// recorded in Status.UnsupportedClaims and the reply is rewritten without
+// them, or annotated with EnableFactCheckAnnotations. f is returned
+// unchanged if it has no final reply or no tool results.
+func FactCheck(llm LLM, f Fragment, opts ...Option) (Fragment, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	return checkFacts(o, llm, f)
+}
+
+// factCheckAnswer is FactCheck for the end of ExecuteTools: failures are
+// logged and the answer is kept.
+func factCheckAnswer(o *Options, llm LLM, f Fragment) Fragment {
+	checked, err := checkFacts(o, llm, f)
+	if err != nil {
+		o.logger.Warn("Failed to fact-check the answer", "error", err)
+		return f
+	}
+	return checked
+}
+
+func checkFacts(o *Options, llm LLM, f Fragment) (Fragment, error) {
+	index := finalAnswerIndex(f)
+	if index < 0 || f.Status == nil || len(f.Status.ToolResults) == 0 {
+		return f, nil
+	}
+	answer := f.Messages[index].Content
+
+	type evidence struct {
+		Name      string
+		Arguments string
+		Result    string
+	}
+	var gathered []evidence
+	for _, r := range f.Status.ToolResults {
+		gathered = append(gathered, evidence{
+			Name:      r.Name,
+			Arguments: string(mustMarshal(r.ToolArguments.Arguments)),
+			Result:    r.Result,
+		})
+	}
+
+	checkPrompt, err := o.prompts.GetPrompt(prompt.PromptFactCheckType).Render(struct {
+		Answer   string
+		Evidence []evidence
+	}{
+		Answer:   answer,
+		Evidence: gathered,
+	})
+	if err != nil {
+		return f, fmt.Errorf("failed to render fact check prompt: %w", err)
+	}
+
+	tool := factCheckTool()
+	result, err := decisionWithStreaming(o.context, llm,
+		append(slices.Clone(f.Messages[:index]), openai.ChatCompletionMessage{
+			Role:    SystemMessageRole.String(),
+			Content: checkPrompt,
+		}),
+		Tools{tool}, tool.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
+	if err != nil {
+		return f, fmt.Errorf("failed to fact-check the answer: %w", err)
+	}
+	if len(result.toolChoices) == 0 {
+		return f, errors.New("no fact check returned by the LLM")
+	}
+
+	var check factCheckResponse
+	data, _ := json.Marshal(result.toolChoices[0].Arguments)
+	if err := json.Unmarshal(data, &check); err != nil {
+		return f, fmt.Errorf("failed to parse fact check: %w", err)
+	}
+	if len(check.Unsupported) == 0 {
+		return f, nil
+	}
+	o.logger.Debug("Unsupported claims found", "claims", check.Unsupported)
+	f.Status.UnsupportedClaims = append(f.Status.UnsupportedClaims, check.Unsupported...)
+
+	var checked string
+	switch {
+	case o.factCheckAnnotate:
+		checked, err = o.prompts.GetPrompt(prompt.PromptUnsupportedClaimsType).Render(struct {
+			Answer string
+			Claims []UnsupportedClaim
+		}{
+			Answer: answer,
+			Claims: check.Unsupported,
+		})
+		if err != nil {
+			return f, fmt.Errorf("failed to render unsupported claims prompt: %w", err)
+		}
+	case strings.TrimSpace(check.Revised) != "":
+		checked = check.Revised
+	default:
+		o.logger.Warn("Unsupported claims found but no revised answer returned", "claims", len(check.Unsupported))
+		return f, nil
+	}
+
+	f.Messages = slices.Clone(f.Messages)
+	f.Messages[index].Content = checked
+	return f, nil
+}
+
+// finalAnswerIndex returns the index of the final assistant reply of f, or
+// -1 if the conversation does not end with one.
+func finalAnswerIndex(f Fragment) int {
+	for i := len(f.Messages) - 1; i >= 0; i-- {
+		msg := f.Messages[i]
+		if msg.Role != AssistantMessageRole.String() {
+			continue
+		}
+		if len(msg.ToolCalls) > 0 || msg.Content == "" {
+			return -1
+		}
+		return i
+	}
+	return -1
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/mudler/cogito/structures"
-	"github.com/mudler/xlog"
 	"github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
 )
@@ -44,6 +43,16 @@ type Status struct {
 	TODOIteration    int                  // Current TODO iteration
 	TODOPhase        string               // Current phase: "work" or "review"
 	InjectedMessages []InjectedMessage    // Track successfully injected messages with timing
+	Reflections      []string             // Lessons learned from failed iterations (see EnableReflection)
+
+	ContextDegradations []ContextDegradation // Prompts shrunk after context-length errors (see EnableContextShrinking)
+	Candidates          []Candidate          // Scored candidate answers of the last BestOf call
+	ReviewScores        []ReviewScore        // Rubric scores of each ContentReview iteration (see WithReviewRubric)
+	UnsupportedClaims   []UnsupportedClaim   // Claims of the answer not backed by tool results (see EnableFactCheck)
+	Compensations       []CompensationStatus // Tool calls undone after the run failed (see EnableCompensation)
+	ToolAPIFallback     string               // Error of the backend rejecting the tools API, when tool calls fell back to text (see DisableToolAPIFallback)
+
+	Extensions Extensions // Integrator-defined data, see SetExtension
 }
 
 type Fragment struct {
@@ -51,6 +60,34 @@ type Fragment struct {
 	ParentFragment *Fragment
 	Status         *Status
 	Multimedia     []Multimedia
+	// Metadata annotates Messages, see MessageMetadata. Entry i belongs to
+	// message i; messages past its end have none.
+	Metadata []MessageMetadata
+}
+
+// MarshalJSON encodes the fragment with its multimedia attachments, so
+// fragments and session states can be serialized.
+func (f Fragment) MarshalJSON() ([]byte, error) {
+	type fragment Fragment
+	return json.Marshal(struct {
+		fragment
+		Multimedia []multimediaJSON
+	}{fragment(f), encodeMultimedia(f.Multimedia)})
+}
+
+// UnmarshalJSON decodes a fragment encoded by MarshalJSON.
+func (f *Fragment) UnmarshalJSON(data []byte) error {
+	type fragment Fragment
+	var decoded struct {
+		fragment
+		Multimedia []multimediaJSON
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*f = Fragment(decoded.fragment)
+	f.Multimedia = decodeMultimedia(decoded.Multimedia)
+	return nil
 }
 
 // Messages returns the chat completion messages from this fragment,
@@ -117,7 +154,9 @@ func NewFragment(messages ...openai.ChatCompletionMessage) Fragment {
 	}
 }
 
-// TODO: Video, Audio, Image input
+// Multimedia is an attachment (image, audio or video) referenced by a remote
+// or base64 data URL. Implement TypedMultimedia to declare a kind other than
+// image, or use NewAudioURL, NewAudioData, NewVideoURL and NewVideoData.
 type Multimedia interface {
 	URL() string
 }
@@ -135,14 +174,9 @@ func (r Fragment) AddMessage(role MessageRole, content string, mm ...Multimedia)
 			},
 		}
 
-		for _, img := range mm {
-			r.Multimedia = append(r.Multimedia, img)
-			multiContent = append(multiContent, openai.ChatMessagePart{
-				Type: openai.ChatMessagePartTypeImageURL,
-				ImageURL: &openai.ChatMessageImageURL{
-					URL: img.URL(),
-				},
-			})
+		for _, m := range mm {
+			r.Multimedia = append(r.Multimedia, m)
+			multiContent = append(multiContent, MultimediaPart(m))
 		}
 		chatCompletionMessage.MultiContent = multiContent
 	} else {
@@ -174,10 +208,13 @@ func (r Fragment) AddStartMessage(role MessageRole, content string, mm ...Multim
 			Content: content,
 		},
 	}, r.Messages...)
+	if len(r.Metadata) > 0 {
+		r.Metadata = append([]MessageMetadata{{}}, r.Metadata...)
+	}
 	return r
 }
 
-func (r Fragment) Extract(ctx context.Context, llm LLM, obj any) error {
+func (r Fragment) Extract(ctx context.Context, llm LLM, obj any, opts ...Option) error {
 	schema, err := jsonschema.GenerateSchemaForType(obj)
 	if err != nil {
 		return fmt.Errorf("failed to generate schema for type: %w", err)
@@ -186,24 +223,124 @@ func (r Fragment) Extract(ctx context.Context, llm LLM, obj any) error {
 	return r.ExtractStructure(ctx, llm, structures.Structure{
 		Schema: *schema,
 		Object: &obj,
-	})
+	}, opts...)
+}
+
+// ExtractionConfig controls the pseudo-tool ExtractStructure uses to force the
+// LLM into returning JSON.
+type ExtractionConfig struct {
+	// ToolName is the name of the pseudo-tool. Defaults to "json"; change it if
+	// it collides with one of your tools.
+	ToolName string
+	// Strict sets the strict flag on the pseudo-tool definition. Some
+	// OpenAI-compatible servers reject strict function schemas.
+	Strict bool
+	// WrapKey, when set, nests the schema under a single property with this
+	// name. The arguments are unwrapped again before unmarshaling. Useful for
+	// servers that only accept object parameters at the top level.
+	WrapKey string
+}
+
+// DefaultExtractionConfig returns the configuration used when neither the
+// client nor the run overrides it.
+func DefaultExtractionConfig() ExtractionConfig {
+	return ExtractionConfig{
+		ToolName: "json",
+		Strict:   true,
+	}
+}
+
+// ExtractionConfigProvider can be implemented by LLM clients to set their own
+// ExtractionConfig. WithExtractionConfig takes precedence over it.
+type ExtractionConfigProvider interface {
+	ExtractionConfig() ExtractionConfig
+}
+
+// llmWrapper is implemented by the internal LLM decorators (usage counting,
+// context shrinking) so optional client interfaces stay reachable through them.
+type llmWrapper interface {
+	unwrap() LLM
+}
+
+// unwrapLLM returns the first LLM in the wrapper chain implementing T.
+func unwrapLLM[T any](llm LLM) (T, bool) {
+	for llm != nil {
+		if t, ok := llm.(T); ok {
+			return t, true
+		}
+		w, ok := llm.(llmWrapper)
+		if !ok {
+			break
+		}
+		llm = w.unwrap()
+	}
+	var zero T
+	return zero, false
+}
+
+// withLLMOptions wraps llm with the call-level options of o: the redactor,
+// the rate limiter and, outside it so cache hits don't wait, the completion
+// cache. The seed is set first, so cached replies are kept per seed, and
+// truncated replies are salvaged last, so their continuations go through
+// all of them.
+func withLLMOptions(llm LLM, o *Options) LLM {
+	return withTruncationSalvage(withSeed(withCompletionCache(withQuota(withRateLimit(withRedaction(llm, o), o), o), o), o), o)
+}
+
+// extractionConfigFor resolves the extraction config for a call: run options
+// first, then the client, then the defaults.
+func extractionConfigFor(llm LLM, o *Options) ExtractionConfig {
+	cfg := DefaultExtractionConfig()
+	if p, ok := unwrapLLM[ExtractionConfigProvider](llm); ok {
+		cfg = p.ExtractionConfig()
+	}
+	if o.extractionConfig != nil {
+		cfg = *o.extractionConfig
+	}
+	if cfg.ToolName == "" {
+		cfg.ToolName = DefaultExtractionConfig().ToolName
+	}
+	return cfg
 }
 
 // ExtractStructure extracts a structure from the result using the provided JSON schema definition
 // and unmarshals it into the provided destination
-func (r Fragment) ExtractStructure(ctx context.Context, llm LLM, s structures.Structure) error {
-	toolName := "json"
+func (r Fragment) ExtractStructure(ctx context.Context, llm LLM, s structures.Structure, opts ...Option) error {
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	if o.textToolCalls {
+		llm = newTextToolsLLM(llm, o.prompts, o.logger)
+	} else if o.toolAPIFallback {
+		llm = newToolFallbackLLM(llm, o.prompts, o.logger)
+	}
+
+	cfg := extractionConfigFor(llm, o)
+	toolName := cfg.ToolName
 	messages := slices.Clone(r.Messages)
 
+	parameters := s.Schema
+	if cfg.WrapKey != "" {
+		parameters = jsonschema.Definition{
+			Type:                 jsonschema.Object,
+			AdditionalProperties: false,
+			Properties: map[string]jsonschema.Definition{
+				cfg.WrapKey: s.Schema,
+			},
+			Required: []string{cfg.WrapKey},
+		}
+	}
+
 	decision := openai.ChatCompletionRequest{
 		Messages: messages,
 		Tools: []openai.Tool{
 			{
 				Type: openai.ToolTypeFunction,
 				Function: &openai.FunctionDefinition{
-					Strict:     true,
+					Strict:     cfg.Strict,
 					Name:       toolName,
-					Parameters: s.Schema,
+					Parameters: parameters,
 				},
 			},
 		},
@@ -231,14 +368,32 @@ func (r Fragment) ExtractStructure(ctx context.Context, llm LLM, s structures.St
 		return fmt.Errorf("no tool calls: %d", len(msg.ToolCalls))
 	}
 
-	return json.Unmarshal([]byte(msg.ToolCalls[0].Function.Arguments), s.Object)
+	arguments := []byte(msg.ToolCalls[0].Function.Arguments)
+	if cfg.WrapKey != "" {
+		wrapped := map[string]json.RawMessage{}
+		if err := json.Unmarshal(arguments, &wrapped); err != nil {
+			return err
+		}
+		inner, ok := wrapped[cfg.WrapKey]
+		if !ok {
+			return fmt.Errorf("missing wrapped key %q in tool arguments", cfg.WrapKey)
+		}
+		arguments = inner
+	}
+
+	return json.Unmarshal(arguments, s.Object)
 }
 
+// ToolChoice is a tool call decided by the LLM. It round-trips through JSON,
+// so it can be stored with a SessionState for resumption or auditing.
 type ToolChoice struct {
 	Name      string         `json:"name"`
 	Arguments map[string]any `json:"arguments"`
-	ID        string         `json:"id"`
-	Reasoning string         `json:"reasoning"`
+	ID        string         `json:"id,omitempty"`        // tool call ID, set when the call is added to the conversation
+	Reasoning string         `json:"reasoning,omitempty"` // why the LLM picked the tool, when available
+	// Confidence in the selection, from 0 to 1: self-reported by the LLM or
+	// derived from token log probabilities. nil when unknown.
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 // ToolCallDecision represents the decision made by a tool call callback
@@ -289,7 +444,7 @@ func (f Fragment) SelectTool(ctx context.Context, llm LLM, availableTools Tools,
 	}
 
 	if len(resp.ChatCompletionResponse.Choices[0].Message.ToolCalls) == 0 {
-		xlog.Debug("LLM did not select any tool", "response", resp.ChatCompletionResponse.Choices[0].Message)
+		LoggerFromContext(ctx).Debug("LLM did not select any tool", "response", resp.ChatCompletionResponse.Choices[0].Message)
 		return Fragment{}, nil, nil
 	}
 
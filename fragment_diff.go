@@ -0,0 +1,113 @@
+package cogito
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// FragmentDiff describes how a fragment changed into another: the messages
+// added and removed, the tool calls made and what the status recorded.
+type FragmentDiff struct {
+	// RemovedMessages are the messages of the old fragment missing from the
+	// new one, e.g. after compaction. AddedMessages replace them.
+	RemovedMessages []openai.ChatCompletionMessage
+	AddedMessages   []openai.ChatCompletionMessage
+	ToolCalls       []openai.ToolCall // Tool calls of the added messages
+	ToolResults     []ToolStatus
+	Reasoning       []string
+	Reflections     []string
+	Iterations      int      // Iterations run in between
+	Usage           LLMUsage // Tokens spent in between
+}
+
+// DiffFragments returns the changes from a to b, where b is a continued, e.g.
+// the result of ExecuteTools on a. Messages are compared from the start:
+// those of a past the first difference are reported as removed.
+func DiffFragments(a, b Fragment) FragmentDiff {
+	var d FragmentDiff
+	common := 0
+	for common < len(a.Messages) && common < len(b.Messages) &&
+		reflect.DeepEqual(a.Messages[common], b.Messages[common]) {
+		common++
+	}
+	d.RemovedMessages = a.Messages[common:]
+	d.AddedMessages = b.Messages[common:]
+	for _, msg := range d.AddedMessages {
+		d.ToolCalls = append(d.ToolCalls, msg.ToolCalls...)
+	}
+
+	as, bs := a.Status, b.Status
+	if as == nil {
+		as = &Status{}
+	}
+	if bs == nil {
+		bs = &Status{}
+	}
+	d.ToolResults = addedItems(as.ToolResults, bs.ToolResults)
+	d.Reasoning = appendedItems(as.ReasoningLog, bs.ReasoningLog)
+	d.Reflections = addedItems(as.Reflections, bs.Reflections)
+	d.Iterations = bs.Iterations - as.Iterations
+	d.Usage = LLMUsage{
+		PromptTokens:     bs.CumulativeUsage.PromptTokens - as.CumulativeUsage.PromptTokens,
+		CompletionTokens: bs.CumulativeUsage.CompletionTokens - as.CumulativeUsage.CompletionTokens,
+		TotalTokens:      bs.CumulativeUsage.TotalTokens - as.CumulativeUsage.TotalTokens,
+	}
+	return d
+}
+
+// addedItems returns the items of b past the length of a, for the status
+// logs, which only grow.
+func addedItems[T any](a, b []T) []T {
+	if len(b) <= len(a) {
+		return nil
+	}
+	return b[len(a):]
+}
+
+// appendedItems returns the items appended to a to get b, when the oldest
+// items of a may have been dropped, as in a capped reasoning log (see
+// WithReasoningLogLimit).
+func appendedItems[T comparable](a, b []T) []T {
+	for k := min(len(a), len(b)); k > 0; k-- {
+		if slices.Equal(a[len(a)-k:], b[:k]) {
+			return b[k:]
+		}
+	}
+	return b
+}
+
+// IsEmpty reports whether nothing changed.
+func (d FragmentDiff) IsEmpty() bool {
+	return len(d.RemovedMessages) == 0 && len(d.AddedMessages) == 0 && len(d.ToolResults) == 0 &&
+		len(d.Reasoning) == 0 && len(d.Reflections) == 0 && d.Iterations == 0 && d.Usage == (LLMUsage{})
+}
+
+// String describes the changes, one per line.
+func (d FragmentDiff) String() string {
+	var sb strings.Builder
+	if len(d.RemovedMessages) > 0 {
+		fmt.Fprintf(&sb, "- %d messages removed\n", len(d.RemovedMessages))
+	}
+	for _, r := range d.Reasoning {
+		fmt.Fprintf(&sb, "~ reasoning: %s\n", r)
+	}
+	for _, msg := range d.AddedMessages {
+		if msg.Content != "" {
+			fmt.Fprintf(&sb, "+ %s: %s\n", msg.Role, msg.Content)
+		}
+		for _, tc := range msg.ToolCalls {
+			fmt.Fprintf(&sb, "+ %s: tool call %s(%s)\n", msg.Role, tc.Function.Name, tc.Function.Arguments)
+		}
+	}
+	for _, r := range d.Reflections {
+		fmt.Fprintf(&sb, "~ reflection: %s\n", r)
+	}
+	if d.Usage.TotalTokens != 0 {
+		fmt.Fprintf(&sb, "~ %d tokens\n", d.Usage.TotalTokens)
+	}
+	return sb.String()
+}
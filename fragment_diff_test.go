@@ -0,0 +1,70 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fragment diffing", func() {
+	It("describes the messages, tool calls and status added", func() {
+		a := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		b := a.Fork().AddMessage(AssistantMessageRole, "It is 21°C in Rome.")
+		b.Status.ReasoningLog = append(b.Status.ReasoningLog, "The user wants the weather")
+		b.Status.Iterations = 2
+
+		diff := DiffFragments(a, b)
+		Expect(diff.RemovedMessages).To(BeEmpty())
+		Expect(diff.AddedMessages).To(HaveLen(1))
+		Expect(diff.Reasoning).To(Equal([]string{"The user wants the weather"}))
+		Expect(diff.Iterations).To(Equal(2))
+		Expect(diff.String()).To(ContainSubstring("+ assistant: It is 21°C in Rome."))
+		Expect(DiffFragments(a, a).IsEmpty()).To(BeTrue())
+	})
+
+	It("reports the messages replaced, e.g. by compaction", func() {
+		a := NewEmptyFragment().
+			AddMessage(UserMessageRole, "first").
+			AddMessage(AssistantMessageRole, "second")
+		b := NewEmptyFragment().AddMessage(SystemMessageRole, "summary")
+
+		diff := DiffFragments(a, b)
+		Expect(diff.RemovedMessages).To(HaveLen(2))
+		Expect(diff.AddedMessages).To(HaveLen(1))
+	})
+
+	It("records the audit trail of ExecuteTools", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.HasTool("get_weather")).
+			ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("It is 21°C in Rome.")
+
+		trail := &AuditTrail{}
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		result, err := ExecuteTools(mockLLM, f, WithTools(weather), WithAuditTrail(trail))
+		Expect(err).ToNot(HaveOccurred())
+
+		entries := trail.Entries()
+		Expect(entries).ToNot(BeEmpty())
+		Expect(entries[0].Step).To(Equal("iteration"))
+		Expect(entries[0].Iteration).To(Equal(1))
+		Expect(entries[0].Diff.ToolCalls).To(HaveLen(1))
+		Expect(entries[0].Diff.ToolCalls[0].Function.Name).To(Equal("get_weather"))
+		Expect(entries[0].Diff.ToolResults[0].Result).To(Equal("Rome: 21°C"))
+
+		last := entries[len(entries)-1]
+		Expect(last.Step).To(Equal("final"))
+		Expect(last.Diff.AddedMessages[len(last.Diff.AddedMessages)-1].Content).To(Equal("It is 21°C in Rome."))
+
+		// Replaying the trail rebuilds the conversation
+		var added int
+		for _, e := range entries {
+			added += len(e.Diff.AddedMessages) - len(e.Diff.RemovedMessages)
+		}
+		Expect(len(f.Messages) + added).To(Equal(len(result.Messages)))
+	})
+})
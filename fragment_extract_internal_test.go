@@ -0,0 +1,88 @@
+package cogito
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mudler/cogito/structures"
+	"github.com/sashabaranov/go-openai"
+)
+
+// recordingExtractLLM records the last request and answers with a single
+// tool call carrying the configured arguments.
+type recordingExtractLLM struct {
+	fakeLLM
+	args string
+	last openai.ChatCompletionRequest
+	conf ExtractionConfig
+}
+
+func (r *recordingExtractLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	r.last = req
+	return LLMReply{ChatCompletionResponse: openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+			Role: "assistant",
+			ToolCalls: []openai.ToolCall{{
+				Type:     openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: req.Tools[0].Function.Name, Arguments: r.args},
+			}},
+		}}},
+	}}, LLMUsage{}, nil
+}
+
+type configuredExtractLLM struct {
+	recordingExtractLLM
+}
+
+func (c *configuredExtractLLM) ExtractionConfig() ExtractionConfig { return c.conf }
+
+func TestExtractStructureDefaults(t *testing.T) {
+	llm := &recordingExtractLLM{args: `{"goal":"ship it"}`}
+	structure, goal := structures.StructureGoal()
+
+	if err := NewEmptyFragment().AddMessage(UserMessageRole, "x").ExtractStructure(context.Background(), llm, structure); err != nil {
+		t.Fatalf("ExtractStructure: %v", err)
+	}
+	fn := llm.last.Tools[0].Function
+	if fn.Name != "json" || !fn.Strict {
+		t.Errorf("got name=%q strict=%v, want json/true", fn.Name, fn.Strict)
+	}
+	if goal.Goal != "ship it" {
+		t.Errorf("goal = %q", goal.Goal)
+	}
+}
+
+func TestExtractStructureRunConfigWithWrapping(t *testing.T) {
+	llm := &recordingExtractLLM{args: `{"data":{"goal":"wrapped"}}`}
+	structure, goal := structures.StructureGoal()
+
+	err := NewEmptyFragment().AddMessage(UserMessageRole, "x").ExtractStructure(context.Background(), llm, structure,
+		WithExtractionConfig(ExtractionConfig{ToolName: "extract", WrapKey: "data"}))
+	if err != nil {
+		t.Fatalf("ExtractStructure: %v", err)
+	}
+	fn := llm.last.Tools[0].Function
+	if fn.Name != "extract" || fn.Strict {
+		t.Errorf("got name=%q strict=%v, want extract/false", fn.Name, fn.Strict)
+	}
+	if llm.last.ToolChoice.(openai.ToolChoice).Function.Name != "extract" {
+		t.Errorf("tool choice does not force the configured tool")
+	}
+	if goal.Goal != "wrapped" {
+		t.Errorf("goal = %q", goal.Goal)
+	}
+}
+
+func TestExtractStructureClientConfigSurvivesCounting(t *testing.T) {
+	inner := &configuredExtractLLM{recordingExtractLLM{args: `{"goal":"ok"}`}}
+	inner.conf = ExtractionConfig{ToolName: "structured_output"}
+	llm := newCountingLLM(inner, &usageCounter{})
+	structure, _ := structures.StructureGoal()
+
+	if err := NewEmptyFragment().AddMessage(UserMessageRole, "x").ExtractStructure(context.Background(), llm, structure); err != nil {
+		t.Fatalf("ExtractStructure: %v", err)
+	}
+	if name := inner.last.Tools[0].Function.Name; name != "structured_output" {
+		t.Errorf("tool name = %q, want structured_output", name)
+	}
+}
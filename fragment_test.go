@@ -75,5 +75,22 @@ var _ = Describe("Fragment test", func() {
 			Expect(fragment.Messages[0].MultiContent[1].Type).To(Equal(openai.ChatMessagePartTypeImageURL))
 			Expect(fragment.Messages[0].MultiContent[1].ImageURL.URL).To(Equal("https://example.com/image.png"))
 		})
+
+		It("should map audio and video to their part types", func() {
+			fragment := NewEmptyFragment().AddMessage("user", "Transcribe and describe",
+				NewAudioData([]byte("RIFF"), "audio/wav"),
+				NewVideoURL("https://example.com/clip.mp4"))
+
+			Expect(fragment.Multimedia).To(HaveLen(2))
+			Expect(MultimediaTypeOf(fragment.Multimedia[0])).To(Equal(MultimediaTypeAudio))
+			Expect(MultimediaTypeOf(fragment.Multimedia[1])).To(Equal(MultimediaTypeVideo))
+
+			parts := fragment.Messages[0].MultiContent
+			Expect(parts).To(HaveLen(3))
+			Expect(parts[1].Type).To(Equal(ChatMessagePartTypeAudioURL))
+			Expect(parts[1].ImageURL.URL).To(Equal("data:audio/wav;base64,UklGRg=="))
+			Expect(parts[2].Type).To(Equal(ChatMessagePartTypeVideoURL))
+			Expect(parts[2].ImageURL.URL).To(Equal("https://example.com/clip.mp4"))
+		})
 	})
 })
@@ -5,13 +5,13 @@ import (
 
 	"github.com/mudler/cogito/prompt"
 	"github.com/mudler/cogito/structures"
-	"github.com/mudler/xlog"
 )
 
 // ExtractGoal extracts a goal from a conversation
 func ExtractGoal(llm LLM, f Fragment, opts ...Option) (*structures.Goal, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	// First we ask the LLM if there is a goal from the conversation
 	prompter := o.prompts.GetPrompt(prompt.PromptIdentifyGoalType)
@@ -20,7 +20,7 @@ func ExtractGoal(llm LLM, f Fragment, opts ...Option) (*structures.Goal, error)
 		Context           string
 		AdditionalContext string
 	}{
-		Context: f.String(),
+		Context: conversationContext(o, prompt.PromptIdentifyGoalType, f),
 	}
 	if o.deepContext && f.ParentFragment != nil {
 		goalIdentifierOptions.AdditionalContext = f.ParentFragment.AllFragmentsStrings()
@@ -44,7 +44,7 @@ func ExtractGoal(llm LLM, f Fragment, opts ...Option) (*structures.Goal, error)
 
 	goalConv = NewEmptyFragment().AddMessage("user", identifiedGoal.Content)
 
-	err = goalConv.ExtractStructure(o.context, llm, structure)
+	err = goalConv.ExtractStructure(o.context, llm, structure, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract boolean structure: %w", err)
 	}
@@ -56,6 +56,7 @@ func ExtractGoal(llm LLM, f Fragment, opts ...Option) (*structures.Goal, error)
 func IsGoalAchieved(llm LLM, f Fragment, goal *structures.Goal, opts ...Option) (*structures.Boolean, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	// First we ask the LLM if there is a goal from the conversation
 	prompter := o.prompts.GetPrompt(prompt.PromptGoalAchievedType)
@@ -66,7 +67,7 @@ func IsGoalAchieved(llm LLM, f Fragment, goal *structures.Goal, opts ...Option)
 		Goal                 string
 		FeedbackConversation string
 	}{
-		Context: f.String(),
+		Context: conversationContext(o, prompt.PromptGoalAchievedType, f),
 	}
 	if goal != nil {
 		goalAchievedOpts.Goal = goal.Goal
@@ -98,7 +99,7 @@ func IsGoalAchieved(llm LLM, f Fragment, goal *structures.Goal, opts ...Option)
 
 	boolConv := NewEmptyFragment().AddMessage("user", reasoningGoal.LastMessage().Content)
 
-	xlog.Debug("Check if goal is achieved in current conversation", "reasoning", reasoningGoal.LastMessage().Content)
+	o.logger.Debug("Check if goal is achieved in current conversation", "reasoning", reasoningGoal.LastMessage().Content)
 
 	/// XXX: ExtractBoolean seems to be really brittle
 	return ExtractBoolean(llm, boolConv, opts...)
@@ -0,0 +1,142 @@
+// Package grpctool turns the unary methods of gRPC services into cogito tools,
+// so internal microservices can be used by agents without an MCP wrapper.
+//
+// Services are described either by the server reflection service
+// (FromReflection) or by generated descriptors (FromServices). Tool arguments
+// are the JSON form of the request message, and the result of a tool is the
+// JSON form of the response.
+package grpctool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mudler/cogito"
+	"github.com/sashabaranov/go-openai"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// Option configures the generated tools.
+type Option func(*options)
+
+type options struct {
+	filter      func(protoreflect.MethodDescriptor) bool
+	metadata    metadata.MD
+	callOptions []grpc.CallOption
+}
+
+// WithMethodFilter only generates tools for the methods fn returns true for.
+func WithMethodFilter(fn func(method protoreflect.MethodDescriptor) bool) Option {
+	return func(o *options) {
+		o.filter = fn
+	}
+}
+
+// WithMetadata sends md with every call, e.g. for authentication.
+func WithMetadata(md map[string]string) Option {
+	return func(o *options) {
+		o.metadata = metadata.New(md)
+	}
+}
+
+// WithCallOptions passes opts to every call.
+func WithCallOptions(opts ...grpc.CallOption) Option {
+	return func(o *options) {
+		o.callOptions = append(o.callOptions, opts...)
+	}
+}
+
+// FromServices generates a tool for every unary method of services, calling
+// them on conn. Use the descriptors of the generated code, e.g.
+// pb.File_inventory_proto.Services().ByName("Inventory").
+func FromServices(conn grpc.ClientConnInterface, services []protoreflect.ServiceDescriptor, opts ...Option) cogito.Tools {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	tools := cogito.Tools{}
+	for _, service := range services {
+		methods := service.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			if method.IsStreamingClient() || method.IsStreamingServer() {
+				continue
+			}
+			if o.filter != nil && !o.filter(method) {
+				continue
+			}
+			tools = append(tools, &methodTool{conn: conn, method: method, options: o})
+		}
+	}
+	return tools
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// methodTool calls a unary gRPC method.
+type methodTool struct {
+	conn    grpc.ClientConnInterface
+	method  protoreflect.MethodDescriptor
+	options *options
+}
+
+// ToolName returns the name of the tool generated for method:
+// "<Service>_<Method>".
+func ToolName(method protoreflect.MethodDescriptor) string {
+	name := string(method.Parent().Name()) + "_" + string(method.Name())
+	return invalidToolNameChars.ReplaceAllString(name, "_")
+}
+
+func (t *methodTool) Tool() openai.Tool {
+	description := strings.TrimSpace(t.method.ParentFile().SourceLocations().ByDescriptor(t.method).LeadingComments)
+	if description == "" {
+		description = fmt.Sprintf("Calls the %s gRPC method", t.method.FullName())
+	}
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        ToolName(t.method),
+			Description: description,
+			Parameters:  MessageSchema(t.method.Input()),
+		},
+	}
+}
+
+func (t *methodTool) Execute(args map[string]any) (string, any, error) {
+	return t.ExecuteWithContext(context.Background(), args)
+}
+
+// ExecuteWithContext implements cogito.ToolWithContext, dispatching the RPC.
+func (t *methodTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+	in := dynamicpb.NewMessage(t.method.Input())
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(data, in); err != nil {
+		return "", nil, fmt.Errorf("invalid arguments for %s: %w", t.method.FullName(), err)
+	}
+
+	if len(t.options.metadata) > 0 {
+		ctx = metadata.NewOutgoingContext(ctx, t.options.metadata)
+	}
+	out := dynamicpb.NewMessage(t.method.Output())
+	fullMethod := fmt.Sprintf("/%s/%s", t.method.Parent().FullName(), t.method.Name())
+	if err := t.conn.Invoke(ctx, fullMethod, in, out, t.options.callOptions...); err != nil {
+		return "", nil, fmt.Errorf("%s failed: %w", t.method.FullName(), err)
+	}
+
+	result, err := protojson.Marshal(out)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode the response of %s: %w", t.method.FullName(), err)
+	}
+	return string(result), nil, nil
+}
@@ -0,0 +1,105 @@
+package grpctool
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/mudler/cogito"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+func startServer(t *testing.T) (*grpc.ClientConn, *metadata.MD) {
+	t.Helper()
+	var md metadata.MD
+	listener := bufconn.Listen(1 << 20)
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		md, _ = metadata.FromIncomingContext(ctx)
+		return handler(ctx, req)
+	}))
+	hs := health.NewServer()
+	hs.SetServingStatus("inventory", healthpb.HealthCheckResponse_NOT_SERVING)
+	healthpb.RegisterHealthServer(server, hs)
+	reflection.Register(server)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, &md
+}
+
+func TestFromReflection(t *testing.T) {
+	conn, md := startServer(t)
+
+	tools, err := FromReflection(context.Background(), conn, WithMetadata(map[string]string{"authorization": "Bearer secret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Watch is server-streaming and skipped
+	if names := strings.Join(tools.Names(), ","); names != "Health_Check,Health_List" {
+		t.Fatalf("tools = %s", names)
+	}
+
+	check := tools.Find("Health_Check")
+	schema, _ := json.Marshal(check.Tool().Function.Parameters)
+	if !strings.Contains(string(schema), `"service":{`) {
+		t.Fatalf("Health_Check schema = %s", schema)
+	}
+
+	result, _, err := check.(cogito.ToolWithContext).ExecuteWithContext(context.Background(), map[string]any{"service": "inventory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, `"NOT_SERVING"`) {
+		t.Fatalf("result = %s", result)
+	}
+	if got := md.Get("authorization"); len(got) != 1 || got[0] != "Bearer secret" {
+		t.Fatalf("metadata = %v", *md)
+	}
+
+	if _, _, err := check.Execute(map[string]any{"service": "unknown"}); err == nil {
+		t.Fatal("expected the NotFound status as an error")
+	}
+}
+
+func TestFromServices(t *testing.T) {
+	conn, _ := startServer(t)
+	services := []protoreflect.ServiceDescriptor{healthpb.File_grpc_health_v1_health_proto.Services().ByName("Health")}
+
+	tools := FromServices(conn, services, WithMethodFilter(func(m protoreflect.MethodDescriptor) bool {
+		return m.Name() == "List"
+	}))
+	if len(tools) != 1 || tools[0].Tool().Function.Name != "Health_List" {
+		t.Fatalf("tools = %v", tools.Names())
+	}
+	result, _, err := tools[0].Execute(map[string]any{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(result, `"inventory"`) {
+		t.Fatalf("result = %s", result)
+	}
+}
+
+func TestMessageSchema(t *testing.T) {
+	schema := MessageSchema((&healthpb.HealthCheckResponse{}).ProtoReflect().Descriptor())
+	status := schema["properties"].(map[string]any)["status"].(map[string]any)
+	if status["type"] != "string" || len(status["enum"].([]any)) != 4 {
+		t.Fatalf("status schema = %v", status)
+	}
+}
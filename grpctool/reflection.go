@@ -0,0 +1,167 @@
+package grpctool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/mudler/cogito"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FromReflection discovers the services of the server behind conn with the
+// gRPC server reflection service, and generates a tool for each of their
+// unary methods like FromServices. The reflection service itself is skipped.
+func FromReflection(ctx context.Context, conn grpc.ClientConnInterface, opts ...Option) (cogito.Tools, error) {
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the reflection stream: %w", err)
+	}
+	defer stream.CloseSend()
+	r := &reflectionResolver{stream: stream, protos: map[string]*descriptorpb.FileDescriptorProto{}, files: &protoregistry.Files{}}
+
+	resp, err := r.request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	services := []protoreflect.ServiceDescriptor{}
+	for _, service := range resp.GetListServicesResponse().GetService() {
+		name := service.GetName()
+		if strings.HasPrefix(name, "grpc.reflection.") {
+			continue
+		}
+		descriptor, err := r.service(name)
+		if err != nil {
+			return nil, err
+		}
+		services = append(services, descriptor)
+	}
+	return FromServices(conn, services, opts...), nil
+}
+
+// reflectionResolver fetches and builds file descriptors over a reflection
+// stream.
+type reflectionResolver struct {
+	stream reflectionpb.ServerReflection_ServerReflectionInfoClient
+	protos map[string]*descriptorpb.FileDescriptorProto
+	files  *protoregistry.Files
+}
+
+func (r *reflectionResolver) request(req *reflectionpb.ServerReflectionRequest) (*reflectionpb.ServerReflectionResponse, error) {
+	if err := r.stream.Send(req); err != nil {
+		return nil, fmt.Errorf("reflection request failed: %w", err)
+	}
+	resp, err := r.stream.Recv()
+	if err == io.EOF {
+		return nil, fmt.Errorf("reflection stream closed by the server")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reflection request failed: %w", err)
+	}
+	if e := resp.GetErrorResponse(); e != nil {
+		return nil, fmt.Errorf("reflection error %d: %s", e.GetErrorCode(), e.GetErrorMessage())
+	}
+	return resp, nil
+}
+
+// addFiles records the file descriptors of a reflection response.
+func (r *reflectionResolver) addFiles(resp *reflectionpb.ServerReflectionResponse) error {
+	for _, data := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		fd := &descriptorpb.FileDescriptorProto{}
+		if err := proto.Unmarshal(data, fd); err != nil {
+			return fmt.Errorf("invalid file descriptor: %w", err)
+		}
+		r.protos[fd.GetName()] = fd
+	}
+	return nil
+}
+
+func (r *reflectionResolver) service(name string) (protoreflect.ServiceDescriptor, error) {
+	resp, err := r.request(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := r.addFiles(resp); err != nil {
+		return nil, err
+	}
+
+	protos := resp.GetFileDescriptorResponse().GetFileDescriptorProto()
+	if len(protos) == 0 {
+		return nil, fmt.Errorf("no file descriptor for service %s", name)
+	}
+	// The first file is the one defining the symbol, the others its dependencies
+	fd := &descriptorpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(protos[0], fd); err != nil {
+		return nil, fmt.Errorf("invalid file descriptor: %w", err)
+	}
+	file, err := r.file(fd.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	short := name[strings.LastIndex(name, ".")+1:]
+	service := file.Services().ByName(protoreflect.Name(short))
+	if service == nil || string(service.FullName()) != name {
+		return nil, fmt.Errorf("service %s not found in %s", name, file.Path())
+	}
+	return service, nil
+}
+
+// file builds the file descriptor at path with its dependencies, fetching
+// the ones the server did not send yet. Files known to the program, like
+// the well-known types, are used when the server does not have them.
+func (r *reflectionResolver) file(path string) (protoreflect.FileDescriptor, error) {
+	if file, err := r.files.FindFileByPath(path); err == nil {
+		return file, nil
+	}
+
+	fd, ok := r.protos[path]
+	if !ok {
+		resp, err := r.request(&reflectionpb.ServerReflectionRequest{
+			MessageRequest: &reflectionpb.ServerReflectionRequest_FileByFilename{FileByFilename: path},
+		})
+		if err == nil {
+			err = r.addFiles(resp)
+		}
+		fd, ok = r.protos[path]
+		if !ok {
+			global, globalErr := protoregistry.GlobalFiles.FindFileByPath(path)
+			if globalErr != nil {
+				if err == nil {
+					err = fmt.Errorf("file %s not returned by the server", path)
+				}
+				return nil, err
+			}
+			if err := r.files.RegisterFile(global); err != nil {
+				return nil, fmt.Errorf("failed to register %s: %w", path, err)
+			}
+			return global, nil
+		}
+	}
+
+	for _, dep := range fd.GetDependency() {
+		if _, err := r.file(dep); err != nil {
+			return nil, err
+		}
+	}
+	file, err := protodesc.NewFile(fd, r.files)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build file descriptor %s: %w", path, err)
+	}
+	if err := r.files.RegisterFile(file); err != nil {
+		return nil, fmt.Errorf("failed to register %s: %w", path, err)
+	}
+	return file, nil
+}
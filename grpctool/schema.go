@@ -0,0 +1,116 @@
+package grpctool
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// maxSchemaDepth bounds the expansion of nested messages, so recursive
+// messages terminate.
+const maxSchemaDepth = 8
+
+// MessageSchema returns the JSON schema of the JSON form of message, as
+// produced and accepted by protojson.
+func MessageSchema(message protoreflect.MessageDescriptor) map[string]any {
+	return messageSchema(message, 0)
+}
+
+func messageSchema(message protoreflect.MessageDescriptor, depth int) map[string]any {
+	if schema, ok := wellKnownSchema(message); ok {
+		return schema
+	}
+	if depth >= maxSchemaDepth {
+		return map[string]any{"type": "object"}
+	}
+
+	properties := map[string]any{}
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		schema := fieldSchema(field, depth)
+		if comments := field.ParentFile().SourceLocations().ByDescriptor(field).LeadingComments; comments != "" {
+			schema["description"] = strings.TrimSpace(comments)
+		}
+		properties[field.JSONName()] = schema
+	}
+	return map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+}
+
+func fieldSchema(field protoreflect.FieldDescriptor, depth int) map[string]any {
+	switch {
+	case field.IsMap():
+		return map[string]any{
+			"type":                 "object",
+			"additionalProperties": singularSchema(field.MapValue(), depth),
+		}
+	case field.IsList():
+		return map[string]any{
+			"type":  "array",
+			"items": singularSchema(field, depth),
+		}
+	default:
+		return singularSchema(field, depth)
+	}
+}
+
+func singularSchema(field protoreflect.FieldDescriptor, depth int) map[string]any {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind,
+		protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "integer"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.StringKind:
+		return map[string]any{"type": "string"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "description": "base64 encoded bytes"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		names := make([]any, 0, values.Len())
+		for i := 0; i < values.Len(); i++ {
+			names = append(names, string(values.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": names}
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchema(field.Message(), depth+1)
+	default:
+		return map[string]any{}
+	}
+}
+
+// wellKnownSchema returns the schema of the well-known types, which protojson
+// does not encode as plain objects.
+func wellKnownSchema(message protoreflect.MessageDescriptor) (map[string]any, bool) {
+	switch message.FullName() {
+	case "google.protobuf.Timestamp":
+		return map[string]any{"type": "string", "format": "date-time"}, true
+	case "google.protobuf.Duration":
+		return map[string]any{"type": "string", "description": "duration in seconds with an s suffix, e.g. 1.5s"}, true
+	case "google.protobuf.FieldMask":
+		return map[string]any{"type": "string", "description": "comma separated field paths"}, true
+	case "google.protobuf.Struct", "google.protobuf.Any", "google.protobuf.Empty":
+		return map[string]any{"type": "object"}, true
+	case "google.protobuf.ListValue":
+		return map[string]any{"type": "array", "items": map[string]any{}}, true
+	case "google.protobuf.Value":
+		return map[string]any{}, true
+	case "google.protobuf.BoolValue":
+		return map[string]any{"type": "boolean"}, true
+	case "google.protobuf.StringValue", "google.protobuf.BytesValue":
+		return map[string]any{"type": "string"}, true
+	case "google.protobuf.Int32Value", "google.protobuf.UInt32Value",
+		"google.protobuf.Int64Value", "google.protobuf.UInt64Value":
+		return map[string]any{"type": "integer"}, true
+	case "google.protobuf.FloatValue", "google.protobuf.DoubleValue":
+		return map[string]any{"type": "number"}, true
+	}
+	return nil, false
+}
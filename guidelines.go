@@ -15,6 +15,9 @@ type Guideline struct {
 	Condition string
 	Action    string
 	Tools     Tools
+	// ToolNames are tools of the run added to Tools by name, e.g. tools of
+	// MCP servers or guidelines read from configuration files.
+	ToolNames []string
 }
 
 type GuidelineMetadataList []GuidelineMetadata
@@ -47,6 +50,7 @@ func (g Guidelines) ToMetadata() GuidelineMetadataList {
 func GetRelevantGuidelines(llm LLM, guidelines Guidelines, fragment Fragment, opts ...Option) (Guidelines, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	prompter := o.prompts.GetPrompt(prompt.PromptGuidelinesType)
 
@@ -56,7 +60,7 @@ func GetRelevantGuidelines(llm LLM, guidelines Guidelines, fragment Fragment, op
 		AdditionalContext string
 	}{
 		Guidelines: guidelines.ToMetadata(),
-		Context:    fragment.String(),
+		Context:    conversationContext(o, prompt.PromptGuidelinesType, fragment),
 	}
 
 	if o.deepContext && fragment.ParentFragment != nil {
@@ -68,7 +72,7 @@ func GetRelevantGuidelines(llm LLM, guidelines Guidelines, fragment Fragment, op
 		return Guidelines{}, fmt.Errorf("failed to render tool reasoner prompt: %w", err)
 	}
 
-	guidelineConv := NewEmptyFragment().AddMessage("user", guidelinePrompt)
+	guidelineConv := NewEmptyFragment().AddMessage("user", guidelinePrompt, fragment.Multimedia...)
 
 	guidelineResult, err := llm.Ask(o.context, guidelineConv)
 	if err != nil {
@@ -81,7 +85,7 @@ func GetRelevantGuidelines(llm LLM, guidelines Guidelines, fragment Fragment, op
 	}
 
 	structure, guides := structures.StructureGuidelines()
-	err = guidelineResult.AddMessage("user", guidelineExtractionPrompt).ExtractStructure(o.context, llm, structure)
+	err = guidelineResult.AddMessage("user", guidelineExtractionPrompt).ExtractStructure(o.context, llm, structure, opts...)
 	if err != nil {
 		return Guidelines{}, fmt.Errorf("failed to extract guidelines: %w", err)
 	}
@@ -100,6 +104,30 @@ func GetRelevantGuidelines(llm LLM, guidelines Guidelines, fragment Fragment, op
 	return g, nil
 }
 
+// resolveGuidelineTools adds the tools named in the ToolNames of the
+// guidelines to their Tools.
+func resolveGuidelineTools(guidelines Guidelines, tools Tools, logger Logger) Guidelines {
+	for i, guideline := range guidelines {
+		if len(guideline.ToolNames) == 0 {
+			continue
+		}
+		resolved := slices.Clone(guideline.Tools)
+		for _, name := range guideline.ToolNames {
+			if resolved.Find(name) != nil {
+				continue
+			}
+			tool := tools.Find(name)
+			if tool == nil {
+				logger.Warn("Guideline references an unknown tool", "tool", name, "condition", guideline.Condition)
+				continue
+			}
+			resolved = append(resolved, tool)
+		}
+		guidelines[i].Tools = resolved
+	}
+	return guidelines
+}
+
 // findUnguidedTools identifies tools that are not in any guideline's Tools list
 func findUnguidedTools(tools Tools, guidelines Guidelines) Tools {
 	// Build a set of tool names that are in guidelines
@@ -146,18 +174,24 @@ func usableTools(llm LLM, fragment Fragment, opts ...Option) (Tools, Guidelines,
 	o := defaultOptions()
 	o.Apply(opts...)
 
-	tools := slices.Clone(o.tools)
+	sourced := []sourcedTool{}
+	for _, tool := range o.localTools() {
+		sourced = append(sourced, sourcedTool{tool: tool, source: "local"})
+	}
 
 	guidelines := slices.Clone(o.guidelines)
 	prompts := []openai.ChatCompletionMessage{}
 
 	for _, session := range o.mcpSessions {
-		mcpTools, err := mcpToolsFromTransport(o.context, session, o.mcpToolFilter)
+		mcpTools, err := mcpToolsFromTransport(o.context, session, o.mcpToolFilter, o.logger)
 		if err != nil {
 			return Tools{}, Guidelines{}, nil, fmt.Errorf("failed to get MCP tools: %w", err)
 		}
+		if namespace := o.mcpNamespaces[session]; namespace != "" {
+			mcpTools = NamespaceTools(namespace, mcpTools...)
+		}
 		for _, tool := range mcpTools {
-			tools = append(tools, tool)
+			sourced = append(sourced, sourcedTool{tool: tool, source: mcpSource(session)})
 		}
 		if o.mcpPrompts {
 			toolPrompts, err := mcpPromptsFromTransport(o.context, session, o.mcpArgs)
@@ -169,6 +203,17 @@ func usableTools(llm LLM, fragment Fragment, opts ...Option) (Tools, Guidelines,
 		}
 	}
 
+	sourced, err := resolveToolCollisions(sourced, o.toolCollisionPolicy, o.logger)
+	if err != nil {
+		return Tools{}, Guidelines{}, nil, err
+	}
+	tools := Tools{}
+	for _, t := range sourced {
+		tools = append(tools, t.tool)
+	}
+	guidelines = resolveGuidelineTools(guidelines, tools, o.logger)
+	guidelines = o.vars.renderGuidelines(guidelines, o.logger)
+
 	// Handle guided tools option
 	if o.guidedTools {
 		if len(o.guidelines) == 0 {
@@ -177,7 +222,7 @@ func usableTools(llm LLM, fragment Fragment, opts ...Option) (Tools, Guidelines,
 			tools = Tools{}
 		} else {
 			// Scenario A: Guidelines exist - create virtual guidelines for unguided tools
-			unguidedTools := findUnguidedTools(tools, o.guidelines)
+			unguidedTools := findUnguidedTools(tools, guidelines)
 			if len(unguidedTools) > 0 {
 				guidelines = append(guidelines, createVirtualGuidelinesFromAllTools(unguidedTools)...)
 			}
@@ -189,15 +234,31 @@ func usableTools(llm LLM, fragment Fragment, opts ...Option) (Tools, Guidelines,
 	}
 
 	if len(guidelines) > 0 {
-		var err error
 		guidelines, err = GetRelevantGuidelines(llm, guidelines, fragment, opts...)
 		if err != nil {
 			return Tools{}, Guidelines{}, nil, fmt.Errorf("failed to get relevant guidelines: %w", err)
 		}
+
+		final := []sourcedTool{}
+		for _, tool := range tools {
+			final = append(final, sourcedTool{tool: tool, source: toolSource(sourced, tool)})
+		}
 		for _, guideline := range guidelines {
-			tools = append(tools, guideline.Tools...)
+			for _, tool := range guideline.Tools {
+				final = append(final, sourcedTool{tool: tool, source: toolSource(sourced, tool)})
+			}
+		}
+		final, err = resolveToolCollisions(final, o.toolCollisionPolicy, o.logger)
+		if err != nil {
+			return Tools{}, Guidelines{}, nil, err
+		}
+		tools = Tools{}
+		for _, t := range final {
+			tools = append(tools, t.tool)
 		}
 	}
 
+	tools, guidelines = visibleTools(o, tools, guidelines)
+
 	return tools, guidelines, prompts, nil
 }
@@ -0,0 +1,193 @@
+package cogito
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// IdempotencyRecord is the recorded result of a tool call, see
+// WithIdempotencyStore.
+type IdempotencyRecord struct {
+	Key       string         `json:"key"`
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments,omitempty"`
+	Result    string         `json:"result"`
+	Time      time.Time      `json:"time"`
+}
+
+// IdempotencyStore records the results of side-effecting tool calls by
+// idempotency key, so they are not executed twice. See WithIdempotencyStore.
+type IdempotencyStore interface {
+	// Get returns the record of key, and false when the call was not
+	// executed yet.
+	Get(ctx context.Context, key string) (IdempotencyRecord, bool, error)
+	// Put records the result of a successful call.
+	Put(ctx context.Context, record IdempotencyRecord) error
+}
+
+// IdempotencyKey returns the idempotency key of the call within scope, e.g.
+// a run ID: a hash of the scope, the tool name and the arguments, so the same
+// call gets the same key on every retry or resume.
+func (tc *ToolChoice) IdempotencyKey(scope string) string {
+	return idempotencyKey(scope, tc.Name, tc.Arguments)
+}
+
+func idempotencyKey(scope, name string, args map[string]any) string {
+	// Map keys are sorted by encoding/json, so equal arguments give equal keys
+	encoded, _ := json.Marshal(args)
+	sum := sha256.Sum256([]byte(scope + "\x00" + name + "\x00" + string(encoded)))
+	return hex.EncodeToString(sum[:])
+}
+
+type idempotencyKeyKey struct{}
+
+// IdempotencyKeyFromContext returns the idempotency key of the tool call
+// running with ctx, when the tool is protected by WithIdempotencyStore, or
+// "". Tools can pass it on to APIs supporting idempotency keys.
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyKey{}).(string)
+	return key
+}
+
+type idempotency struct {
+	store IdempotencyStore
+	tools []string // tools to protect, all when empty
+}
+
+// WithIdempotencyStore executes the calls of the given tools (of all tools,
+// when none is given) at most once: the result of each successful call is
+// recorded in store under its idempotency key, and a call with the same key,
+// e.g. when a run is retried or resumed, returns the recorded result instead
+// of running again. Keys are scoped by the run ID and tenant of the context
+// (see ContextWithRunID and ContextWithTenant); without a run ID, identical
+// calls are never repeated while the store remembers them. Use it for
+// side-effecting tools, such as sending an email.
+func WithIdempotencyStore(store IdempotencyStore, tools ...string) func(o *Options) {
+	return func(o *Options) {
+		o.idempotency = &idempotency{store: store, tools: tools}
+	}
+}
+
+// execute runs the call of tool name with args, unless the store has a result
+// for its key. Store errors are logged and the call runs.
+func (i *idempotency) execute(o *Options, name string, args map[string]any, run func(ctx context.Context) (string, any, error)) (string, any, error) {
+	if len(i.tools) > 0 && !slices.Contains(i.tools, name) {
+		return run(o.context)
+	}
+	key := idempotencyKey(tenantScoped(o.context, RunIDFromContext(o.context)), name, args)
+	record, ok, err := i.store.Get(o.context, key)
+	if err != nil {
+		o.logger.Warn("Failed to read idempotency store", "tool", name, "error", err)
+	}
+	if ok {
+		o.logger.Info("Tool call already executed, returning its recorded result", "tool", name, "key", key)
+		return record.Result, nil, nil
+	}
+
+	result, resultData, err := run(context.WithValue(o.context, idempotencyKeyKey{}, key))
+	if err != nil {
+		return result, resultData, err
+	}
+	record = IdempotencyRecord{Key: key, Tool: name, Arguments: args, Result: result, Time: time.Now()}
+	if err := i.store.Put(o.context, record); err != nil {
+		o.logger.Warn("Failed to record tool call in idempotency store", "tool", name, "error", err)
+	}
+	return result, resultData, nil
+}
+
+// MemoryIdempotencyStore keeps idempotency records in memory, for the
+// lifetime of the process.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+var _ IdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+func (s *MemoryIdempotencyStore) Get(ctx context.Context, key string) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	return record, ok, nil
+}
+
+func (s *MemoryIdempotencyStore) Put(ctx context.Context, record IdempotencyRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.records == nil {
+		s.records = map[string]IdempotencyRecord{}
+	}
+	s.records[record.Key] = record
+	return nil
+}
+
+// FileIdempotencyStore appends idempotency records to a JSON-lines file, so
+// they survive restarts.
+type FileIdempotencyStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+var _ IdempotencyStore = (*FileIdempotencyStore)(nil)
+
+// NewFileIdempotencyStore returns a store appending to the file at path,
+// which is created on the first record.
+func NewFileIdempotencyStore(path string) *FileIdempotencyStore {
+	return &FileIdempotencyStore{path: path}
+}
+
+func (s *FileIdempotencyStore) Get(ctx context.Context, key string) (IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return IdempotencyRecord{}, false, nil
+	}
+	if err != nil {
+		return IdempotencyRecord{}, false, fmt.Errorf("failed to open idempotency file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	for {
+		var record IdempotencyRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			return IdempotencyRecord{}, false, nil
+		} else if err != nil {
+			return IdempotencyRecord{}, false, fmt.Errorf("failed to decode idempotency record: %w", err)
+		}
+		if record.Key == key {
+			return record, true, nil
+		}
+	}
+}
+
+func (s *FileIdempotencyStore) Put(ctx context.Context, record IdempotencyRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open idempotency file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write idempotency record: %w", err)
+	}
+	return nil
+}
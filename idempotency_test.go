@@ -0,0 +1,91 @@
+package cogito_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type emailArgs struct {
+	To string `json:"to"`
+}
+
+// emailSender counts the emails sent and the idempotency keys it received.
+type emailSender struct {
+	keys []string
+}
+
+func (s *emailSender) Run(args emailArgs) (string, any, error) {
+	return s.RunWithContext(context.Background(), args)
+}
+
+func (s *emailSender) RunWithContext(ctx context.Context, args emailArgs) (string, any, error) {
+	s.keys = append(s.keys, IdempotencyKeyFromContext(ctx))
+	return "email sent to " + args.To, nil, nil
+}
+
+var _ = Describe("Idempotency", func() {
+	var sender *emailSender
+
+	run := func(runID string, opts ...Option) Fragment {
+		tool := NewToolDefinition(sender, emailArgs{}, "send_email", "Send an email")
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.AddCreateChatCompletionFunction("send_email", `{"to": "bob@example.com"}`)
+		mockLLM.SetAskResponse("Done.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Email Bob")
+		opts = append(opts, WithTools(tool), WithContext(ContextWithRunID(context.Background(), runID)))
+		result, err := ExecuteTools(mockLLM, f, opts...)
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	BeforeEach(func() {
+		sender = &emailSender{}
+	})
+
+	It("derives deterministic keys from the scope, tool and arguments", func() {
+		tc := &ToolChoice{Name: "send_email", Arguments: map[string]any{"to": "bob@example.com", "cc": "alice@example.com"}}
+		same := &ToolChoice{Name: "send_email", Arguments: map[string]any{"cc": "alice@example.com", "to": "bob@example.com"}}
+		Expect(tc.IdempotencyKey("run-1")).To(Equal(same.IdempotencyKey("run-1")))
+		Expect(tc.IdempotencyKey("run-1")).ToNot(Equal(tc.IdempotencyKey("run-2")))
+		Expect(tc.IdempotencyKey("run-1")).ToNot(Equal((&ToolChoice{Name: "send_email"}).IdempotencyKey("run-1")))
+	})
+
+	It("returns the recorded result instead of running the call again", func() {
+		store := NewFileIdempotencyStore(filepath.Join(GinkgoT().TempDir(), "idempotency.jsonl"))
+		run("run-1", WithIdempotencyStore(store, "send_email"))
+		retried := run("run-1", WithIdempotencyStore(store, "send_email"))
+
+		Expect(sender.keys).To(HaveLen(1))
+		tc := &ToolChoice{Name: "send_email", Arguments: map[string]any{"to": "bob@example.com"}}
+		Expect(sender.keys[0]).To(Equal(tc.IdempotencyKey("run-1")))
+		Expect(retried.Status.ToolResults).To(HaveLen(1))
+		Expect(retried.Status.ToolResults[0].Result).To(Equal("email sent to bob@example.com"))
+
+		record, ok, err := store.Get(context.Background(), sender.keys[0])
+		Expect(err).ToNot(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(record.Tool).To(Equal("send_email"))
+	})
+
+	It("scopes keys by run ID", func() {
+		store := &MemoryIdempotencyStore{}
+		run("run-1", WithIdempotencyStore(store))
+		run("run-2", WithIdempotencyStore(store))
+		Expect(sender.keys).To(HaveLen(2))
+	})
+
+	It("only protects the given tools", func() {
+		store := &MemoryIdempotencyStore{}
+		run("run-1", WithIdempotencyStore(store, "create_ticket"))
+		result := run("run-1", WithIdempotencyStore(store, "create_ticket"))
+		Expect(sender.keys).To(Equal([]string{"", ""}))
+		Expect(strings.Contains(result.String(), "email sent")).To(BeTrue())
+	})
+})
@@ -0,0 +1,111 @@
+package cogito
+
+import (
+	"context"
+	"slices"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// Identity is the caller a run acts for, see WithIdentity.
+type Identity struct {
+	Subject string   // user or service account, e.g. "alice"
+	Roles   []string // e.g. "admin", "read-only"
+}
+
+// HasRole reports whether the identity has one of the given roles.
+func (i Identity) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if slices.Contains(i.Roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// WithIdentity runs as identity: tools requiring roles (see RoleTool) are
+// only offered to the LLM when identity has one of them. Without an identity,
+// those tools are not offered at all. The subject is the caller of policies
+// (see WithPolicy), unless ContextWithCaller sets another one.
+func WithIdentity(identity Identity) func(o *Options) {
+	return func(o *Options) {
+		o.identity = &identity
+	}
+}
+
+// RoleTool is implemented by tools only visible to some roles. ToolDefinition
+// implements it with its Roles field; use RequireRoles for other tools.
+type RoleTool interface {
+	RequiredRoles() []string
+}
+
+// RequiredRoles implements RoleTool.
+func (t *ToolDefinition[T]) RequiredRoles() []string {
+	return t.Roles
+}
+
+// RequireRoles returns tool, only visible to identities with one of roles, for
+// tools not built with ToolDefinition (MCP, OpenAPI, ...).
+func RequireRoles(tool ToolDefinitionInterface, roles ...string) ToolDefinitionInterface {
+	return &roleTool{ToolDefinitionInterface: tool, roles: roles}
+}
+
+type roleTool struct {
+	ToolDefinitionInterface
+	roles []string
+}
+
+func (t *roleTool) RequiredRoles() []string { return t.roles }
+
+func (t *roleTool) ToolConcurrency() ToolConcurrency {
+	return toolConcurrency(t.ToolDefinitionInterface)
+}
+
+func (t *roleTool) OutputJSONSchema() *jsonschema.Definition {
+	return toolOutputSchema(t.ToolDefinitionInterface)
+}
+
+func (t *roleTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	return executeTool(ctx, t.ToolDefinitionInterface, args)
+}
+
+func (t *roleTool) unwrapTool() ToolDefinitionInterface { return t.ToolDefinitionInterface }
+
+// requiredRoles returns the roles required by tool, or by the tools it wraps.
+func requiredRoles(tool ToolDefinitionInterface) []string {
+	var roles []string
+	for tool != nil {
+		if t, ok := tool.(RoleTool); ok {
+			roles = append(roles, t.RequiredRoles()...)
+		}
+		w, ok := tool.(toolWrapper)
+		if !ok {
+			break
+		}
+		tool = w.unwrapTool()
+	}
+	return roles
+}
+
+// toolVisible reports whether the identity of o may use tool.
+func toolVisible(o *Options, tool ToolDefinitionInterface) bool {
+	roles := requiredRoles(tool)
+	return len(roles) == 0 || (o.identity != nil && o.identity.HasRole(roles...))
+}
+
+// visibleTools removes the tools the identity of o may not use from tools and
+// guidelines.
+func visibleTools(o *Options, tools Tools, guidelines Guidelines) (Tools, Guidelines) {
+	visible := func(tool ToolDefinitionInterface) bool {
+		if toolVisible(o, tool) {
+			return true
+		}
+		o.logger.Debug("Tool hidden from identity", "tool", tool.Tool().Function.Name)
+		return false
+	}
+	tools = slices.DeleteFunc(slices.Clone(tools), func(t ToolDefinitionInterface) bool { return !visible(t) })
+	for i := range guidelines {
+		guidelines[i].Tools = slices.DeleteFunc(slices.Clone(guidelines[i].Tools), func(t ToolDefinitionInterface) bool { return !visible(t) })
+	}
+	return tools, guidelines
+}
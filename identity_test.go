@@ -0,0 +1,89 @@
+package cogito_test
+
+import (
+	"context"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type userArgs struct {
+	Name string `json:"name"`
+}
+
+type userDeleter struct{}
+
+func (userDeleter) Run(args userArgs) (string, any, error) {
+	return "deleted " + args.Name, nil, nil
+}
+
+var _ = Describe("Identity", func() {
+	var tools Tools
+
+	BeforeEach(func() {
+		search := cogitotest.NewMockTool("search", "Search users")
+		audit := RequireRoles(cogitotest.NewMockTool("audit_log", "Read the audit log"), "auditor", "admin")
+		deleteUser := &ToolDefinition[userArgs]{
+			ToolRunner:     userDeleter{},
+			InputArguments: userArgs{},
+			Name:           "delete_user",
+			Description:    "Delete a user",
+			Roles:          []string{"admin"},
+		}
+		tools = Tools{search, audit, deleteUser}
+	})
+
+	offered := func(opts ...Option) []string {
+		llm := cogitotest.NewMockLLM()
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Nothing to do."},
+		}}})
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Clean up users")
+		_, err := ExecuteTools(llm, f, append(opts, WithTools(tools...))...)
+		Expect(err).ToNot(HaveOccurred())
+
+		var names []string
+		for _, tool := range llm.Requests()[0].Tools {
+			names = append(names, tool.Function.Name)
+		}
+		return names
+	}
+
+	It("offers the tools of the roles of the identity", func() {
+		Expect(offered(WithIdentity(Identity{Subject: "alice", Roles: []string{"admin"}}))).
+			To(ContainElements("search", "audit_log", "delete_user"))
+		Expect(offered(WithIdentity(Identity{Subject: "bob", Roles: []string{"auditor"}}))).
+			To(And(ContainElements("search", "audit_log"), Not(ContainElement("delete_user"))))
+	})
+
+	It("hides restricted tools without an identity", func() {
+		Expect(offered()).To(And(ContainElement("search"), Not(ContainElements("audit_log")), Not(ContainElement("delete_user"))))
+	})
+
+	It("passes the identity to policies", func() {
+		var input PolicyInput
+		policy := PolicyFunc(func(ctx context.Context, in PolicyInput) (PolicyDecision, error) {
+			input = in
+			return PolicyDecision{Effect: PolicyDeny}, nil
+		})
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("search", `{}`)
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Denied."},
+		}}})
+		llm.SetAskResponse("Denied.")
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Find bob")
+		_, err := ExecuteTools(llm, f, WithTools(tools...), WithPolicy(policy),
+			WithIdentity(Identity{Subject: "bob", Roles: []string{"auditor"}}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(input.Caller).To(Equal("bob"))
+		Expect(input.Roles).To(Equal([]string{"auditor"}))
+
+		rules := PolicyRules{{Roles: []string{"admin"}, Effect: PolicyAllow}, {Effect: PolicyDeny}}
+		decision, _ := rules.Evaluate(context.Background(), input)
+		Expect(decision.Effect).To(Equal(PolicyDeny))
+	})
+})
@@ -0,0 +1,140 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// parseToolArguments decodes the arguments of call. Malformed arguments are
+// repaired before giving up: first leniently (see lenientJSONObject), then
+// by asking the LLM to fix their syntax against the schema of the tool. The
+// usage of the fix is returned.
+func parseToolArguments(ctx context.Context, llm LLM, tools Tools, call openai.FunctionCall,
+	prompts prompt.PromptMap, logger Logger) (map[string]any, LLMUsage, error) {
+	arguments := make(map[string]any)
+	err := json.Unmarshal([]byte(call.Arguments), &arguments)
+	if err == nil {
+		return arguments, LLMUsage{}, nil
+	}
+
+	if repaired, lenientErr := lenientJSONObject(call.Arguments); lenientErr == nil {
+		logger.Debug("Repaired malformed tool arguments", "tool", call.Name)
+		return repaired, LLMUsage{}, nil
+	}
+
+	tool := tools.Find(call.Name)
+	if tool == nil || strings.TrimSpace(call.Arguments) == "" {
+		return nil, LLMUsage{}, err
+	}
+	repaired, usage, repairErr := fixToolArguments(ctx, llm, tool, call.Arguments, err, prompts)
+	if repairErr != nil {
+		logger.Debug("Failed to repair malformed tool arguments", "tool", call.Name, "error", repairErr)
+		return nil, usage, err
+	}
+	logger.Debug("Repaired malformed tool arguments with the LLM", "tool", call.Name)
+	return repaired, usage, nil
+}
+
+// fixToolArguments asks the LLM to fix the JSON syntax of the arguments of
+// tool, which failed to parse with parseErr.
+func fixToolArguments(ctx context.Context, llm LLM, tool ToolDefinitionInterface, arguments string, parseErr error,
+	prompts prompt.PromptMap) (map[string]any, LLMUsage, error) {
+	toolFunc := tool.Tool().Function
+	schema := "{}"
+	if toolFunc.Parameters != nil {
+		schema = string(mustMarshal(toolFunc.Parameters))
+	}
+	repairPrompt, err := prompts.GetPrompt(prompt.PromptToolArgumentsRepairType).Render(struct {
+		ToolName, Arguments, Schema, Error string
+	}{toolFunc.Name, arguments, schema, parseErr.Error()})
+	if err != nil {
+		return nil, LLMUsage{}, fmt.Errorf("failed to render tool arguments repair prompt: %w", err)
+	}
+
+	reply, usage, err := llm.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
+		Messages:       []openai.ChatCompletionMessage{{Role: UserMessageRole.String(), Content: repairPrompt}},
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return nil, usage, err
+	}
+	if len(reply.ChatCompletionResponse.Choices) == 0 {
+		return nil, usage, fmt.Errorf("no choices in reply")
+	}
+	_, content := SplitThinking(reply.ChatCompletionResponse.Choices[0].Message.Content)
+	repaired, err := lenientJSONObject(content)
+	return repaired, usage, err
+}
+
+// lenientJSONObject decodes the JSON object in text, tolerating the usual
+// mistakes of LLMs: fenced code blocks, text around the object, trailing
+// commas and single-quoted strings.
+func lenientJSONObject(text string) (map[string]any, error) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "```") {
+		text = strings.TrimPrefix(text, "```")
+		if nl := strings.IndexByte(text, '\n'); nl >= 0 {
+			text = text[nl+1:] // drop the language
+		}
+		text = strings.TrimSuffix(strings.TrimSpace(text), "```")
+	}
+	start, end := strings.IndexByte(text, '{'), strings.LastIndexByte(text, '}')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object found")
+	}
+	text = text[start : end+1]
+
+	arguments := make(map[string]any)
+	if err := json.Unmarshal([]byte(text), &arguments); err == nil {
+		return arguments, nil
+	}
+	if err := json.Unmarshal([]byte(normalizeJSON(text)), &arguments); err != nil {
+		return nil, err
+	}
+	return arguments, nil
+}
+
+// normalizeJSON rewrites single-quoted strings with double quotes and drops
+// trailing commas.
+func normalizeJSON(text string) string {
+	var sb strings.Builder
+	var quote byte // the quote of the string being read, if any
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0 && c == '\\' && i+1 < len(text):
+			i++
+			next := text[i]
+			if quote == '\'' && next == '\'' {
+				sb.WriteByte('\'')
+			} else {
+				sb.WriteByte(c)
+				sb.WriteByte(next)
+			}
+		case quote != 0 && c == quote:
+			sb.WriteByte('"')
+			quote = 0
+		case quote == '\'' && c == '"':
+			sb.WriteString(`\"`)
+		case quote != 0:
+			sb.WriteByte(c)
+		case c == '"' || c == '\'':
+			sb.WriteByte('"')
+			quote = c
+		case c == ',':
+			rest := strings.TrimLeft(text[i+1:], " \t\r\n")
+			if rest == "" || rest[0] == '}' || rest[0] == ']' {
+				continue
+			}
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,38 @@
+package cogito
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLenientJSONObject(t *testing.T) {
+	want := map[string]any{"city": "Rome", "tags": []any{"a", "b"}}
+	for _, text := range []string{
+		`{"city": "Rome", "tags": ["a", "b"]}`,
+		"```json\n{\"city\": \"Rome\", \"tags\": [\"a\", \"b\"]}\n```",
+		`Here are the arguments: {"city": "Rome", "tags": ["a", "b"]} as requested.`,
+		`{"city": "Rome", "tags": ["a", "b",],}`,
+		`{'city': 'Rome', 'tags': ['a', "b"]}`,
+	} {
+		got, err := lenientJSONObject(text)
+		if err != nil {
+			t.Errorf("lenientJSONObject(%q): %v", text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("lenientJSONObject(%q) = %v, want %v", text, got, want)
+		}
+	}
+
+	got, err := lenientJSONObject(`{'quote': 'it\'s "fine", really', 'text': "a, b,"}`)
+	if err != nil {
+		t.Fatalf("lenientJSONObject: %v", err)
+	}
+	if got["quote"] != `it's "fine", really` || got["text"] != "a, b," {
+		t.Errorf("strings were altered: %v", got)
+	}
+
+	if _, err := lenientJSONObject(`{"city": "Rome" "days": 3}`); err == nil {
+		t.Errorf("expected a missing comma not to be repaired")
+	}
+}
@@ -0,0 +1,44 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tool arguments repair", func() {
+	var (
+		mockLLM *cogitotest.MockLLM
+		weather ToolDefinitionInterface
+		f       Fragment
+	)
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		weather = cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+		mockLLM.SetAskResponse("It is 21°C in Rome.")
+		f = NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+	})
+
+	It("parses common JSON mistakes without asking again", func() {
+		mockLLM.When(cogitotest.HasTool("get_weather")).ReplyToolCall("get_weather", "```json\n{'city': 'Rome',}\n```")
+
+		_, err := ExecuteTools(mockLLM, f, WithTools(weather))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(weather).Calls()).To(Equal([]map[string]any{{"city": "Rome"}}))
+		Expect(mockLLM.Requests()).To(HaveLen(1))
+	})
+
+	It("asks the LLM to fix the JSON before retrying the decision", func() {
+		mockLLM.When(cogitotest.HasTool("get_weather")).ReplyToolCall("get_weather", `{"city": "Rome" "days": 3}`)
+		mockLLM.When(cogitotest.MessageContains("are not valid JSON")).ReplyText(`{"city": "Rome", "days": 3}`)
+
+		_, err := ExecuteTools(mockLLM, f, WithTools(weather))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(weather).Calls()).To(Equal([]map[string]any{{"city": "Rome", "days": float64(3)}}))
+		Expect(mockLLM.Requests()).To(HaveLen(2))
+		Expect(mockLLM.Requests()[1].Messages[0].Content).To(ContainSubstring(`"get_weather"`))
+	})
+})
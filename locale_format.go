@@ -0,0 +1,162 @@
+package cogito
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mudler/cogito/prompt"
+)
+
+// LocaleFormat describes how a locale writes numbers, dates and currency
+// amounts. See EnableLocaleFormatting.
+type LocaleFormat struct {
+	Decimal    string // decimal separator, e.g. ","
+	Group      string // thousands separator, e.g. "."
+	DateLayout string // time layout for dates, e.g. "02.01.2006"
+	// CurrencyAfter places the currency symbol after the amount ("12,50 €")
+	// instead of before it ("€12.50").
+	CurrencyAfter bool
+	// CurrencySpace separates the symbol from the amount with a space.
+	CurrencySpace bool
+}
+
+var (
+	localeFormatsMu sync.RWMutex
+	localeFormats   = map[string]LocaleFormat{
+		"en":    {Decimal: ".", Group: ",", DateLayout: "01/02/2006"},
+		"en-gb": {Decimal: ".", Group: ",", DateLayout: "02/01/2006"},
+		"de":    {Decimal: ",", Group: ".", DateLayout: "02.01.2006", CurrencyAfter: true, CurrencySpace: true},
+		"fr":    {Decimal: ",", Group: " ", DateLayout: "02/01/2006", CurrencyAfter: true, CurrencySpace: true},
+		"it":    {Decimal: ",", Group: ".", DateLayout: "02/01/2006", CurrencyAfter: true, CurrencySpace: true},
+		"es":    {Decimal: ",", Group: ".", DateLayout: "02/01/2006", CurrencyAfter: true, CurrencySpace: true},
+		"pt":    {Decimal: ",", Group: ".", DateLayout: "02/01/2006", CurrencyAfter: true, CurrencySpace: true},
+		"pt-br": {Decimal: ",", Group: ".", DateLayout: "02/01/2006", CurrencySpace: true},
+		"nl":    {Decimal: ",", Group: ".", DateLayout: "02-01-2006", CurrencySpace: true},
+		"pl":    {Decimal: ",", Group: " ", DateLayout: "02.01.2006", CurrencyAfter: true, CurrencySpace: true},
+		"ru":    {Decimal: ",", Group: " ", DateLayout: "02.01.2006", CurrencyAfter: true, CurrencySpace: true},
+		"ja":    {Decimal: ".", Group: ",", DateLayout: "2006/01/02"},
+		"zh":    {Decimal: ".", Group: ",", DateLayout: "2006/01/02"},
+	}
+)
+
+// RegisterLocaleFormat sets the format of a locale, adding one or replacing
+// a built-in one.
+func RegisterLocaleFormat(locale string, format LocaleFormat) {
+	localeFormatsMu.Lock()
+	defer localeFormatsMu.Unlock()
+	localeFormats[prompt.NormalizeLocale(locale)] = format
+}
+
+// LocaleFormatFor returns the format of locale, falling back from a regional
+// locale ("de-AT") to its language ("de").
+func LocaleFormatFor(locale string) (LocaleFormat, bool) {
+	localeFormatsMu.RLock()
+	defer localeFormatsMu.RUnlock()
+	locale = prompt.NormalizeLocale(locale)
+	if f, ok := localeFormats[locale]; ok {
+		return f, true
+	}
+	lang, _, _ := strings.Cut(locale, "-")
+	f, ok := localeFormats[lang]
+	return f, ok
+}
+
+var (
+	// Code spans and fenced blocks are left untouched
+	codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+	// $1,234.56 / USD 12.50 / 12.50 € / 3 EUR
+	currencyPattern = regexp.MustCompile(`(?:([$€£¥]|\b(?:USD|EUR|GBP|JPY|CHF)) ?(\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?)\b)|(?:\b(\d{1,3}(?:,\d{3})+(?:\.\d+)?|\d+(?:\.\d+)?) ?([$€£¥]|(?:USD|EUR|GBP|JPY|CHF)\b))`)
+	// US-style dates: 03/14/2024
+	usDatePattern = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+	// Numbers with thousands grouping (1,234,567.89) and percentages (12.5%)
+	numberPattern = regexp.MustCompile(`\b\d{1,3}(?:,\d{3})+(?:\.\d+)?\b|\b\d+\.\d+%`)
+)
+
+// LocalizeText rewrites the US-formatted numbers, dates and currency amounts
+// in text in the format of locale: "$1,234.50 on 03/14/2024" becomes
+// "1.234,50 $ on 14.03.2024" for "de". Plain decimals such as version
+// numbers are left alone, as are code spans. Unknown locales leave text
+// unchanged.
+func LocalizeText(text, locale string) string {
+	format, ok := LocaleFormatFor(locale)
+	if !ok {
+		return text
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range codePattern.FindAllStringIndex(text, -1) {
+		b.WriteString(localizeSegment(text[last:loc[0]], format))
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	b.WriteString(localizeSegment(text[last:], format))
+	return b.String()
+}
+
+func localizeSegment(s string, f LocaleFormat) string {
+	// Placeholders keep already converted amounts away from the next passes
+	var done []string
+	hold := func(v string) string {
+		done = append(done, v)
+		return "\x00" + strconv.Itoa(len(done)-1) + "\x00"
+	}
+
+	s = currencyPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := currencyPattern.FindStringSubmatch(m)
+		symbol, amount := sub[1], sub[2]
+		if symbol == "" {
+			symbol, amount = sub[4], sub[3]
+		}
+		amount = formatUSNumber(amount, f)
+		sep := ""
+		if f.CurrencySpace || symbol[0] >= 'A' && symbol[0] <= 'Z' {
+			sep = " "
+		}
+		if f.CurrencyAfter {
+			return hold(amount + sep + symbol)
+		}
+		return hold(symbol + sep + amount)
+	})
+
+	s = usDatePattern.ReplaceAllStringFunc(s, func(m string) string {
+		t, err := time.Parse("1/2/2006", m)
+		if err != nil {
+			return m
+		}
+		return hold(t.Format(f.DateLayout))
+	})
+
+	s = numberPattern.ReplaceAllStringFunc(s, func(m string) string {
+		if strings.HasSuffix(m, "%") {
+			return hold(formatUSNumber(strings.TrimSuffix(m, "%"), f) + "%")
+		}
+		return hold(formatUSNumber(m, f))
+	})
+
+	for i, v := range done {
+		s = strings.Replace(s, "\x00"+strconv.Itoa(i)+"\x00", v, 1)
+	}
+	return s
+}
+
+// formatUSNumber rewrites a number written as 1,234.56 with the separators
+// of f, keeping the grouping of the original.
+func formatUSNumber(n string, f LocaleFormat) string {
+	return strings.NewReplacer(",", f.Group, ".", f.Decimal).Replace(n)
+}
+
+// localizeAnswer applies LocalizeText to the final assistant reply of f.
+func localizeAnswer(f Fragment, locale string) Fragment {
+	i := finalAnswerIndex(f)
+	if i < 0 {
+		return f
+	}
+	f.Messages = slices.Clone(f.Messages)
+	f.Messages[i].Content = LocalizeText(f.Messages[i].Content, locale)
+	return f
+}
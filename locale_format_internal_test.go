@@ -0,0 +1,46 @@
+package cogito
+
+import (
+	"testing"
+)
+
+func TestLocalizeText(t *testing.T) {
+	for _, tc := range []struct {
+		locale, in, want string
+	}{
+		{"de", "It costs $1,234.50 as of 03/14/2024.", "It costs 1.234,50 $ as of 14.03.2024."},
+		{"de-AT", "Revenue grew 12.5% to 2,500,000 units (USD 3.99 each).", "Revenue grew 12,5% to 2.500.000 units (3,99 USD each)."},
+		{"fr", "Total: €12.50", "Total: 12,50 €"},
+		{"en-GB", "Due 1/2/2025, total 1,000.", "Due 02/01/2025, total 1,000."},
+		{"ja", "¥1,200", "¥1,200"},
+		// Versions, plain decimals and code are left alone
+		{"de", "Go 1.24 is at `v1,234.5` and\n```\nx = 1,000.5\n```", "Go 1.24 is at `v1,234.5` and\n```\nx = 1,000.5\n```"},
+		// Invalid dates and unknown locales are untouched
+		{"de", "Ratio 13/45/2024", "Ratio 13/45/2024"},
+		{"tlh", "$1,234.50", "$1,234.50"},
+	} {
+		if got := LocalizeText(tc.in, tc.locale); got != tc.want {
+			t.Errorf("LocalizeText(%q, %s) = %q, want %q", tc.in, tc.locale, got, tc.want)
+		}
+	}
+}
+
+func TestRegisterLocaleFormat(t *testing.T) {
+	RegisterLocaleFormat("de_CH", LocaleFormat{Decimal: ".", Group: "'", DateLayout: "02.01.2006", CurrencySpace: true})
+	if got := LocalizeText("CHF 1,234.50 on 03/14/2024", "de-CH"); got != "CHF 1'234.50 on 14.03.2024" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLocalizeAnswerOnlyTouchesFinalReply(t *testing.T) {
+	f := NewEmptyFragment().
+		AddMessage(UserMessageRole, "Price of $1,000.00?").
+		AddMessage(AssistantMessageRole, "It is $1,000.00.")
+	out := localizeAnswer(f, "it")
+	if out.Messages[1].Content != "It is 1.000,00 $." || out.Messages[0].Content != "Price of $1,000.00?" {
+		t.Errorf("messages = %+v", out.Messages)
+	}
+	if f.Messages[1].Content != "It is $1,000.00." {
+		t.Error("input fragment was modified")
+	}
+}
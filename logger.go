@@ -0,0 +1,90 @@
+package cogito
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/mudler/xlog"
+)
+
+// Logger receives the log output of cogito. *slog.Logger implements it, so
+// any slog handler can be used. See WithLogger.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+// xlogOutput is where the default Logger writes, configured as the xlog
+// package by COGITO_LOG_LEVEL and LOG_FORMAT.
+var xlogOutput = xlog.NewLogger(xlog.LogLevel(os.Getenv("COGITO_LOG_LEVEL")), os.Getenv("LOG_FORMAT"))
+
+// xlogLogger is the default Logger. It writes like the xlog functions,
+// which can't be called here as they report their direct caller (this file)
+// as the source of every line.
+type xlogLogger struct{}
+
+func (xlogLogger) Debug(msg string, args ...any) { logXlog(slog.LevelDebug, msg, args) }
+func (xlogLogger) Info(msg string, args ...any)  { logXlog(slog.LevelInfo, msg, args) }
+func (xlogLogger) Warn(msg string, args ...any)  { logXlog(slog.LevelWarn, msg, args) }
+func (xlogLogger) Error(msg string, args ...any) { logXlog(slog.LevelError, msg, args) }
+
+func logXlog(level slog.Level, msg string, args []any) {
+	ctx := context.Background()
+	if !xlogOutput.Enabled(ctx, level) {
+		return
+	}
+	file, line := logCaller()
+	args = append(args, slog.Group("source", slog.String("file", file), slog.Int("L", line)))
+	xlogOutput.Log(ctx, level, msg, args...)
+}
+
+// loggerMethods are the prefixes of the Logger methods of this package,
+// which wrap each other and are skipped to find the line logging.
+var loggerMethods = []string{
+	"github.com/mudler/cogito.xlogLogger.",
+	"github.com/mudler/cogito.redactingLogger.",
+}
+
+// logCaller returns the file and line of the first caller of the loggers
+// of this package.
+func logCaller() (string, int) {
+	pcs := make([]uintptr, 8)
+	// Skip runtime.Callers, logCaller and logXlog
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !more || !slices.ContainsFunc(loggerMethods, func(prefix string) bool {
+			return strings.HasPrefix(frame.Function, prefix)
+		}) {
+			return frame.File, frame.Line
+		}
+	}
+}
+
+// defaultLogger is used where no Options are at hand.
+var defaultLogger Logger = xlogLogger{}
+
+type loggerKey struct{}
+
+// contextWithLogger returns ctx carrying the logger of a run, for the
+// extension points logging without Options (loop detectors, completion
+// caches, ...).
+func contextWithLogger(ctx context.Context, logger Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// LoggerFromContext returns the logger of the run, for loop detectors,
+// completion caches and tools logging along with cogito. It falls back to
+// the default logger. See WithLogger.
+func LoggerFromContext(ctx context.Context) Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(Logger); ok && logger != nil {
+		return logger
+	}
+	return defaultLogger
+}
@@ -0,0 +1,42 @@
+package cogito
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestDefaultLoggerReportsTheCallingLine(t *testing.T) {
+	var out bytes.Buffer
+	saved := xlogOutput
+	xlogOutput = slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	defer func() { xlogOutput = saved }()
+
+	o := defaultOptions()
+	o.Apply(WithRedactor(NewRedactor()))
+	o.logger.Info("Calling tool", "to", "jane@example.com")
+	LoggerFromContext(context.Background()).Warn("Loop detected")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), out.String())
+	}
+	for _, line := range lines {
+		if !strings.Contains(line, "source.file=") || !strings.Contains(line, "logger_internal_test.go") {
+			t.Errorf("line not attributed to its caller: %q", line)
+		}
+	}
+	if strings.Contains(lines[0], "jane@") {
+		t.Errorf("not redacted: %q", lines[0])
+	}
+}
+
+func TestLoggerFromContext(t *testing.T) {
+	logger := &recordingLogger{}
+	LoggerFromContext(contextWithLogger(context.Background(), logger)).Warn("Loop detected")
+	if len(logger.lines) != 1 {
+		t.Errorf("got %d lines, want 1", len(logger.lines))
+	}
+}
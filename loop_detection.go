@@ -0,0 +1,211 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// LoopDetector decides whether calling next, given the tool calls already
+// executed in the run, means the agent is looping.
+type LoopDetector interface {
+	IsLoop(ctx context.Context, past []ToolStatus, next *ToolChoice) bool
+}
+
+// LoopDetectorFunc adapts a function to the LoopDetector interface.
+type LoopDetectorFunc func(ctx context.Context, past []ToolStatus, next *ToolChoice) bool
+
+func (f LoopDetectorFunc) IsLoop(ctx context.Context, past []ToolStatus, next *ToolChoice) bool {
+	return f(ctx, past, next)
+}
+
+// EmbeddingFunc returns the embedding vector of text.
+type EmbeddingFunc func(ctx context.Context, text string) ([]float32, error)
+
+// ExactArgumentsLoopDetector flags a call once the same tool has already been
+// called 'steps' times with exactly the same arguments. This is the detector
+// used by WithLoopDetection.
+func ExactArgumentsLoopDetector(steps int) LoopDetector {
+	return LoopDetectorFunc(func(_ context.Context, past []ToolStatus, next *ToolChoice) bool {
+		return checkForLoop(past, next, steps)
+	})
+}
+
+// NormalizedArgumentsLoopDetector is like ExactArgumentsLoopDetector but
+// compares arguments after normalization: key order, letter case, whitespace
+// and word order in string values are ignored, so "news today" and
+// "Today  news" are considered the same call.
+func NormalizedArgumentsLoopDetector(steps int) LoopDetector {
+	return LoopDetectorFunc(func(_ context.Context, past []ToolStatus, next *ToolChoice) bool {
+		if steps <= 0 || next == nil {
+			return false
+		}
+		want := normalizeArguments(next.Arguments)
+		count := 0
+		for _, p := range past {
+			if p.Name == next.Name && normalizeArguments(p.ToolArguments.Arguments) == want {
+				count++
+			}
+		}
+		return count >= steps
+	})
+}
+
+// EmbeddingLoopDetector flags a call once the same tool has already been
+// called 'steps' times with arguments whose embeddings have a cosine
+// similarity of at least threshold with the new ones. Embeddings are kept by
+// the detector, so each distinct set of arguments is embedded once. Embedding
+// errors are logged and the call is not considered a loop.
+func EmbeddingLoopDetector(embed EmbeddingFunc, threshold float64, steps int) LoopDetector {
+	var mu sync.Mutex
+	embeddings := map[string][]float32{}
+	embedArguments := func(ctx context.Context, arguments map[string]any) ([]float32, error) {
+		text := normalizeArguments(arguments)
+		mu.Lock()
+		embedding, ok := embeddings[text]
+		mu.Unlock()
+		if ok {
+			return embedding, nil
+		}
+		embedding, err := embed(ctx, text)
+		if err != nil {
+			return nil, err
+		}
+		mu.Lock()
+		embeddings[text] = embedding
+		mu.Unlock()
+		return embedding, nil
+	}
+
+	return LoopDetectorFunc(func(ctx context.Context, past []ToolStatus, next *ToolChoice) bool {
+		if steps <= 0 || next == nil || embed == nil {
+			return false
+		}
+		want, err := embedArguments(ctx, next.Arguments)
+		if err != nil {
+			LoggerFromContext(ctx).Warn("Loop detection: failed to embed tool arguments", "error", err)
+			return false
+		}
+		count := 0
+		for _, p := range past {
+			if p.Name != next.Name {
+				continue
+			}
+			got, err := embedArguments(ctx, p.ToolArguments.Arguments)
+			if err != nil {
+				LoggerFromContext(ctx).Warn("Loop detection: failed to embed tool arguments", "error", err)
+				return false
+			}
+			if cosineSimilarity(want, got) >= threshold {
+				count++
+			}
+		}
+		return count >= steps
+	})
+}
+
+// AlternatingLoopDetector flags A/B/A/B patterns: the call is a loop when,
+// together with the most recent calls, it completes 'cycles' repetitions of
+// the same two distinct calls. Arguments are compared normalized.
+func AlternatingLoopDetector(cycles int) LoopDetector {
+	return LoopDetectorFunc(func(_ context.Context, past []ToolStatus, next *ToolChoice) bool {
+		if cycles <= 0 || next == nil {
+			return false
+		}
+		window := 2 * cycles
+		if len(past)+1 < window {
+			return false
+		}
+
+		keys := make([]string, 0, window)
+		for _, p := range past[len(past)-(window-1):] {
+			keys = append(keys, p.Name+":"+normalizeArguments(p.ToolArguments.Arguments))
+		}
+		keys = append(keys, next.Name+":"+normalizeArguments(next.Arguments))
+
+		if keys[0] == keys[1] {
+			return false
+		}
+		for i := 2; i < len(keys); i++ {
+			if keys[i] != keys[i%2] {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// AnyLoopDetector combines detectors, flagging a loop if any of them does.
+func AnyLoopDetector(detectors ...LoopDetector) LoopDetector {
+	return LoopDetectorFunc(func(ctx context.Context, past []ToolStatus, next *ToolChoice) bool {
+		for _, d := range detectors {
+			if d != nil && d.IsLoop(ctx, past, next) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// loopDetectorFor returns the detector configured for the run, if any.
+func loopDetectorFor(o *Options) LoopDetector {
+	if o.loopDetector != nil {
+		return o.loopDetector
+	}
+	if o.loopDetectionSteps > 0 {
+		return ExactArgumentsLoopDetector(o.loopDetectionSteps)
+	}
+	return nil
+}
+
+// normalizeArguments renders arguments canonically: sorted keys, and string
+// values lower-cased with their words sorted.
+func normalizeArguments(args map[string]any) string {
+	b, err := json.Marshal(normalizeValue(args))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func normalizeValue(v any) any {
+	switch t := v.(type) {
+	case string:
+		words := strings.Fields(strings.ToLower(t))
+		slices.Sort(words)
+		return strings.Join(words, " ")
+	case map[string]any:
+		out := make(map[string]any, len(t))
+		for k, val := range t {
+			out[k] = normalizeValue(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(t))
+		for i, val := range t {
+			out[i] = normalizeValue(val)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
@@ -0,0 +1,84 @@
+package cogito
+
+import (
+	"context"
+	"testing"
+)
+
+func pastCall(name string, args map[string]any) ToolStatus {
+	return ToolStatus{Name: name, ToolArguments: ToolChoice{Name: name, Arguments: args}}
+}
+
+func TestNormalizedArgumentsLoopDetector(t *testing.T) {
+	past := []ToolStatus{pastCall("search", map[string]any{"query": "news today"})}
+	d := NormalizedArgumentsLoopDetector(1)
+
+	if !d.IsLoop(context.Background(), past, &ToolChoice{Name: "search", Arguments: map[string]any{"query": "Today  news"}}) {
+		t.Error("reordered query not detected as a loop")
+	}
+	if d.IsLoop(context.Background(), past, &ToolChoice{Name: "search", Arguments: map[string]any{"query": "weather today"}}) {
+		t.Error("different query detected as a loop")
+	}
+	if ExactArgumentsLoopDetector(1).IsLoop(context.Background(), past, &ToolChoice{Name: "search", Arguments: map[string]any{"query": "today news"}}) {
+		t.Error("exact detector should not normalize")
+	}
+}
+
+func TestAlternatingLoopDetector(t *testing.T) {
+	a := map[string]any{"path": "a.txt"}
+	b := map[string]any{"path": "b.txt"}
+	past := []ToolStatus{pastCall("read", a), pastCall("read", b), pastCall("read", a)}
+	d := AlternatingLoopDetector(2)
+
+	if !d.IsLoop(context.Background(), past, &ToolChoice{Name: "read", Arguments: b}) {
+		t.Error("A/B/A/B not detected")
+	}
+	if d.IsLoop(context.Background(), past, &ToolChoice{Name: "write", Arguments: b}) {
+		t.Error("A/B/A/C detected as a loop")
+	}
+	if d.IsLoop(context.Background(), past[:2], &ToolChoice{Name: "read", Arguments: a}) {
+		t.Error("incomplete cycle detected as a loop")
+	}
+}
+
+func TestEmbeddingLoopDetector(t *testing.T) {
+	embed := func(_ context.Context, text string) ([]float32, error) {
+		if text == `{"query":"weather"}` {
+			return []float32{0, 1}, nil
+		}
+		return []float32{1, 0.1}, nil
+	}
+	past := []ToolStatus{pastCall("search", map[string]any{"query": "latest headlines"})}
+	d := EmbeddingLoopDetector(embed, 0.9, 1)
+
+	if !d.IsLoop(context.Background(), past, &ToolChoice{Name: "search", Arguments: map[string]any{"query": "top news"}}) {
+		t.Error("similar query not detected as a loop")
+	}
+	if d.IsLoop(context.Background(), past, &ToolChoice{Name: "search", Arguments: map[string]any{"query": "weather"}}) {
+		t.Error("dissimilar query detected as a loop")
+	}
+}
+
+func TestEmbeddingLoopDetectorEmbedsEachCallOnce(t *testing.T) {
+	embedded := map[string]int{}
+	embed := func(_ context.Context, text string) ([]float32, error) {
+		embedded[text]++
+		return []float32{0, 1}, nil
+	}
+	d := EmbeddingLoopDetector(embed, 0.9, 5)
+
+	var past []ToolStatus
+	for _, query := range []string{"a", "b", "c", "d"} {
+		args := map[string]any{"query": query}
+		d.IsLoop(context.Background(), past, &ToolChoice{Name: "search", Arguments: args})
+		past = append(past, pastCall("search", args))
+	}
+	for text, n := range embedded {
+		if n != 1 {
+			t.Errorf("%s embedded %d times, want 1", text, n)
+		}
+	}
+	if len(embedded) != 4 {
+		t.Errorf("got %d embeddings, want 4", len(embedded))
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
-	"github.com/mudler/xlog"
 	"github.com/sashabaranov/go-openai"
 	"github.com/tmc/langchaingo/jsonschema"
 )
@@ -15,9 +14,11 @@ import (
 type mcpTool struct {
 	name, description string
 	inputSchema       toolInputSchema
+	outputSchema      any // as declared by the server, see OutputJSONSchema
 	session           *mcp.ClientSession
 	ctx               context.Context
 	props             map[string]jsonschema.Definition
+	logger            Logger
 }
 
 func (t *mcpTool) Tool() openai.Tool {
@@ -36,22 +37,28 @@ func (t *mcpTool) Tool() openai.Tool {
 }
 
 func (t *mcpTool) Execute(args map[string]any) (string, any, error) {
+	return t.ExecuteWithContext(t.ctx, args)
+}
 
+// ExecuteWithContext implements ToolWithContext, calling the tool with the
+// run context instead of the one the tool was discovered with.
+func (t *mcpTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
 	// Call a tool on the server.
 	params := &mcp.CallToolParams{
 		Name:      t.name,
 		Arguments: args,
 	}
-	res, err := t.session.CallTool(t.ctx, params)
+	defer trackMCPProgress(ctx, params)()
+	res, err := t.session.CallTool(ctx, params)
 	if err != nil {
-		xlog.Error("CallTool failed: %v", err)
+		t.logger.Error("CallTool failed", "tool", t.name, "error", err)
 		return "", nil, err
 	}
 
-	result := contentToString(res.Content)
+	result := contentToString(res.Content, t.logger)
 
 	if res.IsError {
-		xlog.Error("tool failed", "result", result)
+		t.logger.Error("tool failed", "result", result)
 		return result, nil, errors.New("tool failed:  " + result)
 	}
 
@@ -63,7 +70,7 @@ func (t *mcpTool) Execute(args map[string]any) (string, any, error) {
 // blocks (images, audio, resources) are summarized with a descriptive marker
 // instead of being asserted to *mcp.TextContent, which would panic and crash
 // the host process when a tool returns media (see mudler/LocalAI#10101).
-func contentToString(content []mcp.Content) string {
+func contentToString(content []mcp.Content, logger Logger) string {
 	result := ""
 	for _, c := range content {
 		switch v := c.(type) {
@@ -85,7 +92,7 @@ func contentToString(content []mcp.Content) string {
 				result += fmt.Sprintf("[embedded resource: %s]", v.Resource.URI)
 			}
 		default:
-			xlog.Warn("Unhandled MCP content type", "type", fmt.Sprintf("%T", c))
+			logger.Warn("Unhandled MCP content type", "type", fmt.Sprintf("%T", c))
 		}
 	}
 	return result
@@ -93,7 +100,7 @@ func contentToString(content []mcp.Content) string {
 
 func (t *mcpTool) Close() {
 	if err := t.session.Close(); err != nil {
-		xlog.Warn("Failed to close MCP session", "error", err)
+		t.logger.Warn("Failed to close MCP session", "error", err)
 	}
 }
 
@@ -237,12 +244,12 @@ func mcpPromptsFromTransport(ctx context.Context, session *mcp.ClientSession, ar
 type MCPToolFilter = func(session *mcp.ClientSession, toolName string) bool
 
 // probe the MCP remote and generate tools that are compliant with cogito
-func mcpToolsFromTransport(ctx context.Context, session *mcp.ClientSession, filter MCPToolFilter) ([]ToolDefinitionInterface, error) {
+func mcpToolsFromTransport(ctx context.Context, session *mcp.ClientSession, filter MCPToolFilter, logger Logger) ([]ToolDefinitionInterface, error) {
 	allTools := []ToolDefinitionInterface{}
 
 	tools, err := session.ListTools(ctx, nil)
 	if err != nil {
-		xlog.Error("Error listing tools: %v", err)
+		logger.Error("Error listing tools", "error", err)
 		return nil, err
 	}
 
@@ -252,14 +259,14 @@ func mcpToolsFromTransport(ctx context.Context, session *mcp.ClientSession, filt
 		}
 		dat, err := json.Marshal(tool.InputSchema)
 		if err != nil {
-			xlog.Error("Error marshalling input schema: %v", err)
+			logger.Error("Error marshalling input schema", "tool", tool.Name, "error", err)
 			continue
 		}
 
 		var inputSchema toolInputSchema
 		err = json.Unmarshal(dat, &inputSchema)
 		if err != nil {
-			xlog.Error("Error unmarshalling input schema: %v", err)
+			logger.Error("Error unmarshalling input schema", "tool", tool.Name, "error", err)
 			continue
 		}
 
@@ -275,22 +282,24 @@ func mcpToolsFromTransport(ctx context.Context, session *mcp.ClientSession, filt
 		props := map[string]jsonschema.Definition{}
 		dat, err = json.Marshal(inputSchema.Properties)
 		if err != nil {
-			xlog.Error("Error marshalling input schema: %v", err)
+			logger.Error("Error marshalling input schema", "tool", tool.Name, "error", err)
 			continue
 		}
 		err = json.Unmarshal(dat, &props)
 		if err != nil {
-			xlog.Error("Error unmarshalling input schema properties: %v", err)
+			logger.Error("Error unmarshalling input schema properties", "tool", tool.Name, "error", err)
 			continue
 		}
 
 		allTools = append(allTools, &mcpTool{
-			name:        tool.Name,
-			description: tool.Description,
-			session:     session,
-			ctx:         ctx,
-			props:       props,
-			inputSchema: inputSchema,
+			name:         tool.Name,
+			description:  tool.Description,
+			session:      session,
+			ctx:          ctx,
+			props:        props,
+			inputSchema:  inputSchema,
+			outputSchema: tool.OutputSchema,
+			logger:       logger,
 		})
 	}
 
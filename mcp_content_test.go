@@ -19,7 +19,7 @@ func TestContentToStringImageDoesNotPanic(t *testing.T) {
 		&mcp.ImageContent{MIMEType: "image/png", Data: []byte("\x89PNGfakebytes")},
 	}
 
-	result := contentToString(content)
+	result := contentToString(content, defaultLogger)
 
 	// The text block must still be preserved verbatim.
 	if !strings.Contains(result, "here is your map: ") {
@@ -40,7 +40,7 @@ func TestContentToStringTextOnly(t *testing.T) {
 		&mcp.TextContent{Text: "world"},
 	}
 
-	if got := contentToString(content); got != "hello world" {
+	if got := contentToString(content, defaultLogger); got != "hello world" {
 		t.Fatalf("expected %q, got %q", "hello world", got)
 	}
 }
@@ -52,7 +52,7 @@ func TestContentToStringEmbeddedResourceText(t *testing.T) {
 		&mcp.EmbeddedResource{Resource: &mcp.ResourceContents{URI: "file://x", Text: "embedded body"}},
 	}
 
-	if got := contentToString(content); !strings.Contains(got, "embedded body") {
+	if got := contentToString(content, defaultLogger); !strings.Contains(got, "embedded body") {
 		t.Fatalf("expected embedded resource text in result, got %q", got)
 	}
 }
@@ -65,7 +65,7 @@ func TestContentToStringAudioAndResourceLink(t *testing.T) {
 		&mcp.ResourceLink{URI: "https://example.com/r"},
 	}
 
-	got := contentToString(content)
+	got := contentToString(content, defaultLogger)
 	if !strings.Contains(got, "audio/wav") {
 		t.Fatalf("expected audio mime type in result, got %q", got)
 	}
@@ -69,7 +69,7 @@ var _ = Describe("MCPToolFilter", func() {
 			return keep[tool]
 		}
 
-		tools, err := mcpToolsFromTransport(context.Background(), sess, filter)
+		tools, err := mcpToolsFromTransport(context.Background(), sess, filter, defaultLogger)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(tools).To(HaveLen(1))
 
@@ -82,7 +82,7 @@ var _ = Describe("MCPToolFilter", func() {
 
 	It("treats a nil filter as always-allow (default Options state)", func() {
 		sess, teardown = startInMemoryMCP("alpha", "beta")
-		tools, err := mcpToolsFromTransport(context.Background(), sess, nil)
+		tools, err := mcpToolsFromTransport(context.Background(), sess, nil, defaultLogger)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(tools).To(HaveLen(2))
 	})
@@ -93,6 +93,7 @@ var _ = Describe("MCPToolFilter", func() {
 			context.Background(),
 			sess,
 			func(*mcpsdk.ClientSession, string) bool { return false },
+			defaultLogger,
 		)
 		Expect(err).ToNot(HaveOccurred())
 		Expect(tools).To(BeEmpty())
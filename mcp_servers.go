@@ -0,0 +1,115 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// MCP transports of the servers reached at a URL.
+const (
+	MCPTransportStreamable = "streamable" // streamable HTTP, the default
+	MCPTransportSSE        = "sse"        // HTTP with server-sent events
+)
+
+// MCPServerSpec describes an MCP server, started as a command (stdio
+// transport) or reached at a URL (streamable HTTP or SSE transport).
+type MCPServerSpec struct {
+	Name    string            `yaml:"name" json:"name"`
+	Command string            `yaml:"command" json:"command"`
+	Args    []string          `yaml:"args" json:"args"`
+	Env     map[string]string `yaml:"env" json:"env"`
+	URL     string            `yaml:"url" json:"url"`
+	// Transport is the transport of a server reached at URL:
+	// MCPTransportStreamable (the default) or MCPTransportSSE.
+	Transport string `yaml:"transport" json:"transport"`
+	// Namespace exposes the tools of the server as "<namespace>.<tool>".
+	Namespace string `yaml:"namespace" json:"namespace"`
+}
+
+func (s MCPServerSpec) validate() error {
+	if (s.Command == "") == (s.URL == "") {
+		return fmt.Errorf("needs either a command or a url")
+	}
+	switch s.Transport {
+	case "", MCPTransportStreamable, MCPTransportSSE:
+	default:
+		return fmt.Errorf("has unknown transport %q, use %s or %s", s.Transport, MCPTransportStreamable, MCPTransportSSE)
+	}
+	if s.Transport != "" && s.URL == "" {
+		return fmt.Errorf("has transport %q without a url", s.Transport)
+	}
+	return nil
+}
+
+// Connect starts or reaches the server and returns a session with it. The
+// caller closes the session, which stops a server started as a command.
+func (s MCPServerSpec) Connect(ctx context.Context) (*mcp.ClientSession, error) {
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("MCP server %s %w", s.Name, err)
+	}
+	var transport mcp.Transport
+	switch {
+	case s.Transport == MCPTransportSSE:
+		transport = &mcp.SSEClientTransport{Endpoint: s.URL}
+	case s.URL != "":
+		transport = &mcp.StreamableClientTransport{Endpoint: s.URL}
+	default:
+		cmd := exec.Command(s.Command, s.Args...)
+		cmd.Env = os.Environ()
+		for k, v := range s.Env {
+			cmd.Env = append(cmd.Env, k+"="+v)
+		}
+		transport = &mcp.CommandTransport{Command: cmd}
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "cogito", Version: "v1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: MCPProgressNotificationHandler,
+	})
+	session, err := client.Connect(ctx, transport, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MCP server %s: %w", s.Name, err)
+	}
+	return session, nil
+}
+
+// startMCPServers connects the MCP servers of o for a run. o and the
+// returned options, passed on to the plans and subtasks of the run, use
+// the sessions instead of the servers; stop closes them.
+func startMCPServers(o *Options, opts []Option) (_ []Option, stop func(), err error) {
+	var sessions []*mcp.ClientSession
+	stop = func() {
+		for _, session := range sessions {
+			if err := session.Close(); err != nil {
+				o.logger.Debug("Failed to close MCP session", "error", err)
+			}
+		}
+	}
+	if len(o.mcpServers) == 0 {
+		return opts, stop, nil
+	}
+
+	var started []Option
+	for _, spec := range o.mcpServers {
+		session, err := spec.Connect(o.context)
+		if err != nil {
+			stop()
+			return opts, func() {}, err
+		}
+		o.logger.Debug("Started MCP server", "server", spec.Name)
+		sessions = append(sessions, session)
+		if spec.Namespace != "" {
+			started = append(started, WithMCPNamespace(spec.Namespace, session))
+		} else {
+			started = append(started, WithMCPs(session))
+		}
+	}
+	started = append(started, func(o *Options) { o.mcpServers = nil })
+	for _, opt := range started {
+		opt(o)
+	}
+	return append(slices.Clone(opts), started...), stop, nil
+}
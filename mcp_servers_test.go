@@ -0,0 +1,84 @@
+package cogito_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type echoInput struct {
+	Text string `json:"text"`
+}
+
+func newEchoMCPServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{Name: "echo", Version: "v1.0.0"}, nil)
+	mcp.AddTool(server, &mcp.Tool{Name: "echo", Description: "Echo the text"},
+		func(_ context.Context, _ *mcp.CallToolRequest, in echoInput) (*mcp.CallToolResult, any, error) {
+			return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "echo: " + in.Text}}}, nil, nil
+		})
+	return server
+}
+
+func serverSessions(server *mcp.Server) int {
+	n := 0
+	for range server.Sessions() {
+		n++
+	}
+	return n
+}
+
+var _ = Describe("MCP servers", func() {
+	DescribeTable("connects the servers for the run and tears them down after",
+		func(transport string) {
+			server := newEchoMCPServer()
+			var handler http.Handler
+			if transport == MCPTransportSSE {
+				handler = mcp.NewSSEHandler(func(*http.Request) *mcp.Server { return server }, nil)
+			} else {
+				handler = mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil)
+			}
+			httpServer := httptest.NewServer(handler)
+			defer httpServer.Close()
+
+			mockLLM := cogitotest.NewMockLLM()
+			mockLLM.When(cogitotest.HasTool("tools__echo")).ReplyToolCall("tools__echo", `{"text": "ping"}`)
+			mockLLM.SetAskResponse("The server said ping.")
+
+			f := NewEmptyFragment().AddMessage(UserMessageRole, "Echo ping")
+			result, err := ExecuteTools(mockLLM, f, WithIterations(1),
+				WithMCPServers(MCPServerSpec{Name: "echo", URL: httpServer.URL, Transport: transport, Namespace: "tools"}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolResults).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].Result).To(Equal("echo: ping"))
+
+			Eventually(func() int { return serverSessions(server) }).Should(BeZero())
+		},
+		Entry("streamable HTTP", MCPTransportStreamable),
+		Entry("SSE", MCPTransportSSE),
+	)
+
+	It("fails the run when a server cannot be reached", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Echo ping")
+
+		_, err := ExecuteTools(mockLLM, f,
+			WithMCPServers(MCPServerSpec{Name: "missing", Command: "/nonexistent/mcp-server"}))
+		Expect(err).To(MatchError(ContainSubstring("MCP server missing")))
+		Expect(mockLLM.Requests()).To(BeEmpty())
+	})
+
+	It("rejects invalid specs before running", func() {
+		err := ValidateOptions(WithMCPServers(MCPServerSpec{Name: "bad", Command: "x", Transport: MCPTransportSSE}))
+		var configErr *ConfigError
+		Expect(errors.As(err, &configErr)).To(BeTrue())
+		Expect(configErr.Options).To(Equal([]string{"WithMCPServers"}))
+		Expect(configErr.Problem).To(ContainSubstring("without a url"))
+	})
+})
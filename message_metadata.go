@@ -0,0 +1,106 @@
+package cogito
+
+import (
+	"slices"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// MessageMetadata annotates a message of a Fragment: where it comes from,
+// when it was added, and whether the LLM sees it. The annotations are kept
+// in Fragment.Metadata, never sent to the LLM.
+type MessageMetadata struct {
+	Source string // e.g. "user", "rag" or "ticket:1234"
+	Time   time.Time
+	// Hidden messages stay in the fragment, for the application, but are
+	// left out of every prompt.
+	Hidden bool
+	Tags   []string
+	Values map[string]any // custom keys
+}
+
+// HasTag reports whether the metadata carries tag.
+func (m MessageMetadata) HasTag(tag string) bool {
+	return slices.Contains(m.Tags, tag)
+}
+
+// MessageMetadata returns the metadata of the message at index i, the zero
+// value when it has none.
+func (f Fragment) MessageMetadata(i int) MessageMetadata {
+	if i < 0 || i >= len(f.Metadata) {
+		return MessageMetadata{}
+	}
+	return f.Metadata[i]
+}
+
+// SetMessageMetadata returns the fragment with the metadata of the message at
+// index i set to meta.
+func (f Fragment) SetMessageMetadata(i int, meta MessageMetadata) Fragment {
+	if i < 0 || i >= len(f.Messages) {
+		return f
+	}
+	metadata := slices.Clone(f.Metadata)
+	if i >= len(metadata) {
+		metadata = append(metadata, make([]MessageMetadata, i+1-len(metadata))...)
+	}
+	metadata[i] = meta
+	f.Metadata = metadata
+	return f
+}
+
+// WithMetadata returns the fragment with the metadata of its last message set
+// to meta:
+//
+//	f = f.AddMessage(cogito.UserMessageRole, doc).WithMetadata(cogito.MessageMetadata{Source: "rag", Tags: []string{"docs"}})
+func (f Fragment) WithMetadata(meta MessageMetadata) Fragment {
+	return f.SetMessageMetadata(len(f.Messages)-1, meta)
+}
+
+// Filter returns the fragment without the hidden messages and, when include
+// is set, the messages it rejects. It is how prompts are built from the
+// fragment, see WithMessageFilter.
+func (f Fragment) Filter(include func(MessageMetadata) bool) Fragment {
+	if len(f.Metadata) == 0 {
+		return f
+	}
+	var messages []openai.ChatCompletionMessage
+	var metadata []MessageMetadata
+	for i, msg := range f.Messages {
+		meta := f.MessageMetadata(i)
+		if meta.Hidden || (include != nil && !include(meta)) {
+			continue
+		}
+		messages = append(messages, msg)
+		metadata = append(metadata, meta)
+	}
+	f.Messages = messages
+	f.Metadata = metadata
+	return f
+}
+
+// promptFragment returns the messages of f included in prompts by o.
+func promptFragment(o *Options, f Fragment) Fragment {
+	if o.messageFilter == nil {
+		return f.Filter(nil)
+	}
+	return f.Filter(o.messageFilter)
+}
+
+// askFiltered asks llm for the final answer to the messages of f included
+// by o, and returns f with the answer appended.
+func askFiltered(o *Options, llm LLM, f Fragment) (Fragment, error) {
+	view := promptFragment(o, f)
+	if o.streamCallback != nil {
+		o.streamCallback(StreamEvent{Type: StreamEventAnswer})
+	}
+	result, err := askWithStreaming(o.context, llm, view, o.streamCallback, o.logger)
+	if err != nil || len(view.Messages) == len(f.Messages) {
+		return result, err
+	}
+	if len(result.Messages) >= len(view.Messages) {
+		result.Messages = append(slices.Clone(f.Messages), result.Messages[len(view.Messages):]...)
+	}
+	result.Metadata = f.Metadata
+	return result, nil
+}
@@ -0,0 +1,36 @@
+package cogito
+
+import "testing"
+
+func TestFragmentMetadataStaysAligned(t *testing.T) {
+	f := NewEmptyFragment().
+		AddMessage(UserMessageRole, "visible").
+		AddMessage(UserMessageRole, "secret").WithMetadata(MessageMetadata{Hidden: true}).
+		AddMessage(UserMessageRole, "debug").WithMetadata(MessageMetadata{Source: "debug"}).
+		AddMessage(UserMessageRole, "question")
+
+	if got := f.MessageMetadata(3); got.Source != "" || got.Hidden {
+		t.Errorf("message past the metadata has %+v, want none", got)
+	}
+	f = f.AddStartMessage(SystemMessageRole, "system")
+	if !f.MessageMetadata(2).Hidden || f.MessageMetadata(3).Source != "debug" {
+		t.Errorf("AddStartMessage misaligned the metadata: %+v", f.Metadata)
+	}
+
+	contents := func(f Fragment) (c []string) {
+		for _, m := range f.Messages {
+			c = append(c, m.Content)
+		}
+		return c
+	}
+	if got := contents(f.Filter(nil)); len(got) != 4 || got[2] != "debug" {
+		t.Errorf("Filter(nil) = %q, want the hidden message dropped", got)
+	}
+	got := contents(f.Filter(func(m MessageMetadata) bool { return m.Source != "debug" }))
+	if len(got) != 3 || got[2] != "question" {
+		t.Errorf("Filter(not debug) = %q", got)
+	}
+	if len(f.Messages) != 5 {
+		t.Errorf("Filter modified the fragment")
+	}
+}
@@ -0,0 +1,49 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Message metadata", func() {
+	It("keeps hidden and filtered out messages out of prompts, but in the result", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.HasTool("get_weather")).
+			ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("It is 21°C in Rome.")
+
+		f := NewEmptyFragment().
+			AddMessage(UserMessageRole, "internal note: customer is VIP").
+			WithMetadata(MessageMetadata{Source: "crm", Hidden: true}).
+			AddMessage(UserMessageRole, "debug trace 42").
+			WithMetadata(MessageMetadata{Source: "debug", Tags: []string{"trace"}}).
+			AddMessage(UserMessageRole, "What's the weather in Rome?")
+		result, err := ExecuteTools(mockLLM, f, WithTools(weather),
+			WithMessageFilter(func(m MessageMetadata) bool { return !m.HasTag("trace") }))
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, req := range mockLLM.Requests() {
+			for _, msg := range req.Messages {
+				Expect(msg.Content).ToNot(ContainSubstring("VIP"))
+				Expect(msg.Content).ToNot(ContainSubstring("debug trace"))
+			}
+		}
+		Expect(mockLLM.FragmentHistory).ToNot(BeEmpty())
+		for _, asked := range mockLLM.FragmentHistory {
+			for _, msg := range asked.Messages {
+				Expect(msg.Content).ToNot(ContainSubstring("VIP"))
+				Expect(msg.Content).ToNot(ContainSubstring("debug trace"))
+			}
+		}
+
+		Expect(result.Messages[0].Content).To(ContainSubstring("VIP"))
+		Expect(result.MessageMetadata(0).Source).To(Equal("crm"))
+		Expect(result.MessageMetadata(1).HasTag("trace")).To(BeTrue())
+		Expect(result.LastMessage().Content).To(Equal("It is 21°C in Rome."))
+	})
+})
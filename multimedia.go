@@ -0,0 +1,243 @@
+package cogito
+
+import (
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// MultimediaType is the kind of a Multimedia attachment.
+type MultimediaType string
+
+const (
+	MultimediaTypeImage MultimediaType = "image"
+	MultimediaTypeAudio MultimediaType = "audio"
+	MultimediaTypeVideo MultimediaType = "video"
+)
+
+// Message part types for audio and video. go-openai only models text and
+// image parts, so these parts carry their URL in the ImageURL field; the
+// bundled clients rewrite them into the wire format their backend expects
+// (see clients.EncodeMultimediaParts).
+const (
+	ChatMessagePartTypeAudioURL openai.ChatMessagePartType = "audio_url"
+	ChatMessagePartTypeVideoURL openai.ChatMessagePartType = "video_url"
+)
+
+// TypedMultimedia is implemented by Multimedia declaring their kind.
+// Multimedia not implementing it are treated as images.
+type TypedMultimedia interface {
+	Multimedia
+	Type() MultimediaType
+}
+
+// media is the Multimedia implementation returned by the constructors in this
+// file. url is either a remote URL or a base64 data URL.
+type media struct {
+	kind MultimediaType
+	url  string
+}
+
+func (m media) URL() string          { return m.url }
+func (m media) Type() MultimediaType { return m.kind }
+
+// NewImageURL returns an image attachment referencing a remote URL.
+func NewImageURL(url string) Multimedia {
+	return media{kind: MultimediaTypeImage, url: url}
+}
+
+// NewImageFromBytes returns an image attachment inlined as a base64 data URL,
+// as expected by local backends (LocalAI, Ollama) that cannot fetch remote
+// URLs. An empty mimeType is detected from the content.
+func NewImageFromBytes(data []byte, mimeType string) Multimedia {
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+	return media{kind: MultimediaTypeImage, url: dataURL(data, mimeType)}
+}
+
+// NewImageFromFile reads an image file and returns it inlined as a base64
+// data URL. The MIME type is taken from the file extension, falling back to
+// content detection.
+func NewImageFromFile(path string) (Multimedia, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image: %w", err)
+	}
+	mimeType, _, _ := strings.Cut(mime.TypeByExtension(filepath.Ext(path)), ";")
+	if !strings.HasPrefix(mimeType, "image/") {
+		mimeType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mimeType, "image/") {
+		return nil, fmt.Errorf("%s is not an image (detected %s)", path, mimeType)
+	}
+	return NewImageFromBytes(data, mimeType), nil
+}
+
+// NewAudioURL returns an audio attachment referencing a remote URL.
+func NewAudioURL(url string) Multimedia {
+	return media{kind: MultimediaTypeAudio, url: url}
+}
+
+// NewAudioData returns an audio attachment inlined as a base64 data URL.
+// mimeType is e.g. "audio/wav" or "audio/mpeg".
+func NewAudioData(data []byte, mimeType string) Multimedia {
+	return media{kind: MultimediaTypeAudio, url: dataURL(data, mimeType)}
+}
+
+// NewVideoURL returns a video attachment referencing a remote URL.
+func NewVideoURL(url string) Multimedia {
+	return media{kind: MultimediaTypeVideo, url: url}
+}
+
+// NewVideoData returns a video attachment inlined as a base64 data URL.
+// mimeType is e.g. "video/mp4".
+func NewVideoData(data []byte, mimeType string) Multimedia {
+	return media{kind: MultimediaTypeVideo, url: dataURL(data, mimeType)}
+}
+
+func dataURL(data []byte, mimeType string) string {
+	return fmt.Sprintf("data:%s;base64,%s", mimeType, base64.StdEncoding.EncodeToString(data))
+}
+
+// parseDataURL returns the data and MIME type of a base64 data URL.
+func parseDataURL(url string) ([]byte, string, bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return nil, "", false
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return nil, "", false
+	}
+	mimeType, ok := strings.CutSuffix(meta, ";base64")
+	if !ok {
+		return nil, "", false
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, "", false
+	}
+	return data, mimeType, true
+}
+
+// multimediaJSON is the JSON encoding of a Multimedia attachment: its kind,
+// and either its remote URL or its inlined data with their MIME type.
+type multimediaJSON struct {
+	Type     MultimediaType `json:"type"`
+	URL      string         `json:"url,omitempty"`
+	Data     []byte         `json:"data,omitempty"`
+	MIMEType string         `json:"mime_type,omitempty"`
+}
+
+func encodeMultimedia(mms []Multimedia) []multimediaJSON {
+	if mms == nil {
+		return nil
+	}
+	encoded := make([]multimediaJSON, 0, len(mms))
+	for _, mm := range mms {
+		e := multimediaJSON{Type: MultimediaTypeOf(mm)}
+		if data, mimeType, ok := parseDataURL(mm.URL()); ok {
+			e.Data, e.MIMEType = data, mimeType
+		} else {
+			e.URL = mm.URL()
+		}
+		encoded = append(encoded, e)
+	}
+	return encoded
+}
+
+func decodeMultimedia(encoded []multimediaJSON) []Multimedia {
+	if encoded == nil {
+		return nil
+	}
+	mms := make([]Multimedia, 0, len(encoded))
+	for _, e := range encoded {
+		kind := e.Type
+		if kind == "" {
+			kind = MultimediaTypeImage
+		}
+		url := e.URL
+		if e.Data != nil {
+			url = dataURL(e.Data, e.MIMEType)
+		}
+		mms = append(mms, media{kind: kind, url: url})
+	}
+	return mms
+}
+
+// MultimediaTypeOf returns the kind of a Multimedia attachment.
+func MultimediaTypeOf(mm Multimedia) MultimediaType {
+	if t, ok := mm.(TypedMultimedia); ok {
+		return t.Type()
+	}
+	return MultimediaTypeImage
+}
+
+// MultimediaPart maps a Multimedia attachment to a chat message part.
+func MultimediaPart(mm Multimedia) openai.ChatMessagePart {
+	partType := openai.ChatMessagePartTypeImageURL
+	switch MultimediaTypeOf(mm) {
+	case MultimediaTypeAudio:
+		partType = ChatMessagePartTypeAudioURL
+	case MultimediaTypeVideo:
+		partType = ChatMessagePartTypeVideoURL
+	}
+	return openai.ChatMessagePart{
+		Type:     partType,
+		ImageURL: &openai.ChatMessageImageURL{URL: mm.URL()},
+	}
+}
+
+// messagesWithMultimedia returns the messages of f with the attachments of
+// f.Multimedia that no message carries added to its last user message, so
+// the requests built from the conversation (tool selection, parameter
+// generation) let vision models see them. f.Messages is not modified.
+func messagesWithMultimedia(f Fragment) []openai.ChatCompletionMessage {
+	messages := slices.Clone(f.Messages)
+	if len(f.Multimedia) == 0 {
+		return messages
+	}
+
+	attached := map[string]bool{}
+	for _, m := range messages {
+		for _, part := range m.MultiContent {
+			if part.ImageURL != nil {
+				attached[part.ImageURL.URL] = true
+			}
+		}
+	}
+	var missing []openai.ChatMessagePart
+	for _, mm := range f.Multimedia {
+		if !attached[mm.URL()] {
+			attached[mm.URL()] = true
+			missing = append(missing, MultimediaPart(mm))
+		}
+	}
+	if len(missing) == 0 {
+		return messages
+	}
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != UserMessageRole.String() {
+			continue
+		}
+		m := messages[i]
+		parts := slices.Clone(m.MultiContent)
+		if len(parts) == 0 {
+			parts = []openai.ChatMessagePart{{Type: openai.ChatMessagePartTypeText, Text: m.Content}}
+			m.Content = ""
+		}
+		m.MultiContent = append(parts, missing...)
+		messages[i] = m
+		return messages
+	}
+	return append(messages, openai.ChatCompletionMessage{Role: UserMessageRole.String(), MultiContent: missing})
+}
@@ -0,0 +1,95 @@
+package cogito
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// pngHeader is the PNG signature, enough for content detection.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestNewImageFromBytes(t *testing.T) {
+	if got := NewImageFromBytes([]byte("abc"), "image/jpeg").URL(); got != "data:image/jpeg;base64,YWJj" {
+		t.Errorf("URL = %q", got)
+	}
+	if got := NewImageFromBytes(pngHeader, "").URL(); !strings.HasPrefix(got, "data:image/png;base64,") {
+		t.Errorf("detected URL = %q", got)
+	}
+}
+
+func TestNewImageFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// The extension wins over content detection
+	jpg := filepath.Join(dir, "photo.jpg")
+	if err := os.WriteFile(jpg, []byte("jpeg bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	img, err := NewImageFromFile(jpg)
+	if err != nil {
+		t.Fatalf("NewImageFromFile: %v", err)
+	}
+	if !strings.HasPrefix(img.URL(), "data:image/jpeg;base64,") || MultimediaTypeOf(img) != MultimediaTypeImage {
+		t.Errorf("URL = %q", img.URL())
+	}
+
+	// Unknown extension falls back to content detection
+	noExt := filepath.Join(dir, "chart")
+	if err := os.WriteFile(noExt, pngHeader, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if img, err := NewImageFromFile(noExt); err != nil || !strings.HasPrefix(img.URL(), "data:image/png;base64,") {
+		t.Errorf("detected image = %v, %v", img, err)
+	}
+
+	text := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(text, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewImageFromFile(text); err == nil {
+		t.Error("expected an error for a non-image file")
+	}
+	if _, err := NewImageFromFile(filepath.Join(dir, "missing.png")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestMessagesWithMultimedia(t *testing.T) {
+	receipt := NewImageURL("https://example.com/receipt.png")
+	chart := NewImageURL("https://example.com/chart.png")
+
+	f := NewEmptyFragment().
+		AddMessage(UserMessageRole, "Log this receipt").
+		AddMessage(AssistantMessageRole, "Sure").
+		AddMessage(UserMessageRole, "And this chart", chart)
+	f.Multimedia = append(f.Multimedia, receipt)
+
+	messages := messagesWithMultimedia(f)
+	last := messages[len(messages)-1]
+	if len(last.MultiContent) != 3 || last.MultiContent[2].ImageURL.URL != receipt.URL() {
+		t.Fatalf("last message parts = %+v", last.MultiContent)
+	}
+	if len(f.Messages[2].MultiContent) != 2 {
+		t.Error("the fragment messages were modified")
+	}
+
+	// Attachments already carried by a message are not repeated
+	again := messagesWithMultimedia(Fragment{Messages: messages, Multimedia: f.Multimedia})
+	if len(again[len(again)-1].MultiContent) != 3 {
+		t.Errorf("parts = %+v", again[len(again)-1].MultiContent)
+	}
+
+	// A text message gets a text part before the attachments
+	text := Fragment{
+		Messages:   []openai.ChatCompletionMessage{{Role: UserMessageRole.String(), Content: "Log this receipt"}},
+		Multimedia: []Multimedia{receipt},
+	}
+	got := messagesWithMultimedia(text)[0]
+	if got.Content != "" || len(got.MultiContent) != 2 || got.MultiContent[0].Text != "Log this receipt" {
+		t.Errorf("message = %+v", got)
+	}
+}
@@ -0,0 +1,456 @@
+package cogito
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+	"gopkg.in/yaml.v3"
+)
+
+// OpenAPIAuth holds the credentials sent with every request of the tools
+// generated by ToolsFromOpenAPI. Empty fields are not sent.
+type OpenAPIAuth struct {
+	// BearerToken is sent as "Authorization: Bearer <token>".
+	BearerToken string
+	// APIKey is sent in the APIKeyHeader header, "X-API-Key" by default.
+	APIKey       string
+	APIKeyHeader string
+	// Username and Password are sent as basic authentication.
+	Username string
+	Password string
+	// Headers are added to every request.
+	Headers map[string]string
+}
+
+// OpenAPIOption configures ToolsFromOpenAPI.
+type OpenAPIOption func(*openAPIOptions)
+
+type openAPIOptions struct {
+	baseURL    string
+	client     *http.Client
+	operations []string
+}
+
+// WithOpenAPIBaseURL overrides the server URL declared in the spec.
+func WithOpenAPIBaseURL(baseURL string) OpenAPIOption {
+	return func(o *openAPIOptions) {
+		o.baseURL = baseURL
+	}
+}
+
+// WithOpenAPIHTTPClient sets the client used to fetch the spec and call the
+// API. Defaults to http.DefaultClient.
+func WithOpenAPIHTTPClient(client *http.Client) OpenAPIOption {
+	return func(o *openAPIOptions) {
+		o.client = client
+	}
+}
+
+// WithOpenAPIOperations only generates tools for the given operations,
+// matched against the generated tool names.
+func WithOpenAPIOperations(names ...string) OpenAPIOption {
+	return func(o *openAPIOptions) {
+		o.operations = append(o.operations, names...)
+	}
+}
+
+// ToolsFromOpenAPI generates a tool for every operation of an OpenAPI 3 spec,
+// calling the REST service over HTTP when executed. spec is either the URL of
+// the spec (http:// or https://) or the document itself, in JSON or YAML.
+//
+// Tools are named after the operationId (or the method and path when it is
+// missing). Their arguments are the operation parameters, by name, and the
+// JSON request body under "body". The result of a tool is the response body;
+// responses with a 4xx or 5xx status are returned as errors.
+func ToolsFromOpenAPI(ctx context.Context, spec string, auth OpenAPIAuth, opts ...OpenAPIOption) (Tools, error) {
+	o := &openAPIOptions{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	data := []byte(spec)
+	specURL := ""
+	if strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://") {
+		specURL = spec
+		var err error
+		data, err = fetchOpenAPISpec(ctx, o.client, spec, auth)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if version, _ := doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("unsupported OpenAPI version %q, only OpenAPI 3 is supported", version)
+	}
+
+	baseURL, err := openAPIBaseURL(doc, specURL, o.baseURL)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, _ := doc["paths"].(map[string]any)
+	pathNames := make([]string, 0, len(paths))
+	for path := range paths {
+		pathNames = append(pathNames, path)
+	}
+	sort.Strings(pathNames)
+
+	tools := Tools{}
+	for _, path := range pathNames {
+		item, _ := resolveOpenAPIRef(doc, paths[path], 0).(map[string]any)
+		if item == nil {
+			continue
+		}
+		for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+			operation, _ := item[method].(map[string]any)
+			if operation == nil {
+				continue
+			}
+			tool := newOpenAPITool(doc, baseURL, path, method, item, operation, auth, o.client)
+			if len(o.operations) > 0 && !slices.Contains(o.operations, tool.name) {
+				continue
+			}
+			tools = append(tools, tool)
+		}
+	}
+	return tools, nil
+}
+
+func fetchOpenAPISpec(ctx context.Context, client *http.Client, specURL string, auth OpenAPIAuth) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, specURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAPI spec request: %w", err)
+	}
+	auth.apply(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch OpenAPI spec: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("failed to fetch OpenAPI spec: HTTP %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI spec: %w", err)
+	}
+	return data, nil
+}
+
+// openAPIBaseURL returns the URL the operation paths are relative to: the
+// override if any, else the first server of the spec, resolved against the
+// spec URL when relative.
+func openAPIBaseURL(doc map[string]any, specURL, override string) (string, error) {
+	base := override
+	if base == "" {
+		if servers, _ := doc["servers"].([]any); len(servers) > 0 {
+			if server, _ := servers[0].(map[string]any); server != nil {
+				base, _ = server["url"].(string)
+			}
+		}
+	}
+	if specURL != "" {
+		spec, err := url.Parse(specURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid OpenAPI spec URL: %w", err)
+		}
+		ref, err := url.Parse(base)
+		if err != nil {
+			return "", fmt.Errorf("invalid OpenAPI server URL %q: %w", base, err)
+		}
+		if base == "" {
+			ref = &url.URL{Path: "/"}
+		}
+		base = spec.ResolveReference(ref).String()
+	}
+	if base == "" {
+		return "", fmt.Errorf("the OpenAPI spec has no server URL, set one with WithOpenAPIBaseURL")
+	}
+	return strings.TrimSuffix(base, "/"), nil
+}
+
+// maxOpenAPIRefDepth bounds $ref inlining, so recursive schemas terminate.
+const maxOpenAPIRefDepth = 8
+
+// resolveOpenAPIRef inlines the local $ref ("#/components/...") found in
+// node and its children. Maps and slices are copied, so the result can be
+// edited without changing doc or the schemas other operations share.
+func resolveOpenAPIRef(doc map[string]any, node any, depth int) any {
+	switch n := node.(type) {
+	case map[string]any:
+		if ref, ok := n["$ref"].(string); ok {
+			if depth >= maxOpenAPIRefDepth || !strings.HasPrefix(ref, "#/") {
+				return map[string]any{}
+			}
+			var target any = doc
+			for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+				part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+				m, _ := target.(map[string]any)
+				target = m[part]
+			}
+			return resolveOpenAPIRef(doc, target, depth+1)
+		}
+		resolved := make(map[string]any, len(n))
+		for k, v := range n {
+			resolved[k] = resolveOpenAPIRef(doc, v, depth)
+		}
+		return resolved
+	case []any:
+		resolved := make([]any, len(n))
+		for i, v := range n {
+			resolved[i] = resolveOpenAPIRef(doc, v, depth)
+		}
+		return resolved
+	default:
+		return node
+	}
+}
+
+type openAPIParameter struct {
+	name, in string
+}
+
+// openAPITool is a tool calling an OpenAPI operation.
+type openAPITool struct {
+	name, description string
+	method, url       string
+	parameters        []openAPIParameter
+	schema            map[string]any
+	hasBody           bool
+	auth              OpenAPIAuth
+	client            *http.Client
+}
+
+var invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func newOpenAPITool(doc map[string]any, baseURL, path, method string, item, operation map[string]any, auth OpenAPIAuth, client *http.Client) *openAPITool {
+	name, _ := operation["operationId"].(string)
+	if name == "" {
+		name = method + "_" + strings.Trim(invalidToolNameChars.ReplaceAllString(path, "_"), "_")
+	}
+	name = strings.Trim(invalidToolNameChars.ReplaceAllString(name, "_"), "_")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	summary, _ := operation["summary"].(string)
+	description, _ := operation["description"].(string)
+	description = strings.TrimSpace(summary + "\n" + description)
+	if description == "" {
+		description = strings.ToUpper(method) + " " + path
+	}
+
+	t := &openAPITool{
+		name:        name,
+		description: description,
+		method:      strings.ToUpper(method),
+		url:         baseURL + path,
+		auth:        auth,
+		client:      client,
+	}
+
+	properties := map[string]any{}
+	required := []string{}
+
+	// Operation parameters override the path item ones with the same name and location
+	params := map[openAPIParameter]map[string]any{}
+	order := []openAPIParameter{}
+	for _, list := range []any{item["parameters"], operation["parameters"]} {
+		entries, _ := resolveOpenAPIRef(doc, list, 0).([]any)
+		for _, entry := range entries {
+			param, _ := entry.(map[string]any)
+			if param == nil {
+				continue
+			}
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			if name == "" || in == "cookie" {
+				continue
+			}
+			key := openAPIParameter{name: name, in: in}
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+	for _, key := range order {
+		param := params[key]
+		// Copied as the description is set on it
+		schema := map[string]any{"type": "string"}
+		if s, ok := param["schema"].(map[string]any); ok && s != nil {
+			schema = maps.Clone(s)
+		}
+		if description, _ := param["description"].(string); description != "" {
+			schema["description"] = description
+		}
+		properties[key.name] = schema
+		if req, _ := param["required"].(bool); req || key.in == "path" {
+			required = append(required, key.name)
+		}
+		t.parameters = append(t.parameters, key)
+	}
+
+	if body, _ := resolveOpenAPIRef(doc, operation["requestBody"], 0).(map[string]any); body != nil {
+		content, _ := body["content"].(map[string]any)
+		for mediaType, media := range content {
+			if !strings.Contains(mediaType, "json") {
+				continue
+			}
+			m, _ := media.(map[string]any)
+			schema := map[string]any{"type": "object"}
+			if s, ok := m["schema"].(map[string]any); ok && s != nil {
+				schema = maps.Clone(s)
+			}
+			if description, _ := body["description"].(string); description != "" {
+				schema["description"] = description
+			}
+			properties["body"] = schema
+			if req, _ := body["required"].(bool); req {
+				required = append(required, "body")
+			}
+			t.hasBody = true
+			break
+		}
+	}
+
+	t.schema = map[string]any{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		t.schema["required"] = required
+	}
+	return t
+}
+
+func (t *openAPITool) Tool() openai.Tool {
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        t.name,
+			Description: t.description,
+			Parameters:  t.schema,
+		},
+	}
+}
+
+func (t *openAPITool) Execute(args map[string]any) (string, any, error) {
+	return t.ExecuteWithContext(context.Background(), args)
+}
+
+// ExecuteWithContext implements ToolWithContext, sending the HTTP request of
+// the operation.
+func (t *openAPITool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	target := t.url
+	query := url.Values{}
+	headers := http.Header{}
+	for _, param := range t.parameters {
+		value, ok := args[param.name]
+		if !ok || value == nil {
+			continue
+		}
+		switch param.in {
+		case "path":
+			target = strings.ReplaceAll(target, "{"+param.name+"}", url.PathEscape(openAPIValue(value)))
+		case "query":
+			if values, ok := value.([]any); ok {
+				for _, v := range values {
+					query.Add(param.name, openAPIValue(v))
+				}
+			} else {
+				query.Add(param.name, openAPIValue(value))
+			}
+		case "header":
+			headers.Set(param.name, openAPIValue(value))
+		}
+	}
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if t.hasBody {
+		if value, ok := args["body"]; ok && value != nil {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to encode request body: %w", err)
+			}
+			body = bytes.NewReader(data)
+			headers.Set("Content-Type", "application/json")
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, t.method, target, body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	t.auth.apply(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", nil, fmt.Errorf("request to %s failed: %w", t.name, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read response of %s: %w", t.name, err)
+	}
+	if resp.StatusCode >= 400 {
+		return string(data), nil, fmt.Errorf("%s failed with HTTP %d: %s", t.name, resp.StatusCode, string(data))
+	}
+	return string(data), nil, nil
+}
+
+// openAPIValue formats an argument for a path, query or header parameter.
+func openAPIValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64, bool, int, int64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+func (a OpenAPIAuth) apply(req *http.Request) {
+	for k, v := range a.Headers {
+		req.Header.Set(k, v)
+	}
+	if a.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	}
+	if a.APIKey != "" {
+		header := a.APIKeyHeader
+		if header == "" {
+			header = "X-API-Key"
+		}
+		req.Header.Set(header, a.APIKey)
+	}
+	if a.Username != "" || a.Password != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+}
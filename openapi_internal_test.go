@@ -0,0 +1,187 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const petstoreSpec = `
+openapi: 3.0.0
+info:
+  title: Pets
+  version: "1"
+servers:
+  - url: /v1
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+    get:
+      operationId: getPet
+      summary: Get a pet by id
+      parameters:
+        - name: fields
+          in: query
+          schema:
+            type: string
+      responses:
+        200:
+          description: A pet
+  /pets:
+    post:
+      summary: Create a pet
+      requestBody:
+        required: true
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/Pet'
+      responses:
+        201:
+          description: Created
+components:
+  schemas:
+    Pet:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+`
+
+func TestToolsFromOpenAPI(t *testing.T) {
+	var got *http.Request
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/openapi.yaml" {
+			io.WriteString(w, petstoreSpec)
+			return
+		}
+		got = r
+		data, _ := io.ReadAll(r.Body)
+		gotBody = string(data)
+		if r.URL.Path == "/v1/pets/404" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "no such pet")
+			return
+		}
+		io.WriteString(w, `{"id": 7, "name": "Rex"}`)
+	}))
+	defer server.Close()
+
+	tools, err := ToolsFromOpenAPI(context.Background(), server.URL+"/openapi.yaml", OpenAPIAuth{BearerToken: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := tools.Names(); len(names) != 2 || names[0] != "post_pets" || names[1] != "getPet" {
+		t.Fatalf("tool names = %v", names)
+	}
+
+	getPet := tools.Find("getPet")
+	schema, _ := json.Marshal(getPet.Tool().Function.Parameters)
+	if !strings.Contains(string(schema), `"petId":{"type":"integer"}`) || !strings.Contains(string(schema), `"required":["petId"]`) {
+		t.Fatalf("getPet schema = %s", schema)
+	}
+
+	result, _, err := executeTool(context.Background(), getPet, map[string]any{"petId": float64(7), "fields": "name"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != `{"id": 7, "name": "Rex"}` {
+		t.Fatalf("result = %q", result)
+	}
+	if got.Method != http.MethodGet || got.URL.Path != "/v1/pets/7" || got.URL.Query().Get("fields") != "name" {
+		t.Fatalf("request = %s %s", got.Method, got.URL)
+	}
+	if got.Header.Get("Authorization") != "Bearer secret" {
+		t.Fatalf("missing bearer token, headers = %v", got.Header)
+	}
+
+	createPet := tools.Find("post_pets")
+	schema, _ = json.Marshal(createPet.Tool().Function.Parameters)
+	if !strings.Contains(string(schema), `"body":{"properties":{"name":{"type":"string"}},"required":["name"],"type":"object"}`) {
+		t.Fatalf("post_pets schema = %s", schema)
+	}
+	if _, _, err := createPet.Execute(map[string]any{"body": map[string]any{"name": "Rex"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Method != http.MethodPost || gotBody != `{"name":"Rex"}` || got.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("request = %s %s %s", got.Method, got.URL, gotBody)
+	}
+
+	result, _, err = getPet.Execute(map[string]any{"petId": "404"})
+	if err == nil || result != "no such pet" {
+		t.Fatalf("expected an error for HTTP 404, got %q, %v", result, err)
+	}
+}
+
+func TestToolsFromOpenAPIDocument(t *testing.T) {
+	tools, err := ToolsFromOpenAPI(context.Background(), petstoreSpec, OpenAPIAuth{},
+		WithOpenAPIBaseURL("https://api.example.com/v2"), WithOpenAPIOperations("getPet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tools) != 1 || tools[0].(*openAPITool).url != "https://api.example.com/v2/pets/{petId}" {
+		t.Fatalf("tools = %v", tools.Names())
+	}
+
+	if _, err := ToolsFromOpenAPI(context.Background(), `{"swagger": "2.0"}`, OpenAPIAuth{}); err == nil {
+		t.Fatal("expected an error for a Swagger 2 document")
+	}
+	if _, err := ToolsFromOpenAPI(context.Background(), `{"openapi": "3.1.0", "paths": {}}`, OpenAPIAuth{}); err == nil {
+		t.Fatal("expected an error without a server URL")
+	}
+}
+
+func TestToolsFromOpenAPIKeepSharedSchemasApart(t *testing.T) {
+	spec := `
+openapi: 3.0.0
+servers:
+  - url: https://api.example.com
+paths:
+  /pets:
+    get:
+      operationId: listPets
+      parameters:
+        - name: limit
+          in: query
+          description: How many pets to list
+          schema:
+            $ref: '#/components/schemas/Count'
+  /toys:
+    get:
+      operationId: listToys
+      parameters:
+        - name: limit
+          in: query
+          schema:
+            $ref: '#/components/schemas/Count'
+components:
+  schemas:
+    Count:
+      type: integer
+`
+	tools, err := ToolsFromOpenAPI(context.Background(), spec, OpenAPIAuth{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	limit := func(name string) map[string]any {
+		properties := tools.Find(name).Tool().Function.Parameters.(map[string]any)["properties"].(map[string]any)
+		return properties["limit"].(map[string]any)
+	}
+	if got := limit("listPets")["description"]; got != "How many pets to list" {
+		t.Errorf("listPets limit description = %v", got)
+	}
+	if got, ok := limit("listToys")["description"]; ok {
+		t.Errorf("listToys limit description = %v, want none", got)
+	}
+}
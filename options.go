@@ -2,11 +2,13 @@ package cogito
 
 import (
 	"context"
+	"io"
+	"net/http"
+	"time"
 
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/mudler/cogito/prompt"
 	"github.com/mudler/cogito/structures"
-	"github.com/mudler/xlog"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -37,22 +39,85 @@ type Options struct {
 	toolCallResultCallback            func(ToolStatus)
 	strictGuidelines                  bool
 	mcpSessions                       []*mcp.ClientSession
+	mcpServers                        []MCPServerSpec
 	guidelines                        Guidelines
 	mcpPrompts                        bool
 	mcpArgs                           map[string]string
 	mcpToolFilter                     MCPToolFilter
 	maxRetries                        int
 	loopDetectionSteps                int
+	loopDetector                      LoopDetector
 	forceReasoning                    bool
 	forceReasoningTool                bool
+	consolidatedReasoning             bool
+	speculativeParameters             bool
+	minToolConfidence                 float64
+	argumentClarification             bool
+	planProgressCallback              func(PlanProgress)
+	mcpNamespaces                     map[*mcp.ClientSession]string
+	toolCollisionPolicy               ToolCollisionPolicy
+	toolRegistry                      *ToolRegistry
 	guidedTools                       bool
 	parallelToolExecution             bool
+	extractionConfig                  *ExtractionConfig
+	reflection                        bool
+	contextShrinking                  bool
+	deduplicateToolResults            bool
+	maxToolFollowUps                  int
+	toolResultTransformers            []ToolResultTransformer
+	promptSizeLimit                   int
+	promptSizeCallback                func(PromptSizeDiagnostic)
+	outcomeStore                      OutcomeStore
+	textToolCalls                     bool
+	toolAPIFallback                   bool
+	maxContinuations                  int
+	adaptiveMaxTokens                 int
+	promptVars                        map[string]any
+	locale                            string
+	localeFormatting                  bool
+	factCheck                         bool
+	factCheckAnnotate                 bool
+	redactor                          *Redactor
+	secrets                           Secrets
+	toolCorrectionRounds              int
+	toolResultTTLs                    map[string]time.Duration
+	logger                            Logger
+	reasoningSink                     ReasoningSink
+	reasoningLogLimit                 int
+	datasetRecorder                   *DatasetRecorder
+	entityMemory                      *EntityMemory
+	vars                              *Vars
+	captureDirectResponse             bool
+	toolCacheEnabled                  bool
+	toolCacheTools                    []string
+	toolCache                         *toolResultCache
+	idempotency                       *idempotency
+	compensation                      bool
+	compensations                     *compensationLog
+	transactions                      [][]string
+	policy                            Policy
+	identity                          *Identity
+	tenants                           TenantResolver
+	tenantResolved                    bool
+	tenantErr                         error
+	tenantOptions                     []Option
+	quotas                            *quotas
+	seed                              *int
+	toolLifecycle                     *toolLifecycle
+	validated                         bool
+	batchConcurrency                  int
+	rateLimiter                       Limiter
+	toolRateLimiter                   Limiter
+	completionCache                   CompletionCache
 
 	startWithAction []*ToolChoice
 
 	sinkState bool
 
 	sinkStateTool ToolDefinitionInterface
+	// terminalStates are additional tools ending the run, see WithTerminalStates
+	terminalStates    Tools
+	sinkStateCallback func(choice *ToolChoice, result string)
 
 	// Message injection for concurrent conversation updates
 	messageInjectionChan       chan openai.ChatCompletionMessage
@@ -76,8 +141,27 @@ type Options struct {
 	todoPersistencePath string
 	todos               *structures.TODOList
 
+	// BestOf and ContentReview scoring options
+	candidateLLMs   []LLM
+	reviewRubric    []RubricCriterion
+	reviewThreshold float64
+
 	messagesManipulator func([]openai.ChatCompletionMessage) []openai.ChatCompletionMessage
 
+	// messageFilter selects, by their metadata, the messages of the fragment
+	// included in prompts
+	messageFilter func(MessageMetadata) bool
+
+	auditTrail *AuditTrail
+
+	// Notifications of key events, see WithWebhook
+	webhooks      []webhook
+	webhookClient *http.Client
+
+	// Builders of the conversation context of prompts, see WithContextBuilder
+	contextBuilder  ContextBuilder
+	contextBuilders map[prompt.PromptType]ContextBuilder
+
 	// Streaming callback for live token delivery
 	streamCallback StreamCallback
 
@@ -111,13 +195,18 @@ func defaultOptions() *Options {
 		loopDetectionSteps:     0,
 		forceReasoning:         false,
 		maxAdjustmentAttempts:  5,
+		maxToolFollowUps:       3,
 		sinkStateTool:          &defaultSinkStateTool{},
 		sinkState:              true,
+		toolAPIFallback:        true,
+		maxContinuations:       defaultMaxContinuations,
 		context:                context.Background(),
 		statusCallback:         func(s string) {},
 		reasoningCallback:      func(s string) {},
 		compactionThreshold:    0,  // Disabled by default
 		compactionKeepMessages: 10, // Keep 10 recent messages by default
+		logger:                 defaultLogger,
+		batchConcurrency:       4,
 	}
 }
 
@@ -125,6 +214,21 @@ func (o *Options) Apply(opts ...Option) {
 	for _, opt := range opts {
 		opt(o)
 	}
+	applyTenant(o)
+	if o.logger == nil {
+		o.logger = defaultLogger
+	}
+	applyRedaction(o)
+	if o.locale != "" {
+		if pack, ok := prompt.LocalePrompts(o.locale); ok {
+			o.prompts = pack.Merge(o.prompts)
+		} else {
+			o.logger.Debug("No prompt pack registered for locale, using the default prompts", "locale", o.locale)
+		}
+	}
+	if len(o.promptVars) > 0 {
+		o.prompts = o.prompts.WithVars(o.promptVars)
+	}
 }
 
 var (
@@ -146,6 +250,13 @@ var (
 		o.sinkState = false
 	}
 
+	// DisableToolAPIFallback fails runs whose backend rejects the tools API,
+	// instead of falling back to text-based tool calls (see
+	// WithTextBasedToolCalls) and recording it in Status.ToolAPIFallback.
+	DisableToolAPIFallback Option = func(o *Options) {
+		o.toolAPIFallback = false
+	}
+
 	// EnableInfiniteExecution enables infinite, long-term execution on Plans
 	EnableInfiniteExecution Option = func(o *Options) {
 		o.infiniteExecution = true
@@ -183,6 +294,67 @@ var (
 	EnableParallelToolExecution Option = func(o *Options) {
 		o.parallelToolExecution = true
 	}
+
+	// EnableReflection enables a self-reflection step after a failed iteration
+	// (tool error, loop detection, goal not achieved). The LLM writes a short
+	// lessons-learned note which is stored in Status.Reflections and
+	// Status.ReasoningLog, and injected into the next tool selection prompt.
+	// With reflection enabled, a detected loop is reflected upon and the
+	// repeated call is skipped instead of aborting with ErrLoopDetected.
+	EnableReflection Option = func(o *Options) {
+		o.reflection = true
+	}
+
+	// EnableContextShrinking retries LLM calls rejected for exceeding the
+	// model context window with progressively compacted prompts (dropping
+	// additional context, summarizing long tool results with the LLM,
+	// trimming history) instead of failing the run. Tool results too long to
+	// summarize are truncated. Each degradation is recorded in
+	// Status.ContextDegradations.
+	EnableContextShrinking Option = func(o *Options) {
+		o.contextShrinking = true
+	}
+
+	// EnableToolResultDeduplication replaces a tool result identical to an
+	// earlier result of the same tool with a short "same as previous result"
+	// reference in the conversation. Full results are still recorded in
+	// Status.ToolResults.
+	EnableToolResultDeduplication Option = func(o *Options) {
+		o.deduplicateToolResults = true
+	}
+
+	// EnableCompensation undoes the side effects of a failed or aborted run,
+	// as in the saga pattern: the compensating actions of the tools called
+	// successfully (see ToolDefinition.Compensation and AddCompensation) run
+	// in reverse order when ExecuteTools returns an error, and their outcomes
+	// are recorded in Status.Compensations.
+	EnableCompensation Option = func(o *Options) {
+		o.compensation = true
+	}
+
+	// EnableLocaleFormatting rewrites US-formatted dates, numbers and
+	// currency amounts in the final answer in the format of the locale set
+	// with WithLocale (see LocalizeText). Streamed tokens are delivered as
+	// the model produced them.
+	EnableLocaleFormatting Option = func(o *Options) {
+		o.localeFormatting = true
+	}
+
+	// EnableFactCheck cross-checks the claims of the final answer against
+	// the tool results of the run, rewriting the answer without the claims
+	// the evidence does not support. Unsupported claims are recorded in
+	// Status.UnsupportedClaims. See also FactCheck and
+	// EnableFactCheckAnnotations.
+	EnableFactCheck Option = func(o *Options) {
+		o.factCheck = true
+	}
+
+	// EnableFactCheckAnnotations enables the fact check, annotating the
+	// final answer with the unsupported claims instead of rewriting it.
+	EnableFactCheckAnnotations Option = func(o *Options) {
+		o.factCheck = true
+		o.factCheckAnnotate = true
+	}
 )
 
 // WithIterations allows to set the number of refinement iterations
@@ -192,6 +364,10 @@ func WithIterations(i int) func(o *Options) {
 	}
 }
 
+// WithSinkState replaces the default "reply" sink state: the tool the LLM
+// selects when no other tool is needed, ending the run. Use a TerminalState
+// (e.g. ReplyToUserState) or any tool; a tool that is not a TerminalState
+// makes the LLM write a final reply.
 func WithSinkState(tool ToolDefinitionInterface) func(o *Options) {
 	return func(o *Options) {
 		o.sinkState = true
@@ -199,6 +375,26 @@ func WithSinkState(tool ToolDefinitionInterface) func(o *Options) {
 	}
 }
 
+// WithTerminalStates adds tools ending the run when selected, next to the
+// sink state, e.g. WaitState and GiveUpState. Their Execute runs when they
+// are entered; a TerminalState without Reply ends the run without asking
+// the LLM for a final reply. Enables the sink state.
+func WithTerminalStates(tools ...ToolDefinitionInterface) func(o *Options) {
+	return func(o *Options) {
+		o.sinkState = true
+		o.terminalStates = append(o.terminalStates, tools...)
+	}
+}
+
+// WithSinkStateCallback calls fn when the run enters a sink state, with the
+// terminal tool selected and the result of running it. A text reply instead
+// of a tool call counts as entering the sink state.
+func WithSinkStateCallback(fn func(choice *ToolChoice, result string)) func(o *Options) {
+	return func(o *Options) {
+		o.sinkStateCallback = fn
+	}
+}
+
 // WithPrompt allows to set a custom prompt for a given PromptType
 func WithPrompt(t prompt.PromptType, p prompt.StaticPrompt) func(o *Options) {
 	return func(o *Options) {
@@ -210,6 +406,40 @@ func WithPrompt(t prompt.PromptType, p prompt.StaticPrompt) func(o *Options) {
 	}
 }
 
+// WithPrompts sets several prompts at once, e.g. a map loaded with
+// prompt.LoadDir. It overrides the prompts set so far for the same types.
+func WithPrompts(prompts prompt.PromptMap) func(o *Options) {
+	return func(o *Options) {
+		o.prompts = o.prompts.Merge(prompts)
+	}
+}
+
+// WithLocale selects the prompt pack registered for locale (see
+// prompt.RegisterLocale and prompt.LoadLocales), so the built-in prompts are
+// in the conversation language. Prompts the pack does not translate stay in
+// English, and prompts set with WithPrompt or WithPrompts take precedence.
+// The locale is also the one EnableLocaleFormatting formats answers for.
+func WithLocale(locale string) func(o *Options) {
+	return func(o *Options) {
+		o.locale = locale
+	}
+}
+
+// WithPromptVars makes custom values (user name, locale, current date,
+// environment facts...) available to every prompt template, through the
+// "var" and "vars" template functions: {{ var "user_name" }}. Repeated calls
+// add to the variables set so far.
+func WithPromptVars(vars map[string]any) func(o *Options) {
+	return func(o *Options) {
+		if o.promptVars == nil {
+			o.promptVars = make(map[string]any, len(vars))
+		}
+		for k, v := range vars {
+			o.promptVars[k] = v
+		}
+	}
+}
+
 // WithTools allows to set the tools available to the Agent.
 // Pass *ToolDefinition[T] instances - they will automatically generate openai.Tool via their Tool() method.
 // Example: WithTools(&ToolDefinition[SearchArgs]{...}, &ToolDefinition[WeatherArgs]{...})
@@ -247,6 +477,154 @@ func WithMaxAttempts(i int) func(o *Options) {
 	}
 }
 
+// WithMaxToolFollowUps bounds how many follow-up questions (see NeedsMoreInfo)
+// a single tool call may ask the LLM before it fails. Default is 3.
+func WithMaxToolFollowUps(n int) func(o *Options) {
+	return func(o *Options) {
+		o.maxToolFollowUps = n
+	}
+}
+
+// WithToolResultTransformer adds transformers applied, in order, to every tool
+// result before it is added to the conversation and recorded in
+// Status.ToolResults. See TruncateToolResult, PrettyPrintJSONToolResult,
+// HTMLToTextToolResult and SummarizeToolResult.
+func WithToolResultTransformer(transformers ...ToolResultTransformer) func(o *Options) {
+	return func(o *Options) {
+		o.toolResultTransformers = append(o.toolResultTransformers, transformers...)
+	}
+}
+
+// WithToolResultTTL sets how long results of the given tools (of all tools,
+// when none is given) stay valid across turns. When a conversation is
+// executed again, results older than their TTL are removed from it and the
+// model is told to call the tools again for fresh data. The expired results
+// stay in Status.ToolResults, marked Expired.
+func WithToolResultTTL(ttl time.Duration, tools ...string) func(o *Options) {
+	return func(o *Options) {
+		if o.toolResultTTLs == nil {
+			o.toolResultTTLs = map[string]time.Duration{}
+		}
+		if len(tools) == 0 {
+			o.toolResultTTLs[""] = ttl
+		}
+		for _, t := range tools {
+			o.toolResultTTLs[t] = ttl
+		}
+	}
+}
+
+// WithToolResultCache caches the results of the given tools (of all tools,
+// when none is given) by arguments: calling a tool again with the same
+// arguments returns the earlier result without running it. The cache lasts
+// for the run, or for the whole batch with ExecuteToolsBatch. Failed calls
+// are not cached. Only use it for tools without side effects.
+func WithToolResultCache(tools ...string) func(o *Options) {
+	return func(o *Options) {
+		o.toolCacheEnabled = true
+		o.toolCacheTools = append(o.toolCacheTools, tools...)
+	}
+}
+
+// WithBatchConcurrency sets how many fragments ExecuteToolsBatch runs at the
+// same time. Defaults to 4.
+func WithBatchConcurrency(n int) func(o *Options) {
+	return func(o *Options) {
+		o.batchConcurrency = n
+	}
+}
+
+// WithRateLimiter makes every LLM call (Ask, CreateChatCompletion and
+// streaming) wait on l first. Share one limiter, e.g. a TokenBucket, across
+// concurrent agents using the same API key to stay under the provider's rate
+// limits.
+func WithRateLimiter(l Limiter) func(o *Options) {
+	return func(o *Options) {
+		o.rateLimiter = l
+	}
+}
+
+// WithToolRateLimiter makes every tool execution wait on l first, for tools
+// backed by rate-limited APIs. Cached results (see WithToolResultCache) do
+// not wait.
+func WithToolRateLimiter(l Limiter) func(o *Options) {
+	return func(o *Options) {
+		o.toolRateLimiter = l
+	}
+}
+
+// WithCompletionCache answers chat completions (tool selection, structured
+// extraction, guideline and boolean evaluations) from c when an equivalent
+// request was already made, instead of calling the model. Use
+// NewMemoryCompletionCache for exact matches or NewSemanticCompletionCache to
+// also reuse replies to near-identical requests. Free-form Ask calls and
+// streaming are not cached.
+func WithCompletionCache(c CompletionCache) func(o *Options) {
+	return func(o *Options) {
+		o.completionCache = c
+	}
+}
+
+// WithLogger sends the log output to l instead of stdout, where it is
+// written in the xlog format by default. Any *slog.Logger can be used, e.g.
+// slog.New(slog.DiscardHandler) to silence cogito, or a logger with extra
+// attributes to tag every record of a run.
+func WithLogger(l Logger) func(o *Options) {
+	return func(o *Options) {
+		o.logger = l
+	}
+}
+
+// WithPromptSizeLimit measures every prompt sent during ExecuteTools (roughly
+// four characters per token) and, when it exceeds maxTokens, logs a
+// PromptSizeDiagnostic and shrinks the prompt with the same stages as
+// EnableContextShrinking. Applied stages are recorded in
+// Status.ContextDegradations. 0 (default) disables the guard.
+func WithPromptSizeLimit(maxTokens int) func(o *Options) {
+	return func(o *Options) {
+		o.promptSizeLimit = maxTokens
+	}
+}
+
+// WithMaxContinuations sets how many continuation requests complete a reply
+// cut off by max_tokens (finish_reason "length"), 3 by default. The
+// continuations are stitched back into the reply, so truncated tool
+// arguments still parse. 0 disables them, leaving truncated replies to the
+// JSON repair and the retries.
+func WithMaxContinuations(n int) func(o *Options) {
+	return func(o *Options) {
+		o.maxContinuations = n
+	}
+}
+
+// WithAdaptiveMaxTokens retries the parameter-generation requests truncated
+// by max_tokens with max_tokens doubled, up to limit, and keeps the raised
+// value for the following ones. Replies still truncated at limit are
+// continued (see WithMaxContinuations). 0 (default) disables it.
+func WithAdaptiveMaxTokens(limit int) func(o *Options) {
+	return func(o *Options) {
+		o.adaptiveMaxTokens = limit
+	}
+}
+
+// WithPromptSizeCallback sets a callback receiving the diagnostic of every
+// prompt exceeding WithPromptSizeLimit.
+func WithPromptSizeCallback(fn func(PromptSizeDiagnostic)) func(o *Options) {
+	return func(o *Options) {
+		o.promptSizeCallback = fn
+	}
+}
+
+// WithOutcomeStore records the outcome of every tool call (task, tool,
+// arguments, success) into store, and surfaces approaches that succeeded on
+// similar tasks in the tool selection and planning prompts. See
+// NewFileOutcomeStore.
+func WithOutcomeStore(store OutcomeStore) func(o *Options) {
+	return func(o *Options) {
+		o.outcomeStore = store
+	}
+}
+
 // WithFeedbackCallback sets a callback to get continous feedback during execution of plans
 func WithFeedbackCallback(fn func() *Fragment) func(o *Options) {
 	return func(o *Options) {
@@ -295,6 +673,48 @@ func WithMCPs(sessions ...*mcp.ClientSession) func(o *Options) {
 	}
 }
 
+// WithMCPServers adds MCP servers, started (commands) or connected to (URLs)
+// at the start of each run and torn down when it ends, so callers do not
+// manage the sessions themselves. The plans and subtasks of a run share its
+// sessions.
+func WithMCPServers(specs ...MCPServerSpec) func(o *Options) {
+	return func(o *Options) {
+		o.mcpServers = append(o.mcpServers, specs...)
+	}
+}
+
+// WithMCPNamespace adds MCP sessions like WithMCPs, exposing their tools under
+// namespace (e.g. "weather__get_weather") so they cannot collide with tools
+// of the same name from other sources.
+func WithMCPNamespace(namespace string, sessions ...*mcp.ClientSession) func(o *Options) {
+	return func(o *Options) {
+		if o.mcpNamespaces == nil {
+			o.mcpNamespaces = map[*mcp.ClientSession]string{}
+		}
+		for _, session := range sessions {
+			o.mcpNamespaces[session] = namespace
+		}
+		o.mcpSessions = append(o.mcpSessions, sessions...)
+	}
+}
+
+// WithToolRegistry adds the tools of registry, read again at every iteration
+// so tools can be registered and unregistered during the run.
+func WithToolRegistry(registry *ToolRegistry) func(o *Options) {
+	return func(o *Options) {
+		o.toolRegistry = registry
+	}
+}
+
+// WithToolCollisionPolicy sets how tools sharing a name across WithTools,
+// guidelines and MCP servers are handled. Defaults to PreferLocalTools;
+// identical tools registered twice are never a collision.
+func WithToolCollisionPolicy(policy ToolCollisionPolicy) func(o *Options) {
+	return func(o *Options) {
+		o.toolCollisionPolicy = policy
+	}
+}
+
 // WithMCPArgs sets the arguments for the MCP prompts
 func WithMCPArgs(args map[string]string) func(o *Options) {
 	return func(o *Options) {
@@ -336,6 +756,15 @@ func WithMessagesManipulator(fn func([]openai.ChatCompletionMessage) []openai.Ch
 	}
 }
 
+// WithExtractionConfig overrides the pseudo-tool used to extract structured
+// JSON (tool name, strict flag, schema wrapping) for this run. It takes
+// precedence over an LLM client implementing ExtractionConfigProvider.
+func WithExtractionConfig(cfg ExtractionConfig) func(o *Options) {
+	return func(o *Options) {
+		o.extractionConfig = &cfg
+	}
+}
+
 // WithMaxRetries sets the maximum number of retries for LLM calls
 func WithMaxRetries(retries int) func(o *Options) {
 	return func(o *Options) {
@@ -351,6 +780,25 @@ func WithLoopDetection(steps int) func(o *Options) {
 	}
 }
 
+// WithLoopDetector sets a custom loop detector, replacing the exact-argument
+// comparison of WithLoopDetection. See NormalizedArgumentsLoopDetector,
+// EmbeddingLoopDetector, AlternatingLoopDetector and AnyLoopDetector.
+func WithLoopDetector(detector LoopDetector) func(o *Options) {
+	return func(o *Options) {
+		o.loopDetector = detector
+	}
+}
+
+// WithCaptureDirectResponse makes ExecuteTools return ErrDirectResponse when
+// the LLM answers with text instead of calling a tool. The answer is already
+// the last message of the returned fragment, so callers can use it as the
+// final reply instead of asking the LLM again.
+func WithCaptureDirectResponse() func(o *Options) {
+	return func(o *Options) {
+		o.captureDirectResponse = true
+	}
+}
+
 // WithForceReasoning enables forcing the LLM to reason before selecting tools
 func WithForceReasoning() func(o *Options) {
 	return func(o *Options) {
@@ -370,6 +818,108 @@ func WithForceReasoningTool() func(o *Options) {
 	}
 }
 
+// WithConsolidatedReasoning enables forced reasoning in a single round-trip:
+// the reasoning, the tool choice(s) and their arguments are requested in one
+// structured completion instead of four (reasoning, tool pick, parameter
+// reasoning, parameter generation). If the reply can't be used (no call, an
+// unknown tool or missing required arguments), the multi-step flow runs
+// instead. The prompt can be customized with
+// prompt.PromptConsolidatedReasoningType.
+func WithConsolidatedReasoning() func(o *Options) {
+	return func(o *Options) {
+		o.consolidatedReasoning = true
+		o.forceReasoning = true
+		o.sinkState = true
+	}
+}
+
+// WithSpeculativeParameters lowers the latency of forced reasoning: while
+// the LLM reasons about the next tool, the parameters of the most likely one
+// are generated concurrently, and used if it is picked. The likely tool is the
+// only one suggested by the relevant guidelines, or the only tool available.
+// When another tool is picked the speculation is cancelled and discarded, at
+// the cost of its completion. Speculative parameters are generated from the
+// conversation alone, without the reasoning of the selection. It implies
+// WithForceReasoning.
+func WithSpeculativeParameters() func(o *Options) {
+	return func(o *Options) {
+		o.speculativeParameters = true
+		o.forceReasoning = true
+		o.sinkState = true
+	}
+}
+
+// WithMinToolConfidence sets the confidence (0-1) a tool selection needs to
+// be executed. The confidence is self-reported by the LLM when reasoning is
+// forced, or derived from token log probabilities, which are requested from
+// the backend. Below the threshold no tool runs: the LLM is asked for a
+// clarifying question, returned as the reply (as when the sink state is
+// selected). Selections of unknown confidence are executed.
+func WithMinToolConfidence(x float64) func(o *Options) {
+	return func(o *Options) {
+		o.minToolConfidence = x
+	}
+}
+
+// WithArgumentClarification checks the arguments of every selected tool with
+// required parameters before running it. If required values are missing or
+// were guessed rather than derived from the conversation, ExecuteTools
+// returns a *ClarificationNeeded error (with the missing fields and a
+// question for the user) instead of running the tool. Resume the run with
+// ClarificationNeeded.Resume once the user answers. Costs one extra LLM call
+// per tool call with required parameters.
+func WithArgumentClarification() func(o *Options) {
+	return func(o *Options) {
+		o.argumentClarification = true
+	}
+}
+
+// WithPlanProgressCallback calls fn as ExecutePlan progresses: when a subtask
+// attempt starts and finishes (with the goal-achievement verdict), when the
+// plan is re-evaluated and when it completes. Useful to drive progress bars.
+func WithPlanProgressCallback(fn func(PlanProgress)) func(o *Options) {
+	return func(o *Options) {
+		o.planProgressCallback = fn
+	}
+}
+
+// WithTextBasedToolCalls performs tool calling over plain chat completions,
+// for backends without the tools API. Tool selection is done through a
+// structured prompt describing the tools, the call being parsed from the JSON
+// the model replies with ({"name": ..., "arguments": {...}}). When the model
+// selects a tool without its arguments, they are generated in a second step.
+// Structured extraction asks for the JSON object directly, in JSON mode.
+// Tool calls and results in the history are sent as plain messages. The
+// instructions can be customized with prompt.PromptTextToolCallsType.
+func WithTextBasedToolCalls() func(o *Options) {
+	return func(o *Options) {
+		o.textToolCalls = true
+	}
+}
+
+// WithCompatibilityMode configures cogito for small local models and for
+// backends without function calling support. It composes:
+//   - text-based tool calling (see WithTextBasedToolCalls);
+//   - simplified prompts, without forced reasoning or the tool reasoner;
+//   - lower defaults: 2 retries, 1 adjustment attempt, 1 tool follow-up;
+//   - loop detection over the last 2 steps and context shrinking.
+//
+// Options passed after it override the individual settings.
+func WithCompatibilityMode() func(o *Options) {
+	return func(o *Options) {
+		WithTextBasedToolCalls()(o)
+		o.forceReasoning = false
+		o.forceReasoningTool = false
+		o.consolidatedReasoning = false
+		o.toolReasoner = false
+		o.maxRetries = 2
+		o.maxAdjustmentAttempts = 1
+		o.maxToolFollowUps = 1
+		o.loopDetectionSteps = 2
+		o.contextShrinking = true
+	}
+}
+
 // WithStartWithAction sets the initial tool choice to start with
 func WithStartWithAction(tool ...*ToolChoice) func(o *Options) {
 	return func(o *Options) {
@@ -384,6 +934,62 @@ func WithReasoningCallback(fn func(string)) func(o *Options) {
 	}
 }
 
+// WithReasoningSink sends the reasoning of the run (tool selection
+// rationales, direct replies and reflections) to sink as ReasoningRecords.
+// Use FileReasoningSink for a JSON-lines audit trail, MemoryReasoningSink to
+// inspect it after the run, or ReasoningSinkFunc for a custom destination.
+func WithReasoningSink(sink ReasoningSink) func(o *Options) {
+	return func(o *Options) {
+		o.reasoningSink = sink
+	}
+}
+
+// WithReasoningLogLimit keeps at most n entries, the most recent, in
+// Status.ReasoningLog, so long runs do not grow it unbounded. Combine it with
+// WithReasoningSink to keep the whole reasoning in a store, e.g. a
+// FileReasoningSink queried later by run ID (see ReasoningStore). 0, the
+// default, keeps every entry.
+func WithReasoningLogLimit(n int) func(o *Options) {
+	return func(o *Options) {
+		o.reasoningLogLimit = n
+	}
+}
+
+// WithDatasetRecorder writes every successful tool call of the run to w as
+// a fine-tuning example in format: the conversation the call was selected
+// from, the available tools, the call and its result, one JSON line each.
+// Plans and sub-agents record to the same writer.
+func WithDatasetRecorder(w io.Writer, format DatasetFormat) func(o *Options) {
+	return func(o *Options) {
+		o.datasetRecorder = NewDatasetRecorder(w, format)
+	}
+}
+
+// WithEntityMemory records the values established in the conversation in
+// memory (see EntityMemory) and hints them to the LLM when it selects tools
+// and generates their arguments. Pass the same memory to the runs of a
+// conversation; plans share it.
+func WithEntityMemory(memory *EntityMemory) func(o *Options) {
+	return func(o *Options) {
+		o.entityMemory = memory
+	}
+}
+
+// WithVars sets the variables of the run (see Vars), referenced as
+// {{.vars.name}} in guidelines and tool arguments. The store is created
+// with the option: plans and sub-agents of the run share it.
+func WithVars(values map[string]any) func(o *Options) {
+	return WithVarStore(NewVars(values))
+}
+
+// WithVarStore is WithVars with a store of the caller, to share it across
+// runs or read the variables set by tools after the run.
+func WithVarStore(vars *Vars) func(o *Options) {
+	return func(o *Options) {
+		o.vars = vars
+	}
+}
+
 // WithReviewerLLM specifies a judge LLM for Planning with TODOs.
 // When provided along with a plan, enables Planning with TODOs where the judge LLM
 // reviews work after each iteration and decides whether goal execution is completed or needs rework.
@@ -393,6 +999,59 @@ func WithReviewerLLM(reviewerLLMs ...LLM) func(o *Options) {
 	}
 }
 
+// WithReviewRubric makes ContentReview score the content on each criterion
+// (0 to 10) every iteration, stopping as soon as the average score reaches
+// threshold. DefaultRubric is used when no criteria are given. Scores are
+// recorded in Status.ReviewScores.
+func WithReviewRubric(threshold float64, criteria ...RubricCriterion) func(o *Options) {
+	return func(o *Options) {
+		if len(criteria) == 0 {
+			criteria = DefaultRubric
+		}
+		o.reviewRubric = criteria
+		o.reviewThreshold = threshold
+	}
+}
+
+// WithRedactor masks sensitive data (emails, phone numbers, API keys, ...)
+// with r in every message sent to the LLM, in the status and reasoning
+// callbacks, in the reasoning sink and in the log output. Streamed tokens
+// are not redacted. Use NewRedactor() for the default patterns.
+func WithRedactor(r *Redactor) func(o *Options) {
+	return func(o *Options) {
+		o.redactor = r
+	}
+}
+
+// WithToolCorrection sends a tool call that still fails after the
+// attempts of WithMaxAttempts back to the LLM along with the error, so it
+// can correct the arguments or pick a different tool, for up to rounds
+// rounds before giving up. The failed calls are recorded in
+// ToolStatus.Corrections.
+func WithToolCorrection(rounds int) func(o *Options) {
+	return func(o *Options) {
+		o.toolCorrectionRounds = rounds
+	}
+}
+
+// WithSecrets makes secrets available to tools through their execution
+// context (see GetSecret). Tools implementing ContextTool or
+// ToolWithContext receive it.
+func WithSecrets(secrets Secrets) func(o *Options) {
+	return func(o *Options) {
+		o.secrets = secrets
+	}
+}
+
+// WithCandidateLLMs sets the LLMs generating the candidates of BestOf, e.g.
+// clients of different models or with different temperatures. Candidates
+// are assigned to them in turn; by default the LLM passed to BestOf is used.
+func WithCandidateLLMs(llms ...LLM) func(o *Options) {
+	return func(o *Options) {
+		o.candidateLLMs = append(o.candidateLLMs, llms...)
+	}
+}
+
 // WithTODOPersistence enables file-based TODO persistence.
 // TODOs will be saved to and loaded from the specified file path.
 func WithTODOPersistence(path string) func(o *Options) {
@@ -479,6 +1138,26 @@ func WithCompactionKeepMessages(count int) func(o *Options) {
 	}
 }
 
+// WithMessageFilter sets which messages of the fragment are included in the
+// prompts built from it, by their MessageMetadata. Hidden messages are never
+// included:
+//
+//	cogito.WithMessageFilter(func(m cogito.MessageMetadata) bool { return m.Source != "debug" })
+func WithMessageFilter(include func(MessageMetadata) bool) func(o *Options) {
+	return func(o *Options) {
+		o.messageFilter = include
+	}
+}
+
+// WithAuditTrail records in trail, step by step, how ExecuteTools changed the
+// fragment: the messages and tool calls added, the tool results, the
+// reasoning and the tokens spent, see DiffFragments.
+func WithAuditTrail(trail *AuditTrail) func(o *Options) {
+	return func(o *Options) {
+		o.auditTrail = trail
+	}
+}
+
 // WithStreamCallback sets a callback to receive streaming events during execution.
 // When set alongside a StreamingLLM, final answer generation will stream token-by-token.
 func WithStreamCallback(fn StreamCallback) func(o *Options) {
@@ -596,7 +1275,7 @@ func (d *defaultSinkStateTool) Execute(args map[string]any) (string, any, error)
 	if !ok {
 		return "", nil, nil
 	}
-	xlog.Debug("[defaultSinkStateTool] Running default sink state tool", "reasoning", reasoning)
+	defaultLogger.Debug("[defaultSinkStateTool] Running default sink state tool", "reasoning", reasoning)
 	return reasoning, reasoning, nil
 }
 
@@ -0,0 +1,250 @@
+package cogito
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ToolOutcome records how a tool call went for a task, so later runs on
+// similar tasks can reuse approaches that worked.
+type ToolOutcome struct {
+	Tenant     string    `json:"tenant,omitempty"` // see ContextWithTenant
+	Intent     string    `json:"intent"`           // the task the tool was called for
+	Tool       string    `json:"tool"`             // tool name
+	Arguments  string    `json:"arguments"`        // normalized arguments pattern
+	Quality    float64   `json:"quality"`          // 0 (failed) to 1 (succeeded)
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// OutcomeStore persists tool outcomes across runs. See WithOutcomeStore.
+type OutcomeStore interface {
+	Record(ctx context.Context, outcome ToolOutcome) error
+	// Similar returns up to limit outcomes recorded for intents similar to
+	// intent, most similar first.
+	// Only the outcomes of the tenant of ctx are returned (see ContextWithTenant).
+	Similar(ctx context.Context, intent string, limit int) ([]ToolOutcome, error)
+}
+
+// FileOutcomeStore is an OutcomeStore kept in memory and, when created with a
+// path, appended to a JSON-lines file. Similarity is the word overlap
+// (Jaccard index) between intents.
+type FileOutcomeStore struct {
+	// MinSimilarity is the minimum intent similarity (0-1) for Similar to
+	// return an outcome. Defaults to 0.3.
+	MinSimilarity float64
+
+	mu       sync.Mutex
+	path     string
+	outcomes []ToolOutcome
+}
+
+var _ OutcomeStore = (*FileOutcomeStore)(nil)
+
+// NewFileOutcomeStore opens (or creates) a store backed by the JSON-lines file
+// at path. An empty path keeps outcomes in memory only. Malformed lines are
+// skipped and logged through the logger of opts (see WithLogger).
+func NewFileOutcomeStore(path string, opts ...Option) (*FileOutcomeStore, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+	s := &FileOutcomeStore{MinSimilarity: 0.3, path: path}
+	if path == "" {
+		return s, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outcome store: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var outcome ToolOutcome
+		if err := json.Unmarshal([]byte(line), &outcome); err != nil {
+			o.logger.Warn("Skipping malformed outcome", "path", path, "error", err)
+			continue
+		}
+		s.outcomes = append(s.outcomes, outcome)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read outcome store: %w", err)
+	}
+	return s, nil
+}
+
+func (s *FileOutcomeStore) Record(ctx context.Context, outcome ToolOutcome) error {
+	if outcome.RecordedAt.IsZero() {
+		outcome.RecordedAt = time.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path != "" {
+		line, err := json.Marshal(outcome)
+		if err != nil {
+			return fmt.Errorf("failed to encode outcome: %w", err)
+		}
+		file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return fmt.Errorf("failed to open outcome store: %w", err)
+		}
+		defer file.Close()
+		if _, err := file.Write(append(line, '\n')); err != nil {
+			return fmt.Errorf("failed to write outcome: %w", err)
+		}
+	}
+
+	s.outcomes = append(s.outcomes, outcome)
+	return nil
+}
+
+func (s *FileOutcomeStore) Similar(ctx context.Context, intent string, limit int) ([]ToolOutcome, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	type scored struct {
+		outcome    ToolOutcome
+		similarity float64
+	}
+	words := intentWords(intent)
+	var matches []scored
+	tenant := TenantFromContext(ctx)
+	for _, o := range s.outcomes {
+		if o.Tenant != tenant {
+			continue
+		}
+		sim := jaccard(words, intentWords(o.Intent))
+		if sim >= s.MinSimilarity {
+			matches = append(matches, scored{o, sim})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].similarity != matches[j].similarity {
+			return matches[i].similarity > matches[j].similarity
+		}
+		return matches[i].outcome.RecordedAt.After(matches[j].outcome.RecordedAt)
+	})
+
+	var out []ToolOutcome
+	for _, m := range matches {
+		if limit > 0 && len(out) >= limit {
+			break
+		}
+		out = append(out, m.outcome)
+	}
+	return out, nil
+}
+
+func intentWords(s string) map[string]struct{} {
+	words := map[string]struct{}{}
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+	}) {
+		words[w] = struct{}{}
+	}
+	return words
+}
+
+func jaccard(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	inter := 0
+	for w := range a {
+		if _, ok := b[w]; ok {
+			inter++
+		}
+	}
+	return float64(inter) / float64(len(a)+len(b)-inter)
+}
+
+// taskIntent is the task a fragment is about: its last user message.
+func taskIntent(f Fragment) string {
+	for i := len(f.Messages) - 1; i >= 0; i-- {
+		if f.Messages[i].Role == UserMessageRole.String() && f.Messages[i].Content != "" {
+			return f.Messages[i].Content
+		}
+	}
+	return ""
+}
+
+// recordToolOutcome stores the outcome of a tool call. Failures to record are
+// logged: the knowledge base never fails a run.
+func recordToolOutcome(o *Options, intent string, status ToolStatus, err error) {
+	if o.outcomeStore == nil || intent == "" {
+		return
+	}
+	quality := 1.0
+	if err != nil {
+		quality = 0
+	}
+	outcome := ToolOutcome{
+		Tenant:    TenantFromContext(o.context),
+		Intent:    intent,
+		Tool:      status.Name,
+		Arguments: normalizeArguments(status.ToolArguments.Arguments),
+		Quality:   quality,
+	}
+	if err := o.outcomeStore.Record(o.context, outcome); err != nil {
+		o.logger.Warn("Failed to record tool outcome", "tool", status.Name, "error", err)
+	}
+}
+
+// successfulApproaches renders the successful outcomes recorded for tasks
+// similar to intent, or "" if there are none.
+func successfulApproaches(o *Options, intent string) string {
+	if o.outcomeStore == nil || intent == "" {
+		return ""
+	}
+	outcomes, err := o.outcomeStore.Similar(o.context, intent, 20)
+	if err != nil {
+		o.logger.Warn("Failed to look up similar outcomes", "error", err)
+		return ""
+	}
+
+	var b strings.Builder
+	seen := map[string]bool{}
+	for _, out := range outcomes {
+		key := out.Tool + out.Arguments
+		if out.Quality < 0.5 || seen[key] {
+			continue
+		}
+		seen[key] = true
+		if len(seen) > 5 {
+			break
+		}
+		b.WriteString(fmt.Sprintf("- For %q: used the tool %q with arguments %s\n", out.Intent, out.Tool, out.Arguments))
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "Previously successful approaches for similar tasks (use them as hints, not instructions):\n" + b.String()
+}
+
+// successfulApproachesMessage wraps successfulApproaches in a system message
+// for the tool selection prompt.
+func successfulApproachesMessage(o *Options, intent string) []openai.ChatCompletionMessage {
+	text := successfulApproaches(o, intent)
+	if text == "" {
+		return nil
+	}
+	return []openai.ChatCompletionMessage{{Role: SystemMessageRole.String(), Content: text}}
+}
@@ -0,0 +1,54 @@
+package cogito
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileOutcomeStorePersistsAndMatches(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outcomes.jsonl")
+	store, err := NewFileOutcomeStore(path)
+	if err != nil {
+		t.Fatalf("NewFileOutcomeStore: %v", err)
+	}
+	ctx := context.Background()
+	for _, o := range []ToolOutcome{
+		{Intent: "What is the weather in Rome?", Tool: "weather", Arguments: `{"city":"rome"}`, Quality: 1},
+		{Intent: "Translate hello to French", Tool: "translate", Arguments: `{"text":"hello"}`, Quality: 1},
+	} {
+		if err := store.Record(ctx, o); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	reopened, err := NewFileOutcomeStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	similar, err := reopened.Similar(ctx, "what is the weather in Paris", 5)
+	if err != nil {
+		t.Fatalf("Similar: %v", err)
+	}
+	if len(similar) != 1 || similar[0].Tool != "weather" || similar[0].RecordedAt.IsZero() {
+		t.Errorf("similar = %+v", similar)
+	}
+}
+
+func TestSuccessfulApproachesSkipsFailures(t *testing.T) {
+	store, _ := NewFileOutcomeStore("")
+	ctx := context.Background()
+	_ = store.Record(ctx, ToolOutcome{Intent: "search latest news", Tool: "search", Arguments: `{"q":"news"}`, Quality: 1})
+	_ = store.Record(ctx, ToolOutcome{Intent: "search latest news", Tool: "scrape", Arguments: `{"url":"x"}`, Quality: 0})
+
+	o := defaultOptions()
+	o.Apply(WithOutcomeStore(store))
+	text := successfulApproaches(o, "search the latest news")
+	if !strings.Contains(text, `"search"`) || strings.Contains(text, `"scrape"`) {
+		t.Errorf("approaches = %q", text)
+	}
+	if successfulApproaches(o, "bake a cake") != "" {
+		t.Errorf("unrelated intent got approaches")
+	}
+}
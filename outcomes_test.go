@@ -0,0 +1,58 @@
+package cogito_test
+
+import (
+	"context"
+	"strings"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Outcome store", func() {
+	done := openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "It is sunny."}},
+		},
+	}
+
+	It("records tool outcomes and surfaces them on similar tasks", func() {
+		store, err := NewFileOutcomeStore("")
+		Expect(err).ToNot(HaveOccurred())
+
+		// First run: the weather tool succeeds and is recorded
+		first := cogitotest.NewMockLLM()
+		tool := cogitotest.NewMockTool("weather", "Get the weather")
+		cogitotest.SetRunResult(tool, "sunny")
+		first.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+		first.SetCreateChatCompletionResponse(done)
+
+		_, err = ExecuteTools(first, NewEmptyFragment().AddMessage(UserMessageRole, "What is the weather in Rome today?"),
+			WithTools(tool), WithIterations(2), WithOutcomeStore(store))
+		Expect(err).ToNot(HaveOccurred())
+
+		recorded, err := store.Similar(context.Background(), "What is the weather in Rome today?", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(recorded).To(HaveLen(1))
+		Expect(recorded[0].Tool).To(Equal("weather"))
+		Expect(recorded[0].Quality).To(Equal(1.0))
+
+		// Second run on a similar task sees the approach in the selection prompt
+		second := cogitotest.NewMockLLM()
+		second.SetCreateChatCompletionResponse(done)
+		_, err = ExecuteTools(second, NewEmptyFragment().AddMessage(UserMessageRole, "What is the weather in Milan today?"),
+			WithTools(tool), WithOutcomeStore(store))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(second.RequestHistory).ToNot(BeEmpty())
+		var hint string
+		for _, msg := range second.RequestHistory[0].Messages {
+			if strings.Contains(msg.Content, "Previously successful approaches") {
+				hint = msg.Content
+			}
+		}
+		Expect(hint).To(ContainSubstring(`"weather"`))
+	})
+})
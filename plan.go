@@ -4,11 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/mudler/cogito/prompt"
 	"github.com/mudler/cogito/structures"
-	"github.com/mudler/xlog"
 	"github.com/sashabaranov/go-openai"
 )
 
@@ -26,11 +27,13 @@ var (
 func ExtractPlan(llm LLM, f Fragment, goal *structures.Goal, opts ...Option) (*structures.Plan, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	// First we ask the LLM to organize subtasks
 	prompter := o.prompts.GetPrompt(prompt.PromptPlanType)
 
-	toolDefs := o.tools.Definitions()
+	visible, _ := visibleTools(o, o.localTools(), nil)
+	toolDefs := visible.Definitions()
 	planOptions := struct {
 		Context              string
 		AdditionalContext    string
@@ -38,13 +41,16 @@ func ExtractPlan(llm LLM, f Fragment, goal *structures.Goal, opts ...Option) (*s
 		Tools                []*openai.FunctionDefinition
 		FeedbackConversation string
 	}{
-		Context: f.String(),
+		Context: conversationContext(o, prompt.PromptPlanType, f),
 		Goal:    goal,
 		Tools:   toolDefs,
 	}
 	if o.deepContext && f.ParentFragment != nil {
 		planOptions.AdditionalContext = f.ParentFragment.AllFragmentsStrings()
 	}
+	if approaches := successfulApproaches(o, taskIntent(f)); approaches != "" {
+		planOptions.AdditionalContext = strings.TrimSpace(planOptions.AdditionalContext + "\n\n" + approaches)
+	}
 
 	var feedbackConv *Fragment
 	if o.feedbackCallback != nil {
@@ -65,11 +71,13 @@ func ExtractPlan(llm LLM, f Fragment, goal *structures.Goal, opts ...Option) (*s
 func ReEvaluatePlan(llm LLM, f, subtaskFragment Fragment, goal *structures.Goal, toolStatuses []ToolStatus, subtask string, opts ...Option) (*structures.Plan, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	// First we ask the LLM to organize subtasks
 	prompter := o.prompts.GetPrompt(prompt.PromptReEvaluatePlanType)
 
-	toolDefs := o.tools.Definitions()
+	visible, _ := visibleTools(o, o.localTools(), nil)
+	toolDefs := visible.Definitions()
 	planOptions := struct {
 		Context              string
 		AdditionalContext    string
@@ -80,7 +88,7 @@ func ReEvaluatePlan(llm LLM, f, subtaskFragment Fragment, goal *structures.Goal,
 		PastActionHistory    []ToolStatus
 		FeedbackConversation string
 	}{
-		Context:             f.String(),
+		Context:             conversationContext(o, prompt.PromptReEvaluatePlanType, f),
 		Goal:                goal.Goal,
 		Subtask:             subtask,
 		Tools:               toolDefs,
@@ -135,7 +143,7 @@ func applyPlanFromPrompt(llm LLM, o *Options, planPrompt string, feedbackConv *F
 
 	planConv = NewEmptyFragment().AddMessage("user", prompt)
 
-	err = planConv.ExtractStructure(o.context, llm, structure)
+	err = planConv.ExtractStructure(o.context, llm, structure, convertOptionsToFunctions(o)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract structure: %w", err)
 	}
@@ -148,6 +156,7 @@ func applyPlanFromPrompt(llm LLM, o *Options, planPrompt string, feedbackConv *F
 func ExtractTODOs(llm LLM, plan *structures.Plan, goal *structures.Goal, opts ...Option) (*structures.TODOList, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	prompter := o.prompts.GetPrompt(prompt.PromptTODOGenerationType)
 
@@ -176,7 +185,7 @@ func ExtractTODOs(llm LLM, plan *structures.Plan, goal *structures.Goal, opts ..
 
 	todoConv = NewEmptyFragment().AddMessage("user", identifiedTodo.Content)
 
-	err = todoConv.ExtractStructure(o.context, llm, structure)
+	err = todoConv.ExtractStructure(o.context, llm, structure, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract TODO structure: %w", err)
 	}
@@ -193,6 +202,29 @@ func ExtractTODOs(llm LLM, plan *structures.Plan, goal *structures.Goal, opts ..
 func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures.Goal, opts ...Option) (Fragment, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	if !o.validated {
+		if err := o.Validate(); err != nil {
+			return NewEmptyFragment(), err
+		}
+		opts = withResolvedTenant(o, append(slices.Clone(opts), optionsValidated))
+		o.validated = true
+		if err := startQuotaRun(o); err != nil {
+			return NewEmptyFragment(), err
+		}
+	}
+
+	opts, stopMCPServers, err := startMCPServers(o, opts)
+	if err != nil {
+		return NewEmptyFragment(), err
+	}
+	defer stopMCPServers()
+
+	// Subtasks share the tool result cache of the plan
+	if o.toolCacheEnabled && o.toolCache == nil {
+		o.toolCache = newToolResultCache(o.toolCacheTools)
+	}
 
 	if len(plan.Subtasks) == 0 {
 		return NewEmptyFragment(), fmt.Errorf("no subtasks found in plan")
@@ -212,14 +244,14 @@ func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures
 		// Load TODOs from file if persistence path is set
 		if o.todoPersistencePath != "" {
 			if err := loadTODOsFromFile(o.todoPersistencePath, o.todos); err != nil {
-				xlog.Debug("Failed to load TODOs from file, using provided/generated list", "error", err)
+				o.logger.Debug("Failed to load TODOs from file, using provided/generated list", "error", err)
 			}
 		}
 
 		return executePlanWithTODOs(llm, o.reviewerLLMs, conv, plan, goal, o)
 	}
 
-	xlog.Debug("Executing plan for conversation", "length", len(conv.Messages), "plan", plan.Description, "subtasks", plan.Subtasks)
+	o.logger.Debug("Executing plan for conversation", "length", len(conv.Messages), "plan", plan.Description, "subtasks", plan.Subtasks)
 
 	var toolStatuses []ToolStatus
 
@@ -237,7 +269,7 @@ func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures
 	for {
 		subtask := plan.Subtasks[index]
 
-		xlog.Debug("Executing subtask", "goal", goal.Goal, "subtask", subtask)
+		o.logger.Debug("Executing subtask", "goal", goal.Goal, "subtask", subtask)
 
 		prompter := o.prompts.GetPrompt(prompt.PromptPlanExecutionType)
 
@@ -255,13 +287,20 @@ func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures
 		}
 
 		subtaskConv := NewEmptyFragment().AddMessage("user", prompt)
+		// Carry lessons learned from previous failed attempts into the retry
+		subtaskConv.Status.Reflections = slices.Clone(conversation.Status.Reflections)
 
+		reportPlanProgress(o, PlanSubtaskStarted, plan, index, attempts, false)
 		subtaskConvResult, err := ExecuteTools(llm, subtaskConv, opts...)
-		if err != nil {
+		// A rolled back transaction fails the subtask, which is retried or re-planned
+		rolledBack := errors.Is(err, ErrTransactionFailed)
+		if err != nil && !rolledBack {
 			return *conversation, err
 		}
-		// remove last one as is the answer, not the tool calls
-		subtaskConvResult.Messages = subtaskConvResult.Messages[:len(subtaskConvResult.Messages)-1]
+		if !rolledBack {
+			// remove last one as is the answer, not the tool calls
+			subtaskConvResult.Messages = subtaskConvResult.Messages[:len(subtaskConvResult.Messages)-1]
+		}
 
 		conversation.Messages = append(conversation.Messages, subtaskConvResult.LastAssistantAndToolMessages()...)
 		conversation.Status.Iterations = conversation.Status.Iterations + 1
@@ -269,12 +308,16 @@ func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures
 		conversation.Status.ToolResults = append(conversation.Status.ToolResults, subtaskConvResult.Status.ToolResults...)
 		toolStatuses = append(toolStatuses, subtaskConvResult.Status.ToolResults...)
 
-		boolean, err := IsGoalAchieved(llm, subtaskConvResult, nil, opts...)
-		if err != nil {
-			return *conversation, err
+		boolean := &structures.Boolean{}
+		if !rolledBack {
+			boolean, err = IsGoalAchieved(llm, subtaskConvResult, nil, opts...)
+			if err != nil {
+				return *conversation, err
+			}
 		}
 
-		xlog.Debug("Subtask execution", "achieved", boolean.Boolean, "attempts", attempts, "maxAttempts", o.maxAttempts)
+		o.logger.Debug("Subtask execution", "achieved", boolean.Boolean, "attempts", attempts, "maxAttempts", o.maxAttempts)
+		reportPlanProgress(o, PlanSubtaskFinished, plan, index, attempts, boolean.Boolean)
 
 		toolStatuses := []ToolStatus{}
 		for i := range conversation.Status.ToolsCalled {
@@ -282,25 +325,30 @@ func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures
 		}
 
 		if !boolean.Boolean {
+			if o.reflection {
+				note := reflectOnFailure(llm, subtaskConvResult, fmt.Sprintf("The subtask %q was not achieved.", subtask), o)
+				recordReflection(conversation.Status, note)
+			}
 			if attempts >= o.maxAttempts {
 				if !o.planReEvaluator {
 					return *conversation, ErrGoalNotAchieved
 				}
-				xlog.Debug("All attempts failed, re-evaluating plan")
+				o.logger.Debug("All attempts failed, re-evaluating plan")
 				plan, err = ReEvaluatePlan(llm, *conversation, subtaskConv, goal, toolStatuses, subtask, opts...)
 				if err != nil {
 					return *conversation, err
 				}
+				reportPlanProgress(o, PlanReplanned, plan, 0, 1, false)
 
 				// Start again
 				index = 0
 				attempts = 1
 			} else {
-				xlog.Debug("Attempt failed to achieve goal, retrying")
+				o.logger.Debug("Attempt failed to achieve goal, retrying")
 				attempts++
 			}
 		} else {
-			xlog.Debug("Goal correctly achieved")
+			o.logger.Debug("Goal correctly achieved")
 			attempts = 1 // reset attempts
 			if len(plan.Subtasks)-1 > index {
 				index++
@@ -310,6 +358,7 @@ func ExecutePlan(llm LLM, conv Fragment, plan *structures.Plan, goal *structures
 		}
 	}
 
+	reportPlanProgress(o, PlanCompleted, plan, len(plan.Subtasks)-1, attempts, true)
 	return *conversation, nil
 }
 
@@ -319,7 +368,7 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 		return NewEmptyFragment(), fmt.Errorf("no subtasks found in plan")
 	}
 
-	xlog.Debug("Executing plan with TODOs", "plan", plan.Description, "subtasks", plan.Subtasks, "maxIterations", o.maxIterations)
+	o.logger.Debug("Executing plan with TODOs", "plan", plan.Description, "subtasks", plan.Subtasks, "maxIterations", o.maxIterations)
 
 	conversation := &conv
 	if conversation.Status == nil {
@@ -333,7 +382,7 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 	// Outer loop: TODO iterations
 	for todoIteration := 1; todoIteration <= o.maxIterations; todoIteration++ {
 		conversation.Status.TODOIteration = todoIteration
-		xlog.Debug("Starting TODO iteration", "iteration", todoIteration, "maxIterations", o.maxIterations)
+		o.logger.Debug("Starting TODO iteration", "iteration", todoIteration, "maxIterations", o.maxIterations)
 
 		// Inner loop: execute plan subtasks
 		index := 0
@@ -341,10 +390,11 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 		for index < len(plan.Subtasks) {
 
 			subtask := plan.Subtasks[index]
-			xlog.Debug("Executing subtask", "goal", goal.Goal, "subtask", subtask, "todoIteration", todoIteration)
+			o.logger.Debug("Executing subtask", "goal", goal.Goal, "subtask", subtask, "todoIteration", todoIteration)
 
 			// WORK PHASE
 			conversation.Status.TODOPhase = "work"
+			reportPlanProgress(o, PlanSubtaskStarted, plan, index, attempts, false)
 			workResult, err := executeWorkPhase(workerLLM, o.todos, goal, subtask, previousFeedback, o)
 			if err != nil {
 				return *conversation, fmt.Errorf("work phase failed: %w", err)
@@ -353,7 +403,7 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 			// Update TODOs from work result
 			o.todos, err = updateTODOsFromWork(workerLLM, workResult, o.todos, o)
 			if err != nil {
-				xlog.Debug("Failed to update TODOs from work", "error", err)
+				o.logger.Debug("Failed to update TODOs from work", "error", err)
 			}
 
 			// REVIEW PHASE
@@ -369,13 +419,13 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 			// Update TODOs from feedback
 			o.todos, err = updateTODOsFromFeedback(reviewResult, o.todos, o.todoPersistencePath)
 			if err != nil {
-				xlog.Debug("Failed to update TODOs from feedback", "error", err)
+				o.logger.Debug("Failed to update TODOs from feedback", "error", err)
 			}
 
 			// Save TODOs to file if persistence path is set
 			if o.todoPersistencePath != "" {
 				if err := saveTODOsToFile(o.todoPersistencePath, o.todos); err != nil {
-					xlog.Debug("Failed to save TODOs to file", "error", err)
+					o.logger.Debug("Failed to save TODOs to file", "error", err)
 				}
 			}
 
@@ -390,8 +440,9 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 			conversation.Status.ToolResults = append(conversation.Status.ToolResults, workResult.Status.ToolResults...)
 			toolStatuses = append(toolStatuses, workResult.Status.ToolResults...)
 
+			reportPlanProgress(o, PlanSubtaskFinished, plan, index, attempts, goalCompleted)
 			if goalCompleted {
-				xlog.Debug("Goal execution completed", "subtask", subtask)
+				o.logger.Debug("Goal execution completed", "subtask", subtask)
 				attempts = 1
 				if len(plan.Subtasks)-1 > index {
 					index++
@@ -401,6 +452,7 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 						Plan:  *plan,
 						Tools: toolStatuses,
 					})
+					reportPlanProgress(o, PlanCompleted, plan, index, attempts, true)
 					return *conversation, nil
 				}
 			} else {
@@ -409,7 +461,7 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 					if !o.planReEvaluator {
 						return *conversation, ErrGoalNotAchieved
 					}
-					xlog.Debug("All attempts failed, re-evaluating plan")
+					o.logger.Debug("All attempts failed, re-evaluating plan")
 					// Create a fresh conversation for re-evaluation (fresh context)
 					reEvalConv := NewEmptyFragment()
 					reEvalConv.Status = conversation.Status
@@ -417,11 +469,12 @@ func executePlanWithTODOs(workerLLM LLM, reviewerLLMs []LLM, conv Fragment, plan
 					if err != nil {
 						return *conversation, err
 					}
+					reportPlanProgress(o, PlanReplanned, plan, 0, 1, false)
 					// Start again with fresh context
 					index = 0
 					attempts = 1
 				} else {
-					xlog.Debug("Attempt failed to achieve goal, retrying with feedback", "attempts", attempts)
+					o.logger.Debug("Attempt failed to achieve goal, retrying with feedback", "attempts", attempts)
 					attempts++
 					// Continue with same subtask but with updated feedback
 				}
@@ -468,7 +521,8 @@ func executeWorkPhase(workerLLM LLM, todoList *structures.TODOList, goal *struct
 	// Convert Options struct to Option functions for ExecuteTools
 	opts := convertOptionsToFunctions(o)
 	workResult, err := ExecuteTools(workerLLM, workFragment, opts...)
-	if err != nil {
+	// A rolled back transaction is left to the review phase, which sees it failed
+	if err != nil && !errors.Is(err, ErrTransactionFailed) {
 		return NewEmptyFragment(), fmt.Errorf("failed to execute tools in work phase: %w", err)
 	}
 
@@ -482,7 +536,7 @@ func executeReviewPhase(reviewerLLMs []LLM, workFragment Fragment, goal *structu
 	todoMarkdown := todoList.ToMarkdown()
 
 	// Get work results as string
-	workResults := workFragment.String()
+	workResults := conversationContext(o, prompt.PromptTODOReviewType, workFragment)
 
 	reviewOptions := struct {
 		Goal         string
@@ -511,6 +565,7 @@ func executeReviewPhase(reviewerLLMs []LLM, workFragment Fragment, goal *structu
 	}{}
 
 	for _, reviewerLLM := range reviewerLLMs {
+		reviewerLLM = withLLMOptions(reviewerLLM, o)
 
 		boolean, err := IsGoalAchieved(reviewerLLM, reviewFragment, goal, opts...)
 		if err != nil {
@@ -569,7 +624,7 @@ func updateTODOsFromWork(workerLLM LLM, workFragment Fragment, todoList *structu
 		Context      string
 		TODOMarkdown string
 	}{
-		Context:      workFragment.String(),
+		Context:      conversationContext(o, prompt.PromptTODOTrackingType, workFragment),
 		TODOMarkdown: todoMarkdown,
 	}
 
@@ -583,10 +638,10 @@ func updateTODOsFromWork(workerLLM LLM, workFragment Fragment, todoList *structu
 
 	// We use the worker LLM here to extract the structure. Maybe we should use the reviewer LLM instead?
 	// TODO: Implement a better way to select the LLM to use for extraction?
-	err = trackingConv.ExtractStructure(o.context, workerLLM, structure)
+	err = trackingConv.ExtractStructure(o.context, workerLLM, structure, convertOptionsToFunctions(o)...)
 	if err != nil {
 		// If extraction fails, return original list
-		xlog.Debug("Failed to extract TODO updates from work", "error", err)
+		o.logger.Debug("Failed to extract TODO updates from work", "error", err)
 		return todoList, nil
 	}
 
@@ -683,8 +738,9 @@ func convertOptionsToFunctions(o *Options) []Option {
 	if len(o.guidelines) > 0 {
 		opts = append(opts, WithGuidelines(o.guidelines...))
 	}
-	if len(o.mcpSessions) > 0 {
-		opts = append(opts, WithMCPs(o.mcpSessions...))
+	opts = append(opts, mcpOptions(o)...)
+	if o.toolCollisionPolicy != PreferLocalTools {
+		opts = append(opts, WithToolCollisionPolicy(o.toolCollisionPolicy))
 	}
 	if o.maxAttempts > 0 {
 		opts = append(opts, WithMaxAttempts(o.maxAttempts))
@@ -707,22 +763,171 @@ func convertOptionsToFunctions(o *Options) []Option {
 	if o.forceReasoning {
 		opts = append(opts, WithForceReasoning())
 	}
+	if o.consolidatedReasoning {
+		opts = append(opts, WithConsolidatedReasoning())
+	}
+	if o.minToolConfidence > 0 {
+		opts = append(opts, WithMinToolConfidence(o.minToolConfidence))
+	}
+	if o.argumentClarification {
+		opts = append(opts, WithArgumentClarification())
+	}
+	if o.messageFilter != nil {
+		opts = append(opts, WithMessageFilter(o.messageFilter))
+	}
+	for _, w := range o.webhooks {
+		opts = append(opts, WithWebhook(w.url, w.events...))
+	}
+	if o.webhookClient != nil {
+		opts = append(opts, WithWebhookClient(o.webhookClient))
+	}
+	if o.contextBuilder != nil {
+		opts = append(opts, WithContextBuilder(o.contextBuilder))
+	}
+	for t, builder := range o.contextBuilders {
+		opts = append(opts, WithContextBuilder(builder, t))
+	}
+	if o.planProgressCallback != nil {
+		opts = append(opts, WithPlanProgressCallback(o.planProgressCallback))
+	}
+	if o.toolRegistry != nil {
+		opts = append(opts, WithToolRegistry(o.toolRegistry))
+	}
 	if o.maxRetries > 0 {
 		opts = append(opts, WithMaxRetries(o.maxRetries))
 	}
 	if o.loopDetectionSteps > 0 {
 		opts = append(opts, WithLoopDetection(o.loopDetectionSteps))
 	}
+	if o.loopDetector != nil {
+		opts = append(opts, WithLoopDetector(o.loopDetector))
+	}
 	if len(o.gaps) > 0 {
 		opts = append(opts, WithGaps(o.gaps...))
 	}
-	if o.prompts != nil {
-		for promptType, p := range o.prompts {
-			if staticPrompt, ok := p.(prompt.StaticPrompt); ok {
-				opts = append(opts, WithPrompt(promptType, staticPrompt))
-			}
+	if o.extractionConfig != nil {
+		opts = append(opts, WithExtractionConfig(*o.extractionConfig))
+	}
+	if o.reflection {
+		opts = append(opts, EnableReflection)
+	}
+	if o.contextShrinking {
+		opts = append(opts, EnableContextShrinking)
+	}
+	if o.deduplicateToolResults {
+		opts = append(opts, EnableToolResultDeduplication)
+	}
+	opts = append(opts, WithMaxToolFollowUps(o.maxToolFollowUps))
+	if len(o.toolResultTransformers) > 0 {
+		opts = append(opts, WithToolResultTransformer(o.toolResultTransformers...))
+	}
+	if o.promptSizeLimit > 0 {
+		opts = append(opts, WithPromptSizeLimit(o.promptSizeLimit))
+	}
+	if o.promptSizeCallback != nil {
+		opts = append(opts, WithPromptSizeCallback(o.promptSizeCallback))
+	}
+	if o.outcomeStore != nil {
+		opts = append(opts, WithOutcomeStore(o.outcomeStore))
+	}
+	if o.textToolCalls {
+		opts = append(opts, WithTextBasedToolCalls())
+	}
+	if !o.toolAPIFallback {
+		opts = append(opts, DisableToolAPIFallback)
+	}
+	opts = append(opts, WithMaxContinuations(o.maxContinuations))
+	if o.adaptiveMaxTokens > 0 {
+		opts = append(opts, WithAdaptiveMaxTokens(o.adaptiveMaxTokens))
+	}
+	if len(o.prompts) > 0 {
+		opts = append(opts, WithPrompts(o.prompts))
+	}
+	if len(o.promptVars) > 0 {
+		opts = append(opts, WithPromptVars(o.promptVars))
+	}
+	if o.locale != "" {
+		opts = append(opts, WithLocale(o.locale))
+	}
+	if o.localeFormatting {
+		opts = append(opts, EnableLocaleFormatting)
+	}
+	if o.factCheckAnnotate {
+		opts = append(opts, EnableFactCheckAnnotations)
+	} else if o.factCheck {
+		opts = append(opts, EnableFactCheck)
+	}
+	for tool, ttl := range o.toolResultTTLs {
+		if tool == "" {
+			opts = append(opts, WithToolResultTTL(ttl))
+		} else {
+			opts = append(opts, WithToolResultTTL(ttl, tool))
 		}
 	}
+	opts = append(opts, WithLogger(o.logger))
+	if o.redactor != nil {
+		opts = append(opts, WithRedactor(o.redactor))
+	}
+	if o.secrets != nil {
+		opts = append(opts, WithSecrets(o.secrets))
+	}
+	if o.toolCorrectionRounds > 0 {
+		opts = append(opts, WithToolCorrection(o.toolCorrectionRounds))
+	}
+	if o.rateLimiter != nil {
+		opts = append(opts, WithRateLimiter(o.rateLimiter))
+	}
+	if o.toolRateLimiter != nil {
+		opts = append(opts, WithToolRateLimiter(o.toolRateLimiter))
+	}
+	if o.completionCache != nil {
+		opts = append(opts, WithCompletionCache(o.completionCache))
+	}
+	if o.toolCacheEnabled {
+		cache := o.toolCache
+		opts = append(opts, WithToolResultCache(o.toolCacheTools...), func(o *Options) { o.toolCache = cache })
+	}
+	if o.reasoningSink != nil {
+		opts = append(opts, WithReasoningSink(o.reasoningSink))
+	}
+	if o.idempotency != nil {
+		opts = append(opts, WithIdempotencyStore(o.idempotency.store, o.idempotency.tools...))
+	}
+	if o.compensations != nil {
+		log := o.compensations
+		opts = append(opts, EnableCompensation, func(o *Options) { o.compensations = log })
+	}
+	for _, tools := range o.transactions {
+		opts = append(opts, WithToolTransaction(tools...))
+	}
+	if o.policy != nil {
+		opts = append(opts, WithPolicy(o.policy))
+	}
+	if o.identity != nil {
+		opts = append(opts, WithIdentity(*o.identity))
+	}
+	if o.quotas != nil {
+		opts = append(opts, WithQuota(o.quotas.store, o.quotas.quotas...))
+	}
+	if o.seed != nil {
+		opts = append(opts, WithSeed(*o.seed))
+	}
+	if o.reasoningLogLimit > 0 {
+		opts = append(opts, WithReasoningLogLimit(o.reasoningLogLimit))
+	}
+	if o.datasetRecorder != nil {
+		recorder := o.datasetRecorder
+		opts = append(opts, func(o *Options) { o.datasetRecorder = recorder })
+	}
+	if o.entityMemory != nil {
+		opts = append(opts, WithEntityMemory(o.entityMemory))
+	}
+	if o.vars != nil {
+		opts = append(opts, WithVarStore(o.vars))
+	}
+	if o.validated {
+		opts = append(opts, optionsValidated)
+	}
 
 	return opts
 }
@@ -0,0 +1,58 @@
+package cogito
+
+import "github.com/mudler/cogito/structures"
+
+// PlanProgressEvent is the kind of step reported by WithPlanProgressCallback.
+type PlanProgressEvent string
+
+const (
+	// PlanSubtaskStarted: an attempt at a subtask is starting.
+	PlanSubtaskStarted PlanProgressEvent = "subtask_started"
+	// PlanSubtaskFinished: an attempt at a subtask is over, Achieved holding
+	// the goal-achievement verdict.
+	PlanSubtaskFinished PlanProgressEvent = "subtask_finished"
+	// PlanReplanned: the subtasks failed too many times and the plan was
+	// re-evaluated; Plan is the new plan, restarting from its first subtask.
+	PlanReplanned PlanProgressEvent = "replanned"
+	// PlanCompleted: every subtask was achieved.
+	PlanCompleted PlanProgressEvent = "completed"
+)
+
+// PlanProgress reports a step of ExecutePlan, see WithPlanProgressCallback.
+type PlanProgress struct {
+	Event PlanProgressEvent
+	Plan  *structures.Plan
+	// SubtaskIndex is the position of Subtask in Plan.Subtasks (0-based).
+	SubtaskIndex  int
+	Subtask       string
+	TotalSubtasks int
+	// Attempt is the attempt number at the subtask, from 1 to MaxAttempts.
+	Attempt     int
+	MaxAttempts int
+	// Achieved is the goal-achievement verdict, for PlanSubtaskFinished.
+	Achieved bool
+}
+
+// reportPlanProgress sends a progress event for the subtask at index, if a
+// callback is set.
+func reportPlanProgress(o *Options, event PlanProgressEvent, plan *structures.Plan, index, attempt int, achieved bool) {
+	if o.planProgressCallback == nil && len(o.webhooks) == 0 {
+		return
+	}
+	p := PlanProgress{
+		Event:         event,
+		Plan:          plan,
+		SubtaskIndex:  index,
+		TotalSubtasks: len(plan.Subtasks),
+		Attempt:       attempt,
+		MaxAttempts:   o.maxAttempts,
+		Achieved:      achieved,
+	}
+	if index >= 0 && index < len(plan.Subtasks) {
+		p.Subtask = plan.Subtasks[index]
+	}
+	notifyPlanProgress(o, p)
+	if o.planProgressCallback != nil {
+		o.planProgressCallback(p)
+	}
+}
@@ -4,26 +4,26 @@ import (
 	"fmt"
 
 	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
 	"github.com/mudler/cogito/structures"
-	"github.com/mudler/cogito/tests/mock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
 )
 
 var _ = Describe("Plannings with tools", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 	var originalFragment Fragment
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 		originalFragment = NewEmptyFragment().
 			AddMessage("user", "What is photosynthesis?")
 	})
 
 	Context("ContentReview with tools", func() {
 		It("should execute tools when provided", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Mock goal extraction
 			mockLLM.SetAskResponse("The goal is to find most relevant informations about photosynthesis")
@@ -35,14 +35,14 @@ var _ = Describe("Plannings with tools", func() {
 
 			// Mock tool call (Subtask #1) - tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "chlorophyll"}`)
-			mock.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
+			cogitotest.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
 			mockLLM.SetAskResponse("The plan is to find information about chlorophyll")
 			mockLLM.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
 			mockLLM.SetAskResponse("Subtask is achieved")
 
 			// Mock tool call (Subtask #2) - tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "photosynthesis"}`)
-			mock.SetRunResult(mockTool, "Photosynthesis is the process by which plants convert sunlight into energy.")
+			cogitotest.SetRunResult(mockTool, "Photosynthesis is the process by which plants convert sunlight into energy.")
 			mockLLM.SetAskResponse("The plan is to find information about chlorophyll")
 			mockLLM.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
 			mockLLM.SetAskResponse("Subtask is achieved")
@@ -115,9 +115,70 @@ var _ = Describe("Plannings with tools", func() {
 		})
 	})
 
+	Context("WithPlanProgressCallback", func() {
+		It("reports subtask attempts, verdicts and completion", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			plan := &structures.Plan{Subtasks: []string{"Find chlorophyll", "Find photosynthesis"}}
+			goal := &structures.Goal{Goal: "Explain photosynthesis"}
+
+			// Subtask #1, first attempt: not achieved
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "chlorophyll"}`)
+			cogitotest.SetRunResult(mockTool, "no results")
+			mockLLM.SetAskResponse("Nothing found")
+			mockLLM.AddCreateChatCompletionFunction("json", `{"extract_boolean": false}`)
+			mockLLM.SetAskResponse("Subtask is not achieved")
+
+			// Subtask #1, second attempt: achieved
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "chlorophyll pigment"}`)
+			cogitotest.SetRunResult(mockTool, "Chlorophyll is a green pigment.")
+			mockLLM.SetAskResponse("Chlorophyll is a green pigment")
+			mockLLM.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
+			mockLLM.SetAskResponse("Subtask is achieved")
+
+			// Subtask #2: achieved
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "photosynthesis"}`)
+			cogitotest.SetRunResult(mockTool, "Photosynthesis converts sunlight into energy.")
+			mockLLM.SetAskResponse("Photosynthesis converts sunlight into energy")
+			mockLLM.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
+			mockLLM.SetAskResponse("Subtask is achieved")
+
+			var events []PlanProgress
+			_, err := ExecutePlan(mockLLM, originalFragment, plan, goal,
+				WithTools(mockTool),
+				WithMaxAttempts(2),
+				WithPlanProgressCallback(func(p PlanProgress) {
+					events = append(events, p)
+				}))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(events).To(HaveLen(7))
+			kinds := []PlanProgressEvent{}
+			for _, e := range events {
+				kinds = append(kinds, e.Event)
+				Expect(e.TotalSubtasks).To(Equal(2))
+				Expect(e.MaxAttempts).To(Equal(2))
+			}
+			Expect(kinds).To(Equal([]PlanProgressEvent{
+				PlanSubtaskStarted, PlanSubtaskFinished,
+				PlanSubtaskStarted, PlanSubtaskFinished,
+				PlanSubtaskStarted, PlanSubtaskFinished,
+				PlanCompleted,
+			}))
+
+			Expect(events[1].Subtask).To(Equal("Find chlorophyll"))
+			Expect(events[1].Attempt).To(Equal(1))
+			Expect(events[1].Achieved).To(BeFalse())
+			Expect(events[3].Attempt).To(Equal(2))
+			Expect(events[3].Achieved).To(BeTrue())
+			Expect(events[4].SubtaskIndex).To(Equal(1))
+			Expect(events[4].Subtask).To(Equal("Find photosynthesis"))
+			Expect(events[5].Achieved).To(BeTrue())
+		})
+	})
+
 	Context("TODO-based iterative execution", func() {
 		It("should extract TODOs from plan", func() {
-			mockLLM := mock.NewMockOpenAIClient()
+			mockLLM := cogitotest.NewMockLLM()
 
 			plan := &structures.Plan{
 				Description: "Test plan",
@@ -145,9 +206,9 @@ var _ = Describe("Plannings with tools", func() {
 		})
 
 		It("should execute plan with TODO mode when reviewer LLM is provided", func() {
-			mockWorkerLLM := mock.NewMockOpenAIClient()
-			mockReviewerLLM := mock.NewMockOpenAIClient()
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockWorkerLLM := cogitotest.NewMockLLM()
+			mockReviewerLLM := cogitotest.NewMockLLM()
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			plan := &structures.Plan{
 				Description: "Test plan",
@@ -168,7 +229,7 @@ var _ = Describe("Plannings with tools", func() {
 
 			// Mock work phase - tool selection
 			mockWorkerLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Test result")
+			cogitotest.SetRunResult(mockTool, "Test result")
 
 			// After tool execution, no more tools needed
 			mockWorkerLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
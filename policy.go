@@ -0,0 +1,186 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"slices"
+)
+
+// PolicyEffect is what a Policy decides for a tool call.
+type PolicyEffect int
+
+const (
+	// PolicyAllow executes the call, without asking for approval.
+	PolicyAllow PolicyEffect = iota
+	// PolicyDeny does not execute the call; the LLM is told it was denied.
+	PolicyDeny
+	// PolicyRequireApproval asks the approval callback (see WithToolCallBack)
+	// whether to execute the call. Without one, the call is denied.
+	PolicyRequireApproval
+)
+
+var policyEffects = []string{"allow", "deny", "require_approval"}
+
+func (e PolicyEffect) String() string {
+	if e < 0 || int(e) >= len(policyEffects) {
+		return fmt.Sprintf("PolicyEffect(%d)", int(e))
+	}
+	return policyEffects[e]
+}
+
+func (e PolicyEffect) MarshalText() ([]byte, error) {
+	return []byte(e.String()), nil
+}
+
+func (e *PolicyEffect) UnmarshalText(text []byte) error {
+	for i, name := range policyEffects {
+		if string(text) == name {
+			*e = PolicyEffect(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown policy effect %q", text)
+}
+
+// PolicyInput is what a Policy evaluates: a tool call and who it runs for.
+type PolicyInput struct {
+	Tool      string
+	Arguments map[string]any
+	Fragment  Fragment // conversation the call was selected in
+	Caller    string   // see ContextWithCaller and WithIdentity
+	Roles     []string // roles of the identity, see WithIdentity
+	RunID     string   // see ContextWithRunID
+	SessionID string   // see ContextWithSessionID
+}
+
+// PolicyDecision is the outcome of a Policy.
+type PolicyDecision struct {
+	Effect PolicyEffect
+	Reason string // told to the LLM when the call is denied
+}
+
+// Policy decides whether a tool call is executed, see WithPolicy. Implement
+// it to evaluate CEL or Rego expressions over the PolicyInput, or use
+// PolicyRules.
+type Policy interface {
+	Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+}
+
+// PolicyFunc adapts a function to a Policy.
+type PolicyFunc func(ctx context.Context, input PolicyInput) (PolicyDecision, error)
+
+func (f PolicyFunc) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	return f(ctx, input)
+}
+
+// PolicyRule matches tool calls and gives their effect. Empty fields match
+// anything; patterns use path.Match syntax, e.g. "delete_*".
+type PolicyRule struct {
+	Tools     []string          `json:"tools,omitempty" yaml:"tools,omitempty"`         // tool name patterns
+	Callers   []string          `json:"callers,omitempty" yaml:"callers,omitempty"`     // caller patterns
+	Roles     []string          `json:"roles,omitempty" yaml:"roles,omitempty"`         // roles, one of which the caller has
+	Arguments map[string]string `json:"arguments,omitempty" yaml:"arguments,omitempty"` // argument value patterns, by name
+	Effect    PolicyEffect      `json:"effect" yaml:"effect"`
+	Reason    string            `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+// PolicyRules is a declarative Policy: the first matching rule decides, and
+// calls matching no rule are allowed. End with a rule matching every tool to
+// deny by default. Rules can be loaded from JSON or YAML.
+type PolicyRules []PolicyRule
+
+var _ Policy = PolicyRules(nil)
+
+func (r PolicyRules) Evaluate(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+	for _, rule := range r {
+		if rule.matches(input) {
+			return PolicyDecision{Effect: rule.Effect, Reason: rule.Reason}, nil
+		}
+	}
+	return PolicyDecision{Effect: PolicyAllow}, nil
+}
+
+func (rule PolicyRule) matches(input PolicyInput) bool {
+	if len(rule.Tools) > 0 && !matchesAny(rule.Tools, input.Tool) {
+		return false
+	}
+	if len(rule.Callers) > 0 && !matchesAny(rule.Callers, input.Caller) {
+		return false
+	}
+	if len(rule.Roles) > 0 && !slices.ContainsFunc(rule.Roles, func(role string) bool { return slices.Contains(input.Roles, role) }) {
+		return false
+	}
+	for name, pattern := range rule.Arguments {
+		value, ok := input.Arguments[name]
+		if !ok || !matchesAny([]string{pattern}, fmt.Sprint(value)) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesAny(patterns []string, s string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, s); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// WithPolicy evaluates p before each tool call, to allow, deny or require
+// approval of the call. Denied calls are not executed and the LLM is told
+// why, so it can choose another way. Calls requiring approval go to the
+// callback of WithToolCallBack, which is only asked for those calls when a
+// policy is set. A policy that fails to evaluate denies the call.
+func WithPolicy(p Policy) func(o *Options) {
+	return func(o *Options) {
+		o.policy = p
+	}
+}
+
+// evaluatePolicy returns the decision of the policy of o for tc.
+func evaluatePolicy(o *Options, f Fragment, tc *ToolChoice) PolicyDecision {
+	input := PolicyInput{
+		Tool:      tc.Name,
+		Arguments: tc.Arguments,
+		Fragment:  f,
+		Caller:    CallerFromContext(o.context),
+		RunID:     RunIDFromContext(o.context),
+		SessionID: SessionIDFromContext(o.context),
+	}
+	if o.identity != nil {
+		if input.Caller == "" {
+			input.Caller = o.identity.Subject
+		}
+		input.Roles = o.identity.Roles
+	}
+	decision, err := o.policy.Evaluate(o.context, input)
+	if err != nil {
+		o.logger.Error("Failed to evaluate policy, denying tool call", "tool", tc.Name, "error", err)
+		return PolicyDecision{Effect: PolicyDeny, Reason: "the policy could not be evaluated"}
+	}
+	if decision.Effect != PolicyAllow {
+		o.logger.Info("Policy decision", "tool", tc.Name, "effect", decision.Effect, "reason", decision.Reason)
+	}
+	return decision
+}
+
+// toolCallPolicy evaluates the policy of o for tc, denying the calls that
+// require an approval when there is no tool call callback to give it.
+func toolCallPolicy(o *Options, f Fragment, tc *ToolChoice) PolicyDecision {
+	decision := evaluatePolicy(o, f, tc)
+	if decision.Effect == PolicyRequireApproval && o.toolCallCallback == nil {
+		decision = PolicyDecision{Effect: PolicyDeny, Reason: "the call requires an approval, which is not available"}
+	}
+	return decision
+}
+
+// policyDenialMessage is the tool result of a call denied by the policy.
+func policyDenialMessage(reason string) string {
+	if reason == "" {
+		return "Tool call denied by policy"
+	}
+	return "Tool call denied by policy: " + reason
+}
@@ -0,0 +1,116 @@
+package cogito_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Policy", func() {
+	var (
+		search, deleteUser ToolDefinitionInterface
+		llm                *cogitotest.MockLLM
+	)
+
+	BeforeEach(func() {
+		search = cogitotest.NewMockTool("search", "Search users")
+		cogitotest.SetRunResult(search, "found bob")
+		deleteUser = cogitotest.NewMockTool("delete_user", "Delete a user")
+		cogitotest.SetRunResult(deleteUser, "deleted bob")
+
+		llm = cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("search", `{"name": "bob"}`)
+		llm.AddCreateChatCompletionFunction("delete_user", `{"name": "bob"}`)
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Done."},
+		}}})
+		llm.SetAskResponse("Done.")
+	})
+
+	toolMessages := func(f Fragment) []string {
+		var contents []string
+		for _, m := range f.Messages {
+			if m.Role == ToolMessageRole.String() {
+				contents = append(contents, m.Content)
+			}
+		}
+		return contents
+	}
+
+	run := func(ctx context.Context, opts ...Option) Fragment {
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Remove bob")
+		opts = append(opts, WithTools(search, deleteUser), WithIterations(5), WithContext(ctx))
+		result, err := ExecuteTools(llm, f, opts...)
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	rules := PolicyRules{
+		{Tools: []string{"delete_*"}, Callers: []string{"admin-*"}, Effect: PolicyRequireApproval},
+		{Tools: []string{"delete_*"}, Effect: PolicyDeny, Reason: "only admins can delete"},
+	}
+
+	It("denies calls and tells the LLM why", func() {
+		result := run(context.Background(), WithPolicy(rules))
+		Expect(cogitotest.GetMockTool(deleteUser).Calls()).To(BeEmpty())
+		Expect(cogitotest.GetMockTool(search).Calls()).To(HaveLen(1))
+		Expect(toolMessages(result)).To(Equal([]string{"found bob", "Tool call denied by policy: only admins can delete"}))
+	})
+
+	It("asks for approval of the calls requiring it only", func() {
+		var asked []string
+		approve := WithToolCallBack(func(tc *ToolChoice, _ *SessionState) ToolCallDecision {
+			asked = append(asked, tc.Name)
+			return ToolCallDecision{Approved: true}
+		})
+		run(ContextWithCaller(context.Background(), "admin-alice"), WithPolicy(rules), approve)
+		Expect(asked).To(Equal([]string{"delete_user"}))
+		Expect(cogitotest.GetMockTool(deleteUser).Calls()).To(HaveLen(1))
+	})
+
+	It("denies calls requiring approval without an approval callback", func() {
+		result := run(ContextWithCaller(context.Background(), "admin-alice"), WithPolicy(rules))
+		Expect(cogitotest.GetMockTool(deleteUser).Calls()).To(BeEmpty())
+		Expect(toolMessages(result)[1]).To(ContainSubstring("requires an approval"))
+	})
+
+	It("denies calls when the policy fails", func() {
+		policy := PolicyFunc(func(ctx context.Context, input PolicyInput) (PolicyDecision, error) {
+			if input.Tool == "search" {
+				Expect(input.Arguments).To(Equal(map[string]any{"name": "bob"}))
+				return PolicyDecision{Effect: PolicyAllow}, nil
+			}
+			return PolicyDecision{}, errors.New("policy server unreachable")
+		})
+		run(context.Background(), WithPolicy(policy))
+		Expect(cogitotest.GetMockTool(deleteUser).Calls()).To(BeEmpty())
+	})
+
+	It("denies corrected calls to denied tools", func() {
+		cogitotest.SetRunError(search, errors.New("search is down"))
+		result := run(context.Background(), WithPolicy(rules), WithToolCorrection(1))
+		Expect(cogitotest.GetMockTool(deleteUser).Calls()).To(BeEmpty())
+		Expect(toolMessages(result)).To(ContainElement(ContainSubstring("Tool call denied by policy: only admins can delete")))
+	})
+
+	It("loads rules from JSON", func() {
+		var loaded PolicyRules
+		Expect(json.Unmarshal([]byte(`[{"tools": ["delete_*"], "arguments": {"name": "root"}, "effect": "deny"}]`), &loaded)).To(Succeed())
+		Expect(loaded[0].Effect).To(Equal(PolicyDeny))
+
+		decision, err := loaded.Evaluate(context.Background(), PolicyInput{Tool: "delete_user", Arguments: map[string]any{"name": "root"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision.Effect).To(Equal(PolicyDeny))
+		decision, err = loaded.Evaluate(context.Background(), PolicyInput{Tool: "delete_user", Arguments: map[string]any{"name": "bob"}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decision.Effect).To(Equal(PolicyAllow))
+
+		Expect(json.Unmarshal([]byte(`[{"effect": "maybe"}]`), &loaded)).ToNot(Succeed())
+	})
+})
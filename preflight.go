@@ -0,0 +1,263 @@
+package cogito
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mudler/cogito/structures"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// Checks run by Preflight.
+const (
+	PreflightOptions        = "options"         // the options are valid, see ValidateOptions
+	PreflightModel          = "model"           // the model replies to a chat completion
+	PreflightToolCalling    = "tool_calling"    // the model calls a tool with valid arguments
+	PreflightJSONExtraction = "json_extraction" // ExtractStructure fills a structure
+	PreflightMCP            = "mcp"             // an MCP server lists its tools
+	PreflightGuidelines     = "guidelines"      // a guideline only references registered tools
+)
+
+// PreflightStatus is the outcome of a preflight check.
+type PreflightStatus string
+
+const (
+	PreflightPassed  PreflightStatus = "passed"
+	PreflightWarning PreflightStatus = "warning" // works, degraded
+	PreflightFailed  PreflightStatus = "failed"
+	PreflightSkipped PreflightStatus = "skipped" // not run, a check it depends on failed
+)
+
+// PreflightCheck is the result of a check of Preflight.
+type PreflightCheck struct {
+	Name     string          `json:"name"`              // one of the Preflight* checks
+	Subject  string          `json:"subject,omitempty"` // MCP server or guideline checked
+	Status   PreflightStatus `json:"status"`
+	Detail   string          `json:"detail,omitempty"` // error, or what was found
+	Duration time.Duration   `json:"duration"`
+}
+
+// PreflightReport is the result of Preflight, one check per line when
+// printed.
+type PreflightReport struct {
+	Checks []PreflightCheck `json:"checks"`
+}
+
+// OK reports whether no check failed.
+func (r PreflightReport) OK() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the failed checks.
+func (r PreflightReport) Failed() []PreflightCheck {
+	var failed []PreflightCheck
+	for _, c := range r.Checks {
+		if c.Status == PreflightFailed {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+func (r PreflightReport) String() string {
+	var b strings.Builder
+	for _, c := range r.Checks {
+		name := c.Name
+		if c.Subject != "" {
+			name += " (" + c.Subject + ")"
+		}
+		fmt.Fprintf(&b, "%-8s %s", c.Status, name)
+		if c.Detail != "" {
+			fmt.Fprintf(&b, ": %s", c.Detail)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// preflightTool is the tool the model is asked to call by Preflight.
+var preflightTool = openai.Tool{
+	Type: openai.ToolTypeFunction,
+	Function: &openai.FunctionDefinition{
+		Name:        "echo",
+		Description: "Echo a text back",
+		Parameters: jsonschema.Definition{
+			Type:       jsonschema.Object,
+			Properties: map[string]jsonschema.Definition{"text": {Type: jsonschema.String}},
+			Required:   []string{"text"},
+		},
+	},
+}
+
+// Preflight checks an agent configuration before a production run: that
+// the options are valid, the model responds, supports tool calling and JSON
+// extraction, every MCP server (WithMCPs, WithMCPServers) lists its tools,
+// and the guidelines only reference registered tools. It makes a few small
+// LLM calls and returns a report rather than stopping at the first problem.
+// Backends rejecting the tools API are reported as a warning when tool calls
+// fall back to text (see DisableToolAPIFallback).
+func Preflight(llm LLM, opts ...Option) PreflightReport {
+	o := defaultOptions()
+	o.Apply(opts...)
+	base := llm
+	llm = withLLMOptions(llm, o)
+
+	var report PreflightReport
+	check := func(name, subject string, fn func() (PreflightStatus, string)) PreflightStatus {
+		start := time.Now()
+		status, detail := fn()
+		report.Checks = append(report.Checks, PreflightCheck{
+			Name: name, Subject: subject, Status: status, Detail: detail, Duration: time.Since(start),
+		})
+		return status
+	}
+	skip := func(name, detail string) {
+		report.Checks = append(report.Checks, PreflightCheck{Name: name, Status: PreflightSkipped, Detail: detail})
+	}
+
+	check(PreflightOptions, "", func() (PreflightStatus, string) {
+		if err := o.Validate(); err != nil {
+			return PreflightFailed, err.Error()
+		}
+		return PreflightPassed, ""
+	})
+
+	model := check(PreflightModel, "", func() (PreflightStatus, string) {
+		reply, _, err := llm.CreateChatCompletion(o.context, openai.ChatCompletionRequest{
+			Messages: []openai.ChatCompletionMessage{{Role: UserMessageRole.String(), Content: "Reply with the word OK."}},
+		})
+		if err != nil {
+			return PreflightFailed, err.Error()
+		}
+		if len(reply.ChatCompletionResponse.Choices) == 0 {
+			return PreflightFailed, "no choices in the reply"
+		}
+		return PreflightPassed, ""
+	})
+	if model == PreflightFailed {
+		skip(PreflightToolCalling, "the model does not respond")
+		skip(PreflightJSONExtraction, "the model does not respond")
+	} else {
+		check(PreflightToolCalling, "", func() (PreflightStatus, string) {
+			return preflightToolCalling(llm, o)
+		})
+		check(PreflightJSONExtraction, "", func() (PreflightStatus, string) {
+			var city struct {
+				City string `json:"city"`
+			}
+			s := structures.Structure{
+				Schema: jsonschema.Definition{
+					Type:       jsonschema.Object,
+					Properties: map[string]jsonschema.Definition{"city": {Type: jsonschema.String, Description: "The city mentioned"}},
+					Required:   []string{"city"},
+				},
+				Object: &city,
+			}
+			f := NewEmptyFragment().AddMessage(UserMessageRole, "I live in Rome.")
+			if err := f.ExtractStructure(o.context, base, s, opts...); err != nil {
+				return PreflightFailed, err.Error()
+			}
+			if city.City == "" {
+				return PreflightFailed, "the extracted structure is empty"
+			}
+			return PreflightPassed, ""
+		})
+	}
+
+	tools := slices.Clone(o.localTools())
+	for _, session := range o.mcpSessions {
+		check(PreflightMCP, mcpSource(session), func() (PreflightStatus, string) {
+			mcpTools, err := mcpToolsFromTransport(o.context, session, o.mcpToolFilter, o.logger)
+			if err != nil {
+				return PreflightFailed, err.Error()
+			}
+			if namespace := o.mcpNamespaces[session]; namespace != "" {
+				mcpTools = NamespaceTools(namespace, mcpTools...)
+			}
+			tools = append(tools, mcpTools...)
+			return PreflightPassed, fmt.Sprintf("%d tools", len(mcpTools))
+		})
+	}
+	for _, spec := range o.mcpServers {
+		check(PreflightMCP, spec.Name, func() (PreflightStatus, string) {
+			session, err := spec.Connect(o.context)
+			if err != nil {
+				return PreflightFailed, err.Error()
+			}
+			defer session.Close()
+			mcpTools, err := mcpToolsFromTransport(o.context, session, o.mcpToolFilter, o.logger)
+			if err != nil {
+				return PreflightFailed, err.Error()
+			}
+			if spec.Namespace != "" {
+				mcpTools = NamespaceTools(spec.Namespace, mcpTools...)
+			}
+			tools = append(tools, mcpTools...)
+			return PreflightPassed, fmt.Sprintf("%d tools", len(mcpTools))
+		})
+	}
+
+	for _, guideline := range o.guidelines {
+		if len(guideline.ToolNames) == 0 {
+			continue
+		}
+		check(PreflightGuidelines, guideline.Condition, func() (PreflightStatus, string) {
+			var unknown []string
+			for _, name := range guideline.ToolNames {
+				if tools.Find(name) == nil && guideline.Tools.Find(name) == nil {
+					unknown = append(unknown, name)
+				}
+			}
+			if len(unknown) > 0 {
+				return PreflightFailed, "unknown tools: " + strings.Join(unknown, ", ")
+			}
+			return PreflightPassed, ""
+		})
+	}
+
+	return report
+}
+
+// preflightToolCalling asks the model to call preflightTool, the way runs
+// with o would.
+func preflightToolCalling(llm LLM, o *Options) (PreflightStatus, string) {
+	req := openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: UserMessageRole.String(), Content: `Call the echo tool with the text "ping".`}},
+		Tools:    []openai.Tool{preflightTool},
+		ToolChoice: openai.ToolChoice{
+			Type:     openai.ToolTypeFunction,
+			Function: openai.ToolFunction{Name: preflightTool.Function.Name},
+		},
+	}
+
+	status, fallback := PreflightPassed, ""
+	if o.textToolCalls {
+		llm = newTextToolsLLM(llm, o.prompts, o.logger)
+	}
+	reply, _, err := llm.CreateChatCompletion(o.context, req)
+	if err != nil && !o.textToolCalls && o.toolAPIFallback && IsToolsUnsupportedError(err) {
+		status, fallback = PreflightWarning, "the backend rejects the tools API, tool calls fall back to text: "+err.Error()
+		reply, _, err = newTextToolsLLM(llm, o.prompts, o.logger).CreateChatCompletion(o.context, req)
+	}
+	if err != nil {
+		return PreflightFailed, err.Error()
+	}
+
+	choices := reply.ChatCompletionResponse.Choices
+	if len(choices) == 0 || len(choices[0].Message.ToolCalls) == 0 {
+		return PreflightFailed, "the model did not call the tool"
+	}
+	call := choices[0].Message.ToolCalls[0].Function
+	if call.Name != preflightTool.Function.Name {
+		return PreflightFailed, fmt.Sprintf("the model called the unknown tool %q", call.Name)
+	}
+	var args map[string]any
+	if err := json.Unmarshal([]byte(call.Arguments), &args); err != nil {
+		return PreflightFailed, fmt.Sprintf("invalid tool arguments %q: %v", call.Arguments, err)
+	}
+	return status, fallback
+}
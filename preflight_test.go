@@ -0,0 +1,62 @@
+package cogito_test
+
+import (
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Preflight", func() {
+	statuses := func(report PreflightReport) map[string]PreflightStatus {
+		s := map[string]PreflightStatus{}
+		for _, c := range report.Checks {
+			s[c.Name] = c.Status
+		}
+		return s
+	}
+
+	It("passes a working configuration", func() {
+		llm := cogitotest.NewMockLLM()
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "OK"},
+		}}})
+		llm.AddCreateChatCompletionFunction("echo", `{"text": "ping"}`)
+		llm.AddCreateChatCompletionFunction("json", `{"city": "Rome"}`)
+		search := cogitotest.NewMockTool("search", "Search the web")
+
+		report := Preflight(llm, WithTools(search), WithGuidelines(Guideline{
+			Condition: "The user asks about current events", Action: "Search the web", ToolNames: []string{"search"},
+		}))
+		Expect(report.OK()).To(BeTrue(), report.String())
+		Expect(statuses(report)).To(Equal(map[string]PreflightStatus{
+			PreflightOptions:        PreflightPassed,
+			PreflightModel:          PreflightPassed,
+			PreflightToolCalling:    PreflightPassed,
+			PreflightJSONExtraction: PreflightPassed,
+			PreflightGuidelines:     PreflightPassed,
+		}))
+	})
+
+	It("reports every problem of a broken configuration", func() {
+		llm := cogitotest.NewMockLLM()
+		llm.SetCreateChatCompletionError(errors.New("connection refused"))
+
+		report := Preflight(llm, WithMaxRetries(0), WithGuidelines(Guideline{
+			Condition: "The user asks about current events", Action: "Search the web", ToolNames: []string{"search"},
+		}))
+		Expect(report.OK()).To(BeFalse())
+		Expect(statuses(report)).To(Equal(map[string]PreflightStatus{
+			PreflightOptions:        PreflightFailed,
+			PreflightModel:          PreflightFailed,
+			PreflightToolCalling:    PreflightSkipped,
+			PreflightJSONExtraction: PreflightSkipped,
+			PreflightGuidelines:     PreflightFailed,
+		}))
+		Expect(report.Failed()).To(HaveLen(3))
+		Expect(report.String()).To(ContainSubstring("unknown tools: search"))
+	})
+})
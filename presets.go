@@ -0,0 +1,76 @@
+package cogito
+
+// Presets bundle the options suiting common kinds of agents. They are
+// options themselves: combine them with other options, which override the
+// individual settings when passed after the preset, e.g.
+//
+//	cogito.ExecuteTools(llm, f, cogito.PresetThoroughResearcher(), cogito.WithIterations(20))
+
+// PresetFastChat suits conversational assistants where latency matters
+// more than thoroughness:
+//   - a single tool selection round, without forced reasoning or the tool
+//     reasoner, and parallel tool calls;
+//   - 2 retries of failed LLM calls and 1 tool follow-up;
+//   - loop detection, stopping after 2 identical tool calls.
+func PresetFastChat() func(o *Options) {
+	return func(o *Options) {
+		o.maxIterations = 1
+		o.forceReasoning = false
+		o.forceReasoningTool = false
+		o.consolidatedReasoning = false
+		o.toolReasoner = false
+		o.autoPlan = false
+		o.parallelToolExecution = true
+		o.maxRetries = 2
+		o.maxToolFollowUps = 1
+		o.loopDetectionSteps = 2
+	}
+}
+
+// PresetThoroughResearcher suits agents gathering and cross-checking
+// information over many steps:
+//   - up to 10 tool selection rounds with forced reasoning;
+//   - automatic planning, re-evaluated as the run progresses;
+//   - reflection on failed steps and loop detection, stopping after 3
+//     identical tool calls;
+//   - deduplicated tool results, context shrinking and a fact check of the
+//     final answer against the tool results.
+func PresetThoroughResearcher() func(o *Options) {
+	return func(o *Options) {
+		o.maxIterations = 10
+		o.forceReasoning = true
+		o.sinkState = true
+		o.autoPlan = true
+		o.planReEvaluator = true
+		o.reflection = true
+		o.loopDetectionSteps = 3
+		o.deduplicateToolResults = true
+		o.contextShrinking = true
+		o.factCheck = true
+	}
+}
+
+// PresetCautiousOps suits agents acting on production systems, where a
+// wrong call is costly:
+//   - every tool call goes through approve (see WithToolCallBack), which
+//     can reject, skip or adjust it; with a nil approve the calls are not
+//     intercepted;
+//   - forced reasoning, one tool at a time and no retry of failed tools;
+//   - at most 5 tool selection rounds and 2 adjustment attempts;
+//   - reflection on failed steps and loop detection, stopping after 2
+//     identical tool calls.
+func PresetCautiousOps(approve func(*ToolChoice, *SessionState) ToolCallDecision) func(o *Options) {
+	return func(o *Options) {
+		if approve != nil {
+			o.toolCallCallback = approve
+		}
+		o.maxIterations = 5
+		o.forceReasoning = true
+		o.sinkState = true
+		o.parallelToolExecution = false
+		o.maxAttempts = 1
+		o.maxAdjustmentAttempts = 2
+		o.reflection = true
+		o.loopDetectionSteps = 2
+	}
+}
@@ -0,0 +1,36 @@
+package cogito
+
+import "testing"
+
+func TestPresetsCompose(t *testing.T) {
+	o := defaultOptions()
+	o.Apply(PresetThoroughResearcher(), WithIterations(20))
+	if o.maxIterations != 20 {
+		t.Errorf("maxIterations = %d, want the later option to override the preset", o.maxIterations)
+	}
+	if !o.forceReasoning || !o.autoPlan || !o.reflection || o.loopDetectionSteps != 3 {
+		t.Errorf("researcher preset not applied: %+v", o)
+	}
+
+	o = defaultOptions()
+	o.Apply(PresetThoroughResearcher(), PresetFastChat())
+	if o.forceReasoning || o.autoPlan || o.maxIterations != 1 {
+		t.Errorf("later preset did not override the earlier one")
+	}
+}
+
+func TestPresetCautiousOpsApproval(t *testing.T) {
+	o := defaultOptions()
+	o.Apply(EnableParallelToolExecution, PresetCautiousOps(func(*ToolChoice, *SessionState) ToolCallDecision {
+		return ToolCallDecision{Approved: false}
+	}))
+	if o.toolCallCallback == nil {
+		t.Fatal("approval callback not set")
+	}
+	if o.parallelToolExecution {
+		t.Error("parallel tool execution should be disabled")
+	}
+	if o.toolCallCallback(&ToolChoice{Name: "restart_service"}, nil).Approved {
+		t.Error("the callback passed to the preset should decide")
+	}
+}
@@ -0,0 +1,146 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+type progressKey struct{}
+
+// ToolProgress is an intermediate progress update of a running tool.
+type ToolProgress struct {
+	ToolName   string
+	ToolCallID string
+	Message    string
+	// Progress and Total quantify the progress when known, e.g. 40 of 100
+	// (Total is 0 when unknown). Both are 0 for plain messages.
+	Progress, Total float64
+}
+
+// ProgressReporter reports the progress of a running tool. ExecuteTools
+// passes one to every tool in its context, see Progress; the updates are
+// sent to the status callback and to the stream callback as
+// StreamEventToolProgress events.
+type ProgressReporter struct {
+	report func(ToolProgress)
+	call   ToolChoice
+}
+
+// Progress returns the progress reporter of the tool running with ctx. It
+// is never nil: outside of ExecuteTools, or for tools not receiving the run
+// context, the updates are discarded.
+//
+//	cogito.Progress(ctx).Report("downloaded 40%")
+func Progress(ctx context.Context) *ProgressReporter {
+	if r, ok := ctx.Value(progressKey{}).(*ProgressReporter); ok {
+		return r
+	}
+	return &ProgressReporter{}
+}
+
+// Report reports a progress message.
+func (r *ProgressReporter) Report(message string) {
+	r.ReportProgress(0, 0, message)
+}
+
+// ReportProgress reports a quantified progress, e.g. 40 of 100 (total is
+// 0 when unknown), with an optional message.
+func (r *ProgressReporter) ReportProgress(progress, total float64, message string) {
+	if r == nil || r.report == nil {
+		return
+	}
+	r.report(ToolProgress{
+		ToolName:   r.call.Name,
+		ToolCallID: r.call.ID,
+		Message:    message,
+		Progress:   progress,
+		Total:      total,
+	})
+}
+
+// contextWithProgress returns ctx carrying the progress reporter of call,
+// reporting to the callbacks of o.
+func contextWithProgress(ctx context.Context, o *Options, call ToolChoice) context.Context {
+	reporter := &ProgressReporter{call: call, report: func(p ToolProgress) {
+		o.statusCallback(p.String())
+		if o.streamCallback != nil {
+			o.streamCallback(StreamEvent{
+				Type:       StreamEventToolProgress,
+				ToolName:   p.ToolName,
+				ToolCallID: p.ToolCallID,
+				Content:    p.Message,
+				Progress:   p.Progress,
+				Total:      p.Total,
+			})
+		}
+	}}
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// String formats the update for status messages, e.g.
+// "download: 40/100 fetching pages".
+func (p ToolProgress) String() string {
+	s := p.ToolName + ":"
+	switch {
+	case p.Total > 0:
+		s += fmt.Sprintf(" %g/%g", p.Progress, p.Total)
+	case p.Progress > 0:
+		s += fmt.Sprintf(" %g", p.Progress)
+	}
+	if p.Message != "" {
+		s += " " + p.Message
+	}
+	return s
+}
+
+// MCP progress notifications are matched to the reporter of the tool call
+// that requested them by their progress token.
+var (
+	mcpProgressTokens  atomic.Int64
+	mcpProgressReports sync.Map // progress token -> *ProgressReporter
+)
+
+const mcpProgressGracePeriod = 5 * time.Second
+
+// trackMCPProgress asks the server for progress notifications on params
+// when ctx has a progress reporter. The returned function stops tracking.
+func trackMCPProgress(ctx context.Context, params *mcp.CallToolParams) func() {
+	reporter, ok := ctx.Value(progressKey{}).(*ProgressReporter)
+	if !ok {
+		return func() {}
+	}
+	token := fmt.Sprintf("cogito-%d", mcpProgressTokens.Add(1))
+	if params.Meta == nil {
+		// SetProgressToken loses the token without a Meta map
+		params.Meta = mcp.Meta{}
+	}
+	params.SetProgressToken(token)
+	mcpProgressReports.Store(token, reporter)
+	return func() {
+		// The session handles notifications apart from responses: the last
+		// ones can arrive after the result
+		time.AfterFunc(mcpProgressGracePeriod, func() { mcpProgressReports.Delete(token) })
+	}
+}
+
+// MCPProgressNotificationHandler maps the progress notifications of MCP
+// tools onto the ProgressReporter of their call. The MCP servers of
+// WithMCPServers use it; set it as ProgressNotificationHandler in the
+// mcp.ClientOptions of the sessions passed to WithMCPs:
+//
+//	client := mcp.NewClient(impl, &mcp.ClientOptions{
+//		ProgressNotificationHandler: cogito.MCPProgressNotificationHandler,
+//	})
+func MCPProgressNotificationHandler(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+	if req.Params == nil {
+		return
+	}
+	if reporter, ok := mcpProgressReports.Load(req.Params.ProgressToken); ok {
+		reporter.(*ProgressReporter).ReportProgress(req.Params.Progress, req.Params.Total, req.Params.Message)
+	}
+}
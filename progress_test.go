@@ -0,0 +1,105 @@
+package cogito_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type downloadArgs struct {
+	URL string `json:"url"`
+}
+
+type downloadRunner struct{}
+
+func (downloadRunner) Run(args downloadArgs) (string, any, error) {
+	return downloadRunner{}.RunWithContext(context.Background(), args)
+}
+
+func (downloadRunner) RunWithContext(ctx context.Context, args downloadArgs) (string, any, error) {
+	Progress(ctx).Report("connecting to " + args.URL)
+	Progress(ctx).ReportProgress(40, 100, "downloading")
+	return "downloaded " + args.URL, nil, nil
+}
+
+var _ = Describe("Tool progress", func() {
+	var mockLLM *cogitotest.MockLLM
+	var events *cogitotest.EventRecorder
+	var statuses []string
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		events = cogitotest.NewEventRecorder()
+		statuses = nil
+	})
+
+	progressEvents := func() []StreamEvent {
+		return events.OfType(StreamEventToolProgress)
+	}
+
+	It("reports the progress of local tools to the callbacks", func() {
+		tool := NewToolDefinition(downloadRunner{}, downloadArgs{}, "download", "Download a file")
+		mockLLM.AddCreateChatCompletionFunction("download", `{"url": "http://example.com/a.zip"}`)
+		mockLLM.SetAskResponse("Downloaded.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Download the archive")
+		_, err := ExecuteTools(mockLLM, f, WithTools(tool), WithIterations(1),
+			WithStreamCallback(events.Record),
+			WithStatusCallback(func(s string) { statuses = append(statuses, s) }))
+		Expect(err).ToNot(HaveOccurred())
+
+		progress := progressEvents()
+		Expect(progress).To(HaveLen(2))
+		Expect(progress[0].ToolName).To(Equal("download"))
+		Expect(progress[0].Content).To(Equal("connecting to http://example.com/a.zip"))
+		Expect(progress[1].Progress).To(Equal(40.0))
+		Expect(progress[1].Total).To(Equal(100.0))
+		Expect(statuses).To(ContainElement("download: 40/100 downloading"))
+	})
+
+	It("discards the progress of tools run outside ExecuteTools", func() {
+		Expect(func() { Progress(context.Background()).Report("ignored") }).ToNot(Panic())
+	})
+
+	It("maps MCP progress notifications onto the reporter of the call", func() {
+		server := mcp.NewServer(&mcp.Implementation{Name: "slow", Version: "v1.0.0"}, nil)
+		mcp.AddTool(server, &mcp.Tool{Name: "index", Description: "Index the repository"},
+			func(ctx context.Context, req *mcp.CallToolRequest, _ struct{}) (*mcp.CallToolResult, any, error) {
+				if token := req.Params.GetProgressToken(); token != nil {
+					for _, done := range []float64{1, 2} {
+						err := req.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+							ProgressToken: token, Progress: done, Total: 2, Message: "indexing",
+						})
+						if err != nil {
+							return nil, nil, err
+						}
+					}
+				}
+				return &mcp.CallToolResult{Content: []mcp.Content{&mcp.TextContent{Text: "indexed"}}}, nil, nil
+			})
+		httpServer := httptest.NewServer(mcp.NewStreamableHTTPHandler(func(*http.Request) *mcp.Server { return server }, nil))
+		defer httpServer.Close()
+
+		mockLLM.AddCreateChatCompletionFunction("index", `{}`)
+		mockLLM.SetAskResponse("Indexed.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Index the repository")
+		_, err := ExecuteTools(mockLLM, f, WithIterations(1), WithStreamCallback(events.Record),
+			WithMCPServers(MCPServerSpec{Name: "slow", URL: httpServer.URL}))
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(progressEvents).Should(HaveLen(2))
+		Expect(progressEvents()[1]).To(And(
+			HaveField("ToolName", "index"),
+			HaveField("Progress", 2.0),
+			HaveField("Total", 2.0),
+			HaveField("Content", "indexing"),
+		))
+	})
+})
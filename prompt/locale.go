@@ -0,0 +1,87 @@
+package prompt
+
+import (
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	localesMu sync.RWMutex
+	locales   = map[string]PromptMap{}
+)
+
+// NormalizeLocale lowercases a locale tag and uses "-" as separator, so
+// "de_DE" and "de-de" are the same locale.
+func NormalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// RegisterLocale registers a translated prompt pack for locale (e.g. "de" or
+// "pt-BR"). The pack may translate only some prompts: the others fall back
+// to the English defaults. Registering a locale again merges the packs.
+func RegisterLocale(locale string, pack PromptMap) {
+	localesMu.Lock()
+	defer localesMu.Unlock()
+	locale = NormalizeLocale(locale)
+	locales[locale] = locales[locale].Merge(pack)
+}
+
+// LocalePrompts returns the prompt pack registered for locale. A regional
+// locale without its own pack ("de-AT") falls back to its language ("de").
+func LocalePrompts(locale string) (PromptMap, bool) {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+	locale = NormalizeLocale(locale)
+	if pack, ok := locales[locale]; ok {
+		return pack, true
+	}
+	if lang, _, found := strings.Cut(locale, "-"); found {
+		if pack, ok := locales[lang]; ok {
+			return pack, true
+		}
+	}
+	return nil, false
+}
+
+// Locales returns the locales with a registered prompt pack, sorted.
+func Locales() []string {
+	localesMu.RLock()
+	defer localesMu.RUnlock()
+	var out []string
+	for l := range locales {
+		out = append(out, l)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// LoadLocales registers the prompt packs found in fsys, one subdirectory per
+// locale laid out as for LoadDir (e.g. "de/plan.tmpl", "fr/plan.tmpl"). It
+// returns the locales loaded.
+func LoadLocales(fsys fs.FS) ([]string, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locale directory: %w", err)
+	}
+
+	var loaded []string
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		sub, err := fs.Sub(fsys, entry.Name())
+		if err != nil {
+			return loaded, fmt.Errorf("failed to open locale %s: %w", entry.Name(), err)
+		}
+		pack, err := LoadFS(sub)
+		if err != nil {
+			return loaded, fmt.Errorf("failed to load locale %s: %w", entry.Name(), err)
+		}
+		RegisterLocale(entry.Name(), pack)
+		loaded = append(loaded, NormalizeLocale(entry.Name()))
+	}
+	return loaded, nil
+}
@@ -0,0 +1,49 @@
+package prompt
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLocalePrompts(t *testing.T) {
+	RegisterLocale("xx", PromptMap{PromptPlanType: NewPrompt("plan xx")})
+	RegisterLocale("XX", PromptMap{PromptReflectionType: NewPrompt("reflection xx")})
+	RegisterLocale("xx_YY", PromptMap{PromptPlanType: NewPrompt("plan xx-yy")})
+
+	pack, ok := LocalePrompts("xx")
+	if !ok || len(pack) != 2 {
+		t.Fatalf("xx pack = %v, %v", pack, ok)
+	}
+	if pack, _ := LocalePrompts("XX-yy"); pack[PromptPlanType].(StaticPrompt).Template() != "plan xx-yy" {
+		t.Errorf("regional pack = %v", pack)
+	}
+	if pack, ok := LocalePrompts("xx-ZZ"); !ok || pack[PromptPlanType].(StaticPrompt).Template() != "plan xx" {
+		t.Errorf("language fallback = %v, %v", pack, ok)
+	}
+	if _, ok := LocalePrompts("zz"); ok {
+		t.Error("unregistered locale found")
+	}
+	if !slices.Contains(Locales(), "xx-yy") {
+		t.Errorf("locales = %v", Locales())
+	}
+}
+
+func TestLoadLocales(t *testing.T) {
+	loaded, err := LoadLocales(fstest.MapFS{
+		"qq/plan.tmpl":       {Data: []byte("Plane die Aufgabe")},
+		"qq/reflection.tmpl": {Data: []byte("Reflektiere")},
+		"README.md":          {Data: []byte("not a locale")},
+	})
+	if err != nil || !slices.Equal(loaded, []string{"qq"}) {
+		t.Fatalf("loaded = %v, %v", loaded, err)
+	}
+	pack, _ := LocalePrompts("qq")
+	if out, _ := pack.GetPrompt(PromptPlanType).Render(nil); out != "Plane die Aufgabe" {
+		t.Errorf("plan = %q", out)
+	}
+
+	if _, err := LoadLocales(fstest.MapFS{"qr/unknown.tmpl": {Data: []byte("x")}}); err == nil {
+		t.Error("expected an error for an unknown prompt")
+	}
+}
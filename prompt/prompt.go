@@ -23,6 +23,28 @@ const (
 	PromptConversationCompactionType  PromptType = iota
 	PromptAutoImproveReviewSystemType PromptType = iota
 	PromptAutoImproveReviewUserType   PromptType = iota
+	PromptReflectionType              PromptType = iota
+	PromptToolFollowUpType            PromptType = iota
+	PromptToolResultSummaryType       PromptType = iota
+	PromptTextToolCallsType           PromptType = iota
+	PromptResearchSummaryType         PromptType = iota
+	PromptConsolidatedReasoningType   PromptType = iota
+	PromptToolClarificationType       PromptType = iota
+	PromptArgumentClarificationType   PromptType = iota
+	PromptBranchJudgeType             PromptType = iota
+	PromptBestOfJudgeType             PromptType = iota
+	PromptReviewRubricType            PromptType = iota
+	PromptFactCheckType               PromptType = iota
+	PromptUnsupportedClaimsType       PromptType = iota
+	PromptToolCorrectionType          PromptType = iota
+	PromptUnavailableToolsType        PromptType = iota
+	PromptRerankType                  PromptType = iota
+	PromptEntityHintsType             PromptType = iota
+	PromptVarsType                    PromptType = iota
+	PromptToolArgumentsRepairType     PromptType = iota
+	PromptTruncationContinuationType  PromptType = iota
+	PromptToolDescriptionsType        PromptType = iota
+	PromptReflectionsType             PromptType = iota
 )
 
 var (
@@ -47,6 +69,28 @@ var (
 		PromptConversationCompactionType:  PromptConversationCompaction,
 		PromptAutoImproveReviewSystemType: PromptAutoImproveReviewSystem,
 		PromptAutoImproveReviewUserType:   PromptAutoImproveReviewUser,
+		PromptReflectionType:              PromptReflection,
+		PromptToolFollowUpType:            PromptToolFollowUp,
+		PromptToolResultSummaryType:       PromptToolResultSummary,
+		PromptTextToolCallsType:           PromptTextToolCalls,
+		PromptResearchSummaryType:         PromptResearchSummary,
+		PromptConsolidatedReasoningType:   PromptConsolidatedReasoning,
+		PromptToolClarificationType:       PromptToolClarification,
+		PromptArgumentClarificationType:   PromptArgumentClarification,
+		PromptBranchJudgeType:             PromptBranchJudge,
+		PromptBestOfJudgeType:             PromptBestOfJudge,
+		PromptReviewRubricType:            PromptReviewRubric,
+		PromptFactCheckType:               PromptFactCheck,
+		PromptUnsupportedClaimsType:       PromptUnsupportedClaims,
+		PromptToolCorrectionType:          PromptToolCorrection,
+		PromptUnavailableToolsType:        PromptUnavailableTools,
+		PromptRerankType:                  PromptRerank,
+		PromptEntityHintsType:             PromptEntityHints,
+		PromptVarsType:                    PromptVars,
+		PromptToolArgumentsRepairType:     PromptToolArgumentsRepair,
+		PromptTruncationContinuationType:  PromptTruncationContinuation,
+		PromptToolDescriptionsType:        PromptToolDescriptions,
+		PromptReflectionsType:             PromptReflections,
 	}
 
 	PromptGuidelinesExtraction = NewPrompt("What guidelines should be applied? return only the numbers of the guidelines by using the json tool with a list of integers corresponding to the guidelines.")
@@ -149,7 +193,7 @@ Available tools:
 Tools already called:
 {{ range $index, $tool := .PastActionHistory }}
 - Tool name: "{{$tool.Name}}" 
-  Tool result: {{$tool.Result}}
+  Tool result: {{$tool.Summary}}
   Tool arguments: {{$tool.ToolArguments | toJson}}
 {{ end }}
 
@@ -380,4 +424,216 @@ This is review #{{.ReviewNumber}}.
 ## Tool Execution Results
 {{.ToolResults}}
 {{end}}`)
+
+	PromptReflection = NewPrompt(`You are an AI assistant reflecting on a failed step of a task.
+
+Conversation:
+{{.Context}}
+
+What went wrong:
+{{.Failure}}
+
+In two or three sentences, explain why this step failed and what should be tried differently next time.
+Be concrete (e.g. different tool, different arguments, missing information) and do not repeat the conversation.`)
+
+	PromptReflections = NewPrompt(`Lessons learned from previous failed attempts (take them into account):
+{{- range $index, $reflection := .Reflections }}
+{{add1 $index}}. {{$reflection}}
+{{- end }}`)
+
+	PromptToolFollowUp = NewPrompt(`You are an AI assistant using the tool "{{.Tool}}" (called with arguments {{.Arguments}}).
+The tool needs more information before it can complete.
+
+Conversation:
+{{.Context}}
+{{ if .Previous }}
+Previous questions from the tool:
+{{- range .Previous }}
+Q: {{.Question}}
+A: {{.Answer}}
+{{- end }}
+{{ end }}
+Question from the tool:
+{{.Question}}
+
+Answer the question directly and concisely, using only information from the conversation. Reply with the answer only.`)
+
+	PromptToolResultSummary = NewPrompt(`You are an AI assistant condensing the output of a tool so it fits in a conversation.
+
+Tool: {{.Tool}}
+Arguments: {{.Arguments}}
+
+Output:
+{{.Result}}
+
+Summarize the output, keeping every fact, number, name, identifier and URL that could be needed to answer questions about it. Reply with the summary only.`)
+
+	PromptTextToolCalls = NewPrompt(`{{ if .Forced -}}
+You must use the tool "{{.Forced}}".{{ range .Tools }}{{ if .Description }} {{.Description}}{{ end }}
+Its arguments follow this JSON schema:
+{{.Parameters}}{{ end }}
+
+Reply only with a JSON object holding the arguments, without any other text.
+{{- else -}}
+You can use the following tools:
+{{ range .Tools }}
+- {{.Name}}: {{.Description}}
+  Arguments (JSON schema): {{.Parameters}}
+{{ end }}
+To use a tool, reply only with a JSON object like {"name": "<tool name>", "arguments": {<arguments>}}.
+To use several tools at once, reply with a JSON array of such objects.
+If no tool is needed, reply normally in plain text.
+{{- end }}`)
+
+	PromptResearchSummary = NewPrompt(`You are an AI assistant writing a research summary about: {{.Topic}}
+
+Sources:
+{{ range .Sources }}
+[{{.Index}}] {{.URL}}
+{{.Content}}
+{{ end }}
+Write a concise, factual summary about the topic using only the sources above.
+Cite the sources supporting each statement with their number in square brackets, e.g. [1] or [2][3].
+If the sources do not cover the topic, say so. Reply with the summary only.`)
+
+	PromptConsolidatedReasoning = NewPrompt(`Analyze the current situation and decide the next step. Answer in a single call to the "{{.ToolName}}" tool with:
+1. reasoning: your detailed reasoning about the task and which tool is the most appropriate, and why
+2. calls: the tool{{ if .Parallel }}s{{ end }} to use, each with complete arguments matching its parameters
+{{- if .Parallel }}
+You can select several tools if they can be executed in parallel.
+{{- end }}
+{{- if .SinkState }}
+Choose "{{.SinkState}}" if no tool is needed.
+{{- end }}
+
+Available tools:
+{{ range .Tools }}
+- {{.Name}}: {{.Description}}
+  Parameters: {{.Parameters}}
+{{- end }}`)
+
+	PromptToolClarification = NewPrompt(`You considered using {{ join ", " .Tools }} to answer the conversation{{ if .Reasoning }}, because: {{.Reasoning}}{{ end }}
+However, you are not confident enough in this choice (confidence {{ printf "%.2f" .Confidence }}) to act on it.
+Ask the user a short, specific question to clarify what they want, so that the right action can be taken. Reply with the question only.`)
+
+	PromptArgumentClarification = NewPrompt(`The tool "{{.Tool}}" is about to be called with the following arguments:
+{{.Arguments}}
+
+Its required parameters are: {{ join ", " .Required }}.
+For each required parameter, check whether its value is stated in the conversation or can be confidently derived from it. Report the parameters whose values were guessed or made up, and write a short question asking the user for them.
+Do not report parameters that have sensible values grounded in the conversation.`)
+
+	PromptBranchJudge = NewPrompt(`The same conversation was continued in {{ len .Branches }} alternative ways. Here is how each branch ended:
+{{ range .Branches }}
+Branch "{{.Name}}":
+{{- if .Error }}
+It failed with error: {{.Error}}
+{{- else }}
+Tools called: {{ if .Tools }}{{ join ", " .Tools }}{{ else }}none{{ end }}
+Outcome: {{.Outcome}}
+{{- end }}
+{{ end }}
+Compare the branches and pick the one that best accomplishes what the user asked for. Prefer correct and complete outcomes; failed branches should only be picked if every branch failed.`)
+
+	PromptBestOfJudge = NewPrompt(`Here are {{ len .Candidates }} candidate answers to the last message of the conversation:
+{{ range $i, $c := .Candidates }}
+Candidate {{ add1 $i }}:
+{{ $c }}
+{{ end }}
+Score every candidate from 0 to 10 according to the following criteria:
+{{ if .Criteria }}{{ .Criteria }}{{ else }}Accuracy, completeness and clarity of the answer with respect to what the user asked for.{{ end }}`)
+
+	PromptReviewRubric = NewPrompt(`Evaluate the following answer to the conversation:
+{{.Content}}
+
+Score it from 0 to 10 on each of these criteria:
+{{- range .Criteria }}
+- {{.Name}}: {{.Description}}
+{{- end }}
+Be strict: reserve high scores for answers that fully satisfy the criterion.`)
+
+	PromptFactCheck = NewPrompt(`Fact-check the following answer against the evidence gathered with tools.
+
+Answer:
+{{.Answer}}
+
+Evidence:
+{{- range .Evidence }}
+- {{.Name}}({{.Arguments}}): {{.Result}}
+{{- end }}
+
+List every factual claim of the answer that the evidence does not support, including claims the evidence contradicts, with a correction when the evidence provides one. General knowledge, opinions and claims backed by the evidence must not be listed.
+If there are unsupported claims, also write the answer again, correcting or removing them and leaving everything else unchanged.`)
+
+	PromptUnsupportedClaims = NewPrompt(`{{.Answer}}
+
+Note: the following statements could not be verified against the gathered information:
+{{- range .Claims }}
+- {{.Claim}}{{ if .Correction }} ({{.Correction}}){{ end }}
+{{- end }}`)
+
+	PromptToolCorrection = NewPrompt(`The tool "{{.Tool}}" was called with the following arguments:
+{{.Arguments}}
+
+It failed with this error:
+{{.Error}}
+
+Call the tool again with arguments that fix the error. If the error shows the tool cannot accomplish the task, call a different tool instead.`)
+
+	PromptUnavailableTools = NewPrompt(`The following tools are currently unavailable and must not be called:
+{{- range .Tools }}
+- {{.Name}}: {{.Error}}
+{{- end }}`)
+
+	PromptRerank = NewPrompt(`Score how relevant each document is to the query, from 0 (unrelated) to 1 (answers the query).
+
+Query: {{.Query}}
+
+Documents:
+{{- range $index, $document := .Documents }}
+
+[{{add1 $index}}]
+{{$document}}
+{{- end }}`)
+
+	PromptEntityHints = NewPrompt(`Values already established in this conversation, most recent first:
+{{- range .Entities }}
+- {{.Kind}}: {{.Value}}{{ if ne .Source "user" }} (used with {{.Source}}){{ end }}
+{{- end }}
+
+When a tool argument refers to one of these values, reuse it instead of asking for it again or making up a different one.`)
+
+	PromptVars = NewPrompt(`The following variables are available:
+{{- range .Vars }}
+- {{.Name}}: {{.Value}}
+{{- end }}
+
+To pass the value of a variable in a tool argument, write {{"{{"}}.vars.<name>{{"}}"}} (e.g. {{"{{"}}.vars.{{(index .Vars 0).Name}}{{"}}"}}) instead of copying it: it is replaced with the exact value when the tool runs.`)
+
+	PromptToolArgumentsRepair = NewPrompt(`The arguments of a call to the tool "{{.ToolName}}" are not valid JSON ({{.Error}}):
+
+{{.Arguments}}
+
+The tool expects arguments matching this JSON schema:
+{{.Schema}}
+
+Fix the JSON syntax only, keeping the values as they are. Reply with the fixed JSON object and nothing else.`)
+
+	PromptTruncationContinuation = NewPrompt(`Your previous reply was cut off because it reached the maximum length{{ if .ToolName }} while writing the JSON arguments of the tool "{{.ToolName}}"{{ end }}.
+
+Continue exactly from where it stopped. Reply with the missing part only, without repeating what was already written{{ if .ToolName }} and without code fences{{ end }}.`)
+
+	PromptToolDescriptions = NewPrompt(`You are improving the definition of a tool used by an AI agent, so that the agent picks it for the right requests and fills its parameters correctly.
+
+Tool name: {{.Name}}
+Description: {{.Description}}
+Parameters (JSON schema):
+{{.Parameters}}
+
+Rewrite the definition for clarity:
+- name: a short snake_case name saying what the tool does. Keep the current name if it is already clear.
+- description: one to three sentences saying what the tool does, when to use it and what it returns.
+- parameters: for each parameter, keep its name and describe its meaning and expected format.
+
+Only describe what the definition supports, without inventing capabilities.`)
 )
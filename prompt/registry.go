@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/Masterminds/sprig/v3"
+)
+
+// promptNames maps every PromptType to the name used for it in prompt
+// directories and listings.
+var promptNames = map[PromptType]string{
+	GapAnalysisType:                   "gap_analysis",
+	ContentImproverType:               "content_improver",
+	PromptBooleanType:                 "boolean",
+	PromptIdentifyGoalType:            "identify_goal",
+	PromptGoalAchievedType:            "goal_achieved",
+	PromptPlanType:                    "plan",
+	PromptReEvaluatePlanType:          "re_evaluate_plan",
+	PromptSubtaskExtractionType:       "subtask_extraction",
+	PromptPlanExecutionType:           "plan_execution",
+	PromptGuidelinesType:              "guidelines",
+	PromptGuidelinesExtractionType:    "guidelines_extraction",
+	PromptPlanDecisionType:            "plan_decision",
+	PromptParameterReasoningType:      "parameter_reasoning",
+	PromptTODOGenerationType:          "todo_generation",
+	PromptTODOWorkType:                "todo_work",
+	PromptTODOReviewType:              "todo_review",
+	PromptTODOTrackingType:            "todo_tracking",
+	PromptConversationCompactionType:  "conversation_compaction",
+	PromptAutoImproveReviewSystemType: "auto_improve_review_system",
+	PromptAutoImproveReviewUserType:   "auto_improve_review_user",
+	PromptReflectionType:              "reflection",
+	PromptToolFollowUpType:            "tool_follow_up",
+	PromptToolResultSummaryType:       "tool_result_summary",
+	PromptTextToolCallsType:           "text_tool_calls",
+	PromptResearchSummaryType:         "research_summary",
+	PromptConsolidatedReasoningType:   "consolidated_reasoning",
+	PromptToolClarificationType:       "tool_clarification",
+	PromptArgumentClarificationType:   "argument_clarification",
+	PromptBranchJudgeType:             "branch_judge",
+	PromptBestOfJudgeType:             "best_of_judge",
+	PromptReviewRubricType:            "review_rubric",
+	PromptFactCheckType:               "fact_check",
+	PromptUnsupportedClaimsType:       "unsupported_claims",
+	PromptToolCorrectionType:          "tool_correction",
+	PromptUnavailableToolsType:        "unavailable_tools",
+	PromptRerankType:                  "rerank",
+	PromptEntityHintsType:             "entity_hints",
+	PromptVarsType:                    "vars",
+	PromptToolArgumentsRepairType:     "tool_arguments_repair",
+	PromptTruncationContinuationType:  "truncation_continuation",
+	PromptToolDescriptionsType:        "tool_description_optimization",
+	PromptReflectionsType:             "reflections",
+}
+
+// String returns the name of the prompt type, e.g. "plan".
+func (t PromptType) String() string {
+	if name, ok := promptNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("PromptType(%d)", uint(t))
+}
+
+// PromptTypeByName returns the prompt type with the given name.
+func PromptTypeByName(name string) (PromptType, bool) {
+	for t, n := range promptNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return 0, false
+}
+
+// PromptTypes returns all the built-in prompt types.
+func PromptTypes() []PromptType {
+	types := make([]PromptType, 0, len(promptNames))
+	for t := range promptNames {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Template returns the template source of the prompt.
+func (p StaticPrompt) Template() string {
+	return p.template
+}
+
+// PromptInfo describes a prompt of a PromptMap. See PromptMap.List.
+type PromptInfo struct {
+	Type       PromptType
+	Name       string
+	Overridden bool   // false when the built-in default is used
+	Template   string // template source, empty for non-template prompts
+}
+
+// List describes the prompt in use for every built-in prompt type.
+func (p PromptMap) List() []PromptInfo {
+	var infos []PromptInfo
+	for _, t := range PromptTypes() {
+		pr, overridden := p[t]
+		if vp, ok := pr.(varsPrompt); ok {
+			overridden = !vp.builtin
+		}
+		info := PromptInfo{Type: t, Name: t.String(), Overridden: overridden}
+		pr = p.GetPrompt(t)
+		if vp, ok := pr.(varsPrompt); ok {
+			pr = vp.VarsPrompt
+		}
+		if s, ok := pr.(StaticPrompt); ok {
+			info.Template = s.Template()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// Merge returns a new map with the prompts of p, overridden by the ones of
+// overrides. Neither map is modified.
+func (p PromptMap) Merge(overrides PromptMap) PromptMap {
+	merged := make(PromptMap, len(p)+len(overrides))
+	for t, pr := range p {
+		merged[t] = pr
+	}
+	for t, pr := range overrides {
+		merged[t] = pr
+	}
+	return merged
+}
+
+// LoadDir loads prompt templates from dir. Each file is named after the
+// prompt type it overrides, with any extension (e.g. "plan.tmpl" overrides
+// PromptPlanType). Templates are parsed on load, so syntax errors surface at
+// startup. Files with unknown names are an error; hidden files and
+// subdirectories are skipped.
+func LoadDir(dir string) (PromptMap, error) {
+	return LoadFS(os.DirFS(dir))
+}
+
+// LoadFS is like LoadDir, reading the templates from the root of fsys (e.g. an
+// embed.FS).
+func LoadFS(fsys fs.FS) (PromptMap, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read prompt directory: %w", err)
+	}
+
+	prompts := PromptMap{}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		t, ok := PromptTypeByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown prompt %q (file %s)", name, entry.Name())
+		}
+
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read prompt %s: %w", entry.Name(), err)
+		}
+		if _, err := template.New(name).Funcs(sprig.FuncMap()).Parse(string(content)); err != nil {
+			return nil, fmt.Errorf("invalid prompt template %s: %w", entry.Name(), err)
+		}
+		prompts[t] = NewPrompt(string(content))
+	}
+	return prompts, nil
+}
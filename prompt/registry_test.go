@@ -0,0 +1,116 @@
+package prompt
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestPromptTypeNames(t *testing.T) {
+	for _, pt := range PromptTypes() {
+		got, ok := PromptTypeByName(pt.String())
+		if !ok || got != pt {
+			t.Errorf("round trip of %v = %v, %v", pt, got, ok)
+		}
+		if _, ok := defaultPromptMap[pt]; !ok {
+			t.Errorf("%v has no default prompt", pt)
+		}
+	}
+	if len(PromptTypes()) != len(defaultPromptMap) {
+		t.Errorf("named types = %d, default prompts = %d", len(PromptTypes()), len(defaultPromptMap))
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("plan.tmpl", "Plan for {{.Context | upper}}")
+	write(".notes", "ignored")
+	if err := os.Mkdir(filepath.Join(dir, "de"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	prompts, err := LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir: %v", err)
+	}
+	if len(prompts) != 1 {
+		t.Fatalf("prompts = %v", prompts)
+	}
+	out, err := prompts.GetPrompt(PromptPlanType).Render(struct{ Context string }{"go"})
+	if err != nil || out != "Plan for GO" {
+		t.Errorf("render = %q, %v", out, err)
+	}
+
+	write("planning.tmpl", "typo")
+	if _, err := LoadDir(dir); err == nil || !strings.Contains(err.Error(), `"planning"`) {
+		t.Errorf("unknown prompt error = %v", err)
+	}
+}
+
+func TestLoadFSRejectsInvalidTemplates(t *testing.T) {
+	_, err := LoadFS(fstest.MapFS{"reflection.txt": {Data: []byte("{{ .Broken")}})
+	if err == nil || !strings.Contains(err.Error(), "reflection.txt") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestMergeAndList(t *testing.T) {
+	base := PromptMap{PromptPlanType: NewPrompt("base plan"), PromptReflectionType: NewPrompt("base reflection")}
+	agent := base.Merge(PromptMap{PromptPlanType: NewPrompt("agent plan")})
+
+	if base[PromptPlanType].(StaticPrompt).Template() != "base plan" {
+		t.Error("Merge modified the base map")
+	}
+
+	infos := map[string]PromptInfo{}
+	for _, info := range agent.List() {
+		infos[info.Name] = info
+	}
+	if len(infos) != len(PromptTypes()) {
+		t.Errorf("listed %d prompts", len(infos))
+	}
+	if i := infos["plan"]; !i.Overridden || i.Template != "agent plan" {
+		t.Errorf("plan = %+v", i)
+	}
+	if i := infos["reflection"]; !i.Overridden || i.Template != "base reflection" {
+		t.Errorf("reflection = %+v", i)
+	}
+	if i := infos["boolean"]; i.Overridden || i.Template == "" {
+		t.Errorf("boolean = %+v", i)
+	}
+}
+
+func TestWithVars(t *testing.T) {
+	custom := PromptMap{PromptPlanType: NewPrompt(`Plan for {{ var "user" }} in {{ var "missing" }}{{ range $k, $v := vars }} {{$k}}={{$v}}{{ end }}`)}
+	bound := custom.WithVars(map[string]any{"user": "Ada", "locale": "it"})
+
+	out, err := bound.GetPrompt(PromptPlanType).Render(nil)
+	if err != nil || out != "Plan for Ada in  locale=it user=Ada" {
+		t.Errorf("render = %q, %v", out, err)
+	}
+
+	// Rebinding replaces the variables instead of stacking them
+	out, _ = bound.WithVars(map[string]any{"user": "Bob"}).GetPrompt(PromptPlanType).Render(nil)
+	if out != "Plan for Bob in  user=Bob" {
+		t.Errorf("rebound render = %q", out)
+	}
+
+	// Listings still distinguish overrides from defaults
+	for _, info := range bound.List() {
+		if info.Overridden != (info.Type == PromptPlanType) || info.Template == "" {
+			t.Errorf("info = %+v", info)
+		}
+	}
+
+	// Defaults render unchanged
+	if _, err := bound.GetPrompt(PromptBooleanType).Render(struct{ Context string }{"x"}); err != nil {
+		t.Errorf("default render: %v", err)
+	}
+}
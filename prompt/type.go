@@ -20,10 +20,17 @@ func NewPrompt(template string) StaticPrompt {
 }
 
 func (p StaticPrompt) Render(data any) (string, error) {
+	return p.RenderWithVars(data, nil)
+}
+
+// RenderWithVars renders the prompt with data, making vars available to the
+// template through the "var" (single value by name, empty if unset) and
+// "vars" (whole map) functions, e.g. {{ var "user_name" }}.
+func (p StaticPrompt) RenderWithVars(data any, vars map[string]any) (string, error) {
 
 	b := bytes.NewBuffer([]byte{})
 
-	tmpl, err := template.New("prompt").Funcs(sprig.FuncMap()).Parse(p.template)
+	tmpl, err := template.New("prompt").Funcs(sprig.FuncMap()).Funcs(varFuncs(vars)).Parse(p.template)
 	if err != nil {
 		return "", err
 	}
@@ -33,6 +40,39 @@ func (p StaticPrompt) Render(data any) (string, error) {
 	return b.String(), err
 }
 
+func varFuncs(vars map[string]any) template.FuncMap {
+	return template.FuncMap{
+		"var": func(name string) any {
+			if v, ok := vars[name]; ok {
+				return v
+			}
+			return ""
+		},
+		"vars": func() map[string]any {
+			return vars
+		},
+	}
+}
+
+// VarsPrompt is implemented by prompts that accept prompt variables. See
+// PromptMap.WithVars.
+type VarsPrompt interface {
+	Prompt
+	RenderWithVars(data any, vars map[string]any) (string, error)
+}
+
+// varsPrompt binds variables to a prompt. builtin marks default prompts,
+// so listings still tell them apart from overrides.
+type varsPrompt struct {
+	VarsPrompt
+	vars    map[string]any
+	builtin bool
+}
+
+func (p varsPrompt) Render(data any) (string, error) {
+	return p.RenderWithVars(data, p.vars)
+}
+
 type PromptMap map[PromptType]Prompt
 
 func (p PromptMap) GetPrompt(t PromptType) Prompt {
@@ -44,6 +84,30 @@ func (p PromptMap) GetPrompt(t PromptType) Prompt {
 	return prompter
 }
 
+// WithVars returns a map where every prompt, including the defaults not in
+// p, renders with vars available to its template. Binding variables again
+// replaces the previous ones. Prompts not implementing VarsPrompt are kept as
+// they are.
+func (p PromptMap) WithVars(vars map[string]any) PromptMap {
+	bound := make(PromptMap, len(defaultPromptMap))
+	bind := func(t PromptType, pr Prompt, builtin bool) {
+		if vp, ok := pr.(varsPrompt); ok {
+			pr, builtin = vp.VarsPrompt, vp.builtin
+		}
+		if vp, ok := pr.(VarsPrompt); ok {
+			pr = varsPrompt{VarsPrompt: vp, vars: vars, builtin: builtin}
+		}
+		bound[t] = pr
+	}
+	for t, pr := range defaultPromptMap {
+		bind(t, pr, true)
+	}
+	for t, pr := range p {
+		bind(t, pr, false)
+	}
+	return bound
+}
+
 // DefaultPrompts returns the default prompt map
 func DefaultPrompts() PromptMap {
 	return defaultPromptMap
@@ -0,0 +1,185 @@
+package cogito
+
+import (
+	"context"
+	"slices"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// PromptBlock is the estimated size of one part of a prompt.
+type PromptBlock struct {
+	Name   string
+	Tokens int
+}
+
+// PromptSizeDiagnostic describes a prompt that exceeded the configured size
+// limit (see WithPromptSizeLimit): which blocks contributed how many tokens,
+// and which shrink stages were applied to bring it back under the limit.
+type PromptSizeDiagnostic struct {
+	Limit           int
+	EstimatedTokens int
+	Blocks          []PromptBlock
+	AppliedStages   []string
+	FinalTokens     int
+	Fits            bool // false if the prompt still exceeds the limit after every stage
+}
+
+// estimateTokens roughly estimates tokens as four characters per token.
+func estimateTokens(s string) int {
+	return len(s) / 4
+}
+
+func estimateMessageTokens(m openai.ChatCompletionMessage) int {
+	n := estimateTokens(m.Content) + estimateTokens(m.ReasoningContent)
+	for _, part := range m.MultiContent {
+		n += estimateTokens(part.Text)
+	}
+	for _, tc := range m.ToolCalls {
+		n += estimateTokens(tc.Function.Name) + estimateTokens(tc.Function.Arguments)
+	}
+	return n
+}
+
+func estimatePromptTokens(messages []openai.ChatCompletionMessage, tools []openai.Tool) int {
+	n := 0
+	for _, m := range messages {
+		n += estimateMessageTokens(m)
+	}
+	if len(tools) > 0 {
+		n += estimateTokens(string(mustMarshal(tools)))
+	}
+	return n
+}
+
+// promptBlocks breaks a prompt down by kind of content.
+func promptBlocks(messages []openai.ChatCompletionMessage, tools []openai.Tool) []PromptBlock {
+	order := []string{"system prompt", "additional context", "user messages", "assistant messages", "tool results", "tool definitions"}
+	sizes := map[string]int{}
+	for i, m := range messages {
+		name := ""
+		switch m.Role {
+		case SystemMessageRole.String():
+			name = "system prompt"
+			if i > 0 {
+				name = "additional context"
+			}
+		case UserMessageRole.String():
+			name = "user messages"
+		case AssistantMessageRole.String():
+			name = "assistant messages"
+		case ToolMessageRole.String():
+			name = "tool results"
+		default:
+			name = m.Role
+			if !slices.Contains(order, name) {
+				order = append(order, name)
+			}
+		}
+		sizes[name] += estimateMessageTokens(m)
+	}
+	if len(tools) > 0 {
+		sizes["tool definitions"] = estimateTokens(string(mustMarshal(tools)))
+	}
+
+	var blocks []PromptBlock
+	for _, name := range order {
+		if tokens, ok := sizes[name]; ok {
+			blocks = append(blocks, PromptBlock{Name: name, Tokens: tokens})
+		}
+	}
+	return blocks
+}
+
+// promptGuardLLM wraps an LLM, measuring every prompt before it is sent and
+// shrinking prompts over the limit with the context shrink stages.
+type promptGuardLLM struct {
+	LLM
+	limit    int
+	callback func(PromptSizeDiagnostic)
+	log      *degradationLog
+	shrinker shrinker
+}
+
+func (g *promptGuardLLM) unwrap() LLM { return g.LLM }
+
+// fit returns messages shrunk to the limit, if needed.
+func (g *promptGuardLLM) fit(ctx context.Context, messages []openai.ChatCompletionMessage, tools []openai.Tool) []openai.ChatCompletionMessage {
+	estimated := estimatePromptTokens(messages, tools)
+	if estimated <= g.limit {
+		return messages
+	}
+
+	diag := PromptSizeDiagnostic{
+		Limit:           g.limit,
+		EstimatedTokens: estimated,
+		Blocks:          promptBlocks(messages, tools),
+	}
+	current := messages
+	size := estimated
+	for _, stage := range shrinkStages {
+		if size <= g.limit {
+			break
+		}
+		shrunk := stage.apply(ctx, g.shrinker, current)
+		newSize := estimatePromptTokens(shrunk, tools)
+		g.log.add(ContextDegradation{
+			Stage:          stage.name,
+			MessagesBefore: len(current),
+			MessagesAfter:  len(shrunk),
+			Recovered:      newSize <= g.limit,
+		})
+		diag.AppliedStages = append(diag.AppliedStages, stage.name)
+		current, size = shrunk, newSize
+	}
+	diag.FinalTokens = size
+	diag.Fits = size <= g.limit
+
+	g.shrinker.logger.Warn("Prompt exceeds size limit", "limit", diag.Limit, "estimatedTokens", diag.EstimatedTokens,
+		"blocks", diag.Blocks, "appliedStages", diag.AppliedStages, "finalTokens", diag.FinalTokens)
+	if g.callback != nil {
+		g.callback(diag)
+	}
+	return current
+}
+
+func (g *promptGuardLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	req.Messages = g.fit(ctx, req.Messages, req.Tools)
+	return g.LLM.CreateChatCompletion(ctx, req)
+}
+
+func (g *promptGuardLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	fitted := f
+	fitted.Messages = g.fit(ctx, f.Messages, nil)
+	res, err := g.LLM.Ask(ctx, fitted)
+	if err == nil && len(res.Messages) >= len(fitted.Messages) {
+		// Only the request is shrunk: the reply follows the whole conversation
+		res.Messages = append(slices.Clone(f.Messages), res.Messages[len(fitted.Messages):]...)
+	}
+	return res, err
+}
+
+// promptGuardStreamingLLM preserves StreamingLLM.
+type promptGuardStreamingLLM struct {
+	promptGuardLLM
+	streaming StreamingLLM
+}
+
+func (g *promptGuardStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	req.Messages = g.fit(ctx, req.Messages, req.Tools)
+	return g.streaming.CreateChatCompletionStream(ctx, req)
+}
+
+// newPromptGuardLLM wraps llm so prompts over limit estimated tokens are
+// reported to callback and shrunk, recording each stage into log. Tool
+// results are summarized with llm, accumulating the usage of the summaries
+// into usage when not nil.
+func newPromptGuardLLM(llm LLM, limit int, callback func(PromptSizeDiagnostic), log *degradationLog,
+	prompts prompt.PromptMap, usage *usageCounter, logger Logger) LLM {
+	base := promptGuardLLM{LLM: llm, limit: limit, callback: callback, log: log, shrinker: newShrinker(llm, prompts, usage, logger)}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &promptGuardStreamingLLM{promptGuardLLM: base, streaming: s}
+	}
+	return &base
+}
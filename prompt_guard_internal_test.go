@@ -0,0 +1,94 @@
+package cogito
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// recordingLLM records the messages of the last CreateChatCompletion call.
+type recordingLLM struct {
+	fakeLLM
+	last []openai.ChatCompletionMessage
+}
+
+func (r *recordingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	r.last = req.Messages
+	return r.fakeLLM.CreateChatCompletion(ctx, req)
+}
+
+func TestPromptGuardShrinksAndReports(t *testing.T) {
+	inner := &recordingLLM{}
+	log := &degradationLog{}
+	var diags []PromptSizeDiagnostic
+	llm := newPromptGuardLLM(inner, 500, func(d PromptSizeDiagnostic) { diags = append(diags, d) }, log, nil, nil, defaultLogger)
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: "system", Content: "be helpful"},
+		{Role: "user", Content: "question"},
+		{Role: "tool", Content: strings.Repeat("x", 4000)},
+	}
+	if _, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Messages: messages}); err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+
+	if len(diags) != 1 {
+		t.Fatalf("diagnostics = %d, want 1", len(diags))
+	}
+	d := diags[0]
+	if d.EstimatedTokens != 1004 || !d.Fits || d.FinalTokens > 500 {
+		t.Errorf("diagnostic = %+v", d)
+	}
+	if d.Blocks[len(d.Blocks)-1] != (PromptBlock{Name: "tool results", Tokens: 1000}) {
+		t.Errorf("blocks = %+v", d.Blocks)
+	}
+	if strings.Join(d.AppliedStages, ",") != "drop_additional_context,summarize_tool_results" {
+		t.Errorf("applied stages = %v", d.AppliedStages)
+	}
+	if got := len(inner.last[2].Content); got >= 4000 {
+		t.Errorf("tool result was not trimmed: %d chars", got)
+	}
+	if n := len(log.snapshot()); n != 2 {
+		t.Errorf("degradations = %d, want 2", n)
+	}
+}
+
+func (r *recordingLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	r.last = f.Messages
+	return f.AddMessage(AssistantMessageRole, "answer"), nil
+}
+
+func TestPromptGuardAskKeepsTheConversation(t *testing.T) {
+	inner := &recordingLLM{}
+	llm := newPromptGuardLLM(inner, 500, nil, &degradationLog{}, nil, nil, defaultLogger)
+
+	f := NewEmptyFragment().
+		AddMessage(UserMessageRole, "question").
+		AddMessage(ToolMessageRole, strings.Repeat("x", 4000))
+	result, err := llm.Ask(context.Background(), f)
+	if err != nil {
+		t.Fatalf("Ask: %v", err)
+	}
+	if got := len(inner.last[1].Content); got >= 4000 {
+		t.Errorf("tool result was not trimmed: %d chars", got)
+	}
+	if len(result.Messages) != 3 || len(result.Messages[1].Content) != 4000 || result.Messages[2].Content != "answer" {
+		t.Errorf("messages = %+v", result.Messages)
+	}
+}
+
+func TestPromptGuardLeavesSmallPromptsAlone(t *testing.T) {
+	inner := &recordingLLM{}
+	called := false
+	llm := newPromptGuardLLM(inner, 500, func(PromptSizeDiagnostic) { called = true }, &degradationLog{}, nil, nil, defaultLogger)
+
+	messages := []openai.ChatCompletionMessage{{Role: "user", Content: "hi"}}
+	if _, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Messages: messages}); err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if called || len(inner.last) != 1 {
+		t.Errorf("small prompt was guarded")
+	}
+}
@@ -0,0 +1,46 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	"github.com/mudler/cogito/prompt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Prompt configuration", func() {
+	It("exposes the variables to custom prompt templates", func() {
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
+
+		_, err := ExtractBoolean(llm, NewEmptyFragment().AddMessage(UserMessageRole, "Is it weekend?"),
+			WithPrompt(prompt.PromptBooleanType, prompt.NewPrompt(`Today is {{ var "date" }} for {{ var "user" }}. {{.Context}}`)),
+			WithPromptVars(map[string]any{"date": "Saturday"}),
+			WithPromptVars(map[string]any{"user": "Ada"}),
+		)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(llm.RequestHistory).To(HaveLen(1))
+		Expect(llm.RequestHistory[0].Messages[0].Content).To(Equal("Today is Saturday for Ada. Is it weekend?"))
+	})
+
+	It("uses the prompt pack of the locale, letting explicit prompts win", func() {
+		prompt.RegisterLocale("de", prompt.PromptMap{
+			prompt.PromptBooleanType: prompt.NewPrompt(`Antworte mit ja oder nein: {{.Context}}`),
+			prompt.PromptPlanType:    prompt.NewPrompt(`Plane: {{.Context}}`),
+		})
+
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
+		llm.AddCreateChatCompletionFunction("json", `{"extract_boolean": true}`)
+		conv := NewEmptyFragment().AddMessage(UserMessageRole, "Ist heute Wochenende?")
+
+		_, err := ExtractBoolean(llm, conv, WithLocale("de-AT"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.RequestHistory[0].Messages[0].Content).To(Equal("Antworte mit ja oder nein: Ist heute Wochenende?"))
+
+		_, err = ExtractBoolean(llm, conv, WithPrompt(prompt.PromptBooleanType, prompt.NewPrompt(`Custom: {{.Context}}`)), WithLocale("de"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(llm.RequestHistory[1].Messages[0].Content).To(Equal("Custom: Ist heute Wochenende?"))
+	})
+})
@@ -0,0 +1,305 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// ErrQuotaExceeded matches every QuotaExceededError, with errors.Is.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaScope is who a Quota applies to.
+type QuotaScope int
+
+const (
+	// QuotaPerTenant counts the usage of each tenant, see ContextWithTenant.
+	QuotaPerTenant QuotaScope = iota
+	// QuotaPerIdentity counts the usage of each caller within its tenant, see
+	// WithIdentity and ContextWithCaller.
+	QuotaPerIdentity
+)
+
+// Quota limits the usage of a tenant or caller over a sliding window. Zero
+// limits are unlimited.
+type Quota struct {
+	Scope     QuotaScope
+	Window    time.Duration // e.g. 24 * time.Hour
+	Tokens    int           // LLM tokens
+	ToolCalls int           // tool executions
+	Runs      int           // runs of ExecuteTools and ExecutePlan
+}
+
+// QuotaUsage is an amount of usage counted against quotas.
+type QuotaUsage struct {
+	Tokens    int `json:"tokens,omitempty"`
+	ToolCalls int `json:"tool_calls,omitempty"`
+	Runs      int `json:"runs,omitempty"`
+}
+
+// QuotaStore counts the usage of quota subjects over time. Implement it on a
+// shared database to enforce quotas across replicas. See WithQuota.
+type QuotaStore interface {
+	// Add counts usage for subject at time at.
+	Add(ctx context.Context, subject string, usage QuotaUsage, at time.Time) error
+	// Usage returns the usage of subject since the given time.
+	Usage(ctx context.Context, subject string, since time.Time) (QuotaUsage, error)
+}
+
+// QuotaExceededError is returned when a run, an LLM call or a tool call would
+// exceed a quota.
+type QuotaExceededError struct {
+	Subject  string // e.g. "tenant:acme" or "identity:acme/alice"
+	Resource string // "tokens", "tool calls" or "runs"
+	Limit    int
+	Used     int
+	Window   time.Duration
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("quota exceeded for %s: %d/%d %s over %s", e.Subject, e.Used, e.Limit, e.Resource, e.Window)
+}
+
+func (e *QuotaExceededError) Is(target error) bool { return target == ErrQuotaExceeded }
+
+type quotas struct {
+	store  QuotaStore
+	quotas []Quota
+}
+
+// WithQuota enforces quotas on the usage of tenants and callers, counted in
+// store: once a quota of runs or tokens is used up, runs fail with a
+// QuotaExceededError before starting, and so do the LLM calls of running
+// runs; tool calls going over a quota of tool calls fail the run the same
+// way. Quotas only apply when the run has their subject: a tenant for
+// QuotaPerTenant, an identity or caller for QuotaPerIdentity.
+func WithQuota(store QuotaStore, limits ...Quota) func(o *Options) {
+	return func(o *Options) {
+		o.quotas = &quotas{store: store, quotas: limits}
+	}
+}
+
+// quotaSubject returns the subject of q for the run of o, or "" when the run has
+// none.
+func quotaSubject(o *Options, q Quota) string {
+	tenant := TenantFromContext(o.context)
+	switch q.Scope {
+	case QuotaPerTenant:
+		if tenant != "" {
+			return "tenant:" + tenant
+		}
+	case QuotaPerIdentity:
+		caller := CallerFromContext(o.context)
+		if caller == "" && o.identity != nil {
+			caller = o.identity.Subject
+		}
+		if caller != "" {
+			return "identity:" + tenant + "/" + caller
+		}
+	}
+	return ""
+}
+
+// check returns a QuotaExceededError when need would go over a quota. Only
+// the resources needed are checked. Store errors are logged and do not fail
+// the run.
+func (q *quotas) check(o *Options, need QuotaUsage) error {
+	now := time.Now()
+	for _, quota := range q.quotas {
+		subject := quotaSubject(o, quota)
+		if subject == "" {
+			continue
+		}
+		used, err := q.store.Usage(o.context, subject, now.Add(-quota.Window))
+		if err != nil {
+			o.logger.Warn("Failed to read quota usage", "subject", subject, "error", err)
+			continue
+		}
+		for _, r := range []struct {
+			name              string
+			limit, used, need int
+		}{
+			{"runs", quota.Runs, used.Runs, need.Runs},
+			{"tokens", quota.Tokens, used.Tokens, need.Tokens},
+			{"tool calls", quota.ToolCalls, used.ToolCalls, need.ToolCalls},
+		} {
+			if r.need > 0 && r.limit > 0 && r.used+r.need > r.limit {
+				return &QuotaExceededError{Subject: subject, Resource: r.name, Limit: r.limit, Used: r.used, Window: quota.Window}
+			}
+		}
+	}
+	return nil
+}
+
+// add counts usage against the quotas of the run of o.
+func (q *quotas) add(o *Options, usage QuotaUsage) {
+	now := time.Now()
+	seen := map[string]bool{}
+	for _, quota := range q.quotas {
+		subject := quotaSubject(o, quota)
+		if subject == "" || seen[subject] {
+			continue
+		}
+		seen[subject] = true
+		if err := q.store.Add(o.context, subject, usage, now); err != nil {
+			o.logger.Warn("Failed to record quota usage", "subject", subject, "error", err)
+		}
+	}
+}
+
+// startQuotaRun checks the quotas of runs and tokens of o before a run, and
+// counts the run.
+func startQuotaRun(o *Options) error {
+	if o.quotas == nil {
+		return nil
+	}
+	// A run needs at least a token
+	if err := o.quotas.check(o, QuotaUsage{Runs: 1, Tokens: 1}); err != nil {
+		return err
+	}
+	o.quotas.add(o, QuotaUsage{Runs: 1})
+	return nil
+}
+
+// quotaLLM wraps an LLM, checking the token quotas before every call and
+// counting the tokens used.
+type quotaLLM struct {
+	LLM
+	o *Options
+}
+
+func (q *quotaLLM) unwrap() LLM { return q.LLM }
+
+func (q *quotaLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	if err := q.o.quotas.check(q.o, QuotaUsage{Tokens: 1}); err != nil {
+		return LLMReply{}, LLMUsage{}, err
+	}
+	reply, usage, err := q.LLM.CreateChatCompletion(ctx, req)
+	if err == nil {
+		q.o.quotas.add(q.o, QuotaUsage{Tokens: usage.TotalTokens})
+	}
+	return reply, usage, err
+}
+
+func (q *quotaLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	if err := q.o.quotas.check(q.o, QuotaUsage{Tokens: 1}); err != nil {
+		return f, err
+	}
+	res, err := q.LLM.Ask(ctx, f)
+	if err == nil && res.Status != nil {
+		q.o.quotas.add(q.o, QuotaUsage{Tokens: res.Status.LastUsage.TotalTokens})
+	}
+	return res, err
+}
+
+// quotaStreamingLLM preserves StreamingLLM.
+type quotaStreamingLLM struct {
+	quotaLLM
+	streaming StreamingLLM
+}
+
+func (q *quotaStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	if err := q.o.quotas.check(q.o, QuotaUsage{Tokens: 1}); err != nil {
+		return nil, err
+	}
+	in, err := q.streaming.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StreamEvent, 64)
+	go func() {
+		defer close(out)
+		for ev := range in {
+			if ev.Type == StreamEventDone {
+				q.o.quotas.add(q.o, QuotaUsage{Tokens: ev.Usage.TotalTokens})
+			}
+			select {
+			case out <- ev:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// withQuota wraps llm with the quotas of o, if any. An LLM already wrapped is
+// returned as is, so nested primitives do not count calls twice.
+func withQuota(llm LLM, o *Options) LLM {
+	if o.quotas == nil || llm == nil {
+		return llm
+	}
+	if _, ok := unwrapLLM[*quotaLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*quotaStreamingLLM](llm); ok {
+		return llm
+	}
+	base := quotaLLM{LLM: llm, o: o}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &quotaStreamingLLM{quotaLLM: base, streaming: s}
+	}
+	return &base
+}
+
+// MemoryQuotaStore counts quota usage in memory, in one-minute buckets.
+// Buckets older than Retention (31 days by default) are dropped. Safe for
+// concurrent use.
+type MemoryQuotaStore struct {
+	Retention time.Duration
+
+	mu      sync.Mutex
+	buckets map[string]map[time.Time]QuotaUsage // by subject, then minute
+}
+
+var _ QuotaStore = (*MemoryQuotaStore)(nil)
+
+func (s *MemoryQuotaStore) Add(ctx context.Context, subject string, usage QuotaUsage, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.buckets == nil {
+		s.buckets = map[string]map[time.Time]QuotaUsage{}
+	}
+	buckets := s.buckets[subject]
+	if buckets == nil {
+		buckets = map[time.Time]QuotaUsage{}
+		s.buckets[subject] = buckets
+	}
+	minute := at.Truncate(time.Minute)
+	b, ok := buckets[minute]
+	buckets[minute] = QuotaUsage{Tokens: b.Tokens + usage.Tokens, ToolCalls: b.ToolCalls + usage.ToolCalls, Runs: b.Runs + usage.Runs}
+	if ok {
+		return nil
+	}
+
+	// Drop the old buckets when starting a new one
+	retention := s.Retention
+	if retention <= 0 {
+		retention = 31 * 24 * time.Hour
+	}
+	for m := range buckets {
+		if at.Sub(m) > retention {
+			delete(buckets, m)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryQuotaStore) Usage(ctx context.Context, subject string, since time.Time) (QuotaUsage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var total QuotaUsage
+	for minute, b := range s.buckets[subject] {
+		// Buckets are counted once their minute ends after since
+		if minute.Add(time.Minute).After(since) {
+			total.Tokens += b.Tokens
+			total.ToolCalls += b.ToolCalls
+			total.Runs += b.Runs
+		}
+	}
+	return total, nil
+}
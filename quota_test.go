@@ -0,0 +1,95 @@
+package cogito_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Quotas", func() {
+	var store *MemoryQuotaStore
+	acme := ContextWithTenant(context.Background(), "acme")
+
+	reply := openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+		Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Hello."},
+	}}}
+
+	run := func(ctx context.Context, opts ...Option) error {
+		llm := cogitotest.NewMockLLM()
+		llm.SetCreateChatCompletionResponse(reply)
+		llm.SetUsage(60, 40, 100)
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Hi")
+		_, err := ExecuteTools(llm, f, append(opts, WithContext(ctx))...)
+		return err
+	}
+
+	BeforeEach(func() {
+		store = &MemoryQuotaStore{}
+	})
+
+	It("refuses runs over the quota of runs", func() {
+		quota := WithQuota(store, Quota{Scope: QuotaPerTenant, Window: time.Hour, Runs: 1})
+		Expect(run(acme, quota)).To(Succeed())
+
+		err := run(acme, quota)
+		Expect(err).To(MatchError(ErrQuotaExceeded))
+		var exceeded *QuotaExceededError
+		Expect(errors.As(err, &exceeded)).To(BeTrue())
+		Expect(exceeded.Subject).To(Equal("tenant:acme"))
+		Expect(exceeded.Resource).To(Equal("runs"))
+		Expect(exceeded.Used).To(Equal(1))
+
+		// Other tenants have their own quota
+		Expect(run(ContextWithTenant(context.Background(), "globex"), quota)).To(Succeed())
+	})
+
+	It("refuses runs once the tokens are used up", func() {
+		quota := WithQuota(store, Quota{Scope: QuotaPerIdentity, Window: time.Hour, Tokens: 50})
+		alice := WithIdentity(Identity{Subject: "alice"})
+		Expect(run(acme, quota, alice)).To(Succeed())
+
+		usage, err := store.Usage(context.Background(), "identity:acme/alice", time.Now().Add(-time.Hour))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage).To(Equal(QuotaUsage{Tokens: 100, Runs: 1}))
+
+		err = run(acme, quota, alice)
+		Expect(err).To(MatchError(ErrQuotaExceeded))
+		Expect(err.Error()).To(ContainSubstring("tokens"))
+		Expect(run(acme, quota, WithIdentity(Identity{Subject: "bob"}))).To(Succeed())
+	})
+
+	It("stops runs going over the quota of tool calls", func() {
+		search := cogitotest.NewMockTool("search", "Search the web")
+		cogitotest.SetRunResult(search, "result")
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("search", `{"query": "a"}`)
+		llm.AddCreateChatCompletionFunction("search", `{"query": "b"}`)
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Search twice")
+		_, err := ExecuteTools(llm, f, WithTools(search), WithIterations(3), WithContext(acme),
+			WithQuota(store, Quota{Scope: QuotaPerTenant, Window: time.Hour, ToolCalls: 1}))
+		Expect(err).To(MatchError(ErrQuotaExceeded))
+		Expect(err.Error()).To(ContainSubstring("tool calls"))
+		Expect(cogitotest.GetMockTool(search).Calls()).To(HaveLen(1))
+	})
+
+	It("does not apply quotas without their subject", func() {
+		quota := WithQuota(store, Quota{Scope: QuotaPerTenant, Window: time.Hour, Runs: 1})
+		Expect(run(context.Background(), quota)).To(Succeed())
+		Expect(run(context.Background(), quota)).To(Succeed())
+	})
+
+	It("only counts the usage within the window", func() {
+		Expect(store.Add(context.Background(), "tenant:acme", QuotaUsage{Runs: 1}, time.Now().Add(-2*time.Hour))).To(Succeed())
+		Expect(store.Add(context.Background(), "tenant:acme", QuotaUsage{Runs: 2}, time.Now())).To(Succeed())
+		usage, err := store.Usage(context.Background(), "tenant:acme", time.Now().Add(-time.Hour))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(usage.Runs).To(Equal(2))
+	})
+})
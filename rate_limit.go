@@ -0,0 +1,119 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Limiter paces calls: Wait blocks until the next call may proceed, or fails
+// when ctx is done. *rate.Limiter from golang.org/x/time/rate implements it.
+// See WithRateLimiter.
+type Limiter interface {
+	Wait(ctx context.Context) error
+}
+
+// TokenBucket is a Limiter allowing rate calls per second on average, with
+// bursts of up to burst calls. Safe for concurrent use, so a single bucket
+// can pace every agent sharing an API key.
+type TokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+var _ Limiter = (*TokenBucket)(nil)
+
+// NewTokenBucket returns a full bucket refilled at rate tokens per second.
+// It panics if rate is not positive, as calls would wait forever.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if !(rate > 0) {
+		panic(fmt.Sprintf("cogito: invalid token bucket rate %v, must be positive", rate))
+	}
+	burst = max(burst, 1)
+	return &TokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// rateLimitedLLM wraps an LLM, waiting on limiter before every call.
+type rateLimitedLLM struct {
+	LLM
+	limiter Limiter
+}
+
+func (r *rateLimitedLLM) unwrap() LLM { return r.LLM }
+
+func (r *rateLimitedLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return LLMReply{}, LLMUsage{}, err
+	}
+	return r.LLM.CreateChatCompletion(ctx, req)
+}
+
+func (r *rateLimitedLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return f, err
+	}
+	return r.LLM.Ask(ctx, f)
+}
+
+// rateLimitedStreamingLLM preserves StreamingLLM.
+type rateLimitedStreamingLLM struct {
+	rateLimitedLLM
+	streaming StreamingLLM
+}
+
+func (r *rateLimitedStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	if err := r.limiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+	return r.streaming.CreateChatCompletionStream(ctx, req)
+}
+
+// withRateLimit wraps llm with the rate limiter of o, if any. An LLM already
+// rate limited is returned as is, so nested primitives do not wait twice per
+// call.
+func withRateLimit(llm LLM, o *Options) LLM {
+	if o.rateLimiter == nil || llm == nil {
+		return llm
+	}
+	if _, ok := unwrapLLM[*rateLimitedLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*rateLimitedStreamingLLM](llm); ok {
+		return llm
+	}
+	base := rateLimitedLLM{LLM: llm, limiter: o.rateLimiter}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &rateLimitedStreamingLLM{rateLimitedLLM: base, streaming: s}
+	}
+	return &base
+}
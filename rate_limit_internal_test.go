@@ -0,0 +1,70 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTokenBucketAllowsBurstThenPaces(t *testing.T) {
+	b := NewTokenBucket(50, 2) // one token every 20ms
+	ctx := context.Background()
+
+	start := time.Now()
+	for range 2 {
+		if err := b.Wait(ctx); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Millisecond {
+		t.Fatalf("burst took %v, want immediate", elapsed)
+	}
+
+	if err := b.Wait(ctx); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Fatalf("third call after %v, want it paced to ~20ms", elapsed)
+	}
+}
+
+func TestTokenBucketWaitHonorsContext(t *testing.T) {
+	b := NewTokenBucket(0.1, 1)
+	if err := b.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait = %v, want deadline exceeded", err)
+	}
+}
+
+func TestTokenBucketRejectsInvalidRates(t *testing.T) {
+	for _, rate := range []float64{0, -1, math.NaN()} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewTokenBucket(%v, 1) didn't panic", rate)
+				}
+			}()
+			NewTokenBucket(rate, 1)
+		}()
+	}
+}
+
+func TestWithRateLimitWrapsOnce(t *testing.T) {
+	o := defaultOptions()
+	o.Apply(WithRateLimiter(NewTokenBucket(1, 1)))
+
+	llm := withRateLimit(&fakeLLM{}, o)
+	if _, ok := llm.(*rateLimitedLLM); !ok {
+		t.Fatalf("expected a rate limited LLM, got %T", llm)
+	}
+	if again := withRateLimit(newCountingLLM(llm, &usageCounter{}), o); again.(llmWrapper).unwrap() != llm {
+		t.Fatalf("rate limited LLM was wrapped twice")
+	}
+}
@@ -0,0 +1,80 @@
+package cogito_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Reasoning log limits", func() {
+	run := func(runID string, opts ...Option) Fragment {
+		tool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(tool, "Result")
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role:             AssistantMessageRole.String(),
+				ReasoningContent: "The user wants fresh data, so search.",
+				ToolCalls: []openai.ToolCall{{
+					ID:       "call-1",
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: "search", Arguments: `{"query": "test"}`},
+				}},
+			}}},
+		})
+		mockLLM.SetAskResponse("<think>The search answered it.</think>Done")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Search something")
+		opts = append(opts, WithTools(tool), WithContext(ContextWithRunID(context.Background(), runID)))
+		result, err := ExecuteTools(mockLLM, f, opts...)
+		Expect(err).ToNot(HaveOccurred())
+		return result
+	}
+
+	It("keeps every entry by default", func() {
+		result := run("run-1")
+		Expect(result.Status.ReasoningLog).To(Equal([]string{"The user wants fresh data, so search.", "The search answered it."}))
+	})
+
+	It("keeps the most recent entries, the whole reasoning staying in the store", func() {
+		store := NewFileReasoningSink(filepath.Join(GinkgoT().TempDir(), "reasoning.jsonl"))
+		result := run("run-1", WithReasoningLogLimit(1), WithReasoningSink(store))
+		run("run-2", WithReasoningSink(store))
+		Expect(result.Status.ReasoningLog).To(Equal([]string{"The search answered it."}))
+
+		records, err := store.Query(context.Background(), "run-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].Kind).To(Equal(ReasoningToolSelection))
+		Expect(records[1].Kind).To(Equal(ReasoningFinal))
+		Expect(records[1].Reasoning).To(Equal("The search answered it."))
+		Expect(records[1].RunID).To(Equal("run-1"))
+
+		all, err := store.Query(context.Background(), "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(all).To(HaveLen(4))
+	})
+
+	It("queries records in memory by run ID", func() {
+		store := &MemoryReasoningSink{}
+		run("run-1", WithReasoningSink(store))
+		run("run-2", WithReasoningSink(store))
+		records, err := store.Query(context.Background(), "run-2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(HaveLen(2))
+		Expect(records[0].RunID).To(Equal("run-2"))
+	})
+
+	It("diffs the reasoning of a capped log", func() {
+		a := NewEmptyFragment()
+		a.Status.ReasoningLog = []string{"one", "two"}
+		b := NewEmptyFragment()
+		b.Status.ReasoningLog = []string{"two", "three"}
+		Expect(DiffFragments(a, b).Reasoning).To(Equal([]string{"three"}))
+	})
+})
@@ -0,0 +1,182 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ReasoningKind tells where a ReasoningRecord comes from.
+type ReasoningKind string
+
+const (
+	ReasoningToolSelection ReasoningKind = "tool_selection" // reasoning behind the tools picked
+	ReasoningReply         ReasoningKind = "reply"          // the model answered without picking a tool
+	ReasoningReflection    ReasoningKind = "reflection"     // lesson learned from a failure (see EnableReflection)
+	ReasoningFinal         ReasoningKind = "final"          // chain-of-thought of the final answer
+)
+
+// ReasoningRecord is a piece of reasoning produced during a run.
+type ReasoningRecord struct {
+	Tenant    string        `json:"tenant,omitempty"` // see ContextWithTenant
+	RunID     string        `json:"run_id,omitempty"` // see ContextWithRunID
+	Kind      ReasoningKind `json:"kind"`
+	Iteration int           `json:"iteration"`
+	Tools     []string      `json:"tools,omitempty"` // tools selected, for ReasoningToolSelection
+	Reasoning string        `json:"reasoning"`
+	Time      time.Time     `json:"time"`
+}
+
+// ReasoningSink receives the reasoning of a run. See WithReasoningSink.
+type ReasoningSink interface {
+	Record(ctx context.Context, record ReasoningRecord) error
+}
+
+// ReasoningStore is a ReasoningSink whose records can be read back, e.g. the
+// reasoning spilled out of a capped Status.ReasoningLog (see
+// WithReasoningLogLimit).
+type ReasoningStore interface {
+	ReasoningSink
+	// Query returns the records of the run runID in order, or all the
+	// records when runID is empty, of the tenant of ctx (see ContextWithTenant).
+	Query(ctx context.Context, runID string) ([]ReasoningRecord, error)
+}
+
+// ReasoningSinkFunc adapts a function to ReasoningSink.
+type ReasoningSinkFunc func(ctx context.Context, record ReasoningRecord) error
+
+func (f ReasoningSinkFunc) Record(ctx context.Context, record ReasoningRecord) error {
+	return f(ctx, record)
+}
+
+// MemoryReasoningSink keeps reasoning records in memory.
+type MemoryReasoningSink struct {
+	mu      sync.Mutex
+	records []ReasoningRecord
+}
+
+var _ ReasoningStore = (*MemoryReasoningSink)(nil)
+
+func (s *MemoryReasoningSink) Record(ctx context.Context, record ReasoningRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, record)
+	return nil
+}
+
+// Records returns a copy of the records received so far, in order.
+func (s *MemoryReasoningSink) Records() []ReasoningRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ReasoningRecord, len(s.records))
+	copy(out, s.records)
+	return out
+}
+
+// Query implements ReasoningStore.
+func (s *MemoryReasoningSink) Query(ctx context.Context, runID string) ([]ReasoningRecord, error) {
+	var out []ReasoningRecord
+	for _, r := range s.Records() {
+		if (runID == "" || r.RunID == runID) && r.Tenant == TenantFromContext(ctx) {
+			out = append(out, r)
+		}
+	}
+	return out, nil
+}
+
+// FileReasoningSink appends reasoning records to a JSON-lines file.
+type FileReasoningSink struct {
+	mu   sync.Mutex
+	path string
+}
+
+var _ ReasoningStore = (*FileReasoningSink)(nil)
+
+// NewFileReasoningSink returns a sink appending to the file at path, which is
+// created on the first record.
+func NewFileReasoningSink(path string) *FileReasoningSink {
+	return &FileReasoningSink{path: path}
+}
+
+func (s *FileReasoningSink) Record(ctx context.Context, record ReasoningRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode reasoning record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open reasoning file: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write reasoning record: %w", err)
+	}
+	return nil
+}
+
+// Query implements ReasoningStore by scanning the file. A missing file has no
+// records.
+func (s *FileReasoningSink) Query(ctx context.Context, runID string) ([]ReasoningRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open reasoning file: %w", err)
+	}
+	defer file.Close()
+
+	var out []ReasoningRecord
+	decoder := json.NewDecoder(file)
+	for {
+		var record ReasoningRecord
+		if err := decoder.Decode(&record); err == io.EOF {
+			return out, nil
+		} else if err != nil {
+			return out, fmt.Errorf("failed to decode reasoning record: %w", err)
+		}
+		if (runID == "" || record.RunID == runID) && record.Tenant == TenantFromContext(ctx) {
+			out = append(out, record)
+		}
+	}
+}
+
+// recordReasoning sends reasoning to the sink of o, if any. Sink errors are
+// logged and do not stop the run.
+func recordReasoning(o *Options, f Fragment, kind ReasoningKind, reasoning string, tools ...string) {
+	if o.reasoningSink == nil || reasoning == "" {
+		return
+	}
+	record := ReasoningRecord{Tenant: TenantFromContext(o.context), RunID: RunIDFromContext(o.context), Kind: kind, Tools: tools, Reasoning: reasoning, Time: time.Now()}
+	if f.Status != nil {
+		record.Iteration = f.Status.Iterations
+	}
+	if err := o.reasoningSink.Record(o.context, record); err != nil {
+		o.logger.Warn("Failed to record reasoning", "kind", kind, "error", err)
+	}
+}
+
+// trimReasoningLog drops the oldest entries of the reasoning log of s beyond
+// the limit of o. They remain in the reasoning sink, if any.
+func trimReasoningLog(o *Options, s *Status) {
+	if o.reasoningLogLimit <= 0 || s == nil || len(s.ReasoningLog) <= o.reasoningLogLimit {
+		return
+	}
+	dropped := len(s.ReasoningLog) - o.reasoningLogLimit
+	// Cloned so the dropped entries can be garbage collected
+	s.ReasoningLog = slices.Clone(s.ReasoningLog[dropped:])
+	o.logger.Debug("Trimmed reasoning log", "dropped", dropped, "limit", o.reasoningLogLimit)
+}
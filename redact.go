@@ -0,0 +1,260 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// RedactionPattern masks the matches of Pattern with Replacement, or with
+// "[REDACTED:<Name>]" when Replacement is empty. When Valid is set, only the
+// matches it accepts are masked, e.g. numbers passing a checksum.
+type RedactionPattern struct {
+	Name        string
+	Pattern     *regexp.Regexp
+	Replacement string
+	Valid       func(match string) bool
+}
+
+// DefaultRedactionPatterns are used by NewRedactor when no patterns are given.
+var DefaultRedactionPatterns = []RedactionPattern{
+	{Name: "email", Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{Name: "api_key", Pattern: regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}\b|\bAKIA[0-9A-Z]{16}\b|\bgh[pousr]_[A-Za-z0-9]{36,}\b|\bxox[abprs]-[A-Za-z0-9-]{10,}\b`)},
+	{Name: "bearer_token", Pattern: regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9._~+/-]{16,}=*`)},
+	{Name: "credit_card", Pattern: regexp.MustCompile(`\b(?:\d[ -]?){13,16}\b`), Valid: luhnValid},
+	{Name: "phone", Pattern: regexp.MustCompile(`\+\d{1,3}[ .-]?\(?\d{1,4}\)?(?:[ .-]?\d{2,4}){2,3}\b|\(?\b\d{3}\)?[ -]\d{3}[ -]\d{4}\b`)},
+}
+
+// Redactor masks sensitive data (emails, phone numbers, API keys, ...) in
+// text. See WithRedactor.
+type Redactor struct {
+	patterns []RedactionPattern
+}
+
+// NewRedactor returns a Redactor applying patterns in order, or
+// DefaultRedactionPatterns if none are given.
+func NewRedactor(patterns ...RedactionPattern) *Redactor {
+	if len(patterns) == 0 {
+		patterns = DefaultRedactionPatterns
+	}
+	return &Redactor{patterns: patterns}
+}
+
+// Redact returns s with every match of the patterns masked.
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, p := range r.patterns {
+		replacement := p.Replacement
+		if replacement == "" {
+			replacement = "[REDACTED:" + p.Name + "]"
+		}
+		if p.Valid == nil {
+			s = p.Pattern.ReplaceAllLiteralString(s, replacement)
+			continue
+		}
+		s = p.Pattern.ReplaceAllStringFunc(s, func(match string) string {
+			if !p.Valid(match) {
+				return match
+			}
+			return replacement
+		})
+	}
+	return s
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum of card
+// numbers, so other long numbers (order IDs, timestamps) are not masked.
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// redactValue returns a copy of v, a value decoded from JSON, with its
+// strings redacted.
+func (r *Redactor) redactValue(v any) any {
+	switch v := v.(type) {
+	case string:
+		return r.Redact(v)
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, e := range v {
+			out[k] = r.redactValue(e)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, e := range v {
+			out[i] = r.redactValue(e)
+		}
+		return out
+	}
+	return v
+}
+
+// redactMessages returns a copy of messages with their text redacted.
+func (r *Redactor) redactMessages(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	out := slices.Clone(messages)
+	for i, m := range out {
+		m.Content = r.Redact(m.Content)
+		m.ReasoningContent = r.Redact(m.ReasoningContent)
+		if len(m.MultiContent) > 0 {
+			m.MultiContent = slices.Clone(m.MultiContent)
+			for j := range m.MultiContent {
+				m.MultiContent[j].Text = r.Redact(m.MultiContent[j].Text)
+			}
+		}
+		if len(m.ToolCalls) > 0 {
+			m.ToolCalls = slices.Clone(m.ToolCalls)
+			for j := range m.ToolCalls {
+				m.ToolCalls[j].Function.Arguments = r.Redact(m.ToolCalls[j].Function.Arguments)
+			}
+		}
+		out[i] = m
+	}
+	return out
+}
+
+// redactingLLM wraps an LLM, redacting every message sent to it.
+type redactingLLM struct {
+	LLM
+	redactor *Redactor
+}
+
+func (r *redactingLLM) unwrap() LLM { return r.LLM }
+
+func (r *redactingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	req.Messages = r.redactor.redactMessages(req.Messages)
+	return r.LLM.CreateChatCompletion(ctx, req)
+}
+
+// Ask sends the redacted conversation, but returns the reply appended to
+// the original messages so the caller's history is left as is.
+func (r *redactingLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	original := f.Messages
+	f.Messages = r.redactor.redactMessages(f.Messages)
+	result, err := r.LLM.Ask(ctx, f)
+	if err != nil {
+		return result, err
+	}
+	if len(result.Messages) >= len(original) {
+		result.Messages = append(slices.Clip(original), result.Messages[len(original):]...)
+	}
+	return result, nil
+}
+
+// redactingStreamingLLM preserves StreamingLLM.
+type redactingStreamingLLM struct {
+	redactingLLM
+	streaming StreamingLLM
+}
+
+func (r *redactingStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	req.Messages = r.redactor.redactMessages(req.Messages)
+	return r.streaming.CreateChatCompletionStream(ctx, req)
+}
+
+// withRedaction wraps llm with the redactor of o, if any. An LLM already
+// redacting is returned as is.
+func withRedaction(llm LLM, o *Options) LLM {
+	if o.redactor == nil || llm == nil {
+		return llm
+	}
+	if _, ok := unwrapLLM[*redactingLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*redactingStreamingLLM](llm); ok {
+		return llm
+	}
+	base := redactingLLM{LLM: llm, redactor: o.redactor}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &redactingStreamingLLM{redactingLLM: base, streaming: s}
+	}
+	return &base
+}
+
+// redactingLogger redacts the message and the values of a Logger.
+type redactingLogger struct {
+	logger   Logger
+	redactor *Redactor
+}
+
+func (l redactingLogger) Debug(msg string, args ...any) {
+	l.logger.Debug(l.redactor.Redact(msg), l.redactArgs(args)...)
+}
+
+func (l redactingLogger) Info(msg string, args ...any) {
+	l.logger.Info(l.redactor.Redact(msg), l.redactArgs(args)...)
+}
+
+func (l redactingLogger) Warn(msg string, args ...any) {
+	l.logger.Warn(l.redactor.Redact(msg), l.redactArgs(args)...)
+}
+
+func (l redactingLogger) Error(msg string, args ...any) {
+	l.logger.Error(l.redactor.Redact(msg), l.redactArgs(args)...)
+}
+
+// redactArgs redacts string values and, when they contain sensitive data,
+// replaces other values with their redacted text form. Numbers and booleans
+// are kept as they are.
+func (l redactingLogger) redactArgs(args []any) []any {
+	out := make([]any, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case string:
+			out[i] = l.redactor.Redact(v)
+		case nil, bool, int, int64, float64:
+			out[i] = v
+		default:
+			text := fmt.Sprintf("%+v", v)
+			if redacted := l.redactor.Redact(text); redacted != text {
+				out[i] = redacted
+			} else {
+				out[i] = v
+			}
+		}
+	}
+	return out
+}
+
+// applyRedaction makes the callbacks, reasoning sink and logger of o redact
+// what they receive.
+func applyRedaction(o *Options) {
+	r := o.redactor
+	if r == nil {
+		return
+	}
+	if _, ok := o.logger.(redactingLogger); !ok {
+		o.logger = redactingLogger{logger: o.logger, redactor: r}
+	}
+	if status := o.statusCallback; status != nil {
+		o.statusCallback = func(s string) { status(r.Redact(s)) }
+	}
+	if reasoning := o.reasoningCallback; reasoning != nil {
+		o.reasoningCallback = func(s string) { reasoning(r.Redact(s)) }
+	}
+	if sink := o.reasoningSink; sink != nil {
+		o.reasoningSink = ReasoningSinkFunc(func(ctx context.Context, record ReasoningRecord) error {
+			record.Reasoning = r.Redact(record.Reasoning)
+			return sink.Record(ctx, record)
+		})
+	}
+}
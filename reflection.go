@@ -0,0 +1,68 @@
+package cogito
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// reflectOnFailure asks the LLM for a short "what went wrong / what to try
+// next" note about a failed step. f is the conversation the failure happened
+// in. It returns "" if the note could not be produced: reflection is best
+// effort and never fails the run.
+func reflectOnFailure(llm LLM, f Fragment, failure string, o *Options) string {
+	prompter := o.prompts.GetPrompt(prompt.PromptReflectionType)
+
+	p, err := prompter.Render(struct {
+		Context string
+		Failure string
+	}{
+		Context: conversationContext(o, prompt.PromptReflectionType, f),
+		Failure: failure,
+	})
+	if err != nil {
+		o.logger.Warn("Failed to render reflection prompt", "error", err)
+		return ""
+	}
+
+	res, err := llm.Ask(o.context, NewEmptyFragment().AddMessage(UserMessageRole, p))
+	if err != nil {
+		o.logger.Warn("Failed to reflect on failure", "error", err)
+		return ""
+	}
+	if res.LastMessage() == nil {
+		return ""
+	}
+
+	note := strings.TrimSpace(res.LastMessage().Content)
+	o.logger.Debug("Reflection produced", "failure", failure, "note", note)
+	if note != "" {
+		o.reasoningCallback(note)
+		recordReasoning(o, f, ReasoningReflection, note)
+	}
+	return note
+}
+
+// recordReflection stores a lessons-learned note on the status, both as a
+// reflection (re-injected into later selections) and in the reasoning log.
+func recordReflection(s *Status, note string) {
+	if s == nil || note == "" {
+		return
+	}
+	s.Reflections = append(s.Reflections, note)
+	s.ReasoningLog = append(s.ReasoningLog, note)
+}
+
+// reflectionsMessage renders the recorded lessons as a system message for the
+// tool selection prompt.
+func reflectionsMessage(o *Options, reflections []string) (openai.ChatCompletionMessage, error) {
+	content, err := o.prompts.GetPrompt(prompt.PromptReflectionsType).Render(struct {
+		Reflections []string
+	}{Reflections: reflections})
+	if err != nil {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("failed to render reflections prompt: %w", err)
+	}
+	return openai.ChatCompletionMessage{Role: SystemMessageRole.String(), Content: content}, nil
+}
@@ -0,0 +1,102 @@
+package cogito_test
+
+import (
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	"github.com/mudler/cogito/prompt"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Reflection", func() {
+	var mockLLM *cogitotest.MockLLM
+
+	textReply := func(content string) openai.ChatCompletionResponse {
+		return openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: content}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+	})
+
+	It("records a lesson after a tool error", func() {
+		mockTool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunError(mockTool, errors.New("backend unavailable"))
+
+		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "weather"}`)
+		mockLLM.SetAskResponse("The search backend is down; answer from prior knowledge.")
+		mockLLM.SetCreateChatCompletionResponse(textReply("It is probably sunny."))
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather?")
+		result, err := ExecuteTools(mockLLM, fragment,
+			WithTools(mockTool), WithIterations(2), EnableReflection)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.Reflections).To(Equal([]string{"The search backend is down; answer from prior knowledge."}))
+		Expect(result.Status.ReasoningLog).To(ContainElement("The search backend is down; answer from prior knowledge."))
+
+		// The next selection sees the lesson
+		Expect(mockLLM.RequestHistory[len(mockLLM.RequestHistory)-1].Messages).To(ContainElement(And(
+			HaveField("Role", SystemMessageRole.String()),
+			HaveField("Content", "Lessons learned from previous failed attempts (take them into account):\n1. The search backend is down; answer from prior knowledge."),
+		)))
+	})
+
+	It("shows the lessons through the reflections prompt", func() {
+		mockTool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunError(mockTool, errors.New("backend unavailable"))
+
+		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "weather"}`)
+		mockLLM.SetAskResponse("The search backend is down.")
+		mockLLM.SetCreateChatCompletionResponse(textReply("It is probably sunny."))
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather?")
+		_, err := ExecuteTools(mockLLM, fragment,
+			WithTools(mockTool), WithIterations(2), EnableReflection,
+			WithPrompt(prompt.PromptReflectionsType, prompt.NewPrompt(`Lektionen:{{ range .Reflections }} {{.}}{{ end }}`)))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(mockLLM.RequestHistory[len(mockLLM.RequestHistory)-1].Messages).To(ContainElement(
+			HaveField("Content", "Lektionen: The search backend is down."),
+		))
+	})
+
+	It("reflects on a detected loop instead of aborting", func() {
+		mockTool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(mockTool, "first result")
+
+		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "news"}`)
+		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "news"}`)
+		mockLLM.SetAskResponse("Searching again will return the same result; answer now.")
+		mockLLM.SetCreateChatCompletionResponse(textReply("Here is the news."))
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What's new?")
+		result, err := ExecuteTools(mockLLM, fragment,
+			WithTools(mockTool), WithIterations(3), WithLoopDetection(1), EnableReflection)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		Expect(result.Status.Reflections).To(HaveLen(1))
+		Expect(result.LastMessage().Content).To(Equal("Here is the news."))
+	})
+
+	It("keeps aborting on loops when reflection is disabled", func() {
+		mockTool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(mockTool, "first result")
+
+		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "news"}`)
+		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "news"}`)
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "What's new?")
+		_, err := ExecuteTools(mockLLM, fragment,
+			WithTools(mockTool), WithIterations(3), WithLoopDetection(1))
+		Expect(err).To(MatchError(ErrLoopDetected))
+	})
+})
@@ -0,0 +1,154 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// RerankResult is the relevance of a document, identified by its index in
+// the documents passed to Rerank.
+type RerankResult struct {
+	Index int
+	Score float64
+}
+
+// Reranker orders retrieved documents by relevance to a query, so the most
+// useful ones are injected into prompts first (or only) when the token
+// budget is tight. NewLLMReranker implements it with the LLM; implement it
+// to use a dedicated reranking model.
+type Reranker interface {
+	// Rerank returns one result per document, sorted by decreasing score.
+	Rerank(ctx context.Context, query string, documents []string) ([]RerankResult, error)
+}
+
+// LLMReranker is a Reranker asking the LLM to score the relevance of each
+// document. See NewLLMReranker.
+type LLMReranker struct {
+	llm LLM
+	o   *Options
+	// MaxDocumentLength caps the characters of each document shown to the
+	// LLM. Defaults to 1000.
+	MaxDocumentLength int
+}
+
+// NewLLMReranker returns a Reranker scoring documents with llm. Options
+// customize the prompt (prompt.PromptRerankType), retries and logging.
+func NewLLMReranker(llm LLM, opts ...Option) *LLMReranker {
+	o := defaultOptions()
+	o.Apply(opts...)
+	return &LLMReranker{llm: withLLMOptions(llm, o), o: o, MaxDocumentLength: 1000}
+}
+
+type documentScore struct {
+	Document int     `json:"document"`
+	Score    float64 `json:"score"`
+}
+
+type documentScores struct {
+	Scores []documentScore `json:"scores"`
+}
+
+type rerankToolRunner struct{}
+
+func (r *rerankToolRunner) Run(args documentScores) (string, any, error) {
+	return "", nil, fmt.Errorf("rerank tool should not be executed")
+}
+
+func (r *rerankToolRunner) NewArgs() *documentScores {
+	return &documentScores{}
+}
+
+// rerankTool asks the LLM to score the relevance of n documents.
+func rerankTool(n int) *ToolDefinition[documentScores] {
+	return &ToolDefinition[documentScores]{
+		ToolRunner:  &rerankToolRunner{},
+		Name:        "score_documents",
+		Description: "Score the relevance of every document to the query.",
+		InputArguments: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"scores": map[string]interface{}{
+					"type":        "array",
+					"description": "One score per document.",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"document": map[string]interface{}{
+								"type":        "integer",
+								"description": "The number of the document.",
+								"minimum":     1,
+								"maximum":     n,
+							},
+							"score": map[string]interface{}{
+								"type":        "number",
+								"description": "The relevance of the document, from 0 (unrelated) to 1 (answers the query).",
+								"minimum":     0,
+								"maximum":     1,
+							},
+						},
+						"required": []string{"document", "score"},
+					},
+				},
+			},
+			"required": []string{"scores"},
+		},
+	}
+}
+
+// Rerank implements Reranker. Documents the LLM does not score get 0.
+func (r *LLMReranker) Rerank(ctx context.Context, query string, documents []string) ([]RerankResult, error) {
+	results := make([]RerankResult, len(documents))
+	for i := range documents {
+		results[i].Index = i
+	}
+	if len(documents) < 2 {
+		return results, nil
+	}
+
+	shown := make([]string, len(documents))
+	for i, d := range documents {
+		shown[i] = truncateChars(d, r.MaxDocumentLength)
+	}
+	rerankPrompt, err := r.o.prompts.GetPrompt(prompt.PromptRerankType).Render(struct {
+		Query     string
+		Documents []string
+	}{
+		Query:     query,
+		Documents: shown,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render rerank prompt: %w", err)
+	}
+
+	tool := rerankTool(len(documents))
+	result, err := decisionWithStreaming(ctx, r.llm,
+		[]openai.ChatCompletionMessage{{Role: UserMessageRole.String(), Content: rerankPrompt}},
+		Tools{tool}, tool.Name, r.o.maxRetries, r.o.streamCallback, r.o.logger, r.o.prompts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rerank documents: %w", err)
+	}
+	if len(result.toolChoices) == 0 {
+		return nil, errors.New("no document scores returned by the LLM")
+	}
+
+	var scores documentScores
+	data, _ := json.Marshal(result.toolChoices[0].Arguments)
+	if err := json.Unmarshal(data, &scores); err != nil {
+		return nil, fmt.Errorf("failed to parse document scores: %w", err)
+	}
+	for _, s := range scores.Scores {
+		if s.Document < 1 || s.Document > len(documents) {
+			r.o.logger.Warn("Ignoring score of unknown document", "document", s.Document)
+			continue
+		}
+		results[s.Document-1].Score = s.Score
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results, nil
+}
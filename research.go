@@ -0,0 +1,256 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+)
+
+// ResearchArgs are the arguments of the research tool.
+type ResearchArgs struct {
+	Topic string `json:"topic" description:"The topic or question to research"`
+}
+
+// ResearchConfig configures the research tool. See NewResearchTool.
+type ResearchConfig struct {
+	// Search is the tool used to search the topic. Its result is scanned for
+	// URLs. Required.
+	Search ToolDefinitionInterface
+	// SearchArgument is the argument of Search receiving the topic.
+	// Defaults to "query".
+	SearchArgument string
+	// Fetch is the tool used to retrieve a page. Without it the search
+	// results themselves are summarized.
+	Fetch ToolDefinitionInterface
+	// FetchArgument is the argument of Fetch receiving the URL. Defaults to
+	// "url".
+	FetchArgument string
+	// MaxPages caps the pages fetched per topic. Defaults to 5.
+	MaxPages int
+	// MaxPageLength caps the characters of each page passed to the summary.
+	// Defaults to 4000.
+	MaxPageLength int
+	// Name is the tool name. Defaults to "research".
+	Name string
+	// Reranker orders the fetched pages by relevance to the topic before
+	// they are summarized. See NewLLMReranker.
+	Reranker Reranker
+	// MaxSources caps the pages passed to the summary, keeping the most
+	// relevant ones when Reranker is set. Defaults to MaxPages.
+	MaxSources int
+}
+
+// ResearchSource is a page used by the research tool, cited by its position
+// (starting at 1) in ResearchResult.Sources.
+type ResearchSource struct {
+	URL     string
+	Content string
+}
+
+// ResearchResult is the ResultData of the research tool.
+type ResearchResult struct {
+	Topic   string
+	Summary string
+	Sources []ResearchSource
+}
+
+// NewResearchTool returns a composite tool that, given a topic, searches it
+// with cfg.Search, fetches the result pages with cfg.Fetch, drops duplicate
+// URLs and pages, and has llm summarize them with numbered citations. It
+// replaces several iterations of search and fetch calls with a single
+// research(topic) call. Options customize the summary prompt
+// (prompt.PromptResearchSummaryType).
+func NewResearchTool(llm LLM, cfg ResearchConfig, opts ...Option) ToolDefinitionInterface {
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	if cfg.SearchArgument == "" {
+		cfg.SearchArgument = "query"
+	}
+	if cfg.FetchArgument == "" {
+		cfg.FetchArgument = "url"
+	}
+	if cfg.MaxPages <= 0 {
+		cfg.MaxPages = 5
+	}
+	if cfg.MaxPageLength <= 0 {
+		cfg.MaxPageLength = 4000
+	}
+	if cfg.Name == "" {
+		cfg.Name = "research"
+	}
+
+	return NewToolDefinition(
+		&researchRunner{llm: llm, cfg: cfg, prompts: o.prompts, logger: o.logger},
+		ResearchArgs{},
+		cfg.Name,
+		"Research a topic: searches the web, reads the most relevant pages and returns a summary with cited sources.",
+	)
+}
+
+type researchRunner struct {
+	llm     LLM
+	cfg     ResearchConfig
+	prompts prompt.PromptMap
+	logger  Logger
+}
+
+func (r *researchRunner) Run(args ResearchArgs) (string, any, error) {
+	return r.RunWithContext(context.Background(), args)
+}
+
+func (r *researchRunner) RunWithContext(ctx context.Context, args ResearchArgs) (string, any, error) {
+	if r.cfg.Search == nil {
+		return "", nil, fmt.Errorf("research tool has no search tool configured")
+	}
+	topic := strings.TrimSpace(args.Topic)
+	if topic == "" {
+		return "", nil, fmt.Errorf("no topic to research")
+	}
+
+	searchResult, _, err := executeTool(ctx, r.cfg.Search, map[string]any{r.cfg.SearchArgument: topic})
+	if err != nil {
+		return "", nil, fmt.Errorf("search failed: %w", err)
+	}
+
+	sources := r.rerankSources(ctx, topic, r.fetchSources(ctx, searchResult))
+	if len(sources) == 0 {
+		// Nothing fetched: summarize the search results themselves
+		sources = []ResearchSource{{URL: r.cfg.Search.Tool().Function.Name + " results", Content: truncateChars(searchResult, r.cfg.MaxPageLength)}}
+	}
+
+	type sourceData struct {
+		Index        int
+		URL, Content string
+	}
+	data := struct {
+		Topic   string
+		Sources []sourceData
+	}{Topic: topic}
+	for i, s := range sources {
+		data.Sources = append(data.Sources, sourceData{i + 1, s.URL, s.Content})
+	}
+
+	p, err := r.prompts.GetPrompt(prompt.PromptResearchSummaryType).Render(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to render research summary prompt: %w", err)
+	}
+	res, err := r.llm.Ask(ctx, NewEmptyFragment().AddMessage(UserMessageRole, p))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to summarize research: %w", err)
+	}
+	if res.LastMessage() == nil {
+		return "", nil, fmt.Errorf("failed to summarize research: empty reply")
+	}
+
+	result := ResearchResult{
+		Topic:   topic,
+		Summary: strings.TrimSpace(res.LastMessage().Content),
+		Sources: sources,
+	}
+
+	var b strings.Builder
+	b.WriteString(result.Summary)
+	b.WriteString("\n\nSources:")
+	for i, s := range sources {
+		b.WriteString(fmt.Sprintf("\n[%d] %s", i+1, s.URL))
+	}
+	return b.String(), result, nil
+}
+
+// fetchSources fetches the distinct pages linked from the search results.
+// Pages that fail to load or repeat an earlier page are skipped.
+func (r *researchRunner) fetchSources(ctx context.Context, searchResult string) []ResearchSource {
+	if r.cfg.Fetch == nil {
+		return nil
+	}
+
+	var sources []ResearchSource
+	seenPages := map[string]bool{}
+	for _, u := range extractURLs(searchResult) {
+		if len(sources) >= r.cfg.MaxPages {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			break
+		}
+		page, _, err := executeTool(ctx, r.cfg.Fetch, map[string]any{r.cfg.FetchArgument: u})
+		if err != nil {
+			r.logger.Warn("Research: failed to fetch page", "url", u, "error", err)
+			continue
+		}
+		page = strings.TrimSpace(page)
+		if page == "" || seenPages[page] {
+			continue
+		}
+		seenPages[page] = true
+		sources = append(sources, ResearchSource{URL: u, Content: truncateChars(page, r.cfg.MaxPageLength)})
+	}
+	return sources
+}
+
+// rerankSources orders sources by relevance to topic with the configured
+// Reranker, and keeps at most MaxSources of them. Reranking errors are
+// logged and the fetch order is kept.
+func (r *researchRunner) rerankSources(ctx context.Context, topic string, sources []ResearchSource) []ResearchSource {
+	if r.cfg.Reranker != nil && len(sources) > 1 {
+		documents := make([]string, len(sources))
+		for i, s := range sources {
+			documents[i] = s.Content
+		}
+		ranked, err := r.cfg.Reranker.Rerank(ctx, topic, documents)
+		if err != nil {
+			r.logger.Warn("Research: failed to rerank pages", "error", err)
+		} else {
+			reordered := make([]ResearchSource, 0, len(sources))
+			for _, res := range ranked {
+				if res.Index >= 0 && res.Index < len(sources) {
+					reordered = append(reordered, sources[res.Index])
+				}
+			}
+			sources = reordered
+		}
+	}
+	if r.cfg.MaxSources > 0 && len(sources) > r.cfg.MaxSources {
+		sources = sources[:r.cfg.MaxSources]
+	}
+	return sources
+}
+
+var urlPattern = regexp.MustCompile(`https?://[^\s"'<>()\[\]{}]+`)
+
+// extractURLs returns the distinct URLs in text, in order. URLs differing only
+// by fragment, host case or a trailing slash are considered the same.
+func extractURLs(text string) []string {
+	var urls []string
+	seen := map[string]bool{}
+	for _, raw := range urlPattern.FindAllString(text, -1) {
+		raw = strings.TrimRight(raw, ".,;:!?")
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" {
+			continue
+		}
+		u.Fragment = ""
+		u.Host = strings.ToLower(u.Host)
+		key := strings.TrimSuffix(u.String(), "/")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		urls = append(urls, raw)
+	}
+	return urls
+}
+
+// truncateChars cuts s to at most n bytes, marking the cut.
+func truncateChars(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "") + "\n[...]"
+}
@@ -0,0 +1,111 @@
+package cogito_test
+
+import (
+	"fmt"
+	"strings"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type searchRunner struct{ result string }
+
+func (s *searchRunner) Run(args map[string]any) (string, any, error) {
+	return s.result, nil, nil
+}
+
+type fetchRunner struct {
+	pages   map[string]string
+	fetched []string
+}
+
+func (f *fetchRunner) Run(args map[string]any) (string, any, error) {
+	u := args["url"].(string)
+	f.fetched = append(f.fetched, u)
+	page, ok := f.pages[u]
+	if !ok {
+		return "", nil, fmt.Errorf("not found")
+	}
+	return page, nil, nil
+}
+
+var _ = Describe("Research tool", func() {
+	It("searches, fetches distinct pages and summarizes them with citations", func() {
+		search := NewToolDefinition[map[string]any](&searchRunner{result: `1. Go 1.24 https://go.dev/blog/go1.24.
+2. Same post https://GO.dev/blog/go1.24#intro
+3. Mirror (https://mirror.example.com/go1.24)
+4. Broken https://broken.example.com/`}, map[string]any{"type": "object"}, "search", "Search the web")
+		fetcher := &fetchRunner{pages: map[string]string{
+			"https://go.dev/blog/go1.24":        "Go 1.24 adds generic type aliases.",
+			"https://mirror.example.com/go1.24": "Go 1.24 adds generic type aliases.",
+		}}
+		fetch := NewToolDefinition[map[string]any](fetcher, map[string]any{"type": "object"}, "fetch", "Fetch a page")
+
+		llm := cogitotest.NewMockLLM()
+		llm.SetAskResponse("Go 1.24 supports generic type aliases [1].")
+
+		tool := NewResearchTool(llm, ResearchConfig{Search: search, Fetch: fetch})
+		Expect(tool.Tool().Function.Name).To(Equal("research"))
+
+		text, data, err := tool.Execute(map[string]any{"topic": "What is new in Go 1.24?"})
+		Expect(err).ToNot(HaveOccurred())
+
+		// Duplicate URLs are fetched once; duplicate pages and failures are dropped
+		Expect(fetcher.fetched).To(Equal([]string{
+			"https://go.dev/blog/go1.24",
+			"https://mirror.example.com/go1.24",
+			"https://broken.example.com/",
+		}))
+		result := data.(ResearchResult)
+		Expect(result.Sources).To(HaveLen(1))
+		Expect(result.Summary).To(Equal("Go 1.24 supports generic type aliases [1]."))
+		Expect(text).To(HaveSuffix("Sources:\n[1] https://go.dev/blog/go1.24"))
+
+		Expect(llm.FragmentHistory).To(HaveLen(1))
+		summaryPrompt := llm.FragmentHistory[0].Messages[0].Content
+		Expect(summaryPrompt).To(ContainSubstring("What is new in Go 1.24?"))
+		Expect(summaryPrompt).To(ContainSubstring("[1] https://go.dev/blog/go1.24\nGo 1.24 adds generic type aliases."))
+	})
+
+	It("summarizes the search results when there is nothing to fetch", func() {
+		search := NewToolDefinition[map[string]any](&searchRunner{result: "No links, but Go 1.24 was released in February."},
+			map[string]any{"type": "object"}, "search", "Search the web")
+		llm := cogitotest.NewMockLLM()
+		llm.SetAskResponse("Go 1.24 was released in February [1].")
+
+		text, _, err := NewResearchTool(llm, ResearchConfig{Search: search}).Execute(map[string]any{"topic": "Go 1.24"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(text).To(ContainSubstring("[1] search results"))
+		Expect(strings.Count(llm.FragmentHistory[0].Messages[0].Content, "released in February")).To(Equal(1))
+	})
+
+	It("keeps the most relevant pages when a reranker is set", func() {
+		search := NewToolDefinition[map[string]any](&searchRunner{result: "https://a.example.com https://b.example.com https://c.example.com"},
+			map[string]any{"type": "object"}, "search", "Search the web")
+		fetch := NewToolDefinition[map[string]any](&fetchRunner{pages: map[string]string{
+			"https://a.example.com": "Go gophers are cute.",
+			"https://b.example.com": "Go 1.24 adds generic type aliases.",
+			"https://c.example.com": "Go 1.24 was released in February.",
+		}}, map[string]any{"type": "object"}, "fetch", "Fetch a page")
+
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("score_documents",
+			`{"scores": [{"document": 1, "score": 0.1}, {"document": 2, "score": 0.9}, {"document": 3, "score": 0.6}]}`)
+		llm.SetAskResponse("Go 1.24 adds generic type aliases [1] and was released in February [2].")
+
+		tool := NewResearchTool(llm, ResearchConfig{Search: search, Fetch: fetch, Reranker: NewLLMReranker(llm), MaxSources: 2})
+		text, data, err := tool.Execute(map[string]any{"topic": "What is new in Go 1.24?"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(data.(ResearchResult).Sources).To(Equal([]ResearchSource{
+			{URL: "https://b.example.com", Content: "Go 1.24 adds generic type aliases."},
+			{URL: "https://c.example.com", Content: "Go 1.24 was released in February."},
+		}))
+		Expect(text).To(HaveSuffix("[1] https://b.example.com\n[2] https://c.example.com"))
+
+		rerankPrompt := llm.RequestHistory[0].Messages[0].Content
+		Expect(rerankPrompt).To(And(ContainSubstring("Query: What is new in Go 1.24?"), ContainSubstring("[3]\nGo 1.24 was released in February.")))
+	})
+})
@@ -0,0 +1,109 @@
+package cogito
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// RubricCriterion is a dimension ContentReview scores the content on.
+type RubricCriterion struct {
+	Name        string
+	Description string
+}
+
+// DefaultRubric is the rubric used by WithReviewRubric when no criteria are given.
+var DefaultRubric = []RubricCriterion{
+	{Name: "accuracy", Description: "The answer is factually correct and consistent with the conversation."},
+	{Name: "completeness", Description: "The answer covers everything the user asked for."},
+	{Name: "style", Description: "The answer is clear, well organized and concise."},
+}
+
+// ReviewScore holds the rubric scores of one ContentReview iteration.
+type ReviewScore struct {
+	Iteration int
+	Scores    map[string]float64 // score of each criterion, from 0 to 10
+	Overall   float64            // average of Scores
+}
+
+type rubricToolRunner struct{}
+
+func (r *rubricToolRunner) Run(args map[string]float64) (string, any, error) {
+	return "", nil, fmt.Errorf("rubric tool should not be executed")
+}
+
+func (r *rubricToolRunner) NewArgs() *map[string]float64 {
+	return &map[string]float64{}
+}
+
+// rubricTool asks the LLM to score the content on each criterion.
+func rubricTool(criteria []RubricCriterion) *ToolDefinition[map[string]float64] {
+	properties := map[string]interface{}{}
+	required := make([]string, 0, len(criteria))
+	for _, c := range criteria {
+		properties[c.Name] = map[string]interface{}{
+			"type":        "number",
+			"description": c.Description,
+			"minimum":     0,
+			"maximum":     10,
+		}
+		required = append(required, c.Name)
+	}
+	return &ToolDefinition[map[string]float64]{
+		ToolRunner:  &rubricToolRunner{},
+		Name:        "score_content",
+		Description: "Score the answer on each criterion, from 0 to 10.",
+		InputArguments: map[string]interface{}{
+			"type":       "object",
+			"properties": properties,
+			"required":   required,
+		},
+	}
+}
+
+// scoreContent asks the LLM to score content, the answer to the conversation
+// in f, against the rubric of o.
+func scoreContent(o *Options, llm LLM, f Fragment, content string, iteration int) (ReviewScore, error) {
+	rubricPrompt, err := o.prompts.GetPrompt(prompt.PromptReviewRubricType).Render(struct {
+		Content  string
+		Criteria []RubricCriterion
+	}{
+		Content:  content,
+		Criteria: o.reviewRubric,
+	})
+	if err != nil {
+		return ReviewScore{}, fmt.Errorf("failed to render review rubric prompt: %w", err)
+	}
+
+	tool := rubricTool(o.reviewRubric)
+	result, err := decisionWithStreaming(o.context, llm,
+		append(slices.Clone(f.Messages), openai.ChatCompletionMessage{
+			Role:    SystemMessageRole.String(),
+			Content: rubricPrompt,
+		}),
+		Tools{tool}, tool.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
+	if err != nil {
+		return ReviewScore{}, fmt.Errorf("failed to score content: %w", err)
+	}
+	if len(result.toolChoices) == 0 {
+		return ReviewScore{}, errors.New("no scores returned by the LLM")
+	}
+
+	scores := map[string]float64{}
+	data, _ := json.Marshal(result.toolChoices[0].Arguments)
+	if err := json.Unmarshal(data, &scores); err != nil {
+		return ReviewScore{}, fmt.Errorf("failed to parse content scores: %w", err)
+	}
+
+	score := ReviewScore{Iteration: iteration, Scores: map[string]float64{}}
+	for _, c := range o.reviewRubric {
+		score.Scores[c.Name] = scores[c.Name]
+		score.Overall += scores[c.Name]
+	}
+	score.Overall /= float64(len(o.reviewRubric))
+	return score, nil
+}
@@ -5,13 +5,13 @@ import (
 	"fmt"
 
 	"github.com/mudler/cogito/prompt"
-	"github.com/mudler/xlog"
 )
 
 // ContentReview refines an LLM response until for a fixed number of iterations or if the LLM doesn't find anymore gaps
 func ContentReview(llm LLM, originalFragment Fragment, opts ...Option) (Fragment, error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
 
 	gaps := []string{}
 
@@ -23,9 +23,9 @@ func ContentReview(llm LLM, originalFragment Fragment, opts ...Option) (Fragment
 		var err error
 		originalFragment.Status.Iterations = i + 1
 
-		xlog.Debug("Refined message", "refinedMessage", refinedMessage, "iteration", i+1)
+		o.logger.Debug("Refined message", "refinedMessage", refinedMessage, "iteration", i+1)
 
-		if len(o.tools) > 0 {
+		if len(o.localTools()) > 0 {
 			f, err = ExecuteTools(llm, f, append([]Option{WithGaps(gaps...)}, opts...)...)
 			if err != nil && !errors.Is(err, ErrNoToolSelected) {
 				return Fragment{}, fmt.Errorf("failed to execute tools in iteration %d: %w", i+1, err)
@@ -36,6 +36,21 @@ func ContentReview(llm LLM, originalFragment Fragment, opts ...Option) (Fragment
 			originalFragment.Status.Plans = f.Status.Plans
 		}
 
+		// Score the content against the rubric, if any
+		if len(o.reviewRubric) > 0 {
+			score, err := scoreContent(o, llm, f, reviewedContent(f, refinedMessage), i+1)
+			if err != nil {
+				o.logger.Warn("Failed to score content", "iteration", i+1, "error", err)
+			} else {
+				originalFragment.Status.ReviewScores = append(originalFragment.Status.ReviewScores, score)
+				o.logger.Debug("Content scored", "iteration", i+1, "scores", score.Scores, "overall", score.Overall)
+				if score.Overall >= o.reviewThreshold {
+					o.logger.Debug("Score threshold reached, stop!", "threshold", o.reviewThreshold)
+					break
+				}
+			}
+		}
+
 		// Analyze knowledge gaps
 		gaps, err = ExtractKnowledgeGaps(llm, f, opts...)
 		if err != nil {
@@ -44,11 +59,11 @@ func ContentReview(llm LLM, originalFragment Fragment, opts ...Option) (Fragment
 
 		// If no gaps found, we're done
 		if len(gaps) == 0 {
-			xlog.Debug("No gaps found, stop!")
+			o.logger.Debug("No gaps found, stop!")
 			break
 		}
 
-		xlog.Debug("Knowledge gaps identified", "iteration", i+1, "gaps", gaps)
+		o.logger.Debug("Knowledge gaps identified", "iteration", i+1, "gaps", gaps)
 
 		// Generate improved content based on gaps
 		improvedContent, err := improveContent(llm, f, refinedMessage, gaps, o)
@@ -57,10 +72,19 @@ func ContentReview(llm LLM, originalFragment Fragment, opts ...Option) (Fragment
 		}
 		refinedMessage = improvedContent.LastMessage().Content
 		o.statusCallback(improvedContent.LastMessage().Content)
-		xlog.Debug("Improved content generated", "iteration", i+1)
+		o.logger.Debug("Improved content generated", "iteration", i+1)
 	}
 
-	return originalFragment.AddMessage(AssistantMessageRole, refinedMessage), nil
+	return originalFragment.AddMessage(AssistantMessageRole, reviewedContent(f, refinedMessage)), nil
+}
+
+// reviewedContent returns the content under review: the last refinement, or
+// the last message of f before any refinement.
+func reviewedContent(f Fragment, refinedMessage string) string {
+	if refinedMessage != "" {
+		return refinedMessage
+	}
+	return f.LastMessage().Content
 }
 
 func improveContent(llm LLM, f Fragment, refinedMessage string, gaps []string, o *Options) (Fragment, error) {
@@ -72,7 +96,7 @@ func improveContent(llm LLM, f Fragment, refinedMessage string, gaps []string, o
 		Gaps              []string
 		RefinedMessage    string
 	}{
-		Context:        f.String(),
+		Context:        conversationContext(o, prompt.ContentImproverType, f),
 		Gaps:           gaps,
 		RefinedMessage: refinedMessage,
 	}
@@ -93,7 +117,7 @@ func improveContent(llm LLM, f Fragment, refinedMessage string, gaps []string, o
 	newFragment := NewEmptyFragment().
 		AddMessage("user", p)
 
-	xlog.Debug("Improving content", "prompt", p)
+	o.logger.Debug("Improving content", "prompt", p)
 
 	newFragment.ParentFragment = f.ParentFragment
 
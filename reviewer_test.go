@@ -4,18 +4,18 @@ import (
 	"fmt"
 
 	. "github.com/mudler/cogito"
-	"github.com/mudler/cogito/tests/mock"
+	"github.com/mudler/cogito/cogitotest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
 )
 
 var _ = Describe("ContentReview", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 	var originalFragment Fragment
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 		originalFragment = NewEmptyFragment().
 			AddMessage(UserMessageRole, "What is photosynthesis?").
 			AddMessage(AssistantMessageRole, "Photosynthesis is the process by which plants convert sunlight into energy.")
@@ -23,11 +23,11 @@ var _ = Describe("ContentReview", func() {
 
 	Context("ContentReview with tools", func() {
 		It("should execute tools when provided", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// First iteration - tool selection and execution
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "chlorophyll"}`)
-			mock.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
+			cogitotest.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
 
 			// After tool execution, ToolReEvaluator (toolSelection) returns no tool (text response)
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -52,7 +52,7 @@ var _ = Describe("ContentReview", func() {
 
 			// Second iteration - tool selection and execution
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "why chlorophyll is green"}`)
-			mock.SetRunResult(mockTool, "Chlorophyll is green because it absorbs blue and red light and reflects green light.")
+			cogitotest.SetRunResult(mockTool, "Chlorophyll is green because it absorbs blue and red light and reflects green light.")
 
 			// After second tool execution, ToolReEvaluator (toolSelection) returns no tool (text response)
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -129,4 +129,39 @@ var _ = Describe("ContentReview", func() {
 			Expect(result.Status.ToolResults[1].Result).To(Equal("Chlorophyll is green because it absorbs blue and red light and reflects green light."))
 		})
 	})
+
+	Context("ContentReview with a rubric", func() {
+		It("should stop once the score threshold is reached", func() {
+			// First iteration: below the threshold, gaps are addressed
+			mockLLM.AddCreateChatCompletionFunction("score_content", `{"accuracy": 6, "completeness": 4, "style": 8}`)
+			mockLLM.SetAskResponse("The answer does not mention chlorophyll.")
+			mockLLM.AddCreateChatCompletionFunction("json", `{"gaps": ["Chlorophyll is not mentioned"]}`)
+			mockLLM.SetAskResponse("Photosynthesis is the process by which plants use chlorophyll to convert sunlight into energy.")
+
+			// Second iteration: the threshold is reached
+			mockLLM.AddCreateChatCompletionFunction("score_content", `{"accuracy": 9, "completeness": 8, "style": 8}`)
+
+			result, err := ContentReview(mockLLM, originalFragment, WithIterations(3), WithReviewRubric(8))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LastMessage().Content).To(ContainSubstring("chlorophyll"))
+			Expect(mockLLM.FragmentHistory).To(HaveLen(2))
+
+			Expect(result.Status.ReviewScores).To(HaveLen(2))
+			Expect(result.Status.ReviewScores[0].Iteration).To(Equal(1))
+			Expect(result.Status.ReviewScores[0].Overall).To(Equal(6.0))
+			Expect(result.Status.ReviewScores[1].Scores).To(Equal(map[string]float64{"accuracy": 9, "completeness": 8, "style": 8}))
+			Expect(result.Status.ReviewScores[1].Overall).To(BeNumerically(">=", 8))
+		})
+
+		It("should keep the content when it already meets the threshold", func() {
+			mockLLM.AddCreateChatCompletionFunction("score_content", `{"clarity": 9}`)
+
+			result, err := ContentReview(mockLLM, originalFragment, WithIterations(3),
+				WithReviewRubric(7, RubricCriterion{Name: "clarity", Description: "Easy to understand"}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LastMessage().Content).To(Equal("Photosynthesis is the process by which plants convert sunlight into energy."))
+			Expect(mockLLM.FragmentHistory).To(BeEmpty())
+			Expect(result.Status.ReviewScores).To(HaveLen(1))
+		})
+	})
 })
@@ -0,0 +1,102 @@
+package cogito
+
+import "context"
+
+type (
+	runIDKey     struct{}
+	sessionIDKey struct{}
+	traceIDKey   struct{}
+	callerKey    struct{}
+	tenantKey    struct{}
+	toolCallKey  struct{}
+)
+
+// ContextWithRunID returns a context carrying the ID of a run. Pass it with
+// WithContext (or the *Context variants of the primitives) so tools can read
+// it with RunIDFromContext or ToolCallFromContext.
+func ContextWithRunID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runIDKey{}, id)
+}
+
+// RunIDFromContext returns the run ID carried by ctx, or "".
+func RunIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(runIDKey{}).(string)
+	return id
+}
+
+// ContextWithSessionID returns a context carrying the ID of the session
+// (e.g. the conversation) a run belongs to.
+func ContextWithSessionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, sessionIDKey{}, id)
+}
+
+// SessionIDFromContext returns the session ID carried by ctx, or "".
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey{}).(string)
+	return id
+}
+
+// ContextWithTraceID returns a context carrying a trace ID, to correlate the
+// tool calls of a run with the caller's tracing.
+func ContextWithTraceID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, traceIDKey{}, id)
+}
+
+// TraceIDFromContext returns the trace ID carried by ctx, or "".
+func TraceIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey{}).(string)
+	return id
+}
+
+// ContextWithCaller returns a context carrying the identity of the caller a
+// run acts for, such as a user or service account, for policies (see
+// WithPolicy).
+func ContextWithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+// CallerFromContext returns the caller carried by ctx, or "".
+func CallerFromContext(ctx context.Context) string {
+	caller, _ := ctx.Value(callerKey{}).(string)
+	return caller
+}
+
+// ContextWithTenant returns a context carrying the tenant a run belongs to,
+// when one service hosts agents for many customers, see WithTenants.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx, or "".
+func TenantFromContext(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// ToolCallInfo describes the tool call being executed. Tools receive it in
+// their execution context, see ToolCallFromContext.
+type ToolCallInfo struct {
+	Choice    ToolChoice // name, arguments, ID and reasoning of the call
+	Iteration int        // iteration of ExecuteTools the call belongs to, from 1
+	Attempt   int        // attempt number, from 1 (see WithMaxAttempts)
+	RunID     string     // see ContextWithRunID
+	SessionID string     // see ContextWithSessionID
+	TraceID   string     // see ContextWithTraceID
+}
+
+// ToolCallFromContext returns the tool call a tool is executing for. It is
+// only available to tools implementing ContextTool or ToolWithContext, when
+// run by ExecuteTools.
+func ToolCallFromContext(ctx context.Context) (ToolCallInfo, bool) {
+	call, ok := ctx.Value(toolCallKey{}).(ToolCallInfo)
+	return call, ok
+}
+
+// contextWithToolCall returns ctx carrying call, completed with the run
+// values of ctx.
+func contextWithToolCall(ctx context.Context, call ToolCallInfo) context.Context {
+	call.RunID = RunIDFromContext(ctx)
+	call.SessionID = SessionIDFromContext(ctx)
+	call.TraceID = TraceIDFromContext(ctx)
+	return context.WithValue(ctx, toolCallKey{}, call)
+}
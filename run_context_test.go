@@ -0,0 +1,71 @@
+package cogito_test
+
+import (
+	"context"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type lookupArgs struct {
+	Query string `json:"query"`
+}
+
+// callRecorder records the tool call found in the execution context.
+type callRecorder struct {
+	calls []ToolCallInfo
+}
+
+func (r *callRecorder) Run(args lookupArgs) (string, any, error) {
+	return "no context", nil, nil
+}
+
+func (r *callRecorder) RunWithContext(ctx context.Context, args lookupArgs) (string, any, error) {
+	call, ok := ToolCallFromContext(ctx)
+	if !ok {
+		return "no tool call in context", nil, nil
+	}
+	r.calls = append(r.calls, call)
+	return "found " + args.Query, nil, nil
+}
+
+var _ = Describe("Run context", func() {
+	It("gives tools the current tool call and the run values", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+				Role: AssistantMessageRole.String(),
+				ToolCalls: []openai.ToolCall{{
+					ID:       "call-1",
+					Type:     openai.ToolTypeFunction,
+					Function: openai.FunctionCall{Name: "lookup", Arguments: `{"query": "cogito"}`},
+				}},
+			}}},
+		})
+		mockLLM.SetAskResponse("Found it.")
+
+		recorder := &callRecorder{}
+		tool := NewToolDefinition[lookupArgs](recorder, lookupArgs{}, "lookup", "Look something up")
+
+		ctx := ContextWithTraceID(ContextWithSessionID(ContextWithRunID(context.Background(), "run-1"), "session-1"), "trace-1")
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Look up cogito")
+		result, err := ExecuteToolsContext(ctx, mockLLM, fragment, WithTools(tool))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Status.ToolResults[0].Result).To(Equal("found cogito"))
+
+		Expect(recorder.calls).To(HaveLen(1))
+		call := recorder.calls[0]
+		Expect(call.Choice.Name).To(Equal("lookup"))
+		Expect(call.Choice.ID).ToNot(BeEmpty())
+		Expect(call.Choice.ID).To(Equal(result.Status.ToolResults[0].ToolArguments.ID))
+		Expect(call.Choice.Arguments).To(HaveKeyWithValue("query", "cogito"))
+		Expect(call.Iteration).To(Equal(1))
+		Expect(call.Attempt).To(Equal(1))
+		Expect(call.RunID).To(Equal("run-1"))
+		Expect(call.SessionID).To(Equal("session-1"))
+		Expect(call.TraceID).To(Equal("trace-1"))
+	})
+})
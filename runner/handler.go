@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/mudler/cogito"
+)
+
+// Handler serves the runner over HTTP:
+//
+//	POST   /runs              submit a run on the fragment in the body, answers 202 with {"id": ...}
+//	GET    /runs/{id}         status of the run
+//	GET    /runs/{id}/result  conversation of the finished run (409 while running)
+//	DELETE /runs/{id}         cancel the run
+//
+// Mount it under a prefix with http.StripPrefix. Stream the events of runs
+// with the events handlers, see SetStream.
+func (r *Runner) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /runs", r.submit)
+	mux.HandleFunc("GET /runs/{id}", r.status)
+	mux.HandleFunc("GET /runs/{id}/result", r.result)
+	mux.HandleFunc("DELETE /runs/{id}", r.cancel)
+	return mux
+}
+
+func (r *Runner) submit(w http.ResponseWriter, req *http.Request) {
+	var f cogito.Fragment
+	if err := json.NewDecoder(req.Body).Decode(&f); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid fragment: "+err.Error())
+		return
+	}
+	id, err := r.Submit(req.Context(), f)
+	if err != nil {
+		r.logError("Failed to submit run", "error", err)
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Location", req.URL.Path+"/"+id)
+	writeJSON(w, http.StatusAccepted, map[string]string{"id": id})
+}
+
+func (r *Runner) status(w http.ResponseWriter, req *http.Request) {
+	run, err := r.Status(req.Context(), req.PathValue("id"))
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	// The conversation is served by the result endpoint
+	run.Result = nil
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (r *Runner) result(w http.ResponseWriter, req *http.Request) {
+	run, err := r.Status(req.Context(), req.PathValue("id"))
+	if err != nil {
+		writeRunError(w, err)
+		return
+	}
+	if !run.State.Finished() {
+		writeRunError(w, ErrNotFinished)
+		return
+	}
+	if run.State != StateSucceeded {
+		writeError(w, http.StatusConflict, "run "+string(run.State)+": "+run.Error)
+		return
+	}
+	writeJSON(w, http.StatusOK, run.Result)
+}
+
+func (r *Runner) cancel(w http.ResponseWriter, req *http.Request) {
+	if err := r.Cancel(req.Context(), req.PathValue("id")); err != nil {
+		writeRunError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func writeRunError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, ErrNotFinished), errors.Is(err, ErrFinished):
+		writeError(w, http.StatusConflict, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]any{"error": message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
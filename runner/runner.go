@@ -0,0 +1,251 @@
+// Package runner executes agent runs in the background: Submit returns a run
+// ID at once, and the status and result of the run are polled with it, so
+// services can expose long agent runs over HTTP without holding connections
+// open. Runs are persisted in a Store, and their events can be streamed with
+// the events package.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/events"
+	"github.com/mudler/xlog"
+)
+
+var (
+	// ErrNotFound is returned for unknown run IDs.
+	ErrNotFound = errors.New("run not found")
+	// ErrNotFinished is returned by Result for runs still pending or running.
+	ErrNotFinished = errors.New("run not finished")
+	// ErrFinished is returned by Cancel for runs already over.
+	ErrFinished = errors.New("run already finished")
+)
+
+// State is the state of a run.
+type State string
+
+const (
+	StatePending   State = "pending" // waiting for a free slot, see SetMaxConcurrent
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+// Finished reports whether the run is over.
+func (s State) Finished() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCancelled
+}
+
+// Run is the status of a run, as persisted in the Store.
+type Run struct {
+	ID        string           `json:"id"`
+	State     State            `json:"state"`
+	Error     string           `json:"error,omitempty"`
+	Submitted time.Time        `json:"submitted"`
+	Started   time.Time        `json:"started,omitzero"`
+	Finished  time.Time        `json:"finished,omitzero"`
+	Usage     cogito.LLMUsage  `json:"usage"`
+	Result    *cogito.Fragment `json:"result,omitempty"` // set once succeeded
+}
+
+// Runner runs cogito.ExecuteTools in the background.
+type Runner struct {
+	llm    cogito.LLM
+	opts   []cogito.Option
+	store  Store
+	stream *events.Stream
+	logger cogito.Logger
+	slots  chan struct{}
+
+	mu      sync.Mutex
+	cancels map[string]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// New returns a runner executing cogito.ExecuteTools with llm and opts, and
+// the options given to Submit. Runs are kept in a MemoryStore unless
+// SetStore is called.
+func New(llm cogito.LLM, opts ...cogito.Option) *Runner {
+	return &Runner{
+		llm:     llm,
+		opts:    opts,
+		store:   NewMemoryStore(),
+		cancels: map[string]context.CancelFunc{},
+	}
+}
+
+// SetStore persists the runs in store.
+func (r *Runner) SetStore(store Store) {
+	r.store = store
+}
+
+// SetStream publishes the events of every run to stream, under the run ID.
+// Serve it with events.SSEHandler or events.WebSocketHandler.
+func (r *Runner) SetStream(stream *events.Stream) {
+	r.stream = stream
+}
+
+// SetMaxConcurrent limits the number of runs executing at once; other runs
+// stay pending until a slot is free. Unlimited by default.
+func (r *Runner) SetMaxConcurrent(n int) {
+	r.slots = nil
+	if n > 0 {
+		r.slots = make(chan struct{}, n)
+	}
+}
+
+// SetLogger sends the runner's log output (failed runs and store errors) to
+// l instead of the global xlog logger.
+func (r *Runner) SetLogger(l cogito.Logger) {
+	r.logger = l
+}
+
+func (r *Runner) logError(msg string, args ...any) {
+	if r.logger == nil {
+		xlog.Error(msg, args...)
+		return
+	}
+	r.logger.Error(msg, args...)
+}
+
+// Submit starts a run on f in the background and returns its ID. The run
+// carries the values of ctx (see cogito.ContextWithTenant,
+// cogito.ContextWithCaller) but not its cancellation: use Cancel to stop it.
+// The run ID is set on its context, see cogito.RunIDFromContext.
+func (r *Runner) Submit(ctx context.Context, f cogito.Fragment, opts ...cogito.Option) (string, error) {
+	run := Run{ID: uuid.NewString(), State: StatePending, Submitted: time.Now()}
+	if err := r.store.Save(ctx, run); err != nil {
+		return "", fmt.Errorf("failed to save run: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(cogito.ContextWithRunID(context.WithoutCancel(ctx), run.ID))
+	r.mu.Lock()
+	r.cancels[run.ID] = cancel
+	r.mu.Unlock()
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+		r.execute(runCtx, run, f, opts)
+	}()
+	return run.ID, nil
+}
+
+func (r *Runner) execute(ctx context.Context, run Run, f cogito.Fragment, opts []cogito.Option) {
+	defer func() {
+		r.mu.Lock()
+		delete(r.cancels, run.ID)
+		r.mu.Unlock()
+	}()
+
+	if r.slots != nil {
+		select {
+		case r.slots <- struct{}{}:
+			defer func() { <-r.slots }()
+		case <-ctx.Done():
+			r.finish(run, cogito.Fragment{}, ctx.Err())
+			return
+		}
+	}
+
+	run.State = StateRunning
+	run.Started = time.Now()
+	r.save(run)
+
+	runOpts := append(append([]cogito.Option{}, r.opts...), opts...)
+	if r.stream != nil {
+		runOpts = append(runOpts, cogito.WithStreamCallback(r.stream.Callback(run.ID)))
+	}
+	result, err := cogito.ExecuteToolsAndAnswer(ctx, r.llm, f, runOpts...)
+	r.finish(run, result, err)
+}
+
+// finish records the outcome of run.
+func (r *Runner) finish(run Run, result cogito.Fragment, err error) {
+	run.Finished = time.Now()
+	if result.Status != nil {
+		run.Usage = result.Status.CumulativeUsage
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		run.State = StateCancelled
+		run.Error = err.Error()
+	case err != nil:
+		r.logError("Run failed", "run", run.ID, "error", err)
+		run.State = StateFailed
+		run.Error = err.Error()
+	default:
+		run.State = StateSucceeded
+		run.Result = &result
+	}
+	r.save(run)
+	if r.stream != nil {
+		r.stream.End(run.ID, err)
+	}
+}
+
+func (r *Runner) save(run Run) {
+	if err := r.store.Save(context.Background(), run); err != nil {
+		r.logError("Failed to save run", "run", run.ID, "error", err)
+	}
+}
+
+// Status returns the status of the run id, or ErrNotFound.
+func (r *Runner) Status(ctx context.Context, id string) (Run, error) {
+	run, ok, err := r.store.Load(ctx, id)
+	if err != nil {
+		return Run{}, fmt.Errorf("failed to load run: %w", err)
+	}
+	if !ok {
+		return Run{}, ErrNotFound
+	}
+	return run, nil
+}
+
+// Result returns the conversation of the run id once it succeeded. It returns
+// ErrNotFinished while the run is pending or running, and the error of the run
+// when it failed or was cancelled.
+func (r *Runner) Result(ctx context.Context, id string) (cogito.Fragment, error) {
+	run, err := r.Status(ctx, id)
+	if err != nil {
+		return cogito.Fragment{}, err
+	}
+	switch {
+	case !run.State.Finished():
+		return cogito.Fragment{}, ErrNotFinished
+	case run.State != StateSucceeded:
+		return cogito.Fragment{}, fmt.Errorf("run %s: %s", run.State, run.Error)
+	case run.Result == nil:
+		return cogito.Fragment{}, nil
+	}
+	return *run.Result, nil
+}
+
+// Cancel stops the run id. It returns ErrFinished when the run is over, and
+// ErrNotFound for unknown runs.
+func (r *Runner) Cancel(ctx context.Context, id string) error {
+	r.mu.Lock()
+	cancel, ok := r.cancels[id]
+	r.mu.Unlock()
+	if ok {
+		cancel()
+		return nil
+	}
+	if _, err := r.Status(ctx, id); err != nil {
+		return err
+	}
+	return ErrFinished
+}
+
+// Wait blocks until the runs submitted so far are over.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
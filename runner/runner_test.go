@@ -0,0 +1,194 @@
+package runner_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	"github.com/mudler/cogito/runner"
+	"github.com/sashabaranov/go-openai"
+)
+
+// blockingLLM blocks every call until its context is done.
+type blockingLLM struct {
+	cogito.LLM
+	started chan struct{}
+}
+
+func (b *blockingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (cogito.LLMReply, cogito.LLMUsage, error) {
+	close(b.started)
+	<-ctx.Done()
+	return cogito.LLMReply{}, cogito.LLMUsage{}, ctx.Err()
+}
+
+func question() cogito.Fragment {
+	return cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "What is the capital of Italy?")
+}
+
+func TestSubmitRunsInTheBackground(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("search", "Search for information")
+	cogitotest.SetRunResult(tool, "Rome is the capital of Italy")
+	llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+	llm.SetAskResponse("The capital of Italy is Rome.")
+
+	store, err := runner.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runner.New(llm, cogito.WithTools(tool), cogito.WithIterations(1))
+	r.SetStore(store)
+
+	id, err := r.Submit(context.Background(), question())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	run, err := r.Status(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.State != runner.StateSucceeded || run.Finished.IsZero() {
+		t.Fatalf("run = %+v", run)
+	}
+	result, err := r.Result(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := result.LastMessage(); last == nil || last.Content != "The capital of Italy is Rome." {
+		t.Fatalf("last message = %+v", last)
+	}
+	if len(cogitotest.GetMockTool(tool).Calls()) != 1 {
+		t.Fatal("tool not called")
+	}
+
+	if _, err := r.Status(context.Background(), "missing"); !errors.Is(err, runner.ErrNotFound) {
+		t.Fatalf("Status(missing) error = %v", err)
+	}
+}
+
+func TestFileStoreKeepsTheImagesOfTheResult(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{
+			{Message: openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "A cat."}},
+		},
+	})
+
+	dir := t.TempDir()
+	store, err := runner.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := runner.New(llm, cogito.WithIterations(1))
+	r.SetStore(store)
+
+	photo := cogito.NewImageFromBytes([]byte("\x89PNG\r\n\x1a\ncat"), "image/png")
+	id, err := r.Submit(context.Background(), cogito.NewEmptyFragment().AddMessage(cogito.UserMessageRole, "What is in this photo?", photo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	// A new runner over the same directory, as after a restart
+	reopened, err := runner.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restarted := runner.New(llm)
+	restarted.SetStore(reopened)
+	result, err := restarted.Result(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Multimedia) != 1 || result.Multimedia[0].URL() != photo.URL() {
+		t.Fatalf("multimedia = %+v", result.Multimedia)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	llm := &blockingLLM{LLM: cogitotest.NewMockLLM(), started: make(chan struct{})}
+	r := runner.New(llm)
+	id, err := r.Submit(context.Background(), question())
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-llm.started
+
+	if _, err := r.Result(context.Background(), id); !errors.Is(err, runner.ErrNotFinished) {
+		t.Fatalf("Result of a running run error = %v", err)
+	}
+	if err := r.Cancel(context.Background(), id); err != nil {
+		t.Fatal(err)
+	}
+	r.Wait()
+
+	run, _ := r.Status(context.Background(), id)
+	if run.State != runner.StateCancelled {
+		t.Fatalf("state = %s, want cancelled", run.State)
+	}
+	if err := r.Cancel(context.Background(), id); !errors.Is(err, runner.ErrFinished) {
+		t.Fatalf("Cancel of a finished run error = %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+		Message: openai.ChatCompletionMessage{Role: "assistant", Content: "Rome."},
+	}}})
+	r := runner.New(llm)
+	ts := httptest.NewServer(r.Handler())
+	defer ts.Close()
+
+	body, _ := json.Marshal(question())
+	resp, err := http.Post(ts.URL+"/runs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var submitted struct{ ID string }
+	json.NewDecoder(resp.Body).Decode(&submitted)
+	if resp.StatusCode != http.StatusAccepted || submitted.ID == "" {
+		t.Fatalf("POST /runs = %d, id %q", resp.StatusCode, submitted.ID)
+	}
+	r.Wait()
+
+	resp, err = http.Get(ts.URL + "/runs/" + submitted.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var run runner.Run
+	json.NewDecoder(resp.Body).Decode(&run)
+	if run.State != runner.StateSucceeded || run.Result != nil {
+		t.Fatalf("GET /runs/{id} = %+v", run)
+	}
+
+	resp, err = http.Get(ts.URL + "/runs/" + submitted.ID + "/result")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var result cogito.Fragment
+	json.NewDecoder(resp.Body).Decode(&result)
+	if last := result.LastMessage(); last == nil || last.Content != "Rome." {
+		t.Fatalf("GET /runs/{id}/result = %+v", result.Messages)
+	}
+
+	resp, err = http.Get(ts.URL + "/runs/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("GET /runs/missing = %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store persists the runs of a Runner. Implement it on a shared database to
+// poll runs from every replica of a service.
+type Store interface {
+	// Save creates or replaces run.
+	Save(ctx context.Context, run Run) error
+	// Load returns the run id, and false when there is none.
+	Load(ctx context.Context, id string) (Run, bool, error)
+}
+
+// MemoryStore keeps runs in memory. Safe for concurrent use.
+type MemoryStore struct {
+	mu   sync.Mutex
+	runs map[string]Run
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{runs: map[string]Run{}}
+}
+
+func (s *MemoryStore) Save(ctx context.Context, run Run) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.runs[run.ID] = run
+	return nil
+}
+
+func (s *MemoryStore) Load(ctx context.Context, id string) (Run, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	run, ok := s.runs[id]
+	return run, ok, nil
+}
+
+// FileStore keeps each run in a JSON file named after its ID, in a
+// directory, so runs survive restarts. Runs left pending or running by a
+// restart are not resumed.
+type FileStore struct {
+	dir string
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore returns a store in dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run store: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(id string) string {
+	return filepath.Join(s.dir, filepath.Base(id)+".json")
+}
+
+func (s *FileStore) Save(ctx context.Context, run Run) error {
+	data, err := json.Marshal(run)
+	if err != nil {
+		return fmt.Errorf("failed to serialize run: %w", err)
+	}
+	// Write then rename, so readers never see a partial run
+	tmp := s.path(run.ID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	if err := os.Rename(tmp, s.path(run.ID)); err != nil {
+		return fmt.Errorf("failed to write run: %w", err)
+	}
+	return nil
+}
+
+func (s *FileStore) Load(ctx context.Context, id string) (Run, bool, error) {
+	data, err := os.ReadFile(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return Run{}, false, nil
+	}
+	if err != nil {
+		return Run{}, false, fmt.Errorf("failed to read run: %w", err)
+	}
+	var run Run
+	if err := json.Unmarshal(data, &run); err != nil {
+		return Run{}, false, fmt.Errorf("failed to parse run: %w", err)
+	}
+	return run, true, nil
+}
@@ -0,0 +1,190 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/structures"
+	"github.com/mudler/xlog"
+)
+
+// Job is one run of an agent pipeline. ctx is cancelled when the scheduler
+// stops.
+type Job func(ctx context.Context) (cogito.Fragment, error)
+
+// ToolsJob runs cogito.ExecuteTools on f at every tick. Each run starts again
+// from f.
+func ToolsJob(llm cogito.LLM, f cogito.Fragment, opts ...cogito.Option) Job {
+	return func(ctx context.Context) (cogito.Fragment, error) {
+		return cogito.ExecuteToolsContext(ctx, llm, f, opts...)
+	}
+}
+
+// PlanJob runs cogito.ExecutePlan on f at every tick.
+func PlanJob(llm cogito.LLM, f cogito.Fragment, plan *structures.Plan, goal *structures.Goal, opts ...cogito.Option) Job {
+	return func(ctx context.Context) (cogito.Fragment, error) {
+		return cogito.ExecutePlanContext(ctx, llm, f, plan, goal, opts...)
+	}
+}
+
+// Run is the outcome of one job execution, passed to the callback.
+type Run struct {
+	Name     string
+	Fragment cogito.Fragment
+	Err      error
+	Started  time.Time
+	Finished time.Time
+}
+
+// Schedule returns the next time a job should run after the given time.
+type Schedule interface {
+	Next(after time.Time) time.Time
+}
+
+type interval time.Duration
+
+func (i interval) Next(after time.Time) time.Time { return after.Add(time.Duration(i)) }
+
+// Every runs a job at a fixed interval, the first run being one interval
+// after the scheduler starts. It panics if d is not positive, like
+// time.NewTicker.
+func Every(d time.Duration) Schedule {
+	if d <= 0 {
+		panic(fmt.Sprintf("schedule: non-positive interval %v for Every", d))
+	}
+	return interval(d)
+}
+
+type entry struct {
+	name     string
+	schedule Schedule
+	job      Job
+	callback func(Run)
+}
+
+// Scheduler runs jobs on their schedules. A job is never run concurrently
+// with itself: a tick arriving while the previous run is still in progress
+// is skipped.
+type Scheduler struct {
+	mu      sync.Mutex
+	entries []entry
+	running bool
+	logger  cogito.Logger
+}
+
+func New() *Scheduler {
+	return &Scheduler{}
+}
+
+// SetLogger sends the scheduler's log output (skipped and failed runs) to l
+// instead of the global xlog logger.
+func (s *Scheduler) SetLogger(l cogito.Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = l
+}
+
+func (s *Scheduler) warn(msg string, args ...any) {
+	s.mu.Lock()
+	l := s.logger
+	s.mu.Unlock()
+	if l == nil {
+		xlog.Warn(msg, args...)
+		return
+	}
+	l.Warn(msg, args...)
+}
+
+// Add registers job under name, to be run on schedule once the scheduler
+// starts. callback, if not nil, receives the result of every run.
+func (s *Scheduler) Add(name string, schedule Schedule, job Job, callback func(Run)) error {
+	if schedule == nil || job == nil {
+		return fmt.Errorf("schedule and job are required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running {
+		return fmt.Errorf("cannot add job %q: scheduler already running", name)
+	}
+	s.entries = append(s.entries, entry{name: name, schedule: schedule, job: job, callback: callback})
+	return nil
+}
+
+// Run runs the jobs until ctx is done, then waits for the runs in progress
+// (which see ctx cancelled) to finish and returns ctx.Err().
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return fmt.Errorf("scheduler already running")
+	}
+	s.running = true
+	entries := append([]entry(nil), s.entries...)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.running = false
+		s.mu.Unlock()
+	}()
+
+	var wg sync.WaitGroup
+	for _, e := range entries {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.loop(ctx, e)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (s *Scheduler) loop(ctx context.Context, e entry) {
+	var runs sync.WaitGroup
+	defer runs.Wait()
+
+	busy := make(chan struct{}, 1)
+	next := e.schedule.Next(time.Now())
+	for {
+		if next.IsZero() {
+			s.warn("Scheduled job has no next run, stopping it", "job", e.name)
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case busy <- struct{}{}:
+			runs.Add(1)
+			go func() {
+				defer runs.Done()
+				defer func() { <-busy }()
+				s.execute(ctx, e)
+			}()
+		default:
+			s.warn("Skipping scheduled run, previous run still in progress", "job", e.name)
+		}
+		next = e.schedule.Next(time.Now())
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, e entry) {
+	run := Run{Name: e.name, Started: time.Now()}
+	run.Fragment, run.Err = e.job(ctx)
+	run.Finished = time.Now()
+	if run.Err != nil {
+		s.warn("Scheduled run failed", "job", e.name, "error", run.Err)
+	}
+	if e.callback != nil {
+		e.callback(run)
+	}
+}
@@ -0,0 +1,139 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mudler/cogito"
+)
+
+func TestCronNext(t *testing.T) {
+	from := time.Date(2025, 1, 1, 10, 7, 30, 0, time.UTC) // a Wednesday
+	cases := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2025, 1, 1, 10, 8, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, 1, 1, 10, 15, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"30 8-18/2 * * *", time.Date(2025, 1, 1, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2025, 1, 2, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1,15 * *", time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)},
+		{"0 8 1,15 * *", time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)},
+		// day of month or day of week, as in cron
+		{"0 0 10 * 4", time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, tc := range cases {
+		s, err := Cron(tc.expr)
+		if err != nil {
+			t.Fatalf("Cron(%q): %v", tc.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("Cron(%q).Next = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestCronRejectsInvalidExpressions(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := Cron(expr); err == nil {
+			t.Errorf("Cron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestEveryRejectsNonPositiveIntervals(t *testing.T) {
+	for _, d := range []time.Duration{0, -time.Second} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Every(%v) didn't panic", d)
+				}
+			}()
+			Every(d)
+		}()
+	}
+}
+
+func TestSchedulerRunsJobsAndReportsResults(t *testing.T) {
+	s := New()
+	results := make(chan Run, 10)
+	job := func(ctx context.Context) (cogito.Fragment, error) {
+		return cogito.NewEmptyFragment().AddMessage(cogito.AssistantMessageRole, "all good"), nil
+	}
+	if err := s.Add("monitor", Every(10*time.Millisecond), job, func(r Run) { results <- r }); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 55*time.Millisecond)
+	defer cancel()
+	if err := s.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run = %v, want deadline exceeded", err)
+	}
+	close(results)
+
+	n := 0
+	for r := range results {
+		n++
+		if r.Name != "monitor" || r.Err != nil || r.Fragment.LastMessage().Content != "all good" {
+			t.Fatalf("unexpected run %+v", r)
+		}
+	}
+	if n < 3 {
+		t.Fatalf("got %d runs, want at least 3", n)
+	}
+}
+
+func TestSchedulerSkipsOverlappingRuns(t *testing.T) {
+	s := New()
+	var concurrent, maxConcurrent, runs atomic.Int32
+	job := func(ctx context.Context) (cogito.Fragment, error) {
+		runs.Add(1)
+		n := concurrent.Add(1)
+		defer concurrent.Add(-1)
+		if n > maxConcurrent.Load() {
+			maxConcurrent.Store(n)
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(35 * time.Millisecond):
+		}
+		return cogito.Fragment{}, ctx.Err()
+	}
+	s.Add("slow", Every(5*time.Millisecond), job, nil)
+	logger := &skipLogger{}
+	s.SetLogger(logger)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+
+	if maxConcurrent.Load() != 1 {
+		t.Fatalf("max concurrent runs = %d, want 1", maxConcurrent.Load())
+	}
+	if runs.Load() > 4 {
+		t.Fatalf("got %d runs, want overlapping ticks skipped", runs.Load())
+	}
+	if concurrent.Load() != 0 {
+		t.Fatalf("Run returned with runs still in progress")
+	}
+	if logger.warnings.Load() == 0 {
+		t.Fatalf("skipped runs were not logged")
+	}
+}
+
+type skipLogger struct {
+	warnings atomic.Int32
+}
+
+func (l *skipLogger) Debug(msg string, args ...any) {}
+func (l *skipLogger) Info(msg string, args ...any)  {}
+func (l *skipLogger) Warn(msg string, args ...any)  { l.warnings.Add(1) }
+func (l *skipLogger) Error(msg string, args ...any) {}
@@ -0,0 +1,88 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrSecretNotFound is returned by Secrets when a secret does not exist.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// Secrets provides named secrets (API keys, tokens, ...) to tools. Set it
+// with WithSecrets; tools read it from their execution context with
+// GetSecret, so they do not need to capture credentials at construction
+// time or read environment variables themselves.
+type Secrets interface {
+	Get(name string) (string, error)
+}
+
+// MapSecrets serves secrets from memory.
+type MapSecrets map[string]string
+
+func (m MapSecrets) Get(name string) (string, error) {
+	if v, ok := m[name]; ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+// EnvSecrets serves secrets from environment variables named Prefix+name,
+// e.g. "COGITO_" + "GITHUB_TOKEN".
+type EnvSecrets struct {
+	Prefix string
+}
+
+func (e EnvSecrets) Get(name string) (string, error) {
+	if v, ok := os.LookupEnv(e.Prefix + name); ok {
+		return v, nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+}
+
+// FileSecrets serves secrets from the files of a directory, one secret per
+// file named after it, as mounted by Docker and Kubernetes secrets. A
+// trailing newline is trimmed.
+type FileSecrets struct {
+	Dir string
+}
+
+func (f FileSecrets) Get(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+	data, err := os.ReadFile(filepath.Join(f.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+type secretsKey struct{}
+
+// ContextWithSecrets returns a context carrying secrets. ExecuteTools does it
+// for the secrets set with WithSecrets before running a tool.
+func ContextWithSecrets(ctx context.Context, secrets Secrets) context.Context {
+	return context.WithValue(ctx, secretsKey{}, secrets)
+}
+
+// SecretsFromContext returns the secrets carried by ctx, or nil.
+func SecretsFromContext(ctx context.Context) Secrets {
+	secrets, _ := ctx.Value(secretsKey{}).(Secrets)
+	return secrets
+}
+
+// GetSecret returns the secret name from the secrets carried by ctx.
+func GetSecret(ctx context.Context, name string) (string, error) {
+	secrets := SecretsFromContext(ctx)
+	if secrets == nil {
+		return "", fmt.Errorf("%w: %s (no secrets provider set)", ErrSecretNotFound, name)
+	}
+	return secrets.Get(name)
+}
@@ -0,0 +1,60 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSecretsProviders(t *testing.T) {
+	t.Setenv("TEST_COGITO_TOKEN", "from-env")
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "TOKEN"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, secrets := range map[string]Secrets{
+		"map":  MapSecrets{"TOKEN": "from-map"},
+		"env":  EnvSecrets{Prefix: "TEST_COGITO_"},
+		"file": FileSecrets{Dir: dir},
+	} {
+		v, err := secrets.Get("TOKEN")
+		if err != nil || v != "from-"+name {
+			t.Errorf("%s: Get = %q, %v", name, v, err)
+		}
+		if _, err := secrets.Get("MISSING"); !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("%s: missing secret error = %v", name, err)
+		}
+	}
+
+	if _, err := (FileSecrets{Dir: dir}).Get("../TOKEN"); err == nil || errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("path traversal error = %v", err)
+	}
+}
+
+type secretRunner struct{}
+
+func (secretRunner) Run(args struct{}) (string, any, error) {
+	return "", nil, errors.New("no context")
+}
+
+func (secretRunner) RunWithContext(ctx context.Context, args struct{}) (string, any, error) {
+	token, err := GetSecret(ctx, "TOKEN")
+	return token, nil, err
+}
+
+func TestToolsReceiveSecrets(t *testing.T) {
+	tool := NewToolDefinition[struct{}](secretRunner{}, struct{}{}, "whoami", "")
+
+	o := defaultOptions()
+	o.Apply(WithSecrets(MapSecrets{"TOKEN": "s3cret"}))
+	if result, _, err := runTool(o, tool, ToolCallInfo{Choice: ToolChoice{Name: "whoami"}}); err != nil || result != "s3cret" {
+		t.Errorf("runTool = %q, %v", result, err)
+	}
+
+	if _, _, err := runTool(defaultOptions(), tool, ToolCallInfo{Choice: ToolChoice{Name: "whoami"}}); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("without secrets: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+)
+
+type seedKey struct{}
+
+// WithSeed makes runs reproducible: seed is sent with every LLM request, for
+// the backends supporting it, and the IDs of tool calls are derived from seed
+// and their position in the conversation instead of being random. Two runs
+// with the same inputs and a deterministic backend produce the same
+// messages, so their fragments can be compared in tests or cached. Times
+// recorded in Status (e.g. ToolStatus.ExecutedAt) still vary.
+func WithSeed(seed int) func(o *Options) {
+	return func(o *Options) {
+		o.seed = &seed
+	}
+}
+
+// SeedFromContext returns the seed of the run, for LLM clients building
+// their own requests in Ask. See WithSeed.
+func SeedFromContext(ctx context.Context) (int, bool) {
+	seed, ok := ctx.Value(seedKey{}).(int)
+	return seed, ok
+}
+
+// seedNamespace is the namespace of the tool call IDs derived from seeds.
+var seedNamespace = uuid.MustParse("6f1c1f43-8a2e-4e39-9a57-2d0f3b8e5c11")
+
+// toolCallID returns the ID of the i-th tool call chosen after the messages
+// of f: random, or derived from the seed of o.
+func toolCallID(o *Options, f Fragment, i int) string {
+	if o.seed == nil {
+		return uuid.New().String()
+	}
+	return uuid.NewSHA1(seedNamespace, fmt.Appendf(nil, "%d/%d/%d", *o.seed, len(f.Messages), i)).String()
+}
+
+// seededLLM wraps an LLM, setting the seed of every request.
+type seededLLM struct {
+	LLM
+	seed int
+}
+
+func (s *seededLLM) unwrap() LLM { return s.LLM }
+
+func (s *seededLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	if req.Seed == nil {
+		req.Seed = &s.seed
+	}
+	return s.LLM.CreateChatCompletion(context.WithValue(ctx, seedKey{}, s.seed), req)
+}
+
+// Ask passes the seed on the context, see SeedFromContext.
+func (s *seededLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	return s.LLM.Ask(context.WithValue(ctx, seedKey{}, s.seed), f)
+}
+
+// seededStreamingLLM preserves StreamingLLM.
+type seededStreamingLLM struct {
+	seededLLM
+	streaming StreamingLLM
+}
+
+func (s *seededStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	if req.Seed == nil {
+		req.Seed = &s.seed
+	}
+	return s.streaming.CreateChatCompletionStream(context.WithValue(ctx, seedKey{}, s.seed), req)
+}
+
+// withSeed wraps llm with the seed of o, if any. An LLM already seeded is
+// returned as is.
+func withSeed(llm LLM, o *Options) LLM {
+	if o.seed == nil || llm == nil {
+		return llm
+	}
+	if _, ok := unwrapLLM[*seededLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*seededStreamingLLM](llm); ok {
+		return llm
+	}
+	base := seededLLM{LLM: llm, seed: *o.seed}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &seededStreamingLLM{seededLLM: base, streaming: s}
+	}
+	return &base
+}
@@ -0,0 +1,46 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Seeded runs", func() {
+	run := func(opts ...Option) (Fragment, *cogitotest.MockLLM) {
+		search := cogitotest.NewMockTool("search", "Search the web")
+		cogitotest.SetRunResult(search, "Rome")
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Rome."},
+		}}})
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What is the capital of Italy?")
+		result, err := ExecuteTools(llm, f, append(opts, WithTools(search), WithIterations(2))...)
+		Expect(err).ToNot(HaveOccurred())
+		return result, llm
+	}
+
+	It("produces the same messages for the same seed", func() {
+		first, llm := run(WithSeed(42))
+		second, _ := run(WithSeed(42))
+		Expect(second.Messages).To(Equal(first.Messages))
+
+		other, _ := run(WithSeed(7))
+		Expect(other.Messages[1].ToolCalls[0].ID).ToNot(Equal(first.Messages[1].ToolCalls[0].ID))
+
+		for _, req := range llm.Requests() {
+			Expect(req.Seed).ToNot(BeNil())
+			Expect(*req.Seed).To(Equal(42))
+		}
+	})
+
+	It("keeps random tool call IDs without a seed", func() {
+		first, llm := run()
+		second, _ := run()
+		Expect(second.Messages[1].ToolCalls[0].ID).ToNot(Equal(first.Messages[1].ToolCalls[0].ID))
+		Expect(llm.Requests()[0].Seed).To(BeNil())
+	})
+})
@@ -0,0 +1,277 @@
+// Package server exposes a cogito agent as an OpenAI-compatible chat
+// completions API, so existing chat UIs and SDKs can talk to it. Tool
+// execution, planning and guidelines happen behind the API, following the
+// cogito options the server is created with; clients only see the answers.
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mudler/cogito"
+	"github.com/mudler/xlog"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Server serves the OpenAI-compatible endpoints:
+//
+//	POST /v1/chat/completions   run the agent on the conversation (streaming supported)
+//	GET  /v1/models             list the agent as a model
+//
+// Sampling parameters and tools of the requests are ignored: the agent runs
+// with its own LLM and tools.
+type Server struct {
+	llm    cogito.LLM
+	opts   []cogito.Option
+	model  string
+	keys   []string
+	logger cogito.Logger
+	mux    *http.ServeMux
+}
+
+// New returns a server running cogito.ExecuteTools with llm and opts on
+// every request.
+func New(llm cogito.LLM, opts ...cogito.Option) *Server {
+	s := &Server{
+		llm:   llm,
+		opts:  opts,
+		model: "cogito",
+		mux:   http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+	s.mux.HandleFunc("GET /v1/models", s.models)
+	return s
+}
+
+// SetModel sets the model name reported to clients. Defaults to "cogito".
+func (s *Server) SetModel(name string) {
+	s.model = name
+}
+
+// SetAPIKeys requires clients to send one of keys as a bearer token.
+func (s *Server) SetAPIKeys(keys ...string) {
+	s.keys = keys
+}
+
+// SetLogger sends the server's log output (failed requests) to l instead of
+// the global xlog logger.
+func (s *Server) SetLogger(l cogito.Logger) {
+	s.logger = l
+}
+
+func (s *Server) logError(msg string, args ...any) {
+	if s.logger == nil {
+		xlog.Error(msg, args...)
+		return
+	}
+	s.logger.Error(msg, args...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeError(w, http.StatusUnauthorized, "invalid_api_key", "invalid or missing API key")
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authorized(r *http.Request) bool {
+	if len(s.keys) == 0 {
+		return true
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	for _, key := range s.keys {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(key)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) models(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, openai.ModelsList{Models: []openai.Model{{
+		ID:      s.model,
+		Object:  "model",
+		OwnedBy: "cogito",
+	}}})
+}
+
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req openai.ChatCompletionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("invalid request: %v", err))
+		return
+	}
+	if len(req.Messages) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "messages must not be empty")
+		return
+	}
+
+	id := "chatcmpl-" + uuid.NewString()
+	created := time.Now().Unix()
+	f := cogito.NewFragment(req.Messages...)
+
+	if !req.Stream {
+		result, err := s.run(r, f, nil)
+		if err != nil {
+			s.logError("Chat completion failed", "error", err)
+			writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+			return
+		}
+		usage := result.Status.CumulativeUsage
+		writeJSON(w, http.StatusOK, openai.ChatCompletionResponse{
+			ID:      id,
+			Object:  "chat.completion",
+			Created: created,
+			Model:   s.model,
+			Choices: []openai.ChatCompletionChoice{{
+				Index: 0,
+				Message: openai.ChatCompletionMessage{
+					Role:    openai.ChatMessageRoleAssistant,
+					Content: result.LastMessage().Content,
+				},
+				FinishReason: openai.FinishReasonStop,
+			}},
+			Usage: openai.Usage{
+				PromptTokens:     usage.PromptTokens,
+				CompletionTokens: usage.CompletionTokens,
+				TotalTokens:      usage.TotalTokens,
+			},
+		})
+		return
+	}
+
+	stream := &eventStream{w: w, id: id, created: created, model: s.model}
+	stream.start()
+	result, err := s.run(r, f, stream.event)
+	if err != nil {
+		s.logError("Chat completion failed", "error", err)
+		stream.error(err)
+		return
+	}
+	if !stream.streamed {
+		// The answer did not come token by token: send it whole
+		stream.delta(openai.ChatCompletionStreamChoiceDelta{Content: result.LastMessage().Content})
+	}
+	stream.finish()
+}
+
+// run runs the agent on f, see cogito.ExecuteToolsAndAnswer.
+func (s *Server) run(r *http.Request, f cogito.Fragment, streamCallback cogito.StreamCallback) (cogito.Fragment, error) {
+	opts := append([]cogito.Option{}, s.opts...)
+	if streamCallback != nil {
+		opts = append(opts, cogito.WithStreamCallback(streamCallback))
+	}
+	return cogito.ExecuteToolsAndAnswer(r.Context(), s.llm, f, opts...)
+}
+
+// eventStream writes chat completion chunks as server-sent events. Only the
+// deltas of the final answer are sent, not the ones of tool selection.
+type eventStream struct {
+	mu        sync.Mutex
+	w         http.ResponseWriter
+	id        string
+	created   int64
+	model     string
+	answering bool // the final answer started
+	streamed  bool
+}
+
+func (e *eventStream) start() {
+	e.w.Header().Set("Content-Type", "text/event-stream")
+	e.w.Header().Set("Cache-Control", "no-cache")
+	e.w.Header().Set("Connection", "keep-alive")
+	e.w.WriteHeader(http.StatusOK)
+	e.delta(openai.ChatCompletionStreamChoiceDelta{Role: openai.ChatMessageRoleAssistant})
+}
+
+func (e *eventStream) event(ev cogito.StreamEvent) {
+	e.mu.Lock()
+	if ev.Type == cogito.StreamEventAnswer {
+		e.answering = true
+	}
+	answering := e.answering
+	if answering && ev.Type == cogito.StreamEventContent {
+		e.streamed = true
+	}
+	e.mu.Unlock()
+	if !answering {
+		return
+	}
+
+	switch ev.Type {
+	case cogito.StreamEventContent:
+		e.delta(openai.ChatCompletionStreamChoiceDelta{Content: ev.Content})
+	case cogito.StreamEventReasoning:
+		e.delta(openai.ChatCompletionStreamChoiceDelta{ReasoningContent: ev.Content})
+	}
+}
+
+func (e *eventStream) delta(delta openai.ChatCompletionStreamChoiceDelta) {
+	e.chunk(openai.ChatCompletionStreamChoice{Index: 0, Delta: delta})
+}
+
+func (e *eventStream) chunk(choice openai.ChatCompletionStreamChoice) {
+	e.send(openai.ChatCompletionStreamResponse{
+		ID:      e.id,
+		Object:  "chat.completion.chunk",
+		Created: e.created,
+		Model:   e.model,
+		Choices: []openai.ChatCompletionStreamChoice{choice},
+	})
+}
+
+func (e *eventStream) send(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprintf(e.w, "data: %s\n\n", data)
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (e *eventStream) finish() {
+	e.chunk(openai.ChatCompletionStreamChoice{Index: 0, FinishReason: openai.FinishReasonStop})
+	e.done()
+}
+
+func (e *eventStream) error(err error) {
+	e.send(errorBody("server_error", err.Error()))
+	e.done()
+}
+
+func (e *eventStream) done() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	fmt.Fprint(e.w, "data: [DONE]\n\n")
+	if flusher, ok := e.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func errorBody(kind, message string) map[string]any {
+	return map[string]any{"error": map[string]any{"message": message, "type": kind}}
+}
+
+func writeError(w http.ResponseWriter, status int, kind, message string) {
+	writeJSON(w, status, errorBody(kind, message))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,208 @@
+package server_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	"github.com/mudler/cogito/server"
+	"github.com/sashabaranov/go-openai"
+)
+
+func newServer(t *testing.T) (*httptest.Server, *cogitotest.MockLLM) {
+	t.Helper()
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("search", "Search for information")
+	cogitotest.SetRunResult(tool, "Rome is the capital of Italy")
+	llm.AddCreateChatCompletionFunction("search", `{"query": "capital of Italy"}`)
+	llm.SetAskResponse("The capital of Italy is Rome.")
+
+	s := server.New(llm, cogito.WithTools(tool))
+	s.SetModel("agent")
+	s.SetAPIKeys("secret")
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, llm
+}
+
+func post(t *testing.T, url string, req openai.ChatCompletionRequest) *http.Response {
+	t.Helper()
+	body, _ := json.Marshal(req)
+	httpReq, _ := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", bytes.NewReader(body))
+	httpReq.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+var question = []openai.ChatCompletionMessage{{Role: "user", Content: "What is the capital of Italy?"}}
+
+func TestChatCompletions(t *testing.T) {
+	ts, llm := newServer(t)
+
+	resp := post(t, ts.URL, openai.ChatCompletionRequest{Model: "agent", Messages: question})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	var completion openai.ChatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&completion); err != nil {
+		t.Fatal(err)
+	}
+	if completion.Model != "agent" || completion.Choices[0].Message.Content != "The capital of Italy is Rome." {
+		t.Fatalf("completion = %+v", completion)
+	}
+	// The tool ran behind the API
+	if len(llm.RequestHistory) == 0 || !strings.Contains(llm.FragmentHistory[0].String(), "Rome is the capital of Italy") {
+		t.Fatalf("the search tool result did not reach the final answer")
+	}
+}
+
+func TestChatCompletionsStreaming(t *testing.T) {
+	ts, _ := newServer(t)
+
+	resp := post(t, ts.URL, openai.ChatCompletionRequest{Model: "agent", Messages: question, Stream: true})
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type = %q", ct)
+	}
+
+	content := ""
+	finished, done := false, false
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk openai.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		content += chunk.Choices[0].Delta.Content
+		if chunk.Choices[0].FinishReason == openai.FinishReasonStop {
+			finished = true
+		}
+	}
+	if content != "The capital of Italy is Rome." || !finished || !done {
+		t.Fatalf("content = %q, finished = %v, done = %v", content, finished, done)
+	}
+}
+
+func TestAuthAndModels(t *testing.T) {
+	ts, _ := newServer(t)
+
+	resp, err := http.Get(ts.URL + "/v1/models")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status without key = %d", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var models openai.ModelsList
+	json.NewDecoder(resp.Body).Decode(&models)
+	if len(models.Models) != 1 || models.Models[0].ID != "agent" {
+		t.Fatalf("models = %+v", models)
+	}
+
+	if resp := post(t, ts.URL, openai.ChatCompletionRequest{Model: "agent"}); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status for empty messages = %d", resp.StatusCode)
+	}
+}
+
+// streamingLLM streams the replies of a MockLLM word by word: answers from
+// its Ask queue, tool selections from its CreateChatCompletion queue.
+type streamingLLM struct {
+	*cogitotest.MockLLM
+}
+
+func (s streamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan cogito.StreamEvent, error) {
+	var msg openai.ChatCompletionMessage
+	if len(req.Tools) == 0 {
+		f, err := s.Ask(ctx, cogito.Fragment{Messages: req.Messages})
+		if err != nil {
+			return nil, err
+		}
+		msg = *f.LastMessage()
+	} else {
+		reply, _, err := s.CreateChatCompletion(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		msg = reply.ChatCompletionResponse.Choices[0].Message
+	}
+
+	ch := make(chan cogito.StreamEvent, 64)
+	for _, word := range strings.SplitAfter(msg.Content, " ") {
+		ch <- cogito.StreamEvent{Type: cogito.StreamEventContent, Content: word}
+	}
+	for i, tc := range msg.ToolCalls {
+		ch <- cogito.StreamEvent{Type: cogito.StreamEventToolCall, ToolCallIndex: i, ToolCallID: tc.ID,
+			ToolName: tc.Function.Name, ToolArgs: tc.Function.Arguments}
+	}
+	ch <- cogito.StreamEvent{Type: cogito.StreamEventDone}
+	close(ch)
+	return ch, nil
+}
+
+func TestChatCompletionsStreamsOnlyTheAnswer(t *testing.T) {
+	llm := cogitotest.NewMockLLM()
+	tool := cogitotest.NewMockTool("search", "Search for information")
+	cogitotest.SetRunResult(tool, "Rome is the capital of Italy")
+	llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+		Message: openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleAssistant,
+			Content: "Let me look it up.",
+			ToolCalls: []openai.ToolCall{{ID: "call_1", Type: openai.ToolTypeFunction,
+				Function: openai.FunctionCall{Name: "search", Arguments: `{"query": "capital of Italy"}`}}},
+		},
+		FinishReason: openai.FinishReasonToolCalls,
+	}}})
+	llm.SetAskResponse("The capital of Italy is Rome.")
+
+	s := server.New(streamingLLM{llm}, cogito.WithTools(tool), cogito.WithIterations(1))
+	s.SetAPIKeys("secret")
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	resp := post(t, ts.URL, openai.ChatCompletionRequest{Model: "agent", Messages: question, Stream: true})
+	var deltas []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var chunk openai.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatal(err)
+		}
+		if content := chunk.Choices[0].Delta.Content; content != "" {
+			deltas = append(deltas, content)
+		}
+	}
+	if got := strings.Join(deltas, ""); got != "The capital of Italy is Rome." || len(deltas) < 2 {
+		t.Fatalf("deltas = %q, want the answer alone, token by token", deltas)
+	}
+}
@@ -0,0 +1,113 @@
+package cogito
+
+import (
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// TerminalState is a sink-state tool: when the LLM selects it, the run stops.
+// Use it with WithSinkState or WithTerminalStates to give agents explicit
+// ways to end a run, e.g. ReplyToUserState, WaitState and GiveUpState.
+type TerminalState struct {
+	Name        string
+	Description string
+	// Reply makes the LLM write a final reply when the state is entered.
+	// Without it the run ends on the tool results, without a reply.
+	Reply bool
+}
+
+var _ ToolDefinitionInterface = (*TerminalState)(nil)
+
+func (t *TerminalState) Tool() openai.Tool {
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        t.Name,
+			Description: t.Description,
+			Parameters: jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"reason": {Type: jsonschema.String, Description: "Why the run ends in this state"},
+				},
+			},
+		},
+	}
+}
+
+// Execute returns the reason given by the LLM for entering the state.
+func (t *TerminalState) Execute(args map[string]any) (string, any, error) {
+	reason, _ := args["reason"].(string)
+	return reason, reason, nil
+}
+
+// ReplyToUserState ends the run with a reply to the user.
+func ReplyToUserState() *TerminalState {
+	return &TerminalState{
+		Name:        "reply_to_user",
+		Description: "Use this when you have everything needed to answer the user",
+		Reply:       true,
+	}
+}
+
+// WaitState ends the run without replying, e.g. to wait for an external
+// event or for the user to provide more input.
+func WaitState() *TerminalState {
+	return &TerminalState{
+		Name:        "wait",
+		Description: "Use this when nothing can be done until something else happens, such as an external event or more input from the user",
+	}
+}
+
+// GiveUpState ends the run without replying when the task can't be completed.
+func GiveUpState() *TerminalState {
+	return &TerminalState{
+		Name:        "give_up",
+		Description: "Use this when the task cannot be completed with the available tools and information",
+	}
+}
+
+// sinkStates returns the tools ending the run: the sink state first, then the
+// terminal states set with WithTerminalStates.
+func (o *Options) sinkStates() Tools {
+	if !o.sinkState {
+		return nil
+	}
+	return append(Tools{o.sinkStateTool}, o.terminalStates...)
+}
+
+// isSinkState reports whether name is one of the tools ending the run.
+func (o *Options) isSinkState(name string) bool {
+	for _, t := range o.sinkStates() {
+		if f := t.Tool().Function; f != nil && f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// enterSinkState runs the terminal tool selected by choice (the sink state
+// when choice is nil), notifies the callback set with WithSinkStateCallback,
+// and reports whether the LLM should write a final reply.
+func enterSinkState(o *Options, choice *ToolChoice) bool {
+	if choice == nil {
+		choice = &ToolChoice{Name: o.sinkStateTool.Tool().Function.Name, Arguments: map[string]any{}}
+	}
+	tool := o.sinkStates().Find(choice.Name)
+	if tool == nil {
+		return true
+	}
+
+	result, _, err := executeTool(o.context, tool, choice.Arguments)
+	if err != nil {
+		o.logger.Warn("Sink state tool failed", "tool", choice.Name, "error", err)
+	}
+	o.logger.Debug("Entered sink state", "tool", choice.Name, "result", result)
+	if o.sinkStateCallback != nil {
+		o.sinkStateCallback(choice, result)
+	}
+
+	if t, ok := tool.(*TerminalState); ok {
+		return t.Reply
+	}
+	return true
+}
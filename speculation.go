@@ -0,0 +1,97 @@
+package cogito
+
+import (
+	"context"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// speculation generates the parameters of the most likely next tool while
+// the tool selection runs, see WithSpeculativeParameters.
+type speculation struct {
+	tool   string
+	cancel context.CancelFunc
+	done   chan struct{}
+	choice *ToolChoice
+	err    error
+	used   bool
+}
+
+// likelyTool predicts the tool the selection will pick: the only tool
+// suggested by the guidelines, or the only tool available. It is nil when
+// there is no clear candidate.
+func likelyTool(tools Tools, guidelines Guidelines) ToolDefinitionInterface {
+	suggested := map[string]bool{}
+	for _, g := range guidelines {
+		for _, t := range g.Tools {
+			suggested[t.Tool().Function.Name] = true
+		}
+	}
+	if len(suggested) == 1 {
+		for name := range suggested {
+			return tools.Find(name)
+		}
+	}
+	if len(suggested) == 0 && len(tools) == 1 {
+		return tools[0]
+	}
+	return nil
+}
+
+// startSpeculation starts generating the parameters of the likely tool,
+// returning nil when there is nothing to speculate on.
+func startSpeculation(o *Options, llm LLM, tools Tools, guidelines Guidelines, conversation []openai.ChatCompletionMessage) *speculation {
+	if !o.speculativeParameters || !o.forceReasoning || o.consolidatedReasoning {
+		return nil
+	}
+	tool := likelyTool(tools, guidelines)
+	if tool == nil {
+		return nil
+	}
+	toolFunc := tool.Tool().Function
+	if toolFunc == nil || toolFunc.Parameters == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(o.context)
+	s := &speculation{tool: toolFunc.Name, cancel: cancel, done: make(chan struct{})}
+
+	// The speculation must not stream: its completion may be discarded
+	so := *o
+	so.context = ctx
+	so.streamCallback = nil
+
+	o.logger.Debug("[toolSelection] Speculatively generating parameters", "tool", s.tool)
+	go func() {
+		defer close(s.done)
+		s.choice, s.err = generateToolParameters(&so, llm, tool, conversation, "")
+	}()
+	return s
+}
+
+// result waits for the parameters generated for tool. It returns false when
+// the speculation was on another tool or failed.
+func (s *speculation) result(o *Options, tool string) (*ToolChoice, bool) {
+	if s == nil || s.used || s.tool != tool {
+		return nil, false
+	}
+	<-s.done
+	s.used = true
+	if s.err != nil {
+		o.logger.Warn("[toolSelection] Speculative parameter generation failed", "tool", tool, "error", s.err)
+		return nil, false
+	}
+	o.logger.Debug("[toolSelection] Using speculative parameters", "tool", tool)
+	return s.choice, true
+}
+
+// discard cancels the speculation if it is still running.
+func (s *speculation) discard(o *Options) {
+	if s == nil {
+		return
+	}
+	if !s.used {
+		o.logger.Debug("[toolSelection] Discarding speculative parameters", "tool", s.tool)
+	}
+	s.cancel()
+}
@@ -0,0 +1,74 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+// forcedRequests returns the requests of mockLLM forcing the tool name.
+func forcedRequests(mockLLM *cogitotest.MockLLM, name string) []openai.ChatCompletionRequest {
+	var requests []openai.ChatCompletionRequest
+	for _, req := range mockLLM.Requests() {
+		if cogitotest.ForcesTool(name)(req) {
+			requests = append(requests, req)
+		}
+	}
+	return requests
+}
+
+var _ = Describe("Speculative parameters", func() {
+	var mockLLM *cogitotest.MockLLM
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.ForcesTool("reasoning")).
+			ReplyJSON("reasoning", map[string]any{"reasoning": "The user wants the weather"}).Repeat()
+	})
+
+	It("uses the parameters generated while the tool is selected", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+
+		mockLLM.When(cogitotest.ForcesTool("pick_tool")).ReplyJSON("pick_tool", map[string]any{"tool": "get_weather"})
+		mockLLM.When(cogitotest.ForcesTool("get_weather")).ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("It is 21°C in Rome.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		result, err := ExecuteTools(mockLLM, f, WithTools(weather), WithSpeculativeParameters())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(weather).Calls()).To(Equal([]map[string]any{{"city": "Rome"}}))
+		Expect(result.Status.ToolsCalled).To(HaveLen(1))
+
+		// The parameters were generated once, without the parameter reasoning
+		// step of forced reasoning
+		Expect(forcedRequests(mockLLM, "get_weather")).To(HaveLen(1))
+		Expect(forcedRequests(mockLLM, "reasoning")).To(HaveLen(1))
+	})
+
+	It("discards the speculation when another tool is picked", func() {
+		search := cogitotest.NewMockTool("search", "Search the web")
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+
+		mockLLM.SetAskResponse("The guideline is relevant.")
+		mockLLM.When(cogitotest.ForcesTool("json")).ReplyToolCall("json", `{"guidelines": [1]}`)
+		mockLLM.When(cogitotest.ForcesTool("pick_tool")).ReplyJSON("pick_tool", map[string]any{"tool": "get_weather"})
+		mockLLM.When(cogitotest.ForcesTool("search")).ReplyToolCall("search", `{"query": "news"}`).Repeat()
+		mockLLM.When(cogitotest.ForcesTool("get_weather")).ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("It is 21°C in Rome.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		_, err := ExecuteTools(mockLLM, f, WithTools(weather), WithSpeculativeParameters(),
+			WithGuidelines(Guideline{Condition: "The user asks for news", Action: "Search the web", Tools: Tools{search}}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(search).Calls()).To(BeEmpty())
+		Expect(cogitotest.GetMockTool(weather).Calls()).To(Equal([]map[string]any{{"city": "Rome"}}))
+
+		// The parameters of the picked tool were generated with its reasoning
+		Expect(forcedRequests(mockLLM, "get_weather")).To(HaveLen(1))
+		Expect(forcedRequests(mockLLM, "reasoning")).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,222 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// StatefulTool is implemented by tools holding resources across calls, such
+// as browser sessions or database connections. It can be implemented by the
+// tool itself or, for ToolDefinition, by its ToolRunner.
+//
+// ExecuteTools initializes a stateful tool before its first call, consults
+// Healthy before offering it to the LLM again, and closes it when the run
+// ends. Tools that were never called are neither initialized nor closed.
+type StatefulTool interface {
+	// Init acquires the resources of the tool. A failed Init fails the call,
+	// and is attempted again on the next one.
+	Init(ctx context.Context) error
+	// Close releases the resources acquired by Init.
+	Close() error
+	// Healthy returns an error when the tool cannot serve calls. Unhealthy
+	// tools are not offered to the LLM, which is told they are unavailable.
+	Healthy() error
+}
+
+// runnerHolder is implemented by ToolDefinition, to find a StatefulTool
+// among its ToolRunner.
+type runnerHolder interface {
+	toolRunner() any
+}
+
+func (t *ToolDefinition[T]) toolRunner() any { return t.ToolRunner }
+
+// toolWrapper is implemented by tools wrapping another one (namespaces,
+// concurrency limits).
+type toolWrapper interface {
+	unwrapTool() ToolDefinitionInterface
+}
+
+func (t *namespacedTool) unwrapTool() ToolDefinitionInterface { return t.tool }
+
+func (t *limitedTool) unwrapTool() ToolDefinitionInterface { return t.ToolDefinitionInterface }
+
+// wrappedTool returns the first tool in the wrapper chain implementing T.
+func wrappedTool[T any](tool ToolDefinitionInterface) (T, bool) {
+	for tool != nil {
+		if t, ok := tool.(T); ok {
+			return t, true
+		}
+		w, ok := tool.(toolWrapper)
+		if !ok {
+			break
+		}
+		tool = w.unwrapTool()
+	}
+	var zero T
+	return zero, false
+}
+
+// StatefulToolOf returns the StatefulTool behind tool, if any: tool itself,
+// the ToolRunner of a ToolDefinition, or a tool it wraps (namespaces,
+// concurrency limits, roles, ...).
+func StatefulToolOf(tool ToolDefinitionInterface) (StatefulTool, bool) {
+	for tool != nil {
+		if s, ok := tool.(StatefulTool); ok {
+			return s, true
+		}
+		if h, ok := tool.(runnerHolder); ok {
+			s, ok := h.toolRunner().(StatefulTool)
+			return s, ok
+		}
+		w, ok := tool.(toolWrapper)
+		if !ok {
+			break
+		}
+		tool = w.unwrapTool()
+	}
+	return nil, false
+}
+
+// toolLifecycle tracks the stateful tools initialized during a run.
+// Tools are identified by name, as they are recreated for each iteration
+// when they come from MCP servers.
+type toolLifecycle struct {
+	mu          sync.Mutex
+	initialized map[string]StatefulTool
+	pending     map[string]*toolInit
+	order       []string
+}
+
+// toolInit is the initialization of a tool in progress, which concurrent
+// calls of the tool wait for.
+type toolInit struct {
+	done chan struct{}
+	err  error
+}
+
+func newToolLifecycle() *toolLifecycle {
+	return &toolLifecycle{initialized: map[string]StatefulTool{}, pending: map[string]*toolInit{}}
+}
+
+// init initializes tool, named name, unless it is not stateful or already
+// initialized. Tools are initialized concurrently, each one once: calls of
+// a tool being initialized wait for it, and a failed initialization is
+// retried by the next call.
+func (l *toolLifecycle) init(ctx context.Context, name string, tool ToolDefinitionInterface) error {
+	if l == nil {
+		return nil
+	}
+	stateful, ok := StatefulToolOf(tool)
+	if !ok {
+		return nil
+	}
+	l.mu.Lock()
+	if _, done := l.initialized[name]; done {
+		l.mu.Unlock()
+		return nil
+	}
+	if p, ok := l.pending[name]; ok {
+		l.mu.Unlock()
+		select {
+		case <-p.done:
+			return p.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	p := &toolInit{done: make(chan struct{})}
+	l.pending[name] = p
+	l.mu.Unlock()
+
+	err := stateful.Init(ctx)
+
+	l.mu.Lock()
+	delete(l.pending, name)
+	if err == nil {
+		l.initialized[name] = stateful
+		l.order = append(l.order, name)
+	}
+	l.mu.Unlock()
+	if err != nil {
+		p.err = fmt.Errorf("failed to initialize tool %s: %w", name, err)
+	}
+	close(p.done)
+	return p.err
+}
+
+// healthy splits tools into the ones that can be offered to the LLM and
+// the initialized stateful tools reporting unhealthy, with their error.
+func (l *toolLifecycle) healthy(tools Tools) (Tools, map[string]error) {
+	if l == nil {
+		return tools, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.initialized) == 0 {
+		return tools, nil
+	}
+	var unavailable map[string]error
+	available := slices.DeleteFunc(slices.Clone(tools), func(t ToolDefinitionInterface) bool {
+		name := t.Tool().Function.Name
+		stateful, ok := l.initialized[name]
+		if !ok {
+			return false
+		}
+		err := stateful.Healthy()
+		if err == nil {
+			return false
+		}
+		if unavailable == nil {
+			unavailable = map[string]error{}
+		}
+		unavailable[name] = err
+		return true
+	})
+	return available, unavailable
+}
+
+// close closes the initialized tools, in reverse order of initialization.
+func (l *toolLifecycle) close(logger Logger) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.order) - 1; i >= 0; i-- {
+		name := l.order[i]
+		if err := l.initialized[name].Close(); err != nil {
+			logger.Warn("Failed to close tool", "tool", name, "error", err)
+		}
+		delete(l.initialized, name)
+	}
+	l.order = nil
+}
+
+// unavailableToolsMessage tells the LLM which tools are unavailable and why.
+func unavailableToolsMessage(o *Options, unavailable map[string]error) (openai.ChatCompletionMessage, error) {
+	type unavailableTool struct {
+		Name  string
+		Error string
+	}
+	var list []unavailableTool
+	for name, err := range unavailable {
+		o.logger.Warn("Tool is unhealthy, not offering it", "tool", name, "error", err)
+		list = append(list, unavailableTool{Name: name, Error: err.Error()})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	content, err := o.prompts.GetPrompt(prompt.PromptUnavailableToolsType).Render(struct {
+		Tools []unavailableTool
+	}{Tools: list})
+	if err != nil {
+		return openai.ChatCompletionMessage{}, fmt.Errorf("failed to render unavailable tools prompt: %w", err)
+	}
+	return openai.ChatCompletionMessage{Role: SystemMessageRole.String(), Content: content}, nil
+}
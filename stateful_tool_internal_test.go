@@ -0,0 +1,63 @@
+package cogito
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// blockingTool is a stateful tool whose Init waits for release.
+type blockingTool struct {
+	release chan struct{}
+	inits   atomic.Int32
+}
+
+func (b *blockingTool) Init(ctx context.Context) error {
+	b.inits.Add(1)
+	if b.release != nil {
+		<-b.release
+	}
+	return nil
+}
+
+func (b *blockingTool) Close() error   { return nil }
+func (b *blockingTool) Healthy() error { return nil }
+
+func (b *blockingTool) Tool() openai.Tool { return openai.Tool{} }
+
+func (b *blockingTool) Execute(args map[string]any) (string, any, error) { return "", nil, nil }
+
+func TestToolLifecycleInitializesToolsConcurrently(t *testing.T) {
+	l := newToolLifecycle()
+	slow := &blockingTool{release: make(chan struct{})}
+	fast := &blockingTool{}
+
+	slowDone := make(chan error, 2)
+	for range 2 {
+		go func() { slowDone <- l.init(context.Background(), "slow", slow) }()
+	}
+
+	fastDone := make(chan error, 1)
+	go func() { fastDone <- l.init(context.Background(), "fast", fast) }()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Fatalf("init fast: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fast tool waited for the slow one")
+	}
+
+	close(slow.release)
+	for range 2 {
+		if err := <-slowDone; err != nil {
+			t.Fatalf("init slow: %v", err)
+		}
+	}
+	if n := slow.inits.Load(); n != 1 {
+		t.Errorf("slow tool initialized %d times, want 1", n)
+	}
+}
@@ -0,0 +1,114 @@
+package cogito_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type browseArgs struct {
+	URL string `json:"url"`
+}
+
+// browserSession is a stateful tool whose session crashes after crashAfter
+// calls.
+type browserSession struct {
+	inits, closes, calls int
+	crashAfter           int
+}
+
+func (b *browserSession) Init(ctx context.Context) error {
+	b.inits++
+	return nil
+}
+
+func (b *browserSession) Close() error {
+	b.closes++
+	return nil
+}
+
+func (b *browserSession) Healthy() error {
+	if b.inits == 0 {
+		return errors.New("not started")
+	}
+	if b.crashAfter > 0 && b.calls >= b.crashAfter {
+		return errors.New("session crashed")
+	}
+	return nil
+}
+
+func (b *browserSession) Run(args browseArgs) (string, any, error) {
+	if b.inits == 0 {
+		return "", nil, errors.New("browser not initialized")
+	}
+	b.calls++
+	return "page " + args.URL, nil, nil
+}
+
+var _ = Describe("Stateful tools", func() {
+	var mockLLM *cogitotest.MockLLM
+	var fragment Fragment
+
+	answer := func(content string) openai.ChatCompletionResponse {
+		return openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: content}},
+			},
+		}
+	}
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		fragment = NewEmptyFragment().AddMessage(UserMessageRole, "Open the docs")
+	})
+
+	It("initializes the tool before its first call and closes it at the end of the run", func() {
+		browser := &browserSession{}
+		tool := NewToolDefinition[browseArgs](browser, browseArgs{}, "browse", "Open a web page")
+
+		mockLLM.AddCreateChatCompletionFunction("browse", `{"url": "docs"}`)
+		mockLLM.AddCreateChatCompletionFunction("browse", `{"url": "api"}`)
+		mockLLM.SetCreateChatCompletionResponse(answer("Done."))
+
+		result, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(3))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Status.ToolResults).To(HaveLen(2))
+		Expect(result.Status.ToolResults[0].Result).To(Equal("page docs"))
+		Expect(browser.inits).To(Equal(1))
+		Expect(browser.closes).To(Equal(1))
+	})
+
+	It("does not offer unhealthy tools and tells the LLM they are unavailable", func() {
+		browser := &browserSession{crashAfter: 1}
+		tool := NewToolDefinition[browseArgs](browser, browseArgs{}, "browse", "Open a web page")
+
+		mockLLM.AddCreateChatCompletionFunction("browse", `{"url": "docs"}`)
+		mockLLM.SetCreateChatCompletionResponse(answer("The browser is unavailable."))
+
+		_, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(browser.calls).To(Equal(1))
+		Expect(browser.closes).To(Equal(1))
+
+		request := mockLLM.RequestHistory[len(mockLLM.RequestHistory)-1]
+		Expect(request.Tools).ToNot(ContainElement(HaveField("Function.Name", "browse")))
+		Expect(request.Messages).To(ContainElement(HaveField("Content", ContainSubstring("browse: session crashed"))))
+	})
+
+	It("leaves tools that were never called alone", func() {
+		browser := &browserSession{}
+		tool := NewToolDefinition[browseArgs](browser, browseArgs{}, "browse", "Open a web page")
+
+		mockLLM.SetCreateChatCompletionResponse(answer("Nothing to open."))
+
+		_, err := ExecuteTools(mockLLM, fragment, WithTools(tool))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(browser.inits).To(Equal(0))
+		Expect(browser.closes).To(Equal(0))
+	})
+})
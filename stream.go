@@ -4,14 +4,16 @@ package cogito
 type StreamEventType string
 
 const (
-	StreamEventReasoning  StreamEventType = "reasoning"   // LLM thinking delta
-	StreamEventContent    StreamEventType = "content"     // answer text delta
-	StreamEventToolCall   StreamEventType = "tool_call"   // tool selected + args
-	StreamEventToolResult StreamEventType = "tool_result" // tool execution result
-	StreamEventStatus     StreamEventType = "status"      // status message
-	StreamEventDone       StreamEventType = "done"        // stream complete
-	StreamEventError      StreamEventType = "error"       // error
-	StreamEventSubAgent   StreamEventType = "sub_agent"   // sub-agent event
+	StreamEventReasoning    StreamEventType = "reasoning"     // LLM thinking delta
+	StreamEventContent      StreamEventType = "content"       // answer text delta
+	StreamEventToolCall     StreamEventType = "tool_call"     // tool selected + args
+	StreamEventToolResult   StreamEventType = "tool_result"   // tool execution result
+	StreamEventStatus       StreamEventType = "status"        // status message
+	StreamEventDone         StreamEventType = "done"          // stream complete
+	StreamEventError        StreamEventType = "error"         // error
+	StreamEventSubAgent     StreamEventType = "sub_agent"     // sub-agent event
+	StreamEventToolProgress StreamEventType = "tool_progress" // progress of a running tool, see Progress
+	StreamEventAnswer       StreamEventType = "answer"        // the final answer starts, its deltas follow
 )
 
 // StreamEvent represents a single streaming event from the LLM or tool pipeline.
@@ -27,6 +29,8 @@ type StreamEvent struct {
 	Error         error    // populated on error
 	Usage         LLMUsage // populated on done
 	AgentID       string   // populated for sub-agent events
+	Progress      float64  // for tool_progress: progress so far, when quantified
+	Total         float64  // for tool_progress: total progress, 0 when unknown
 }
 
 // StreamCallback is a function that receives streaming events.
@@ -48,7 +48,7 @@ func TestAskWithStreamingSingleToolCall(t *testing.T) {
 		received = append(received, ev)
 	}
 
-	result, err := askWithStreaming(context.Background(), llm, f, cb)
+	result, err := askWithStreaming(context.Background(), llm, f, cb, defaultLogger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -100,7 +100,7 @@ func TestAskWithStreamingParallelToolCalls(t *testing.T) {
 	}
 
 	f := NewEmptyFragment().AddMessage(UserMessageRole, "Search and weather")
-	result, err := askWithStreaming(context.Background(), llm, f, func(ev StreamEvent) {})
+	result, err := askWithStreaming(context.Background(), llm, f, func(ev StreamEvent) {}, defaultLogger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -137,7 +137,7 @@ func TestAskWithStreamingMixedContentAndToolCalls(t *testing.T) {
 	}
 
 	f := NewEmptyFragment().AddMessage(UserMessageRole, "Look up item 42")
-	result, err := askWithStreaming(context.Background(), llm, f, func(ev StreamEvent) {})
+	result, err := askWithStreaming(context.Background(), llm, f, func(ev StreamEvent) {}, defaultLogger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -163,7 +163,7 @@ func TestAskWithStreamingNoToolCalls(t *testing.T) {
 	}
 
 	f := NewEmptyFragment().AddMessage(UserMessageRole, "Hi")
-	result, err := askWithStreaming(context.Background(), llm, f, func(ev StreamEvent) {})
+	result, err := askWithStreaming(context.Background(), llm, f, func(ev StreamEvent) {}, defaultLogger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -193,7 +193,7 @@ func TestAskWithStreamingFinishReason(t *testing.T) {
 		if ev.Type == StreamEventDone {
 			doneEvent = ev
 		}
-	})
+	}, defaultLogger)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -0,0 +1,91 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUnknownTenant is returned by Tenants for tenants it has no options for.
+var ErrUnknownTenant = errors.New("unknown tenant")
+
+// TenantResolver returns the options of a tenant, applied over the options of
+// the run: its tools, guidelines, limits, entity memory, and so on. See
+// WithTenants.
+type TenantResolver interface {
+	TenantOptions(ctx context.Context, tenant string) ([]Option, error)
+}
+
+// TenantResolverFunc adapts a function to a TenantResolver, e.g. to load the
+// options of tenants from a database.
+type TenantResolverFunc func(ctx context.Context, tenant string) ([]Option, error)
+
+func (f TenantResolverFunc) TenantOptions(ctx context.Context, tenant string) ([]Option, error) {
+	return f(ctx, tenant)
+}
+
+// Tenants is a TenantResolver with the options of each tenant.
+type Tenants map[string][]Option
+
+var _ TenantResolver = Tenants(nil)
+
+func (t Tenants) TenantOptions(ctx context.Context, tenant string) ([]Option, error) {
+	opts, ok := t[tenant]
+	if !ok {
+		return nil, ErrUnknownTenant
+	}
+	return opts, nil
+}
+
+// WithTenants hosts the agents of many tenants in one service: every run
+// needs a tenant on its context (see ContextWithTenant), whose options are
+// resolved with r and applied over those of the run. A run without a tenant,
+// or whose tenant can't be resolved, fails. The stores shared between runs
+// (completion caches, idempotency stores, outcome stores, reasoning stores
+// and entity memories) keep the records of each tenant apart.
+func WithTenants(r TenantResolver) func(o *Options) {
+	return func(o *Options) {
+		o.tenants = r
+	}
+}
+
+// applyTenant applies the options of the tenant of the run, once all the
+// options are applied.
+func applyTenant(o *Options) {
+	if o.tenants == nil || o.tenantResolved {
+		return
+	}
+	o.tenantResolved = true
+	tenant := TenantFromContext(o.context)
+	if tenant == "" {
+		o.tenantErr = errors.New("no tenant in the context, see ContextWithTenant")
+		return
+	}
+	opts, err := o.tenants.TenantOptions(o.context, tenant)
+	if err != nil {
+		o.tenantErr = fmt.Errorf("failed to resolve tenant %q: %w", tenant, err)
+		return
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	o.tenantOptions = opts
+}
+
+// withResolvedTenant returns opts with the options of the tenant resolved for
+// o, so the nested calls of the run don't resolve it again.
+func withResolvedTenant(o *Options, opts []Option) []Option {
+	if o.tenants == nil || o.tenantErr != nil {
+		return opts
+	}
+	return append(append(opts, o.tenantOptions...), func(o *Options) { o.tenantResolved = true })
+}
+
+// tenantScoped returns key scoped by the tenant of ctx, if any.
+func tenantScoped(ctx context.Context, key string) string {
+	tenant := TenantFromContext(ctx)
+	if tenant == "" {
+		return key
+	}
+	return tenant + "\x00" + key
+}
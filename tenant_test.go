@@ -0,0 +1,82 @@
+package cogito_test
+
+import (
+	"context"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Tenants", func() {
+	acme := ContextWithTenant(context.Background(), "acme")
+	globex := ContextWithTenant(context.Background(), "globex")
+
+	tenants := Tenants{
+		"acme":   {WithTools(cogitotest.NewMockTool("acme_crm", "Search the Acme CRM"))},
+		"globex": {WithTools(cogitotest.NewMockTool("globex_erp", "Search the Globex ERP")), WithIterations(1)},
+	}
+
+	offered := func(ctx context.Context) []string {
+		llm := cogitotest.NewMockLLM()
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Hello."},
+		}}})
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Hi")
+		_, err := ExecuteTools(llm, f, WithTools(cogitotest.NewMockTool("search", "Search the web")), WithTenants(tenants), WithContext(ctx))
+		Expect(err).ToNot(HaveOccurred())
+
+		var names []string
+		for _, tool := range llm.Requests()[0].Tools {
+			names = append(names, tool.Function.Name)
+		}
+		return names
+	}
+
+	It("applies the options of the tenant of the context", func() {
+		Expect(offered(acme)).To(And(ContainElements("search", "acme_crm"), Not(ContainElement("globex_erp"))))
+		Expect(offered(globex)).To(And(ContainElements("search", "globex_erp"), Not(ContainElement("acme_crm"))))
+	})
+
+	It("fails runs without a known tenant", func() {
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Hi")
+		_, err := ExecuteTools(cogitotest.NewMockLLM(), f, WithTenants(tenants))
+		Expect(err).To(MatchError(ContainSubstring("no tenant")))
+		_, err = ExecuteTools(cogitotest.NewMockLLM(), f, WithTenants(tenants), WithContext(ContextWithTenant(context.Background(), "initech")))
+		Expect(err).To(MatchError(ErrUnknownTenant))
+	})
+
+	It("keeps the cached completions of tenants apart", func() {
+		cache := NewMemoryCompletionCache()
+		req := openai.ChatCompletionRequest{Model: "m", Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "Hi"}}}
+		cache.Put(acme, req, LLMReply{})
+		_, ok := cache.Get(acme, req)
+		Expect(ok).To(BeTrue())
+		_, ok = cache.Get(globex, req)
+		Expect(ok).To(BeFalse())
+		_, ok = cache.Get(context.Background(), req)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps the outcomes and reasoning of tenants apart", func() {
+		outcomes := &FileOutcomeStore{}
+		Expect(outcomes.Record(acme, ToolOutcome{Tenant: "acme", Intent: "find the customer", Tool: "acme_crm", Quality: 1})).To(Succeed())
+		found, err := outcomes.Similar(acme, "find the customer", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(HaveLen(1))
+		found, err = outcomes.Similar(globex, "find the customer", 10)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeEmpty())
+
+		sink := &MemoryReasoningSink{}
+		Expect(sink.Record(acme, ReasoningRecord{Tenant: "acme", RunID: "run-1", Reasoning: "look up the CRM"})).To(Succeed())
+		records, err := sink.Query(acme, "run-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(HaveLen(1))
+		records, err = sink.Query(globex, "run-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(records).To(BeEmpty())
+	})
+})
@@ -0,0 +1,98 @@
+package cogito
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// TerminalApproval returns a tool call callback, for WithToolCallBack, that
+// asks for each tool call on a terminal. It prints the tool, its arguments
+// and the reasoning of the LLM, then reads one of:
+//
+//	y, yes      run the call
+//	s, skip     skip the call and continue
+//	m, modify   run the call with arguments typed as JSON
+//	a, adjust   send feedback to the LLM, which revises the call
+//	n, no       reject the call, stopping the run
+//
+// Reading past the end of in rejects the call. Prompts are written to out.
+func TerminalApproval(in io.Reader, out io.Writer) func(*ToolChoice, *SessionState) ToolCallDecision {
+	var mu sync.Mutex // parallel tool calls are asked one at a time
+	reader := bufio.NewReader(in)
+	readLine := func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			fmt.Fprintln(out)
+			return "", false
+		}
+		return strings.TrimSpace(line), true
+	}
+
+	return func(tc *ToolChoice, _ *SessionState) ToolCallDecision {
+		mu.Lock()
+		defer mu.Unlock()
+
+		fmt.Fprintf(out, "\nThe agent wants to run the tool %q\n", tc.Name)
+		args, err := json.MarshalIndent(tc.Arguments, "  ", "  ")
+		if err == nil && len(tc.Arguments) > 0 {
+			fmt.Fprintf(out, "Arguments:\n  %s\n", args)
+		}
+		if tc.Reasoning != "" {
+			fmt.Fprintf(out, "Reasoning: %s\n", tc.Reasoning)
+		}
+
+		for {
+			answer, ok := readLine("Run it? [y]es, [s]kip, [m]odify, [a]djust, [n]o: ")
+			if !ok {
+				return ToolCallDecision{Approved: false}
+			}
+			switch strings.ToLower(answer) {
+			case "y", "yes":
+				return ToolCallDecision{Approved: true}
+			case "s", "skip":
+				return ToolCallDecision{Approved: true, Skip: true}
+			case "n", "no":
+				return ToolCallDecision{Approved: false}
+			case "a", "adjust":
+				feedback, ok := readLine("Feedback for the agent: ")
+				if !ok {
+					return ToolCallDecision{Approved: false}
+				}
+				if feedback != "" {
+					return ToolCallDecision{Approved: true, Adjustment: feedback}
+				}
+			case "m", "modify":
+				line, ok := readLine("New arguments (JSON on one line, empty to go back): ")
+				if !ok {
+					return ToolCallDecision{Approved: false}
+				}
+				if line == "" {
+					continue
+				}
+				var arguments map[string]any
+				if err := json.Unmarshal([]byte(line), &arguments); err != nil {
+					fmt.Fprintf(out, "Invalid JSON object: %v\n", err)
+					continue
+				}
+				modified := *tc
+				modified.Arguments = arguments
+				return ToolCallDecision{Approved: true, Modified: &modified}
+			default:
+				fmt.Fprintf(out, "Unknown answer %q\n", answer)
+			}
+		}
+	}
+}
+
+// StdinApproval is TerminalApproval on the standard input and output:
+//
+//	cogito.ExecuteTools(llm, f, cogito.WithTools(tools...), cogito.WithToolCallBack(cogito.StdinApproval()))
+func StdinApproval() func(*ToolChoice, *SessionState) ToolCallDecision {
+	return TerminalApproval(os.Stdin, os.Stdout)
+}
@@ -0,0 +1,66 @@
+package cogito_test
+
+import (
+	"bytes"
+	"strings"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TerminalApproval", func() {
+	tc := &ToolChoice{Name: "delete_file", Arguments: map[string]any{"path": "/tmp/a"}, Reasoning: "The user asked to clean up"}
+
+	decide := func(input string) (ToolCallDecision, string) {
+		var out bytes.Buffer
+		decision := TerminalApproval(strings.NewReader(input), &out)(tc, &SessionState{ToolChoice: tc})
+		return decision, out.String()
+	}
+
+	It("prints the tool call", func() {
+		_, out := decide("y\n")
+		Expect(out).To(ContainSubstring(`The agent wants to run the tool "delete_file"`))
+		Expect(out).To(ContainSubstring(`"path": "/tmp/a"`))
+		Expect(out).To(ContainSubstring("Reasoning: The user asked to clean up"))
+	})
+
+	DescribeTable("decisions",
+		func(input string, expected ToolCallDecision) {
+			decision, _ := decide(input)
+			Expect(decision).To(Equal(expected))
+		},
+		Entry("approve", "y\n", ToolCallDecision{Approved: true}),
+		Entry("skip", "skip\n", ToolCallDecision{Approved: true, Skip: true}),
+		Entry("reject", "n\n", ToolCallDecision{Approved: false}),
+		Entry("adjust", "a\nuse /tmp/b instead\n", ToolCallDecision{Approved: true, Adjustment: "use /tmp/b instead"}),
+		Entry("end of input", "", ToolCallDecision{Approved: false}),
+		Entry("unknown answer, then approve", "maybe\nY\n", ToolCallDecision{Approved: true}),
+	)
+
+	It("modifies the arguments, asking again on invalid JSON", func() {
+		decision, out := decide("m\n{oops\nm\n{\"path\": \"/tmp/b\"}\n")
+		Expect(out).To(ContainSubstring("Invalid JSON object"))
+		Expect(decision.Approved).To(BeTrue())
+		Expect(decision.Modified).ToNot(BeNil())
+		Expect(decision.Modified.Name).To(Equal("delete_file"))
+		Expect(decision.Modified.Arguments).To(Equal(map[string]any{"path": "/tmp/b"}))
+		Expect(tc.Arguments).To(Equal(map[string]any{"path": "/tmp/a"}))
+	})
+
+	It("plugs into WithToolCallBack", func() {
+		tool := cogitotest.NewMockTool("delete_file", "Delete a file")
+		cogitotest.SetRunResult(tool, "deleted")
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.AddCreateChatCompletionFunction("delete_file", `{"path": "/tmp/a"}`)
+		mockLLM.SetAskResponse("Done.")
+
+		var out bytes.Buffer
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Delete /tmp/a")
+		_, err := ExecuteTools(mockLLM, f, WithTools(tool),
+			WithToolCallBack(TerminalApproval(strings.NewReader("m\n{\"path\": \"/tmp/b\"}\n"), &out)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(tool).Calls()).To(Equal([]map[string]any{{"path": "/tmp/b"}}))
+	})
+})
@@ -0,0 +1,54 @@
+// Package mock is kept for compatibility.
+//
+// Deprecated: use github.com/mudler/cogito/cogitotest.
+package mock
+
+import (
+	"github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+)
+
+// MockOpenAIClient is a scriptable LLM.
+//
+// Deprecated: use cogitotest.MockLLM.
+type MockOpenAIClient = cogitotest.MockLLM
+
+// MockTool is a tool returning scripted results.
+//
+// Deprecated: use cogitotest.MockTool.
+type MockTool = cogitotest.MockTool
+
+// NewMockOpenAIClient returns a MockOpenAIClient without responses.
+//
+// Deprecated: use cogitotest.NewMockLLM.
+func NewMockOpenAIClient() *MockOpenAIClient {
+	return cogitotest.NewMockLLM()
+}
+
+// NewMockTool returns a mock tool named name.
+//
+// Deprecated: use cogitotest.NewMockTool.
+func NewMockTool(name, description string) cogito.ToolDefinitionInterface {
+	return cogitotest.NewMockTool(name, description)
+}
+
+// GetMockTool extracts the MockTool from a ToolDef (if it contains one)
+//
+// Deprecated: use cogitotest.GetMockTool.
+func GetMockTool(toolDef cogito.ToolDefinitionInterface) *MockTool {
+	return cogitotest.GetMockTool(toolDef)
+}
+
+// SetRunResult sets the result for a mock tool within a ToolDef
+//
+// Deprecated: use cogitotest.SetRunResult.
+func SetRunResult(toolDef cogito.ToolDefinitionInterface, result string) {
+	cogitotest.SetRunResult(toolDef, result)
+}
+
+// SetRunError sets an error for a mock tool within a ToolDef
+//
+// Deprecated: use cogitotest.SetRunError.
+func SetRunError(toolDef cogito.ToolDefinitionInterface, err error) {
+	cogitotest.SetRunError(toolDef, err)
+}
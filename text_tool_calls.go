@@ -0,0 +1,377 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// textToolsLLM implements tool calling on top of plain chat completions, for
+// backends (or models) without support for the tools API. Tool definitions
+// are described in the system prompt and tool calls are parsed back from the
+// JSON the model replies with. When a tool is forced (as in structured
+// extraction) the model is asked for the arguments only, in JSON mode.
+type textToolsLLM struct {
+	LLM
+	prompts prompt.PromptMap
+	logger  Logger
+}
+
+func (t *textToolsLLM) unwrap() LLM { return t.LLM }
+
+// textToolCall is the JSON shape the model uses to call a tool.
+type textToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+func (t *textToolsLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	original := req
+	tools := req.Tools
+	forced := forcedToolName(req.ToolChoice)
+
+	req.Messages = textToolMessages(req.Messages)
+	if len(tools) == 0 {
+		return t.LLM.CreateChatCompletion(ctx, req)
+	}
+
+	instructions, err := t.instructions(tools, forced)
+	if err != nil {
+		return LLMReply{}, LLMUsage{}, err
+	}
+	req.Messages = withSystemInstructions(req.Messages, instructions)
+	req.Tools = nil
+	req.ToolChoice = nil
+	req.ParallelToolCalls = nil
+	if forced != "" && req.ResponseFormat == nil {
+		req.ResponseFormat = &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject}
+	}
+
+	reply, usage, err := t.LLM.CreateChatCompletion(ctx, req)
+	if err != nil || len(reply.ChatCompletionResponse.Choices) == 0 {
+		return reply, usage, err
+	}
+
+	msg := &reply.ChatCompletionResponse.Choices[0].Message
+	if len(msg.ToolCalls) > 0 {
+		// The backend handled the tools after all
+		return reply, usage, nil
+	}
+	// The chain-of-thought of reasoning models can mention tools
+	reply.ReasoningContent = separateThinking(msg, reply.ReasoningContent)
+	calls := parseTextToolCalls(msg.Content, tools, forced)
+	if len(calls) == 0 {
+		return reply, usage, nil
+	}
+	t.logger.Debug("[textToolsLLM] parsed tool calls from text", "calls", len(calls))
+
+	// The model picked a tool without its arguments: generate them in a
+	// second, forced step
+	for i, call := range calls {
+		if forced != "" || call.Function.Arguments != "{}" || !hasParameters(tools, call.Function.Name) {
+			continue
+		}
+		args, argsUsage, err := t.generateArguments(ctx, original, call.Function.Name)
+		usage = addUsage(usage, argsUsage)
+		if err != nil {
+			t.logger.Warn("Failed to generate tool arguments", "tool", call.Function.Name, "error", err)
+			continue
+		}
+		calls[i].Function.Arguments = args
+	}
+
+	msg.ToolCalls = calls
+	msg.Content = ""
+	reply.ChatCompletionResponse.Choices[0].FinishReason = openai.FinishReasonToolCalls
+	return reply, usage, nil
+}
+
+// generateArguments asks the model for the arguments of the tool it selected
+// in req, forcing that tool.
+func (t *textToolsLLM) generateArguments(ctx context.Context, req openai.ChatCompletionRequest, name string) (string, LLMUsage, error) {
+	for _, tool := range req.Tools {
+		if tool.Function != nil && tool.Function.Name == name {
+			req.Tools = []openai.Tool{tool}
+			break
+		}
+	}
+	req.ToolChoice = openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: name}}
+	req.Messages = append(slices.Clone(req.Messages), openai.ChatCompletionMessage{
+		Role:    AssistantMessageRole.String(),
+		Content: fmt.Sprintf("I will use the tool %q.", name),
+	})
+
+	reply, usage, err := t.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return "", usage, err
+	}
+	if len(reply.ChatCompletionResponse.Choices) == 0 || len(reply.ChatCompletionResponse.Choices[0].Message.ToolCalls) == 0 {
+		return "", usage, fmt.Errorf("no arguments in reply")
+	}
+	return reply.ChatCompletionResponse.Choices[0].Message.ToolCalls[0].Function.Arguments, usage, nil
+}
+
+// hasParameters reports whether the named tool declares any parameter.
+func hasParameters(tools []openai.Tool, name string) bool {
+	for _, tool := range tools {
+		if tool.Function == nil || tool.Function.Name != name || tool.Function.Parameters == nil {
+			continue
+		}
+		dat, err := json.Marshal(tool.Function.Parameters)
+		if err != nil {
+			return false
+		}
+		var schema struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		}
+		return json.Unmarshal(dat, &schema) == nil && len(schema.Properties) > 0
+	}
+	return false
+}
+
+func (t *textToolsLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	converted := f
+	converted.Messages = textToolMessages(f.Messages)
+	res, err := t.LLM.Ask(ctx, converted)
+	if err != nil {
+		return res, err
+	}
+	// Give back the caller's own history, followed by the new messages
+	if len(res.Messages) >= len(converted.Messages) {
+		res.Messages = append(slices.Clone(f.Messages), res.Messages[len(converted.Messages):]...)
+	}
+	return res, nil
+}
+
+// instructions renders the tool protocol prompt for tools.
+func (t *textToolsLLM) instructions(tools []openai.Tool, forced string) (string, error) {
+	type toolSpec struct {
+		Name, Description, Parameters string
+	}
+	var specs []toolSpec
+	for _, tool := range tools {
+		if tool.Function == nil || (forced != "" && tool.Function.Name != forced) {
+			continue
+		}
+		params := "{}"
+		if tool.Function.Parameters != nil {
+			dat, err := json.Marshal(tool.Function.Parameters)
+			if err != nil {
+				return "", fmt.Errorf("failed to encode parameters of tool %q: %w", tool.Function.Name, err)
+			}
+			params = string(dat)
+		}
+		specs = append(specs, toolSpec{tool.Function.Name, tool.Function.Description, params})
+	}
+
+	out, err := t.prompts.GetPrompt(prompt.PromptTextToolCallsType).Render(struct {
+		Tools  []toolSpec
+		Forced string
+	}{specs, forced})
+	if err != nil {
+		return "", fmt.Errorf("failed to render tool call instructions: %w", err)
+	}
+	return out, nil
+}
+
+// textToolsStreamingLLM preserves StreamingLLM. Requests with tools are
+// answered in one go (the reply has to be parsed whole) and replayed as
+// stream events; other requests stream through.
+type textToolsStreamingLLM struct {
+	textToolsLLM
+	streaming StreamingLLM
+}
+
+func (t *textToolsStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	if len(req.Tools) == 0 {
+		req.Messages = textToolMessages(req.Messages)
+		return t.streaming.CreateChatCompletionStream(ctx, req)
+	}
+
+	reply, usage, err := t.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan StreamEvent, 4)
+	go func() {
+		defer close(ch)
+		if reply.ReasoningContent != "" {
+			ch <- StreamEvent{Type: StreamEventReasoning, Content: reply.ReasoningContent}
+		}
+		finish := "stop"
+		if choices := reply.ChatCompletionResponse.Choices; len(choices) > 0 {
+			msg := choices[0].Message
+			if msg.Content != "" {
+				ch <- StreamEvent{Type: StreamEventContent, Content: msg.Content}
+			}
+			for i, tc := range msg.ToolCalls {
+				ch <- StreamEvent{Type: StreamEventToolCall, ToolName: tc.Function.Name, ToolArgs: tc.Function.Arguments,
+					ToolCallID: tc.ID, ToolCallIndex: i}
+				finish = string(openai.FinishReasonToolCalls)
+			}
+		}
+		ch <- StreamEvent{Type: StreamEventDone, FinishReason: finish, Usage: usage}
+	}()
+	return ch, nil
+}
+
+// newTextToolsLLM wraps llm so tool calls go through the text protocol.
+func newTextToolsLLM(llm LLM, prompts prompt.PromptMap, logger Logger) LLM {
+	if _, ok := unwrapLLM[*textToolsLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*textToolsStreamingLLM](llm); ok {
+		return llm
+	}
+	base := textToolsLLM{LLM: llm, prompts: prompts, logger: logger}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &textToolsStreamingLLM{textToolsLLM: base, streaming: s}
+	}
+	return &base
+}
+
+// forcedToolName returns the function a request's tool choice forces, if any.
+func forcedToolName(choice any) string {
+	switch c := choice.(type) {
+	case openai.ToolChoice:
+		return c.Function.Name
+	case *openai.ToolChoice:
+		if c != nil {
+			return c.Function.Name
+		}
+	}
+	return ""
+}
+
+// withSystemInstructions adds instructions to the leading system message,
+// creating one if needed: small models often only honour a single system
+// prompt at the start of the conversation.
+func withSystemInstructions(messages []openai.ChatCompletionMessage, instructions string) []openai.ChatCompletionMessage {
+	if len(messages) > 0 && messages[0].Role == SystemMessageRole.String() && len(messages[0].MultiContent) == 0 {
+		messages[0].Content = strings.TrimSpace(messages[0].Content + "\n\n" + instructions)
+		return messages
+	}
+	return append([]openai.ChatCompletionMessage{{Role: SystemMessageRole.String(), Content: instructions}}, messages...)
+}
+
+// textToolMessages rewrites tool calls and tool results in the history as
+// plain messages, for backends that reject the tool roles.
+func textToolMessages(messages []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	out := slices.Clone(messages)
+	names := map[string]string{}
+	for i, msg := range out {
+		switch {
+		case len(msg.ToolCalls) > 0:
+			calls := make([]textToolCall, 0, len(msg.ToolCalls))
+			for _, tc := range msg.ToolCalls {
+				names[tc.ID] = tc.Function.Name
+				args := json.RawMessage(tc.Function.Arguments)
+				if !json.Valid(args) {
+					args = json.RawMessage("{}")
+				}
+				calls = append(calls, textToolCall{Name: tc.Function.Name, Arguments: args})
+			}
+			var dat []byte
+			if len(calls) == 1 {
+				dat, _ = json.Marshal(calls[0])
+			} else {
+				dat, _ = json.Marshal(calls)
+			}
+			out[i].Content = strings.TrimSpace(msg.Content + "\n" + string(dat))
+			out[i].ToolCalls = nil
+		case msg.Role == openai.ChatMessageRoleTool:
+			name := names[msg.ToolCallID]
+			if name == "" {
+				name = msg.Name
+			}
+			out[i] = openai.ChatCompletionMessage{
+				Role:    UserMessageRole.String(),
+				Content: fmt.Sprintf("Result of the tool %q:\n%s", name, msg.Content),
+			}
+		}
+	}
+	return out
+}
+
+// parseTextToolCalls extracts tool calls from a text reply. With a forced
+// tool the reply is the arguments object; otherwise it is a call object or
+// an array of them. Calls to unknown tools are ignored.
+func parseTextToolCalls(content string, tools []openai.Tool, forced string) []openai.ToolCall {
+	raw, ok := extractJSON(content)
+	if !ok {
+		return nil
+	}
+
+	newCall := func(name string, args json.RawMessage) openai.ToolCall {
+		if len(args) == 0 || string(args) == "null" {
+			args = json.RawMessage("{}")
+		}
+		return openai.ToolCall{
+			ID:       uuid.New().String(),
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: name, Arguments: string(args)},
+		}
+	}
+
+	if forced != "" {
+		// Tolerate models wrapping the arguments in the call shape anyway
+		var call textToolCall
+		if err := json.Unmarshal(raw, &call); err == nil && call.Name == forced && len(call.Arguments) > 0 {
+			raw = call.Arguments
+		}
+		if raw[0] != '{' {
+			return nil
+		}
+		return []openai.ToolCall{newCall(forced, raw)}
+	}
+
+	known := map[string]bool{}
+	for _, tool := range tools {
+		if tool.Function != nil {
+			known[tool.Function.Name] = true
+		}
+	}
+
+	var calls []textToolCall
+	if raw[0] == '[' {
+		if err := json.Unmarshal(raw, &calls); err != nil {
+			return nil
+		}
+	} else {
+		var call textToolCall
+		if err := json.Unmarshal(raw, &call); err != nil {
+			return nil
+		}
+		calls = []textToolCall{call}
+	}
+
+	var out []openai.ToolCall
+	for _, c := range calls {
+		if !known[c.Name] {
+			continue
+		}
+		out = append(out, newCall(c.Name, c.Arguments))
+	}
+	return out
+}
+
+// extractJSON returns the first JSON object or array found in s, skipping
+// any surrounding prose or code fences.
+func extractJSON(s string) (json.RawMessage, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != '{' && s[i] != '[' {
+			continue
+		}
+		var raw json.RawMessage
+		if err := json.NewDecoder(strings.NewReader(s[i:])).Decode(&raw); err == nil {
+			return raw, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,129 @@
+package cogito
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// textReplyLLM replies with the texts of replies in turn (repeating the last
+// one), recording requests.
+type textReplyLLM struct {
+	fakeLLM
+	replies  []string
+	requests []openai.ChatCompletionRequest
+}
+
+func (r *textReplyLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	r.requests = append(r.requests, req)
+	reply := r.replies[min(len(r.requests), len(r.replies))-1]
+	return LLMReply{ChatCompletionResponse: openai.ChatCompletionResponse{
+		Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Role: "assistant", Content: reply}}},
+	}}, LLMUsage{TotalTokens: 10}, nil
+}
+
+func TestParseTextToolCalls(t *testing.T) {
+	tools := []openai.Tool{
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "search"}},
+		{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "weather"}},
+	}
+
+	calls := parseTextToolCalls("Sure!\n```json\n{\"name\": \"search\", \"arguments\": {\"q\": \"go\"}}\n```", tools, "")
+	if len(calls) != 1 || calls[0].Function.Name != "search" || calls[0].Function.Arguments != `{"q": "go"}` || calls[0].ID == "" {
+		t.Errorf("single call = %+v", calls)
+	}
+
+	calls = parseTextToolCalls(`[{"name":"search","arguments":{"q":"a"}},{"name":"unknown"},{"name":"weather"}]`, tools, "")
+	if len(calls) != 2 || calls[1].Function.Name != "weather" || calls[1].Function.Arguments != "{}" {
+		t.Errorf("array calls = %+v", calls)
+	}
+
+	if calls := parseTextToolCalls("The answer is 42.", tools, ""); calls != nil {
+		t.Errorf("plain text parsed as %+v", calls)
+	}
+
+	// Forced tools take the arguments object, wrapped or not
+	for _, reply := range []string{`{"city": "Rome"}`, `{"name": "weather", "arguments": {"city": "Rome"}}`} {
+		calls := parseTextToolCalls(reply, tools, "weather")
+		if len(calls) != 1 || calls[0].Function.Name != "weather" || !strings.Contains(calls[0].Function.Arguments, `"Rome"`) {
+			t.Errorf("forced %q = %+v", reply, calls)
+		}
+	}
+}
+
+func TestTextToolMessages(t *testing.T) {
+	messages := []openai.ChatCompletionMessage{
+		{Role: "user", Content: "weather?"},
+		{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "1", Function: openai.FunctionCall{Name: "weather", Arguments: `{"city":"Rome"}`}}}},
+		{Role: "tool", ToolCallID: "1", Content: "sunny"},
+	}
+	out := textToolMessages(messages)
+	if out[1].ToolCalls != nil || out[1].Content != `{"name":"weather","arguments":{"city":"Rome"}}` {
+		t.Errorf("assistant = %+v", out[1])
+	}
+	if out[2].Role != "user" || out[2].ToolCallID != "" || !strings.Contains(out[2].Content, `"weather"`) {
+		t.Errorf("tool result = %+v", out[2])
+	}
+	if messages[1].ToolCalls == nil || messages[2].Role != "tool" {
+		t.Error("input messages were modified")
+	}
+}
+
+func TestTextToolsLLMForcedToolUsesJSONMode(t *testing.T) {
+	inner := &textReplyLLM{replies: []string{`{"answer": "yes"}`}}
+	llm := newTextToolsLLM(inner, nil, defaultLogger)
+	if newTextToolsLLM(llm, nil, defaultLogger) != llm {
+		t.Error("wrapped twice")
+	}
+
+	reply, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages:   []openai.ChatCompletionMessage{{Role: "system", Content: "Be brief."}, {Role: "user", Content: "ok?"}},
+		Tools:      []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "json"}}},
+		ToolChoice: openai.ToolChoice{Type: openai.ToolTypeFunction, Function: openai.ToolFunction{Name: "json"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := inner.requests[0]
+	if req.Tools != nil || req.ToolChoice != nil || req.ResponseFormat == nil || req.ResponseFormat.Type != openai.ChatCompletionResponseFormatTypeJSONObject {
+		t.Errorf("request = %+v", req)
+	}
+	if len(req.Messages) != 2 || !strings.HasPrefix(req.Messages[0].Content, "Be brief.") || !strings.Contains(req.Messages[0].Content, `"json"`) {
+		t.Errorf("messages = %+v", req.Messages)
+	}
+	calls := reply.ChatCompletionResponse.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Arguments != `{"answer": "yes"}` {
+		t.Errorf("calls = %+v", calls)
+	}
+}
+
+func TestTextToolsLLMGeneratesMissingArguments(t *testing.T) {
+	inner := &textReplyLLM{replies: []string{`{"name": "weather"}`, `{"city": "Rome"}`}}
+	llm := newTextToolsLLM(inner, nil, defaultLogger)
+
+	weather := openai.Tool{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{
+		Name:       "weather",
+		Parameters: map[string]any{"type": "object", "properties": map[string]any{"city": map[string]any{"type": "string"}}},
+	}}
+	reply, usage, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "weather in Rome?"}},
+		Tools:    []openai.Tool{weather, {Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "reply"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inner.requests) != 2 || usage.TotalTokens != 20 {
+		t.Fatalf("requests = %d, usage = %+v", len(inner.requests), usage)
+	}
+	second := inner.requests[1]
+	if second.ResponseFormat == nil || !strings.Contains(second.Messages[0].Content, `You must use the tool "weather"`) ||
+		second.Messages[len(second.Messages)-1].Role != "assistant" {
+		t.Errorf("argument request = %+v", second)
+	}
+	calls := reply.ChatCompletionResponse.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Arguments != `{"city": "Rome"}` {
+		t.Errorf("calls = %+v", calls)
+	}
+}
@@ -0,0 +1,89 @@
+package cogito
+
+import (
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// thinkTags are the tags reasoning models (DeepSeek-R1, QwQ, ...) wrap their
+// chain-of-thought in when the backend returns it inline in the content.
+var thinkTags = []string{"think", "thinking"}
+
+// SplitThinking separates the chain-of-thought of a reasoning model, in
+// <think> blocks of content, from the answer. A closing tag without an
+// opening one ends a chain-of-thought opened by the chat template, and an
+// unterminated block a truncated one. Content without blocks is returned
+// as the answer unchanged.
+func SplitThinking(content string) (thinking, answer string) {
+	var thoughts []string
+	rest := content
+	found := false
+	for _, tag := range thinkTags {
+		open, close := "<"+tag+">", "</"+tag+">"
+		if !strings.Contains(rest, open) && !strings.Contains(rest, close) {
+			continue
+		}
+		found = true
+		if i := strings.Index(rest, close); i >= 0 && !strings.Contains(rest[:i], open) {
+			thoughts = append(thoughts, rest[:i])
+			rest = rest[i+len(close):]
+		}
+		for {
+			start := strings.Index(rest, open)
+			if start < 0 {
+				break
+			}
+			end := strings.Index(rest[start:], close)
+			if end < 0 {
+				thoughts = append(thoughts, rest[start+len(open):])
+				rest = rest[:start]
+				break
+			}
+			thoughts = append(thoughts, rest[start+len(open):start+end])
+			rest = rest[:start] + rest[start+end+len(close):]
+		}
+	}
+	if !found {
+		return "", content
+	}
+	for _, t := range thoughts {
+		thinking = joinReasoning(thinking, strings.TrimSpace(t))
+	}
+	return thinking, strings.TrimSpace(rest)
+}
+
+// joinReasoning appends the chain-of-thought b to a.
+func joinReasoning(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return strings.TrimSpace(a) + "\n\n" + strings.TrimSpace(b)
+}
+
+// separateThinking moves the <think> blocks of the content of msg to its
+// ReasoningContent, and returns reasoning, the chain-of-thought returned
+// apart by the backend, with them appended.
+func separateThinking(msg *openai.ChatCompletionMessage, reasoning string) string {
+	thinking, answer := SplitThinking(msg.Content)
+	if thinking == "" && answer == msg.Content {
+		return reasoning
+	}
+	msg.Content = answer
+	msg.ReasoningContent = joinReasoning(msg.ReasoningContent, thinking)
+	return joinReasoning(reasoning, thinking)
+}
+
+// logFinalReasoning records the chain-of-thought of the final reply of f in
+// its ReasoningLog and the reasoning sink of o.
+func logFinalReasoning(o *Options, f Fragment) {
+	msg := f.LastMessage()
+	if msg == nil || f.Status == nil || msg.ReasoningContent == "" {
+		return
+	}
+	f.Status.ReasoningLog = append(f.Status.ReasoningLog, msg.ReasoningContent)
+	recordReasoning(o, f, ReasoningFinal, msg.ReasoningContent)
+}
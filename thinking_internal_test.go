@@ -0,0 +1,24 @@
+package cogito
+
+import "testing"
+
+func TestSplitThinking(t *testing.T) {
+	for _, tc := range []struct {
+		content, thinking, answer string
+	}{
+		{"<think>The user greets me.</think>\n\nHello!", "The user greets me.", "Hello!"},
+		// Opened by the chat template (DeepSeek-R1)
+		{"The user greets me.\n</think>\nHello!", "The user greets me.", "Hello!"},
+		{"<think>a</think>Hi <think>b</think>there", "a\n\nb", "Hi there"},
+		{"<thinking>a</thinking>Hi", "a", "Hi"},
+		// Truncated while thinking
+		{"<think>Let me see", "Let me see", ""},
+		// Content without blocks is left alone
+		{"  Hello!\n", "", "  Hello!\n"},
+	} {
+		thinking, answer := SplitThinking(tc.content)
+		if thinking != tc.thinking || answer != tc.answer {
+			t.Errorf("SplitThinking(%q) = %q, %q, want %q, %q", tc.content, thinking, answer, tc.thinking, tc.answer)
+		}
+	}
+}
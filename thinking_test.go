@@ -0,0 +1,44 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Reasoning models", func() {
+	It("moves <think> blocks out of tool selection and the final reply", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.HasTool("get_weather")).
+			ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("<think>The tool said 21°C.</think>\nIt is 21°C in Rome.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		result, err := ExecuteTools(mockLLM, f, WithTools(weather))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.LastMessage().Content).To(Equal("It is 21°C in Rome."))
+		Expect(result.LastMessage().ReasoningContent).To(Equal("The tool said 21°C."))
+		Expect(result.Status.ReasoningLog).To(ContainElement("The tool said 21°C."))
+	})
+	It("does not parse tool calls from the chain-of-thought", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		deleteFile := cogitotest.NewMockTool("delete_file", "Delete a file")
+		cogitotest.SetRunResult(weather, "Rome: 21°C")
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.MessageContains("get_weather")).ReplyText(
+			`<think>Not {"name": "delete_file", "arguments": {}}, the user wants the weather.</think>` +
+				`{"name": "get_weather", "arguments": {"city": "Rome"}}`)
+		mockLLM.SetAskResponse("It is 21°C in Rome.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		_, err := ExecuteTools(mockLLM, f, WithTools(weather, deleteFile), WithTextBasedToolCalls())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(deleteFile).Calls()).To(BeEmpty())
+		Expect(cogitotest.GetMockTool(weather).Calls()).To(Equal([]map[string]any{{"city": "Rome"}}))
+	})
+})
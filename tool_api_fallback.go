@@ -0,0 +1,150 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// toolsParams are the request parameters of the tools API.
+var toolsParams = []string{"tools", "tool_choice", "functions", "function_call", "parallel_tool_calls"}
+
+// toolsUnsupportedMessages match the errors of backends and gateways
+// without support for the tools API.
+var toolsUnsupportedMessages = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\b(?:does not|doesn't|do not|don't) support (?:tools|tool use|tool calls|tool calling|function calling|functions)\b`),
+	regexp.MustCompile(`(?i)\b(?:tools|tool use|tool calls|tool calling|function calling|functions|tool_choice) (?:is|are) not supported\b`),
+	regexp.MustCompile(`(?i)\bunrecognized request arguments? supplied: .*\b(?:tools|tool_choice|functions)\b`),
+	regexp.MustCompile(`(?i)\bunknown field "?(?:tools|tool_choice|functions|function_call|parallel_tool_calls)\b`),
+}
+
+// IsToolsUnsupportedError reports whether err is a provider error rejecting
+// the tools of a request, as returned by backends and gateways without
+// support for the tools API: an API error on a tools parameter with the
+// unsupported_parameter or unknown_parameter code, or an error saying that
+// tools are not supported. Other errors about tools, such as an invalid tool
+// schema, are not.
+func IsToolsUnsupportedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) && apiErr.Param != nil && slices.Contains(toolsParams, *apiErr.Param) {
+		if code, ok := apiErr.Code.(string); ok && (code == "unsupported_parameter" || code == "unknown_parameter") {
+			return true
+		}
+	}
+	for _, re := range toolsUnsupportedMessages {
+		if re.MatchString(err.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolFallbackLLM wraps an LLM, switching to text-based tool calling (see
+// WithTextBasedToolCalls) for good once the backend rejects the tools of a
+// request.
+type toolFallbackLLM struct {
+	LLM
+	text   LLM
+	logger Logger
+
+	mu     sync.Mutex
+	reason string // error of the backend, once fallen back
+}
+
+func (t *toolFallbackLLM) unwrap() LLM { return t.LLM }
+
+// fallenBack returns the error that made t fall back to text, or "".
+func (t *toolFallbackLLM) fallenBack() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.reason
+}
+
+// fallBack reports whether err rejects the tools API, switching t to text
+// if so.
+func (t *toolFallbackLLM) fallBack(err error) bool {
+	if !IsToolsUnsupportedError(err) {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reason == "" {
+		t.logger.Error("The backend rejected the tools API, falling back to text-based tool calls for the rest of the run; "+
+			"pass DisableToolAPIFallback to fail instead", "error", err)
+		t.reason = err.Error()
+	}
+	return true
+}
+
+func (t *toolFallbackLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	if t.fallenBack() != "" {
+		return t.text.CreateChatCompletion(ctx, req)
+	}
+	reply, usage, err := t.LLM.CreateChatCompletion(ctx, req)
+	if err == nil || len(req.Tools) == 0 || !t.fallBack(err) {
+		return reply, usage, err
+	}
+	return t.text.CreateChatCompletion(ctx, req)
+}
+
+// toolFallbackStreamingLLM preserves StreamingLLM. Only errors returned when
+// opening the stream fall back; errors delivered mid-stream do not.
+type toolFallbackStreamingLLM struct {
+	toolFallbackLLM
+	streaming StreamingLLM
+}
+
+func (t *toolFallbackStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	text := t.text.(StreamingLLM)
+	if t.fallenBack() != "" {
+		return text.CreateChatCompletionStream(ctx, req)
+	}
+	ch, err := t.streaming.CreateChatCompletionStream(ctx, req)
+	if err == nil || len(req.Tools) == 0 || !t.fallBack(err) {
+		return ch, err
+	}
+	return text.CreateChatCompletionStream(ctx, req)
+}
+
+// newToolFallbackLLM wraps llm so a backend rejecting the tools API is
+// retried with text-based tool calls. An LLM already falling back, or
+// already calling tools through text, is returned as is.
+func newToolFallbackLLM(llm LLM, prompts prompt.PromptMap, logger Logger) LLM {
+	if _, ok := unwrapLLM[*toolFallbackLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*toolFallbackStreamingLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*textToolsLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*textToolsStreamingLLM](llm); ok {
+		return llm
+	}
+	text := newTextToolsLLM(llm, prompts, logger)
+	if s, ok := llm.(StreamingLLM); ok {
+		return &toolFallbackStreamingLLM{toolFallbackLLM: toolFallbackLLM{LLM: llm, text: text, logger: logger}, streaming: s}
+	}
+	return &toolFallbackLLM{LLM: llm, text: text, logger: logger}
+}
+
+// toolAPIFallback returns the error that made llm fall back to text-based
+// tool calls, or "".
+func toolAPIFallback(llm LLM) string {
+	if t, ok := unwrapLLM[*toolFallbackLLM](llm); ok {
+		return t.fallenBack()
+	}
+	if t, ok := unwrapLLM[*toolFallbackStreamingLLM](llm); ok {
+		return t.fallenBack()
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// toolsRejectingLLM fails the requests with tools, as gateways without the
+// tools API do.
+type toolsRejectingLLM struct {
+	textReplyLLM
+	rejected int
+}
+
+func (r *toolsRejectingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	if len(req.Tools) > 0 {
+		r.rejected++
+		return LLMReply{}, LLMUsage{}, errors.New(`400 Bad Request: unrecognized request argument supplied: tools`)
+	}
+	return r.textReplyLLM.CreateChatCompletion(ctx, req)
+}
+
+func TestIsToolsUnsupportedError(t *testing.T) {
+	param := "tools"
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("connection refused"), false},
+		{errors.New("model not supported"), false},
+		{&openai.APIError{Code: "unsupported_parameter", Param: &param}, true},
+		{fmt.Errorf("wrapped: %w", &openai.APIError{Code: "unknown_parameter", Param: &param}), true},
+		{errors.New("this model does not support tools"), true},
+		{errors.New("registry.ollama.ai/library/gemma:2b does not support tools"), true},
+		{errors.New(`json: unknown field "tool_choice"`), true},
+		{errors.New(`400 Bad Request: unrecognized request argument supplied: tools`), true},
+		// Errors about the tools, not the tools API
+		{&openai.APIError{Code: "invalid_value", Param: &param, Message: "Invalid schema for function 'search'"}, false},
+		{errors.New("tool search failed: this city is not supported"), false},
+		{errors.New("function get_weather is not allowed for this user"), false},
+	}
+	for _, c := range cases {
+		if got := IsToolsUnsupportedError(c.err); got != c.want {
+			t.Errorf("IsToolsUnsupportedError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestToolFallbackLLMSwitchesToText(t *testing.T) {
+	inner := &toolsRejectingLLM{textReplyLLM: textReplyLLM{replies: []string{`{"name": "search", "arguments": {"q": "go"}}`}}}
+	llm := newToolFallbackLLM(inner, nil, defaultLogger)
+	if newToolFallbackLLM(llm, nil, defaultLogger) != llm {
+		t.Error("wrapped twice")
+	}
+
+	req := openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "search go"}},
+		Tools:    []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "search"}}},
+	}
+	for i := range 2 {
+		reply, _, err := llm.CreateChatCompletion(context.Background(), req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		calls := reply.ChatCompletionResponse.Choices[0].Message.ToolCalls
+		if len(calls) != 1 || calls[0].Function.Name != "search" {
+			t.Errorf("request %d: calls = %+v", i, calls)
+		}
+	}
+	// The tools API is only tried once
+	if inner.rejected != 1 || len(inner.requests) != 2 {
+		t.Errorf("rejected = %d, text requests = %d", inner.rejected, len(inner.requests))
+	}
+	if toolAPIFallback(llm) == "" {
+		t.Error("fallback not recorded")
+	}
+}
+
+func TestExecuteToolsRecordsToolAPIFallback(t *testing.T) {
+	inner := &toolsRejectingLLM{textReplyLLM: textReplyLLM{replies: []string{`{"name": "lookup", "arguments": {"company": "acme"}}`, "Done."}}}
+	runner := &lookupRunner{}
+	tool := NewToolDefinition(runner, lookupArgs{}, "lookup", "Look up the plan of a company")
+
+	result, err := ExecuteTools(inner, NewEmptyFragment().AddMessage(UserMessageRole, "Plan of acme?"), WithTools(tool))
+	if err != nil && !errors.Is(err, ErrNoToolSelected) {
+		t.Fatal(err)
+	}
+	if runner.runs.Load() != 1 {
+		t.Fatalf("tool ran %d times, want 1", runner.runs.Load())
+	}
+	if result.Status == nil || result.Status.ToolAPIFallback == "" {
+		t.Fatalf("status = %+v", result.Status)
+	}
+
+	// Disabled, the error fails the run
+	inner = &toolsRejectingLLM{textReplyLLM: textReplyLLM{replies: []string{"Done."}}}
+	if _, err := ExecuteTools(inner, NewEmptyFragment().AddMessage(UserMessageRole, "Plan of acme?"), WithTools(tool), WithMaxRetries(1), DisableToolAPIFallback); err == nil || !IsToolsUnsupportedError(err) {
+		t.Fatalf("error = %v", err)
+	}
+}
@@ -0,0 +1,127 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// toolResultCache memoizes successful tool results by tool name and
+// arguments. Concurrent calls with the same key wait for the first one.
+type toolResultCache struct {
+	tools []string // tools to cache, all when empty
+
+	mu      sync.Mutex
+	entries map[string]*toolCacheEntry
+}
+
+type toolCacheEntry struct {
+	done       chan struct{}
+	result     string
+	resultData any
+	err        error
+}
+
+func newToolResultCache(tools []string) *toolResultCache {
+	return &toolResultCache{tools: tools, entries: map[string]*toolCacheEntry{}}
+}
+
+// execute runs the call of tool name with args through the cache. Failed
+// calls are not cached, so the next call with the same arguments runs again.
+func (c *toolResultCache) execute(ctx context.Context, name string, args map[string]any, run func() (string, any, error)) (string, any, error) {
+	if len(c.tools) > 0 && !slices.Contains(c.tools, name) {
+		return run()
+	}
+	// Map keys are sorted by encoding/json, so equal arguments give equal keys
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		return run()
+	}
+	key := name + "\x00" + string(encoded)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return "", nil, ctx.Err()
+		}
+		if entry.err == nil {
+			return entry.result, entry.resultData, nil
+		}
+		return run()
+	}
+	entry := &toolCacheEntry{done: make(chan struct{})}
+	c.entries[key] = entry
+	c.mu.Unlock()
+
+	entry.result, entry.resultData, entry.err = run()
+	if entry.err != nil {
+		c.mu.Lock()
+		delete(c.entries, key)
+		c.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.result, entry.resultData, entry.err
+}
+
+// toolCallContext returns ctx with what the tools read from it during call:
+// the call itself, progress reporting, variables and secrets.
+func toolCallContext(ctx context.Context, o *Options, call ToolCallInfo) context.Context {
+	ctx = contextWithToolCall(ctx, call)
+	ctx = contextWithProgress(ctx, o, call.Choice)
+	if o.vars != nil {
+		ctx = context.WithValue(ctx, varsKey{}, o.vars)
+	}
+	if o.secrets != nil {
+		ctx = ContextWithSecrets(ctx, o.secrets)
+	}
+	return ctx
+}
+
+// runTool executes the tool call described by call, through the idempotency
+// store, the tool result cache and the tool rate limiter when enabled, within
+// the concurrency limits of the tool, initializing it first when it is a
+// StatefulTool. The tool receives call, its progress reporter, the secrets
+// and the variables of o in its context, and its arguments with their
+// variable references replaced.
+func runTool(o *Options, tool ToolDefinitionInterface, call ToolCallInfo) (string, any, error) {
+	args := o.vars.renderArguments(call.Choice.Arguments)
+	run := func(ctx context.Context) (string, any, error) {
+		if o.quotas != nil {
+			o.quotas.add(o, QuotaUsage{ToolCalls: 1})
+		}
+		if o.toolRateLimiter != nil {
+			if err := o.toolRateLimiter.Wait(ctx); err != nil {
+				return "", nil, err
+			}
+		}
+		release, err := acquireToolSlots(ctx, tool, toolConcurrency(tool))
+		if err != nil {
+			return "", nil, err
+		}
+		defer release()
+		if err := o.toolLifecycle.init(ctx, call.Choice.Name, tool); err != nil {
+			return "", nil, err
+		}
+		result, resultData, err := executeTool(toolCallContext(ctx, o, call), tool, args)
+		if err == nil && o.compensations != nil {
+			if fn := toolCompensation(tool); fn != nil {
+				o.compensations.add(call.Choice.ID, call.Choice.Name, args, result, fn)
+			}
+		}
+		return result, resultData, err
+	}
+	if o.toolCache != nil {
+		uncached := run
+		run = func(ctx context.Context) (string, any, error) {
+			return o.toolCache.execute(ctx, tool.Tool().Function.Name, args, func() (string, any, error) { return uncached(ctx) })
+		}
+	}
+	if o.idempotency != nil {
+		return o.idempotency.execute(o, tool.Tool().Function.Name, args, run)
+	}
+	return run(o.context)
+}
@@ -0,0 +1,165 @@
+package cogito
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// ToolConcurrency limits how a tool may run concurrently with itself and
+// with other tools, e.g. for stateful browsers or database migrations. The
+// limits apply process-wide, across parallel tool calls, plans and
+// sub-agents: MaxParallel bounds the calls of the tool value declaring it,
+// while groups are shared by every tool naming them.
+type ToolConcurrency struct {
+	// MaxParallel is the maximum number of simultaneous calls of the tool.
+	// 0 means unlimited.
+	MaxParallel int
+	// Group serializes the tools sharing it: only one call of any tool of
+	// the group runs at a time. Empty means no group.
+	Group string
+}
+
+// ConcurrencyLimitedTool is implemented by tools declaring concurrency
+// limits. ToolDefinition implements it with its Concurrency field; use
+// LimitConcurrency for other tools.
+type ConcurrencyLimitedTool interface {
+	ToolConcurrency() ToolConcurrency
+}
+
+// ToolConcurrency implements ConcurrencyLimitedTool.
+func (t *ToolDefinition[T]) ToolConcurrency() ToolConcurrency {
+	return t.Concurrency
+}
+
+// LimitConcurrency returns tool with the concurrency limits c, for tools not
+// built with ToolDefinition (MCP, OpenAPI, ...).
+func LimitConcurrency(tool ToolDefinitionInterface, c ToolConcurrency) ToolDefinitionInterface {
+	return &limitedTool{ToolDefinitionInterface: tool, concurrency: c}
+}
+
+type limitedTool struct {
+	ToolDefinitionInterface
+	concurrency ToolConcurrency
+}
+
+func (t *limitedTool) ToolConcurrency() ToolConcurrency { return t.concurrency }
+
+func (t *limitedTool) OutputJSONSchema() *jsonschema.Definition {
+	return toolOutputSchema(t.ToolDefinitionInterface)
+}
+
+func (t *limitedTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	return executeTool(ctx, t.ToolDefinitionInterface, args)
+}
+
+// toolConcurrency returns the concurrency limits of tool, if any.
+func toolConcurrency(tool ToolDefinitionInterface) ToolConcurrency {
+	if t, ok := tool.(ConcurrencyLimitedTool); ok {
+		return t.ToolConcurrency()
+	}
+	return ToolConcurrency{}
+}
+
+// toolSlotsKey identifies a semaphore: the tool declaring a MaxParallel
+// limit, or the name of a group.
+type toolSlotsKey struct {
+	tool  ToolDefinitionInterface
+	group string
+}
+
+// toolSlots is a semaphore and the number of calls holding or waiting on it.
+type toolSlots struct {
+	sem   chan struct{}
+	users int
+}
+
+// toolSemaphores holds the semaphores in use, shared by every run of the
+// process. Idle semaphores are dropped, so tools and groups leave nothing
+// behind and a changed limit applies from the next call.
+var toolSemaphores = struct {
+	sync.Mutex
+	slots map[toolSlotsKey]*toolSlots
+}{slots: map[toolSlotsKey]*toolSlots{}}
+
+// useToolSemaphore returns the semaphore of key with size slots, and the
+// function to call once it's no longer used.
+func useToolSemaphore(key toolSlotsKey, size int) (chan struct{}, func()) {
+	toolSemaphores.Lock()
+	defer toolSemaphores.Unlock()
+	slots := toolSemaphores.slots[key]
+	if slots == nil || cap(slots.sem) != size {
+		// Calls holding the previous semaphore release it as they end
+		slots = &toolSlots{sem: make(chan struct{}, size)}
+		toolSemaphores.slots[key] = slots
+	}
+	slots.users++
+	return slots.sem, func() {
+		toolSemaphores.Lock()
+		defer toolSemaphores.Unlock()
+		slots.users--
+		if slots.users == 0 && toolSemaphores.slots[key] == slots {
+			delete(toolSemaphores.slots, key)
+		}
+	}
+}
+
+// limitingTool returns the tool declaring the MaxParallel limit of tool:
+// the innermost of the tools it wraps with the same limit, so wrapping a
+// tool again (namespaces, roles, ...) doesn't give it another semaphore.
+func limitingTool(tool ToolDefinitionInterface, maxParallel int) ToolDefinitionInterface {
+	for {
+		w, ok := tool.(toolWrapper)
+		if !ok {
+			return tool
+		}
+		inner := w.unwrapTool()
+		if inner == nil || toolConcurrency(inner).MaxParallel != maxParallel {
+			return tool
+		}
+		tool = inner
+	}
+}
+
+// acquireToolSlots waits until a call of tool may run under the limits c,
+// or ctx is done. The group is acquired before the tool so calls waiting on
+// both cannot deadlock. release must be called when the call ends.
+func acquireToolSlots(ctx context.Context, tool ToolDefinitionInterface, c ToolConcurrency) (release func(), err error) {
+	var held []chan struct{}
+	var done []func()
+	release = func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			<-held[i]
+		}
+		for _, d := range done {
+			d()
+		}
+	}
+
+	var sems []chan struct{}
+	if c.Group != "" {
+		sem, d := useToolSemaphore(toolSlotsKey{group: c.Group}, 1)
+		sems, done = append(sems, sem), append(done, d)
+	}
+	if c.MaxParallel > 0 {
+		key := toolSlotsKey{tool: limitingTool(tool, c.MaxParallel)}
+		if !reflect.TypeOf(key.tool).Comparable() {
+			// Can't be told apart from other tools, fall back to its name
+			key = toolSlotsKey{group: "tool:" + tool.Tool().Function.Name}
+		}
+		sem, d := useToolSemaphore(key, c.MaxParallel)
+		sems, done = append(sems, sem), append(done, d)
+	}
+	for _, sem := range sems {
+		select {
+		case sem <- struct{}{}:
+			held = append(held, sem)
+		case <-ctx.Done():
+			release()
+			return func() {}, ctx.Err()
+		}
+	}
+	return release, nil
+}
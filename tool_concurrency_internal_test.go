@@ -0,0 +1,107 @@
+package cogito
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyProbe counts the calls running at the same time, across every
+// tool sharing it.
+type concurrencyProbe struct {
+	running, peak atomic.Int32
+}
+
+func (p *concurrencyProbe) Run(args struct{}) (string, any, error) {
+	n := p.running.Add(1)
+	for {
+		peak := p.peak.Load()
+		if n <= peak || p.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	p.running.Add(-1)
+	return "done", nil, nil
+}
+
+func runConcurrently(t *testing.T, o *Options, tools ...ToolDefinitionInterface) {
+	t.Helper()
+	var wg sync.WaitGroup
+	for _, tool := range tools {
+		for range 3 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, _, err := runTool(o, tool, ToolCallInfo{Choice: ToolChoice{Name: tool.Tool().Function.Name}}); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestToolMaxParallel(t *testing.T) {
+	probe := &concurrencyProbe{}
+	tool := &ToolDefinition[struct{}]{ToolRunner: probe, InputArguments: struct{}{}, Name: "test_max_parallel",
+		Concurrency: ToolConcurrency{MaxParallel: 2}}
+
+	runConcurrently(t, defaultOptions(), tool, tool)
+	if peak := probe.peak.Load(); peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+}
+
+func TestToolGroupsSerializeCalls(t *testing.T) {
+	probe := &concurrencyProbe{}
+	browse := &ToolDefinition[struct{}]{ToolRunner: probe, InputArguments: struct{}{}, Name: "test_browse"}
+	click := &ToolDefinition[struct{}]{ToolRunner: probe, InputArguments: struct{}{}, Name: "test_click"}
+
+	runConcurrently(t, defaultOptions(),
+		LimitConcurrency(browse, ToolConcurrency{Group: "test_browser"}),
+		NamespaceTools("ui", LimitConcurrency(click, ToolConcurrency{Group: "test_browser"}))[0])
+	if peak := probe.peak.Load(); peak != 1 {
+		t.Errorf("peak concurrency = %d, want 1", peak)
+	}
+}
+
+func TestToolMaxParallelIsPerTool(t *testing.T) {
+	probe := &concurrencyProbe{}
+	// Tools of unrelated agents, sharing a name
+	first := &ToolDefinition[struct{}]{ToolRunner: probe, InputArguments: struct{}{}, Name: "test_shared_name",
+		Concurrency: ToolConcurrency{MaxParallel: 1}}
+	second := &ToolDefinition[struct{}]{ToolRunner: probe, InputArguments: struct{}{}, Name: "test_shared_name",
+		Concurrency: ToolConcurrency{MaxParallel: 1}}
+
+	runConcurrently(t, defaultOptions(), first, NamespaceTools("ui", second)[0])
+	if peak := probe.peak.Load(); peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+
+	toolSemaphores.Lock()
+	defer toolSemaphores.Unlock()
+	for key := range toolSemaphores.slots {
+		if key.tool == first || key.tool == second {
+			t.Error("kept the semaphore of an idle tool")
+		}
+	}
+}
+
+func TestToolSlotsHonorContext(t *testing.T) {
+	c := ToolConcurrency{Group: "test_cancel"}
+	tool := &ToolDefinition[struct{}]{ToolRunner: &concurrencyProbe{}, InputArguments: struct{}{}, Name: "test_cancel_tool"}
+	release, err := acquireToolSlots(context.Background(), tool, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := acquireToolSlots(ctx, tool, c); err == nil {
+		t.Error("acquired a slot of a busy group")
+	}
+}
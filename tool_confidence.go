@@ -0,0 +1,98 @@
+package cogito
+
+import (
+	"context"
+	"math"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+type logprobsKey struct{}
+
+// withLogprobs marks the requests of ctx as asking the backend for token log
+// probabilities, to derive the confidence of the tool selections.
+func withLogprobs(ctx context.Context) context.Context {
+	return context.WithValue(ctx, logprobsKey{}, true)
+}
+
+func wantsLogprobs(ctx context.Context) bool {
+	v, _ := ctx.Value(logprobsKey{}).(bool)
+	return v
+}
+
+// logprobConfidence derives a confidence from the token log probabilities of
+// a reply: the geometric mean of the token probabilities. It returns nil when
+// the backend didn't return log probabilities.
+func logprobConfidence(logprobs *openai.LogProbs) *float64 {
+	if logprobs == nil || len(logprobs.Content) == 0 {
+		return nil
+	}
+	var sum float64
+	for _, t := range logprobs.Content {
+		sum += t.LogProb
+	}
+	confidence := math.Exp(sum / float64(len(logprobs.Content)))
+	return &confidence
+}
+
+// lowConfidenceTools returns the names of the selected tools whose known
+// confidence is below the threshold set with WithMinToolConfidence. Sink
+// states are never considered low confidence, as they only end the run.
+func lowConfidenceTools(o *Options, selected []*ToolChoice) []string {
+	if o.minToolConfidence <= 0 {
+		return nil
+	}
+	var low []string
+	for _, t := range selected {
+		if o.isSinkState(t.Name) {
+			continue
+		}
+		if t.Confidence != nil && *t.Confidence < o.minToolConfidence {
+			low = append(low, t.Name)
+		}
+	}
+	return low
+}
+
+// clarificationQuestion asks the LLM for a question to clarify the user's
+// intent, instead of running tools it is not confident about. If that fails,
+// it returns "" and the run ends as if the sink state was selected.
+func clarificationQuestion(o *Options, llm LLM, messages []openai.ChatCompletionMessage, selected []*ToolChoice, reasoning string) string {
+	names := make([]string, len(selected))
+	confidence := 1.0
+	for i, t := range selected {
+		names[i] = t.Name
+		if t.Confidence != nil {
+			confidence = min(confidence, *t.Confidence)
+		}
+	}
+
+	clarificationPrompt, err := o.prompts.GetPrompt(prompt.PromptToolClarificationType).Render(struct {
+		Tools      []string
+		Reasoning  string
+		Confidence float64
+	}{
+		Tools:      names,
+		Reasoning:  reasoning,
+		Confidence: confidence,
+	})
+	if err != nil {
+		o.logger.Warn("Failed to render tool clarification prompt", "error", err)
+		return ""
+	}
+
+	conv := Fragment{Messages: append(append([]openai.ChatCompletionMessage{}, messages...), openai.ChatCompletionMessage{
+		Role:    SystemMessageRole.String(),
+		Content: clarificationPrompt,
+	})}
+	if o.streamCallback != nil {
+		o.streamCallback(StreamEvent{Type: StreamEventAnswer})
+	}
+	reply, err := askWithStreaming(o.context, llm, conv, o.streamCallback, o.logger)
+	if err != nil {
+		o.logger.Warn("Failed to ask for clarification", "error", err)
+		return ""
+	}
+	return reply.LastMessage().Content
+}
@@ -0,0 +1,40 @@
+package cogito
+
+import (
+	"math"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestLogprobConfidence(t *testing.T) {
+	if c := logprobConfidence(nil); c != nil {
+		t.Fatalf("confidence without logprobs = %v, want nil", *c)
+	}
+
+	logprobs := &openai.LogProbs{Content: []openai.LogProb{
+		{Token: "search", LogProb: math.Log(0.9)},
+		{Token: "(", LogProb: math.Log(0.4)},
+	}}
+	if c := logprobConfidence(logprobs); c == nil || math.Abs(*c-0.6) > 1e-9 {
+		t.Fatalf("confidence = %v, want the geometric mean 0.6", c)
+	}
+}
+
+func TestLowConfidenceToolsSkipsUnknownAndSinkState(t *testing.T) {
+	o := defaultOptions()
+	o.Apply(WithMinToolConfidence(0.5), WithForceReasoning())
+	sink := o.sinkStateTool.Tool().Function.Name
+
+	confidence := func(c float64) *float64 { return &c }
+	low := lowConfidenceTools(o, []*ToolChoice{
+		{Name: "search", Confidence: confidence(0.3)},
+		{Name: "weather"},
+		{Name: "calendar", Confidence: confidence(0.8)},
+		{Name: "email", Confidence: confidence(0)},
+		{Name: sink, Confidence: confidence(0.1)},
+	})
+	if len(low) != 2 || low[0] != "search" || low[1] != "email" {
+		t.Fatalf("low confidence tools = %v, want [search email]", low)
+	}
+}
@@ -0,0 +1,147 @@
+package cogito
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// ToolCorrection is a failed call of a tool that was sent back to the LLM
+// for correction. See WithToolCorrection.
+type ToolCorrection struct {
+	Name      string
+	Arguments map[string]any
+	Error     string
+}
+
+// correctFailedToolCall sends the failure of tc back to the LLM, which
+// either corrects the arguments or picks another tool, and runs the new
+// call once allowed (see approveCorrectedCall), for up to
+// o.toolCorrectionRounds rounds. tc is updated to the last
+// call made. The failed calls are returned along with the outcome of the
+// last one.
+func correctFailedToolCall(llm LLM, f Fragment, tools Tools, tc *ToolChoice, callErr error, iteration int, o *Options) (
+	result string, resultData any, followUps []ToolFollowUp, corrections []ToolCorrection, err error) {
+	err = callErr
+	candidates := slices.DeleteFunc(slices.Clone(tools), func(t ToolDefinitionInterface) bool {
+		return o.isSinkState(t.Tool().Function.Name)
+	})
+
+	// failed is the call whose failure is sent back: the last call made, or
+	// the last corrected call rejected
+	failed := *tc
+	for round := range o.toolCorrectionRounds {
+		corrections = append(corrections, ToolCorrection{Name: failed.Name, Arguments: failed.Arguments, Error: err.Error()})
+
+		correctionPrompt, renderErr := o.prompts.GetPrompt(prompt.PromptToolCorrectionType).Render(struct {
+			Tool      string
+			Arguments string
+			Error     string
+		}{
+			Tool:      failed.Name,
+			Arguments: string(mustMarshal(failed.Arguments)),
+			Error:     err.Error(),
+		})
+		if renderErr != nil {
+			return result, resultData, followUps, corrections, fmt.Errorf("failed to render tool correction prompt: %w", renderErr)
+		}
+
+		decided, decisionErr := decisionWithStreaming(o.context, llm,
+			append(slices.Clone(f.Messages), openai.ChatCompletionMessage{
+				Role:    SystemMessageRole.String(),
+				Content: correctionPrompt,
+			}),
+			candidates, "", o.maxRetries, o.streamCallback, o.logger, o.prompts)
+		if decisionErr != nil {
+			o.logger.Warn("Failed to correct tool call", "tool", tc.Name, "error", decisionErr)
+			return result, resultData, followUps, corrections, err
+		}
+		if len(decided.toolChoices) == 0 {
+			o.logger.Debug("No corrected tool call, giving up", "tool", tc.Name)
+			return result, resultData, followUps, corrections, err
+		}
+
+		corrected, rejection := approveCorrectedCall(o, f, decided.toolChoices[0])
+		if rejection != nil {
+			// Correct the rejected call again, following the reason
+			failed, err = *decided.toolChoices[0], rejection
+			continue
+		}
+		if corrected == nil {
+			o.logger.Debug("Corrected tool call not approved, giving up", "tool", decided.toolChoices[0].Name)
+			return result, resultData, followUps, corrections, err
+		}
+		tool := tools.Find(corrected.Name)
+		if tool == nil {
+			o.logger.Warn("Corrected tool call refers to an unknown tool", "tool", corrected.Name)
+			return result, resultData, followUps, corrections, err
+		}
+		o.logger.Debug("Retrying tool call with correction", "tool", corrected.Name, "arguments", corrected.Arguments, "round", round+1)
+		tc.Name, tc.Arguments = corrected.Name, corrected.Arguments
+		failed = *tc
+
+		result, resultData, err = runTool(o, tool, ToolCallInfo{Choice: *tc, Iteration: iteration, Attempt: o.maxAttempts + round + 1})
+		if err == nil {
+			result, resultData, followUps, err = resolveToolFollowUps(llm, f, tool, tc, result, resultData, o)
+		}
+		if err == nil {
+			_, err = toolOutput(tool, result, resultData)
+		}
+		if err == nil {
+			return result, resultData, followUps, corrections, nil
+		}
+	}
+	return result, resultData, followUps, corrections, err
+}
+
+// approveCorrectedCall submits a corrected call to the policy and to the
+// tool call callback, as the calls selected by the LLM are, returning the
+// call to run. It returns nil when the call is rejected or skipped, with an
+// error when the call can be corrected again: a policy denial or the
+// feedback of an adjustment.
+func approveCorrectedCall(o *Options, f Fragment, tc *ToolChoice) (*ToolChoice, error) {
+	if o.policy != nil {
+		decision := toolCallPolicy(o, f, tc)
+		switch decision.Effect {
+		case PolicyAllow:
+			return tc, nil
+		case PolicyDeny:
+			return nil, errors.New(policyDenialMessage(decision.Reason))
+		}
+	}
+	if o.toolCallCallback == nil {
+		return tc, nil
+	}
+	decision := o.toolCallCallback(tc, &SessionState{ToolChoice: tc, Fragment: f})
+	switch {
+	case !decision.Approved || decision.Skip:
+		return nil, nil
+	case decision.Modified != nil:
+		return decision.Modified, nil
+	case decision.Adjustment != "":
+		return nil, fmt.Errorf("the corrected call was not approved: %s", decision.Adjustment)
+	}
+	return tc, nil
+}
+
+// rewriteToolCall updates the call with the ID of choice in the assistant
+// messages of f to the name and arguments of choice, so the conversation
+// shows the call that produced the result.
+func rewriteToolCall(f Fragment, choice *ToolChoice) Fragment {
+	for i := len(f.Messages) - 1; i >= 0; i-- {
+		for j, call := range f.Messages[i].ToolCalls {
+			if call.ID != choice.ID {
+				continue
+			}
+			f.Messages = slices.Clone(f.Messages)
+			f.Messages[i].ToolCalls = slices.Clone(f.Messages[i].ToolCalls)
+			f.Messages[i].ToolCalls[j].Function.Name = choice.Name
+			f.Messages[i].ToolCalls[j].Function.Arguments = string(mustMarshal(choice.Arguments))
+			return f
+		}
+	}
+	return f
+}
@@ -0,0 +1,175 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/mudler/cogito/structures"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// toolNamePattern matches the tool names accepted by the tools API.
+var toolNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// toolDescriptionRewrite is the definition of a tool rewritten by the LLM.
+type toolDescriptionRewrite struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Parameters  []struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	} `json:"parameters"`
+}
+
+var toolDescriptionRewriteSchema = jsonschema.Definition{
+	Type: jsonschema.Object,
+	Properties: map[string]jsonschema.Definition{
+		"name":        {Type: jsonschema.String, Description: "The name of the tool, in snake_case"},
+		"description": {Type: jsonschema.String, Description: "What the tool does, when to use it and what it returns"},
+		"parameters": {
+			Type: jsonschema.Array,
+			Items: &jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"name":        {Type: jsonschema.String, Description: "The name of the parameter, unchanged"},
+					"description": {Type: jsonschema.String, Description: "The meaning and expected format of the parameter"},
+				},
+				Required: []string{"name", "description"},
+			},
+		},
+	},
+	Required: []string{"name", "description", "parameters"},
+}
+
+// OptimizeToolDescriptions rewrites the names, descriptions and parameter
+// descriptions of tools for clarity, with one LLM call per tool. It is meant
+// as a one-time pass, e.g. at startup, over tools with terse definitions such
+// as the ones generated from MCP servers or OpenAPI specs; pass the LLM that
+// will run the agent, so the definitions are phrased for it.
+//
+// The returned tools execute the original ones. Parameter names are kept, and
+// so is the namespace of namespaced tools (see NamespaceTools); a new name is
+// only used when it is a valid tool name not taken by another tool. Tools
+// that could not be rewritten are returned as they are, their errors joined
+// in the returned error. The prompt can be customized with
+// prompt.PromptToolDescriptionsType.
+func OptimizeToolDescriptions(llm LLM, tools Tools, opts ...Option) (Tools, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	taken := map[string]bool{}
+	for _, name := range tools.Names() {
+		taken[name] = true
+	}
+
+	var errs []error
+	optimized := make(Tools, 0, len(tools))
+	for i, tool := range tools {
+		definition, err := optimizeToolDescription(o.context, llm, tool.Tool(), taken, o, opts)
+		if err != nil {
+			o.logger.Warn("Failed to optimize the tool description", "tool", i, "error", err)
+			errs = append(errs, fmt.Errorf("tool %d: %w", i, err))
+			optimized = append(optimized, tool)
+			continue
+		}
+		optimized = append(optimized, &describedTool{tool: tool, definition: definition})
+	}
+	return optimized, errors.Join(errs...)
+}
+
+// optimizeToolDescription returns the definition of tool rewritten by llm.
+// taken holds the names in use, updated when the tool is renamed.
+func optimizeToolDescription(ctx context.Context, llm LLM, tool openai.Tool, taken map[string]bool, o *Options, opts []Option) (openai.Tool, error) {
+	if tool.Function == nil {
+		return tool, fmt.Errorf("tool has no function definition")
+	}
+	function := *tool.Function
+
+	parameters := map[string]any{}
+	if function.Parameters != nil {
+		data, err := json.Marshal(function.Parameters)
+		if err != nil {
+			return tool, fmt.Errorf("failed to encode parameters: %w", err)
+		}
+		if err := json.Unmarshal(data, &parameters); err != nil {
+			return tool, fmt.Errorf("failed to decode parameters: %w", err)
+		}
+	}
+
+	namespace, name := "", function.Name
+	if i := strings.LastIndex(name, ToolNamespaceSeparator); i >= 0 {
+		namespace, name = name[:i+len(ToolNamespaceSeparator)], name[i+len(ToolNamespaceSeparator):]
+	}
+
+	optimizationPrompt, err := o.prompts.GetPrompt(prompt.PromptToolDescriptionsType).Render(struct {
+		Name, Description, Parameters string
+	}{name, function.Description, string(mustMarshal(parameters))})
+	if err != nil {
+		return tool, fmt.Errorf("failed to render tool description optimization prompt: %w", err)
+	}
+
+	var rewrite toolDescriptionRewrite
+	f := NewEmptyFragment().AddMessage(UserMessageRole, optimizationPrompt)
+	if err := f.ExtractStructure(ctx, llm, structures.Structure{Schema: toolDescriptionRewriteSchema, Object: &rewrite}, opts...); err != nil {
+		return tool, err
+	}
+
+	if rewrite.Name != name && toolNamePattern.MatchString(rewrite.Name) && !taken[namespace+rewrite.Name] {
+		taken[namespace+rewrite.Name] = true
+		function.Name = namespace + rewrite.Name
+	}
+	if rewrite.Description != "" {
+		function.Description = rewrite.Description
+	}
+	if properties, ok := parameters["properties"].(map[string]any); ok {
+		for _, p := range rewrite.Parameters {
+			if property, ok := properties[p.Name].(map[string]any); ok && p.Description != "" {
+				property["description"] = p.Description
+			}
+		}
+		function.Parameters = parameters
+	}
+	tool.Function = &function
+	return tool, nil
+}
+
+// describedTool is a tool exposed with another definition, see
+// OptimizeToolDescriptions.
+type describedTool struct {
+	tool       ToolDefinitionInterface
+	definition openai.Tool
+}
+
+func (t *describedTool) Tool() openai.Tool {
+	tool := t.definition
+	if tool.Function != nil {
+		function := *tool.Function
+		tool.Function = &function
+	}
+	return tool
+}
+
+func (t *describedTool) Execute(args map[string]any) (string, any, error) {
+	return t.tool.Execute(args)
+}
+
+func (t *describedTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	return executeTool(ctx, t.tool, args)
+}
+
+func (t *describedTool) ToolConcurrency() ToolConcurrency {
+	return toolConcurrency(t.tool)
+}
+
+func (t *describedTool) OutputJSONSchema() *jsonschema.Definition {
+	return toolOutputSchema(t.tool)
+}
+
+func (t *describedTool) unwrapTool() ToolDefinitionInterface { return t.tool }
@@ -0,0 +1,71 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("OptimizeToolDescriptions", func() {
+	It("rewrites the definitions and keeps executing the original tools", func() {
+		weather := cogitotest.NewMockTool("wthr", "wthr api")
+		weather.(*ToolDefinition[map[string]any]).InputArguments = map[string]any{
+			"type":       "object",
+			"properties": map[string]any{"q": map[string]any{"type": "string"}},
+		}
+		cogitotest.SetRunResult(weather, "sunny")
+		search := cogitotest.NewMockTool("search", "Search the web")
+		docs := cogitotest.NewMockTool("docs", "docs")
+
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("json", `{"name": "get_weather", "description": "Get the current weather of a city.",
+			"parameters": [{"name": "q", "description": "The name of the city, e.g. Rome"}]}`)
+		// Taken names are not used
+		llm.AddCreateChatCompletionFunction("json", `{"name": "get_weather", "description": "Search the web for a query.", "parameters": []}`)
+
+		optimized, err := OptimizeToolDescriptions(llm, Tools{weather, search, docs})
+		// No reply is left for docs, it is kept as is
+		Expect(err).To(MatchError(ContainSubstring("tool 2")))
+		Expect(optimized.Names()).To(Equal([]string{"get_weather", "search", "docs"}))
+		Expect(optimized[2]).To(BeIdenticalTo(docs))
+
+		function := optimized[0].Tool().Function
+		Expect(function.Description).To(Equal("Get the current weather of a city."))
+		Expect(function.Parameters).To(HaveKeyWithValue("properties",
+			HaveKeyWithValue("q", HaveKeyWithValue("description", "The name of the city, e.g. Rome"))))
+		Expect(optimized[1].Tool().Function.Description).To(Equal("Search the web for a query."))
+		Expect(llm.Requests()[0].Messages[0].Content).To(ContainSubstring("wthr api"))
+
+		result, _, err := optimized[0].Execute(map[string]any{"q": "Rome"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal("sunny"))
+		Expect(cogitotest.GetMockTool(weather).Calls()).To(HaveLen(1))
+	})
+
+	It("keeps the roles of the original tools", func() {
+		audit := RequireRoles(cogitotest.NewMockTool("audit", "audit"), "admin")
+		llm := cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("json", `{"name": "read_audit_log", "description": "Read the audit log.", "parameters": []}`)
+		optimized, err := OptimizeToolDescriptions(llm, Tools{audit})
+		Expect(err).ToNot(HaveOccurred())
+
+		offered := func(opts ...Option) []string {
+			llm := cogitotest.NewMockLLM()
+			llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+				Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Nothing to do."},
+			}}})
+			f := NewEmptyFragment().AddMessage(UserMessageRole, "Show the audit log")
+			_, err := ExecuteTools(llm, f, append(opts, WithTools(optimized...))...)
+			Expect(err).ToNot(HaveOccurred())
+			var names []string
+			for _, tool := range llm.Requests()[0].Tools {
+				names = append(names, tool.Function.Name)
+			}
+			return names
+		}
+		Expect(offered()).ToNot(ContainElement("read_audit_log"))
+		Expect(offered(WithIdentity(Identity{Subject: "alice", Roles: []string{"admin"}}))).To(ContainElement("read_audit_log"))
+	})
+})
@@ -0,0 +1,123 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/mudler/cogito/prompt"
+)
+
+// NeedsMoreInfo is returned by a tool as its result data to ask the LLM a
+// follow-up question before producing the final result (e.g. a booking flow
+// asking for a date). ExecuteTools has the LLM answer Question and passes the
+// answer to the tool's Continue method. State is opaque to cogito and handed
+// back to the tool unchanged.
+type NeedsMoreInfo struct {
+	Question string
+	State    any
+}
+
+// FollowUpTool is implemented by tools that can ask follow-up questions by
+// returning a *NeedsMoreInfo result. Continue receives the context of the
+// call (as ExecuteWithContext does), the pending request and the LLM answer,
+// and returns like Execute: either the final result or another
+// *NeedsMoreInfo.
+type FollowUpTool interface {
+	Continue(ctx context.Context, info NeedsMoreInfo, answer string) (string, any, error)
+}
+
+// ToolFollowUp is one question/answer exchange between a tool and the LLM.
+type ToolFollowUp struct {
+	Question string
+	Answer   string
+}
+
+// Continue implements FollowUpTool by delegating to the ToolRunner, when it
+// supports follow-ups.
+func (t *ToolDefinition[T]) Continue(ctx context.Context, info NeedsMoreInfo, answer string) (string, any, error) {
+	runner, ok := t.ToolRunner.(FollowUpTool)
+	if !ok {
+		return "", nil, fmt.Errorf("tool %s asked for more information but does not implement FollowUpTool", t.Name)
+	}
+	return runner.Continue(ctx, info, answer)
+}
+
+// asNeedsMoreInfo reports whether a tool's result data is a follow-up request.
+func asNeedsMoreInfo(resultData any) (NeedsMoreInfo, bool) {
+	switch v := resultData.(type) {
+	case *NeedsMoreInfo:
+		if v != nil {
+			return *v, true
+		}
+	case NeedsMoreInfo:
+		return v, true
+	}
+	return NeedsMoreInfo{}, false
+}
+
+// resolveToolFollowUps runs the bounded inner exchange for a tool that asked
+// for more information: the LLM answers each question and the tool continues,
+// until it produces a final result or o.maxToolFollowUps is reached.
+func resolveToolFollowUps(llm LLM, f Fragment, tool ToolDefinitionInterface, tc *ToolChoice,
+	result string, resultData any, o *Options) (string, any, []ToolFollowUp, error) {
+	var followUps []ToolFollowUp
+
+	for {
+		info, ok := asNeedsMoreInfo(resultData)
+		if !ok {
+			return result, resultData, followUps, nil
+		}
+		if len(followUps) >= o.maxToolFollowUps {
+			return "", nil, followUps, fmt.Errorf("tool %s still needs more information after %d follow-ups: %s",
+				tc.Name, len(followUps), info.Question)
+		}
+		continuer, ok := wrappedTool[FollowUpTool](tool)
+		if !ok {
+			return "", nil, followUps, fmt.Errorf("tool %s asked for more information but does not implement FollowUpTool", tc.Name)
+		}
+
+		answer, err := answerToolFollowUp(llm, f, tc, info.Question, followUps, o)
+		if err != nil {
+			return "", nil, followUps, err
+		}
+		o.logger.Debug("Tool follow-up answered", "tool", tc.Name, "question", info.Question, "answer", answer)
+		followUps = append(followUps, ToolFollowUp{Question: info.Question, Answer: answer})
+
+		ctx := toolCallContext(o.context, o, ToolCallInfo{Choice: *tc})
+		result, resultData, err = continuer.Continue(ctx, info, answer)
+		if err != nil {
+			return "", nil, followUps, err
+		}
+	}
+}
+
+func answerToolFollowUp(llm LLM, f Fragment, tc *ToolChoice, question string, previous []ToolFollowUp, o *Options) (string, error) {
+	prompter := o.prompts.GetPrompt(prompt.PromptToolFollowUpType)
+
+	p, err := prompter.Render(struct {
+		Context   string
+		Tool      string
+		Arguments string
+		Question  string
+		Previous  []ToolFollowUp
+	}{
+		Context:   conversationContext(o, prompt.PromptToolFollowUpType, f),
+		Tool:      tc.Name,
+		Arguments: string(mustMarshal(tc.Arguments)),
+		Question:  question,
+		Previous:  previous,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to render tool follow-up prompt: %w", err)
+	}
+
+	res, err := llm.Ask(o.context, NewEmptyFragment().AddMessage(UserMessageRole, p))
+	if err != nil {
+		return "", fmt.Errorf("failed to answer tool follow-up: %w", err)
+	}
+	if res.LastMessage() == nil {
+		return "", fmt.Errorf("failed to answer tool follow-up: empty reply")
+	}
+	return strings.TrimSpace(res.LastMessage().Content), nil
+}
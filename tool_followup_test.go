@@ -0,0 +1,101 @@
+package cogito_test
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type BookingArgs struct {
+	City string `json:"city"`
+}
+
+// bookingTool asks for a date before confirming a booking.
+type bookingTool struct {
+	continued ToolCallInfo // the call Continue ran for
+}
+
+func (b *bookingTool) Run(args BookingArgs) (string, any, error) {
+	return "", &NeedsMoreInfo{Question: "Which date?", State: args.City}, nil
+}
+
+func (b *bookingTool) Continue(ctx context.Context, info NeedsMoreInfo, answer string) (string, any, error) {
+	b.continued, _ = ToolCallFromContext(ctx)
+	return fmt.Sprintf("Booked %s on %s", info.State, answer), nil, nil
+}
+
+var _ = Describe("Tool follow-ups", func() {
+	var mockLLM *cogitotest.MockLLM
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+	})
+
+	It("runs an inner exchange until the tool produces a result", func() {
+		booking := &bookingTool{}
+		tool := NewToolDefinition(booking, BookingArgs{}, "book", "Book a hotel")
+
+		mockLLM.AddCreateChatCompletionFunction("book", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("May 3rd")
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Your room is booked."}},
+			},
+		})
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Book me a hotel in Rome on May 3rd")
+		result, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		status := result.Status.ToolResults[0]
+		Expect(status.Result).To(Equal("Booked Rome on May 3rd"))
+		Expect(status.FollowUps).To(Equal([]ToolFollowUp{{Question: "Which date?", Answer: "May 3rd"}}))
+		Expect(booking.continued.Choice.Name).To(Equal("book"))
+	})
+
+	It("continues a follow-up through the tools wrapping it", func() {
+		booking := &bookingTool{}
+		tool := NewToolDefinition(booking, BookingArgs{}, "book", "Book a hotel")
+		wrapped := NamespaceTools("hotels", LimitConcurrency(tool, ToolConcurrency{MaxParallel: 1}))
+
+		mockLLM.AddCreateChatCompletionFunction("hotels__book", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("May 3rd")
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Your room is booked."}},
+			},
+		})
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Book me a hotel in Rome on May 3rd")
+		result, err := ExecuteTools(mockLLM, fragment, WithTools(wrapped...), WithIterations(2))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		Expect(result.Status.ToolResults[0].Result).To(Equal("Booked Rome on May 3rd"))
+		Expect(booking.continued.Choice.Name).To(Equal("hotels__book"))
+	})
+
+	It("fails the tool call once the follow-up budget is exhausted", func() {
+		tool := NewToolDefinition(&bookingTool{}, BookingArgs{}, "book", "Book a hotel")
+
+		mockLLM.AddCreateChatCompletionFunction("book", `{"city": "Rome"}`)
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Sorry."}},
+			},
+		})
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Book me a hotel in Rome")
+		result, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2), WithMaxToolFollowUps(0))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		Expect(result.Status.ToolResults[0].Result).To(ContainSubstring("still needs more information"))
+	})
+})
@@ -0,0 +1,66 @@
+package cogito
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// toolResultTTL returns the freshness TTL of a tool's results (0 = forever).
+func toolResultTTL(o *Options, tool string) time.Duration {
+	if ttl, ok := o.toolResultTTLs[tool]; ok {
+		return ttl
+	}
+	return o.toolResultTTLs[""]
+}
+
+// expireStaleToolResults removes from the conversation the tool results that
+// outlived their TTL, marking them Expired in the status, and tells the model
+// they must be refreshed. Expired calls are also dropped from the loop
+// detection history so that calling the tool again is not taken for a loop.
+func expireStaleToolResults(f Fragment, o *Options, now time.Time) Fragment {
+	if len(o.toolResultTTLs) == 0 || f.Status == nil {
+		return f
+	}
+
+	expired := map[string]bool{} // tool call IDs
+	var notes []string
+	results := slices.Clone(f.Status.ToolResults)
+	for i, r := range results {
+		ttl := toolResultTTL(o, r.Name)
+		if r.Expired || ttl <= 0 || r.ExecutedAt.IsZero() || now.Sub(r.ExecutedAt) <= ttl {
+			continue
+		}
+		results[i].Expired = true
+		if r.ToolArguments.ID != "" {
+			expired[r.ToolArguments.ID] = true
+		}
+		notes = append(notes, fmt.Sprintf("- %s %s (from %s ago)", r.Name, string(mustMarshal(r.ToolArguments.Arguments)),
+			now.Sub(r.ExecutedAt).Round(time.Second)))
+	}
+	if len(notes) == 0 {
+		return f
+	}
+	o.logger.Debug("Expiring stale tool results", "count", len(notes))
+
+	status := *f.Status
+	status.ToolResults = results
+	status.PastActions = slices.DeleteFunc(slices.Clone(status.PastActions), func(a ToolStatus) bool {
+		return a.ToolArguments.ID != "" && expired[a.ToolArguments.ID]
+	})
+	f.Status = &status
+
+	f.Messages = slices.Clone(f.Messages)
+	for i, msg := range f.Messages {
+		if msg.Role == openai.ChatMessageRoleTool && expired[msg.ToolCallID] {
+			f.Messages[i].Content = "This result has expired and was removed. Call the tool again for up-to-date data."
+		}
+	}
+
+	return f.AddMessage(SystemMessageRole, "The results of these earlier tool calls are out of date and were removed:\n"+
+		strings.Join(notes, "\n")+
+		"\nDo not rely on them or on answers based on them: call the tools again to refresh the data when it is needed.")
+}
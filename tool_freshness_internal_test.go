@@ -0,0 +1,59 @@
+package cogito
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestExpireStaleToolResults(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	weather := ToolStatus{Name: "weather", Result: "sunny", ExecutedAt: now.Add(-2 * time.Hour),
+		ToolArguments: ToolChoice{ID: "w1", Name: "weather", Arguments: map[string]any{"city": "Rome"}}}
+	docs := ToolStatus{Name: "docs", Result: "manual", ExecutedAt: now.Add(-48 * time.Hour),
+		ToolArguments: ToolChoice{ID: "d1", Name: "docs"}}
+
+	f := NewEmptyFragment().AddMessage(UserMessageRole, "weather and docs?")
+	f.Messages = append(f.Messages,
+		openai.ChatCompletionMessage{Role: "assistant", ToolCalls: []openai.ToolCall{{ID: "w1"}, {ID: "d1"}}},
+		openai.ChatCompletionMessage{Role: "tool", ToolCallID: "w1", Content: "sunny"},
+		openai.ChatCompletionMessage{Role: "tool", ToolCallID: "d1", Content: "manual"},
+	)
+	f.Status.ToolResults = []ToolStatus{weather, docs}
+	f.Status.PastActions = []ToolStatus{weather, docs}
+
+	o := defaultOptions()
+	o.Apply(WithToolResultTTL(time.Hour, "weather"))
+	out := expireStaleToolResults(f, o, now)
+
+	if !out.Status.ToolResults[0].Expired || out.Status.ToolResults[1].Expired {
+		t.Errorf("results = %+v", out.Status.ToolResults)
+	}
+	if len(out.Status.PastActions) != 1 || out.Status.PastActions[0].Name != "docs" {
+		t.Errorf("past actions = %+v", out.Status.PastActions)
+	}
+	if strings.Contains(out.Messages[2].Content, "sunny") || out.Messages[3].Content != "manual" {
+		t.Errorf("tool messages = %q, %q", out.Messages[2].Content, out.Messages[3].Content)
+	}
+	note := out.LastMessage()
+	if note.Role != "system" || !strings.Contains(note.Content, `weather {"city":"Rome"} (from 2h0m0s ago)`) {
+		t.Errorf("note = %+v", note)
+	}
+	if f.Messages[2].Content != "sunny" || f.Status.ToolResults[0].Expired {
+		t.Error("input fragment was modified")
+	}
+
+	// Already expired results are not reported again
+	if again := expireStaleToolResults(out, o, now.Add(time.Hour)); len(again.Messages) != len(out.Messages) {
+		t.Errorf("expired results reported twice")
+	}
+
+	// A default TTL applies to every tool
+	o = defaultOptions()
+	o.Apply(WithToolResultTTL(24*time.Hour), WithToolResultTTL(0, "docs"))
+	if out := expireStaleToolResults(f, o, now); out.Status.ToolResults[1].Expired || out.Status.ToolResults[0].Expired {
+		t.Errorf("results = %+v", out.Status.ToolResults)
+	}
+}
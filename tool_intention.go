@@ -9,14 +9,16 @@ type ReasoningResponse struct {
 
 // IntentionResponseSingle is used to extract a single tool choice from the intention tool
 type IntentionResponseSingle struct {
-	Tool      string `json:"tool"`
-	Reasoning string `json:"reasoning"`
+	Tool       string   `json:"tool"`
+	Reasoning  string   `json:"reasoning"`
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 // IntentionResponseMultiple is used to extract multiple tool choices from the intention tool
 type IntentionResponseMultiple struct {
-	Tools     []string `json:"tools"`
-	Reasoning string   `json:"reasoning"`
+	Tools      []string `json:"tools"`
+	Reasoning  string   `json:"reasoning"`
+	Confidence *float64 `json:"confidence,omitempty"`
 }
 
 // reasoningToolRunner runs the reasoning tool (which just captures reasoning, doesn't execute)
@@ -72,6 +74,14 @@ func (i *intentionToolWrapperMultiple) NewArgs() *IntentionResponseMultiple {
 	return &IntentionResponseMultiple{}
 }
 
+// confidenceProperty lets the LLM self-report how sure it is of a tool choice
+var confidenceProperty = map[string]interface{}{
+	"type":        "number",
+	"description": "How confident you are in this choice, from 0 (guessing) to 1 (certain)",
+	"minimum":     0,
+	"maximum":     1,
+}
+
 // intentionToolSingle creates a tool that forces the LLM to pick one of the available tools
 func intentionToolSingle(toolNames []string, sinkStateName string) *ToolDefinition[IntentionResponseSingle] {
 	// Build enum for the tool names
@@ -100,6 +110,7 @@ func intentionToolSingle(toolNames []string, sinkStateName string) *ToolDefiniti
 					"type":        "string",
 					"description": "The reasoning for the tool choice",
 				},
+				"confidence": confidenceProperty,
 			},
 			"required": []string{"tool"},
 		},
@@ -138,8 +149,78 @@ func intentionToolMultiple(toolNames []string, sinkStateName string) *ToolDefini
 					"type":        "string",
 					"description": "The reasoning for the tool choices",
 				},
+				"confidence": confidenceProperty,
 			},
 			"required": []string{"tools"},
 		},
 	}
 }
+
+// ConsolidatedToolCall is a tool call, with its arguments, requested through the consolidated decision tool
+type ConsolidatedToolCall struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// ConsolidatedResponse is used to extract the reasoning, the tool choices and their arguments from the consolidated decision tool
+type ConsolidatedResponse struct {
+	Reasoning  string                 `json:"reasoning"`
+	Calls      []ConsolidatedToolCall `json:"calls"`
+	Confidence *float64               `json:"confidence,omitempty"`
+}
+
+// consolidatedToolWrapper wraps the consolidated decision tool to match the Tool interface
+type consolidatedToolWrapper struct{}
+
+func (c *consolidatedToolWrapper) Run(args ConsolidatedResponse) (string, any, error) {
+	return "", nil, fmt.Errorf("consolidated decision tool should not be executed")
+}
+
+func (c *consolidatedToolWrapper) NewArgs() *ConsolidatedResponse {
+	return &ConsolidatedResponse{}
+}
+
+// consolidatedTool creates a tool asking for the reasoning, the tool choice(s) and their arguments in a single call
+func consolidatedTool(toolNames []string, parallel bool) *ToolDefinition[ConsolidatedResponse] {
+	calls := map[string]interface{}{
+		"type":        "array",
+		"description": "The tool calls to perform",
+		"items": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"tool": map[string]interface{}{
+					"type":        "string",
+					"description": "The tool to use",
+					"enum":        toolNames,
+				},
+				"arguments": map[string]interface{}{
+					"type":        "object",
+					"description": "The arguments for the tool, matching its parameters",
+				},
+			},
+			"required": []string{"tool", "arguments"},
+		},
+		"minItems": 1,
+	}
+	if !parallel {
+		calls["maxItems"] = 1
+	}
+
+	return &ToolDefinition[ConsolidatedResponse]{
+		ToolRunner:  &consolidatedToolWrapper{},
+		Name:        "reason_and_call",
+		Description: "Provide your reasoning and the tool call(s) to perform, with their arguments.",
+		InputArguments: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"reasoning": map[string]interface{}{
+					"type":        "string",
+					"description": "Your detailed reasoning about the current task and which tool(s) to use",
+				},
+				"calls":      calls,
+				"confidence": confidenceProperty,
+			},
+			"required": []string{"reasoning", "calls"},
+		},
+	}
+}
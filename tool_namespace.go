@@ -0,0 +1,214 @@
+package cogito
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// ToolNamespaceSeparator joins a namespace and a tool name, as in
+// "weather__get_weather". Tool names of the tools API only allow letters,
+// digits, "_" and "-".
+const ToolNamespaceSeparator = "__"
+
+// maxToolNameLength is the length of the longest tool name accepted by the
+// tools API.
+const maxToolNameLength = 64
+
+// ToolCollisionPolicy decides which tool is kept when tools from different
+// sources (WithTools, guidelines, MCP servers) share a name.
+type ToolCollisionPolicy int
+
+const (
+	// PreferLocalTools keeps the tools given with WithTools or guidelines
+	// over MCP tools. It is the default.
+	PreferLocalTools ToolCollisionPolicy = iota
+	// PreferMCPTools keeps MCP tools over local ones.
+	PreferMCPTools
+	// ErrorOnToolCollision fails the run with a *ToolCollisionError.
+	ErrorOnToolCollision
+)
+
+// ToolCollisionError is returned under ErrorOnToolCollision when distinct
+// tools share a name. Sources lists where each of them comes from: "local",
+// "guideline" or "mcp:<server name>".
+type ToolCollisionError struct {
+	Name    string
+	Sources []string
+}
+
+func (e *ToolCollisionError) Error() string {
+	return fmt.Sprintf("tool name %q is provided by more than one source: %s", e.Name, strings.Join(e.Sources, ", "))
+}
+
+// NamespaceTools exposes tools under namespace, so "get_weather" becomes
+// "weather__get_weather". Use it to keep tools with the same name apart.
+// Names longer than 64 characters are shortened, ending with a hash of the
+// full name so they stay distinct.
+func NamespaceTools(namespace string, tools ...ToolDefinitionInterface) Tools {
+	namespaced := make(Tools, 0, len(tools))
+	for _, tool := range tools {
+		namespaced = append(namespaced, &namespacedTool{namespace: namespace, tool: tool})
+	}
+	return namespaced
+}
+
+type namespacedTool struct {
+	namespace string
+	tool      ToolDefinitionInterface
+}
+
+func (t *namespacedTool) Tool() openai.Tool {
+	tool := t.tool.Tool()
+	if tool.Function != nil {
+		function := *tool.Function
+		function.Name = namespacedToolName(t.namespace, function.Name)
+		tool.Function = &function
+	}
+	return tool
+}
+
+// namespacedToolName returns the name of the tool name exposed under
+// namespace, shortened to maxToolNameLength.
+func namespacedToolName(namespace, name string) string {
+	full := namespace + ToolNamespaceSeparator + name
+	if len(full) <= maxToolNameLength {
+		return full
+	}
+	sum := sha256.Sum256([]byte(full))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	return full[:maxToolNameLength-len(suffix)] + suffix
+}
+
+func (t *namespacedTool) Execute(args map[string]any) (string, any, error) {
+	return t.tool.Execute(args)
+}
+
+func (t *namespacedTool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	return executeTool(ctx, t.tool, args)
+}
+
+func (t *namespacedTool) ToolConcurrency() ToolConcurrency {
+	return toolConcurrency(t.tool)
+}
+
+func (t *namespacedTool) OutputJSONSchema() *jsonschema.Definition {
+	return toolOutputSchema(t.tool)
+}
+
+// sourcedTool is a tool together with where it was registered from.
+type sourcedTool struct {
+	tool   ToolDefinitionInterface
+	source string
+}
+
+func (s sourcedTool) isMCP() bool {
+	return strings.HasPrefix(s.source, "mcp")
+}
+
+// mcpSource describes an MCP session as a tool source.
+func mcpSource(session *mcp.ClientSession) string {
+	if session != nil {
+		if res := session.InitializeResult(); res != nil && res.ServerInfo != nil && res.ServerInfo.Name != "" {
+			return "mcp:" + res.ServerInfo.Name
+		}
+	}
+	return "mcp"
+}
+
+// toolSource returns the source of tool among sourced, "guideline" for tools
+// that only come from guidelines.
+func toolSource(sourced []sourcedTool, tool ToolDefinitionInterface) string {
+	for _, s := range sourced {
+		if sameTool(s.tool, tool) {
+			return s.source
+		}
+	}
+	return "guideline"
+}
+
+// sameTool reports whether a and b are the same tool registered twice, which
+// is not a collision.
+func sameTool(a, b ToolDefinitionInterface) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Kind() == reflect.Pointer && vb.Kind() == reflect.Pointer && va.Type() == vb.Type() && va.Pointer() == vb.Pointer() {
+		return true
+	}
+	return reflect.DeepEqual(a.Tool(), b.Tool())
+}
+
+// resolveToolCollisions drops duplicated tools and settles name collisions
+// following policy. Tools keep the order of their first appearance, and
+// collisions are checked in that order so the outcome is deterministic.
+func resolveToolCollisions(tools []sourcedTool, policy ToolCollisionPolicy, logger Logger) ([]sourcedTool, error) {
+	groups := map[string][]sourcedTool{}
+	order := []string{}
+	for _, t := range tools {
+		name := ""
+		if t.tool.Tool().Function != nil {
+			name = t.tool.Tool().Function.Name
+		}
+		duplicate := false
+		for _, other := range groups[name] {
+			if sameTool(t.tool, other.tool) {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			continue
+		}
+		if _, ok := groups[name]; !ok {
+			order = append(order, name)
+		}
+		groups[name] = append(groups[name], t)
+	}
+
+	resolved := make([]sourcedTool, 0, len(order))
+	for _, name := range order {
+		group := groups[name]
+		if len(group) == 1 {
+			resolved = append(resolved, group[0])
+			continue
+		}
+
+		sources := make([]string, len(group))
+		for i, t := range group {
+			sources[i] = t.source
+		}
+		if policy == ErrorOnToolCollision {
+			return nil, &ToolCollisionError{Name: name, Sources: sources}
+		}
+
+		kept := group[0]
+		for _, t := range group {
+			if t.isMCP() == (policy == PreferMCPTools) {
+				kept = t
+				break
+			}
+		}
+		logger.Warn("Tool name collision, keeping one of the tools", "tool", name, "kept", kept.source, "sources", sources)
+		resolved = append(resolved, kept)
+	}
+	return resolved, nil
+}
+
+// mcpOptions re-creates the MCP options of o, keeping session namespaces.
+func mcpOptions(o *Options) []Option {
+	opts := []Option{}
+	for _, session := range o.mcpSessions {
+		if namespace := o.mcpNamespaces[session]; namespace != "" {
+			opts = append(opts, WithMCPNamespace(namespace, session))
+		} else {
+			opts = append(opts, WithMCPs(session))
+		}
+	}
+	return opts
+}
@@ -0,0 +1,89 @@
+package cogito
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func lookupTool(name, description string) ToolDefinitionInterface {
+	return NewToolDefinition(&lookupRunner{}, lookupArgs{}, name, description)
+}
+
+func TestNamespaceTools(t *testing.T) {
+	runner := &lookupRunner{}
+	tools := NamespaceTools("crm", NewToolDefinition(runner, lookupArgs{}, "lookup", "Look up a company"))
+
+	if name := tools[0].Tool().Function.Name; name != "crm__lookup" {
+		t.Fatalf("namespaced name = %q, want crm__lookup", name)
+	}
+	if tools.Find("crm__lookup") == nil || tools.Find("lookup") != nil {
+		t.Fatalf("namespaced tool should only be found by its namespaced name")
+	}
+	if _, _, err := tools[0].Execute(map[string]any{"company": "acme"}); err != nil || runner.runs.Load() != 1 {
+		t.Fatalf("namespaced tool did not run the wrapped tool: %v", err)
+	}
+}
+
+func TestNamespaceToolsShortensLongNames(t *testing.T) {
+	long := strings.Repeat("a", 60)
+	tools := NamespaceTools("crm", lookupTool(long+"_1", "One"), lookupTool(long+"_2", "Two"))
+	first, second := tools[0].Tool().Function.Name, tools[1].Tool().Function.Name
+	if len(first) != 64 || len(second) != 64 || first == second || !strings.HasPrefix(first, "crm__aaa") {
+		t.Fatalf("names = %q, %q", first, second)
+	}
+	if !toolNamePattern.MatchString(first) {
+		t.Fatalf("invalid tool name %q", first)
+	}
+	if tools.Find(first) != tools[0] {
+		t.Fatalf("shortened name not found")
+	}
+}
+
+func TestResolveToolCollisions(t *testing.T) {
+	local := lookupTool("lookup", "Local lookup")
+	remote := lookupTool("lookup", "Remote lookup")
+	other := lookupTool("search", "Search")
+	tools := []sourcedTool{
+		{tool: remote, source: "mcp:crm"},
+		{tool: other, source: "local"},
+		{tool: local, source: "local"},
+		{tool: local, source: "guideline"},
+	}
+
+	resolved, err := resolveToolCollisions(tools, PreferLocalTools, defaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 2 || resolved[0].tool != local || resolved[1].tool != other {
+		t.Fatalf("prefer local resolved to %+v", resolved)
+	}
+
+	resolved, err = resolveToolCollisions(tools, PreferMCPTools, defaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resolved) != 2 || resolved[0].tool != remote {
+		t.Fatalf("prefer mcp resolved to %+v", resolved)
+	}
+
+	_, err = resolveToolCollisions(tools, ErrorOnToolCollision, defaultLogger)
+	var collision *ToolCollisionError
+	if !errors.As(err, &collision) {
+		t.Fatalf("error = %v, want a ToolCollisionError", err)
+	}
+	if collision.Name != "lookup" || len(collision.Sources) != 2 || collision.Sources[0] != "mcp:crm" || collision.Sources[1] != "local" {
+		t.Fatalf("collision = %+v", collision)
+	}
+}
+
+func TestResolveToolCollisionsIgnoresDuplicates(t *testing.T) {
+	tool := lookupTool("lookup", "Lookup")
+	resolved, err := resolveToolCollisions([]sourcedTool{
+		{tool: tool, source: "local"},
+		{tool: tool, source: "guideline"},
+	}, ErrorOnToolCollision, defaultLogger)
+	if err != nil || len(resolved) != 1 {
+		t.Fatalf("duplicates should be merged, got %v, %v", resolved, err)
+	}
+}
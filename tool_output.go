@@ -0,0 +1,226 @@
+package cogito
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// ToolWithOutputSchema is implemented by tools declaring the JSON schema of
+// their results. ExecuteTools validates the results against it, handling a
+// mismatch like a tool error (retried, see WithMaxAttempts, and corrected,
+// see WithToolCorrection), and exposes them parsed in ToolStatus.Output.
+//
+// ToolDefinition implements it with its OutputSchema, and MCP tools with the
+// output schema declared by their server, validating their structured
+// content. The JSON payload of a ToolResult is validated instead of the
+// text, when there is one.
+type ToolWithOutputSchema interface {
+	OutputJSONSchema() *jsonschema.Definition
+}
+
+// toolOutputSchema returns the output schema of tool, if any.
+func toolOutputSchema(tool ToolDefinitionInterface) *jsonschema.Definition {
+	if t, ok := tool.(ToolWithOutputSchema); ok {
+		return t.OutputJSONSchema()
+	}
+	return nil
+}
+
+// OutputJSONSchema implements ToolWithOutputSchema. It is nil without an
+// OutputSchema.
+func (t ToolDefinition[T]) OutputJSONSchema() *jsonschema.Definition {
+	if t.OutputSchema == nil {
+		return nil
+	}
+	schema, err := toJSONSchema(t.OutputSchema)
+	if err != nil {
+		panic(fmt.Errorf("unsupported OutputSchema type: %T, error: %w", t.OutputSchema, err))
+	}
+	return schema
+}
+
+// OutputJSONSchema implements ToolWithOutputSchema with the output schema
+// of the MCP tool, if its server declares one.
+// It is nil when the schema cannot be represented.
+func (t *mcpTool) OutputJSONSchema() *jsonschema.Definition {
+	if t.outputSchema == nil {
+		return nil
+	}
+	dat, err := json.Marshal(t.outputSchema)
+	if err != nil {
+		t.logger.Debug("Ignoring the output schema of MCP tool", "tool", t.name, "error", err)
+		return nil
+	}
+	raw := map[string]any{}
+	if err := json.Unmarshal(dat, &raw); err != nil {
+		t.logger.Debug("Ignoring the output schema of MCP tool", "tool", t.name, "error", err)
+		return nil
+	}
+	coerceSchema(raw)
+	schema := &jsonschema.Definition{}
+	if err := json.Unmarshal(mustMarshal(raw), schema); err != nil {
+		t.logger.Debug("Ignoring the output schema of MCP tool", "tool", t.name, "error", err)
+		return nil
+	}
+	return schema
+}
+
+// toolOutput parses the result of tool and validates it against its output
+// schema. It returns nil without a schema. The output is the structured
+// content of MCP results, the JSON payload of rich results (see
+// ToolResult), and the result parsed as JSON otherwise.
+func toolOutput(tool ToolDefinitionInterface, result string, resultData any) (any, error) {
+	schema := toolOutputSchema(tool)
+	if schema == nil {
+		return nil, nil
+	}
+
+	raw := []byte(result)
+	structured := any(nil)
+	if res, ok := resultData.(*mcp.CallToolResult); ok {
+		structured = res.StructuredContent
+	} else if rich, ok := asToolResult(resultData); ok {
+		structured = rich.JSON
+	}
+	if structured != nil {
+		var err error
+		if raw, err = json.Marshal(structured); err != nil {
+			return nil, fmt.Errorf("tool output is not valid JSON: %w", err)
+		}
+	}
+	var output any
+	if err := json.Unmarshal(raw, &output); err != nil {
+		return nil, fmt.Errorf("tool output is not valid JSON: %w", err)
+	}
+	if err := validateJSONSchema(*schema, output, "output"); err != nil {
+		return nil, fmt.Errorf("tool output does not match its schema: %w", err)
+	}
+	return output, nil
+}
+
+// validateJSONSchema checks value, decoded from JSON, against schema. It
+// covers types, enums, required and nested properties, and array items;
+// null values are accepted, as nullable types are simplified in schemas.
+func validateJSONSchema(schema jsonschema.Definition, value any, path string) error {
+	if value == nil {
+		return nil
+	}
+	mismatch := func() error {
+		return fmt.Errorf("%s: expected %s, got %s", path, schema.Type, jsonTypeName(value))
+	}
+	switch schema.Type {
+	case jsonschema.Object:
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return mismatch()
+		}
+		for _, name := range schema.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %q", path, name)
+			}
+		}
+		for name, prop := range schema.Properties {
+			if v, ok := obj[name]; ok {
+				if err := validateJSONSchema(prop, v, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case jsonschema.Array:
+		items, ok := value.([]any)
+		if !ok {
+			return mismatch()
+		}
+		if schema.Items != nil {
+			for i, item := range items {
+				if err := validateJSONSchema(*schema.Items, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case jsonschema.String:
+		if _, ok := value.(string); !ok {
+			return mismatch()
+		}
+	case jsonschema.Number:
+		if _, ok := value.(float64); !ok {
+			return mismatch()
+		}
+	case jsonschema.Integer:
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return mismatch()
+		}
+	case jsonschema.Boolean:
+		if _, ok := value.(bool); !ok {
+			return mismatch()
+		}
+	}
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, fmt.Sprint(value)) {
+		return fmt.Errorf("%s: %v is not one of %s", path, value, strings.Join(schema.Enum, ", "))
+	}
+	return nil
+}
+
+func jsonTypeName(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// maxSummaryValueLength bounds the values shown by ToolStatus.Summary.
+const maxSummaryValueLength = 200
+
+// Summary describes the result for prompts: the top-level fields of the
+// Output of tools with an output schema, with long values shortened, and
+// the Result otherwise.
+func (s ToolStatus) Summary() string {
+	obj, ok := s.Output.(map[string]any)
+	if !ok {
+		if s.Output == nil {
+			return s.Result
+		}
+		return summaryValue(s.Output)
+	}
+	names := make([]string, 0, len(obj))
+	for name := range obj {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := make([]string, len(names))
+	for i, name := range names {
+		fields[i] = name + ": " + summaryValue(obj[name])
+	}
+	return strings.Join(fields, "; ")
+}
+
+func summaryValue(v any) string {
+	var s string
+	switch v := v.(type) {
+	case string:
+		s = v
+	case []any:
+		return fmt.Sprintf("[%d items]", len(v))
+	default:
+		s = string(mustMarshal(v))
+	}
+	if len(s) > maxSummaryValueLength {
+		s = s[:maxSummaryValueLength] + "..."
+	}
+	return s
+}
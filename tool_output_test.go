@@ -0,0 +1,80 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type forecastOutput struct {
+	City        string  `json:"city"`
+	Temperature float64 `json:"temperature"`
+	Conditions  string  `json:"conditions" enum:"sunny,cloudy,rainy"`
+}
+
+type rawForecastRunner struct{ results []string }
+
+func (r *rawForecastRunner) Run(args WeatherArgs) (string, any, error) {
+	result := r.results[0]
+	if len(r.results) > 1 {
+		r.results = r.results[1:]
+	}
+	return result, nil, nil
+}
+
+var _ = Describe("Tool output schemas", func() {
+	var mockLLM *cogitotest.MockLLM
+	var fragment Fragment
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		fragment = NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+	})
+
+	forecastTool := func(results ...string) ToolDefinitionInterface {
+		return &ToolDefinition[WeatherArgs]{
+			ToolRunner:     &rawForecastRunner{results: results},
+			InputArguments: WeatherArgs{},
+			OutputSchema:   forecastOutput{},
+			Name:           "get_weather",
+			Description:    "Get the weather",
+		}
+	}
+
+	It("exposes the parsed output of valid results", func() {
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("It's sunny in Rome.")
+
+		result, err := ExecuteTools(mockLLM, fragment, WithIterations(1),
+			WithTools(forecastTool(`{"city": "Rome", "temperature": 24.5, "conditions": "sunny"}`)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		status := result.Status.ToolResults[0]
+		Expect(status.Output).To(Equal(map[string]any{"city": "Rome", "temperature": 24.5, "conditions": "sunny"}))
+		Expect(status.Summary()).To(Equal("city: Rome; conditions: sunny; temperature: 24.5"))
+	})
+
+	It("handles results not matching the schema as tool errors", func() {
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("The weather is unavailable.")
+
+		result, err := ExecuteTools(mockLLM, fragment, WithIterations(1), WithMaxAttempts(2),
+			WithTools(forecastTool(`{"city": "Rome", "temperature": "warm"}`, `{"city": "Rome", "temperature": 24, "conditions": "sunny"}`)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		Expect(result.Status.ToolResults[0].Output).To(HaveKeyWithValue("temperature", 24.0))
+	})
+
+	It("reports the mismatch when the attempts run out", func() {
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("The weather is unavailable.")
+
+		result, err := ExecuteTools(mockLLM, fragment, WithIterations(1),
+			WithTools(forecastTool(`{"city": "Rome", "temperature": 24, "conditions": "foggy"}`)))
+		Expect(err).ToNot(HaveOccurred())
+		status := result.Status.ToolResults[0]
+		Expect(status.Output).To(BeNil())
+		Expect(status.Result).To(ContainSubstring("output.conditions: foggy is not one of sunny, cloudy, rainy"))
+	})
+})
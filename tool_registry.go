@@ -0,0 +1,85 @@
+package cogito
+
+import "sync"
+
+// ToolRegistry is a set of tools that can change while a run is in progress.
+// Pass it with WithToolRegistry: ExecuteTools reads it at every iteration, so
+// tools registered or unregistered by a tool or a callback are offered to
+// the LLM from the next iteration on. A discovery tool can, for example,
+// register specialized tools once it learns what system it is talking to.
+//
+// It is safe for concurrent use.
+type ToolRegistry struct {
+	mu    sync.RWMutex
+	tools Tools
+}
+
+// NewToolRegistry returns a registry holding tools.
+func NewToolRegistry(tools ...ToolDefinitionInterface) *ToolRegistry {
+	r := &ToolRegistry{}
+	r.Register(tools...)
+	return r
+}
+
+// Register adds tools to the registry, replacing registered tools with the
+// same name.
+func (r *ToolRegistry) Register(tools ...ToolDefinitionInterface) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, tool := range tools {
+		name := tool.Tool().Function.Name
+		replaced := false
+		for i, registered := range r.tools {
+			if registered.Tool().Function.Name == name {
+				r.tools[i] = tool
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			r.tools = append(r.tools, tool)
+		}
+	}
+}
+
+// Unregister removes the tools with the given names. It returns how many
+// tools were removed.
+func (r *ToolRegistry) Unregister(names ...string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	kept := r.tools[:0]
+	removed := 0
+	for _, tool := range r.tools {
+		remove := false
+		for _, name := range names {
+			if tool.Tool().Function.Name == name {
+				remove = true
+				break
+			}
+		}
+		if remove {
+			removed++
+			continue
+		}
+		kept = append(kept, tool)
+	}
+	clear(r.tools[len(kept):])
+	r.tools = kept
+	return removed
+}
+
+// Tools returns a snapshot of the registered tools.
+func (r *ToolRegistry) Tools() Tools {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append(Tools{}, r.tools...)
+}
+
+// localTools returns the tools given with WithTools followed by the ones
+// currently in the tool registry.
+func (o *Options) localTools() Tools {
+	if o.toolRegistry == nil {
+		return o.tools
+	}
+	return append(append(Tools{}, o.tools...), o.toolRegistry.Tools()...)
+}
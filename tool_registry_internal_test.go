@@ -0,0 +1,35 @@
+package cogito
+
+import "testing"
+
+func TestToolRegistry(t *testing.T) {
+	r := NewToolRegistry(lookupTool("lookup", "Lookup v1"), lookupTool("search", "Search"))
+
+	r.Register(lookupTool("lookup", "Lookup v2"), lookupTool("report", "Report"))
+	tools := r.Tools()
+	if names := tools.Names(); len(names) != 3 || names[0] != "lookup" || names[1] != "search" || names[2] != "report" {
+		t.Fatalf("registered tools = %v", names)
+	}
+	if d := tools.Find("lookup").Tool().Function.Description; d != "Lookup v2" {
+		t.Fatalf("lookup was not replaced, description = %q", d)
+	}
+
+	if n := r.Unregister("search", "missing"); n != 1 {
+		t.Fatalf("unregistered %d tools, want 1", n)
+	}
+	if names := r.Tools().Names(); len(names) != 2 || names[0] != "lookup" || names[1] != "report" {
+		t.Fatalf("tools after unregister = %v", names)
+	}
+	// Snapshots are not affected by later changes
+	if len(tools) != 3 {
+		t.Fatalf("snapshot changed to %v", tools.Names())
+	}
+}
+
+func TestLocalToolsIncludesRegistry(t *testing.T) {
+	o := defaultOptions()
+	o.Apply(WithTools(lookupTool("lookup", "Lookup")), WithToolRegistry(NewToolRegistry(lookupTool("search", "Search"))))
+	if names := o.localTools().Names(); len(names) != 2 || names[1] != "search" {
+		t.Fatalf("local tools = %v", names)
+	}
+}
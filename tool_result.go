@@ -0,0 +1,74 @@
+package cogito
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToolResult is a rich tool output combining text, images and structured
+// data. Tools return it as result data (the second value returned by Run or
+// Execute). ExecuteTools then:
+//   - uses String() as the tool message when the text result is empty,
+//   - appends Images to Fragment.Multimedia and shows them to the LLM in a
+//     follow-up user message (tool messages cannot carry images),
+//   - keeps the ToolResult itself in ToolStatus.ResultData.
+type ToolResult struct {
+	Text   string
+	Images []Multimedia
+	JSON   any // structured payload, rendered as JSON in the conversation
+}
+
+// String renders the textual part of the result: Text followed by JSON.
+func (r ToolResult) String() string {
+	var parts []string
+	if r.Text != "" {
+		parts = append(parts, r.Text)
+	}
+	if r.JSON != nil {
+		b, err := json.MarshalIndent(r.JSON, "", "  ")
+		if err != nil {
+			parts = append(parts, fmt.Sprintf("%v", r.JSON))
+		} else {
+			parts = append(parts, string(b))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MarshalJSON encodes the result with its images.
+func (r ToolResult) MarshalJSON() ([]byte, error) {
+	type toolResult ToolResult
+	return json.Marshal(struct {
+		toolResult
+		Images []multimediaJSON
+	}{toolResult(r), encodeMultimedia(r.Images)})
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON.
+func (r *ToolResult) UnmarshalJSON(data []byte) error {
+	type toolResult ToolResult
+	var decoded struct {
+		toolResult
+		Images []multimediaJSON
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*r = ToolResult(decoded.toolResult)
+	r.Images = decodeMultimedia(decoded.Images)
+	return nil
+}
+
+// asToolResult reports whether a tool's result data is a ToolResult.
+func asToolResult(resultData any) (ToolResult, bool) {
+	switch v := resultData.(type) {
+	case *ToolResult:
+		if v != nil {
+			return *v, true
+		}
+	case ToolResult:
+		return v, true
+	}
+	return ToolResult{}, false
+}
@@ -0,0 +1,65 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+type chartImage string
+
+func (c chartImage) URL() string { return string(c) }
+
+type ChartArgs struct {
+	Metric string `json:"metric"`
+}
+
+// chartTool returns a rendered chart along with the plotted data.
+type chartTool struct{}
+
+func (c *chartTool) Run(args ChartArgs) (string, any, error) {
+	return "", &ToolResult{
+		Text:   "Chart of " + args.Metric,
+		Images: []Multimedia{chartImage("data:image/png;base64,AAAA")},
+		JSON:   map[string]int{"max": 42},
+	}, nil
+}
+
+var _ = Describe("Rich tool results", func() {
+	It("renders text and JSON and shows images to the LLM", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		tool := NewToolDefinition(&chartTool{}, ChartArgs{}, "chart", "Plot a metric")
+
+		mockLLM.AddCreateChatCompletionFunction("chart", `{"metric": "latency"}`)
+		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+			Choices: []openai.ChatCompletionChoice{
+				{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Latency peaks at 42."}},
+			},
+		})
+
+		fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Plot latency")
+		result, err := ExecuteTools(mockLLM, fragment, WithTools(tool), WithIterations(2))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(result.Status.ToolResults).To(HaveLen(1))
+		Expect(result.Status.ToolResults[0].Result).To(Equal("Chart of latency\n{\n  \"max\": 42\n}"))
+		Expect(result.Status.ToolResults[0].ResultData).To(BeAssignableToTypeOf(&ToolResult{}))
+
+		Expect(result.Multimedia).To(ConsistOf(chartImage("data:image/png;base64,AAAA")))
+
+		var imageParts []openai.ChatMessagePart
+		for i, msg := range result.Messages {
+			for _, part := range msg.MultiContent {
+				if part.Type == openai.ChatMessagePartTypeImageURL {
+					Expect(msg.Role).To(Equal(UserMessageRole.String()))
+					Expect(result.Messages[i-1].Role).To(Equal(ToolMessageRole.String()))
+					imageParts = append(imageParts, part)
+				}
+			}
+		}
+		Expect(imageParts).To(HaveLen(1))
+		Expect(imageParts[0].ImageURL.URL).To(Equal("data:image/png;base64,AAAA"))
+	})
+})
@@ -0,0 +1,124 @@
+package cogito
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mudler/cogito/prompt"
+)
+
+// ToolResultTransformer post-processes a tool result before it is added to
+// the conversation and recorded in Status.ToolResults. See
+// WithToolResultTransformer.
+type ToolResultTransformer func(ToolStatus) ToolStatus
+
+// applyToolResultTransformers runs the transformers in order.
+func applyToolResultTransformers(status ToolStatus, transformers []ToolResultTransformer) ToolStatus {
+	for _, t := range transformers {
+		status = t(status)
+	}
+	return status
+}
+
+// TruncateToolResult truncates results longer than maxTokens, estimated at
+// four bytes per token. Results are cut on a rune boundary.
+func TruncateToolResult(maxTokens int) ToolResultTransformer {
+	return func(s ToolStatus) ToolStatus {
+		maxChars := maxTokens * 4
+		if maxTokens <= 0 || len(s.Result) <= maxChars {
+			return s
+		}
+		kept := truncateUTF8(s.Result, maxChars)
+		s.Result = fmt.Sprintf("%s\n[... truncated %d characters]", kept, utf8.RuneCountInString(s.Result[len(kept):]))
+		return s
+	}
+}
+
+// PrettyPrintJSONToolResult indents results that are valid JSON. Other
+// results are left untouched.
+func PrettyPrintJSONToolResult() ToolResultTransformer {
+	return func(s ToolStatus) ToolStatus {
+		trimmed := strings.TrimSpace(s.Result)
+		if trimmed == "" || !json.Valid([]byte(trimmed)) {
+			return s
+		}
+		var b bytes.Buffer
+		if err := json.Indent(&b, []byte(trimmed), "", "  "); err == nil {
+			s.Result = b.String()
+		}
+		return s
+	}
+}
+
+var (
+	htmlDropBlocks = regexp.MustCompile(`(?is)<(script|style|head|noscript)[^>]*>.*?</(script|style|head|noscript)>`)
+	htmlComments   = regexp.MustCompile(`(?s)<!--.*?-->`)
+	htmlBreaks     = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6]|/title)\s*/?>`)
+	htmlTags       = regexp.MustCompile(`(?s)<[^>]+>`)
+	htmlSpaces     = regexp.MustCompile(`[ \t\r\f\v]+`)
+	htmlBlankLines = regexp.MustCompile(`\n\s*\n+`)
+	htmlDetect     = regexp.MustCompile(`(?i)<(html|body|div|p|span|a|table|br|h[1-6]|ul|li)[\s>/]`)
+)
+
+// HTMLToTextToolResult converts results that look like HTML to plain text,
+// dropping scripts, styles and markup.
+func HTMLToTextToolResult() ToolResultTransformer {
+	return func(s ToolStatus) ToolStatus {
+		if !htmlDetect.MatchString(s.Result) {
+			return s
+		}
+		text := htmlDropBlocks.ReplaceAllString(s.Result, "")
+		text = htmlComments.ReplaceAllString(text, "")
+		text = htmlBreaks.ReplaceAllString(text, "\n")
+		text = htmlTags.ReplaceAllString(text, " ")
+		text = html.UnescapeString(text)
+		text = htmlSpaces.ReplaceAllString(text, " ")
+		lines := strings.Split(text, "\n")
+		for i, l := range lines {
+			lines[i] = strings.TrimSpace(l)
+		}
+		text = htmlBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+		s.Result = strings.TrimSpace(text)
+		return s
+	}
+}
+
+// SummarizeToolResult asks llm to summarize results longer than thresholdChars
+// characters. opts configure the call (context, prompts). On failure the
+// result is left untouched.
+func SummarizeToolResult(llm LLM, thresholdChars int, opts ...Option) ToolResultTransformer {
+	o := defaultOptions()
+	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	return func(s ToolStatus) ToolStatus {
+		if len(s.Result) <= thresholdChars {
+			return s
+		}
+		p, err := o.prompts.GetPrompt(prompt.PromptToolResultSummaryType).Render(struct {
+			Tool      string
+			Arguments string
+			Result    string
+		}{
+			Tool:      s.Name,
+			Arguments: string(mustMarshal(s.ToolArguments.Arguments)),
+			Result:    s.Result,
+		})
+		if err != nil {
+			o.logger.Warn("Failed to render tool result summary prompt", "error", err)
+			return s
+		}
+		res, err := llm.Ask(o.context, NewEmptyFragment().AddMessage(UserMessageRole, p))
+		if err != nil || res.LastMessage() == nil {
+			o.logger.Warn("Failed to summarize tool result", "tool", s.Name, "error", err)
+			return s
+		}
+		s.Result = strings.TrimSpace(res.LastMessage().Content)
+		return s
+	}
+}
@@ -0,0 +1,78 @@
+package cogito
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// summaryLLM answers every Ask with a fixed summary.
+type summaryLLM struct {
+	fakeLLM
+	prompt string
+}
+
+func (s *summaryLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	s.prompt = f.LastMessage().Content
+	return f.AddMessage(AssistantMessageRole, " short summary "), nil
+}
+
+func TestTruncateToolResult(t *testing.T) {
+	s := TruncateToolResult(10)(ToolStatus{Result: strings.Repeat("a", 100)})
+	if !strings.HasPrefix(s.Result, strings.Repeat("a", 40)+"\n[... truncated 60 characters]") {
+		t.Errorf("Result = %q", s.Result)
+	}
+	if s := TruncateToolResult(10)(ToolStatus{Result: "short"}); s.Result != "short" {
+		t.Errorf("short result changed: %q", s.Result)
+	}
+	s = TruncateToolResult(1)(ToolStatus{Result: "abcé€"})
+	if !utf8.ValidString(s.Result) || !strings.HasPrefix(s.Result, "abc\n[... truncated 2 characters]") {
+		t.Errorf("Result = %q, want it cut before the multi-byte runes", s.Result)
+	}
+}
+
+func TestPrettyPrintJSONToolResult(t *testing.T) {
+	s := PrettyPrintJSONToolResult()(ToolStatus{Result: `{"a":[1,2]}`})
+	if s.Result != "{\n  \"a\": [\n    1,\n    2\n  ]\n}" {
+		t.Errorf("Result = %q", s.Result)
+	}
+	if s := PrettyPrintJSONToolResult()(ToolStatus{Result: "not json"}); s.Result != "not json" {
+		t.Errorf("non-JSON result changed: %q", s.Result)
+	}
+}
+
+func TestHTMLToTextToolResult(t *testing.T) {
+	in := `<html><head><title>x</title><style>p{}</style></head><body><h1>Title</h1><p>Fish &amp; chips</p><script>alert(1)</script></body></html>`
+	s := HTMLToTextToolResult()(ToolStatus{Result: in})
+	if s.Result != "Title\nFish & chips" {
+		t.Errorf("Result = %q", s.Result)
+	}
+	if s := HTMLToTextToolResult()(ToolStatus{Result: "a < b"}); s.Result != "a < b" {
+		t.Errorf("plain text changed: %q", s.Result)
+	}
+}
+
+func TestSummarizeToolResult(t *testing.T) {
+	llm := &summaryLLM{}
+	transform := SummarizeToolResult(llm, 50)
+
+	if s := transform(ToolStatus{Name: "fetch", Result: "small"}); s.Result != "small" || llm.prompt != "" {
+		t.Errorf("result under threshold was summarized")
+	}
+	s := transform(ToolStatus{Name: "fetch", Result: strings.Repeat("page ", 100)})
+	if s.Result != "short summary" {
+		t.Errorf("Result = %q", s.Result)
+	}
+	if !strings.Contains(llm.prompt, "Tool: fetch") {
+		t.Errorf("prompt does not mention the tool: %q", llm.prompt)
+	}
+}
+
+func TestApplyToolResultTransformersInOrder(t *testing.T) {
+	s := applyToolResultTransformers(ToolStatus{Result: `<p>{"a":1}</p>`},
+		[]ToolResultTransformer{HTMLToTextToolResult(), PrettyPrintJSONToolResult()})
+	if s.Result != "{\n  \"a\": 1\n}" {
+		t.Errorf("Result = %q", s.Result)
+	}
+}
@@ -9,9 +9,7 @@ import (
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	"github.com/mudler/cogito/prompt"
-	"github.com/mudler/xlog"
 	"github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
 )
@@ -20,6 +18,10 @@ var (
 	ErrNoToolSelected              error = errors.New("no tool selected by the LLM")
 	ErrLoopDetected                error = errors.New("loop detected: same tool called repeatedly with same parameters")
 	ErrToolCallCallbackInterrupted error = errors.New("interrupted via ToolCallCallback")
+	// ErrDirectResponse is returned with WithCaptureDirectResponse when the LLM
+	// answered without calling a tool: the answer is the last message of the
+	// returned fragment, there is no need to Ask again.
+	ErrDirectResponse error = errors.New("the LLM answered directly without calling a tool")
 )
 
 type ToolStatus struct {
@@ -28,6 +30,11 @@ type ToolStatus struct {
 	Result        string
 	Name          string
 	ResultData    any
+	FollowUps     []ToolFollowUp   // Questions the tool asked the LLM before producing Result
+	ExecutedAt    time.Time        // When the tool ran
+	Corrections   []ToolCorrection // Failed calls sent back to the LLM for correction (see WithToolCorrection)
+	Expired       bool             // Result older than its TTL, removed from the conversation (see WithToolResultTTL)
+	Output        any              // Result parsed as JSON, for tools with an output schema (see ToolWithOutputSchema)
 }
 
 type SessionState struct {
@@ -45,6 +52,12 @@ type decisionResult struct {
 	message     string
 	reasoning   string
 	usage       LLMUsage
+	// argumentsComplete is set when the tool choices already carry their
+	// arguments, so they don't need to be generated separately.
+	argumentsComplete bool
+	// confidence derived from the token log probabilities of the reply, nil
+	// when the backend doesn't return them.
+	confidence *float64
 }
 
 type ToolDefinitionInterface interface {
@@ -57,10 +70,33 @@ type Tool[T any] interface {
 	Run(args T) (string, any, error)
 }
 
+// ToolWithContext is implemented by tools that accept the run context.
+// ExecuteTools prefers it over Execute so cancellation, deadlines and
+// request-scoped values reach the tool.
+type ToolWithContext interface {
+	ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error)
+}
+
+// ContextTool is the context-aware counterpart of Tool. A ToolRunner
+// implementing it has RunWithContext called instead of Run.
+type ContextTool[T any] interface {
+	RunWithContext(ctx context.Context, args T) (string, any, error)
+}
+
 type ToolDefinition[T any] struct {
 	ToolRunner        Tool[T]
 	InputArguments    any
 	Name, Description string
+	Concurrency       ToolConcurrency // limits on concurrent calls, see ToolConcurrency
+	// OutputSchema is the JSON schema of the results, as a struct or a JSON
+	// schema map like InputArguments. Optional, see ToolWithOutputSchema.
+	OutputSchema any
+	// Compensation undoes a successful call when the run fails. Optional,
+	// see EnableCompensation.
+	Compensation CompensationFunc
+	// Roles restricts the tool to identities with one of these roles.
+	// Optional, see WithIdentity.
+	Roles []string
 }
 
 func NewToolDefinition[T any](toolRunner Tool[T], inputArguments any, name, description string) ToolDefinitionInterface {
@@ -75,27 +111,9 @@ func NewToolDefinition[T any](toolRunner Tool[T], inputArguments any, name, desc
 var _ ToolDefinitionInterface = &ToolDefinition[any]{}
 
 func (t ToolDefinition[T]) Tool() openai.Tool {
-	var schema *jsonschema.Definition
-
-	// Handle map[string]interface{} (JSON schema format)
-	if inputMap, ok := t.InputArguments.(map[string]any); ok {
-		dat, err := json.Marshal(inputMap)
-		if err != nil {
-			panic(err)
-		}
-		s := &jsonschema.Definition{}
-		err = json.Unmarshal(dat, s)
-		if err != nil {
-			panic(err)
-		}
-		schema = s
-	} else {
-		// Try to generate schema from struct type
-		var err error
-		schema, err = jsonschema.GenerateSchemaForType(t.InputArguments)
-		if err != nil {
-			panic(fmt.Errorf("unsupported InputArguments type: %T, error: %w", t.InputArguments, err))
-		}
+	schema, err := toJSONSchema(t.InputArguments)
+	if err != nil {
+		panic(fmt.Errorf("unsupported InputArguments type: %T, error: %w", t.InputArguments, err))
 	}
 
 	return openai.Tool{
@@ -108,8 +126,33 @@ func (t ToolDefinition[T]) Tool() openai.Tool {
 	}
 }
 
+// toJSONSchema returns the JSON schema of v: a JSON schema map, or a value
+// of the struct type to generate it from.
+func toJSONSchema(v any) (*jsonschema.Definition, error) {
+	// Handle map[string]interface{} (JSON schema format)
+	if schemaMap, ok := v.(map[string]any); ok {
+		dat, err := json.Marshal(schemaMap)
+		if err != nil {
+			return nil, err
+		}
+		s := &jsonschema.Definition{}
+		if err := json.Unmarshal(dat, s); err != nil {
+			return nil, err
+		}
+		return s, nil
+	}
+	// Try to generate schema from struct type
+	return jsonschema.GenerateSchemaForType(v)
+}
+
 // Execute implements ToolDef.Execute by marshaling the arguments map to type T and calling ToolRunner.Run
 func (t *ToolDefinition[T]) Execute(args map[string]any) (string, any, error) {
+	return t.ExecuteWithContext(context.Background(), args)
+}
+
+// ExecuteWithContext implements ToolWithContext. The context is passed to
+// ToolRunner when it implements ContextTool.
+func (t *ToolDefinition[T]) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
 	if t.ToolRunner == nil {
 		return "", nil, fmt.Errorf("tool %s has no ToolRunner", t.Name)
 	}
@@ -128,9 +171,20 @@ func (t *ToolDefinition[T]) Execute(args map[string]any) (string, any, error) {
 	}
 
 	// Call Run with the typed arguments
+	if runner, ok := t.ToolRunner.(ContextTool[T]); ok {
+		return runner.RunWithContext(ctx, *argsPtr)
+	}
 	return t.ToolRunner.Run(*argsPtr)
 }
 
+// executeTool runs tool with ctx when it supports it.
+func executeTool(ctx context.Context, tool ToolDefinitionInterface, args map[string]any) (string, any, error) {
+	if t, ok := tool.(ToolWithContext); ok {
+		return t.ExecuteWithContext(ctx, args)
+	}
+	return tool.Execute(args)
+}
+
 type Tools []ToolDefinitionInterface
 
 func (t Tools) Find(name string) ToolDefinitionInterface {
@@ -160,6 +214,49 @@ func (t Tools) Definitions() []*openai.FunctionDefinition {
 	return defs
 }
 
+// MarshalJSON encodes the tools as their OpenAI definitions, so fragments
+// and session states can be serialized.
+func (t Tools) MarshalJSON() ([]byte, error) {
+	if t == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.ToOpenAI())
+}
+
+// UnmarshalJSON decodes tools encoded by MarshalJSON. Only the definitions are
+// restored: executing them fails, pass the real tools with WithTools.
+func (t *Tools) UnmarshalJSON(data []byte) error {
+	var defs []openai.Tool
+	if err := json.Unmarshal(data, &defs); err != nil {
+		return err
+	}
+	if defs == nil {
+		*t = nil
+		return nil
+	}
+	tools := make(Tools, 0, len(defs))
+	for _, def := range defs {
+		tools = append(tools, restoredTool{def})
+	}
+	*t = tools
+	return nil
+}
+
+// restoredTool is a tool definition decoded from JSON, without its runner.
+type restoredTool struct {
+	tool openai.Tool
+}
+
+func (r restoredTool) Tool() openai.Tool { return r.tool }
+
+func (r restoredTool) Execute(args map[string]any) (string, any, error) {
+	name := ""
+	if r.tool.Function != nil {
+		name = r.tool.Function.Name
+	}
+	return "", nil, fmt.Errorf("tool %q was restored from JSON and cannot be executed", name)
+}
+
 func (t Tools) Names() []string {
 	names := make([]string, len(t))
 	for i, tool := range t {
@@ -188,6 +285,26 @@ func checkForLoop(pastActions []ToolStatus, currentTool *ToolChoice, loopDetecti
 	return count >= loopDetectionSteps
 }
 
+// duplicateToolResultRef returns a short reference to an earlier identical
+// result of the same tool, or "" if there is none (or the result is not
+// longer than the reference itself).
+func duplicateToolResultRef(previous []ToolStatus, current ToolStatus) string {
+	for i, prev := range previous {
+		if prev.Expired || prev.Name != current.Name || prev.Result != current.Result {
+			continue
+		}
+		ref := fmt.Sprintf("Same as previous result of tool %q (result #%d).", current.Name, i+1)
+		if prev.ToolArguments.ID != "" {
+			ref = fmt.Sprintf("Same as previous result of tool %q (tool call %s).", current.Name, prev.ToolArguments.ID)
+		}
+		if len(ref) >= len(current.Result) {
+			return ""
+		}
+		return ref
+	}
+	return ""
+}
+
 // normalizeSystemMessages consolidates all system messages at the beginning of the
 // conversation. Some models (e.g., Qwen) require system messages to appear only at
 // the start of the conversation and will reject requests with mid-conversation system
@@ -275,16 +392,17 @@ func mergeConsecutiveAssistantMessages(messages []openai.ChatCompletionMessage)
 // callback are available, forwarding reasoning/content/tool_call deltas live.
 // Falls back to decision() when streaming is not possible.
 func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.ChatCompletionMessage,
-	tools Tools, forceTool string, maxRetries int, streamCB StreamCallback) (*decisionResult, error) {
+	tools Tools, forceTool string, maxRetries int, streamCB StreamCallback, logger Logger, prompts prompt.PromptMap) (*decisionResult, error) {
 
 	sllm, isStreaming := llm.(StreamingLLM)
 	if !isStreaming || streamCB == nil {
-		return decision(ctx, llm, conversation, tools, forceTool, maxRetries)
+		return decision(ctx, llm, conversation, tools, forceTool, maxRetries, logger, prompts)
 	}
 
 	req := openai.ChatCompletionRequest{
 		Messages: mergeConsecutiveAssistantMessages(normalizeSystemMessages(conversation)),
 		Tools:    tools.ToOpenAI(),
+		LogProbs: wantsLogprobs(ctx),
 	}
 
 	if forceTool != "" {
@@ -294,7 +412,7 @@ func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.C
 		}
 	}
 
-	xlog.Debug("[decisionWithStreaming] available tools for selection", "tools", tools.Names())
+	logger.Debug("[decisionWithStreaming] available tools for selection", "tools", tools.Names())
 
 	var lastErr error
 	for attempts := 0; attempts < maxRetries; attempts++ {
@@ -305,7 +423,7 @@ func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.C
 		ch, err := sllm.CreateChatCompletionStream(ctx, req)
 		if err != nil {
 			lastErr = err
-			xlog.Warn("Streaming attempt to make a decision failed", "attempt", attempts+1, "error", err)
+			logger.Warn("Streaming attempt to make a decision failed", "attempt", attempts+1, "error", err)
 			if werr := backoffOrCancel(ctx, attempts); werr != nil {
 				return nil, werr
 			}
@@ -352,7 +470,7 @@ func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.C
 
 		if streamErr != nil {
 			lastErr = streamErr
-			xlog.Warn("Streaming decision encountered error", "attempt", attempts+1, "error", streamErr)
+			logger.Warn("Streaming decision encountered error", "attempt", attempts+1, "error", streamErr)
 			if werr := backoffOrCancel(ctx, attempts); werr != nil {
 				return nil, werr
 			}
@@ -365,15 +483,15 @@ func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.C
 			toolCalls = append(toolCalls, *toolCallMap[idx])
 		}
 
-		reasoning := reasoningBuf.String()
-		content := contentBuf.String()
+		thinking, content := SplitThinking(contentBuf.String())
+		reasoning := joinReasoning(reasoningBuf.String(), thinking)
 
-		xlog.Debug("[decisionWithStreaming] processed", "message", content, "reasoning", reasoning)
+		logger.Debug("[decisionWithStreaming] processed", "message", content, "reasoning", reasoning)
 
 		if len(toolCalls) == 0 {
 			if content == "" {
 				// Model produced no visible content (empty response or only reasoning) — retry
-				xlog.Warn("Streaming decision produced no content, retrying", "attempt", attempts+1)
+				logger.Warn("Streaming decision produced no content, retrying", "attempt", attempts+1)
 				if werr := backoffOrCancel(ctx, attempts); werr != nil {
 					return nil, werr
 				}
@@ -386,10 +504,11 @@ func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.C
 		toolChoices := make([]*ToolChoice, 0, len(toolCalls))
 		allParsed := true
 		for _, toolCall := range toolCalls {
-			arguments := make(map[string]any)
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &arguments); err != nil {
+			arguments, repairUsage, err := parseToolArguments(ctx, llm, tools, toolCall.Function, prompts, logger)
+			usage = addUsage(usage, repairUsage)
+			if err != nil {
 				lastErr = err
-				xlog.Warn("Attempt to parse streamed tool arguments failed", "attempt", attempts+1, "error", err)
+				logger.Warn("Attempt to parse streamed tool arguments failed", "attempt", attempts+1, "error", err)
 				allParsed = false
 				break
 			}
@@ -406,7 +525,7 @@ func decisionWithStreaming(ctx context.Context, llm LLM, conversation []openai.C
 			continue
 		}
 
-		xlog.Debug("[decisionWithStreaming] tools selected", "message", content, "toolChoices", len(toolChoices))
+		logger.Debug("[decisionWithStreaming] tools selected", "message", content, "toolChoices", len(toolChoices))
 		return &decisionResult{
 			toolChoices: toolChoices,
 			message:     content,
@@ -434,11 +553,12 @@ func backoffOrCancel(ctx context.Context, attempt int) error {
 // decision forces the LLM to make a tool choice with retry logic
 // Similar to agent.go's decision function but adapted for cogito's architecture
 func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletionMessage,
-	tools Tools, forceTool string, maxRetries int) (*decisionResult, error) {
+	tools Tools, forceTool string, maxRetries int, logger Logger, prompts prompt.PromptMap) (*decisionResult, error) {
 
 	decision := openai.ChatCompletionRequest{
 		Messages: mergeConsecutiveAssistantMessages(normalizeSystemMessages(conversation)),
 		Tools:    tools.ToOpenAI(),
+		LogProbs: wantsLogprobs(ctx),
 	}
 
 	if forceTool != "" {
@@ -448,7 +568,7 @@ func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletion
 		}
 	}
 
-	xlog.Debug("[decision] available tools for selection", "tools", tools.Names())
+	logger.Debug("[decision] available tools for selection", "tools", tools.Names())
 
 	var lastErr error
 	for attempts := 0; attempts < maxRetries; attempts++ {
@@ -459,7 +579,7 @@ func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletion
 		resp, usage, err := llm.CreateChatCompletion(ctx, decision)
 		if err != nil {
 			lastErr = err
-			xlog.Warn("Attempt to make a decision failed", "attempt", attempts+1, "error", err)
+			logger.Warn("Attempt to make a decision failed", "attempt", attempts+1, "error", err)
 			if werr := backoffOrCancel(ctx, attempts); werr != nil {
 				return nil, werr
 			}
@@ -468,7 +588,7 @@ func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletion
 
 		if len(resp.ChatCompletionResponse.Choices) != 1 {
 			lastErr = fmt.Errorf("no choices: %d", len(resp.ChatCompletionResponse.Choices))
-			xlog.Warn("Attempt to make a decision failed", "attempt", attempts+1, "error", lastErr)
+			logger.Warn("Attempt to make a decision failed", "attempt", attempts+1, "error", lastErr)
 			if werr := backoffOrCancel(ctx, attempts); werr != nil {
 				return nil, werr
 			}
@@ -476,23 +596,24 @@ func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletion
 		}
 
 		msg := resp.ChatCompletionResponse.Choices[0].Message
-		reasoning := resp.ReasoningContent
+		reasoning := separateThinking(&msg, resp.ReasoningContent)
 		//reasoning := resp.Choices[0].Reasoning
-		xlog.Debug("[decision] processed", "message", msg.Content, "reasoning", reasoning)
+		logger.Debug("[decision] processed", "message", msg.Content, "reasoning", reasoning)
 
+		confidence := logprobConfidence(resp.ChatCompletionResponse.Choices[0].LogProbs)
 		if len(msg.ToolCalls) == 0 {
 			// No tool call - the LLM just responded with text
-			return &decisionResult{message: msg.Content, reasoning: reasoning, usage: usage}, nil
+			return &decisionResult{message: msg.Content, reasoning: reasoning, usage: usage, confidence: confidence}, nil
 		}
 
 		// Process all tool calls
 		toolChoices := make([]*ToolChoice, 0, len(msg.ToolCalls))
 		for _, toolCall := range msg.ToolCalls {
-			arguments := make(map[string]any)
-
-			if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &arguments); err != nil {
+			arguments, repairUsage, err := parseToolArguments(ctx, llm, tools, toolCall.Function, prompts, logger)
+			usage = addUsage(usage, repairUsage)
+			if err != nil {
 				lastErr = err
-				xlog.Warn("Attempt to parse tool arguments failed", "attempt", attempts+1, "error", err)
+				logger.Warn("Attempt to parse tool arguments failed", "attempt", attempts+1, "error", err)
 				if werr := backoffOrCancel(ctx, attempts); werr != nil {
 					return nil, werr
 				}
@@ -500,12 +621,13 @@ func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletion
 			}
 
 			toolChoices = append(toolChoices, &ToolChoice{
-				Name:      toolCall.Function.Name,
-				Arguments: arguments,
+				Name:       toolCall.Function.Name,
+				Arguments:  arguments,
+				Confidence: confidence,
 			})
 		}
 
-		xlog.Debug("[decision] tools selected", "message", msg.Content, "toolChoices", len(toolChoices))
+		logger.Debug("[decision] tools selected", "message", msg.Content, "toolChoices", len(toolChoices))
 
 		// If we successfully parsed all tool calls, return the result
 		if len(toolChoices) == len(msg.ToolCalls) {
@@ -514,6 +636,7 @@ func decision(ctx context.Context, llm LLM, conversation []openai.ChatCompletion
 				message:     msg.Content,
 				reasoning:   reasoning,
 				usage:       usage,
+				confidence:  confidence,
 			}
 			return result, nil
 		}
@@ -576,9 +699,9 @@ func generateToolParameters(o *Options, llm LLM, tool ToolDefinitionInterface, c
 				Role:    "system",
 				Content: paramPrompt,
 			}),
-			Tools{reasoningTool()}, "reasoning", o.maxRetries, o.streamCallback)
+			Tools{reasoningTool()}, "reasoning", o.maxRetries, o.streamCallback, o.logger, o.prompts)
 		if err != nil {
-			xlog.Warn("Failed to get parameter reasoning, using original reasoning", "error", err)
+			o.logger.Warn("Failed to get parameter reasoning, using original reasoning", "error", err)
 			// Fall back to original single-step approach
 			conv = append([]openai.ChatCompletionMessage{
 				{
@@ -612,7 +735,7 @@ func generateToolParameters(o *Options, llm LLM, tool ToolDefinitionInterface, c
 	}
 
 	// Use decision to force parameter generation
-	result, err := decisionWithStreaming(o.context, llm, conv, Tools{tool}, toolFunc.Name, o.maxRetries, o.streamCallback)
+	result, err := decisionWithStreaming(withParameterGeneration(o.context), llm, conv, Tools{tool}, toolFunc.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate parameters for tool %s: %w", toolFunc.Name, err)
 	}
@@ -624,6 +747,121 @@ func generateToolParameters(o *Options, llm LLM, tool ToolDefinitionInterface, c
 	return result.toolChoices[0], nil
 }
 
+// pickToolConsolidated asks for the reasoning, the tool choice(s) and their
+// arguments in a single completion. Any failure (no call, unknown tool,
+// missing required arguments) is returned as an error so the caller can fall
+// back to the multi-step flow.
+func pickToolConsolidated(ctx context.Context, llm LLM, messages []openai.ChatCompletionMessage, tools Tools, o *Options) (*decisionResult, error) {
+	type toolInfo struct {
+		Name        string
+		Description string
+		Parameters  string
+	}
+	var toolNames []string
+	var infos []toolInfo
+	for _, tool := range tools {
+		toolFunc := tool.Tool().Function
+		if toolFunc == nil {
+			continue
+		}
+		toolNames = append(toolNames, toolFunc.Name)
+		params, _ := json.Marshal(toolFunc.Parameters)
+		infos = append(infos, toolInfo{Name: toolFunc.Name, Description: toolFunc.Description, Parameters: string(params)})
+	}
+
+	sinkStateName := ""
+	if o.sinkState {
+		sinkStateName = o.sinkStateTool.Tool().Function.Name
+	}
+
+	decisionTool := consolidatedTool(toolNames, o.parallelToolExecution)
+	decisionPrompt, err := o.prompts.GetPrompt(prompt.PromptConsolidatedReasoningType).Render(struct {
+		ToolName  string
+		Parallel  bool
+		SinkState string
+		Tools     []toolInfo
+	}{
+		ToolName:  decisionTool.Name,
+		Parallel:  o.parallelToolExecution,
+		SinkState: sinkStateName,
+		Tools:     infos,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render consolidated reasoning prompt: %w", err)
+	}
+
+	result, err := decisionWithStreaming(ctx, llm,
+		append(slices.Clone(messages), openai.ChatCompletionMessage{
+			Role:    "user",
+			Content: decisionPrompt,
+		}),
+		Tools{decisionTool}, decisionTool.Name, o.maxRetries, o.streamCallback, o.logger, o.prompts)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.toolChoices) == 0 {
+		return nil, fmt.Errorf("no consolidated decision returned")
+	}
+
+	var response ConsolidatedResponse
+	data, _ := json.Marshal(result.toolChoices[0].Arguments)
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, fmt.Errorf("failed to parse consolidated decision: %w", err)
+	}
+	if len(response.Calls) == 0 {
+		return nil, fmt.Errorf("consolidated decision has no tool calls")
+	}
+
+	confidence := response.Confidence
+	if confidence == nil {
+		confidence = result.confidence
+	}
+
+	var toolChoices []*ToolChoice
+	for _, call := range response.Calls {
+		if o.parallelToolExecution && o.isSinkState(call.Tool) {
+			o.logger.Debug("[pickTool] Sink state detected in consolidated decision")
+			continue
+		}
+		tool := tools.Find(call.Tool)
+		if tool == nil {
+			return nil, fmt.Errorf("chosen tool %q not found", call.Tool)
+		}
+		if call.Arguments == nil {
+			call.Arguments = map[string]any{}
+		}
+		if missing := missingArguments(tool, call.Arguments); len(missing) > 0 {
+			return nil, fmt.Errorf("tool %q is missing required arguments %v", call.Tool, missing)
+		}
+		toolChoices = append(toolChoices, &ToolChoice{
+			Name:       call.Tool,
+			Arguments:  call.Arguments,
+			Reasoning:  response.Reasoning,
+			Confidence: confidence,
+		})
+	}
+
+	o.logger.Debug("[pickTool] Tools selected via consolidated decision", "count", len(toolChoices))
+	return &decisionResult{
+		toolChoices:       toolChoices,
+		reasoning:         response.Reasoning,
+		usage:             result.usage,
+		argumentsComplete: true,
+		confidence:        confidence,
+	}, nil
+}
+
+// missingArguments returns the required parameters of tool absent from args.
+func missingArguments(tool ToolDefinitionInterface, args map[string]any) []string {
+	var missing []string
+	for _, name := range requiredParameters(tool) {
+		if _, ok := args[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
 // pickTool selects tools from available tools with enhanced reasoning
 func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts ...Option) (*decisionResult, error) {
 	o := defaultOptions()
@@ -636,24 +874,32 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 		toolNames = append(toolNames, tool.Tool().Function.Name)
 
 	}
-	xlog.Debug("[pickTool] Starting tool selection",
+	o.logger.Debug("[pickTool] Starting tool selection",
 		"tools", toolNames,
 		"forceReasoning", o.forceReasoning, "parallelToolExecution", o.parallelToolExecution)
 
 	// If not forcing reasoning, try direct tool selection
 	if !o.forceReasoning {
-		xlog.Debug("[pickTool] Using direct tool selection")
-		result, err := decisionWithStreaming(ctx, llm, messages, tools, "", o.maxRetries, o.streamCallback)
+		o.logger.Debug("[pickTool] Using direct tool selection")
+		result, err := decisionWithStreaming(ctx, llm, messages, tools, "", o.maxRetries, o.streamCallback, o.logger, o.prompts)
 		if err != nil {
 			return nil, fmt.Errorf("tool selection failed: %w", err)
 		}
 
-		xlog.Debug("[pickTool] Tools selected", "count", len(result.toolChoices))
+		o.logger.Debug("[pickTool] Tools selected", "count", len(result.toolChoices))
 		return result, nil
 	}
 
+	if o.consolidatedReasoning {
+		result, err := pickToolConsolidated(ctx, llm, messages, tools, o)
+		if err == nil {
+			return result, nil
+		}
+		o.logger.Warn("[pickTool] Consolidated decision failed, falling back to multi-step reasoning", "error", err)
+	}
+
 	// Force reasoning approach
-	xlog.Debug("[pickTool] Using forced reasoning approach with intention tool", "forceReasoningTool", o.forceReasoningTool)
+	o.logger.Debug("[pickTool] Using forced reasoning approach with intention tool", "forceReasoningTool", o.forceReasoningTool)
 
 	var reasoning string
 
@@ -679,7 +925,7 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 			Role:    "user",
 			Content: reasoningPrompt,
 		}),
-		Tools{reasoningTool()}, "reasoning", o.maxRetries, o.streamCallback)
+		Tools{reasoningTool()}, "reasoning", o.maxRetries, o.streamCallback, o.logger, o.prompts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reasoning: %w", err)
 	}
@@ -694,7 +940,7 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 		reasoning = reasoningResponse.Reasoning
 	}
 
-	xlog.Debug("[pickTool] Got reasoning", "reasoning", reasoning)
+	o.logger.Debug("[pickTool] Got reasoning", "reasoning", reasoning)
 
 	// Step 2: Build tool names list for the intention tool
 	toolNames = []string{}
@@ -705,7 +951,7 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 	}
 
 	// Step 3: Force the LLM to pick tools using the appropriate intention tool
-	xlog.Debug(
+	o.logger.Debug(
 		"[pickTool] Forcing tool pick via intention tool",
 		"available_tools", toolNames,
 		"parallel", o.parallelToolExecution,
@@ -741,13 +987,13 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 
 	intentionResult, err := decisionWithStreaming(ctx, llm,
 		intentionMessages,
-		intentionTools, intentionToolName, o.maxRetries, o.streamCallback)
+		intentionTools, intentionToolName, o.maxRetries, o.streamCallback, o.logger, o.prompts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to pick tool via intention: %w", err)
 	}
 
 	if len(intentionResult.toolChoices) == 0 {
-		xlog.Debug("[pickTool] No tool picked from intention")
+		o.logger.Debug("[pickTool] No tool picked from intention")
 		return &decisionResult{message: intentionResult.message, reasoning: reasoning}, nil
 	}
 
@@ -771,24 +1017,29 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 		if intentionReasoning == "" {
 			intentionReasoning = intentionResponse.Reasoning
 		}
+		confidence := intentionResponse.Confidence
+		if confidence == nil {
+			confidence = intentionResult.confidence
+		}
 
 		for _, toolName := range intentionResponse.Tools {
-			if o.sinkState && toolName == o.sinkStateTool.Tool().Function.Name {
+			if o.isSinkState(toolName) {
 				hasSinkState = true
-				xlog.Debug("[pickTool] Sink state detected in multiple selection", "hasSinkState", hasSinkState)
+				o.logger.Debug("[pickTool] Sink state detected in multiple selection", "hasSinkState", hasSinkState)
 				continue
 			}
 
 			chosenTool := tools.Find(toolName)
 			if chosenTool == nil {
-				xlog.Debug("[pickTool] Chosen tool not found", "tool", toolName)
+				o.logger.Debug("[pickTool] Chosen tool not found", "tool", toolName)
 				continue
 			}
 
 			toolChoices = append(toolChoices, &ToolChoice{
-				Name:      toolName,
-				Arguments: make(map[string]any),
-				Reasoning: intentionReasoning,
+				Name:       toolName,
+				Arguments:  make(map[string]any),
+				Reasoning:  intentionReasoning,
+				Confidence: confidence,
 			})
 		}
 	} else {
@@ -804,27 +1055,33 @@ func pickTool(ctx context.Context, llm LLM, fragment Fragment, tools Tools, opts
 			intentionReasoning = intentionResponse.Reasoning
 		}
 
+		confidence := intentionResponse.Confidence
+		if confidence == nil {
+			confidence = intentionResult.confidence
+		}
+
 		if intentionResponse.Tool == "" {
-			xlog.Debug("[pickTool] No tool selected")
+			o.logger.Debug("[pickTool] No tool selected")
 			return nil, fmt.Errorf("no tool selected")
 		}
 
 		chosenTool := tools.Find(intentionResponse.Tool)
 		if chosenTool == nil {
-			xlog.Debug("[pickTool] Chosen tool not found", "tool", intentionResponse.Tool)
+			o.logger.Debug("[pickTool] Chosen tool not found", "tool", intentionResponse.Tool)
 			return nil, fmt.Errorf("chosen tool not found")
 		}
 
 		toolChoices = append(toolChoices, &ToolChoice{
-			Name:      intentionResponse.Tool,
-			Arguments: make(map[string]any),
-			Reasoning: intentionReasoning,
+			Name:       intentionResponse.Tool,
+			Arguments:  make(map[string]any),
+			Reasoning:  intentionReasoning,
+			Confidence: confidence,
 		})
 	}
 
-	xlog.Debug("[pickTool] Tools selected via intention", "count", len(toolChoices), "hasSinkState", hasSinkState)
+	o.logger.Debug("[pickTool] Tools selected via intention", "count", len(toolChoices), "hasSinkState", hasSinkState)
 	if hasSinkState {
-		xlog.Debug("[pickTool] Sink state found, returning tools to execute first", "tool_count", len(toolChoices))
+		o.logger.Debug("[pickTool] Sink state found, returning tools to execute first", "tool_count", len(toolChoices))
 	}
 
 	// Return the tool choices without parameters - they'll be generated separately
@@ -846,14 +1103,14 @@ func decideToPlan(llm LLM, f Fragment, tools Tools, opts ...Option) (bool, error
 		}
 	}
 
-	xlog.Debug("definitions", "tools", tools.Definitions())
+	o.logger.Debug("definitions", "tools", tools.Definitions())
 	prompt, err := prompter.Render(
 		struct {
 			Context           string
 			Tools             []*openai.FunctionDefinition
 			AdditionalContext string
 		}{
-			Context:           f.String(),
+			Context:           conversationContext(o, prompt.PromptPlanDecisionType, f),
 			Tools:             tools.Definitions(),
 			AdditionalContext: additionalContext,
 		},
@@ -862,7 +1119,7 @@ func decideToPlan(llm LLM, f Fragment, tools Tools, opts ...Option) (bool, error
 		return false, fmt.Errorf("failed to render content improver prompt: %w", err)
 	}
 
-	planDecision, err := llm.Ask(o.context, NewEmptyFragment().AddMessage("user", prompt))
+	planDecision, err := llm.Ask(o.context, NewEmptyFragment().AddMessage("user", prompt, f.Multimedia...))
 	if err != nil {
 		return false, fmt.Errorf("failed to ask LLM for plan decision: %w", err)
 	}
@@ -876,23 +1133,26 @@ func decideToPlan(llm LLM, f Fragment, tools Tools, opts ...Option) (bool, error
 }
 
 func doPlan(llm LLM, f Fragment, tools Tools, opts ...Option) (Fragment, bool, error) {
+	o := defaultOptions()
+	o.Apply(opts...)
+
 	planDecision, err := decideToPlan(llm, f, tools, opts...)
 	if err != nil {
 		return f, false, fmt.Errorf("failed to decide if planning is needed: %w", err)
 	}
 	if planDecision {
-		xlog.Debug("Planning is needed")
+		o.logger.Debug("Planning is needed")
 		goal, err := ExtractGoal(llm, f, opts...)
 		if err != nil {
 			return f, false, fmt.Errorf("failed to extract goal: %w", err)
 		}
-		xlog.Debug("Extracted goal from Plan", "goal", goal.Goal)
+		o.logger.Debug("Extracted goal from Plan", "goal", goal.Goal)
 		plan, err := ExtractPlan(llm, f, goal, opts...)
 		if err != nil {
 			return f, false, fmt.Errorf("failed to extract plan: %w", err)
 		}
-		xlog.Debug("Extracted plan subtasks", "goal", goal.Goal, "subtasks", plan.Subtasks)
-		xlog.Debug("Plan description", "description", plan.Description)
+		o.logger.Debug("Extracted plan subtasks", "goal", goal.Goal, "subtasks", plan.Subtasks)
+		o.logger.Debug("Plan description", "description", plan.Description)
 
 		// opts without autoplan disabled
 		f, err = ExecutePlan(llm, f, plan, goal, append(opts, func(o *Options) { o.autoPlan = false })...)
@@ -908,10 +1168,11 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 	o := defaultOptions()
 	o.Apply(opts...)
 
-	xlog.Debug("[toolSelection] Starting tool selection", "tools_count", len(tools), "forceReasoning", o.forceReasoning)
+	o.logger.Debug("[toolSelection] Starting tool selection", "tools_count", len(tools), "forceReasoning", o.forceReasoning)
 
-	// Build the conversation for tool selection
-	messages := slices.Clone(f.Messages)
+	// Build the conversation for tool selection, showing the attachments of
+	// the fragment to vision models
+	messages := messagesWithMultimedia(promptFragment(o, f))
 
 	// Add guidelines to the conversation if available
 	if len(guidelines) > 0 {
@@ -933,6 +1194,15 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 		}, messages...)
 	}
 
+	// Add lessons learned from previous failed iterations
+	if f.Status != nil && len(f.Status.Reflections) > 0 {
+		reflections, err := reflectionsMessage(o, f.Status.Reflections)
+		if err != nil {
+			return f, nil, false, "", err
+		}
+		messages = append([]openai.ChatCompletionMessage{reflections}, messages...)
+	}
+
 	// Add additional prompts if provided
 	if len(toolPrompts) > 0 {
 		// Prepend additional prompts to conversation
@@ -943,18 +1213,25 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 		messages = o.messagesManipulator(messages)
 	}
 
+	spec := startSpeculation(o, llm, tools, guidelines, messages)
+	defer spec.discard(o)
+
 	if o.sinkState {
-		xlog.Debug("[toolSelection] Sink state enabled, adding to the available tools", "sink", o.sinkStateTool.Tool().Function.Name)
-		tools = append(tools, o.sinkStateTool)
+		o.logger.Debug("[toolSelection] Sink state enabled, adding to the available tools", "sink", o.sinkStates().Names())
+		tools = append(tools, o.sinkStates()...)
 		for _, t := range tools {
-			xlog.Debug("[toolSelection] tool=", "tool", t.Tool().Function.Name)
+			o.logger.Debug("[toolSelection] tool=", "tool", t.Tool().Function.Name)
 
 		}
 
 	}
 
 	// Use the enhanced pickTool function
-	results, err := pickTool(o.context, llm, Fragment{Messages: messages}, tools, opts...)
+	ctx := o.context
+	if o.minToolConfidence > 0 {
+		ctx = withLogprobs(ctx)
+	}
+	results, err := pickTool(ctx, llm, Fragment{Messages: messages}, tools, opts...)
 	if err != nil {
 		return f, nil, false, "", fmt.Errorf("failed to pick tool: %w", err)
 	}
@@ -968,27 +1245,45 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 			// When sink state is enabled and the LLM replied with text instead of
 			// calling a tool, treat it as equivalent to calling the sink state
 			// (the LLM chose to reply rather than use a tool).
-			xlog.Debug("[toolSelection] No tool selected but LLM replied (sink state equivalent)", "message", results.message)
+			o.logger.Debug("[toolSelection] No tool selected but LLM replied (sink state equivalent)", "message", results.message)
 			o.reasoningCallback(reasoning)
+			recordReasoning(o, f, ReasoningReply, reasoning)
 			return f, nil, true, results.message, nil
 		}
 
 		// No tool was selected, reasoning contains the response
-		xlog.Debug("[toolSelection] No tool selected", "reasoning", reasoning)
+		o.logger.Debug("[toolSelection] No tool selected", "reasoning", reasoning)
 		o.statusCallback(reasoning)
 		o.reasoningCallback(reasoning)
+		recordReasoning(o, f, ReasoningReply, reasoning)
 		return f, nil, true, results.message, nil
 	}
 
 	if reasoning != "" {
 		o.reasoningCallback(reasoning)
+		names := make([]string, len(selectedTools))
+		for i, t := range selectedTools {
+			names[i] = t.Name
+		}
+		recordReasoning(o, f, ReasoningToolSelection, reasoning, names...)
 	}
 
 	for _, t := range selectedTools {
-		xlog.Debug("[toolSelection] Tool selected", "name", t.Name)
+		if t.Confidence != nil {
+			o.logger.Debug("[toolSelection] Tool selected", "name", t.Name, "confidence", *t.Confidence)
+		} else {
+			o.logger.Debug("[toolSelection] Tool selected", "name", t.Name)
+		}
 	}
 
-	xlog.Debug("[toolSelection] Tools selected", "count", len(selectedTools), "reasoning", reasoning)
+	if low := lowConfidenceTools(o, selectedTools); len(low) > 0 {
+		o.logger.Info("[toolSelection] Tool selection confidence below threshold, asking for clarification",
+			"tools", low, "min_confidence", o.minToolConfidence)
+		f.Status.LastUsage = results.usage
+		return f, nil, true, clarificationQuestion(o, llm, messages, selectedTools, reasoning), nil
+	}
+
+	o.logger.Debug("[toolSelection] Tools selected", "count", len(selectedTools), "reasoning", reasoning)
 	o.statusCallback(fmt.Sprintf("Selected %d tool(s)", len(selectedTools)))
 
 	// Track reasoning in fragment
@@ -998,7 +1293,7 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 
 	// Process each selected tool
 	var toolCalls []openai.ToolCall
-	for _, selectedTool := range selectedTools {
+	for i, selectedTool := range selectedTools {
 
 		// Check if we need to generate or refine parameters
 		selectedToolObj := tools.Find(selectedTool.Name)
@@ -1008,12 +1303,15 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 
 		// If force reasoning is enabled and we got incomplete parameters, regenerate them
 		toolFunc := selectedToolObj.Tool().Function
-		if o.forceReasoning && toolFunc != nil && toolFunc.Parameters != nil {
-			xlog.Debug("[toolSelection] Regenerating parameters with reasoning", "tool", selectedTool.Name)
-
-			enhancedChoice, err := generateToolParameters(o, llm, selectedToolObj, messages, reasoning)
+		if o.forceReasoning && !results.argumentsComplete && toolFunc != nil && toolFunc.Parameters != nil {
+			enhancedChoice, ok := spec.result(o, selectedTool.Name)
+			var err error
+			if !ok {
+				o.logger.Debug("[toolSelection] Regenerating parameters with reasoning", "tool", selectedTool.Name)
+				enhancedChoice, err = generateToolParameters(o, llm, selectedToolObj, messages, reasoning)
+			}
 			if err != nil {
-				xlog.Warn("[toolSelection] Failed to regenerate parameters, using original", "error", err, "tool", selectedTool.Name)
+				o.logger.Warn("[toolSelection] Failed to regenerate parameters, using original", "error", err, "tool", selectedTool.Name)
 			} else {
 				selectedTool.Name = enhancedChoice.Name
 				selectedTool.Arguments = enhancedChoice.Arguments
@@ -1021,8 +1319,12 @@ func toolSelection(llm LLM, f Fragment, tools Tools, guidelines Guidelines, tool
 			}
 		}
 
+		if selectedTool.Reasoning == "" {
+			selectedTool.Reasoning = reasoning
+		}
+
 		// Generate ID for the tool call before creating the message
-		toolCallID := uuid.New().String()
+		toolCallID := toolCallID(o, f, i)
 		selectedTool.ID = toolCallID
 
 		toolCalls = append(toolCalls, openai.ToolCall{
@@ -1061,7 +1363,29 @@ func (s *SessionState) Resume(llm LLM, opts ...Option) (Fragment, error) {
 // askWithStreaming calls llm.Ask() but uses streaming when available and a stream callback is set.
 // It type-asserts the LLM to StreamingLLM, streams events via the callback, and accumulates
 // the full response into a Fragment identical to what Ask() would return.
-func askWithStreaming(ctx context.Context, llm LLM, f Fragment, streamCB StreamCallback) (Fragment, error) {
+// The <think> blocks of reasoning models are moved out of the reply content.
+func askWithStreaming(ctx context.Context, llm LLM, f Fragment, streamCB StreamCallback, logger Logger) (Fragment, error) {
+	result, err := askStreaming(ctx, llm, f, streamCB, logger)
+	if err != nil {
+		return result, err
+	}
+	// LLM clients rebuild the fragment from its messages
+	if result.Multimedia == nil {
+		result.Multimedia = f.Multimedia
+	}
+	if len(result.Messages) <= len(f.Messages) {
+		return result, nil
+	}
+	if result.Metadata == nil {
+		result.Metadata = f.Metadata
+	}
+	if msg := result.LastMessage(); msg.Role == AssistantMessageRole.String() {
+		separateThinking(msg, "")
+	}
+	return result, nil
+}
+
+func askStreaming(ctx context.Context, llm LLM, f Fragment, streamCB StreamCallback, logger Logger) (Fragment, error) {
 	sllm, isStreaming := llm.(StreamingLLM)
 	if !isStreaming || streamCB == nil {
 		return llm.Ask(ctx, f)
@@ -1073,7 +1397,7 @@ func askWithStreaming(ctx context.Context, llm LLM, f Fragment, streamCB StreamC
 	})
 	if err != nil {
 		// Fall back to non-streaming on error
-		xlog.Warn("Streaming failed, falling back to non-streaming", "error", err)
+		logger.Warn("Streaming failed, falling back to non-streaming", "error", err)
 		return llm.Ask(ctx, f)
 	}
 
@@ -1146,9 +1470,37 @@ func askWithStreaming(ctx context.Context, llm LLM, f Fragment, streamCB StreamC
 func ExecuteTools(llm LLM, f Fragment, opts ...Option) (result Fragment, retErr error) {
 	o := defaultOptions()
 	o.Apply(opts...)
+	llm = withLLMOptions(llm, o)
+
+	// Plans and sub-agents run nested ExecuteTools with validated options,
+	// only the outer run notifies its start and end
+	outerRun := !o.validated
+	if !o.validated {
+		if err := o.Validate(); err != nil {
+			return f, err
+		}
+		opts = withResolvedTenant(o, append(slices.Clone(opts), optionsValidated))
+		o.validated = true
+		if err := startQuotaRun(o); err != nil {
+			return f, err
+		}
+	}
+
+	opts, stopMCPServers, err := startMCPServers(o, opts)
+	if err != nil {
+		return f, err
+	}
+	defer stopMCPServers()
 
-	if !o.sinkState && o.forceReasoning {
-		return f, fmt.Errorf("force reasoning is enabled but sink state is not enabled")
+	if o.toolCacheEnabled && o.toolCache == nil {
+		o.toolCache = newToolResultCache(o.toolCacheTools)
+	}
+	if o.compensation && o.compensations == nil {
+		o.compensations = &compensationLog{}
+	}
+	var transactions *transactionLog
+	if len(o.transactions) > 0 {
+		transactions = newTransactionLog(o.transactions)
 	}
 
 	// Inject sub-agent tools if agent spawning is enabled
@@ -1183,8 +1535,86 @@ func ExecuteTools(llm LLM, f Fragment, opts ...Option) (result Fragment, retErr
 		if o.toolCallCallback != nil {
 			subAgentOpts = append(subAgentOpts, WithToolCallBack(o.toolCallCallback))
 		}
-		if len(o.mcpSessions) > 0 {
-			subAgentOpts = append(subAgentOpts, WithMCPs(o.mcpSessions...))
+		subAgentOpts = append(subAgentOpts, mcpOptions(o)...)
+		if o.toolCollisionPolicy != PreferLocalTools {
+			subAgentOpts = append(subAgentOpts, WithToolCollisionPolicy(o.toolCollisionPolicy))
+		}
+		if len(o.prompts) > 0 {
+			subAgentOpts = append(subAgentOpts, WithPrompts(o.prompts))
+		}
+		if len(o.promptVars) > 0 {
+			subAgentOpts = append(subAgentOpts, WithPromptVars(o.promptVars))
+		}
+		if o.locale != "" {
+			subAgentOpts = append(subAgentOpts, WithLocale(o.locale))
+		}
+		subAgentOpts = append(subAgentOpts, WithLogger(o.logger))
+		if o.secrets != nil {
+			subAgentOpts = append(subAgentOpts, WithSecrets(o.secrets))
+		}
+		if o.toolCorrectionRounds > 0 {
+			subAgentOpts = append(subAgentOpts, WithToolCorrection(o.toolCorrectionRounds))
+		}
+		// Sub-agents must not send what the parent redacts
+		if o.redactor != nil {
+			subAgentOpts = append(subAgentOpts, WithRedactor(o.redactor))
+		}
+		if o.rateLimiter != nil {
+			subAgentOpts = append(subAgentOpts, WithRateLimiter(o.rateLimiter))
+		}
+		if o.toolRateLimiter != nil {
+			subAgentOpts = append(subAgentOpts, WithToolRateLimiter(o.toolRateLimiter))
+		}
+		if o.completionCache != nil {
+			subAgentOpts = append(subAgentOpts, WithCompletionCache(o.completionCache))
+		}
+		if o.reasoningSink != nil {
+			subAgentOpts = append(subAgentOpts, WithReasoningSink(o.reasoningSink))
+		}
+		if o.idempotency != nil {
+			subAgentOpts = append(subAgentOpts, WithIdempotencyStore(o.idempotency.store, o.idempotency.tools...))
+		}
+		if o.compensations != nil {
+			log := o.compensations
+			subAgentOpts = append(subAgentOpts, EnableCompensation, func(o *Options) { o.compensations = log })
+		}
+		for _, tools := range o.transactions {
+			subAgentOpts = append(subAgentOpts, WithToolTransaction(tools...))
+		}
+		if o.policy != nil {
+			subAgentOpts = append(subAgentOpts, WithPolicy(o.policy))
+		}
+		if o.identity != nil {
+			subAgentOpts = append(subAgentOpts, WithIdentity(*o.identity))
+		}
+		if o.quotas != nil {
+			subAgentOpts = append(subAgentOpts, WithQuota(o.quotas.store, o.quotas.quotas...))
+		}
+		if o.seed != nil {
+			subAgentOpts = append(subAgentOpts, WithSeed(*o.seed))
+		}
+		if !o.toolAPIFallback {
+			subAgentOpts = append(subAgentOpts, DisableToolAPIFallback)
+		}
+		subAgentOpts = append(subAgentOpts, WithMaxContinuations(o.maxContinuations))
+		if o.adaptiveMaxTokens > 0 {
+			subAgentOpts = append(subAgentOpts, WithAdaptiveMaxTokens(o.adaptiveMaxTokens))
+		}
+		if o.reasoningLogLimit > 0 {
+			subAgentOpts = append(subAgentOpts, WithReasoningLogLimit(o.reasoningLogLimit))
+		}
+		if o.datasetRecorder != nil {
+			recorder := o.datasetRecorder
+			subAgentOpts = append(subAgentOpts, func(o *Options) { o.datasetRecorder = recorder })
+		}
+		if o.vars != nil {
+			subAgentOpts = append(subAgentOpts, WithVarStore(o.vars))
+		}
+		if o.validated {
+			subAgentOpts = append(subAgentOpts, optionsValidated)
+		}
+		if o.toolRegistry != nil {
+			subAgentOpts = append(subAgentOpts, WithToolRegistry(o.toolRegistry))
 		}
 
 		agentTools := []ToolDefinitionInterface{
@@ -1211,16 +1641,67 @@ func ExecuteTools(llm LLM, f Fragment, opts ...Option) (result Fragment, retErr
 	// callbacks) can report cumulative usage. The sub-agent fallback LLM
 	// (agentLLM, captured above) stays unwrapped so its usage is not folded in.
 	runUsage := &usageCounter{}
+	// Registered first, so the final step sees the result as returned
+	audit := newAuditRecorder(o.auditTrail, f, runUsage)
+	defer func() { audit.record("final", result) }()
+	var degradations *degradationLog
+	if o.contextShrinking || o.promptSizeLimit > 0 {
+		degradations = &degradationLog{}
+	}
+	if o.textToolCalls {
+		llm = newTextToolsLLM(llm, o.prompts, o.logger)
+	} else if o.toolAPIFallback {
+		llm = newToolFallbackLLM(llm, o.prompts, o.logger)
+	}
+	if o.contextShrinking {
+		llm = newShrinkingLLM(llm, degradations, o.prompts, runUsage, o.logger)
+	}
+	if o.promptSizeLimit > 0 {
+		llm = newPromptGuardLLM(llm, o.promptSizeLimit, o.promptSizeCallback, degradations, o.prompts, runUsage, o.logger)
+	}
 	llm = newCountingLLM(llm, runUsage)
+	if outerRun {
+		notifyWebhooks(o, WebhookPayload{Event: WebhookRunStarted, Text: "Run started"})
+	}
 	defer func() {
+		answered := retErr == nil || errors.Is(retErr, ErrNoToolSelected) || errors.Is(retErr, ErrDirectResponse)
+		if o.factCheck && answered {
+			result = factCheckAnswer(o, llm, result)
+		}
+		if o.localeFormatting && o.locale != "" && answered {
+			result = localizeAnswer(result, o.locale)
+		}
+		if outerRun && !answered && o.compensations != nil && result.Status != nil {
+			result.Status.Compensations = append(result.Status.Compensations, o.compensations.compensate(o)...)
+		}
 		if result.Status != nil {
+			trimReasoningLog(o, result.Status)
 			result.Status.CumulativeUsage = runUsage.snapshot()
+			if degradations != nil {
+				result.Status.ContextDegradations = append(result.Status.ContextDegradations, degradations.snapshot()...)
+			}
+			if reason := toolAPIFallback(llm); reason != "" {
+				result.Status.ToolAPIFallback = reason
+			}
+		}
+		if outerRun {
+			notifyRunEnd(o, result, retErr, answered, runUsage.snapshot())
 		}
 	}()
 
+	// Stateful tools are initialized on first use and closed when the run ends
+	o.toolLifecycle = newToolLifecycle()
+	defer o.toolLifecycle.close(o.logger)
+
+	f = expireStaleToolResults(f, o, time.Now())
+
+	// Approaches that worked on similar tasks in previous runs
+	intent := taskIntent(f)
+	experience := successfulApproachesMessage(o, intent)
+
 	// should I plan?
 	if o.autoPlan {
-		xlog.Debug("Checking if planning is needed")
+		o.logger.Debug("Checking if planning is needed")
 		tools, _, _, err := usableTools(llm, f, opts...)
 		if err != nil {
 			return f, fmt.Errorf("failed to get relevant guidelines: %w", err)
@@ -1232,12 +1713,12 @@ func ExecuteTools(llm LLM, f Fragment, opts ...Option) (result Fragment, retErr
 			return f, fmt.Errorf("failed to execute planning: %w", err)
 		}
 		if executedPlan {
-			xlog.Debug("Plan was executed")
+			o.logger.Debug("Plan was executed")
 		} else {
-			xlog.Debug("Planning is not needed")
+			o.logger.Debug("Planning is not needed")
 		}
 		if len(f.Status.ToolsCalled) == 0 {
-			xlog.Debug("No tools called via planning, continuing with tool selection")
+			o.logger.Debug("No tools called via planning, continuing with tool selection")
 		} else {
 			return f, nil
 		}
@@ -1261,23 +1742,27 @@ func ExecuteTools(llm LLM, f Fragment, opts ...Option) (result Fragment, retErr
 	}
 
 	var hasSinkState bool
+	var sinkChoice *ToolChoice
 
 TOOL_LOOP:
 	for {
+		trimReasoningLog(o, f.Status)
+		audit.record("iteration", f)
+
 		// Check context cancellation and handle message injection via select
 		select {
 		case <-o.context.Done():
-			xlog.Warn("ExecuteTools context cancelled")
+			o.logger.Warn("ExecuteTools context cancelled")
 			return f, o.context.Err()
 		case msg, ok := <-o.messageInjectionChan:
 			if !ok {
 				// Channel closed, continue normal loop
-				xlog.Debug("Message injection channel closed")
+				o.logger.Debug("Message injection channel closed")
 			} else {
 				// Inject the message at current position
 				position := len(f.Messages)
 				f = f.AddMessage(MessageRole(msg.Role), msg.Content)
-				xlog.Debug("Injected message at position", "position", position, "role", msg.Role)
+				o.logger.Debug("Injected message at position", "position", position, "role", msg.Role)
 
 				// Send result feedback
 				if o.messageInjectionResultChan != nil {
@@ -1285,7 +1770,7 @@ TOOL_LOOP:
 					case o.messageInjectionResultChan <- MessageInjectionResult{Count: 1, Position: position}:
 					default:
 						// Non-blocking send, drop if channel is full
-						xlog.Debug("Could not send injection result feedback (channel full or nil)")
+						o.logger.Debug("Could not send injection result feedback (channel full or nil)")
 					}
 				}
 
@@ -1304,7 +1789,7 @@ TOOL_LOOP:
 		// Check total iterations to prevent infinite loops
 		// This is the absolute limit across all tool executions including re-evaluations
 		if totalIterations >= o.maxIterations {
-			xlog.Warn("Max total iterations reached, stopping execution",
+			o.logger.Warn("Max total iterations reached, stopping execution",
 				"totalIterations", totalIterations, "maxIterations", o.maxIterations)
 			if o.statusCallback != nil {
 				o.statusCallback("Max total iterations reached, stopping execution")
@@ -1314,12 +1799,12 @@ TOOL_LOOP:
 			if o.compactionThreshold > 0 {
 				var compacted bool
 				var compactErr error
-				f, compacted, compactErr = checkAndCompact(o.context, llm, f, o.compactionThreshold, o.compactionKeepMessages, o.prompts)
+				f, compacted, compactErr = checkAndCompact(o.context, llm, f, o.compactionThreshold, o.compactionKeepMessages, o.prompts, o.logger)
 				if compactErr != nil {
 					return f, fmt.Errorf("failed to compact: %w", compactErr)
 				}
 				if compacted {
-					xlog.Debug("Fragment compacted before final response")
+					o.logger.Debug("Fragment compacted before final response")
 				}
 			}
 
@@ -1329,7 +1814,7 @@ TOOL_LOOP:
 
 			status := f.Status
 			parentBeforeAsk := f.ParentFragment
-			f, err := askWithStreaming(o.context, llm, f, o.streamCallback)
+			f, err := askFiltered(o, llm, f)
 			if err != nil {
 				return f, fmt.Errorf("failed to ask LLM: %w", err)
 			}
@@ -1338,9 +1823,13 @@ TOOL_LOOP:
 			f.Status.LastUsage = status.LastUsage
 			f.Status.Iterations = status.Iterations
 			f.Status.ReasoningLog = status.ReasoningLog
+			logFinalReasoning(o, f)
 			f.Status.TODOs = status.TODOs
 			f.Status.TODOIteration = status.TODOIteration
 			f.Status.TODOPhase = status.TODOPhase
+			f.Status.Reflections = status.Reflections
+			f.Status.ContextDegradations = status.ContextDegradations
+			f.Status.Extensions = status.Extensions
 			// Preserve original parent (LLM.Ask often sets response.ParentFragment to the request fragment)
 			if parentBeforeAsk != nil {
 				f.ParentFragment = parentBeforeAsk
@@ -1358,13 +1847,13 @@ TOOL_LOOP:
 
 		// Check and compact if token threshold exceeded (before running next tool loop iteration)
 		if o.compactionThreshold > 0 {
-			compactedF, compacted, compactErr := checkAndCompact(o.context, llm, f, o.compactionThreshold, o.compactionKeepMessages, o.prompts)
+			compactedF, compacted, compactErr := checkAndCompact(o.context, llm, f, o.compactionThreshold, o.compactionKeepMessages, o.prompts, o.logger)
 			if compactErr != nil {
 				return f, fmt.Errorf("failed to compact: %w", compactErr)
 			}
 			if compacted {
 				f = compactedF
-				xlog.Debug("Fragment compacted successfully before next tool loop iteration")
+				o.logger.Debug("Fragment compacted successfully before next tool loop iteration")
 			}
 		}
 
@@ -1373,6 +1862,33 @@ TOOL_LOOP:
 		if err != nil {
 			return f, fmt.Errorf("failed to get relevant guidelines: %w", err)
 		}
+		toolPrompts = append(toolPrompts, experience...)
+		tools, unavailable := o.toolLifecycle.healthy(tools)
+		if len(unavailable) > 0 {
+			unavailableMessage, err := unavailableToolsMessage(o, unavailable)
+			if err != nil {
+				return f, err
+			}
+			toolPrompts = append(toolPrompts, unavailableMessage)
+		}
+		if o.vars != nil {
+			varsPrompt, ok, err := varsMessage(o)
+			if err != nil {
+				return f, err
+			}
+			if ok {
+				toolPrompts = append(toolPrompts, varsPrompt)
+			}
+		}
+		if o.entityMemory != nil {
+			hints, ok, err := entityHintsMessage(o, f)
+			if err != nil {
+				return f, err
+			}
+			if ok {
+				toolPrompts = append(toolPrompts, hints)
+			}
+		}
 
 		var selectedToolFragment Fragment
 		var selectedToolResults []*ToolChoice
@@ -1381,11 +1897,11 @@ TOOL_LOOP:
 
 		// If ToolReEvaluator set a next action, use it directly
 		if len(startingActions) > 0 {
-			xlog.Debug("Starting with actions", "count", len(startingActions))
-			for _, t := range startingActions {
+			o.logger.Debug("Starting with actions", "count", len(startingActions))
+			for i, t := range startingActions {
 				selectedToolResults = append(selectedToolResults, t)
 				// Generate ID before creating the message
-				t.ID = uuid.New().String()
+				t.ID = toolCallID(o, f, i)
 			}
 			startingActions = []*ToolChoice{} // Clear it so we don't reuse it
 
@@ -1411,7 +1927,7 @@ TOOL_LOOP:
 
 			// check if I would need toplan?
 			if o.autoPlan && o.planReEvaluator {
-				xlog.Debug("Checking if planning is needed")
+				o.logger.Debug("Checking if planning is needed")
 				// Decide if planning is needed
 				var executedPlan bool
 				f, executedPlan, err = doPlan(llm, f, tools, opts...)
@@ -1419,10 +1935,10 @@ TOOL_LOOP:
 					return f, fmt.Errorf("failed to execute planning: %w", err)
 				}
 				if executedPlan {
-					xlog.Debug("Plan was executed")
+					o.logger.Debug("Plan was executed")
 					continue
 				} else {
-					xlog.Debug("Planning is not needed")
+					o.logger.Debug("Planning is not needed")
 				}
 			}
 
@@ -1434,13 +1950,16 @@ TOOL_LOOP:
 					// The LLM replied with text instead of calling a tool - this is
 					// equivalent to selecting the sink state (reply).
 					f = f.AddMessage(AssistantMessageRole, reasoning)
+					if o.sinkState {
+						enterSinkState(o, nil)
+					}
 				}
 				if o.statusCallback != nil && reasoning == "" {
 					o.statusCallback("No tool was selected")
 				}
 				// If background agents are still running, block until a completion message arrives
 				if (o.agentManager != nil && o.agentManager.HasRunning()) || (o.pendingWork != nil && o.pendingWork()) {
-					xlog.Debug("No tool selected but background agents still running, blocking for completions")
+					o.logger.Debug("No tool selected but background agents still running, blocking for completions")
 					if o.onPark != nil {
 						// reasoning holds the no-tool text reply recorded in the
 						// fragment above — the parked reply the embedder surfaces.
@@ -1456,7 +1975,7 @@ TOOL_LOOP:
 							}
 							position := len(f.Messages)
 							f = f.AddMessage(MessageRole(msg.Role), msg.Content)
-							xlog.Debug("Injected background completion message", "position", position)
+							o.logger.Debug("Injected background completion message", "position", position)
 							if o.messageInjectionResultChan != nil {
 								select {
 								case o.messageInjectionResultChan <- MessageInjectionResult{Count: 1, Position: position}:
@@ -1475,6 +1994,9 @@ TOOL_LOOP:
 				if o.autoImproveState != nil {
 					executeAutoImproveReview(llm, f, o.autoImproveState, o)
 				}
+				if o.captureDirectResponse && reasoning != "" {
+					return f, ErrDirectResponse
+				}
 				return f, nil
 			}
 			if err != nil {
@@ -1482,8 +2004,31 @@ TOOL_LOOP:
 			}
 		}
 
+		for _, choice := range selectedToolResults {
+			if w, ok := wrappedTool[*WaitTool](tools.Find(choice.Name)); ok {
+				s := w.suspend(f, choice)
+				o.logger.Info("Run suspended by the wait tool", "event", s.Event, "until", s.Until, "reason", s.Reason)
+				return f, s
+			}
+		}
+
+		if o.argumentClarification {
+			for _, choice := range selectedToolResults {
+				tool := tools.Find(choice.Name)
+				if tool == nil || o.isSinkState(choice.Name) {
+					continue
+				}
+				if c := checkArguments(o, llm, f.Messages, tool, choice); c != nil {
+					o.logger.Info("Tool arguments need clarification", "tool", c.Tool, "missing", c.MissingFields)
+					f = f.AddMessage(AssistantMessageRole, c.Question)
+					c.Fragment = f
+					return f, c
+				}
+			}
+		}
+
 		if len(selectedToolResults) == 0 {
-			xlog.Debug("No tool selected by the LLM")
+			o.logger.Debug("No tool selected by the LLM")
 			if o.statusCallback != nil {
 				o.statusCallback("No tool was selected by the LLM")
 			}
@@ -1507,8 +2052,9 @@ TOOL_LOOP:
 			if len(lastMsg.ToolCalls) > 0 {
 				for i, toolCall := range lastMsg.ToolCalls {
 					if i < len(selectedToolResults) {
-						if toolCall.ID == "" {
-							selectedToolResults[i].ID = uuid.New().String()
+						// Seeded runs replace the IDs of the backend too
+						if toolCall.ID == "" || o.seed != nil {
+							selectedToolResults[i].ID = toolCallID(o, f, i)
 							lastMsg.ToolCalls[i].ID = selectedToolResults[i].ID
 						} else {
 							selectedToolResults[i].ID = toolCall.ID
@@ -1520,34 +2066,39 @@ TOOL_LOOP:
 		}
 
 		// Generate IDs for any tools that still don't have one
-		for _, toolResult := range selectedToolResults {
+		for i, toolResult := range selectedToolResults {
 			if toolResult.ID == "" {
-				toolResult.ID = uuid.New().String()
+				toolResult.ID = toolCallID(o, f, i)
 			}
 		}
 
-		xlog.Debug("Picked tools with args", "count", len(selectedToolResults))
+		o.logger.Debug("Picked tools with args", "count", len(selectedToolResults))
 
 		// Check for sink state and separate tools
 		var toolsToExecute []*ToolChoice
-		sinkStateName := ""
-		if o.sinkState {
-			sinkStateName = o.sinkStateTool.Tool().Function.Name
-		}
-
 		for _, toolResult := range selectedToolResults {
-			if o.sinkState && toolResult.Name == sinkStateName {
+			if o.isSinkState(toolResult.Name) {
 				hasSinkState = true
-				xlog.Debug("Sink state detected, will stop after executing other tools", "tool", toolResult.Name)
+				sinkChoice = toolResult
+				o.logger.Debug("Sink state detected, will stop after executing other tools", "tool", toolResult.Name)
 			} else {
 				toolsToExecute = append(toolsToExecute, toolResult)
 			}
 		}
 
 		// Check for loop detection on all tools
+		loopDetector := loopDetectorFor(o)
 		for _, toolResult := range toolsToExecute {
-			if checkForLoop(f.Status.PastActions, toolResult, o.loopDetectionSteps) {
-				xlog.Warn("Loop detected, stopping execution", "tool", toolResult.Name)
+			if loopDetector != nil && loopDetector.IsLoop(contextWithLogger(o.context, o.logger), f.Status.PastActions, toolResult) {
+				if o.reflection {
+					o.logger.Warn("Loop detected, reflecting and skipping the repeated call", "tool", toolResult.Name)
+					note := reflectOnFailure(llm, f, fmt.Sprintf("The tool %q was called repeatedly with similar arguments (latest: %s) without making progress.",
+						toolResult.Name, string(mustMarshal(toolResult.Arguments))), o)
+					recordReflection(f.Status, note)
+					hasSinkState = false
+					continue TOOL_LOOP
+				}
+				o.logger.Warn("Loop detected, stopping execution", "tool", toolResult.Name)
 				return f, ErrLoopDetected
 			}
 		}
@@ -1556,7 +2107,7 @@ TOOL_LOOP:
 		if len(toolsToExecute) == 0 && hasSinkState {
 			// If background agents are still running, block until a completion message arrives
 			if (o.agentManager != nil && o.agentManager.HasRunning()) || (o.pendingWork != nil && o.pendingWork()) {
-				xlog.Debug("Sink state selected but background agents still running, blocking for completions")
+				o.logger.Debug("Sink state selected but background agents still running, blocking for completions")
 				hasSinkState = false // Reset so we re-enter the loop
 				if o.onPark != nil {
 					// Sink-state park: the reply is produced by the sink state
@@ -1573,7 +2124,7 @@ TOOL_LOOP:
 						}
 						position := len(f.Messages)
 						f = f.AddMessage(MessageRole(msg.Role), msg.Content)
-						xlog.Debug("Injected background completion message", "position", position)
+						o.logger.Debug("Injected background completion message", "position", position)
 						if o.messageInjectionResultChan != nil {
 							select {
 							case o.messageInjectionResultChan <- MessageInjectionResult{Count: 1, Position: position}:
@@ -1588,40 +2139,61 @@ TOOL_LOOP:
 				}
 				continue TOOL_LOOP
 			}
-			xlog.Debug("Only sink state selected, stopping execution")
+			o.logger.Debug("Only sink state selected, stopping execution")
 			break
 		}
 
 		// Process tool call callbacks for each tool
 		var finalToolsToExecute []*ToolChoice
 		var toolsToSkip []*ToolChoice
+		var toolsDenied []*ToolChoice
+		denials := map[*ToolChoice]string{}
 
 	reprocessCallbacks:
-		if o.toolCallCallback != nil {
+		if o.toolCallCallback != nil || o.policy != nil {
 			for _, toolResult := range toolsToExecute {
+				if o.policy != nil {
+					decision := toolCallPolicy(o, f, toolResult)
+					switch decision.Effect {
+					case PolicyAllow:
+						finalToolsToExecute = append(finalToolsToExecute, toolResult)
+						continue
+					case PolicyDeny:
+						toolsDenied = append(toolsDenied, toolResult)
+						denials[toolResult] = decision.Reason
+						continue
+					}
+				}
+
 				sessionState := &SessionState{
 					ToolChoice: toolResult,
 					Fragment:   f,
 				}
 
+				notifyWebhooks(o, WebhookPayload{
+					Event:     WebhookApprovalRequired,
+					Text:      "Approval required for tool " + toolResult.Name,
+					Tool:      toolResult.Name,
+					Arguments: toolResult.Arguments,
+				})
 				decision := o.toolCallCallback(toolResult, sessionState)
 				if !decision.Approved {
 					return f, ErrToolCallCallbackInterrupted
 				}
 
 				if decision.Skip {
-					xlog.Debug("Skipping tool call as requested by callback", "tool", toolResult.Name)
+					o.logger.Debug("Skipping tool call as requested by callback", "tool", toolResult.Name)
 					toolsToSkip = append(toolsToSkip, toolResult)
 					continue
 				}
 
 				if decision.Modified != nil {
-					xlog.Debug("Using directly modified tool choice", "tool", decision.Modified.Name)
+					o.logger.Debug("Using directly modified tool choice", "tool", decision.Modified.Name)
 					finalToolsToExecute = append(finalToolsToExecute, decision.Modified)
 				} else if decision.Adjustment != "" {
 					// For adjustments with multiple tools, re-run toolSelection with adjustment prompt
 					// This is a simplified approach - in the future we could adjust individual tools
-					xlog.Debug("Adjusting tool selection", "adjustment", decision.Adjustment)
+					o.logger.Debug("Adjusting tool selection", "adjustment", decision.Adjustment)
 
 					adjustmentPrompt := fmt.Sprintf(
 						`The user reviewed the proposed tool calls and provided feedback.
@@ -1653,20 +2225,19 @@ Please provide revised tool call based on this feedback.`,
 						Content: adjustmentPrompt,
 					}), opts...)
 					if noTool {
-						xlog.Debug("No tool selected after adjustment, stopping")
+						o.logger.Debug("No tool selected after adjustment, stopping")
 						hasSinkState = true
 						break TOOL_LOOP
 					}
 					if err != nil {
 						return f, fmt.Errorf("failed to adjust tool selection: %w", err)
 					}
-					if o.sinkState {
-						for _, t := range adjustedTools {
-							if t.Name == o.sinkStateTool.Tool().Function.Name {
-								xlog.Debug("No tool selected after adjustment, stopping")
-								hasSinkState = true
-								break TOOL_LOOP
-							}
+					for _, t := range adjustedTools {
+						if o.isSinkState(t.Name) {
+							o.logger.Debug("No tool selected after adjustment, stopping")
+							hasSinkState = true
+							sinkChoice = t
+							break TOOL_LOOP
 						}
 					}
 					// Process adjusted tools through callbacks again
@@ -1677,6 +2248,7 @@ Please provide revised tool call based on this feedback.`,
 					selectedToolResults = adjustedTools
 					// Reset finalToolsToExecute to reprocess all tools
 					finalToolsToExecute = []*ToolChoice{}
+					toolsDenied = nil
 					// Re-process callbacks for adjusted tools
 					goto reprocessCallbacks
 				} else {
@@ -1695,11 +2267,20 @@ Please provide revised tool call based on this feedback.`,
 		// Update fragment with the message (ID should already be set in ToolCall)
 		f = f.AddLastMessage(selectedToolFragment)
 		f.Status.LastUsage = selectedToolFragment.Status.LastUsage
+		for _, denied := range toolsDenied {
+			f = f.AddToolMessage(policyDenialMessage(denials[denied]), denied.ID)
+		}
+
+		if o.quotas != nil {
+			if err := o.quotas.check(o, QuotaUsage{ToolCalls: len(finalToolsToExecute)}); err != nil {
+				return f, err
+			}
+		}
 
 		// Check context before executing tools
 		select {
 		case <-o.context.Done():
-			xlog.Warn("ExecuteTools context cancelled before tool execution")
+			o.logger.Warn("ExecuteTools context cancelled before tool execution")
 			return f, o.context.Err()
 		default:
 		}
@@ -1716,7 +2297,7 @@ Please provide revised tool call based on this feedback.`,
 
 		if o.parallelToolExecution && len(finalToolsToExecute) > 1 {
 			// Parallel execution
-			xlog.Debug("Executing tools in parallel", "count", len(finalToolsToExecute))
+			o.logger.Debug("Executing tools in parallel", "count", len(finalToolsToExecute))
 			resultChan := make(chan toolExecutionResult, len(finalToolsToExecute))
 
 			for _, toolChoice := range finalToolsToExecute {
@@ -1733,31 +2314,51 @@ Please provide revised tool call based on this feedback.`,
 
 					attempts := 1
 					var result string
+					var resultData any
+					var followUps []ToolFollowUp
 					var execErr error
 				RETRY:
 					for range o.maxAttempts {
-						result, _, execErr = toolResult.Execute(tc.Arguments)
+						result, resultData, execErr = runTool(o, toolResult, ToolCallInfo{Choice: *tc, Iteration: totalIterations, Attempt: attempts})
+						if execErr == nil {
+							result, resultData, followUps, execErr = resolveToolFollowUps(llm, f, toolResult, tc, result, resultData, o)
+						}
+						if execErr == nil {
+							_, execErr = toolOutput(toolResult, result, resultData)
+						}
 						if execErr != nil {
 							if attempts >= o.maxAttempts {
 								result = fmt.Sprintf("Error running tool: %v", execErr)
-								xlog.Warn("Tool execution failed after all attempts", "tool", tc.Name, "error", execErr)
+								o.logger.Warn("Tool execution failed after all attempts", "tool", tc.Name, "error", execErr)
 								break RETRY
 							}
-							xlog.Warn("Tool execution failed, retrying", "tool", tc.Name, "attempt", attempts, "error", execErr)
+							o.logger.Warn("Tool execution failed, retrying", "tool", tc.Name, "attempt", attempts, "error", execErr)
 							attempts++
 						} else {
 							break RETRY
 						}
 					}
 
+					var corrections []ToolCorrection
+					if execErr != nil && o.toolCorrectionRounds > 0 {
+						result, resultData, followUps, corrections, execErr = correctFailedToolCall(llm, f, tools, tc, execErr, totalIterations, o)
+						if execErr != nil {
+							result = fmt.Sprintf("Error running tool: %v", execErr)
+						}
+					}
+
 					resultChan <- toolExecutionResult{
 						toolChoice: tc,
 						result:     result,
 						status: ToolStatus{
 							Result:        result,
+							ResultData:    resultData,
 							Executed:      true,
 							ToolArguments: *tc,
 							Name:          tc.Name,
+							FollowUps:     followUps,
+							ExecutedAt:    time.Now(),
+							Corrections:   corrections,
 						},
 						err: execErr,
 					}
@@ -1779,22 +2380,37 @@ Please provide revised tool call based on this feedback.`,
 				attempts := 1
 				var result string
 				var resultData any
+				var followUps []ToolFollowUp
 			RETRY:
 				for range o.maxAttempts {
-					result, resultData, err = toolResult.Execute(toolChoice.Arguments)
+					result, resultData, err = runTool(o, toolResult, ToolCallInfo{Choice: *toolChoice, Iteration: totalIterations, Attempt: attempts})
+					if err == nil {
+						result, resultData, followUps, err = resolveToolFollowUps(llm, f, toolResult, toolChoice, result, resultData, o)
+					}
+					if err == nil {
+						_, err = toolOutput(toolResult, result, resultData)
+					}
 					if err != nil {
 						if attempts >= o.maxAttempts {
 							result = fmt.Sprintf("Error running tool: %v", err)
-							xlog.Warn("Tool execution failed after all attempts", "tool", toolChoice.Name, "error", err)
+							o.logger.Warn("Tool execution failed after all attempts", "tool", toolChoice.Name, "error", err)
 							break RETRY
 						}
-						xlog.Warn("Tool execution failed, retrying", "tool", toolChoice.Name, "attempt", attempts, "error", err)
+						o.logger.Warn("Tool execution failed, retrying", "tool", toolChoice.Name, "attempt", attempts, "error", err)
 						attempts++
 					} else {
 						break RETRY
 					}
 				}
 
+				var corrections []ToolCorrection
+				if err != nil && o.toolCorrectionRounds > 0 {
+					result, resultData, followUps, corrections, err = correctFailedToolCall(llm, f, tools, toolChoice, err, totalIterations, o)
+					if err != nil {
+						result = fmt.Sprintf("Error running tool: %v", err)
+					}
+				}
+
 				executionResults = append(executionResults, toolExecutionResult{
 					toolChoice: toolChoice,
 					result:     result,
@@ -1804,19 +2420,72 @@ Please provide revised tool call based on this feedback.`,
 						Executed:      true,
 						ToolArguments: *toolChoice,
 						Name:          toolChoice.Name,
+						FollowUps:     followUps,
+						ExecutedAt:    time.Now(),
+						Corrections:   corrections,
 					},
 					err: err,
 				})
 			}
 		}
 
+		// Roll back the transactions with a failed call
+		var transactionErr error
+		if transactions != nil {
+			calls := make([]transactionCall, len(executionResults))
+			for i, execResult := range executionResults {
+				calls[i] = transactionCall{choice: execResult.toolChoice, result: execResult.result, err: execResult.err}
+			}
+			var rolledBack map[int]error
+			var compensations []CompensationStatus
+			rolledBack, compensations, transactionErr = transactions.settle(o, tools, calls)
+			f.Status.Compensations = append(f.Status.Compensations, compensations...)
+			for i, err := range rolledBack {
+				executionResults[i].err = err
+				executionResults[i].result = fmt.Sprintf("%s\n\nThis call was %v", executionResults[i].result, err)
+				executionResults[i].status.Result = executionResults[i].result
+			}
+		}
+
 		// Process execution results
+		// Images from rich tool results are shown in user messages after all
+		// tool messages, since tool messages must directly follow their calls.
+		type toolImagesMessage struct {
+			tool   string
+			images []Multimedia
+		}
+		var toolImages []toolImagesMessage
 		for _, execResult := range executionResults {
+			richResult, isRichResult := asToolResult(execResult.status.ResultData)
+			if isRichResult && execResult.status.Result == "" {
+				execResult.status.Result = richResult.String()
+				execResult.result = execResult.status.Result
+			}
+			if execResult.err == nil {
+				execResult.status.Output, _ = toolOutput(tools.Find(execResult.toolChoice.Name), execResult.status.Result, execResult.status.ResultData)
+			}
+			if len(o.toolResultTransformers) > 0 {
+				execResult.status = applyToolResultTransformers(execResult.status, o.toolResultTransformers)
+				execResult.result = execResult.status.Result
+			}
 			o.statusCallback(execResult.result)
 
 			// Add tool result to fragment with the tool_call_id
-			f = f.AddToolMessage(execResult.result, execResult.toolChoice.ID)
-			xlog.Debug("Tool result", "tool", execResult.toolChoice.Name, "result", execResult.result)
+			content := execResult.result
+			if o.deduplicateToolResults {
+				if ref := duplicateToolResultRef(f.Status.ToolResults, execResult.status); ref != "" {
+					o.logger.Debug("Duplicate tool result replaced with a reference", "tool", execResult.toolChoice.Name)
+					content = ref
+				}
+			}
+			if len(execResult.status.Corrections) > 0 {
+				f = rewriteToolCall(f, execResult.toolChoice)
+			}
+			f = f.AddToolMessage(content, execResult.toolChoice.ID)
+			if isRichResult && len(richResult.Images) > 0 {
+				toolImages = append(toolImages, toolImagesMessage{tool: execResult.toolChoice.Name, images: richResult.Images})
+			}
+			o.logger.Debug("Tool result", "tool", execResult.toolChoice.Name, "result", execResult.result)
 
 			toolResult := tools.Find(execResult.toolChoice.Name)
 			if toolResult != nil {
@@ -1824,24 +2493,64 @@ Please provide revised tool call based on this feedback.`,
 			}
 			f.Status.ToolResults = append(f.Status.ToolResults, execResult.status)
 			f.Status.PastActions = append(f.Status.PastActions, execResult.status) // Track for loop detection
+			if execResult.err == nil {
+				recordDatasetExample(o, f, tools, execResult.toolChoice, execResult.result)
+				if o.entityMemory != nil {
+					o.entityMemory.rememberArguments(o.context, execResult.toolChoice)
+				}
+			}
 
 			if o.toolCallResultCallback != nil {
 				o.toolCallResultCallback(execResult.status)
 			}
+			if o.streamCallback != nil {
+				o.streamCallback(StreamEvent{
+					Type:       StreamEventToolResult,
+					ToolName:   execResult.toolChoice.Name,
+					ToolCallID: execResult.toolChoice.ID,
+					ToolResult: execResult.result,
+				})
+			}
+			recordToolOutcome(o, intent, execResult.status, execResult.err)
+		}
+
+		for _, ti := range toolImages {
+			f = f.AddMessage(UserMessageRole, fmt.Sprintf("Images returned by the tool %q:", ti.tool), ti.images...)
+		}
+
+		for _, execResult := range executionResults {
+			if execResult.err != nil {
+				notifyToolFailed(o, execResult.toolChoice, execResult.err)
+			}
+		}
+
+		// Reflect on failed tool calls so the next selection can learn from them
+		if o.reflection {
+			for _, execResult := range executionResults {
+				if execResult.err == nil {
+					continue
+				}
+				note := reflectOnFailure(llm, f, fmt.Sprintf("The tool %q failed with error: %v", execResult.toolChoice.Name, execResult.err), o)
+				recordReflection(f.Status, note)
+			}
 		}
 
 		f.Status.Iterations = f.Status.Iterations + 1
 
-		xlog.Debug("Tools called", "tools", f.Status.ToolsCalled.Names())
+		o.logger.Debug("Tools called", "tools", f.Status.ToolsCalled.Names())
+
+		if transactionErr != nil {
+			return f, transactionErr
+		}
 
 	}
 
 	// If sink state was found, stop execution after processing all tools
-	if hasSinkState {
-		xlog.Debug("Sink state was found, stopping execution after processing tools")
+	if hasSinkState && enterSinkState(o, sinkChoice) {
+		o.logger.Debug("Sink state was found, stopping execution after processing tools")
 		status := f.Status
 		var err error
-		f, err = askWithStreaming(o.context, llm, f, o.streamCallback)
+		f, err = askFiltered(o, llm, f)
 		if err != nil {
 			return f, fmt.Errorf("failed to ask LLM: %w", err)
 		}
@@ -1851,9 +2560,13 @@ Please provide revised tool call based on this feedback.`,
 		f.Status.LastUsage = status.LastUsage
 		f.Status.Iterations = status.Iterations
 		f.Status.ReasoningLog = status.ReasoningLog
+		logFinalReasoning(o, f)
 		f.Status.TODOs = status.TODOs
 		f.Status.TODOIteration = status.TODOIteration
 		f.Status.TODOPhase = status.TODOPhase
+		f.Status.Reflections = status.Reflections
+		f.Status.ContextDegradations = status.ContextDegradations
+		f.Status.Extensions = status.Extensions
 	}
 
 	// AutoImprove: run review step after main loop
@@ -1884,8 +2597,8 @@ Please provide revised tool call based on this feedback.`,
 // compactFragment compacts the conversation by generating a summary of the history
 // and keeping only the most recent messages.
 // Returns a new fragment with the summary prepended and recent messages appended.
-func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int, prompts prompt.PromptMap) (Fragment, error) {
-	xlog.Debug("[compactFragment] Starting conversation compaction", "currentMessages", len(f.Messages), "keepMessages", keepMessages)
+func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int, prompts prompt.PromptMap, logger Logger) (Fragment, error) {
+	logger.Debug("[compactFragment] Starting conversation compaction", "currentMessages", len(f.Messages), "keepMessages", keepMessages)
 
 	// Get the conversation context (everything except the most recent messages)
 	var contextMessages []openai.ChatCompletionMessage
@@ -1896,6 +2609,8 @@ func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int,
 	} else {
 		contextMessages = f.Messages
 	}
+	// Hidden messages are not summarized, the LLM must not see them
+	contextMessages = Fragment{Messages: contextMessages, Metadata: f.Metadata}.Filter(nil).Messages
 
 	// Extract tool results from context
 	for _, msg := range contextMessages {
@@ -1947,7 +2662,7 @@ func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int,
 		summary = summaryFragment.Messages[len(summaryFragment.Messages)-1].Content
 	}
 
-	xlog.Debug("[compactFragment] Generated summary", "summaryLength", len(summary))
+	logger.Debug("[compactFragment] Generated summary", "summaryLength", len(summary))
 
 	// Build new fragment with summary + recent messages
 	newFragment := NewEmptyFragment()
@@ -1960,8 +2675,9 @@ func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int,
 
 	// Add the recent messages we want to keep
 	if len(f.Messages) > keepMessages {
-		recentMessages := f.Messages[len(f.Messages)-keepMessages:]
-		for _, msg := range recentMessages {
+		start := len(f.Messages) - keepMessages
+		recentMessages := f.Messages[start:]
+		for i, msg := range recentMessages {
 			newFragment = newFragment.AddMessage(MessageRole(msg.Role), msg.Content)
 			// Preserve tool calls if any
 			if len(msg.ToolCalls) > 0 {
@@ -1969,11 +2685,17 @@ func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int,
 				lastMsg.ToolCalls = msg.ToolCalls
 				newFragment.Messages[len(newFragment.Messages)-1] = lastMsg
 			}
+			if start+i < len(f.Metadata) {
+				newFragment = newFragment.WithMetadata(f.Metadata[start+i])
+			}
 		}
 	} else {
 		// If we don't have more than keepMessages, just use what we have
-		for _, msg := range f.Messages {
+		for i, msg := range f.Messages {
 			newFragment = newFragment.AddMessage(MessageRole(msg.Role), msg.Content)
+			if i < len(f.Metadata) {
+				newFragment = newFragment.WithMetadata(f.Metadata[i])
+			}
 		}
 	}
 
@@ -1987,17 +2709,21 @@ func compactFragment(ctx context.Context, llm LLM, f Fragment, keepMessages int,
 			PastActions:      f.Status.PastActions,
 			InjectedMessages: f.Status.InjectedMessages,
 			Iterations:       f.Status.Iterations,
+			Reflections:      f.Status.Reflections,
+
+			ContextDegradations: f.Status.ContextDegradations,
+			Extensions:          f.Status.Extensions,
 		}
 	}
 
-	xlog.Debug("[compactFragment] Compaction complete", "newMessages", len(newFragment.Messages))
+	logger.Debug("[compactFragment] Compaction complete", "newMessages", len(newFragment.Messages))
 
 	return newFragment, nil
 }
 
 // checkAndCompact checks if actual token count from LLM response exceeds threshold and performs compaction if needed
 // Returns the (potentially compacted) fragment and whether compaction was performed
-func checkAndCompact(ctx context.Context, llm LLM, f Fragment, threshold int, keepMessages int, prompts prompt.PromptMap) (Fragment, bool, error) {
+func checkAndCompact(ctx context.Context, llm LLM, f Fragment, threshold int, keepMessages int, prompts prompt.PromptMap, logger Logger) (Fragment, bool, error) {
 	if threshold <= 0 {
 		return f, false, nil // Compaction disabled
 	}
@@ -2006,7 +2732,7 @@ func checkAndCompact(ctx context.Context, llm LLM, f Fragment, threshold int, ke
 	totalUsedTokens := 0
 	if f.Status != nil && f.Status.LastUsage.TotalTokens > 0 {
 		totalUsedTokens = f.Status.LastUsage.TotalTokens
-		xlog.Debug("[checkAndCompact] Using actual usage tokens from LLM response", "totalUsedTokens", totalUsedTokens, "threshold", threshold)
+		logger.Debug("[checkAndCompact] Using actual usage tokens from LLM response", "totalUsedTokens", totalUsedTokens, "threshold", threshold)
 	} else {
 		// Fallback to rough estimate if no usage data available (first iteration)
 		for _, msg := range f.Messages {
@@ -2020,12 +2746,12 @@ func checkAndCompact(ctx context.Context, llm LLM, f Fragment, threshold int, ke
 				totalUsedTokens += len(tc.Function.Name) + len(tc.Function.Arguments)
 			}
 		}
-		xlog.Debug("[checkAndCompact] Using rough estimate (no usage data)", "totalUsedTokens", totalUsedTokens, "threshold", threshold)
+		logger.Debug("[checkAndCompact] Using rough estimate (no usage data)", "totalUsedTokens", totalUsedTokens, "threshold", threshold)
 	}
 
 	if totalUsedTokens >= threshold {
-		xlog.Debug("[checkAndCompact] Token threshold exceeded", "totalUsedTokens", totalUsedTokens, "threshold", threshold)
-		compacted, err := compactFragment(ctx, llm, f, keepMessages, prompts)
+		logger.Debug("[checkAndCompact] Token threshold exceeded", "totalUsedTokens", totalUsedTokens, "threshold", threshold)
+		compacted, err := compactFragment(ctx, llm, f, keepMessages, prompts, logger)
 		if err != nil {
 			return f, false, err
 		}
@@ -2,7 +2,7 @@ package cogito_test
 
 import (
 	. "github.com/mudler/cogito"
-	"github.com/mudler/cogito/tests/mock"
+	"github.com/mudler/cogito/cogitotest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
@@ -10,13 +10,13 @@ import (
 
 var _ = Describe("ExecuteTools cumulative usage", func() {
 	It("sums token usage across every LLM call in the run", func() {
-		mockLLM := mock.NewMockOpenAIClient()
+		mockLLM := cogitotest.NewMockLLM()
 
 		// One tool round then a final text answer => >= 2 CreateChatCompletion
 		// calls plus one Ask. Each configured call reports 100 total tokens.
 		mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-		mockTool := mock.NewMockTool("search", "Search for information")
-		mock.SetRunResult(mockTool, "Result")
+		mockTool := cogitotest.NewMockTool("search", "Search for information")
+		cogitotest.SetRunResult(mockTool, "Result")
 		mockLLM.SetAskResponse("Final answer")
 		mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 			Choices: []openai.ChatCompletionChoice{
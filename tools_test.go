@@ -1,23 +1,54 @@
 package cogito_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	. "github.com/mudler/cogito"
-	"github.com/mudler/cogito/tests/mock"
+	"github.com/mudler/cogito/cogitotest"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/sashabaranov/go-openai"
 	"github.com/sashabaranov/go-openai/jsonschema"
 )
 
+type countingLimiter struct {
+	waits int
+	err   error
+}
+
+func (l *countingLimiter) Wait(ctx context.Context) error {
+	l.waits++
+	return l.err
+}
+
+type discoverArgs struct{}
+
+// discoverRunner registers its tools in the registry when it runs.
+type discoverRunner struct {
+	registry *ToolRegistry
+	tools    []ToolDefinitionInterface
+}
+
+func (d *discoverRunner) Run(args discoverArgs) (string, any, error) {
+	d.registry.Register(d.tools...)
+	return "connected to a PostgreSQL database", nil, nil
+}
+
 var _ = Describe("ExecuteTools", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 	var originalFragment Fragment
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 		originalFragment = NewEmptyFragment().
 			AddMessage(UserMessageRole, "What is photosynthesis?").
 			AddMessage(AssistantMessageRole, "Photosynthesis is the process by which plants convert sunlight into energy.")
@@ -25,7 +56,7 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("ToolDefinition", func() {
 		It("should create a valid ToolDefinition", func() {
-			mockToolDef := mock.NewMockTool("search", "Search for information")
+			mockToolDef := cogitotest.NewMockTool("search", "Search for information")
 			mockToolDefT := mockToolDef.(*ToolDefinition[map[string]any])
 			toolDefinition := ToolDefinition[map[string]any]{
 				ToolRunner:  mockToolDefT.ToolRunner,
@@ -53,7 +84,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should create a valid ToolDefinition with enums and description", func() {
-			mockToolDef := mock.NewMockTool("search", "Search for information")
+			mockToolDef := cogitotest.NewMockTool("search", "Search for information")
 			mockToolDefT := mockToolDef.(*ToolDefinition[map[string]any])
 			toolDefinition := ToolDefinition[map[string]any]{
 				ToolRunner:  mockToolDefT.ToolRunner,
@@ -82,7 +113,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should create a valid ToolDefinition which arg is not required", func() {
-			mockToolDef := mock.NewMockTool("search", "Search for information")
+			mockToolDef := cogitotest.NewMockTool("search", "Search for information")
 			mockToolDefT := mockToolDef.(*ToolDefinition[map[string]any])
 			toolDefinition := ToolDefinition[map[string]any]{
 				ToolRunner:  mockToolDefT.ToolRunner,
@@ -111,23 +142,23 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("ExecuteTools with tools", func() {
 		It("should execute tools when provided", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// First tool selection and execution
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "chlorophyll"}`)
-			mock.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
+			cogitotest.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
 			// After tool execution, ToolReEvaluator (toolSelection) picks next tool
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "grass"}`)
 
 			// Second tool selection and execution
 			// (The "grass" tool call above will be picked as nextAction)
-			mock.SetRunResult(mockTool, "Grass is a plant that grows on the ground.")
+			cogitotest.SetRunResult(mockTool, "Grass is a plant that grows on the ground.")
 			// After tool execution, ToolReEvaluator (toolSelection) picks next tool
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "baz"}`)
 
 			// Third tool selection and execution
 			// (The "baz" tool call above will be picked as nextAction)
-			mock.SetRunResult(mockTool, "Baz is a plant that grows on the ground.")
+			cogitotest.SetRunResult(mockTool, "Baz is a plant that grows on the ground.")
 
 			// After ToolReEvaluator returns no tool, Ask() is called to get final response
 			mockLLM.SetAskResponse("Here is the final response with all the information gathered.")
@@ -156,15 +187,15 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should execute tools when provided with guidelines", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
-			mockWeatherTool := mock.NewMockTool("get_weather", "Get the weather")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			mockWeatherTool := cogitotest.NewMockTool("get_weather", "Get the weather")
 			// First iteration
 			// 1. Guidelines selection:
 			mockLLM.SetAskResponse("Only the first guideline is relevant.")
 			mockLLM.AddCreateChatCompletionFunction("json", `{"guidelines": [1]}`)
 			// 2. Tool selection (direct):
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "chlorophyll"}`)
-			mock.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
+			cogitotest.SetRunResult(mockTool, "Chlorophyll is a green pigment found in plants.")
 
 			// Second iteration
 			// 1. Guidelines selection:
@@ -172,7 +203,7 @@ var _ = Describe("ExecuteTools", func() {
 			mockLLM.AddCreateChatCompletionFunction("json", `{"guidelines": [1]}`)
 			// 2. Tool selection:
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "grass"}`)
-			mock.SetRunResult(mockTool, "Grass is a plant that grows on the ground.")
+			cogitotest.SetRunResult(mockTool, "Grass is a plant that grows on the ground.")
 
 			// Third iteration
 			// 1. Guidelines selection:
@@ -180,7 +211,7 @@ var _ = Describe("ExecuteTools", func() {
 			mockLLM.AddCreateChatCompletionFunction("json", `{"guidelines": [2]}`)
 			// 2. Tool selection:
 			mockLLM.AddCreateChatCompletionFunction("get_weather", `{"query": "baz"}`)
-			mock.SetRunResult(mockWeatherTool, "Baz is a plant that grows on the ground.")
+			cogitotest.SetRunResult(mockWeatherTool, "Baz is a plant that grows on the ground.")
 
 			// When max iterations is reached, Ask() is called to get final response
 			mockLLM.SetAskResponse("All tasks completed.")
@@ -233,7 +264,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should execute autoplan basic functionality", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Mock planning decision - decide that planning is needed
 			mockLLM.SetAskResponse("Yes, this task requires planning to be completed effectively.")
@@ -252,7 +283,7 @@ var _ = Describe("ExecuteTools", func() {
 			// Mock first subtask execution - search
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "photosynthesis basics"}`)
 			mockLLM.SetAskResponse("Photosynthesis is the process by which plants convert sunlight into energy.")
-			mock.SetRunResult(mockTool, "Photosynthesis is the process by which plants convert sunlight into energy.")
+			cogitotest.SetRunResult(mockTool, "Photosynthesis is the process by which plants convert sunlight into energy.")
 			mockLLM.SetAskResponse("Photosynthesis is the process by which plants convert sunlight into energy.")
 
 			// Mock goal achievement check for first subtask
@@ -306,7 +337,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should not execute autoplan when planning is not needed", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// Mock planning decision - decide that planning is NOT needed
 			mockLLM.SetAskResponse("No, this task does not require planning.")
@@ -315,7 +346,7 @@ var _ = Describe("ExecuteTools", func() {
 			// Mock regular tool execution (since planning is not needed, it falls back to normal tool execution)
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "photosynthesis"}`)
 			mockLLM.SetAskResponse("Photosynthesis is the process by which plants convert sunlight into energy.")
-			mock.SetRunResult(mockTool, "Photosynthesis is the process by which plants convert sunlight into energy.")
+			cogitotest.SetRunResult(mockTool, "Photosynthesis is the process by which plants convert sunlight into energy.")
 			// After tool execution, ToolReEvaluator (toolSelection) returns no tool (text response)
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{
@@ -360,13 +391,13 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("Tool Call Callbacks", func() {
 		It("should call the callback with ToolChoice and SessionState", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			var receivedTool *ToolChoice
 			var receivedState *SessionState
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Test result")
+			cogitotest.SetRunResult(mockTool, "Test result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -396,7 +427,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should interrupt execution when Approved is false", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
@@ -412,7 +443,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should skip tool call when Skip is true", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
@@ -455,11 +486,11 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should use directly modified tool choice when Modified is set", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// First tool selection (will be modified)
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "original"}`)
-			mock.SetRunResult(mockTool, "Modified result")
+			cogitotest.SetRunResult(mockTool, "Modified result")
 			mockLLM.SetAskResponse("LLM result")
 
 			var executedArgs map[string]any
@@ -485,14 +516,14 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should handle adjustment feedback and re-evaluate tool call", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			callbackCount := 0
 
 			// First tool selection (original)
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "original"}`)
 			// Adjustment: LLM re-evaluates with feedback
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "adjusted"}`)
-			mock.SetRunResult(mockTool, "Adjusted result")
+			cogitotest.SetRunResult(mockTool, "Adjusted result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -529,7 +560,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should respect max adjustment attempts limit", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			callbackCount := 0
 
 			// First tool selection
@@ -537,7 +568,7 @@ var _ = Describe("ExecuteTools", func() {
 			// Adjustment attempts (will hit max)
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "adjusted1"}`)
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "adjusted2"}`)
-			mock.SetRunResult(mockTool, "Final result")
+			cogitotest.SetRunResult(mockTool, "Final result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -572,7 +603,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should handle skip during adjustment loop", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			callbackCount := 0
 
 			// First tool selection
@@ -608,8 +639,8 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should handle direct modification during adjustment loop", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
-			mock.SetRunResult(mockTool, "Directly modified result")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Directly modified result")
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "original"}`)
@@ -657,12 +688,12 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("SessionState and Resume", func() {
 		It("should create SessionState with ToolChoice and Fragment", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			var savedState *SessionState
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Test result")
+			cogitotest.SetRunResult(mockTool, "Test result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -690,7 +721,7 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should resume execution from SessionState", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			var savedState *SessionState
 
 			// First execution - interrupt after saving state
@@ -706,7 +737,7 @@ var _ = Describe("ExecuteTools", func() {
 			Expect(savedState).ToNot(BeNil())
 
 			// Resume execution
-			mock.SetRunResult(mockTool, "Resumed result")
+			cogitotest.SetRunResult(mockTool, "Resumed result")
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{
 					{
@@ -727,8 +758,8 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("WithStartWithAction", func() {
 		It("should start execution with a pre-selected tool", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
-			mock.SetRunResult(mockTool, "Pre-selected result")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Pre-selected result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -758,10 +789,10 @@ var _ = Describe("ExecuteTools", func() {
 		})
 
 		It("should start execution with multiple pre-selected tools", func() {
-			mockSearchTool := mock.NewMockTool("search", "Search for information")
-			mockWeatherTool := mock.NewMockTool("get_weather", "Get weather information")
-			mock.SetRunResult(mockSearchTool, "Search result")
-			mock.SetRunResult(mockWeatherTool, "Weather result")
+			mockSearchTool := cogitotest.NewMockTool("search", "Search for information")
+			mockWeatherTool := cogitotest.NewMockTool("get_weather", "Get weather information")
+			cogitotest.SetRunResult(mockSearchTool, "Search result")
+			cogitotest.SetRunResult(mockWeatherTool, "Weather result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -802,10 +833,10 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("Multiple Tool Selection", func() {
 		It("should handle multiple tool selections sequentially", func() {
-			mockSearchTool := mock.NewMockTool("search", "Search for information")
-			mockWeatherTool := mock.NewMockTool("get_weather", "Get weather information")
-			mock.SetRunResult(mockSearchTool, "Search result")
-			mock.SetRunResult(mockWeatherTool, "Weather result")
+			mockSearchTool := cogitotest.NewMockTool("search", "Search for information")
+			mockWeatherTool := cogitotest.NewMockTool("get_weather", "Get weather information")
+			cogitotest.SetRunResult(mockSearchTool, "Search result")
+			cogitotest.SetRunResult(mockWeatherTool, "Weather result")
 			mockLLM.SetAskResponse("LLM result")
 
 			// LLM selects multiple tools in a single response
@@ -859,10 +890,10 @@ var _ = Describe("ExecuteTools", func() {
 
 	Context("Parallel Tool Execution", func() {
 		It("should execute multiple tools in parallel when enabled", func() {
-			mockSearchTool := mock.NewMockTool("search", "Search for information")
-			mockWeatherTool := mock.NewMockTool("get_weather", "Get weather information")
-			mock.SetRunResult(mockSearchTool, "Search result")
-			mock.SetRunResult(mockWeatherTool, "Weather result")
+			mockSearchTool := cogitotest.NewMockTool("search", "Search for information")
+			mockWeatherTool := cogitotest.NewMockTool("get_weather", "Get weather information")
+			cogitotest.SetRunResult(mockSearchTool, "Search result")
+			cogitotest.SetRunResult(mockWeatherTool, "Weather result")
 			mockLLM.SetAskResponse("LLM result")
 			// LLM selects multiple tools using the parallel intention tool
 			// First, reasoning step - now uses the reasoning tool
@@ -934,16 +965,263 @@ var _ = Describe("ExecuteTools", func() {
 		})
 	})
 
+	Context("WithConsolidatedReasoning", func() {
+		It("should pick the tool and its arguments in a single completion", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+
+			mockLLM.AddCreateChatCompletionFunction("reason_and_call",
+				`{"reasoning": "I need to search", "calls": [{"tool": "search", "arguments": {"query": "weather"}}]}`)
+			mockLLM.SetAskResponse("Sunny")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithConsolidatedReasoning())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result.Status.ToolsCalled).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].ToolArguments.Arguments).To(HaveKeyWithValue("query", "weather"))
+			Expect(result.Status.ToolResults[0].ToolArguments.Reasoning).To(Equal("I need to search"))
+			Expect(mockLLM.RequestHistory).To(HaveLen(1))
+			Expect(mockLLM.RequestHistory[0].Tools).To(HaveLen(1))
+			Expect(mockLLM.RequestHistory[0].Tools[0].Function.Name).To(Equal("reason_and_call"))
+		})
+
+		It("should fall back to the multi-step flow when the decision can't be used", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+
+			mockLLM.AddCreateChatCompletionFunction("reason_and_call",
+				`{"reasoning": "I need to search", "calls": [{"tool": "unknown", "arguments": {}}]}`)
+			mockLLM.AddCreateChatCompletionFunction("reasoning", `{"reasoning": "I need to search"}`)
+			mockLLM.AddCreateChatCompletionFunction("pick_tool", `{"tool": "search"}`)
+			mockLLM.AddCreateChatCompletionFunction("reasoning", `{"reasoning": "Search for the weather"}`)
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "weather"}`)
+			mockLLM.SetAskResponse("Sunny")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithConsolidatedReasoning())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result.Status.ToolsCalled).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].ToolArguments.Arguments).To(HaveKeyWithValue("query", "weather"))
+			Expect(mockLLM.RequestHistory).To(HaveLen(5))
+		})
+	})
+
+	Context("WithMinToolConfidence", func() {
+		It("should ask for clarification instead of running a low confidence selection", func() {
+			mockTool := cogitotest.NewMockTool("weather", "Get the weather")
+
+			mockLLM.AddCreateChatCompletionFunction("reason_and_call",
+				`{"reasoning": "Maybe the weather", "confidence": 0.2, "calls": [{"tool": "weather", "arguments": {}}]}`)
+			mockLLM.SetAskResponse("Which city do you mean?")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithConsolidatedReasoning(), WithMinToolConfidence(0.5))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result.Status.ToolsCalled).To(BeEmpty())
+			Expect(result.LastMessage().Content).To(Equal("Which city do you mean?"))
+			Expect(mockLLM.FragmentHistory).To(HaveLen(1))
+			Expect(mockLLM.FragmentHistory[0].LastMessage().Content).To(ContainSubstring("weather"))
+		})
+
+		It("should treat a reported confidence of 0 as the lowest", func() {
+			mockTool := cogitotest.NewMockTool("weather", "Get the weather")
+
+			mockLLM.AddCreateChatCompletionFunction("reason_and_call",
+				`{"reasoning": "A guess", "confidence": 0, "calls": [{"tool": "weather", "arguments": {}}]}`)
+			mockLLM.SetAskResponse("Which city do you mean?")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithConsolidatedReasoning(), WithMinToolConfidence(0.5))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result.Status.ToolsCalled).To(BeEmpty())
+			Expect(mockLLM.Requests()[0].LogProbs).To(BeTrue())
+		})
+
+		It("should run selections above the threshold", func() {
+			mockTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunResult(mockTool, "Sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("reason_and_call",
+				`{"reasoning": "The user asks for the weather", "confidence": 0.9, "calls": [{"tool": "weather", "arguments": {}}]}`)
+			mockLLM.SetAskResponse("It is sunny")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithConsolidatedReasoning(), WithMinToolConfidence(0.5))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result.Status.ToolsCalled).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].ToolArguments.Confidence).To(HaveValue(Equal(0.9)))
+		})
+	})
+
+	Context("WithArgumentClarification", func() {
+		weatherTool := func() ToolDefinitionInterface {
+			tool := cogitotest.NewMockTool("weather", "Get the weather for a city")
+			tool.(*ToolDefinition[map[string]any]).InputArguments = map[string]any{
+				"type":       "object",
+				"properties": map[string]any{"city": map[string]any{"type": "string"}},
+				"required":   []string{"city"},
+			}
+			return tool
+		}
+
+		It("should ask for the missing arguments and resume with the answer", func() {
+			tool := weatherTool()
+			cogitotest.SetRunResult(tool, "Sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Paris"}`)
+			mockLLM.AddCreateChatCompletionFunction("check_arguments", `{"missing": ["city"], "question": "Which city?"}`)
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(tool), WithArgumentClarification())
+			var clarification *ClarificationNeeded
+			Expect(errors.As(err, &clarification)).To(BeTrue())
+			Expect(clarification.Tool).To(Equal("weather"))
+			Expect(clarification.MissingFields).To(Equal([]string{"city"}))
+			Expect(clarification.Question).To(Equal("Which city?"))
+			Expect(result.LastMessage().Content).To(Equal("Which city?"))
+			Expect(result.Status.ToolsCalled).To(BeEmpty())
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.AddCreateChatCompletionFunction("check_arguments", `{"missing": []}`)
+			mockLLM.SetAskResponse("It is sunny in Rome")
+
+			result, err = clarification.Resume(mockLLM, "Rome", WithTools(tool), WithArgumentClarification())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolsCalled).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].ToolArguments.Arguments).To(HaveKeyWithValue("city", "Rome"))
+			Expect(result.Messages).To(ContainElement(HaveField("Content", "Rome")))
+		})
+
+		It("should ask for required arguments the LLM left out", func() {
+			tool := weatherTool()
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{}`)
+			mockLLM.AddCreateChatCompletionFunction("check_arguments", `{"missing": []}`)
+
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(tool), WithArgumentClarification())
+			var clarification *ClarificationNeeded
+			Expect(errors.As(err, &clarification)).To(BeTrue())
+			Expect(clarification.MissingFields).To(Equal([]string{"city"}))
+			Expect(clarification.Question).To(ContainSubstring("city"))
+		})
+	})
+
+	Context("Terminal states", func() {
+		It("should end the run without a reply when giving up", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			mockLLM.AddCreateChatCompletionFunction("give_up", `{"reason": "No tool can book flights"}`)
+
+			var entered *ToolChoice
+			var enteredResult string
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithTerminalStates(WaitState(), GiveUpState()),
+				WithSinkStateCallback(func(choice *ToolChoice, result string) {
+					entered, enteredResult = choice, result
+				}))
+			Expect(err).To(MatchError(ErrNoToolSelected))
+
+			Expect(entered).ToNot(BeNil())
+			Expect(entered.Name).To(Equal("give_up"))
+			Expect(enteredResult).To(Equal("No tool can book flights"))
+			Expect(mockLLM.FragmentHistory).To(BeEmpty())
+			Expect(result.Status.ToolsCalled).To(BeEmpty())
+
+			var offered []string
+			for _, t := range mockLLM.RequestHistory[0].Tools {
+				offered = append(offered, t.Function.Name)
+			}
+			Expect(offered).To(ContainElements("search", "reply", "wait", "give_up"))
+		})
+
+		It("should reply when entering a replying sink state", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			mockLLM.AddCreateChatCompletionFunction("reply_to_user", `{"reason": "I know the answer"}`)
+			mockLLM.SetAskResponse("Paris")
+
+			var entered string
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithSinkState(ReplyToUserState()),
+				WithSinkStateCallback(func(choice *ToolChoice, result string) {
+					entered = choice.Name
+				}))
+			Expect(err).To(MatchError(ErrNoToolSelected))
+
+			Expect(entered).To(Equal("reply_to_user"))
+			Expect(result.LastMessage().Content).To(Equal("Paris"))
+		})
+	})
+
+	Context("WaitTool", func() {
+		It("should suspend the run and resume it when the event fires", func() {
+			notifyTool := cogitotest.NewMockTool("notify", "Notify the team")
+			cogitotest.SetRunResult(notifyTool, "Notified")
+			waitTool := NewWaitTool(map[string]string{"deploy_finished": "The deployment is over"})
+
+			mockLLM.AddCreateChatCompletionFunction("wait_for", `{"event": "deploy_finished", "reason": "Waiting for the deploy"}`)
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(notifyTool, waitTool))
+
+			var suspension *Suspension
+			Expect(errors.As(err, &suspension)).To(BeTrue())
+			Expect(suspension.Event).To(Equal("deploy_finished"))
+			Expect(suspension.Reason).To(Equal("Waiting for the deploy"))
+
+			data, err := json.Marshal(suspension)
+			Expect(err).ToNot(HaveOccurred())
+			var restored Suspension
+			Expect(json.Unmarshal(data, &restored)).To(Succeed())
+
+			trigger := NewTrigger(mockLLM, WithTools(notifyTool, waitTool))
+			trigger.Add(&restored)
+			Expect(trigger.Fire("something_else", "")).To(BeEmpty())
+			Expect(mockLLM.RequestHistory).To(HaveLen(1))
+
+			mockLLM.AddCreateChatCompletionFunction("notify", `{}`)
+			mockLLM.SetAskResponse("The team was notified")
+			results := trigger.Fire("deploy_finished", "version 1.2 is live")
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].Err).ToNot(HaveOccurred())
+			Expect(results[0].Fragment.Status.ToolsCalled).To(HaveLen(1))
+			Expect(trigger.Pending()).To(BeEmpty())
+
+			resumed := mockLLM.RequestHistory[1].Messages
+			Expect(resumed[len(resumed)-1].Role).To(Equal("tool"))
+			Expect(resumed[len(resumed)-1].Content).To(ContainSubstring("version 1.2 is live"))
+		})
+
+		It("should resume sleeping runs once they are due", func() {
+			waitTool := NewWaitTool(nil)
+			mockLLM.AddCreateChatCompletionFunction("wait_for", `{"seconds": 60}`)
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(waitTool))
+
+			var suspension *Suspension
+			Expect(errors.As(err, &suspension)).To(BeTrue())
+			Expect(suspension.Until).To(BeTemporally("~", time.Now().Add(time.Minute), 5*time.Second))
+
+			mockLLM.AddCreateChatCompletionFunction("wait_for", `{"event": "never"}`)
+			trigger := NewTrigger(mockLLM, WithTools(waitTool))
+			trigger.Add(suspension)
+			Expect(trigger.FireDue(time.Now())).To(BeEmpty())
+
+			results := trigger.FireDue(time.Now().Add(2 * time.Minute))
+			Expect(results).To(HaveLen(1))
+			Expect(errors.As(results[0].Err, &suspension)).To(BeTrue())
+			Expect(trigger.Pending()).To(HaveLen(1))
+			Expect(trigger.Pending()[0].Event).To(Equal("never"))
+		})
+	})
+
 	Context("WithMaxAdjustmentAttempts", func() {
 		It("should use default max adjustment attempts when not specified", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 			callbackCount := 0
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "original"}`)
 			// Adjustment attempts
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "adjusted"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 			// After tool execution, ToolReEvaluator returns no tool
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -975,14 +1253,578 @@ var _ = Describe("ExecuteTools", func() {
 			Expect(len(result.Status.ToolsCalled)).To(Equal(1))
 		})
 	})
+
+	Context("WithLoopDetector", func() {
+		It("should detect loops on reworded arguments", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "news today"}`)
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "today news"}`)
+
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithIterations(3),
+				WithLoopDetector(NormalizedArgumentsLoopDetector(1)))
+			Expect(err).To(MatchError(ErrLoopDetected))
+		})
+	})
+
+	Context("EnableToolResultDeduplication", func() {
+		It("should replace repeated results with a reference but keep them in Status", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			longResult := strings.Repeat("identical search output ", 20)
+			cogitotest.SetRunResult(mockTool, longResult)
+			cogitotest.SetRunResult(mockTool, longResult)
+
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "first"}`)
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "second"}`)
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Done."}},
+				},
+			})
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithIterations(3), EnableToolResultDeduplication)
+			Expect(err).ToNot(HaveOccurred())
+
+			var toolMessages []string
+			for _, msg := range result.Messages {
+				if msg.Role == ToolMessageRole.String() {
+					toolMessages = append(toolMessages, msg.Content)
+				}
+			}
+			Expect(toolMessages).To(HaveLen(2))
+			Expect(toolMessages[0]).To(Equal(longResult))
+			Expect(toolMessages[1]).To(HavePrefix("Same as previous result of tool \"search\""))
+
+			Expect(result.Status.ToolResults).To(HaveLen(2))
+			Expect(result.Status.ToolResults[1].Result).To(Equal(longResult))
+		})
+	})
+
+	Context("WithToolResultTransformer", func() {
+		It("should transform results before adding them to the conversation", func() {
+			mockTool := cogitotest.NewMockTool("fetch", "Fetch a page")
+			cogitotest.SetRunResult(mockTool, "<html><body><p>Hello &amp; welcome</p></body></html>")
+
+			mockLLM.AddCreateChatCompletionFunction("fetch", `{"url": "https://example.com"}`)
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Done."}},
+				},
+			})
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithIterations(2), WithToolResultTransformer(HTMLToTextToolResult(), TruncateToolResult(2)))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(result.Status.ToolResults).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].Result).To(HavePrefix("Hello &"))
+			Expect(result.Messages).To(ContainElement(HaveField("Content", result.Status.ToolResults[0].Result)))
+		})
+	})
+
+	Context("EnableLocaleFormatting", func() {
+		It("should format numbers and dates of the final answer in the locale", func() {
+			mockTool := cogitotest.NewMockTool("price", "Get a price")
+			cogitotest.SetRunResult(mockTool, "1234.5 USD")
+
+			mockLLM.AddCreateChatCompletionFunction("price", `{"item": "laptop"}`)
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "It costs $1,234.50 since 03/14/2024."}},
+				},
+			})
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithIterations(2), WithLocale("de-DE"), EnableLocaleFormatting)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LastMessage().Content).To(Equal("It costs 1.234,50 $ since 14.03.2024."))
+			// Tool results are not rewritten
+			Expect(result.Status.ToolResults[0].Result).To(Equal("1234.5 USD"))
+		})
+	})
+
+	Context("EnableFactCheck", func() {
+		answer := func(content string) openai.ChatCompletionResponse {
+			return openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: content}},
+				},
+			}
+		}
+
+		It("should rewrite claims not supported by the tool results", func() {
+			mockTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunResult(mockTool, "Rome: 21°C, sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.SetCreateChatCompletionResponse(answer("It is 25°C and sunny in Rome, with no rain expected all week."))
+			mockLLM.AddCreateChatCompletionFunction("report_unsupported_claims",
+				`{"unsupported": [{"claim": "It is 25°C", "correction": "It is 21°C"}, {"claim": "no rain expected all week"}], "revised": "It is 21°C and sunny in Rome."}`)
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithIterations(2), EnableFactCheck)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LastMessage().Content).To(Equal("It is 21°C and sunny in Rome."))
+			Expect(result.Status.UnsupportedClaims).To(Equal([]UnsupportedClaim{
+				{Claim: "It is 25°C", Correction: "It is 21°C"},
+				{Claim: "no rain expected all week"},
+			}))
+
+			checkRequest := mockLLM.RequestHistory[len(mockLLM.RequestHistory)-1]
+			Expect(checkRequest.Messages).To(ContainElement(HaveField("Content", ContainSubstring("Rome: 21°C, sunny"))))
+		})
+
+		It("should annotate unsupported claims when asked to", func() {
+			mockTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunResult(mockTool, "Rome: 21°C, sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.SetCreateChatCompletionResponse(answer("It is 21°C in Rome and the sea is calm."))
+			mockLLM.AddCreateChatCompletionFunction("report_unsupported_claims", `{"unsupported": [{"claim": "the sea is calm"}]}`)
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithIterations(2), EnableFactCheckAnnotations)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LastMessage().Content).To(And(
+				HavePrefix("It is 21°C in Rome and the sea is calm."),
+				ContainSubstring("- the sea is calm"),
+			))
+		})
+
+		It("should keep answers whose claims are supported", func() {
+			mockTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunResult(mockTool, "Rome: 21°C, sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.SetCreateChatCompletionResponse(answer("It is 21°C and sunny in Rome."))
+			mockLLM.AddCreateChatCompletionFunction("report_unsupported_claims", `{"unsupported": []}`)
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithIterations(2), EnableFactCheck)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.LastMessage().Content).To(Equal("It is 21°C and sunny in Rome."))
+			Expect(result.Status.UnsupportedClaims).To(BeEmpty())
+		})
+	})
+	Context("Multimedia", func() {
+		It("should show the fragment attachments to tool selection and parameter generation", func() {
+			expenseTool := cogitotest.NewMockTool("log_expense", "Log an expense")
+			cogitotest.SetRunResult(expenseTool, "logged")
+
+			mockLLM.AddCreateChatCompletionFunction("reasoning", `{"reasoning": "The receipt shows a 12 EUR lunch"}`)
+			mockLLM.AddCreateChatCompletionFunction("pick_tool", `{"tool": "log_expense"}`)
+			mockLLM.AddCreateChatCompletionFunction("reasoning", `{"reasoning": "The amount is 12"}`)
+			mockLLM.AddCreateChatCompletionFunction("log_expense", `{"amount": 12}`)
+			mockLLM.SetAskResponse("Logged 12 EUR.")
+
+			receipt := NewImageURL("https://example.com/receipt.png")
+			fragment := NewEmptyFragment().AddMessage(UserMessageRole, "Read this receipt and log the expense")
+			fragment.Multimedia = []Multimedia{receipt}
+
+			_, err := ExecuteTools(mockLLM, fragment, WithTools(expenseTool), WithForceReasoning())
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mockLLM.RequestHistory).To(HaveLen(4))
+			for _, request := range mockLLM.RequestHistory {
+				Expect(request.Messages).To(ContainElement(HaveField("MultiContent", ContainElement(
+					HaveField("ImageURL", HaveField("URL", receipt.URL()))))))
+			}
+		})
+	})
+
+	Context("WithToolCorrection", func() {
+		It("should send a failed tool call back to the LLM for correction", func() {
+			legacyTool := cogitotest.NewMockTool("legacy_weather", "Get the weather (deprecated)")
+			cogitotest.SetRunError(legacyTool, errors.New("city must be an ISO code"))
+			weatherTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunResult(weatherTool, "Rome: 21°C, sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("legacy_weather", `{"city": "Rome"}`)
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "It is 21°C in Rome."}},
+				},
+			})
+
+			result, err := ExecuteTools(mockLLM, originalFragment,
+				WithTools(legacyTool, weatherTool), WithIterations(2), WithToolCorrection(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolResults).To(HaveLen(1))
+
+			status := result.Status.ToolResults[0]
+			Expect(status.Name).To(Equal("weather"))
+			Expect(status.Result).To(Equal("Rome: 21°C, sunny"))
+			Expect(status.Corrections).To(Equal([]ToolCorrection{
+				{Name: "legacy_weather", Arguments: map[string]any{"city": "Rome"}, Error: "city must be an ISO code"},
+			}))
+
+			correctionRequest := mockLLM.RequestHistory[1]
+			Expect(correctionRequest.Messages).To(ContainElement(HaveField("Content", ContainSubstring("city must be an ISO code"))))
+
+			// The conversation shows the call that produced the result
+			var calls []string
+			for _, m := range result.Messages {
+				for _, call := range m.ToolCalls {
+					calls = append(calls, call.Function.Name)
+				}
+			}
+			Expect(calls).To(Equal([]string{"weather"}))
+		})
+
+		It("should report the error when the correction fails too", func() {
+			weatherTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunError(weatherTool, errors.New("service unavailable"))
+
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Roma"}`)
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "The weather service is unavailable."}},
+				},
+			})
+
+			result, err := ExecuteTools(mockLLM, originalFragment,
+				WithTools(weatherTool), WithIterations(2), WithToolCorrection(1))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolResults).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].Result).To(ContainSubstring("service unavailable"))
+			Expect(result.Status.ToolResults[0].Corrections).To(HaveLen(1))
+		})
+
+		It("should submit the corrected call to the tool call callback", func() {
+			legacyTool := cogitotest.NewMockTool("legacy_weather", "Get the weather (deprecated)")
+			cogitotest.SetRunError(legacyTool, errors.New("city must be an ISO code"))
+			weatherTool := cogitotest.NewMockTool("weather", "Get the weather")
+			cogitotest.SetRunResult(weatherTool, "Rome: 21°C, sunny")
+
+			mockLLM.AddCreateChatCompletionFunction("legacy_weather", `{"city": "Rome"}`)
+			mockLLM.AddCreateChatCompletionFunction("weather", `{"city": "Rome"}`)
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{
+					{Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "The weather is unavailable."}},
+				},
+			})
+
+			var reviewed []string
+			result, err := ExecuteTools(mockLLM, originalFragment,
+				WithTools(legacyTool, weatherTool), WithIterations(2), WithToolCorrection(1),
+				WithToolCallBack(func(tc *ToolChoice, _ *SessionState) ToolCallDecision {
+					reviewed = append(reviewed, tc.Name)
+					return ToolCallDecision{Approved: true, Skip: tc.Name == "weather"}
+				}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reviewed).To(Equal([]string{"legacy_weather", "weather"}))
+			Expect(cogitotest.GetMockTool(weatherTool).Calls()).To(BeEmpty())
+			Expect(result.Status.ToolResults[0].Name).To(Equal("legacy_weather"))
+			Expect(result.Status.ToolResults[0].Result).To(ContainSubstring("city must be an ISO code"))
+		})
+	})
+
+	Context("WithReasoningSink", func() {
+		It("should record the reasoning behind the selected tools", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+					Role:             AssistantMessageRole.String(),
+					ReasoningContent: "The user wants fresh data, so search.",
+					ToolCalls: []openai.ToolCall{{
+						ID:       "call-1",
+						Type:     openai.ToolTypeFunction,
+						Function: openai.FunctionCall{Name: "search", Arguments: `{"query": "test"}`},
+					}},
+				}}},
+			})
+			mockLLM.SetAskResponse("Done")
+
+			sink := &MemoryReasoningSink{}
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithReasoningSink(sink))
+			Expect(err).ToNot(HaveOccurred())
+
+			records := sink.Records()
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Kind).To(Equal(ReasoningToolSelection))
+			Expect(records[0].Tools).To(Equal([]string{"search"}))
+			Expect(records[0].Reasoning).To(Equal("The user wants fresh data, so search."))
+		})
+
+		It("should append records as JSON lines with the file sink", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "reasoning.jsonl")
+			sink := NewFileReasoningSink(path)
+			Expect(sink.Record(context.Background(), ReasoningRecord{Kind: ReasoningReply, Reasoning: "first"})).To(Succeed())
+			Expect(sink.Record(context.Background(), ReasoningRecord{Kind: ReasoningReflection, Reasoning: "second"})).To(Succeed())
+
+			data, err := os.ReadFile(path)
+			Expect(err).ToNot(HaveOccurred())
+			lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+			Expect(lines).To(HaveLen(2))
+			var record ReasoningRecord
+			Expect(json.Unmarshal([]byte(lines[1]), &record)).To(Succeed())
+			Expect(record.Kind).To(Equal(ReasoningReflection))
+			Expect(record.Reasoning).To(Equal("second"))
+		})
+	})
+
+	Context("SessionState serialization", func() {
+		It("should round-trip the tool choice with its reasoning and ID", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+					Role:             AssistantMessageRole.String(),
+					ReasoningContent: "Searching gives fresh data.",
+					ToolCalls: []openai.ToolCall{{
+						ID:       "call-1",
+						Type:     openai.ToolTypeFunction,
+						Function: openai.FunctionCall{Name: "search", Arguments: `{"query": "test"}`},
+					}},
+				}}},
+			})
+			mockLLM.SetAskResponse("Done")
+
+			var captured []byte
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithToolCallBack(func(tc *ToolChoice, s *SessionState) ToolCallDecision {
+					var err error
+					captured, err = json.Marshal(s)
+					Expect(err).ToNot(HaveOccurred())
+					return ToolCallDecision{Approved: true}
+				}))
+			Expect(err).ToNot(HaveOccurred())
+
+			var restored SessionState
+			Expect(json.Unmarshal(captured, &restored)).To(Succeed())
+			Expect(restored.ToolChoice.Name).To(Equal("search"))
+			Expect(restored.ToolChoice.Arguments).To(Equal(map[string]any{"query": "test"}))
+			Expect(restored.ToolChoice.Reasoning).To(Equal("Searching gives fresh data."))
+			Expect(restored.ToolChoice.ID).ToNot(BeEmpty())
+			Expect(restored.Fragment.Messages).To(Equal(originalFragment.Messages))
+		})
+
+		It("should round-trip the images of the fragment and of rich tool results", func() {
+			photo := NewImageFromBytes([]byte("\x89PNG\r\n\x1a\nphoto"), "image/png")
+			chart := NewImageURL("https://example.com/chart.png")
+			f := NewEmptyFragment().AddMessage(UserMessageRole, "What is in this photo?", photo)
+			f.Multimedia = append(f.Multimedia, chart)
+			f.Status.ToolResults = []ToolStatus{{
+				Name:       "plot",
+				ResultData: ToolResult{Text: "Plotted", Images: []Multimedia{chart}},
+			}}
+
+			data, err := json.Marshal(SessionState{ToolChoice: &ToolChoice{Name: "plot"}, Fragment: f})
+			Expect(err).ToNot(HaveOccurred())
+
+			var restored SessionState
+			Expect(json.Unmarshal(data, &restored)).To(Succeed())
+			Expect(restored.Fragment.Multimedia).To(HaveLen(2))
+			Expect(restored.Fragment.Multimedia[0].URL()).To(Equal(photo.URL()))
+			Expect(MultimediaTypeOf(restored.Fragment.Multimedia[0])).To(Equal(MultimediaTypeImage))
+			Expect(restored.Fragment.Multimedia[1].URL()).To(Equal(chart.URL()))
+			Expect(restored.Fragment.Messages).To(Equal(f.Messages))
+
+			var result ToolResult
+			resultData, err := json.Marshal(restored.Fragment.Status.ToolResults[0].ResultData)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(json.Unmarshal(resultData, &result)).To(Succeed())
+			Expect(result.Text).To(Equal("Plotted"))
+			Expect(result.Images).To(HaveLen(1))
+			Expect(result.Images[0].URL()).To(Equal(chart.URL()))
+		})
+
+		It("should keep the definitions of the tools called", func() {
+			f := NewEmptyFragment().AddMessage(UserMessageRole, "Search something")
+			f.Status.ToolsCalled = Tools{cogitotest.NewMockTool("search", "Search for information")}
+
+			data, err := json.Marshal(SessionState{ToolChoice: &ToolChoice{Name: "search"}, Fragment: f})
+			Expect(err).ToNot(HaveOccurred())
+
+			var restored SessionState
+			Expect(json.Unmarshal(data, &restored)).To(Succeed())
+			Expect(restored.Fragment.Status.ToolsCalled.Names()).To(Equal([]string{"search"}))
+			_, _, err = restored.Fragment.Status.ToolsCalled[0].Execute(map[string]any{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("WithCaptureDirectResponse", func() {
+		It("should return the direct answer with ErrDirectResponse", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
+				Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{
+					Role:    AssistantMessageRole.String(),
+					Content: "Hello! How can I help?",
+				}}},
+			})
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithCaptureDirectResponse())
+			Expect(err).To(MatchError(ErrDirectResponse))
+			Expect(result.LastMessage().Role).To(Equal(AssistantMessageRole.String()))
+			Expect(result.LastMessage().Content).To(Equal("Hello! How can I help?"))
+			Expect(result.Status.ToolsCalled).To(BeEmpty())
+			Expect(mockLLM.FragmentHistory).To(BeEmpty())
+		})
+
+		It("should not change the result when a tool is called", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
+			mockLLM.SetAskResponse("Done")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithCaptureDirectResponse())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolsCalled).To(HaveLen(1))
+		})
+	})
+
+	Context("WithRateLimiter", func() {
+		It("should wait on the limiters before every LLM call and tool execution", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
+			mockLLM.SetAskResponse("Done")
+
+			llmLimiter, toolLimiter := &countingLimiter{}, &countingLimiter{}
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithRateLimiter(llmLimiter), WithToolRateLimiter(toolLimiter))
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(llmLimiter.waits).To(Equal(len(mockLLM.RequestHistory) + len(mockLLM.FragmentHistory)))
+			Expect(toolLimiter.waits).To(Equal(1))
+		})
+
+		It("should stop when the limiter gives up", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			limiter := &countingLimiter{err: fmt.Errorf("rate limited")}
+
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithRateLimiter(limiter), WithMaxRetries(1))
+			Expect(err).To(HaveOccurred())
+			Expect(mockLLM.RequestHistory).To(BeEmpty())
+		})
+	})
+
+	Context("WithLogger", func() {
+		It("should send the log output of the run to the given logger", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
+			mockLLM.SetAskResponse("Done")
+
+			var buf bytes.Buffer
+			logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})).With("run", "r-42")
+
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool), WithLogger(logger))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buf.String()).To(ContainSubstring("[decision] tools selected"))
+			Expect(buf.String()).To(ContainSubstring("run=r-42"))
+		})
+	})
+
+	Context("WithStreamCallback", func() {
+		It("should emit the tool results", func() {
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
+			mockLLM.SetAskResponse("Done")
+
+			var results []StreamEvent
+			_, err := ExecuteTools(mockLLM, originalFragment, WithTools(mockTool),
+				WithStreamCallback(func(ev StreamEvent) {
+					if ev.Type == StreamEventToolResult {
+						results = append(results, ev)
+					}
+				}))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].ToolName).To(Equal("search"))
+			Expect(results[0].ToolResult).To(Equal("Result"))
+		})
+	})
+
+	Context("WithToolRegistry", func() {
+		It("offers tools registered during the run from the next iteration", func() {
+			queryTool := cogitotest.NewMockTool("query_db", "Run a SQL query")
+			cogitotest.SetRunResult(queryTool, "42 rows")
+			registry := NewToolRegistry()
+			discover := NewToolDefinition(&discoverRunner{registry: registry, tools: []ToolDefinitionInterface{queryTool}},
+				discoverArgs{}, "discover", "Discover the system")
+			registry.Register(discover)
+
+			mockLLM.AddCreateChatCompletionFunction("discover", `{}`)
+			mockLLM.AddCreateChatCompletionFunction("query_db", `{"query": "SELECT count(*) FROM users"}`)
+			mockLLM.SetAskResponse("There are 42 users")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithToolRegistry(registry), WithIterations(2))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolsCalled).To(HaveLen(2))
+			Expect(result.Status.ToolResults[1].Name).To(Equal("query_db"))
+			Expect(result.Status.ToolResults[1].Result).To(Equal("42 rows"))
+
+			names := func(tools []openai.Tool) []string {
+				out := []string{}
+				for _, t := range tools {
+					out = append(out, t.Function.Name)
+				}
+				return out
+			}
+			Expect(names(mockLLM.RequestHistory[0].Tools)).ToNot(ContainElement("query_db"))
+			Expect(names(mockLLM.RequestHistory[1].Tools)).To(ContainElement("query_db"))
+		})
+	})
+
+	Context("Tool name collisions", func() {
+		It("keeps the first local tool by default", func() {
+			first := cogitotest.NewMockTool("search", "Search the web")
+			second := cogitotest.NewMockTool("search", "Search the intranet")
+			cogitotest.SetRunResult(first, "web result")
+			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
+			mockLLM.SetAskResponse("Done")
+
+			result, err := ExecuteTools(mockLLM, originalFragment, WithTools(first, second))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolResults).To(HaveLen(1))
+			Expect(result.Status.ToolResults[0].Result).To(Equal("web result"))
+			for _, tool := range mockLLM.RequestHistory[0].Tools {
+				Expect(tool.Function.Description).ToNot(Equal("Search the intranet"))
+			}
+		})
+
+		It("fails with a ToolCollisionError under ErrorOnToolCollision", func() {
+			first := cogitotest.NewMockTool("search", "Search the web")
+			second := cogitotest.NewMockTool("search", "Search the intranet")
+
+			_, err := ExecuteTools(mockLLM, originalFragment,
+				WithTools(first, second), WithToolCollisionPolicy(ErrorOnToolCollision))
+			var collision *ToolCollisionError
+			Expect(errors.As(err, &collision)).To(BeTrue())
+			Expect(collision.Name).To(Equal("search"))
+			Expect(collision.Sources).To(Equal([]string{"local", "local"}))
+			Expect(mockLLM.RequestHistory).To(BeEmpty())
+		})
+
+		It("keeps namespaced tools apart", func() {
+			intranetSearch := cogitotest.NewMockTool("search", "Search the intranet")
+			cogitotest.SetRunResult(intranetSearch, "intranet result")
+			web := NamespaceTools("web", cogitotest.NewMockTool("search", "Search the web"))
+			intranet := NamespaceTools("intranet", intranetSearch)
+			mockLLM.AddCreateChatCompletionFunction("intranet__search", `{"query": "test"}`)
+			mockLLM.SetAskResponse("Done")
+
+			result, err := ExecuteTools(mockLLM, originalFragment,
+				WithTools(web...), WithTools(intranet...), WithToolCollisionPolicy(ErrorOnToolCollision))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Status.ToolResults[0].Name).To(Equal("intranet__search"))
+			Expect(result.Status.ToolResults[0].Result).To(Equal("intranet result"))
+		})
+	})
 })
 
 var _ = Describe("ExecuteTools with Compaction", func() {
-	var mockLLM *mock.MockOpenAIClient
+	var mockLLM *cogitotest.MockLLM
 	var originalFragment Fragment
 
 	BeforeEach(func() {
-		mockLLM = mock.NewMockOpenAIClient()
+		mockLLM = cogitotest.NewMockLLM()
 		originalFragment = NewEmptyFragment().
 			AddMessage(UserMessageRole, "Task 1").
 			AddMessage(AssistantMessageRole, "Done 1")
@@ -992,8 +1834,8 @@ var _ = Describe("ExecuteTools with Compaction", func() {
 		It("should not compact when threshold is disabled (0)", func() {
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
 
-			mockTool := mock.NewMockTool("search", "Search for information")
-			mock.SetRunResult(mockTool, "Result")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 			mockLLM.SetUsage(100, 100, 1000)
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -1024,8 +1866,8 @@ var _ = Describe("ExecuteTools with Compaction", func() {
 
 		It("should not compact when tokens below threshold", func() {
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mockTool := mock.NewMockTool("search", "Search for information")
-			mock.SetRunResult(mockTool, "Result")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
 				Choices: []openai.ChatCompletionChoice{
@@ -1053,11 +1895,11 @@ var _ = Describe("ExecuteTools with Compaction", func() {
 		})
 
 		It("should compact when token threshold is exceeded", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			// First tool selection
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 
 			// After tool execution, no more tools needed
@@ -1103,10 +1945,10 @@ var _ = Describe("ExecuteTools with Compaction", func() {
 		})
 
 		It("should preserve parent fragment after compaction", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -1146,10 +1988,10 @@ var _ = Describe("ExecuteTools with Compaction", func() {
 		})
 
 		It("should preserve status after compaction", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
@@ -1193,10 +2035,10 @@ var _ = Describe("ExecuteTools with Compaction", func() {
 		})
 
 		It("should use rough token estimate when LastUsage is not set", func() {
-			mockTool := mock.NewMockTool("search", "Search for information")
+			mockTool := cogitotest.NewMockTool("search", "Search for information")
 
 			mockLLM.AddCreateChatCompletionFunction("search", `{"query": "test"}`)
-			mock.SetRunResult(mockTool, "Result")
+			cogitotest.SetRunResult(mockTool, "Result")
 			mockLLM.SetAskResponse("LLM result")
 
 			mockLLM.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{
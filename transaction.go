@@ -0,0 +1,109 @@
+package cogito
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// ErrTransactionFailed is returned by ExecuteTools when a call of a tool
+// transaction failed and the transaction was rolled back, see
+// WithToolTransaction.
+var ErrTransactionFailed = errors.New("tool transaction failed")
+
+// WithToolTransaction makes the calls of the given tools within a run of
+// ExecuteTools a transaction, all or nothing: when one of them fails, the
+// successful ones are undone by their compensating actions (see
+// ToolDefinition.Compensation), the most recent first, and the run stops
+// with ErrTransactionFailed. In ExecutePlan each subtask is a run, and a
+// rolled back subtask counts as not achieved, so it is retried or re-planned.
+// WithToolTransaction can be used several times for independent
+// transactions.
+func WithToolTransaction(tools ...string) func(o *Options) {
+	return func(o *Options) {
+		o.transactions = append(slices.Clip(o.transactions), tools)
+	}
+}
+
+// transactionCall is a tool call of an iteration, as seen by transactions.
+type transactionCall struct {
+	choice *ToolChoice
+	result string
+	err    error
+}
+
+// transactionLog tracks the successful calls of the transactions of a run.
+type transactionLog struct {
+	transactions [][]string
+	done         map[int][]transactionCall // by transaction
+}
+
+func newTransactionLog(transactions [][]string) *transactionLog {
+	return &transactionLog{transactions: transactions, done: map[int][]transactionCall{}}
+}
+
+// settle records the calls of an iteration and rolls back the transactions
+// with a failed call. It returns the error of the calls rolled back, by index
+// in calls, the outcomes of the compensations and the error of the failed
+// transactions, if any.
+func (l *transactionLog) settle(o *Options, tools Tools, calls []transactionCall) (map[int]error, []CompensationStatus, error) {
+	rolledBack := map[int]error{}
+	var compensations []CompensationStatus
+	var failures []error
+	for t, group := range l.transactions {
+		var failed error
+		var current []int // successful calls of the iteration
+		for i, c := range calls {
+			if !slices.Contains(group, c.choice.Name) {
+				continue
+			}
+			if c.err != nil && failed == nil {
+				failed = fmt.Errorf("%s: %w", c.choice.Name, c.err)
+			} else if c.err == nil {
+				current = append(current, i)
+			}
+		}
+		for _, i := range current {
+			l.done[t] = append(l.done[t], calls[i])
+		}
+		if failed == nil {
+			continue
+		}
+
+		o.logger.Warn("Tool transaction failed, rolling back", "tools", group, "error", failed)
+		for _, i := range current {
+			rolledBack[i] = fmt.Errorf("rolled back, as %v", failed)
+		}
+		done := l.done[t]
+		delete(l.done, t)
+		for i := len(done) - 1; i >= 0; i-- {
+			compensations = append(compensations, rollbackCall(o, tools, done[i]))
+		}
+		failures = append(failures, failed)
+	}
+	if len(failures) == 0 {
+		return rolledBack, compensations, nil
+	}
+	return rolledBack, compensations, fmt.Errorf("%w: %w", ErrTransactionFailed, errors.Join(failures...))
+}
+
+// rollbackCall undoes c with the compensating action of its tool. The call
+// is forgotten by the compensations of the run, so it is not undone twice.
+func rollbackCall(o *Options, tools Tools, c transactionCall) CompensationStatus {
+	status := CompensationStatus{Name: c.choice.Name, Arguments: c.choice.Arguments, Result: c.result}
+	if o.compensations != nil {
+		o.compensations.forget(c.choice.ID, c.choice.Name, c.result)
+	}
+	fn := toolCompensation(tools.Find(c.choice.Name))
+	if fn == nil {
+		o.logger.Error("Tool call of a failed transaction has no compensating action", "tool", c.choice.Name)
+		status.Error = "no compensating action"
+	} else if err := fn(context.WithoutCancel(o.context), c.choice.Arguments, c.result); err != nil {
+		o.logger.Error("Compensation failed", "tool", c.choice.Name, "error", err)
+		status.Error = err.Error()
+	}
+	status.Time = time.Now()
+	return status
+}
@@ -0,0 +1,80 @@
+package cogito_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sashabaranov/go-openai"
+)
+
+var _ = Describe("Tool transactions", func() {
+	var (
+		undone       []string
+		dns, lb, log ToolDefinitionInterface
+		llm          *cogitotest.MockLLM
+	)
+
+	BeforeEach(func() {
+		undone = nil
+		undo := func(ctx context.Context, args map[string]any, result string) error {
+			undone = append(undone, result)
+			return nil
+		}
+		dns = cogitotest.NewMockTool("create_dns_record", "Create a DNS record")
+		cogitotest.SetRunResult(dns, "record created")
+		dns = AddCompensation(dns, undo)
+		log = cogitotest.NewMockTool("log_change", "Log a change")
+		cogitotest.SetRunResult(log, "logged")
+		log = AddCompensation(log, undo)
+		lb = cogitotest.NewMockTool("configure_load_balancer", "Configure the load balancer")
+
+		llm = cogitotest.NewMockLLM()
+		llm.AddCreateChatCompletionFunction("create_dns_record", `{"name": "api"}`)
+		llm.AddCreateChatCompletionFunction("log_change", `{}`)
+		llm.AddCreateChatCompletionFunction("configure_load_balancer", `{"backend": "api"}`)
+		llm.SetCreateChatCompletionResponse(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{
+			Message: openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: "Done."},
+		}}})
+		llm.SetAskResponse("Done.")
+	})
+
+	It("rolls back the group when one of its calls fails", func() {
+		cogitotest.SetRunError(lb, errors.New("backend unreachable"))
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Expose the API")
+		result, err := ExecuteTools(llm, f, WithTools(dns, log, lb), WithIterations(5), EnableCompensation,
+			WithToolTransaction("create_dns_record", "configure_load_balancer"))
+		Expect(err).To(MatchError(ErrTransactionFailed))
+		Expect(err.Error()).To(ContainSubstring("backend unreachable"))
+
+		// The DNS record is undone once, by the transaction, then the log
+		// entry by the compensation of the failed run
+		Expect(undone).To(Equal([]string{"record created", "logged"}))
+		Expect(result.Status.Compensations).To(HaveLen(2))
+		Expect(result.Status.Compensations[0].Name).To(Equal("create_dns_record"))
+		Expect(result.Status.Compensations[0].Arguments).To(Equal(map[string]any{"name": "api"}))
+		Expect(result.Status.Compensations[0].Error).To(BeEmpty())
+	})
+
+	It("keeps the group when its calls succeed", func() {
+		cogitotest.SetRunResult(lb, "configured")
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Expose the API")
+		_, err := ExecuteTools(llm, f, WithTools(dns, log, lb), WithIterations(5),
+			WithToolTransaction("create_dns_record", "configure_load_balancer"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(undone).To(BeEmpty())
+	})
+
+	It("only rolls back the group of the failed call", func() {
+		cogitotest.SetRunError(lb, errors.New("backend unreachable"))
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Expose the API")
+		result, err := ExecuteTools(llm, f, WithTools(dns, log, lb), WithIterations(5),
+			WithToolTransaction("log_change", "configure_load_balancer"), WithToolTransaction("create_dns_record"))
+		Expect(err).To(MatchError(ErrTransactionFailed))
+		Expect(undone).To(Equal([]string{"logged"}))
+		Expect(result.Status.Compensations).To(HaveLen(1))
+	})
+})
@@ -0,0 +1,240 @@
+package cogito
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/mudler/cogito/structures"
+)
+
+// transcript is the readable form of a fragment shared by ToMarkdown and
+// ToHTML.
+type transcript struct {
+	Entries    []transcriptEntry
+	Plans      []PlanStatus
+	TODOs      []structures.TODO
+	Reasoning  []string
+	Iterations int
+	Usage      LLMUsage
+}
+
+type transcriptEntry struct {
+	Title     string // e.g. "User" or "Tool result: get_weather"
+	Content   string
+	Code      bool // Content is shown verbatim, e.g. tool results
+	Reasoning string
+	ToolCalls []transcriptToolCall
+	Hidden    bool // see MessageMetadata
+}
+
+type transcriptToolCall struct {
+	Name      string
+	Arguments string // indented JSON when valid
+}
+
+func newTranscript(f Fragment) transcript {
+	var t transcript
+	toolNames := map[string]string{} // tool call ID -> tool name
+	for i, msg := range f.Messages {
+		e := transcriptEntry{
+			Title:     roleTitle(msg.Role),
+			Content:   msg.Content,
+			Reasoning: msg.ReasoningContent,
+			Hidden:    f.MessageMetadata(i).Hidden,
+		}
+		if msg.Content == "" {
+			var texts []string
+			for _, part := range msg.MultiContent {
+				if part.Text != "" {
+					texts = append(texts, part.Text)
+				}
+			}
+			e.Content = strings.Join(texts, "\n\n")
+		}
+		for _, tc := range msg.ToolCalls {
+			toolNames[tc.ID] = tc.Function.Name
+			e.ToolCalls = append(e.ToolCalls, transcriptToolCall{Name: tc.Function.Name, Arguments: indentJSON(tc.Function.Arguments)})
+		}
+		if msg.Role == ToolMessageRole.String() {
+			name := toolNames[msg.ToolCallID]
+			if name == "" {
+				name = msg.Name
+			}
+			if name != "" {
+				e.Title += ": " + name
+			}
+			e.Content, e.Code = indentJSON(msg.Content), true
+		}
+		t.Entries = append(t.Entries, e)
+	}
+	if f.Status != nil {
+		t.Plans = f.Status.Plans
+		if f.Status.TODOs != nil {
+			t.TODOs = f.Status.TODOs.TODOs
+		}
+		t.Reasoning = f.Status.ReasoningLog
+		t.Iterations = f.Status.Iterations
+		t.Usage = f.Status.CumulativeUsage
+	}
+	return t
+}
+
+func roleTitle(role string) string {
+	switch role {
+	case ToolMessageRole.String():
+		return "Tool result"
+	case "":
+		return "Message"
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
+
+// indentJSON indents s when it is JSON, and returns it unchanged otherwise.
+func indentJSON(s string) string {
+	var buf bytes.Buffer
+	if json.Valid([]byte(s)) && json.Indent(&buf, []byte(s), "", "  ") == nil {
+		return buf.String()
+	}
+	return s
+}
+
+// fence returns a Markdown code fence longer than the backtick runs of s.
+func fence(s string) string {
+	f := "```"
+	for strings.Contains(s, f) {
+		f += "`"
+	}
+	return f
+}
+
+// ToMarkdown renders the fragment as a readable Markdown transcript: the
+// messages, with their reasoning, tool calls and tool results, followed by
+// the plans, TODOs and reasoning log of its status. It is meant to be shared,
+// e.g. attached to tickets.
+func (f Fragment) ToMarkdown() string {
+	t := newTranscript(f)
+	var sb strings.Builder
+	sb.WriteString("# Transcript\n")
+	for _, e := range t.Entries {
+		fmt.Fprintf(&sb, "\n### %s\n\n", e.Title)
+		if e.Hidden {
+			sb.WriteString("_Hidden from the LLM_\n\n")
+		}
+		if e.Reasoning != "" {
+			sb.WriteString("<details><summary>Reasoning</summary>\n\n")
+			for _, line := range strings.Split(e.Reasoning, "\n") {
+				fmt.Fprintf(&sb, "> %s\n", line)
+			}
+			sb.WriteString("\n</details>\n\n")
+		}
+		if e.Code {
+			fmt.Fprintf(&sb, "%s\n%s\n%s\n\n", fence(e.Content), e.Content, fence(e.Content))
+		} else if e.Content != "" {
+			fmt.Fprintf(&sb, "%s\n\n", e.Content)
+		}
+		for _, tc := range e.ToolCalls {
+			fmt.Fprintf(&sb, "**Tool call** `%s`\n\n%sjson\n%s\n%s\n\n", tc.Name, fence(tc.Arguments), tc.Arguments, fence(tc.Arguments))
+		}
+	}
+
+	if len(t.Plans) > 0 {
+		sb.WriteString("\n## Plans\n")
+		for i, p := range t.Plans {
+			fmt.Fprintf(&sb, "\n### Plan %d: %s\n\n", i+1, p.Plan.Description)
+			for j, subtask := range p.Plan.Subtasks {
+				fmt.Fprintf(&sb, "%d. %s\n", j+1, subtask)
+			}
+			for _, ts := range p.Tools {
+				fmt.Fprintf(&sb, "- `%s`: %s\n", ts.Name, ts.Result)
+			}
+		}
+	}
+	if len(t.TODOs) > 0 {
+		sb.WriteString("\n## TODOs\n\n")
+		for _, todo := range t.TODOs {
+			mark := " "
+			if todo.Completed {
+				mark = "x"
+			}
+			fmt.Fprintf(&sb, "- [%s] %s\n", mark, todo.Description)
+		}
+	}
+	if len(t.Reasoning) > 0 {
+		sb.WriteString("\n## Reasoning\n\n")
+		for i, r := range t.Reasoning {
+			fmt.Fprintf(&sb, "%d. %s\n", i+1, strings.ReplaceAll(r, "\n", " "))
+		}
+	}
+	if t.Iterations > 0 || t.Usage.TotalTokens > 0 {
+		fmt.Fprintf(&sb, "\n---\n\n%d iterations, %d tokens\n", t.Iterations, t.Usage.TotalTokens)
+	}
+	return sb.String()
+}
+
+var transcriptHTML = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Transcript</title>
+<style>
+body { font-family: sans-serif; max-width: 860px; margin: 2em auto; line-height: 1.5; }
+.message { border-left: 4px solid #ccc; padding: 0.2em 1em; margin: 1em 0; }
+.user { border-color: #4a90d9; } .assistant { border-color: #5cb85c; } .tool { border-color: #f0ad4e; }
+.hidden { opacity: 0.6; }
+.content { white-space: pre-wrap; }
+pre { background: #f6f8fa; padding: 0.8em; overflow-x: auto; }
+summary { color: #666; cursor: pointer; }
+</style>
+</head>
+<body>
+<h1>Transcript</h1>
+{{range .Entries}}<div class="message {{.Class}}{{if .Hidden}} hidden{{end}}">
+<h3>{{.Title}}</h3>
+{{if .Hidden}}<p><em>Hidden from the LLM</em></p>
+{{end}}{{if .Reasoning}}<details><summary>Reasoning</summary><div class="content">{{.Reasoning}}</div></details>
+{{end}}{{if .Code}}<pre>{{.Content}}</pre>
+{{else if .Content}}<div class="content">{{.Content}}</div>
+{{end}}{{range .ToolCalls}}<p><strong>Tool call</strong> <code>{{.Name}}</code></p>
+<pre>{{.Arguments}}</pre>
+{{end}}</div>
+{{end}}{{if .Plans}}<h2>Plans</h2>
+{{range $i, $p := .Plans}}<h3>Plan {{inc $i}}: {{$p.Plan.Description}}</h3>
+<ol>{{range $p.Plan.Subtasks}}<li>{{.}}</li>{{end}}</ol>
+{{if $p.Tools}}<ul>{{range $p.Tools}}<li><code>{{.Name}}</code>: {{.Result}}</li>{{end}}</ul>
+{{end}}{{end}}{{end}}{{if .TODOs}}<h2>TODOs</h2>
+<ul>{{range .TODOs}}<li><input type="checkbox" disabled{{if .Completed}} checked{{end}}> {{.Description}}</li>{{end}}</ul>
+{{end}}{{if .Reasoning}}<h2>Reasoning</h2>
+<ol>{{range .Reasoning}}<li class="content">{{.}}</li>{{end}}</ol>
+{{end}}{{if or .Iterations .Usage.TotalTokens}}<hr>
+<p>{{.Iterations}} iterations, {{.Usage.TotalTokens}} tokens</p>
+{{end}}</body>
+</html>
+`))
+
+// ToHTML renders the fragment as a standalone HTML page, see ToMarkdown.
+func (f Fragment) ToHTML() string {
+	t := newTranscript(f)
+	type htmlEntry struct {
+		transcriptEntry
+		Class string
+	}
+	entries := make([]htmlEntry, len(t.Entries))
+	for i, e := range t.Entries {
+		entries[i] = htmlEntry{e, f.Messages[i].Role}
+	}
+	var buf bytes.Buffer
+	err := transcriptHTML.Execute(&buf, struct {
+		transcript
+		Entries []htmlEntry
+	}{t, entries})
+	if err != nil {
+		// Only possible with a broken template
+		panic(fmt.Sprintf("failed to render transcript: %v", err))
+	}
+	return buf.String()
+}
@@ -0,0 +1,46 @@
+package cogito_test
+
+import (
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transcripts", func() {
+	var result Fragment
+
+	BeforeEach(func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.SetRunResult(weather, `{"city": "Rome", "temperature": 21}`)
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.HasTool("get_weather")).
+			ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("<think>The tool said 21.</think>It is <b>21°C</b> in Rome.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		var err error
+		result, err = ExecuteTools(mockLLM, f, WithTools(weather))
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("renders Markdown", func() {
+		md := result.ToMarkdown()
+		Expect(md).To(ContainSubstring("### User\n\nWhat's the weather in Rome?"))
+		Expect(md).To(ContainSubstring("**Tool call** `get_weather`\n\n```json\n{\n  \"city\": \"Rome\"\n}\n```"))
+		Expect(md).To(ContainSubstring("### Tool result: get_weather"))
+		Expect(md).To(ContainSubstring("\"temperature\": 21"))
+		Expect(md).To(ContainSubstring("> The tool said 21."))
+		Expect(md).To(ContainSubstring("## Reasoning"))
+	})
+
+	It("renders escaped HTML", func() {
+		page := result.ToHTML()
+		Expect(page).To(HavePrefix("<!DOCTYPE html>"))
+		Expect(page).To(ContainSubstring(`<div class="message tool">`))
+		Expect(page).To(ContainSubstring("<code>get_weather</code>"))
+		Expect(page).To(ContainSubstring("It is &lt;b&gt;21°C&lt;/b&gt; in Rome."))
+		Expect(page).ToNot(ContainSubstring("<b>21°C</b>"))
+	})
+})
@@ -0,0 +1,269 @@
+package cogito
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+// defaultMaxContinuations is the number of continuation requests sent for a
+// truncated reply, see WithMaxContinuations.
+const defaultMaxContinuations = 3
+
+// minContinuationOverlap is the shortest prefix of a continuation that is
+// dropped when it repeats the end of the truncated text.
+const minContinuationOverlap = 8
+
+type parameterGenerationKey struct{}
+
+// withParameterGeneration marks the requests of ctx as generating the
+// arguments of a tool, see WithAdaptiveMaxTokens.
+func withParameterGeneration(ctx context.Context) context.Context {
+	return context.WithValue(ctx, parameterGenerationKey{}, true)
+}
+
+func isParameterGeneration(ctx context.Context) bool {
+	v, _ := ctx.Value(parameterGenerationKey{}).(bool)
+	return v
+}
+
+// truncationLLM wraps an LLM, salvaging the replies cut off by max_tokens
+// (finish_reason "length"): continuation requests complete the content, or
+// the arguments of the last tool call, and are stitched back into the reply.
+// With an adaptive limit, truncated parameter-generation requests are first
+// retried with a raised max_tokens, kept for the following ones.
+type truncationLLM struct {
+	LLM
+	continuations int
+	limit         int // adaptive max_tokens ceiling, 0 when disabled
+	prompts       prompt.PromptMap
+	logger        Logger
+
+	mu        sync.Mutex
+	maxTokens int // max_tokens learned for parameter generation
+}
+
+func (t *truncationLLM) unwrap() LLM { return t.LLM }
+
+// prepare sets the learned max_tokens on the parameter-generation requests.
+func (t *truncationLLM) prepare(ctx context.Context, req openai.ChatCompletionRequest) openai.ChatCompletionRequest {
+	if t.limit <= 0 || !isParameterGeneration(ctx) {
+		return req
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.maxTokens > req.MaxTokens {
+		req.MaxTokens = t.maxTokens
+	}
+	return req
+}
+
+// raise doubles the max_tokens of a truncated parameter-generation request
+// (or, when unset, the tokens it completed) up to the adaptive limit. It
+// reports false when the request cannot be raised further.
+func (t *truncationLLM) raise(ctx context.Context, req *openai.ChatCompletionRequest, usage LLMUsage) bool {
+	if t.limit <= 0 || !isParameterGeneration(ctx) || (req.MaxTokens > 0 && req.MaxTokens >= t.limit) {
+		return false
+	}
+	next := 2 * max(req.MaxTokens, usage.CompletionTokens)
+	if next == 0 || next > t.limit {
+		next = t.limit
+	}
+	t.mu.Lock()
+	t.maxTokens = max(t.maxTokens, next)
+	t.mu.Unlock()
+	t.logger.Warn("Tool arguments truncated, raising max_tokens", "maxTokens", next)
+	req.MaxTokens = next
+	return true
+}
+
+func (t *truncationLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	req = t.prepare(ctx, req)
+	var total LLMUsage
+	for {
+		reply, usage, err := t.LLM.CreateChatCompletion(ctx, req)
+		total = addUsage(total, usage)
+		if err != nil || !truncated(reply) {
+			return reply, total, err
+		}
+		if t.raise(ctx, &req, usage) {
+			continue
+		}
+		choice := &reply.ChatCompletionResponse.Choices[0]
+		toolName, partial := truncatedText(choice.Message)
+		rest, finish, usage := t.continueText(ctx, req, toolName, partial)
+		total = addUsage(total, usage)
+		if n := len(choice.Message.ToolCalls); n > 0 {
+			choice.Message.ToolCalls[n-1].Function.Arguments += rest
+		} else {
+			choice.Message.Content += rest
+		}
+		choice.FinishReason = finish
+		return reply, total, nil
+	}
+}
+
+// continueText asks for the rest of partial, cut off in the reply to req,
+// until the model finishes or the continuations run out. toolName is the
+// tool whose arguments partial holds, or "" for content. It returns the rest
+// of the text and the finish reason of the last continuation.
+func (t *truncationLLM) continueText(ctx context.Context, req openai.ChatCompletionRequest, toolName, partial string) (string, openai.FinishReason, LLMUsage) {
+	var (
+		rest  strings.Builder
+		usage LLMUsage
+	)
+	finish := openai.FinishReasonLength
+	instructions, err := t.prompts.GetPrompt(prompt.PromptTruncationContinuationType).Render(struct{ ToolName string }{toolName})
+	if err != nil {
+		t.logger.Warn("Failed to render the truncation continuation prompt", "error", err)
+		return "", finish, usage
+	}
+	for i := 0; i < t.continuations && finish == openai.FinishReasonLength; i++ {
+		t.logger.Warn("Reply truncated by max_tokens, requesting a continuation", "tool", toolName, "continuation", i+1)
+		cont := openai.ChatCompletionRequest{
+			Model:       req.Model,
+			Temperature: req.Temperature,
+			MaxTokens:   req.MaxTokens,
+			Seed:        req.Seed,
+			Messages: append(req.Messages[:len(req.Messages):len(req.Messages)],
+				openai.ChatCompletionMessage{Role: AssistantMessageRole.String(), Content: partial + rest.String()},
+				openai.ChatCompletionMessage{Role: UserMessageRole.String(), Content: instructions}),
+		}
+		reply, u, err := t.LLM.CreateChatCompletion(ctx, cont)
+		usage = addUsage(usage, u)
+		if err != nil || len(reply.ChatCompletionResponse.Choices) == 0 {
+			t.logger.Warn("Continuation of a truncated reply failed", "error", err)
+			break
+		}
+		choice := reply.ChatCompletionResponse.Choices[0]
+		text := choice.Message.Content
+		if toolName != "" {
+			text = stripCodeFence(text)
+		}
+		rest.WriteString(dropOverlap(partial+rest.String(), text))
+		finish = choice.FinishReason
+	}
+	return rest.String(), finish, usage
+}
+
+// truncated reports whether reply was cut off by max_tokens.
+func truncated(reply LLMReply) bool {
+	choices := reply.ChatCompletionResponse.Choices
+	return len(choices) == 1 && choices[0].FinishReason == openai.FinishReasonLength
+}
+
+// truncatedText returns the text cut off in msg: the arguments of its last
+// tool call, with the name of the tool, or its content.
+func truncatedText(msg openai.ChatCompletionMessage) (toolName, partial string) {
+	if n := len(msg.ToolCalls); n > 0 {
+		return msg.ToolCalls[n-1].Function.Name, msg.ToolCalls[n-1].Function.Arguments
+	}
+	return "", msg.Content
+}
+
+// stripCodeFence removes the markdown code fence around s, if any.
+func stripCodeFence(s string) string {
+	trimmed := strings.TrimSpace(s)
+	if !strings.HasPrefix(trimmed, "```") {
+		return s
+	}
+	trimmed = strings.TrimPrefix(trimmed, "```")
+	if i := strings.IndexByte(trimmed, '\n'); i >= 0 {
+		trimmed = trimmed[i+1:]
+	}
+	return strings.TrimSuffix(strings.TrimSpace(trimmed), "```")
+}
+
+// dropOverlap removes the start of continuation repeating the end of text,
+// as models often restart a few words back.
+func dropOverlap(text, continuation string) string {
+	for n := min(len(text), len(continuation)); n >= minContinuationOverlap; n-- {
+		if strings.HasSuffix(text, continuation[:n]) {
+			return continuation[n:]
+		}
+	}
+	return continuation
+}
+
+// truncationStreamingLLM preserves StreamingLLM. Streamed replies cannot be
+// retried once forwarded, so they are only continued: the continuations are
+// sent as further content or tool call deltas before the done event.
+type truncationStreamingLLM struct {
+	*truncationLLM
+	streaming StreamingLLM
+}
+
+func (t *truncationStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	req = t.prepare(ctx, req)
+	in, err := t.streaming.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan StreamEvent)
+	go func() {
+		defer close(out)
+		var (
+			content strings.Builder
+			args    = map[int]*strings.Builder{}
+			names   = map[int]string{}
+			last    = -1
+		)
+		for ev := range in {
+			switch ev.Type {
+			case StreamEventContent:
+				content.WriteString(ev.Content)
+			case StreamEventToolCall:
+				if args[ev.ToolCallIndex] == nil {
+					args[ev.ToolCallIndex] = &strings.Builder{}
+				}
+				args[ev.ToolCallIndex].WriteString(ev.ToolArgs)
+				if ev.ToolName != "" {
+					names[ev.ToolCallIndex] = ev.ToolName
+				}
+				last = ev.ToolCallIndex
+			case StreamEventDone:
+				if ev.FinishReason == string(openai.FinishReasonLength) {
+					t.raise(ctx, &req, ev.Usage)
+					toolName, partial := "", content.String()
+					if last >= 0 {
+						toolName, partial = names[last], args[last].String()
+					}
+					rest, finish, usage := t.continueText(ctx, req, toolName, partial)
+					if rest != "" {
+						delta := StreamEvent{Type: StreamEventContent, Content: rest}
+						if last >= 0 {
+							delta = StreamEvent{Type: StreamEventToolCall, ToolCallIndex: last, ToolArgs: rest}
+						}
+						out <- delta
+					}
+					ev.FinishReason = string(finish)
+					ev.Usage = addUsage(ev.Usage, usage)
+				}
+			}
+			out <- ev
+		}
+	}()
+	return out, nil
+}
+
+// withTruncationSalvage wraps llm to salvage truncated replies, as configured
+// in o. An LLM already salvaging them is returned as is.
+func withTruncationSalvage(llm LLM, o *Options) LLM {
+	if o.maxContinuations <= 0 && o.adaptiveMaxTokens <= 0 {
+		return llm
+	}
+	if _, ok := unwrapLLM[*truncationLLM](llm); ok {
+		return llm
+	}
+	if _, ok := unwrapLLM[*truncationStreamingLLM](llm); ok {
+		return llm
+	}
+	t := &truncationLLM{LLM: llm, continuations: o.maxContinuations, limit: o.adaptiveMaxTokens, prompts: o.prompts, logger: o.logger}
+	if s, ok := llm.(StreamingLLM); ok {
+		return &truncationStreamingLLM{truncationLLM: t, streaming: s}
+	}
+	return t
+}
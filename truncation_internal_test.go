@@ -0,0 +1,142 @@
+package cogito
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// choicesLLM replies with the choices of replies in turn, recording
+// requests.
+type choicesLLM struct {
+	fakeLLM
+	replies  []openai.ChatCompletionChoice
+	requests []openai.ChatCompletionRequest
+}
+
+func (s *choicesLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	s.requests = append(s.requests, req)
+	choice := s.replies[min(len(s.requests), len(s.replies))-1]
+	return LLMReply{ChatCompletionResponse: openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{choice}}},
+		LLMUsage{CompletionTokens: 100, TotalTokens: 150}, nil
+}
+
+func truncatedToolCall(args string) openai.ChatCompletionChoice {
+	return openai.ChatCompletionChoice{
+		FinishReason: openai.FinishReasonLength,
+		Message: openai.ChatCompletionMessage{Role: "assistant", ToolCalls: []openai.ToolCall{{
+			ID: "1", Type: openai.ToolTypeFunction, Function: openai.FunctionCall{Name: "write", Arguments: args},
+		}}},
+	}
+}
+
+func textChoice(content string, finish openai.FinishReason) openai.ChatCompletionChoice {
+	return openai.ChatCompletionChoice{FinishReason: finish, Message: openai.ChatCompletionMessage{Role: "assistant", Content: content}}
+}
+
+func TestTruncationLLMContinuesToolArguments(t *testing.T) {
+	inner := &choicesLLM{replies: []openai.ChatCompletionChoice{
+		truncatedToolCall(`{"title": "Rome", "body": "Rome is the capital`),
+		textChoice("```json\nthe capital of Italy", openai.FinishReasonLength),
+		textChoice(`."}`, openai.FinishReasonStop),
+	}}
+	o := defaultOptions()
+	llm := withTruncationSalvage(inner, o)
+	if withTruncationSalvage(llm, o) != llm {
+		t.Error("wrapped twice")
+	}
+
+	reply, usage, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "Write about Rome"}},
+		Tools:    []openai.Tool{{Type: openai.ToolTypeFunction, Function: &openai.FunctionDefinition{Name: "write"}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := reply.ChatCompletionResponse.Choices[0].Message.ToolCalls[0].Function.Arguments
+	var decoded map[string]string
+	if err := json.Unmarshal([]byte(args), &decoded); err != nil || decoded["body"] != "Rome is the capital of Italy." {
+		t.Fatalf("arguments = %s (%v)", args, err)
+	}
+	if reply.ChatCompletionResponse.Choices[0].FinishReason != openai.FinishReasonStop || usage.TotalTokens != 450 {
+		t.Errorf("finish = %s, usage = %+v", reply.ChatCompletionResponse.Choices[0].FinishReason, usage)
+	}
+
+	cont := inner.requests[2]
+	if cont.Tools != nil || len(cont.Messages) != 3 || cont.Messages[1].Role != "assistant" ||
+		cont.Messages[1].Content != `{"title": "Rome", "body": "Rome is the capital of Italy` || !strings.Contains(cont.Messages[2].Content, `"write"`) {
+		t.Errorf("continuation = %+v", cont)
+	}
+}
+
+func TestTruncationLLMContinuesContent(t *testing.T) {
+	inner := &choicesLLM{replies: []openai.ChatCompletionChoice{
+		textChoice("Rome is", openai.FinishReasonLength),
+		textChoice(" the capital of Italy.", openai.FinishReasonStop),
+	}}
+	llm := withTruncationSalvage(inner, defaultOptions())
+	reply, _, err := llm.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "Capital of Italy?"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reply.ChatCompletionResponse.Choices[0].Message.Content; got != "Rome is the capital of Italy." {
+		t.Errorf("content = %q", got)
+	}
+
+	// Disabled, replies are returned as they come
+	o := defaultOptions()
+	WithMaxContinuations(0)(o)
+	if withTruncationSalvage(inner, o) != inner {
+		t.Error("wrapped while disabled")
+	}
+}
+
+func TestTruncationLLMRaisesMaxTokens(t *testing.T) {
+	inner := &choicesLLM{replies: []openai.ChatCompletionChoice{
+		truncatedToolCall(`{"title": "Rome", "body": "Rome is`),
+		truncatedToolCall(`{"title": "Rome", "body": "Rome is the capital of Italy."}`),
+	}}
+	inner.replies[1].FinishReason = openai.FinishReasonToolCalls
+	o := defaultOptions()
+	WithAdaptiveMaxTokens(1000)(o)
+	llm := withTruncationSalvage(inner, o)
+
+	req := openai.ChatCompletionRequest{Messages: []openai.ChatCompletionMessage{{Role: "user", Content: "Write about Rome"}}}
+	ctx := withParameterGeneration(context.Background())
+	reply, _, err := llm.CreateChatCompletion(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(reply.ChatCompletionResponse.Choices[0].Message.ToolCalls[0].Function.Arguments, `Italy."}`) {
+		t.Errorf("reply = %+v", reply)
+	}
+	if len(inner.requests) != 2 || inner.requests[0].MaxTokens != 0 || inner.requests[1].MaxTokens != 200 {
+		t.Fatalf("requests = %+v", inner.requests)
+	}
+
+	// The raised limit is kept for parameter generation only
+	if _, _, err := llm.CreateChatCompletion(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := llm.CreateChatCompletion(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if inner.requests[2].MaxTokens != 200 || inner.requests[3].MaxTokens != 0 {
+		t.Errorf("max tokens = %d, %d", inner.requests[2].MaxTokens, inner.requests[3].MaxTokens)
+	}
+}
+
+func TestDropOverlap(t *testing.T) {
+	if got := dropOverlap("Rome is the capital", "the capital of Italy"); got != " of Italy" {
+		t.Errorf("overlap = %q", got)
+	}
+	// Short overlaps are kept, they are likely legitimate
+	if got := dropOverlap(`"a": "b`, `b"}`); got != `b"}` {
+		t.Errorf("short overlap = %q", got)
+	}
+}
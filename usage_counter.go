@@ -14,6 +14,7 @@ type usageCounter struct {
 	prompt     atomic.Int64
 	completion atomic.Int64
 	total      atomic.Int64
+	calls      atomic.Int64 // LLM calls made, successful or not
 }
 
 func (c *usageCounter) add(u LLMUsage) {
@@ -40,6 +41,7 @@ type countingLLM struct {
 }
 
 func (c *countingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (LLMReply, LLMUsage, error) {
+	c.counter.calls.Add(1)
 	reply, usage, err := c.LLM.CreateChatCompletion(ctx, req)
 	if err == nil {
 		c.counter.add(usage)
@@ -52,6 +54,7 @@ func (c *countingLLM) CreateChatCompletion(ctx context.Context, req openai.ChatC
 // call. If a future Ask returned a fragment carrying a stale LastUsage, this
 // would re-add it — the assumption is that Ask always sets LastUsage fresh.
 func (c *countingLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
+	c.counter.calls.Add(1)
 	res, err := c.LLM.Ask(ctx, f)
 	if err == nil && res.Status != nil {
 		c.counter.add(res.Status.LastUsage)
@@ -59,6 +62,8 @@ func (c *countingLLM) Ask(ctx context.Context, f Fragment) (Fragment, error) {
 	return res, err
 }
 
+func (c *countingLLM) unwrap() LLM { return c.LLM }
+
 // countingStreamingLLM preserves StreamingLLM so wrapping does not disable the
 // streaming code path for callers that use it. Usage is accumulated from the
 // StreamEventDone event's Usage field.
@@ -74,6 +79,7 @@ type countingStreamingLLM struct {
 }
 
 func (c *countingStreamingLLM) CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (<-chan StreamEvent, error) {
+	c.counter.calls.Add(1)
 	in, err := c.streaming.CreateChatCompletionStream(ctx, req)
 	if err != nil {
 		return nil, err
@@ -107,3 +113,12 @@ func newCountingLLM(llm LLM, counter *usageCounter) LLM {
 	}
 	return &base
 }
+
+// addUsage sums two usages.
+func addUsage(a, b LLMUsage) LLMUsage {
+	return LLMUsage{
+		PromptTokens:     a.PromptTokens + b.PromptTokens,
+		CompletionTokens: a.CompletionTokens + b.CompletionTokens,
+		TotalTokens:      a.TotalTokens + b.TotalTokens,
+	}
+}
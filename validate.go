@@ -0,0 +1,98 @@
+package cogito
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ConfigError is an invalid combination of options. ExecuteTools and
+// ExecutePlan return it, wrapped in a joined error when there are several,
+// before making any LLM call.
+type ConfigError struct {
+	Options []string // the options involved
+	Problem string
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration (%s): %s", strings.Join(e.Options, ", "), e.Problem)
+}
+
+// ValidateOptions checks opts for invalid and ineffective combinations, as
+// ExecuteTools and ExecutePlan do at their start. Invalid combinations are
+// returned as ConfigErrors; options that would be ignored are logged as
+// warnings.
+func ValidateOptions(opts ...Option) error {
+	o := defaultOptions()
+	o.Apply(opts...)
+	return o.Validate()
+}
+
+// Validate returns the invalid combinations of o as ConfigErrors, and logs
+// warnings for the options that have no effect in combination with the
+// others. See ValidateOptions.
+func (o *Options) Validate() error {
+	var errs []error
+	invalid := func(problem string, options ...string) {
+		errs = append(errs, &ConfigError{Options: options, Problem: problem})
+	}
+	warn := func(msg string, options ...string) {
+		o.logger.Warn("Ineffective options: "+msg, "options", strings.Join(options, ", "))
+	}
+
+	if o.forceReasoning && !o.sinkState {
+		invalid("force reasoning is enabled but sink state is not enabled", "WithForceReasoning", "DisableSinkState")
+	}
+	if o.strictGuidelines && len(o.guidelines) == 0 && !o.guidedTools {
+		invalid("strict guidelines without guidelines leave no tool to call", "EnableStrictGuidelines", "WithGuidelines")
+	}
+	if o.minToolConfidence < 0 || o.minToolConfidence > 1 {
+		invalid(fmt.Sprintf("the minimum tool confidence must be between 0 and 1, got %v", o.minToolConfidence), "WithMinToolConfidence")
+	}
+	if o.maxRetries < 1 {
+		invalid(fmt.Sprintf("at least 1 LLM call attempt is needed, got %d", o.maxRetries), "WithMaxRetries")
+	}
+	if o.maxAttempts < 1 {
+		invalid(fmt.Sprintf("at least 1 tool execution attempt is needed, got %d", o.maxAttempts), "WithMaxAttempts")
+	}
+	if o.datasetRecorder != nil && o.datasetRecorder.format != DatasetFormatOpenAI && o.datasetRecorder.format != DatasetFormatShareGPT {
+		invalid(fmt.Sprintf("unknown dataset format %q", o.datasetRecorder.format), "WithDatasetRecorder")
+	}
+	for _, server := range o.mcpServers {
+		if err := server.validate(); err != nil {
+			invalid(fmt.Sprintf("MCP server %q %v", server.Name, err), "WithMCPServers")
+		}
+	}
+	if o.compactionThreshold > 0 && o.compactionKeepMessages < 1 {
+		invalid("compaction must keep at least 1 message", "WithCompactionThreshold", "WithCompactionKeepMessages")
+	}
+
+	if o.tenantErr != nil {
+		errs = append(errs, o.tenantErr)
+	}
+
+	if len(o.startWithAction) > 0 && o.autoPlan {
+		warn("the start actions are not run when the task is planned", "WithStartWithAction", "EnableAutoPlan")
+	}
+	if o.planReEvaluator && !o.autoPlan {
+		warn("plans are only re-evaluated with automatic planning", "EnableAutoPlanReEvaluator", "EnableAutoPlan")
+	}
+	if o.localeFormatting && o.locale == "" {
+		warn("locale formatting needs a locale", "EnableLocaleFormatting", "WithLocale")
+	}
+	if o.mcpPrompts && len(o.mcpSessions) == 0 {
+		warn("MCP prompts need MCP sessions", "EnableMCPPrompts", "WithMCPs")
+	}
+	if o.autoImproveReviewerLLM != nil && o.autoImproveState == nil {
+		warn("the auto-improve reviewer needs an auto-improve state", "WithAutoImproveReviewerLLM", "WithAutoImproveState")
+	}
+	if !o.enableAgentSpawning && (o.agentLLM != nil || len(o.agentDefinitions) > 0 || o.agentLLMFactory != nil || o.agentDispatcher != nil) {
+		warn("sub-agent options need agent spawning", "WithAgentLLM/WithAgentDefinitions/WithAgentLLMFactory/WithAgentDispatcher", "EnableAgentSpawning")
+	}
+
+	return errors.Join(errs...)
+}
+
+// optionsValidated marks options validated by the run they come from, so
+// its plans, subtasks and sub-agents do not validate them again.
+func optionsValidated(o *Options) { o.validated = true }
@@ -0,0 +1,48 @@
+package cogito
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateOptionsErrors(t *testing.T) {
+	err := ValidateOptions(WithForceReasoning(), DisableSinkState, WithMinToolConfidence(2))
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("error = %v, want a ConfigError", err)
+	}
+	for _, want := range []string{"sink state is not enabled", "between 0 and 1"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %q", err, want)
+		}
+	}
+
+	if err := ValidateOptions(WithForceReasoning(), WithMinToolConfidence(0.7), WithIterations(3)); err != nil {
+		t.Errorf("valid options rejected: %v", err)
+	}
+}
+
+func TestValidateOptionsWarnings(t *testing.T) {
+	logger := &recordingLogger{}
+	err := ValidateOptions(WithLogger(logger), WithStartWithAction(&ToolChoice{Name: "search"}), EnableAutoPlan, EnableLocaleFormatting)
+	if err != nil {
+		t.Fatalf("ineffective options should only warn, got %v", err)
+	}
+	logged := strings.Join(logger.lines, "\n")
+	for _, want := range []string{"WithStartWithAction, EnableAutoPlan", "EnableLocaleFormatting, WithLocale"} {
+		if !strings.Contains(logged, want) {
+			t.Errorf("no warning about %s in:\n%s", want, logged)
+		}
+	}
+}
+
+func TestExecuteToolsRejectsInvalidOptions(t *testing.T) {
+	// The LLM is never called: the options are rejected first
+	var llm struct{ LLM }
+	_, err := ExecuteTools(llm, NewEmptyFragment().AddMessage(UserMessageRole, "hi"), EnableStrictGuidelines)
+	var cfgErr *ConfigError
+	if !errors.As(err, &cfgErr) || cfgErr.Options[0] != "EnableStrictGuidelines" {
+		t.Fatalf("error = %v, want the strict guidelines ConfigError", err)
+	}
+}
@@ -0,0 +1,182 @@
+package cogito
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/mudler/cogito/prompt"
+	"github.com/sashabaranov/go-openai"
+)
+
+type varsKey struct{}
+
+// Vars is the variable store of a run, see WithVars. Guideline conditions
+// and actions, and the string arguments of tool calls, can reference its
+// variables as {{.vars.name}}: they are replaced with their values, so
+// deterministic data is threaded through the run without relying on the LLM
+// to copy it. Tools update the variables through VarsFromContext.
+//
+// It is safe for concurrent use.
+type Vars struct {
+	mu     sync.RWMutex
+	values map[string]any
+}
+
+// NewVars returns a store with a copy of values.
+func NewVars(values map[string]any) *Vars {
+	v := &Vars{values: map[string]any{}}
+	maps.Copy(v.values, values)
+	return v
+}
+
+// Get returns the value of the variable name.
+func (v *Vars) Get(name string) (any, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, ok := v.values[name]
+	return value, ok
+}
+
+// Set sets the variable name to value.
+func (v *Vars) Set(name string, value any) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.values[name] = value
+}
+
+// Delete removes the variable name.
+func (v *Vars) Delete(name string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.values, name)
+}
+
+// Values returns a copy of the variables.
+func (v *Vars) Values() map[string]any {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return maps.Clone(v.values)
+}
+
+// VarsFromContext returns the variable store of the run a tool executes
+// for, nil outside of a run with WithVars or WithVarStore.
+func VarsFromContext(ctx context.Context) *Vars {
+	v, _ := ctx.Value(varsKey{}).(*Vars)
+	return v
+}
+
+// render replaces the variable references of text. Text that cannot be
+// rendered, like a reference to an unknown variable, is returned unchanged.
+func (v *Vars) render(text string, logger Logger) string {
+	if v == nil || !strings.Contains(text, "{{") {
+		return text
+	}
+	tmpl, err := template.New("vars").Option("missingkey=error").Parse(text)
+	if err != nil {
+		logger.Warn("Failed to parse variable references", "text", text, "error", err)
+		return text
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, map[string]any{"vars": v.Values()}); err != nil {
+		logger.Warn("Failed to render variable references", "text", text, "error", err)
+		return text
+	}
+	return sb.String()
+}
+
+// varReference matches a reference to a variable, {{.vars.name}}.
+var varReference = regexp.MustCompile(`\{\{\s*\.vars\.([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// substitute replaces the references to variables of text with their
+// values. Unlike render, it does not run a template: tool arguments are
+// written by the LLM, which must not be able to run template actions.
+// References to unknown variables are left unchanged.
+func (v *Vars) substitute(text string, values map[string]any) string {
+	if !strings.Contains(text, "{{") {
+		return text
+	}
+	return varReference.ReplaceAllStringFunc(text, func(ref string) string {
+		value, ok := values[varReference.FindStringSubmatch(ref)[1]]
+		if !ok {
+			return ref
+		}
+		return fmt.Sprint(value)
+	})
+}
+
+// renderArguments returns args with the variable references of their
+// string values, nested ones included, replaced.
+func (v *Vars) renderArguments(args map[string]any) map[string]any {
+	if v == nil {
+		return args
+	}
+	rendered, _ := v.renderValue(args, v.Values()).(map[string]any)
+	return rendered
+}
+
+func (v *Vars) renderValue(value any, values map[string]any) any {
+	switch value := value.(type) {
+	case string:
+		return v.substitute(value, values)
+	case map[string]any:
+		rendered := make(map[string]any, len(value))
+		for k, item := range value {
+			rendered[k] = v.renderValue(item, values)
+		}
+		return rendered
+	case []any:
+		rendered := make([]any, len(value))
+		for i, item := range value {
+			rendered[i] = v.renderValue(item, values)
+		}
+		return rendered
+	}
+	return value
+}
+
+// renderGuidelines returns guidelines with the variable references of their
+// conditions and actions replaced.
+func (v *Vars) renderGuidelines(guidelines Guidelines, logger Logger) Guidelines {
+	if v == nil {
+		return guidelines
+	}
+	for i := range guidelines {
+		guidelines[i].Condition = v.render(guidelines[i].Condition, logger)
+		guidelines[i].Action = v.render(guidelines[i].Action, logger)
+	}
+	return guidelines
+}
+
+// varsMessage tells the LLM which variables its tool arguments can
+// reference, false when there are none.
+func varsMessage(o *Options) (openai.ChatCompletionMessage, bool, error) {
+	values := o.vars.Values()
+	if len(values) == 0 {
+		return openai.ChatCompletionMessage{}, false, nil
+	}
+	type variable struct {
+		Name  string
+		Value string
+	}
+	var list []variable
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		value := fmt.Sprint(values[name])
+		if len(value) > maxSummaryValueLength {
+			value = value[:maxSummaryValueLength] + "..."
+		}
+		list = append(list, variable{Name: name, Value: value})
+	}
+	content, err := o.prompts.GetPrompt(prompt.PromptVarsType).Render(struct {
+		Vars []variable
+	}{Vars: list})
+	if err != nil {
+		return openai.ChatCompletionMessage{}, false, fmt.Errorf("failed to render variables prompt: %w", err)
+	}
+	return openai.ChatCompletionMessage{Role: SystemMessageRole.String(), Content: content}, true, nil
+}
@@ -0,0 +1,107 @@
+package cogito_test
+
+import (
+	"context"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type profileArgs struct {
+	Email string `json:"email"`
+}
+
+// profileRunner looks up the city of a user and stores it in the run
+// variables.
+type profileRunner struct{ emails []string }
+
+func (r *profileRunner) Run(args profileArgs) (string, any, error) {
+	return r.RunWithContext(context.Background(), args)
+}
+
+func (r *profileRunner) RunWithContext(ctx context.Context, args profileArgs) (string, any, error) {
+	r.emails = append(r.emails, args.Email)
+	VarsFromContext(ctx).Set("user_city", "Rome")
+	return "The user lives in Rome", nil, nil
+}
+
+var _ = Describe("Run variables", func() {
+	var mockLLM *cogitotest.MockLLM
+	var fragment Fragment
+
+	BeforeEach(func() {
+		mockLLM = cogitotest.NewMockLLM()
+		fragment = NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather where I live?")
+	})
+
+	It("replaces variable references in tool arguments and lets tools update them", func() {
+		profile := &profileRunner{}
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather")
+		cogitotest.SetRunResult(weather, "Sunny")
+
+		mockLLM.Expect("lists the variables", cogitotest.SystemPromptContains("- user_email: ada@example.com"))
+		mockLLM.AddCreateChatCompletionFunction("get_profile", `{"email": "{{.vars.user_email}}"}`)
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "{{.vars.user_city}}"}`)
+		mockLLM.SetAskResponse("It's sunny in Rome.")
+
+		vars := NewVars(map[string]any{"user_email": "ada@example.com"})
+		result, err := ExecuteTools(mockLLM, fragment, WithIterations(2), WithVarStore(vars),
+			WithTools(NewToolDefinition(profile, profileArgs{}, "get_profile", "Get the user profile"), weather))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mockLLM.Verify()).To(Succeed())
+
+		Expect(profile.emails).To(Equal([]string{"ada@example.com"}))
+		calls := cogitotest.GetMockTool(weather).Calls()
+		Expect(calls).To(HaveLen(1))
+		Expect(calls[0]).To(HaveKeyWithValue("city", "Rome"))
+		Expect(vars.Values()).To(HaveKeyWithValue("user_city", "Rome"))
+		// The conversation keeps the call as the LLM wrote it
+		Expect(result.Status.ToolResults[1].ToolArguments.Arguments).To(HaveKeyWithValue("city", "{{.vars.user_city}}"))
+	})
+
+	It("leaves references to unknown variables unchanged", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather")
+		cogitotest.SetRunResult(weather, "Sunny")
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "{{.vars.missing}}"}`)
+		mockLLM.SetAskResponse("Unknown city.")
+
+		_, err := ExecuteTools(mockLLM, fragment, WithIterations(1), WithVars(map[string]any{"user_email": "ada@example.com"}),
+			WithTools(weather))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(weather).Calls()[0]).To(HaveKeyWithValue("city", "{{.vars.missing}}"))
+	})
+
+	It("only replaces plain variable references in tool arguments", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather")
+		cogitotest.SetRunResult(weather, "Sunny")
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "{{ .vars.user_city }}, {{range $k, $v := .vars}}{{$v}}{{end}}"}`)
+		mockLLM.SetAskResponse("Sunny.")
+
+		_, err := ExecuteTools(mockLLM, fragment, WithIterations(1), WithVars(map[string]any{"user_city": "Rome", "token": "secret"}),
+			WithTools(weather))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cogitotest.GetMockTool(weather).Calls()[0]).To(HaveKeyWithValue("city", "Rome, {{range $k, $v := .vars}}{{$v}}{{end}}"))
+	})
+
+	It("replaces variable references in guidelines", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather")
+		cogitotest.SetRunResult(weather, "Sunny")
+
+		mockLLM.Expect("renders the guideline", cogitotest.SystemPromptContains("then Use the weather tool for Rome"))
+		mockLLM.SetAskResponse("The guideline is relevant.")
+		mockLLM.AddCreateChatCompletionFunction("json", `{"guidelines": [1]}`)
+		mockLLM.AddCreateChatCompletionFunction("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("It's sunny in Rome.")
+
+		_, err := ExecuteTools(mockLLM, fragment, WithIterations(1), WithVars(map[string]any{"user_city": "Rome"}),
+			WithGuidelines(Guideline{
+				Condition: "The user asks about the weather",
+				Action:    "Use the weather tool for {{.vars.user_city}}",
+				Tools:     Tools{weather},
+			}))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mockLLM.Verify()).To(Succeed())
+	})
+})
@@ -0,0 +1,214 @@
+package cogito
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+	"github.com/sashabaranov/go-openai/jsonschema"
+)
+
+// WaitTool lets the LLM suspend the run until an external event fires or a
+// delay elapses, instead of polling. When the LLM calls it, ExecuteTools
+// stops and returns a *Suspension: persist it, and resume the run with
+// Suspension.Resume or a Trigger.
+type WaitTool struct {
+	// Events are the events the agent can wait for, with their description.
+	// Empty allows any event name.
+	Events map[string]string
+}
+
+var _ ToolDefinitionInterface = (*WaitTool)(nil)
+
+// NewWaitTool returns a WaitTool for the given events (name to description).
+func NewWaitTool(events map[string]string) *WaitTool {
+	return &WaitTool{Events: events}
+}
+
+func (w *WaitTool) Tool() openai.Tool {
+	description := "Suspend the task until an external event happens or some time has passed, instead of checking repeatedly. " +
+		"Set 'event' to wait for an event, or 'seconds' to sleep."
+	event := jsonschema.Definition{Type: jsonschema.String, Description: "The event to wait for"}
+	if len(w.Events) > 0 {
+		names := make([]string, 0, len(w.Events))
+		for name := range w.Events {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		event.Enum = names
+		description += " Events:"
+		for _, name := range names {
+			description += fmt.Sprintf("\n- %s: %s", name, w.Events[name])
+		}
+	}
+
+	return openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        "wait_for",
+			Description: description,
+			Parameters: jsonschema.Definition{
+				Type: jsonschema.Object,
+				Properties: map[string]jsonschema.Definition{
+					"event":   event,
+					"seconds": {Type: jsonschema.Number, Description: "How long to sleep, in seconds"},
+					"reason":  {Type: jsonschema.String, Description: "What the task is waiting for and why"},
+				},
+			},
+		},
+	}
+}
+
+// Execute is not called by ExecuteTools, which suspends the run instead.
+func (w *WaitTool) Execute(args map[string]any) (string, any, error) {
+	return "", nil, fmt.Errorf("the wait tool suspends the run and is not executed")
+}
+
+// Suspension is returned by ExecuteTools when the LLM calls a WaitTool. It
+// can be serialized to JSON and resumed later, from another process.
+type Suspension struct {
+	// Event is the event the run waits for, empty for a sleep.
+	Event string `json:"event,omitempty"`
+	// Until is when a sleep ends, zero when waiting for an event.
+	Until  time.Time `json:"until,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	// State is the conversation before the wait call, and the call itself.
+	State SessionState `json:"state"`
+}
+
+func (s *Suspension) Error() string {
+	if s.Event != "" {
+		return fmt.Sprintf("run suspended until event %q", s.Event)
+	}
+	return fmt.Sprintf("run suspended until %s", s.Until.Format(time.RFC3339))
+}
+
+// Resume continues the suspended run, with payload (e.g. the event data)
+// given to the LLM as the result of the wait call.
+func (s *Suspension) Resume(llm LLM, payload string, opts ...Option) (Fragment, error) {
+	return ExecuteTools(llm, s.resumeFragment(payload), opts...)
+}
+
+func (s *Suspension) resumeFragment(payload string) Fragment {
+	f := s.State.Fragment
+	f.Messages = slices.Clone(f.Messages)
+
+	choice := s.State.ToolChoice
+	if choice == nil {
+		choice = &ToolChoice{Name: "wait_for"}
+	}
+	id := choice.ID
+	if id == "" {
+		id = uuid.New().String()
+	}
+	args, _ := json.Marshal(choice.Arguments)
+
+	result := payload
+	switch {
+	case s.Event != "" && payload != "":
+		result = fmt.Sprintf("The event %q happened: %s", s.Event, payload)
+	case s.Event != "":
+		result = fmt.Sprintf("The event %q happened.", s.Event)
+	case payload == "":
+		result = "The wait is over."
+	}
+
+	f.Messages = append(f.Messages, openai.ChatCompletionMessage{
+		Role: AssistantMessageRole.String(),
+		ToolCalls: []openai.ToolCall{{
+			ID:       id,
+			Type:     openai.ToolTypeFunction,
+			Function: openai.FunctionCall{Name: choice.Name, Arguments: string(args)},
+		}},
+	})
+	return f.AddToolMessage(result, id)
+}
+
+// suspend builds the Suspension for a call to the wait tool.
+func (w *WaitTool) suspend(f Fragment, choice *ToolChoice) *Suspension {
+	s := &Suspension{State: SessionState{ToolChoice: choice, Fragment: f}}
+	s.Event, _ = choice.Arguments["event"].(string)
+	s.Reason, _ = choice.Arguments["reason"].(string)
+	if seconds, ok := choice.Arguments["seconds"].(float64); ok && s.Event == "" {
+		s.Until = time.Now().Add(time.Duration(seconds * float64(time.Second)))
+	}
+	return s
+}
+
+// Resumed is the outcome of resuming a suspended run.
+type Resumed struct {
+	Suspension *Suspension
+	Fragment   Fragment
+	Err        error
+}
+
+// Trigger resumes suspended runs when the events they wait for fire, or
+// when their sleep is over. Runs that suspend again are kept for the next
+// event. Safe for concurrent use.
+type Trigger struct {
+	llm  LLM
+	opts []Option
+
+	mu        sync.Mutex
+	suspended []*Suspension
+}
+
+// NewTrigger returns a Trigger resuming runs with llm and opts, which should
+// include the tools of the original run.
+func NewTrigger(llm LLM, opts ...Option) *Trigger {
+	return &Trigger{llm: llm, opts: opts}
+}
+
+// Add registers a suspended run, e.g. one restored from storage.
+func (t *Trigger) Add(s *Suspension) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.suspended = append(t.suspended, s)
+}
+
+// Pending returns the runs still suspended, e.g. to persist them.
+func (t *Trigger) Pending() []*Suspension {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.suspended)
+}
+
+// Fire resumes, one after the other, every run waiting for event, giving
+// them payload.
+func (t *Trigger) Fire(event, payload string) []Resumed {
+	return t.resume(func(s *Suspension) bool { return s.Event != "" && s.Event == event }, payload)
+}
+
+// FireDue resumes the runs whose sleep is over at now.
+func (t *Trigger) FireDue(now time.Time) []Resumed {
+	return t.resume(func(s *Suspension) bool { return s.Event == "" && !s.Until.After(now) }, "")
+}
+
+func (t *Trigger) resume(match func(*Suspension) bool, payload string) []Resumed {
+	t.mu.Lock()
+	var due []*Suspension
+	t.suspended = slices.DeleteFunc(t.suspended, func(s *Suspension) bool {
+		if match(s) {
+			due = append(due, s)
+			return true
+		}
+		return false
+	})
+	t.mu.Unlock()
+
+	results := make([]Resumed, 0, len(due))
+	for _, s := range due {
+		f, err := s.Resume(t.llm, payload, t.opts...)
+		var next *Suspension
+		if errors.As(err, &next) {
+			t.Add(next)
+		}
+		results = append(results, Resumed{Suspension: s, Fragment: f, Err: err})
+	}
+	return results
+}
@@ -0,0 +1,25 @@
+;; echo returns its arguments as its output: {"result": "..."} is returned
+;; as the result of the tool, {"error": "..."} as its error.
+(module
+  (memory (export "memory") 1)
+  (global $next (mut i32) (i32.const 1024))
+  (data (i32.const 0) "{\"name\":\"echo\",\"description\":\"Return the arguments as the output\",\"parameters\":{\"type\":\"object\",\"properties\":{\"result\":{\"type\":\"string\"},\"error\":{\"type\":\"string\"}}}}")
+
+  (func (export "alloc") (param $size i32) (result i32)
+    global.get $next
+    global.get $next
+    local.get $size
+    i32.add
+    global.set $next)
+
+  (func (export "describe") (result i64)
+    i64.const 165)
+
+  (func (export "execute") (param $offset i32) (param $size i32) (result i64)
+    local.get $offset
+    i64.extend_i32_u
+    i64.const 32
+    i64.shl
+    local.get $size
+    i64.extend_i32_u
+    i64.or))
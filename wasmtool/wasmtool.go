@@ -0,0 +1,212 @@
+// Package wasmtool loads tools compiled to WebAssembly, so third-party tools
+// can be distributed as .wasm files and run sandboxed without recompiling the
+// host application.
+//
+// # ABI
+//
+// A tool module exports its linear memory as "memory" and the functions:
+//
+//	alloc(size i32) i32                 reserve size bytes, return their offset
+//	describe() i64                      return the tool definition
+//	execute(offset i32, size i32) i64   run the tool on the JSON arguments
+//
+// and optionally dealloc(offset i32, size i32) to release a buffer returned
+// by alloc, describe or execute. Values returned as i64 pack the offset of a
+// JSON document in the high 32 bits and its size in the low 32 bits.
+//
+// describe returns {"name": ..., "description": ..., "parameters": <JSON
+// schema>}. execute receives the arguments object chosen by the LLM and
+// returns {"result": "..."} or {"error": "..."}.
+//
+// The WebAssembly engine is pluggable through Runtime. WazeroRuntime runs
+// the modules with wazero; an adapter for another engine only needs to
+// instantiate modules and expose calls and memory access.
+package wasmtool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/mudler/cogito"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Runtime instantiates WebAssembly modules. Instances must not share state,
+// and must not have access to the host beyond what the runtime grants.
+type Runtime interface {
+	Instantiate(ctx context.Context, wasm []byte) (Instance, error)
+}
+
+// Instance is an instantiated module.
+type Instance interface {
+	// Call calls the exported function name. It returns ErrNotExported when
+	// the module does not export it.
+	Call(ctx context.Context, name string, params ...uint64) ([]uint64, error)
+	// Read returns size bytes of the memory at offset.
+	Read(offset, size uint32) ([]byte, bool)
+	// Write copies data to the memory at offset.
+	Write(offset uint32, data []byte) bool
+	Close(ctx context.Context) error
+}
+
+// ErrNotExported is returned by Instance.Call for functions the module does
+// not export.
+var ErrNotExported = errors.New("function not exported")
+
+// LoadFile loads the tool module at path, see Load.
+func LoadFile(ctx context.Context, rt Runtime, path string) (*Tool, error) {
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return Load(ctx, rt, wasm)
+}
+
+// Load instantiates the tool module wasm with rt and reads its definition.
+// The instance is kept for the calls of the tool: close it with Close.
+func Load(ctx context.Context, rt Runtime, wasm []byte) (*Tool, error) {
+	instance, err := rt.Instantiate(ctx, wasm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to instantiate the tool module: %w", err)
+	}
+
+	t := &Tool{instance: instance}
+	results, err := instance.Call(ctx, "describe")
+	if err != nil {
+		instance.Close(ctx)
+		return nil, fmt.Errorf("describe failed: %w", err)
+	}
+	data, err := t.output(ctx, results)
+	if err != nil {
+		instance.Close(ctx)
+		return nil, fmt.Errorf("describe failed: %w", err)
+	}
+
+	var definition struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+	}
+	if err := json.Unmarshal(data, &definition); err != nil {
+		instance.Close(ctx)
+		return nil, fmt.Errorf("invalid tool definition: %w", err)
+	}
+	if definition.Name == "" {
+		instance.Close(ctx)
+		return nil, fmt.Errorf("invalid tool definition: missing name")
+	}
+	parameters := definition.Parameters
+	if len(parameters) == 0 {
+		parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+
+	t.definition = openai.FunctionDefinition{
+		Name:        definition.Name,
+		Description: definition.Description,
+		Parameters:  parameters,
+	}
+	return t, nil
+}
+
+// Tool is a tool run by a WebAssembly module. Calls are serialized, as a
+// module instance runs one call at a time.
+type Tool struct {
+	mu         sync.Mutex
+	instance   Instance
+	definition openai.FunctionDefinition
+}
+
+var _ cogito.ToolWithContext = (*Tool)(nil)
+
+func (t *Tool) Tool() openai.Tool {
+	definition := t.definition
+	return openai.Tool{Type: openai.ToolTypeFunction, Function: &definition}
+}
+
+func (t *Tool) Execute(args map[string]any) (string, any, error) {
+	return t.ExecuteWithContext(context.Background(), args)
+}
+
+// ExecuteWithContext implements cogito.ToolWithContext, passing args to the
+// execute function of the module.
+func (t *Tool) ExecuteWithContext(ctx context.Context, args map[string]any) (string, any, error) {
+	input, err := json.Marshal(args)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to encode arguments: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	offset, err := t.input(ctx, input)
+	if err != nil {
+		return "", nil, err
+	}
+	results, err := t.instance.Call(ctx, "execute", uint64(offset), uint64(len(input)))
+	t.free(ctx, offset, uint32(len(input)))
+	if err != nil {
+		return "", nil, fmt.Errorf("%s failed: %w", t.definition.Name, err)
+	}
+	data, err := t.output(ctx, results)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s failed: %w", t.definition.Name, err)
+	}
+
+	var output struct {
+		Result string `json:"result"`
+		Error  string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		return "", nil, fmt.Errorf("%s returned an invalid output: %w", t.definition.Name, err)
+	}
+	if output.Error != "" {
+		return output.Result, nil, fmt.Errorf("%s failed: %s", t.definition.Name, output.Error)
+	}
+	return output.Result, nil, nil
+}
+
+// Close releases the module instance.
+func (t *Tool) Close(ctx context.Context) error {
+	return t.instance.Close(ctx)
+}
+
+// input copies data to a buffer allocated in the module memory.
+func (t *Tool) input(ctx context.Context, data []byte) (uint32, error) {
+	results, err := t.instance.Call(ctx, "alloc", uint64(len(data)))
+	if err != nil {
+		return 0, fmt.Errorf("alloc failed: %w", err)
+	}
+	if len(results) != 1 {
+		return 0, fmt.Errorf("alloc returned %d values, want 1", len(results))
+	}
+	offset := uint32(results[0])
+	if !t.instance.Write(offset, data) {
+		return 0, fmt.Errorf("alloc returned an offset out of memory: %d", offset)
+	}
+	return offset, nil
+}
+
+// output reads the JSON document packed in results, and frees it.
+func (t *Tool) output(ctx context.Context, results []uint64) ([]byte, error) {
+	if len(results) != 1 {
+		return nil, fmt.Errorf("returned %d values, want 1", len(results))
+	}
+	offset, size := uint32(results[0]>>32), uint32(results[0])
+	data, ok := t.instance.Read(offset, size)
+	if !ok {
+		return nil, fmt.Errorf("returned a buffer out of memory: offset %d, size %d", offset, size)
+	}
+	data = append([]byte(nil), data...)
+	t.free(ctx, offset, size)
+	return data, nil
+}
+
+// free calls dealloc. It is optional, modules without it manage their
+// buffers themselves.
+func (t *Tool) free(ctx context.Context, offset, size uint32) {
+	_, _ = t.instance.Call(ctx, "dealloc", uint64(offset), uint64(size))
+}
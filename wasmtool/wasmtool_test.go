@@ -0,0 +1,150 @@
+package wasmtool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeRuntime instantiates fakeInstances, which implement the tool ABI in Go.
+type fakeRuntime struct{ instances []*fakeInstance }
+
+func (r *fakeRuntime) Instantiate(ctx context.Context, wasm []byte) (Instance, error) {
+	if string(wasm) != "greeter" {
+		return nil, errors.New("invalid module")
+	}
+	i := &fakeInstance{memory: make([]byte, 1024), next: 8}
+	r.instances = append(r.instances, i)
+	return i, nil
+}
+
+type fakeInstance struct {
+	memory []byte
+	next   uint32
+	freed  int
+	closed bool
+}
+
+func (i *fakeInstance) alloc(size uint32) uint32 {
+	offset := i.next
+	i.next += size
+	return offset
+}
+
+func (i *fakeInstance) output(data string) []uint64 {
+	offset := i.alloc(uint32(len(data)))
+	copy(i.memory[offset:], data)
+	return []uint64{uint64(offset)<<32 | uint64(len(data))}
+}
+
+func (i *fakeInstance) Call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	switch name {
+	case "alloc":
+		return []uint64{uint64(i.alloc(uint32(params[0])))}, nil
+	case "dealloc":
+		i.freed++
+		return nil, nil
+	case "describe":
+		return i.output(`{"name": "greet", "description": "Greet someone", "parameters": {"type": "object", "properties": {"name": {"type": "string"}}}}`), nil
+	case "execute":
+		var args struct{ Name string }
+		if err := json.Unmarshal(i.memory[params[0]:params[0]+params[1]], &args); err != nil {
+			return nil, err
+		}
+		if args.Name == "" {
+			return i.output(`{"error": "name is required"}`), nil
+		}
+		return i.output(`{"result": "Hello, ` + args.Name + `!"}`), nil
+	}
+	return nil, ErrNotExported
+}
+
+func (i *fakeInstance) Read(offset, size uint32) ([]byte, bool) {
+	if uint64(offset)+uint64(size) > uint64(len(i.memory)) {
+		return nil, false
+	}
+	return i.memory[offset : offset+size], true
+}
+
+func (i *fakeInstance) Write(offset uint32, data []byte) bool {
+	if uint64(offset)+uint64(len(data)) > uint64(len(i.memory)) {
+		return false
+	}
+	copy(i.memory[offset:], data)
+	return true
+}
+
+func (i *fakeInstance) Close(ctx context.Context) error {
+	i.closed = true
+	return nil
+}
+
+func TestLoad(t *testing.T) {
+	rt := &fakeRuntime{}
+	tool, err := Load(context.Background(), rt, []byte("greeter"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	definition := tool.Tool().Function
+	if definition.Name != "greet" || definition.Description != "Greet someone" {
+		t.Fatalf("definition = %+v", definition)
+	}
+	schema, _ := json.Marshal(definition.Parameters)
+	if !strings.Contains(string(schema), `"name":{"type":"string"}`) {
+		t.Fatalf("parameters = %s", schema)
+	}
+
+	result, _, err := tool.Execute(map[string]any{"name": "Ada"})
+	if err != nil || result != "Hello, Ada!" {
+		t.Fatalf("result = %q, %v", result, err)
+	}
+	if _, _, err := tool.Execute(map[string]any{}); err == nil || !strings.Contains(err.Error(), "name is required") {
+		t.Fatalf("expected the tool error, got %v", err)
+	}
+
+	// describe output, and input and output of the two calls
+	if freed := rt.instances[0].freed; freed != 5 {
+		t.Fatalf("freed %d buffers, want 5", freed)
+	}
+	tool.Close(context.Background())
+	if !rt.instances[0].closed {
+		t.Fatal("instance not closed")
+	}
+}
+
+func TestLoadInvalidModule(t *testing.T) {
+	if _, err := Load(context.Background(), &fakeRuntime{}, []byte("not wasm")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestWazeroRuntime(t *testing.T) {
+	ctx := context.Background()
+	rt := NewWazeroRuntime(ctx)
+	defer rt.Close(ctx)
+
+	// testdata/echo.wasm is built from testdata/echo.wat
+	tool, err := LoadFile(ctx, rt, "testdata/echo.wasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tool.Close(ctx)
+	if name := tool.Tool().Function.Name; name != "echo" {
+		t.Fatalf("name = %q", name)
+	}
+
+	result, _, err := tool.Execute(map[string]any{"result": "Hello, Ada!"})
+	if err != nil || result != "Hello, Ada!" {
+		t.Fatalf("result = %q, %v", result, err)
+	}
+	if _, _, err := tool.Execute(map[string]any{"error": "boom"}); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected the tool error, got %v", err)
+	}
+
+	if _, err := Load(ctx, rt, []byte("not wasm")); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,69 @@
+package wasmtool
+
+import (
+	"context"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+)
+
+// WazeroRuntime is a Runtime backed by wazero, a WebAssembly engine written
+// in Go. Modules get no host functions, WASI included: they only compute
+// over the JSON documents exchanged through their memory.
+type WazeroRuntime struct {
+	runtime wazero.Runtime
+}
+
+var _ Runtime = (*WazeroRuntime)(nil)
+
+// NewWazeroRuntime returns a WazeroRuntime. Close it once the tools it
+// loaded are closed.
+func NewWazeroRuntime(ctx context.Context) *WazeroRuntime {
+	return &WazeroRuntime{runtime: wazero.NewRuntime(ctx)}
+}
+
+// Instantiate implements Runtime. Instances are anonymous, so several tools
+// and several instances of a module can share the runtime.
+func (r *WazeroRuntime) Instantiate(ctx context.Context, wasm []byte) (Instance, error) {
+	module, err := r.runtime.InstantiateWithConfig(ctx, wasm, wazero.NewModuleConfig().WithName(""))
+	if err != nil {
+		return nil, err
+	}
+	return wazeroInstance{module}, nil
+}
+
+// Close releases the runtime and the instances left open.
+func (r *WazeroRuntime) Close(ctx context.Context) error {
+	return r.runtime.Close(ctx)
+}
+
+// wazeroInstance is an Instance of a wazero module.
+type wazeroInstance struct {
+	module api.Module
+}
+
+func (i wazeroInstance) Call(ctx context.Context, name string, params ...uint64) ([]uint64, error) {
+	fn := i.module.ExportedFunction(name)
+	if fn == nil {
+		return nil, ErrNotExported
+	}
+	return fn.Call(ctx, params...)
+}
+
+func (i wazeroInstance) Read(offset, size uint32) ([]byte, bool) {
+	if i.module.Memory() == nil {
+		return nil, false
+	}
+	return i.module.Memory().Read(offset, size)
+}
+
+func (i wazeroInstance) Write(offset uint32, data []byte) bool {
+	if i.module.Memory() == nil {
+		return false
+	}
+	return i.module.Memory().Write(offset, data)
+}
+
+func (i wazeroInstance) Close(ctx context.Context) error {
+	return i.module.Close(ctx)
+}
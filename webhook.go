@@ -0,0 +1,164 @@
+package cogito
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/mudler/cogito/structures"
+)
+
+// WebhookEvent is a key event of a run notified to webhooks, see WithWebhook.
+type WebhookEvent string
+
+const (
+	WebhookRunStarted       WebhookEvent = "run_started"
+	WebhookRunCompleted     WebhookEvent = "run_completed"
+	WebhookRunFailed        WebhookEvent = "run_failed"
+	WebhookToolFailed       WebhookEvent = "tool_failed"
+	WebhookApprovalRequired WebhookEvent = "approval_required" // a tool call is submitted to WithToolCallBack
+	WebhookPlanCompleted    WebhookEvent = "plan_completed"
+	WebhookPlanReplanned    WebhookEvent = "plan_replanned"
+)
+
+// WebhookPayload is the JSON body POSTed to webhooks. Text summarizes the
+// event in a line, which is what Slack incoming webhooks display.
+type WebhookPayload struct {
+	Event     WebhookEvent     `json:"event"`
+	Time      time.Time        `json:"time"`
+	Text      string           `json:"text"`
+	RunID     string           `json:"run_id,omitempty"` // see ContextWithRunID
+	Tool      string           `json:"tool,omitempty"`
+	Arguments map[string]any   `json:"arguments,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Answer    string           `json:"answer,omitempty"`
+	Plan      *structures.Plan `json:"plan,omitempty"`
+	Usage     *LLMUsage        `json:"usage,omitempty"`
+}
+
+type webhook struct {
+	url    string
+	events []WebhookEvent // all when empty
+}
+
+// defaultWebhookClient sends the notifications of runs without
+// WithWebhookClient.
+var defaultWebhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// WithWebhook POSTs a JSON WebhookPayload to url on the given events of the
+// run, or on all of them when none is given. Notifications are sent in the
+// background and failures are only logged, they never slow down or fail the
+// run. WithWebhook can be used several times, e.g. for different events.
+func WithWebhook(url string, events ...WebhookEvent) func(o *Options) {
+	return func(o *Options) {
+		o.webhooks = append(slices.Clip(o.webhooks), webhook{url: url, events: events})
+	}
+}
+
+// WithWebhookClient sets the HTTP client sending webhook notifications, e.g.
+// to add authentication. It defaults to a client with a 10 seconds timeout.
+func WithWebhookClient(client *http.Client) func(o *Options) {
+	return func(o *Options) {
+		o.webhookClient = client
+	}
+}
+
+// notifyWebhooks sends p to the webhooks of o subscribed to its event.
+func notifyWebhooks(o *Options, p WebhookPayload) {
+	if len(o.webhooks) == 0 {
+		return
+	}
+	p.Time = time.Now()
+	if o.context != nil {
+		p.RunID = RunIDFromContext(o.context)
+	}
+	body, err := json.Marshal(p.redact(o.redactor))
+	if err != nil {
+		o.logger.Warn("Failed to encode webhook payload", "event", p.Event, "error", err)
+		return
+	}
+	client := o.webhookClient
+	if client == nil {
+		client = defaultWebhookClient
+	}
+	for _, w := range o.webhooks {
+		if len(w.events) > 0 && !slices.Contains(w.events, p.Event) {
+			continue
+		}
+		go func(url string) {
+			// Not tied to the run: a cancelled run is still notified
+			req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, url, bytes.NewReader(body))
+			if err != nil {
+				o.logger.Warn("Failed to notify webhook", "event", p.Event, "error", err)
+				return
+			}
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := client.Do(req)
+			if err != nil {
+				o.logger.Warn("Failed to notify webhook", "event", p.Event, "error", err)
+				return
+			}
+			resp.Body.Close()
+			if resp.StatusCode >= 300 {
+				o.logger.Warn("Webhook rejected notification", "event", p.Event, "status", resp.StatusCode)
+			}
+		}(w.url)
+	}
+}
+
+// redact returns p with its text redacted by r, if any.
+func (p WebhookPayload) redact(r *Redactor) WebhookPayload {
+	if r == nil {
+		return p
+	}
+	p.Text, p.Error, p.Answer = r.Redact(p.Text), r.Redact(p.Error), r.Redact(p.Answer)
+	if p.Arguments != nil {
+		p.Arguments = r.redactValue(p.Arguments).(map[string]any)
+	}
+	if p.Plan != nil {
+		plan := structures.Plan{Description: r.Redact(p.Plan.Description), Subtasks: make([]string, len(p.Plan.Subtasks))}
+		for i, subtask := range p.Plan.Subtasks {
+			plan.Subtasks[i] = r.Redact(subtask)
+		}
+		p.Plan = &plan
+	}
+	return p
+}
+
+// notifyRunEnd notifies the end of a run with result and err.
+func notifyRunEnd(o *Options, result Fragment, err error, answered bool, usage LLMUsage) {
+	if !answered {
+		notifyWebhooks(o, WebhookPayload{Event: WebhookRunFailed, Text: fmt.Sprintf("Run failed: %v", err), Error: err.Error(), Usage: &usage})
+		return
+	}
+	p := WebhookPayload{Event: WebhookRunCompleted, Text: "Run completed", Usage: &usage}
+	if msg := result.LastMessage(); msg != nil && msg.Role == AssistantMessageRole.String() {
+		p.Answer = msg.Content
+	}
+	notifyWebhooks(o, p)
+}
+
+// notifyToolFailed notifies the failure of the call tc.
+func notifyToolFailed(o *Options, tc *ToolChoice, err error) {
+	notifyWebhooks(o, WebhookPayload{
+		Event:     WebhookToolFailed,
+		Text:      fmt.Sprintf("Tool %s failed: %v", tc.Name, err),
+		Tool:      tc.Name,
+		Arguments: tc.Arguments,
+		Error:     err.Error(),
+	})
+}
+
+// notifyPlanProgress notifies the plan events of p.
+func notifyPlanProgress(o *Options, p PlanProgress) {
+	switch p.Event {
+	case PlanCompleted:
+		notifyWebhooks(o, WebhookPayload{Event: WebhookPlanCompleted, Text: "Plan completed: " + p.Plan.Description, Plan: p.Plan})
+	case PlanReplanned:
+		notifyWebhooks(o, WebhookPayload{Event: WebhookPlanReplanned, Text: "Plan re-evaluated: " + p.Plan.Description, Plan: p.Plan})
+	}
+}
@@ -0,0 +1,117 @@
+package cogito_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/mudler/cogito"
+	"github.com/mudler/cogito/cogitotest"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Webhooks", func() {
+	var (
+		server   *httptest.Server
+		mu       sync.Mutex
+		received []WebhookPayload
+	)
+
+	BeforeEach(func() {
+		received = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var p WebhookPayload
+			Expect(json.NewDecoder(r.Body).Decode(&p)).To(Succeed())
+			mu.Lock()
+			received = append(received, p)
+			mu.Unlock()
+		}))
+		DeferCleanup(server.Close)
+	})
+
+	events := func() []WebhookEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		var evs []WebhookEvent
+		for _, p := range received {
+			evs = append(evs, p.Event)
+		}
+		return evs
+	}
+
+	It("notifies the key events of a run", func() {
+		weather := cogitotest.NewMockTool("get_weather", "Get the weather of a city")
+		cogitotest.GetMockTool(weather).SetRunError(errors.New("service unavailable"))
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.HasTool("get_weather")).
+			ReplyToolCall("get_weather", `{"city": "Rome"}`)
+		mockLLM.SetAskResponse("The weather service is unavailable.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "What's the weather in Rome?")
+		_, err := ExecuteTools(mockLLM, f, WithTools(weather), WithWebhook(server.URL),
+			WithToolCallBack(func(*ToolChoice, *SessionState) ToolCallDecision {
+				return ToolCallDecision{Approved: true}
+			}))
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(events).Should(ConsistOf(WebhookRunStarted, WebhookApprovalRequired, WebhookToolFailed, WebhookRunCompleted))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range received {
+			switch p.Event {
+			case WebhookToolFailed:
+				Expect(p.Tool).To(Equal("get_weather"))
+				Expect(p.Arguments).To(Equal(map[string]any{"city": "Rome"}))
+				Expect(p.Error).To(ContainSubstring("service unavailable"))
+				Expect(p.Text).To(ContainSubstring("get_weather failed"))
+			case WebhookRunCompleted:
+				Expect(p.Answer).To(Equal("The weather service is unavailable."))
+			}
+		}
+	})
+
+	It("redacts the notifications", func() {
+		notify := cogitotest.NewMockTool("notify", "Notify a user")
+		cogitotest.GetMockTool(notify).SetRunError(errors.New("no mailbox for bob@example.com"))
+
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.When(cogitotest.HasTool("notify")).
+			ReplyToolCall("notify", `{"to": ["bob@example.com"], "text": "Hello"}`)
+		mockLLM.SetAskResponse("I could not notify bob@example.com.")
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Say hello to bob@example.com")
+		_, err := ExecuteTools(mockLLM, f, WithTools(notify), WithRedactor(NewRedactor()),
+			WithWebhook(server.URL, WebhookToolFailed, WebhookRunCompleted))
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(events).Should(ConsistOf(WebhookToolFailed, WebhookRunCompleted))
+		mu.Lock()
+		defer mu.Unlock()
+		for _, p := range received {
+			encoded, err := json.Marshal(p)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(encoded)).ToNot(ContainSubstring("bob@example.com"))
+			if p.Event == WebhookToolFailed {
+				Expect(p.Arguments).To(Equal(map[string]any{"to": []any{"[REDACTED:email]"}, "text": "Hello"}))
+			}
+		}
+	})
+
+	It("only notifies the subscribed events", func() {
+		mockLLM := cogitotest.NewMockLLM()
+		mockLLM.SetAskError(errors.New("model unavailable"))
+		mockLLM.SetCreateChatCompletionError(errors.New("model unavailable"))
+
+		f := NewEmptyFragment().AddMessage(UserMessageRole, "Hello")
+		_, err := ExecuteTools(mockLLM, f, WithTools(cogitotest.NewMockTool("noop", "Do nothing")),
+			WithWebhook(server.URL, WebhookRunFailed), WithMaxRetries(1))
+		Expect(err).To(HaveOccurred())
+
+		Eventually(events).Should(Equal([]WebhookEvent{WebhookRunFailed}))
+		Consistently(events, "100ms").Should(HaveLen(1))
+	})
+})